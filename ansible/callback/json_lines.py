@@ -160,6 +160,12 @@ class CallbackModule(CallbackBase):
     #     self.playbook_on_not_import_for_host(host, missing_file)
 
     def v2_playbook_on_play_start(self, play):
+        # Stop the playbook before the play starts if the operation was
+        # cancelled. KeyboardInterrupt is not swallowed by the callback
+        # dispatcher, and ansible-playbook exits as if it was interrupted.
+        cancel_file = os.environ.get("ANSIBLE_CANCEL_FILE")
+        if cancel_file and os.path.exists(cancel_file):
+            raise KeyboardInterrupt()
         data = {
             'name': play.name
         }
@@ -9,9 +9,21 @@ def final_image(upstream_image, registry_url = '', private = False):
         raise errors.AnsibleFilterError('Must pass registry url when using private registry.')
     return registry_url + "/" + upstream_image
 
+# Returns the versioned name of the container image for the given
+# architecture. Images that are not multi-arch define the name and
+# version to use on other architectures under the architecture's key.
+def arch_image(image, arch = 'amd64'):
+    variant = {}
+    if arch != 'amd64':
+        if arch not in image:
+            raise errors.AnsibleFilterError('Image %s is not available for %s.' % (image['name'], arch))
+        variant = image[arch]
+    return '%s:%s' % (variant.get('name', image['name']), variant.get('version', image['version']))
+
 class FilterModule(object):
     filter_map = {
-        'final_image': final_image
+        'final_image': final_image,
+        'arch_image': arch_image
     }
 
     def filters(self):
@@ -0,0 +1,40 @@
+#!/bin/bash
+# Checks the expiration of the Kubernetes cluster. The webhook is notified
+# when the cluster is about to expire, and when it expires the nodes are
+# cordoned. This file is managed by KET.
+set -euo pipefail
+
+STATE_DIR="{{ cluster_expiration_dir }}"
+WARN_BEFORE={{ cluster_expiration.warn_before_seconds }}
+WEBHOOK_URL="{{ cluster_expiration.webhook_url }}"
+
+expires_at=$(cat "$STATE_DIR/expires_at")
+now=$(date -u +%s)
+
+notify() {
+  local event="$1"
+  if [ -z "$WEBHOOK_URL" ]; then
+    return 0
+  fi
+  curl --silent --show-error --fail --max-time 30 -X POST \
+    -H "Content-Type: application/json" \
+    -d "{\"cluster\":\"{{ kubernetes_cluster_name }}\",\"event\":\"$event\",\"expires_at\":\"$(date -u -d @"$expires_at" +%Y-%m-%dT%H:%M:%SZ)\"}" \
+    "$WEBHOOK_URL"
+}
+
+if [ "$now" -ge "$expires_at" ]; then
+  if [ ! -f "$STATE_DIR/expired" ]; then
+    echo "cluster expired, cordoning the nodes"
+    for node in $(kubectl --kubeconfig {{ kubernetes_kubeconfig_path }} get nodes -o name); do
+      kubectl --kubeconfig {{ kubernetes_kubeconfig_path }} cordon "${node#*/}"
+    done
+    notify expired
+    touch "$STATE_DIR/expired"
+  fi
+elif [ "$now" -ge $((expires_at - WARN_BEFORE)) ]; then
+  if [ ! -f "$STATE_DIR/warned" ]; then
+    echo "cluster expires at $(date -u -d @"$expires_at")"
+    notify expiring
+    touch "$STATE_DIR/warned"
+  fi
+fi
@@ -0,0 +1,65 @@
+#!/bin/bash
+# Takes encrypted snapshots of the Kubernetes etcd cluster, and uploads them
+# to {{ etcd_backup.remote }}. This file is managed by KET.
+#
+# Usage:
+#   {{ etcd_backup_script }} backup
+#   {{ etcd_backup_script }} download SNAPSHOT_NAME OUTPUT_FILE
+set -euo pipefail
+
+REMOTE="{{ etcd_backup.remote }}"
+RETENTION={{ etcd_backup.retention }}
+WORKDIR=$(mktemp -d)
+trap 'rm -rf "$WORKDIR"' EXIT
+
+rclone() {
+{% if etcd_backup.provider == "gcs" %}
+  docker run --rm --net=host -v "$WORKDIR":/snapshots \
+    -v {{ etcd_backup_credentials_path }}:/credentials.json:ro \
+    {{ images.rclone }} --gcs-service-account-file /credentials.json "$@"
+{% else %}
+  docker run --rm --net=host -v "$WORKDIR":/snapshots \
+    --env-file {{ etcd_backup_credentials_path }} \
+    {{ images.rclone }} --s3-provider AWS --s3-env-auth {% if etcd_backup.region != "" %}--s3-region {{ etcd_backup.region }} {% endif %}"$@"
+{% endif %}
+}
+
+backup() {
+  local name="etcd-snapshot-$(date -u +%Y%m%dT%H%M%SZ).db"
+  docker run --rm --net=host -e ETCDCTL_API=3 \
+    --volume={{ etcd_install_dir }}:{{ etcd_install_dir }}:ro \
+    --volume="$WORKDIR":/snapshots \
+    {{ images.etcd }} /usr/local/bin/etcdctl \
+    --endpoints=https://127.0.0.1:{{ etcd_service_client_port }} \
+    --cacert={{ etcd_certificates.ca }} --cert={{ etcd_certificates.etcd_client }} --key={{ etcd_certificates.etcd_client_key }} \
+    snapshot save "/snapshots/$name"
+  openssl enc -aes-256-cbc -md sha256 -salt -pass file:{{ etcd_backup_key_path }} \
+    -in "$WORKDIR/$name" -out "$WORKDIR/$name.enc"
+  rclone copyto "/snapshots/$name.enc" "$REMOTE/$name.enc"
+  echo "uploaded snapshot $name.enc"
+
+  # delete the snapshots beyond the retention count, oldest first
+  rclone lsf --files-only "$REMOTE" | grep '^etcd-snapshot-.*\.db\.enc$' | sort | head -n -"$RETENTION" | while read -r old; do
+    rclone deletefile "$REMOTE/$old"
+    echo "deleted snapshot $old"
+  done
+}
+
+download() {
+  rclone copyto "$REMOTE/$1" /snapshots/snapshot.enc
+  openssl enc -d -aes-256-cbc -md sha256 -pass file:{{ etcd_backup_key_path }} \
+    -in "$WORKDIR/snapshot.enc" -out "$2"
+}
+
+case "${1:-backup}" in
+  backup)
+    backup
+    ;;
+  download)
+    download "$2" "$3"
+    ;;
+  *)
+    echo "unknown command $1" >&2
+    exit 1
+    ;;
+esac
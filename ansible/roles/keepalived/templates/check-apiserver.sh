@@ -0,0 +1,3 @@
+#!/bin/sh
+# The API server rejects anonymous requests, so only its port is checked
+exec nc -z -w 2 127.0.0.1 {{ kubernetes_master_secure_port }}
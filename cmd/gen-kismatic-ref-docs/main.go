@@ -7,6 +7,7 @@ import (
 	godoc "go/doc"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"os"
 	"reflect"
 	"strings"
@@ -134,7 +135,7 @@ func docForType(typeName string, allTypes []*godoc.Type, parentFieldName string)
 							docs = append(docs, docForType(typeName, allTypes, fieldName)...)
 						}
 					case *ast.MapType:
-						typeName = fmt.Sprintf("map[%s]%s", x.Key.(*ast.Ident).Name, x.Value.(*ast.Ident).Name)
+						typeName = types.ExprString(x)
 						d, err := parseDoc(fieldName, typeName, f.Doc.Text())
 						if err != nil {
 							panic(err)
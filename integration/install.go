@@ -65,6 +65,7 @@ func installKismaticMini(node NodeDeets, sshKey string) error {
 		MasterNodeShortName: node.PublicIP,
 		SSHKeyFile:          sshKey,
 		SSHUser:             sshUser,
+		AllowSingleNode:     true,
 	}
 	return installKismaticWithPlan(plan, sshKey)
 }
@@ -123,7 +124,7 @@ func installKismaticWithPlan(plan PlanAWS, sshKey string) error {
 	writePlanFile(plan)
 
 	By("Punch it Chewie!")
-	cmd := exec.Command("./kismatic", "install", "apply", "-f", "kismatic-testing.yaml")
+	cmd := exec.Command("./kismatic", "install", "apply", "-f", "kismatic-testing.yaml", "--accept-warnings")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -191,7 +192,7 @@ func installKismaticWithABadNode() {
 	f.Close()
 
 	By("Validing our plan")
-	cmd := exec.Command("./kismatic", "install", "validate", "-f", f.Name())
+	cmd := exec.Command("./kismatic", "install", "validate", "-f", f.Name(), "--accept-warnings")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	err = cmd.Run()
@@ -200,7 +201,7 @@ func installKismaticWithABadNode() {
 	}
 
 	By("Well, try it anyway")
-	cmd = exec.Command("./kismatic", "install", "apply", "-f", f.Name())
+	cmd = exec.Command("./kismatic", "install", "apply", "-f", f.Name(), "--accept-warnings")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	err = cmd.Run()
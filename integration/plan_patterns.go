@@ -18,6 +18,7 @@ type PlanAWS struct {
 	HomeDirectory                string
 	DisablePackageInstallation   bool
 	DisconnectedInstallation     bool
+	AllowSingleNode              bool
 	DockerRegistryServer         string
 	DockerRegistryCAPath         string
 	DockerRegistryUsername       string
@@ -47,6 +48,8 @@ const planAWSOverlay = `cluster:
   admin_password: abbazabba
   disable_package_installation: {{.DisablePackageInstallation}}
   disconnected_installation: {{.DisconnectedInstallation}}
+  allow_single_node: {{.AllowSingleNode}}
+  selinux: enforcing
   networking:
     type: overlay                                                 # Required for KET <= v1.4.1
     pod_cidr_block: 172.16.0.0/16
@@ -102,7 +102,7 @@ func ValidateKismaticMini(node NodeDeets, user, sshKey string) PlanAWS {
 
 	// Run validation
 	By("Validate our plan")
-	ver := exec.Command("./kismatic", "install", "validate", "-f", f.Name())
+	ver := exec.Command("./kismatic", "install", "validate", "-f", f.Name(), "--accept-warnings")
 	ver.Stdout = os.Stdout
 	ver.Stderr = os.Stderr
 	err = ver.Run()
@@ -141,7 +141,7 @@ func ValidateKismaticMiniDenyPkgInstallation(node NodeDeets, sshUser, sshKey str
 
 	// Run validation
 	By("Validate our plan")
-	cmd := exec.Command("./kismatic", "install", "validate", "-f", f.Name())
+	cmd := exec.Command("./kismatic", "install", "validate", "-f", f.Name(), "--accept-warnings")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -175,7 +175,7 @@ func ValidateKismaticMiniWithBadSSH(node NodeDeets, user, sshKey string) PlanAWS
 
 	// Run validation
 	By("Validate our plan")
-	ver := exec.Command("./kismatic", "install", "validate", "-f", f.Name())
+	ver := exec.Command("./kismatic", "install", "validate", "-f", f.Name(), "--accept-warnings")
 	ver.Stdout = os.Stdout
 	ver.Stderr = os.Stderr
 	err = ver.Run()
@@ -14,6 +14,9 @@ type ClusterCatalog struct {
 	PodCIDR                   string `yaml:"kubernetes_pods_cidr"`
 	DNSServiceIP              string `yaml:"kubernetes_dns_service_ip"`
 	EnableModifyHosts         bool   `yaml:"modify_hosts_file"`
+	EnableManageFirewall      bool   `yaml:"manage_firewall"`
+	SELinuxMode               string `yaml:"selinux_mode"`
+	HardeningProfile          string `yaml:"hardening_profile"`
 	EnablePackageInstallation bool   `yaml:"allow_package_installation"`
 	DisconnectedInstallation  bool   `yaml:"disconnected_installation"`
 	KuberangPath              string `yaml:"kuberang_path"`
@@ -31,6 +34,11 @@ type ClusterCatalog struct {
 	DockerRegistryUsername             string `yaml:"docker_registry_username"`
 	DockerRegistryPassword             string `yaml:"docker_registry_password"`
 
+	RegistryCredentials struct {
+		DockerConfigJSON string   `yaml:"docker_config_json"`
+		Namespaces       []string `yaml:"namespaces"`
+	} `yaml:"registry_credentials"`
+
 	ForceEtcdRestart              bool `yaml:"force_etcd_restart"`
 	ForceAPIServerRestart         bool `yaml:"force_apiserver_restart"`
 	ForceControllerManagerRestart bool `yaml:"force_controller_manager_restart"`
@@ -46,8 +54,20 @@ type ClusterCatalog struct {
 
 	WorkerNode string `yaml:"worker_node"`
 
+	VirtualIP struct {
+		Enabled   bool
+		Address   string
+		Interface string
+		RouterID  int `yaml:"router_id"`
+	} `yaml:"virtual_ip"`
+
 	NFSVolumes []NFSVolume `yaml:"nfs_volumes"`
 
+	PostInstall struct {
+		Manifests []PostInstallManifest
+		Retries   int
+	} `yaml:"post_install"`
+
 	EnableGluster bool `yaml:"configure_storage"`
 
 	// volume add vars
@@ -66,31 +86,135 @@ type ClusterCatalog struct {
 
 	OnlineUpgrade bool `yaml:"online_upgrade"`
 
+	UpgradeAddOns []string `yaml:"upgrade_add_ons"`
+
 	DiagnosticsDirectory string `yaml:"diagnostics_dir"`
 	DiagnosticsDateTime  string `yaml:"diagnostics_date_time"`
 
-	DockerDirectLVMEnabled                 bool   `yaml:"docker_direct_lvm_enabled"`
-	DockerDirectLVMBlockDevicePath         string `yaml:"docker_direct_lvm_block_device_path"`
-	DockerDirectLVMDeferredDeletionEnabled bool   `yaml:"docker_direct_lvm_deferred_deletion_enabled"`
+	CISBenchmarkDirectory string `yaml:"cis_benchmark_dir"`
+	CISBenchmarkDateTime  string `yaml:"cis_benchmark_date_time"`
+
+	EtcdBackup struct {
+		Enabled           bool
+		Schedule          string
+		Provider          string
+		Remote            string
+		Region            string
+		CredentialsFile   string `yaml:"credentials_file"`
+		EncryptionKeyFile string `yaml:"encryption_key_file"`
+		Retention         int
+		Snapshot          string
+	} `yaml:"etcd_backup"`
+
+	DefaultPolicies struct {
+		Enabled               bool
+		DenyIngressNamespaces []string `yaml:"deny_ingress_namespaces"`
+	} `yaml:"default_policies"`
+
+	KubeletCertificateRotation struct {
+		Client bool
+		Server bool
+	} `yaml:"kubelet_certificate_rotation"`
+
+	HostDNS struct {
+		Nameservers   []string
+		SearchDomains []string `yaml:"search_domains"`
+		Options       []string
+	} `yaml:"host_dns"`
+
+	TimeSync struct {
+		Enabled        bool
+		Servers        []string
+		MaxSkewSeconds int `yaml:"max_skew_seconds"`
+	} `yaml:"time_sync"`
+
+	EtcdDataVolume struct {
+		Enabled          bool
+		BlockDevice      string `yaml:"block_device"`
+		Filesystem       string
+		MaxSyncLatencyMS int `yaml:"max_sync_latency_ms"`
+	} `yaml:"etcd_data_volume"`
+
+	Rollback struct {
+		Name              string
+		LocalDir          string            `yaml:"local_dir"`
+		KubernetesVersion string            `yaml:"kubernetes_version"`
+		NodeVersions      map[string]string `yaml:"node_versions"`
+	}
+
+	Audit struct {
+		Enabled    bool
+		Level      string
+		Sink       string
+		WebhookURL string `yaml:"webhook_url"`
+	}
+
+	AuthWebhook struct {
+		AuthenticationURL string `yaml:"authentication_url"`
+		AuthorizationURL  string `yaml:"authorization_url"`
+		CAPath            string `yaml:"ca_path"`
+	} `yaml:"auth_webhook"`
+
+	ClusterExpiration struct {
+		Enabled           bool
+		TTLSeconds        int64  `yaml:"ttl_seconds"`
+		ExpiresAt         int64  `yaml:"expires_at"`
+		WarnBeforeSeconds int64  `yaml:"warn_before_seconds"`
+		WebhookURL        string `yaml:"webhook_url"`
+	} `yaml:"cluster_expiration"`
+
+	SysctlSettings     map[string]string            `yaml:"sysctl_settings"`
+	SysctlRoleSettings map[string]map[string]string `yaml:"sysctl_role_settings"`
+
+	DockerDirectLVMEnabled                 bool     `yaml:"docker_direct_lvm_enabled"`
+	DockerDirectLVMBlockDevicePath         string   `yaml:"docker_direct_lvm_block_device_path"`
+	DockerDirectLVMDeferredDeletionEnabled bool     `yaml:"docker_direct_lvm_deferred_deletion_enabled"`
+	DockerRegistryMirrors                  []string `yaml:"docker_registry_mirrors"`
+	DockerInsecureRegistries               []string `yaml:"docker_insecure_registries"`
 
 	LocalKubeconfigDirectory string `yaml:"local_kubeconfig_directory"`
+	AddOnManifestsDirectory  string `yaml:"add_on_manifests_dir"`
 
 	CloudProvider string `yaml:"cloud_provider"`
 	CloudConfig   string `yaml:"cloud_config_local"`
 
 	DNS struct {
-		Enabled bool
+		Enabled  bool
+		Provider string
+		Options  struct {
+			UpstreamNameservers []string            `yaml:"upstream_nameservers"`
+			StubDomains         map[string][]string `yaml:"stub_domains"`
+			Autoscaler          struct {
+				Enabled         bool
+				CoresPerReplica int `yaml:"cores_per_replica"`
+				NodesPerReplica int `yaml:"nodes_per_replica"`
+				MinReplicas     int `yaml:"min_replicas"`
+				MaxReplicas     int `yaml:"max_replicas"`
+			}
+			NodeLocalCache struct {
+				Enabled bool
+				IP      string
+			} `yaml:"node_local_cache"`
+		}
 	}
 
 	RunPodValidation bool `yaml:"run_pod_validation"`
 
 	CNI struct {
-		Enabled  bool
-		Provider string
-		Options  struct {
+		Enabled    bool
+		Provider   string
+		MTU        int `yaml:"mtu"`
+		Encryption bool
+		Options    struct {
 			Calico struct {
 				Mode     string
 				LogLevel string `yaml:"log_level"`
+				BGP      struct {
+					ASNumber       uint32          `yaml:"as_number"`
+					NodeToNodeMesh bool            `yaml:"node_to_node_mesh"`
+					Peers          []CalicoBGPPeer `yaml:"peers"`
+				} `yaml:"bgp"`
+				IPPools []CalicoIPPool `yaml:"ip_pools"`
 			}
 		}
 	}
@@ -111,6 +235,18 @@ type ClusterCatalog struct {
 
 	Dashboard struct {
 		Enabled bool
+		Ingress struct {
+			Enabled bool
+			Host    string
+			Auth    string
+			OIDC    struct {
+				IssuerURL    string `yaml:"issuer_url"`
+				ClientID     string `yaml:"client_id"`
+				ClientSecret string `yaml:"client_secret"`
+				CookieSecret string `yaml:"cookie_secret"`
+				EmailDomain  string `yaml:"email_domain"`
+			} `yaml:"oidc"`
+		}
 	}
 
 	Helm struct {
@@ -121,6 +257,30 @@ type ClusterCatalog struct {
 		Enabled bool
 	}
 
+	ClusterAutoscaler struct {
+		Enabled    bool
+		NodeGroups []AutoscalerNodeGroup `yaml:"node_groups"`
+	} `yaml:"cluster_autoscaler"`
+
+	ExternalDNS struct {
+		Enabled         bool
+		Provider        string
+		DomainFilters   []string `yaml:"domain_filters"`
+		GoogleProject   string   `yaml:"google_project"`
+		IngressWildcard string   `yaml:"ingress_wildcard"`
+	} `yaml:"external_dns"`
+
+	RebootCoordinator struct {
+		Enabled              bool
+		SentinelFile         string `yaml:"sentinel_file"`
+		Period               string
+		BlockingPodSelectors []string `yaml:"blocking_pod_selectors"`
+	} `yaml:"reboot_coordinator"`
+
+	MetricsServer struct {
+		Enabled bool
+	} `yaml:"metrics_server"`
+
 	InsecureNetworkingEtcd bool `yaml:"insecure_networking_etcd"`
 
 	HTTPProxy  string `yaml:"http_proxy"`
@@ -128,6 +288,7 @@ type ClusterCatalog struct {
 	NoProxy    string `yaml:"no_proxy"`
 
 	NodeLabels         map[string][]string          `yaml:"node_labels"`
+	NodeTaints         map[string][]string          `yaml:"node_taints"`
 	KubeletNodeOptions map[string]map[string]string `yaml:"kubelet_node_overrides"`
 }
 
@@ -136,6 +297,36 @@ type NFSVolume struct {
 	Path string
 }
 
+// PostInstallManifest is a manifest that is applied after the cluster is
+// installed. The source is a local file, or a URL that is downloaded on the
+// master node. The manifest is named after its position in the list, so that
+// the manifests are applied in order.
+type PostInstallManifest struct {
+	Source string
+	URL    bool
+	Name   string
+}
+
+// CalicoBGPPeer is a BGP peer of all the calico nodes, or of a single node
+// when the node is set
+type CalicoBGPPeer struct {
+	IP       string `yaml:"ip"`
+	ASNumber uint32 `yaml:"as_number"`
+	Node     string `yaml:"node"`
+}
+
+type CalicoIPPool struct {
+	CIDR        string `yaml:"cidr"`
+	IPIPMode    string `yaml:"ipip_mode"`
+	NATOutgoing bool   `yaml:"nat_outgoing"`
+}
+
+type AutoscalerNodeGroup struct {
+	Name    string
+	MinSize int `yaml:"min_size"`
+	MaxSize int `yaml:"max_size"`
+}
+
 func (c *ClusterCatalog) EnableRestart() {
 	c.ForceEtcdRestart = true
 	c.ForceAPIServerRestart = true
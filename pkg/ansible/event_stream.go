@@ -9,7 +9,7 @@ import (
 )
 
 // EventStream reads JSON lines from the incoming stream, and convert them
-// into a stream of events.
+// into a stream of events. Known secret patterns in the lines are masked.
 func EventStream(in io.Reader) <-chan Event {
 	lr := util.NewLineReader(in, 64*1024)
 	out := make(chan Event)
@@ -21,7 +21,8 @@ func EventStream(in io.Reader) <-chan Event {
 			if err != nil { // we are done with the stream
 				break
 			}
-			event, err := eventFromJSONLine(line)
+			// Mask the secrets in the task results before they are explained
+			event, err := eventFromJSONLine([]byte(util.Redact(string(line))))
 			if err != nil {
 				// handle this error? Maybe have an outErr channel
 				continue
@@ -32,6 +32,11 @@ type Node struct {
 	SSHPort int
 	// SSHUser is the SSH user for logging into the node
 	SSHUser string
+	// SSHProxyCommand is the command that tunnels the SSH connection to the
+	// node, if the node is not reachable directly
+	SSHProxyCommand string
+	// Arch is the CPU architecture of the node, if different from amd64
+	Arch string
 }
 
 // ToINI converts the inventory into INI format
@@ -44,7 +49,14 @@ func (i Inventory) ToINI() []byte {
 			if n.InternalIP != "" {
 				internalIP = n.InternalIP
 			}
-			fmt.Fprintf(w, "%q ansible_host=%q internal_ipv4=%q ansible_ssh_private_key_file=%q ansible_port=%d ansible_user=%q\n", n.Host, n.PublicIP, internalIP, n.SSHPrivateKey, n.SSHPort, n.SSHUser)
+			fmt.Fprintf(w, "%q ansible_host=%q internal_ipv4=%q ansible_ssh_private_key_file=%q ansible_port=%d ansible_user=%q", n.Host, n.PublicIP, internalIP, n.SSHPrivateKey, n.SSHPort, n.SSHUser)
+			if n.SSHProxyCommand != "" {
+				fmt.Fprintf(w, " ansible_ssh_common_args=%q", fmt.Sprintf("-o ProxyCommand=%q", n.SSHProxyCommand))
+			}
+			if n.Arch != "" {
+				fmt.Fprintf(w, " node_arch=%q", n.Arch)
+			}
+			fmt.Fprintln(w)
 		}
 	}
 
@@ -48,6 +48,16 @@ func TestInventoryINIGeneration(t *testing.T) {
 						SSHPrivateKey: "id_rsa",
 						SSHPort:       2222,
 						SSHUser:       "alice and bob",
+						Arch:          "arm64",
+					},
+					{
+						Host:            "worker03",
+						PublicIP:        "10.0.0.5",
+						InternalIP:      "192.168.0.15",
+						SSHPrivateKey:   "id_rsa",
+						SSHPort:         2222,
+						SSHUser:         "alice",
+						SSHProxyCommand: "ssh -i 'id_rsa' -W %h:%p alice@bastion",
 					},
 				},
 			},
@@ -62,7 +72,8 @@ func TestInventoryINIGeneration(t *testing.T) {
 "master01" ansible_host="10.0.0.2" internal_ipv4="192.168.0.12" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice"
 [worker]
 "worker01" ansible_host="10.0.0.3" internal_ipv4="192.168.0.13" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice"
-"worker02" ansible_host="10.0.0.4" internal_ipv4="192.168.0.14" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice and bob"
+"worker02" ansible_host="10.0.0.4" internal_ipv4="192.168.0.14" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice and bob" node_arch="arm64"
+"worker03" ansible_host="10.0.0.5" internal_ipv4="192.168.0.15" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice" ansible_ssh_common_args="-o ProxyCommand=\"ssh -i 'id_rsa' -W %h:%p alice@bastion\""
 `
 
 	if ini != expected {
@@ -36,6 +36,13 @@ type Runner interface {
 	// against the specific node.
 	// It returns a read-only channel that must be consumed for the playbook execution to proceed.
 	StartPlaybookOnNode(playbookFile string, inventory Inventory, cc ClusterCatalog, node ...string) (<-chan Event, error)
+	// CancelPlaybook requests the cancellation of the running playbook. The
+	// playbook stops before starting its next play, so that no play is left
+	// half-way through.
+	CancelPlaybook() error
+	// KillPlaybook kills the processes of the running playbook immediately,
+	// which can leave the nodes half-way through a play.
+	KillPlaybook() error
 }
 
 type runner struct {
@@ -49,6 +56,7 @@ type runner struct {
 	runDir       string
 	waitPlaybook func() error
 	namedPipe    string
+	pid          int
 }
 
 // NewRunner returns a new runner for running Ansible playbooks.
@@ -94,6 +102,34 @@ func (r *runner) WaitPlaybook() error {
 	return nil
 }
 
+// CancelPlaybook creates the cancellation file that is checked by the
+// json_lines callback plugin at the start of each play.
+func (r *runner) CancelPlaybook() error {
+	if r.waitPlaybook == nil {
+		return fmt.Errorf("cancel called, but playbook not started")
+	}
+	if err := ioutil.WriteFile(r.cancelFile(), nil, 0644); err != nil {
+		return fmt.Errorf("error creating cancellation file: %v", err)
+	}
+	return nil
+}
+
+// KillPlaybook kills the process group of ansible, which runs in its own
+// process group and does not get the signals sent to kismatic.
+func (r *runner) KillPlaybook() error {
+	if r.waitPlaybook == nil {
+		return fmt.Errorf("kill called, but playbook not started")
+	}
+	if err := syscall.Kill(-r.pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("error killing ansible: %v", err)
+	}
+	return nil
+}
+
+func (r *runner) cancelFile() string {
+	return filepath.Join(r.runDir, "cancel")
+}
+
 // RunPlaybook with the given inventory and extra vars
 func (r *runner) StartPlaybook(playbookFile string, inv Inventory, cc ClusterCatalog) (<-chan Event, error) {
 	return r.startPlaybook(playbookFile, inv, cc) // Don't set the --limit arg
@@ -117,26 +153,25 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 	if err != nil {
 		return nil, fmt.Errorf("error writing cluster catalog data to yaml: %v", err)
 	}
-	clusterCatalogFile := filepath.Join(r.ansibleDir, "clustercatalog.yaml")
+	// The files are written to the directory of the run, so that the runs of
+	// a batch, that share the ansible directory, don't overwrite each other's
+	clusterCatalogFile := filepath.Join(r.runDir, "clustercatalog.yaml")
 	if err = ioutil.WriteFile(clusterCatalogFile, yamlBytes, 0644); err != nil {
 		return nil, fmt.Errorf("error writing cluster catalog file to %q: %v", clusterCatalogFile, err)
 	}
 
-	inventoryFile := filepath.Join(r.ansibleDir, "inventory.ini")
+	inventoryFile := filepath.Join(r.runDir, "inventory.ini")
 	if err := ioutil.WriteFile(inventoryFile, inv.ToINI(), 0644); err != nil {
 		return nil, fmt.Errorf("error writing inventory file to %q: %v", inventoryFile, err)
 	}
 
-	if err := copyFileContents(clusterCatalogFile, filepath.Join(r.runDir, "clustercatalog.yaml")); err != nil {
-		return nil, fmt.Errorf("error copying clustercatalog.yaml to %q: %v", r.runDir, err)
-	}
-	if err := copyFileContents(inventoryFile, filepath.Join(r.runDir, "inventory.ini")); err != nil {
-		return nil, fmt.Errorf("error copying inventory.ini to %q: %v", r.runDir, err)
-	}
-
 	cmd := exec.Command(filepath.Join(r.ansibleDir, "bin", "ansible-playbook"), "-i", inventoryFile, "-s", playbook, "--extra-vars", "@"+clusterCatalogFile)
 	cmd.Stdout = r.out
 	cmd.Stderr = r.errOut
+	// Run ansible in its own process group, so that an interrupt from the
+	// terminal reaches kismatic only, and the playbook can be cancelled at a
+	// play boundary instead.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	log.SetOutput(r.out)
 
@@ -156,18 +191,22 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 	}
 	r.namedPipe = np
 
-	os.Setenv("PYTHONPATH", r.pythonPath)
-	os.Setenv("ANSIBLE_CALLBACK_PLUGINS", filepath.Join(r.ansibleDir, "playbooks", "callback"))
-	os.Setenv("ANSIBLE_CALLBACK_WHITELIST", "json_lines")
-	os.Setenv("ANSIBLE_CONFIG", filepath.Join(r.ansibleDir, "playbooks", "ansible.cfg"))
-	os.Setenv("ANSIBLE_JSON_LINES_PIPE", r.namedPipe)
+	// The environment is set on the command instead of the process, as the
+	// runs of a batch run at the same time
+	env := []string{
+		"PYTHONPATH=" + r.pythonPath,
+		"ANSIBLE_CALLBACK_PLUGINS=" + filepath.Join(r.ansibleDir, "playbooks", "callback"),
+		"ANSIBLE_CALLBACK_WHITELIST=json_lines",
+		"ANSIBLE_CONFIG=" + filepath.Join(r.ansibleDir, "playbooks", "ansible.cfg"),
+		"ANSIBLE_JSON_LINES_PIPE=" + r.namedPipe,
+		"ANSIBLE_CANCEL_FILE=" + r.cancelFile(),
+	}
+	cmd.Env = append(os.Environ(), env...)
 
 	// Print Ansible command
-	fmt.Fprintf(r.out, "export PYTHONPATH=%v\n", os.Getenv("PYTHONPATH"))
-	fmt.Fprintf(r.out, "export ANSIBLE_CALLBACK_PLUGINS=%v\n", os.Getenv("ANSIBLE_CALLBACK_PLUGINS"))
-	fmt.Fprintf(r.out, "export ANSIBLE_CALLBACK_WHITELIST=%v\n", os.Getenv("ANSIBLE_CALLBACK_WHITELIST"))
-	fmt.Fprintf(r.out, "export ANSIBLE_CONFIG=%v\n", os.Getenv("ANSIBLE_CONFIG"))
-	fmt.Fprintf(r.out, "export ANSIBLE_JSON_LINES_PIPE=%v\n", os.Getenv("ANSIBLE_JSON_LINES_PIPE"))
+	for _, e := range env {
+		fmt.Fprintf(r.out, "export %s\n", e)
+	}
 	fmt.Fprintln(r.out, strings.Join(cmd.Args, " "))
 
 	// Starts async execution of ansible, which will block until
@@ -177,6 +216,7 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 		return nil, fmt.Errorf("error running playbook: %v", err)
 	}
 	r.waitPlaybook = cmd.Wait
+	r.pid = cmd.Process.Pid
 
 	// Create the event stream out of the named pipe
 	eventStreamFile, err := os.OpenFile(r.namedPipe, os.O_RDWR, os.ModeNamedPipe)
@@ -207,20 +247,3 @@ func getPythonPath() (string, error) {
 	lib64 := filepath.Join(wd, "ansible", "lib64", "python2.7", "site-packages")
 	return fmt.Sprintf("%s:%s", lib, lib64), nil
 }
-
-func copyFileContents(src, dst string) (err error) {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-	if _, err = io.Copy(out, in); err != nil {
-		return err
-	}
-	return out.Sync()
-}
@@ -0,0 +1,54 @@
+package ansible
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TestAnsibleYAML lints the playbooks, roles and variables, which are only
+// parsed by ansible when they run. Besides being valid YAML, the lines must be
+// indented by multiples of two spaces: a line that lost its indentation or its
+// comment marker is often still valid YAML, as it continues the scalar of the
+// previous line. Templates are rendered with jinja2 first, and are skipped.
+func TestAnsibleYAML(t *testing.T) {
+	err := filepath.Walk("../../ansible", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == "templates" {
+			return filepath.SkipDir
+		}
+		if ext := filepath.Ext(path); info.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+		d, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		if err := yaml.Unmarshal(d, &v); err != nil {
+			t.Errorf("%s is not valid YAML: %v", path, err)
+		}
+		s := bufio.NewScanner(bytes.NewReader(d))
+		for n := 1; s.Scan(); n++ {
+			line := s.Text()
+			if indent := len(line) - len(strings.TrimLeft(line, " ")); strings.TrimSpace(line) != "" && indent%2 != 0 {
+				t.Errorf("%s:%d is indented by %d spaces, expected a multiple of 2", path, n, indent)
+			}
+			if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+				t.Errorf("%s:%d is indented with tabs", path, n)
+			}
+		}
+		return s.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
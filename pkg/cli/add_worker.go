@@ -8,12 +8,14 @@ import (
 	"strings"
 
 	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/trace"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
 )
 
 type addWorkerOpts struct {
 	NodeLabels               []string
+	WorkerPool               string
 	GeneratedAssetsDirectory string
 	RestartServices          bool
 	OutputFormat             string
@@ -48,10 +50,14 @@ func NewCmdAddWorker(out io.Writer, installOpts *installOpts) *cobra.Command {
 					newWorker.Labels[pair[0]] = pair[1]
 				}
 			}
-			return doAddWorker(out, installOpts.planFilename, opts, newWorker)
+			tracer := trace.FromEnvironment("add-worker")
+			err := doAddWorker(out, installOpts.planFilename, installOpts.valuesFilename, opts, newWorker, tracer)
+			finishTrace(out, tracer, err)
+			return err
 		},
 	}
 	cmd.Flags().StringSliceVarP(&opts.NodeLabels, "labels", "l", []string{}, "key=value pairs separated by ','")
+	cmd.Flags().StringVar(&opts.WorkerPool, "pool", "", "name of the worker pool the node is added to, as defined in the plan file")
 	cmd.Flags().StringVar(&opts.GeneratedAssetsDirectory, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&opts.RestartServices, "restart-services", false, "force restart clusters services (Use with care)")
 	cmd.Flags().BoolVar(&opts.Verbose, "verbose", false, "enable verbose logging from the installation")
@@ -60,16 +66,26 @@ func NewCmdAddWorker(out io.Writer, installOpts *installOpts) *cobra.Command {
 	return cmd
 }
 
-func doAddWorker(out io.Writer, planFile string, opts *addWorkerOpts, newWorker install.Node) error {
-	planner := &install.FilePlanner{File: planFile}
+func doAddWorker(out io.Writer, planFile, valuesFile string, opts *addWorkerOpts, newWorker install.Node, tracer *trace.Tracer) (err error) {
+	planner := &install.FilePlanner{File: planFile, ValuesFile: valuesFile}
 	if !planner.PlanExists() {
 		return planFileNotFoundErr{filename: planFile}
 	}
+	// the updated plan is written back to the plan file, which would replace
+	// the variable references with their values
+	isTemplate, err := planner.IsTemplate()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	if isTemplate {
+		return fmt.Errorf("plan file %q references variables: add the new node to the plan file, and run \"kismatic install apply\" instead", planFile)
+	}
 	execOpts := install.ExecutorOptions{
 		GeneratedAssetsDirectory: opts.GeneratedAssetsDirectory,
 		RestartServices:          opts.RestartServices,
 		OutputFormat:             opts.OutputFormat,
 		Verbose:                  opts.Verbose,
+		Tracer:                   tracer,
 	}
 	executor, err := install.NewExecutor(out, os.Stderr, execOpts)
 	if err != nil {
@@ -83,7 +99,7 @@ func doAddWorker(out io.Writer, planFile string, opts *addWorkerOpts, newWorker
 		util.PrintValidationErrors(out, errs)
 		return errors.New("information provided about the new worker node is invalid")
 	}
-	if _, errs := install.ValidatePlan(plan); errs != nil {
+	if _, errs := install.ValidateExistingPlan(plan); errs != nil {
 		util.PrintValidationErrors(out, errs)
 		return errors.New("the plan file failed validation")
 	}
@@ -98,13 +114,25 @@ func doAddWorker(out io.Writer, planFile string, opts *addWorkerOpts, newWorker
 	if err = ensureNodeIsNew(*plan, newWorker); err != nil {
 		return err
 	}
+	if opts.WorkerPool != "" && !hasWorkerPool(*plan, opts.WorkerPool) {
+		return fmt.Errorf("worker pool %q is not defined in the plan file", opts.WorkerPool)
+	}
 	if !opts.SkipPreFlight {
 		util.PrintHeader(out, "Running Pre-Flight Checks On New Worker", '=')
 		if err = executor.RunNewWorkerPreFlightCheck(*plan, newWorker); err != nil {
 			return err
 		}
 	}
-	updatedPlan, err := executor.AddWorker(plan, newWorker)
+	release, err := fetchAssets(out, plan, opts.GeneratedAssetsDirectory, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	updatedPlan, err := executor.AddWorker(plan, newWorker, opts.WorkerPool)
 	if err != nil {
 		return err
 	}
@@ -117,7 +145,11 @@ func doAddWorker(out io.Writer, planFile string, opts *addWorkerOpts, newWorker
 // returns an error if the plan contains a worker that is "equivalent"
 // to the new worker that is being added
 func ensureNodeIsNew(plan install.Plan, newWorker install.Node) error {
-	for _, n := range plan.Worker.Nodes {
+	workers := append([]install.Node{}, plan.Worker.Nodes...)
+	for _, pool := range plan.WorkerPools {
+		workers = append(workers, pool.Nodes...)
+	}
+	for _, n := range workers {
 		if n.Host == newWorker.Host {
 			return fmt.Errorf("according to the plan file, the host name of the new node is already being used by another worker node")
 		}
@@ -130,3 +162,12 @@ func ensureNodeIsNew(plan install.Plan, newWorker install.Node) error {
 	}
 	return nil
 }
+
+func hasWorkerPool(plan install.Plan, name string) bool {
+	for _, pool := range plan.WorkerPools {
+		if pool.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/trace"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
 )
@@ -16,17 +17,22 @@ type applyCmd struct {
 	executor           install.Executor
 	planFile           string
 	generatedAssetsDir string
+	runsDir            string
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
+	acceptWarnings     bool
+	tracer             *trace.Tracer
 }
 
 type applyOpts struct {
 	generatedAssetsDir string
+	runsDir            string
 	restartServices    bool
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
+	acceptWarnings     bool
 }
 
 // NewCmdApply creates a cluter using the plan file
@@ -39,12 +45,14 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: installOpts.planFilename}
+			planner := &install.FilePlanner{File: installOpts.planFilename, ValuesFile: installOpts.valuesFilename}
+			tracer := trace.FromEnvironment("apply")
 			executorOpts := install.ExecutorOptions{
 				GeneratedAssetsDirectory: applyOpts.generatedAssetsDir,
 				RestartServices:          applyOpts.restartServices,
 				OutputFormat:             applyOpts.outputFormat,
 				Verbose:                  applyOpts.verbose,
+				Tracer:                   tracer,
 			}
 			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
 			if err != nil {
@@ -60,8 +68,12 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 				verbose:            applyOpts.verbose,
 				outputFormat:       applyOpts.outputFormat,
 				skipPreFlight:      applyOpts.skipPreFlight,
+				acceptWarnings:     applyOpts.acceptWarnings,
+				tracer:             tracer,
 			}
-			return applyCmd.run()
+			err = applyCmd.run()
+			finishTrace(out, tracer, err)
+			return err
 		},
 	}
 
@@ -71,11 +83,12 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 	cmd.Flags().BoolVar(&applyOpts.verbose, "verbose", false, "enable verbose logging from the installation")
 	cmd.Flags().StringVarP(&applyOpts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
 	cmd.Flags().BoolVar(&applyOpts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
+	addAcceptWarningsFlag(cmd.Flags(), &applyOpts.acceptWarnings)
 
 	return cmd
 }
 
-func (c *applyCmd) run() error {
+func (c *applyCmd) run() (err error) {
 	// Validate and run pre-flight
 	opts := &validateOpts{
 		planFile:           c.planFile,
@@ -83,24 +96,44 @@ func (c *applyCmd) run() error {
 		outputFormat:       c.outputFormat,
 		skipPreFlight:      c.skipPreFlight,
 		generatedAssetsDir: c.generatedAssetsDir,
+		failOnWarnings:     !c.acceptWarnings,
 	}
-	err := doValidate(c.out, c.planner, opts)
+	span := c.tracer.Start("validate")
+	err = doValidate(c.out, c.planner, opts)
+	span.End(err)
 	if err != nil {
-		return fmt.Errorf("error validating plan: %v", err)
+		return stepErr{step: "validating plan", err: err}
 	}
 	plan, err := c.planner.Read()
 	if err != nil {
 		return fmt.Errorf("error reading plan file: %v", err)
 	}
 
+	// Fetch the existing assets, and put them back in the assets storage and
+	// the secrets store when done, even if the installation fails
+	release, err := fetchAssets(c.out, plan, c.generatedAssetsDir, c.runsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+
 	// Generate certificates
-	if err := c.executor.GenerateCertificates(plan, false); err != nil {
+	span = c.tracer.Start("certificates")
+	err = c.executor.GenerateCertificates(plan, false)
+	span.End(err)
+	if err != nil {
 		return fmt.Errorf("error installing: %v", err)
 	}
 
 	// Generate kubeconfig
 	util.PrintHeader(c.out, "Generating Kubeconfig File", '=')
+	span = c.tracer.Start("kubeconfig")
 	err = install.GenerateKubeconfig(plan, c.generatedAssetsDir)
+	span.End(err)
 	if err != nil {
 		return fmt.Errorf("error generating kubeconfig file: %v", err)
 	}
@@ -111,6 +144,17 @@ func (c *applyCmd) run() error {
 		return fmt.Errorf("error installing: %v", err)
 	}
 
+	// Generate compliance report
+	span = c.tracer.Start("compliance-report")
+	reportFile, err := install.GenerateComplianceReport(plan, c.generatedAssetsDir)
+	span.End(err)
+	if err != nil {
+		return fmt.Errorf("error generating compliance report: %v", err)
+	}
+	if reportFile != "" {
+		util.PrettyPrintOk(c.out, "Generated compliance report %q", reportFile)
+	}
+
 	// Run smoketest
 	// Don't run
 	if plan.NetworkConfigured() {
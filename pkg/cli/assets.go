@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// defaultRunsDir is where the executor keeps the logs of the runs, unless
+// told otherwise
+const defaultRunsDir = "runs"
+
+// fetchAssets downloads the generated assets and the runs of the cluster from
+// its assets storage, and fetches the private keys and the kubeconfig from its
+// secrets store. The returned function puts them back, and must be called
+// when done. The default runs directory is used if runsDir is empty.
+func fetchAssets(out io.Writer, plan *install.Plan, generatedAssetsDir, runsDir string) (func() error, error) {
+	if runsDir == "" {
+		runsDir = defaultRunsDir
+	}
+	if err := install.DownloadAssets(plan, generatedAssetsDir, runsDir); err != nil {
+		util.PrettyPrintErr(out, "Downloading assets from %s", plan.Cluster.AssetsStorage.Location)
+		return nil, externalServiceErr{fmt.Errorf("error downloading assets: %v", err)}
+	}
+	if plan.Cluster.AssetsStorage.Location != "" {
+		util.PrettyPrintOk(out, "Downloaded assets from %s", plan.Cluster.AssetsStorage.Location)
+	}
+	if err := fetchSecretAssets(out, plan, generatedAssetsDir); err != nil {
+		return nil, err
+	}
+	return func() error {
+		// the secrets are removed from the directory before it is uploaded
+		if err := storeSecretAssets(out, plan, generatedAssetsDir); err != nil {
+			return err
+		}
+		if err := install.UploadAssets(plan, generatedAssetsDir, runsDir); err != nil {
+			util.PrettyPrintErr(out, "Uploading assets to %s", plan.Cluster.AssetsStorage.Location)
+			return externalServiceErr{fmt.Errorf("error uploading assets: %v", err)}
+		}
+		if plan.Cluster.AssetsStorage.Location != "" {
+			util.PrettyPrintOk(out, "Uploaded assets to %s", plan.Cluster.AssetsStorage.Location)
+		}
+		return nil
+	}, nil
+}
+
+// fetchSecretAssets fetches the private keys and the kubeconfig of the
+// cluster from its secrets store into the generated assets directory
+func fetchSecretAssets(out io.Writer, plan *install.Plan, generatedAssetsDir string) error {
+	n, err := install.FetchSecretAssets(plan, generatedAssetsDir)
+	if err != nil {
+		util.PrettyPrintErr(out, "Fetching assets from the secrets store")
+		return externalServiceErr{fmt.Errorf("error fetching assets from the secrets store: %v", err)}
+	}
+	if n > 0 {
+		util.PrettyPrintOk(out, "Fetched %d assets from the %s secrets store", n, plan.Cluster.SecretsStore.Provider)
+	}
+	return nil
+}
+
+// storeSecretAssets moves the private keys and the kubeconfig of the cluster
+// from the generated assets directory to its secrets store
+func storeSecretAssets(out io.Writer, plan *install.Plan, generatedAssetsDir string) error {
+	n, err := install.StoreSecretAssets(plan, generatedAssetsDir)
+	if err != nil {
+		util.PrettyPrintErr(out, "Storing assets in the secrets store")
+		return externalServiceErr{fmt.Errorf("error storing assets in the secrets store: %v", err)}
+	}
+	if n > 0 {
+		util.PrettyPrintOk(out, "Moved %d assets to the %s secrets store", n, plan.Cluster.SecretsStore.Provider)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+type auditOpts struct {
+	planFilename string
+	user         string
+	verb         string
+	path         string
+	since        time.Duration
+	failedOnly   bool
+	lines        int
+	outputFormat string
+}
+
+// NewCmdAudit returns the command for querying the API server audit log
+func NewCmdAudit(out io.Writer) *cobra.Command {
+	opts := &auditOpts{}
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "query the audit log of the Kubernetes API server",
+		Long: `Query the audit log of the Kubernetes API server.
+
+The audit log is read from every master node over SSH, and requires the audit log of the cluster to be enabled with the file sink.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			planner := &install.FilePlanner{File: opts.planFilename}
+			return doAudit(out, planner, opts)
+		},
+	}
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	cmd.Flags().StringVar(&opts.user, "user", "", "only show the requests made by this user")
+	cmd.Flags().StringVar(&opts.verb, "verb", "", "only show the requests with this verb, such as get, create or delete")
+	cmd.Flags().StringVar(&opts.path, "path", "", "only show the requests whose path starts with this prefix, such as /api/v1/namespaces/default")
+	cmd.Flags().DurationVar(&opts.since, "since", time.Hour, "only show the requests made within this duration. 0 shows all requests")
+	cmd.Flags().BoolVar(&opts.failedOnly, "failed", false, "only show the requests that failed")
+	cmd.Flags().IntVar(&opts.lines, "lines", 10000, "number of the most recent lines of the audit log that are read on each master node")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"json")`)
+	return cmd
+}
+
+func doAudit(out io.Writer, planner install.Planner, opts *auditOpts) error {
+	if opts.outputFormat != "simple" && opts.outputFormat != "json" {
+		return fmt.Errorf("output format %q is not supported", opts.outputFormat)
+	}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	if !plan.Cluster.Audit.Enabled {
+		return errors.New("the audit log is not enabled in the plan file")
+	}
+	if plan.Cluster.Audit.Sink != "" && plan.Cluster.Audit.Sink != "file" {
+		return fmt.Errorf("the audit events are sent to a %s, and can only be queried there", plan.Cluster.Audit.Sink)
+	}
+
+	filter := install.AuditFilter{
+		User:       opts.user,
+		Verb:       opts.verb,
+		Path:       opts.path,
+		FailedOnly: opts.failedOnly,
+	}
+	if opts.since > 0 {
+		filter.Since = time.Now().Add(-opts.since)
+	}
+	var events []install.AuditEvent
+	for _, master := range plan.Master.Nodes {
+		client, err := plan.GetSSHClient(master.Host)
+		if err != nil {
+			return err
+		}
+		output, err := client.Output(true, fmt.Sprintf("sudo tail -n %d %s", opts.lines, install.AuditLogPath))
+		if err != nil {
+			return fmt.Errorf("error reading the audit log of node %q: %s", master.Host, output)
+		}
+		nodeEvents, err := install.ReadAuditEvents(strings.NewReader(output), master.Host, filter)
+		if err != nil {
+			return fmt.Errorf("error reading the audit log of node %q: %v", master.Host, err)
+		}
+		events = append(events, nodeEvents...)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StageTimestamp.Before(events[j].StageTimestamp)
+	})
+
+	if opts.outputFormat == "json" {
+		b, err := json.MarshalIndent(events, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling audit events: %v", err)
+		}
+		fmt.Fprintln(out, string(b))
+		return nil
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(out, "No requests matched the query")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tMASTER\tUSER\tVERB\tPATH\tCODE\tLATENCY")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%v\n", e.StageTimestamp.Local().Format(time.RFC3339), e.Host, e.User.Username, e.Verb, e.RequestURI, e.Code(), e.Latency())
+	}
+	return w.Flush()
+}
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/gitops"
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type batchOpts struct {
+	dir                string
+	selector           []string
+	parallel           int
+	batchDir           string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+	skipPreFlight      bool
+	acceptWarnings     bool
+	online             bool
+}
+
+// batchOperation runs the operation on the cluster of the plan file, and
+// writes its output to out
+type batchOperation func(out io.Writer, cluster, planFile string) error
+
+// batchResult is the result of the operation on one of the clusters of a batch
+type batchResult struct {
+	Cluster  string    `json:"cluster"`
+	PlanFile string    `json:"planFile"`
+	Result   string    `json:"result"`
+	Class    string    `json:"class,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Log is the file where the output of the operation was written
+	Log string `json:"log"`
+}
+
+// batchReport is the combined report of the operation on all the clusters
+type batchReport struct {
+	Operation string        `json:"operation"`
+	Selector  []string      `json:"selector,omitempty"`
+	Started   time.Time     `json:"started"`
+	Finished  time.Time     `json:"finished"`
+	Clusters  []batchResult `json:"clusters"`
+}
+
+// NewCmdBatch returns the command for running an operation on a group of clusters
+func NewCmdBatch(out io.Writer) *cobra.Command {
+	opts := &batchOpts{}
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "run an operation on the clusters of a fleet that match a selector",
+		Long: `Run an operation on the clusters of a fleet that match a selector, such as upgrading all the clusters with the label env=dev.
+
+Each plan file in the --dir directory describes a cluster, named after the file, as with the gitops command.
+The clusters whose cluster.labels match all the key=value pairs of --selector are selected, and the operation
+runs on up to --parallel clusters at a time. The output of each cluster is written to its own log file, and the
+combined report is printed and written to report.json, in a directory of the batch under --batch-dir.
+
+The generated assets of each cluster are stored in a directory named after the cluster, under
+--generated-assets-dir, and its runs are recorded in a directory named after the cluster, under runs.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.PersistentFlags().StringVar(&opts.dir, "dir", ".", "path to the directory of the plan files of the clusters")
+	cmd.PersistentFlags().StringSliceVarP(&opts.selector, "selector", "l", []string{}, "key=value pairs separated by ',' that the labels of the clusters must match")
+	cmd.PersistentFlags().IntVar(&opts.parallel, "parallel", 1, "the maximum number of clusters the operation runs on at a time")
+	cmd.PersistentFlags().StringVar(&opts.batchDir, "batch-dir", "batch", "path to the directory where the logs and the report of each batch are written")
+	cmd.PersistentFlags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.PersistentFlags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.PersistentFlags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format of the logs (options \"simple\"|\"raw\")")
+	cmd.PersistentFlags().BoolVar(&opts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks")
+
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "apply the plan files of the selected clusters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doBatch(out, "apply", opts, func(out io.Writer, cluster, planFile string) error {
+				return applyPlanFile(out, planFile, applyOpts{
+					generatedAssetsDir: filepath.Join(opts.generatedAssetsDir, cluster),
+					runsDir:            filepath.Join(defaultRunsDir, cluster),
+					verbose:            opts.verbose,
+					outputFormat:       opts.outputFormat,
+					skipPreFlight:      opts.skipPreFlight,
+					acceptWarnings:     opts.acceptWarnings,
+				})
+			})
+		},
+	}
+	addAcceptWarningsFlag(applyCmd.Flags(), &opts.acceptWarnings)
+	cmd.AddCommand(applyCmd)
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "upgrade the selected clusters",
+		Long: `Upgrade the selected clusters, one node at a time, as with the upgrade offline command.
+
+With --online, the upgrade runs the safety and availability checks, and drains the nodes before upgrading them,
+as with the upgrade online command. A cluster that fails the checks is not upgraded, and is reported as failed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doBatch(out, "upgrade", opts, func(out io.Writer, cluster, planFile string) error {
+				upgradeOpts := &upgradeOpts{
+					generatedAssetsDir: filepath.Join(opts.generatedAssetsDir, cluster),
+					runsDir:            filepath.Join(defaultRunsDir, cluster),
+					verbose:            opts.verbose,
+					outputFormat:       opts.outputFormat,
+					skipPreflight:      opts.skipPreFlight,
+					online:             opts.online,
+					planFile:           planFile,
+					maxParallelWorkers: 1,
+				}
+				// Nobody answers the prompts of a batch, so the upgrade does
+				// not continue when it asks
+				return upgrade(strings.NewReader(""), out, upgradeOpts, nil)
+			})
+		},
+	}
+	upgradeCmd.Flags().BoolVar(&opts.online, "online", false, "perform an online upgrade of the clusters")
+	cmd.AddCommand(upgradeCmd)
+	return cmd
+}
+
+func doBatch(out io.Writer, operation string, opts *batchOpts, op batchOperation) error {
+	if opts.parallel < 1 {
+		return fmt.Errorf("parallel must be greater or equal to 1, got: %d", opts.parallel)
+	}
+	selector, err := parseSelector(opts.selector)
+	if err != nil {
+		return err
+	}
+	files, err := gitops.PlanFiles(opts.dir)
+	if err != nil {
+		return err
+	}
+	clusters, err := selectClusters(files, selector)
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return fmt.Errorf("no plan files in %q match the selector %v", opts.dir, opts.selector)
+	}
+
+	report := batchReport{Operation: operation, Selector: opts.selector, Started: time.Now()}
+	dir := filepath.Join(opts.batchDir, fmt.Sprintf("%s-%s", operation, report.Started.Format("2006-01-02-15-04-05")))
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating batch directory: %v", err)
+	}
+	util.PrintHeader(out, fmt.Sprintf("Running %s on %d clusters, %d at a time", operation, len(clusters), opts.parallel), '=')
+	var outLock sync.Mutex
+	report.Clusters = runBatch(clusters, opts.parallel, func(cluster string) batchResult {
+		r := batchResult{Cluster: cluster, PlanFile: files[cluster], Log: filepath.Join(dir, cluster+".log"), Started: time.Now()}
+		outLock.Lock()
+		fmt.Fprintf(out, "Started %s of cluster %q, logging to %q\n", operation, cluster, r.Log)
+		outLock.Unlock()
+		err := runBatchOperation(op, r.Log, cluster, r.PlanFile)
+		r.Finished = time.Now()
+		outLock.Lock()
+		defer outLock.Unlock()
+		if err != nil {
+			r.Result = install.RunResultFailed
+			r.Class = classifyApplyErr(err).(gitops.ClassifiedError).Class
+			r.Error = util.Redact(err.Error())
+			util.PrettyPrintErr(out, "%s of cluster %q (%s): %s", operation, cluster, r.Class, r.Error)
+			return r
+		}
+		r.Result = install.RunResultSucceeded
+		util.PrettyPrintOk(out, "%s of cluster %q", operation, cluster)
+		return r
+	})
+	report.Finished = time.Now()
+
+	reportFile := filepath.Join(dir, "report.json")
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling batch report: %v", err)
+	}
+	if err = ioutil.WriteFile(reportFile, b, 0600); err != nil {
+		return fmt.Errorf("error writing batch report: %v", err)
+	}
+	util.PrintHeader(out, "Batch Report", '=')
+	if err = printBatchReport(out, report); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nThe report was written to %q\n", reportFile)
+
+	failed := 0
+	for _, r := range report.Clusters {
+		if r.Result == install.RunResultFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed on %d of %d clusters", operation, failed, len(report.Clusters))
+	}
+	return nil
+}
+
+// runBatchOperation runs the operation on the cluster, writing its output to
+// the log file
+func runBatchOperation(op batchOperation, logFile, cluster, planFile string) error {
+	f, err := os.Create(logFile)
+	if err != nil {
+		return fmt.Errorf("error creating log file %q: %v", logFile, err)
+	}
+	defer f.Close()
+	return op(f, cluster, planFile)
+}
+
+// runBatch runs the function on the clusters, on up to parallel clusters at
+// a time, and returns the results in the order of the clusters
+func runBatch(clusters []string, parallel int, run func(cluster string) batchResult) []batchResult {
+	results := make([]batchResult, len(clusters))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, c string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = run(c)
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// parseSelector returns the labels of the key=value pairs
+func parseSelector(pairs []string) (map[string]string, error) {
+	selector := map[string]string{}
+	for _, l := range pairs {
+		pair := strings.Split(l, "=")
+		if len(pair) != 2 || pair[0] == "" {
+			return nil, fmt.Errorf("invalid selector %q provided, must be key=value pair", l)
+		}
+		selector[pair[0]] = pair[1]
+	}
+	return selector, nil
+}
+
+// selectClusters returns the names of the clusters whose labels match all
+// the labels of the selector, sorted by name
+func selectClusters(files map[string]string, selector map[string]string) ([]string, error) {
+	var selected []string
+	for cluster, file := range files {
+		fp := install.FilePlanner{File: file}
+		plan, err := fp.Read()
+		if err != nil {
+			return nil, fmt.Errorf("error reading plan file of cluster %q: %v", cluster, err)
+		}
+		if matchesSelector(plan.Cluster.Labels, selector) {
+			selected = append(selected, cluster)
+		}
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+func matchesSelector(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if val, ok := labels[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+func printBatchReport(out io.Writer, report batchReport) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tRESULT\tDURATION\tLOG")
+	for _, r := range report.Clusters {
+		result := r.Result
+		if r.Class != "" {
+			result = fmt.Sprintf("%s (%s)", r.Result, r.Class)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Cluster, result, r.Finished.Sub(r.Started)/time.Second*time.Second, r.Log)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		pairs    []string
+		expected map[string]string
+		valid    bool
+	}{
+		{
+			pairs:    []string{},
+			expected: map[string]string{},
+			valid:    true,
+		},
+		{
+			pairs:    []string{"env=dev", "team=payments"},
+			expected: map[string]string{"env": "dev", "team": "payments"},
+			valid:    true,
+		},
+		{
+			pairs:    []string{"env=dev", "team"},
+			expected: nil,
+			valid:    false,
+		},
+		{
+			pairs:    []string{"=dev"},
+			expected: nil,
+			valid:    false,
+		},
+	}
+	for i, test := range tests {
+		selector, err := parseSelector(test.pairs)
+		if (err == nil) != test.valid {
+			t.Errorf("test %d: expected valid to be %v, but got error %v", i, test.valid, err)
+		}
+		if !reflect.DeepEqual(selector, test.expected) {
+			t.Errorf("test %d: expected %v, but got %v", i, test.expected, selector)
+		}
+	}
+}
+
+func TestMatchesSelector(t *testing.T) {
+	tests := []struct {
+		labels   map[string]string
+		selector map[string]string
+		matches  bool
+	}{
+		{
+			labels:   nil,
+			selector: map[string]string{},
+			matches:  true,
+		},
+		{
+			labels:   map[string]string{"env": "dev", "team": "payments"},
+			selector: map[string]string{"env": "dev"},
+			matches:  true,
+		},
+		{
+			labels:   map[string]string{"env": "prod"},
+			selector: map[string]string{"env": "dev"},
+			matches:  false,
+		},
+		{
+			labels:   map[string]string{"env": "dev"},
+			selector: map[string]string{"env": "dev", "team": "payments"},
+			matches:  false,
+		},
+	}
+	for i, test := range tests {
+		if ok := matchesSelector(test.labels, test.selector); ok != test.matches {
+			t.Errorf("test %d: expected %v, but got %v", i, test.matches, ok)
+		}
+	}
+}
+
+func TestRunBatchLimitsConcurrency(t *testing.T) {
+	clusters := []string{"a", "b", "c", "d", "e"}
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	results := runBatch(clusters, 2, func(cluster string) batchResult {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return batchResult{Cluster: cluster}
+	})
+	if maxRunning != 2 {
+		t.Errorf("expected 2 clusters to run at a time, but got %d", maxRunning)
+	}
+	for i, r := range results {
+		if r.Cluster != clusters[i] {
+			t.Errorf("expected result %d to be of cluster %q, but got %q", i, clusters[i], r.Cluster)
+		}
+	}
+}
+
+func TestDoBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kismatic-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	plans := filepath.Join(dir, "clusters")
+	if err = os.Mkdir(plans, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, labels := range map[string]map[string]string{
+		"dev1": {"env": "dev"},
+		"dev2": {"env": "dev"},
+		"prod": {"env": "prod"},
+	} {
+		fp := install.FilePlanner{File: filepath.Join(plans, name+".yaml")}
+		if err = fp.Write(&install.Plan{Cluster: install.Cluster{Name: name, Labels: labels}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := &batchOpts{dir: plans, selector: []string{"env=dev"}, parallel: 2, batchDir: filepath.Join(dir, "batch")}
+	var lock sync.Mutex
+	var ran []string
+	op := func(out io.Writer, cluster, planFile string) error {
+		lock.Lock()
+		ran = append(ran, cluster)
+		lock.Unlock()
+		fmt.Fprintf(out, "applying %s", cluster)
+		if cluster == "dev2" {
+			return errors.New("error running playbook")
+		}
+		return nil
+	}
+	err = doBatch(ioutil.Discard, "apply", opts, op)
+	if err == nil || err.Error() != "apply failed on 1 of 2 clusters" {
+		t.Errorf("expected the batch to fail on 1 of 2 clusters, but got %v", err)
+	}
+	if len(ran) != 2 {
+		t.Errorf("expected the operation to run on the 2 dev clusters, but ran on %v", ran)
+	}
+	reports, err := filepath.Glob(filepath.Join(opts.batchDir, "apply-*", "report.json"))
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("expected a batch report, but found %v (%v)", reports, err)
+	}
+	log, err := ioutil.ReadFile(filepath.Join(filepath.Dir(reports[0]), "dev1.log"))
+	if err != nil || string(log) != "applying dev1" {
+		t.Errorf("expected the output of the cluster in its log, but got %q (%v)", log, err)
+	}
+}
@@ -17,6 +17,8 @@ func NewCmdCertificates(out io.Writer) *cobra.Command {
 	}
 
 	cmd.AddCommand(NewCmdGenerate(out))
+	cmd.AddCommand(NewCmdKubeconfig(out))
+	cmd.AddCommand(NewCmdCertificatesStatus(out))
 
 	return cmd
 }
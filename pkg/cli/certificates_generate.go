@@ -17,6 +17,7 @@ type certificatesGenerateOpts struct {
 	organizations      []string
 	overwrite          bool
 	generatedAssetsDir string
+	planFilename       string
 }
 
 // NewCmdGenerate creates a new certificates generate command
@@ -52,11 +53,30 @@ func NewCmdGenerate(out io.Writer) *cobra.Command {
 	cmd.Flags().StringSliceVar(&opts.organizations, "organizations", []string{}, "comma-separated list of names that should be included in the certificate's organization field.")
 	cmd.Flags().BoolVar(&opts.overwrite, "overwrite", false, "overwrite existing certificate if it already exists in the target directory.")
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
 
 	return cmd
 }
 
-func doCertificatesGenerate(name string, opts *certificatesGenerateOpts, out io.Writer) error {
+func doCertificatesGenerate(name string, opts *certificatesGenerateOpts, out io.Writer) (err error) {
+	// the CA is fetched from the assets storage and the secrets store of the
+	// cluster of the plan file, if there is one
+	planner := &install.FilePlanner{File: opts.planFilename}
+	if planner.PlanExists() {
+		var plan *install.Plan
+		if plan, err = planner.Read(); err != nil {
+			return fmt.Errorf("error reading plan file %q: %v", opts.planFilename, err)
+		}
+		var release func() error
+		if release, err = fetchAssets(out, plan, opts.generatedAssetsDir, defaultRunsDir); err != nil {
+			return err
+		}
+		defer func() {
+			if releaseErr := release(); releaseErr != nil && err == nil {
+				err = releaseErr
+			}
+		}()
+	}
 	ansibleDir := "ansible"
 	certsDir := filepath.Join(opts.generatedAssetsDir, "keys")
 	pki := &install.LocalPKI{
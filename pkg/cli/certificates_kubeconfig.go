@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type certificatesKubeconfigOpts struct {
+	planFilename       string
+	user               string
+	groups             []string
+	ttl                time.Duration
+	outputFile         string
+	generatedAssetsDir string
+}
+
+// NewCmdKubeconfig creates a new certificates kubeconfig command
+func NewCmdKubeconfig(out io.Writer) *cobra.Command {
+	opts := &certificatesKubeconfigOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "kubeconfig [options]",
+		Short: "Generate a kubeconfig with a short-lived client certificate, expects 'ca.pem' and 'ca-key.pem' to be in the --generated-assets-dir",
+		Long: `Generate a kubeconfig with a new client certificate that is signed by the cluster CA and expires after --ttl.
+
+The client certificate is not stored in the --generated-assets-dir, so a leaked kubeconfig can only be used until it expires.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			if opts.ttl <= 0 {
+				cmd.Help()
+				return fmt.Errorf("--ttl must be greater than 0")
+			}
+			if opts.user == "" {
+				cmd.Help()
+				return fmt.Errorf("--user cannot be empty")
+			}
+			return doCertificatesKubeconfig(out, os.Stderr, opts)
+		},
+	}
+
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	cmd.Flags().StringVar(&opts.user, "user", "admin", "the user of the client certificate")
+	cmd.Flags().StringSliceVar(&opts.groups, "groups", []string{"system:masters"}, "comma-separated list of the groups of the user")
+	cmd.Flags().DurationVar(&opts.ttl, "ttl", 8*time.Hour, "the length of time that the client certificate is valid for")
+	cmd.Flags().StringVarP(&opts.outputFile, "output", "o", "", "path to the file where the kubeconfig is written. If left blank, the kubeconfig is written to stdout")
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+
+	return cmd
+}
+
+// doCertificatesKubeconfig writes the kubeconfig to the output file, or to out
+// if there is none. In that case, the status is written to stderr, so that out
+// only contains the kubeconfig.
+func doCertificatesKubeconfig(out, stderr io.Writer, opts *certificatesKubeconfigOpts) (err error) {
+	status := out
+	if opts.outputFile == "" {
+		status = stderr
+	}
+	planner := &install.FilePlanner{File: opts.planFilename}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file %q: %v", opts.planFilename, err)
+	}
+	// the CA may be kept in the assets storage and the secrets store
+	release, err := fetchAssets(status, plan, opts.generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	pki := &install.LocalPKI{
+		GeneratedCertsDirectory: filepath.Join(opts.generatedAssetsDir, "keys"),
+		Log:                     status,
+	}
+	ca, err := pki.GetClusterCA()
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := install.MintKubeconfig(plan, ca, opts.user, opts.groups, opts.ttl)
+	if err != nil {
+		return err
+	}
+	if opts.outputFile == "" {
+		_, err = out.Write(kubeconfig)
+		return err
+	}
+	// the kubeconfig contains a private key
+	if err := ioutil.WriteFile(opts.outputFile, kubeconfig, 0600); err != nil {
+		return fmt.Errorf("error writing kubeconfig file: %v", err)
+	}
+	util.PrettyPrintOk(out, "Kubeconfig for user %q written to %q, the client certificate expires in %v", opts.user, opts.outputFile, opts.ttl)
+	return nil
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+// fakeVault is a vault command line tool that keeps the secrets in the
+// directory of the FAKE_VAULT_DIR environment variable
+const fakeVault = `#!/bin/sh
+file="$FAKE_VAULT_DIR/$(echo "$@" | tr ' /=' '___')"
+case "$2" in
+put) cat > "$FAKE_VAULT_DIR/kv_get_-field_value_$(echo "$3" | tr '/' '_')" ;;
+get) [ -f "$file" ] && cat "$file" || { echo "No value found" >&2; exit 2; } ;;
+esac
+`
+
+func TestCertificatesKubeconfigStdout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	binDir := filepath.Join(dir, "bin")
+	vaultDir := filepath.Join(dir, "vault")
+	generatedDir := filepath.Join(dir, "generated")
+	for _, d := range []string{binDir, vaultDir, filepath.Join(generatedDir, "keys")} {
+		if err = os.MkdirAll(d, 0700); err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+	}
+	if err = ioutil.WriteFile(filepath.Join(binDir, "vault"), []byte(fakeVault), 0700); err != nil {
+		t.Fatalf("error writing fake vault: %v", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	defer os.Unsetenv("FAKE_VAULT_DIR")
+	os.Setenv("FAKE_VAULT_DIR", vaultDir)
+
+	planFile := filepath.Join(dir, "kismatic-cluster.yaml")
+	plan := `cluster:
+  name: kubeconfig-test
+  secrets_store:
+    provider: vault
+    path: secret/kismatic
+master:
+  load_balanced_fqdn: master.example.com
+`
+	if err = ioutil.WriteFile(planFile, []byte(plan), 0600); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	// the CA key is moved to the secrets store when the command is done
+	pki := &install.LocalPKI{
+		CACsr:                   "../install/test/ca-csr.json",
+		GeneratedCertsDirectory: filepath.Join(generatedDir, "keys"),
+		Log:                     ioutil.Discard,
+	}
+	if _, err = pki.GenerateClusterCA(&install.Plan{Cluster: install.Cluster{Name: "kubeconfig-test", Certificates: install.CertsConfig{CAExpiry: "17520h"}}}); err != nil {
+		t.Fatalf("error generating CA: %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	opts := &certificatesKubeconfigOpts{
+		planFilename:       planFile,
+		user:               "admin",
+		groups:             []string{"system:masters"},
+		ttl:                time.Hour,
+		generatedAssetsDir: generatedDir,
+	}
+	if err = doCertificatesKubeconfig(stdout, stderr, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(stdout.String(), "apiVersion: v1") {
+		t.Errorf("expected stdout to only contain the kubeconfig, but got:\n%s", stdout.String())
+	}
+	if strings.Contains(stdout.String(), "secrets store") {
+		t.Errorf("expected the status to be written to stderr, but stdout was:\n%s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "Moved 1 assets to the vault secrets store") {
+		t.Errorf("expected the status to be written to stderr, but got:\n%s", stderr.String())
+	}
+}
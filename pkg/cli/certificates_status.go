@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+const (
+	kubeletStaticCert        = "/etc/kubernetes/pki/kubelet.pem"
+	kubeletRotatedClientCert = "/var/lib/kubelet/pki/kubelet-client-current.pem"
+	kubeletRotatedServerCert = "/var/lib/kubelet/pki/kubelet-server-current.pem"
+)
+
+type certificatesStatusOpts struct {
+	planFilename string
+	warnWithin   time.Duration
+}
+
+// kubeletCertificatesNearExpiryErr is returned when the certificates of
+// some kubelets expire within the warning period
+type kubeletCertificatesNearExpiryErr struct {
+	count int
+}
+
+func (e kubeletCertificatesNearExpiryErr) Error() string {
+	return fmt.Sprintf("%d kubelet certificate(s) expired or expire soon", e.count)
+}
+
+// NewCmdCertificatesStatus creates a new certificates status command
+func NewCmdCertificatesStatus(out io.Writer) *cobra.Command {
+	opts := &certificatesStatusOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "status [options]",
+		Short: "Report the expiration of the certificates of the kubelets",
+		Long: `Report the expiration of the client and serving certificates of the kubelet of every node.
+
+When certificate rotation is enabled in the plan file, the certificates renewed by the kubelets are checked instead of the certificates generated by kismatic.
+The command fails if a certificate is expired or expires within --warn-within.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			if opts.warnWithin < 0 {
+				cmd.Help()
+				return fmt.Errorf("--warn-within cannot be negative")
+			}
+			return doCertificatesStatus(out, opts)
+		},
+	}
+
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	cmd.Flags().DurationVar(&opts.warnWithin, "warn-within", 30*24*time.Hour, "report the certificates that expire within this length of time")
+
+	return cmd
+}
+
+func doCertificatesStatus(out io.Writer, opts *certificatesStatusOpts) error {
+	planner := &install.FilePlanner{File: opts.planFilename}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file %q: %v", opts.planFilename, err)
+	}
+	certs := []struct {
+		name string
+		path string
+	}{
+		{name: "client", path: kubeletStaticCert},
+		{name: "serving", path: kubeletStaticCert},
+	}
+	if plan.Cluster.KubeletCertificateRotation.Client {
+		certs[0].path = kubeletRotatedClientCert
+	}
+	if plan.Cluster.KubeletCertificateRotation.Server {
+		certs[1].path = kubeletRotatedServerCert
+	}
+
+	now := time.Now()
+	nearExpiry := 0
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprint(w, "Node\tCertificate\tExpires\tStatus\n")
+	for _, node := range plan.KubeletNodes() {
+		client, err := plan.GetSSHClient(node.Host)
+		if err != nil {
+			return err
+		}
+		for _, c := range certs {
+			// the kubelet uses the generated certificate until it is renewed
+			output, err := client.Output(false, fmt.Sprintf("sudo cat %s 2>/dev/null || sudo cat %s", c.path, kubeletStaticCert))
+			if err != nil {
+				return fmt.Errorf("error reading the %s certificate of node %q: %v", c.name, node.Host, err)
+			}
+			cert, err := parseCertificatePEM([]byte(output))
+			if err != nil {
+				return fmt.Errorf("error parsing the %s certificate of node %q: %v", c.name, node.Host, err)
+			}
+			status := certificateStatus(cert.NotAfter, now, opts.warnWithin)
+			if status != "OK" {
+				nearExpiry++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", node.Host, c.name, cert.NotAfter.Format(time.RFC3339), status)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if nearExpiry > 0 {
+		return kubeletCertificatesNearExpiryErr{count: nearExpiry}
+	}
+	return nil
+}
+
+// parseCertificatePEM returns the first certificate of the PEM data, which
+// may also contain the private key
+func parseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// certificateStatus returns whether a certificate that is valid until
+// notAfter is expired, expires within the warning period, or is OK
+func certificateStatus(notAfter, now time.Time, warnWithin time.Duration) string {
+	switch {
+	case !now.Before(notAfter):
+		return "EXPIRED"
+	case notAfter.Sub(now) <= warnWithin:
+		return "EXPIRING"
+	default:
+		return "OK"
+	}
+}
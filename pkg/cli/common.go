@@ -2,7 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"io"
 
+	"github.com/apprenda/kismatic/pkg/trace"
+	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/pflag"
 )
 
@@ -10,6 +13,10 @@ func addPlanFileFlag(flagSet *pflag.FlagSet, p *string) {
 	flagSet.StringVarP(p, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
 }
 
+func addValuesFileFlag(flagSet *pflag.FlagSet, p *string) {
+	flagSet.StringVar(p, "values-file", "", "path to a file with the values of the ${VAR} placeholders in the plan file. Placeholders not found in the file are read from the environment")
+}
+
 type planFileNotFoundErr struct {
 	filename string
 }
@@ -17,3 +24,59 @@ type planFileNotFoundErr struct {
 func (e planFileNotFoundErr) Error() string {
 	return fmt.Sprintf("Plan file not found at %q. If you don't have a plan file, you may generate one with 'kismatic install plan'", e.filename)
 }
+
+// planInvalidErr is returned when the plan file fails validation
+type planInvalidErr struct{}
+
+func (e planInvalidErr) Error() string {
+	return "Plan file validation error prevents installation from proceeding"
+}
+
+// planWarningsErr is returned when the plan file has warnings that were not
+// accepted
+type planWarningsErr struct{}
+
+func (e planWarningsErr) Error() string {
+	return "Plan file warnings prevent installation from proceeding, use --accept-warnings to proceed anyway"
+}
+
+// sshUnreachableErr is returned when the nodes cannot be reached over SSH
+type sshUnreachableErr struct{}
+
+func (e sshUnreachableErr) Error() string {
+	return "SSH connectivity validation error prevents installation from proceeding"
+}
+
+// externalServiceErr is returned when an external service that kismatic
+// depends on fails, such as the assets storage or the secrets store
+type externalServiceErr struct {
+	err error
+}
+
+func (e externalServiceErr) Error() string {
+	return e.err.Error()
+}
+
+// stepErr is the failure of a step of an operation. It keeps the error of
+// the step, so that the failure can be classified.
+type stepErr struct {
+	step string
+	err  error
+}
+
+func (e stepErr) Error() string {
+	return fmt.Sprintf("error %s: %v", e.step, e.err)
+}
+
+// finishTrace exports the spans of the operation. Failing to export them
+// does not fail the operation.
+func finishTrace(out io.Writer, tracer *trace.Tracer, err error) {
+	if tracer == nil {
+		return
+	}
+	if exportErr := tracer.Finish(err); exportErr != nil {
+		util.PrettyPrintWarn(out, "Could not export the trace of the operation: %v", exportErr)
+		return
+	}
+	util.PrettyPrintOk(out, "Exported the trace of the operation with ID %s", tracer.TraceID())
+}
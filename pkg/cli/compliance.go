@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type complianceCISOpts struct {
+	planFilename       string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+}
+
+// NewCmdCompliance returns the compliance command
+func NewCmdCompliance(out io.Writer) *cobra.Command {
+	var planFile string
+	cmd := &cobra.Command{
+		Use:   "compliance",
+		Short: "audit the compliance of your Kubernetes cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFileFlag(cmd.PersistentFlags(), &planFile)
+	cmd.AddCommand(NewCmdComplianceCIS(out, &planFile))
+	return cmd
+}
+
+// NewCmdComplianceCIS returns the command for running the CIS Kubernetes
+// Benchmark on the cluster nodes
+func NewCmdComplianceCIS(out io.Writer, planFile *string) *cobra.Command {
+	opts := &complianceCISOpts{}
+	cmd := &cobra.Command{
+		Use:   "cis",
+		Short: "run the CIS Kubernetes Benchmark on the cluster nodes using kube-bench",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			opts.planFilename = *planFile
+			return doComplianceCIS(out, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	return cmd
+}
+
+func doComplianceCIS(out io.Writer, opts *complianceCISOpts) error {
+	util.PrintHeader(out, "Running CIS Kubernetes Benchmark", '=')
+
+	planner := install.FilePlanner{File: opts.planFilename}
+	if !planner.PlanExists() {
+		util.PrettyPrintErr(out, "Reading plan file")
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		util.PrettyPrintErr(out, "Reading plan file")
+		return fmt.Errorf("error reading plan file %q: %v", opts.planFilename, err)
+	}
+	util.PrettyPrintOk(out, "Reading plan file")
+
+	execOpts := install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+	}
+	executor, err := install.NewDiagnosticsExecutor(out, os.Stderr, execOpts)
+	if err != nil {
+		return err
+	}
+	report, err := executor.RunCISBenchmark(*plan)
+	if err != nil {
+		return fmt.Errorf("error running CIS benchmark: %v", err)
+	}
+
+	fmt.Fprintln(out)
+	for _, n := range report.Nodes {
+		fmt.Fprintf(out, "%s (%s): %d pass, %d fail, %d warn, %d info\n", n.Host, n.Target, n.Pass, n.Fail, n.Warn, n.Info)
+	}
+	fmt.Fprintln(out)
+	util.PrintColor(out, util.Blue, "The CIS benchmark report was written to the %q directory.\n", filepath.Join(opts.generatedAssetsDir, "cis-benchmark"))
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d CIS benchmark checks failed", failed)
+	}
+	return nil
+}
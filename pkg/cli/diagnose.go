@@ -37,6 +37,8 @@ func NewCmdDiagnostic(out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
 	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
 
+	cmd.AddCommand(NewCmdDiagnosticExec(out, opts))
+
 	return cmd
 }
 
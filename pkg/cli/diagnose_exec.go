@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// diagnosticCommands are the commands that can be run on the nodes for triage,
+// keyed by the name used on the command line
+var diagnosticCommands = map[string]string{
+	"kubelet-logs": "sudo journalctl -u kubelet --no-pager -n 200",
+	"containers":   "sudo docker ps -a",
+	"disk-usage":   "df -h",
+}
+
+type diagsExecOpts struct {
+	planFilename string
+	logFile      string
+	host         string
+	command      string
+}
+
+// NewCmdDiagnosticExec runs a diagnostic command on a node of the cluster
+func NewCmdDiagnosticExec(out io.Writer, diagsOpts *diagsOpts) *cobra.Command {
+	opts := &diagsExecOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "exec HOST COMMAND",
+		Short: "Runs a diagnostic command on a node in the cluster",
+		Long: fmt.Sprintf(`Runs a diagnostic command on a node in the cluster over SSH, and prints its output.
+
+Only the following commands can be run:
+%s
+Every run is recorded in the log file, with the user that ran it.`, diagnosticCommandsUsage()),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Usage()
+			}
+			opts.planFilename = diagsOpts.planFilename
+			opts.host = args[0]
+			opts.command = args[1]
+			planner := &install.FilePlanner{File: opts.planFilename}
+			return doDiagnosticExec(out, planner, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.logFile, "log-file", filepath.Join("diagnostics", "exec.log"), "path to the file where the runs are recorded")
+
+	return cmd
+}
+
+func doDiagnosticExec(out io.Writer, planner install.Planner, opts *diagsExecOpts) error {
+	command, ok := diagnosticCommands[opts.command]
+	if !ok {
+		return fmt.Errorf("command %q is not allowed. Allowed commands are:\n%s", opts.command, diagnosticCommandsUsage())
+	}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	client, err := plan.GetSSHClient(opts.host)
+	if err != nil {
+		return err
+	}
+	// record the run before connecting, so that failed runs are recorded too
+	if err = recordDiagnosticExec(opts.logFile, opts.host, opts.command); err != nil {
+		return fmt.Errorf("error recording the run: %v", err)
+	}
+	output, err := client.Output(false, command)
+	fmt.Fprint(out, output)
+	if err != nil {
+		return fmt.Errorf("error running %q on node %q: %v", opts.command, opts.host, err)
+	}
+	return nil
+}
+
+// recordDiagnosticExec appends the run of the command to the log file
+func recordDiagnosticExec(logFile, host, command string) error {
+	if err := os.MkdirAll(filepath.Dir(logFile), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	_, err = fmt.Fprintf(f, "%s user=%q host=%q command=%q\n", time.Now().UTC().Format(time.RFC3339), username, host, command)
+	return err
+}
+
+func diagnosticCommandsUsage() string {
+	names := make([]string, 0, len(diagnosticCommands))
+	for name := range diagnosticCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&b, "- %s: %s\n", name, diagnosticCommands[name])
+	}
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestDiagnosticExecRejectsCommands(t *testing.T) {
+	tests := []struct {
+		command    string
+		planExists bool
+	}{
+		{
+			command:    "rm -rf /",
+			planExists: true,
+		},
+		{
+			command:    "kubelet-logs; reboot",
+			planExists: true,
+		},
+		{
+			command:    "disk-usage",
+			planExists: false,
+		},
+	}
+	for i, test := range tests {
+		planner := &fakePlanner{exists: test.planExists, plan: &install.Plan{}}
+		opts := &diagsExecOpts{host: "worker01", command: test.command}
+		if err := doDiagnosticExec(&bytes.Buffer{}, planner, opts); err == nil {
+			t.Errorf("test %d: expected an error, but didn't get one", i)
+		}
+		if planner.readCalled {
+			t.Errorf("test %d: expected the plan not to be read", i)
+		}
+	}
+}
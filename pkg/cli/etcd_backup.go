@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type etcdRestoreOptions struct {
+	verbose            bool
+	outputFormat       string
+	generatedAssetsDir string
+	force              bool
+}
+
+// NewCmdEtcdBackup returns the etcd-backup command
+func NewCmdEtcdBackup(in io.Reader, out io.Writer) *cobra.Command {
+	var planFile string
+	cmd := &cobra.Command{
+		Use:   "etcd-backup",
+		Short: "manage the scheduled backups of the Kubernetes etcd cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFileFlag(cmd.PersistentFlags(), &planFile)
+	cmd.AddCommand(NewCmdEtcdBackupRestore(in, out, &planFile))
+	return cmd
+}
+
+// NewCmdEtcdBackupRestore returns the command for restoring the etcd cluster
+// from a snapshot
+func NewCmdEtcdBackupRestore(in io.Reader, out io.Writer, planFile *string) *cobra.Command {
+	opts := etcdRestoreOptions{}
+	cmd := &cobra.Command{
+		Use:   "restore snapshot-name",
+		Short: "restore the Kubernetes etcd cluster from a snapshot",
+		Long: `Restore the Kubernetes etcd cluster from a snapshot uploaded by the scheduled etcd backups.
+
+WARNING all changes made to the cluster after the snapshot was taken will be lost.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			if opts.force == false {
+				ans, err := util.PromptForString(in, out, "Are you sure you want to restore the etcd cluster? All changes made after the snapshot will be lost", "N", []string{"N", "y"})
+				if err != nil {
+					return fmt.Errorf("error getting user response: %v", err)
+				}
+				if strings.ToLower(ans) != "y" {
+					os.Exit(0)
+				}
+			}
+			return doEtcdRestore(out, opts, *planFile, args[0])
+		},
+	}
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw)`)
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.force, "force", false, `do not prompt`)
+	return cmd
+}
+
+func doEtcdRestore(out io.Writer, opts etcdRestoreOptions, planFile string, snapshot string) (err error) {
+	planner := &install.FilePlanner{File: planFile}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planFile}
+	}
+	execOpts := install.ExecutorOptions{
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+	}
+	exec, err := install.NewExecutor(out, out, execOpts)
+	if err != nil {
+		return err
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return err
+	}
+	release, err := fetchAssets(out, plan, opts.generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+
+	if err := exec.RestoreEtcd(*plan, snapshot); err != nil {
+		return fmt.Errorf("error restoring etcd: %v", err)
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Successfully restored the etcd cluster from snapshot %q.\n", snapshot)
+	return nil
+}
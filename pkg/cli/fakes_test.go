@@ -31,7 +31,7 @@ type fakeExecutor struct {
 	err           error
 }
 
-func (fe *fakeExecutor) AddWorker(p *install.Plan, newWorker install.Node) (*install.Plan, error) {
+func (fe *fakeExecutor) AddWorker(p *install.Plan, newWorker install.Node, pool string) (*install.Plan, error) {
 	return nil, nil
 }
 
@@ -72,6 +72,34 @@ func (fe *fakeExecutor) UpgradeClusterServices(install.Plan) error {
 	return nil
 }
 
+func (fe *fakeExecutor) UpgradeAddOns(p install.Plan, addOns []string) ([]string, error) {
+	return addOns, fe.err
+}
+
+func (fe *fakeExecutor) RestoreEtcd(install.Plan, string) error {
+	return nil
+}
+
+func (fe *fakeExecutor) SnapshotForRollback(install.Plan, install.RollbackPoint) error {
+	return fe.err
+}
+
+func (fe *fakeExecutor) Rollback(install.Plan, install.RollbackPoint) error {
+	return fe.err
+}
+
+func (fe *fakeExecutor) Hibernate(install.Plan) error {
+	return fe.err
+}
+
+func (fe *fakeExecutor) Resume(install.Plan) error {
+	return fe.err
+}
+
+func (fe *fakeExecutor) BakeImage(install.Plan, install.Node) error {
+	return fe.err
+}
+
 func (fe *fakeExecutor) RunSmokeTest(p *install.Plan) error {
 	return nil
 }
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/gitops"
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type fleetOpts struct {
+	file               string
+	dir                string
+	templatesDir       string
+	generatedAssetsDir string
+	dryRun             bool
+	autoApprove        bool
+	verbose            bool
+	outputFormat       string
+	skipPreFlight      bool
+	acceptWarnings     bool
+}
+
+// NewCmdFleet returns the command for managing a fleet of clusters declared
+// in a fleet file
+func NewCmdFleet(in io.Reader, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "manage a fleet of clusters declared in a fleet file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.AddCommand(NewCmdFleetApply(in, out))
+	return cmd
+}
+
+// NewCmdFleetApply returns the command for converging a fleet of clusters to
+// its fleet file
+func NewCmdFleetApply(in io.Reader, out io.Writer) *cobra.Command {
+	opts := fleetOpts{}
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "create, update and remove the clusters of a fleet to match the fleet file",
+		Long: `Create, update and remove the clusters of a fleet to match the fleet file.
+
+The fleet file lists the clusters of the fleet, with the template their plan file is generated from, the values
+of the variables of the template, and the fields of the plan file that override the template. The plan files are
+generated and compared with the plan files that were last applied. The changes are printed, and once confirmed, the
+plan files are written to the --dir directory and applied. Nothing is written with --dry-run.
+
+KET does not destroy clusters: when a cluster is removed from the fleet file, it is forgotten, and its machines
+must be deprovisioned.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			if opts.file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			apply := func(cluster, planFile string) error {
+				util.PrintHeader(out, fmt.Sprintf("Applying cluster %q", cluster), '=')
+				return classifyApplyErr(applyPlanFile(out, planFile, applyOpts{
+					generatedAssetsDir: filepath.Join(opts.generatedAssetsDir, cluster),
+					verbose:            opts.verbose,
+					outputFormat:       opts.outputFormat,
+					skipPreFlight:      opts.skipPreFlight,
+					acceptWarnings:     opts.acceptWarnings,
+				}))
+			}
+			return doFleetApply(in, out, opts, apply)
+		},
+	}
+	cmd.Flags().StringVarP(&opts.file, "file", "f", "", "path to the fleet file")
+	cmd.Flags().StringVar(&opts.dir, "dir", "fleet", "path to the directory where the plan files of the clusters are generated, and the applied plan files are recorded")
+	addTemplatesDirFlag(cmd.Flags(), &opts.templatesDir)
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "print the changes, but don't apply them")
+	cmd.Flags().BoolVar(&opts.autoApprove, "auto-approve", false, "apply the changes without asking for confirmation")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().BoolVar(&opts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks")
+	addAcceptWarningsFlag(cmd.Flags(), &opts.acceptWarnings)
+	return cmd
+}
+
+func doFleetApply(in io.Reader, out io.Writer, opts fleetOpts, apply func(cluster, planFile string) error) error {
+	fleet, err := install.ReadFleet(opts.file)
+	if err != nil {
+		return err
+	}
+	store := install.TemplateStore{Dir: opts.templatesDir}
+	// The plan files are generated in a temporary directory to print the
+	// changes, and only written to the --dir directory once confirmed
+	tmpDir, err := ioutil.TempDir("", "kismatic-fleet")
+	if err != nil {
+		return fmt.Errorf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err = writeFleetPlanFiles(fleet, store, tmpDir); err != nil {
+		return err
+	}
+	reconciler := gitops.Reconciler{
+		Dir:       tmpDir,
+		StateFile: filepath.Join(opts.dir, "state.json"),
+		Apply:     apply,
+	}
+	changes, err := reconciler.Diff()
+	if err != nil {
+		return err
+	}
+	util.PrintHeader(out, "Fleet Changes", '=')
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "The clusters of the fleet are up to date")
+		return nil
+	}
+	printFleetChanges(out, changes)
+	if opts.dryRun {
+		return nil
+	}
+	if !opts.autoApprove {
+		ans, err := util.PromptForString(in, out, "Apply these changes?", "N", []string{"N", "y"})
+		if err != nil {
+			return fmt.Errorf("error getting user response: %v", err)
+		}
+		if strings.ToLower(ans) != "y" {
+			fmt.Fprintln(out, "The changes were not applied")
+			return nil
+		}
+	}
+	plansDir := filepath.Join(opts.dir, "clusters")
+	if err = writeFleetPlanFiles(fleet, store, plansDir); err != nil {
+		return err
+	}
+	reconciler.Dir = plansDir
+	res, err := reconciler.Reconcile(opts.file)
+	if err != nil {
+		return fmt.Errorf("error applying the fleet: %v", err)
+	}
+	printReconcileResult(out, res)
+	if len(res.Failed) > 0 {
+		return fmt.Errorf("%d clusters of the fleet failed to apply", len(res.Failed))
+	}
+	return nil
+}
+
+// writeFleetPlanFiles generates the plan files of the clusters of the fleet in
+// the directory, and removes the plan files of the clusters that are no
+// longer in the fleet
+func writeFleetPlanFiles(fleet *install.Fleet, store install.TemplateStore, dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating directory %q: %v", dir, err)
+	}
+	planFiles := map[string]string{}
+	for _, c := range fleet.Clusters {
+		p, err := c.Render(store)
+		if err != nil {
+			return fmt.Errorf("error generating plan file of cluster %q: %v", c.Name, err)
+		}
+		fp := install.FilePlanner{File: filepath.Join(dir, c.Name+".yaml")}
+		if err = fp.Write(p); err != nil {
+			return fmt.Errorf("error writing plan file of cluster %q: %v", c.Name, err)
+		}
+		planFiles[c.Name] = fp.File
+	}
+	existing, err := gitops.PlanFiles(dir)
+	if err != nil {
+		return err
+	}
+	for name, file := range existing {
+		if planFiles[name] == file {
+			continue
+		}
+		if err = os.Remove(file); err != nil {
+			return fmt.Errorf("error removing plan file %q: %v", file, err)
+		}
+	}
+	return nil
+}
+
+func printFleetChanges(out io.Writer, changes []gitops.Change) {
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Action]++
+		switch c.Action {
+		case gitops.ChangeCreate:
+			util.PrintColor(out, util.Green, "  + %s will be created\n", c.Cluster)
+		case gitops.ChangeUpdate:
+			util.PrintColor(out, util.Orange, "  ~ %s will be updated\n", c.Cluster)
+		case gitops.ChangeRemove:
+			util.PrintColor(out, util.Red, "  - %s will be removed from the fleet, its machines must be deprovisioned\n", c.Cluster)
+		}
+	}
+	fmt.Fprintf(out, "\n%d to create, %d to update, %d to remove\n\n", counts[gitops.ChangeCreate], counts[gitops.ChangeUpdate], counts[gitops.ChangeRemove])
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestDoFleetApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kismatic-fleet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	planFile := filepath.Join(dir, "plan.yaml")
+	if err = ioutil.WriteFile(planFile, []byte("cluster:\n  name: ${CLUSTER_NAME}\n  admin_password: ${ADMIN_PASSWORD}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := install.TemplateStore{Dir: filepath.Join(dir, "templates")}
+	if _, err = store.Create("small", planFile, map[string]string{"CLUSTER_NAME": "", "ADMIN_PASSWORD": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	fleetFile := filepath.Join(dir, "fleet.yaml")
+	writeFleet := func(fleet string) {
+		if err := ioutil.WriteFile(fleetFile, []byte(fleet), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := fleetOpts{file: fleetFile, dir: filepath.Join(dir, "fleet"), templatesDir: store.Dir}
+	var applied []string
+	apply := func(cluster, planFile string) error {
+		applied = append(applied, cluster)
+		return nil
+	}
+
+	tests := []struct {
+		fleet       string
+		dryRun      bool
+		answer      string
+		applied     []string
+		outContains string
+		planFiles   []string
+	}{
+		{
+			fleet:       "clusters:\n- name: dev\n  template: small\n- name: prod\n  template: small\n",
+			dryRun:      true,
+			outContains: "2 to create, 0 to update, 0 to remove",
+		},
+		{
+			fleet:       "clusters:\n- name: dev\n  template: small\n- name: prod\n  template: small\n",
+			answer:      "N\n",
+			outContains: "The changes were not applied",
+		},
+		{
+			fleet:       "clusters:\n- name: dev\n  template: small\n- name: prod\n  template: small\n",
+			answer:      "y\n",
+			applied:     []string{"dev", "prod"},
+			outContains: "2 to create, 0 to update, 0 to remove",
+			planFiles:   []string{"dev", "prod"},
+		},
+		{
+			fleet:       "clusters:\n- name: dev\n  template: small\n",
+			dryRun:      true,
+			outContains: "0 to create, 0 to update, 1 to remove",
+			planFiles:   []string{"dev", "prod"},
+		},
+		{
+			fleet:       "clusters:\n- name: dev\n  template: small\n- name: prod\n  template: small\n",
+			outContains: "The clusters of the fleet are up to date",
+			planFiles:   []string{"dev", "prod"},
+		},
+		{
+			fleet:       "clusters:\n- name: dev\n  template: small\n  overrides:\n    cluster:\n      labels:\n        env: dev\n",
+			answer:      "y\n",
+			applied:     []string{"dev"},
+			outContains: "0 to create, 1 to update, 1 to remove",
+			planFiles:   []string{"dev"},
+		},
+	}
+	for i, test := range tests {
+		writeFleet(test.fleet)
+		applied = nil
+		opts.dryRun = test.dryRun
+		out := &bytes.Buffer{}
+		if err := doFleetApply(strings.NewReader(test.answer), out, opts, apply); err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(applied, test.applied) {
+			t.Errorf("test %d: expected %v to be applied, but got %v", i, test.applied, applied)
+		}
+		if !strings.Contains(out.String(), test.outContains) {
+			t.Errorf("test %d: expected the output to contain %q, but got:\n%s", i, test.outContains, out.String())
+		}
+		var planFiles []string
+		for _, name := range []string{"dev", "prod"} {
+			if _, err := os.Stat(filepath.Join(opts.dir, "clusters", name+".yaml")); err == nil {
+				planFiles = append(planFiles, name)
+			}
+		}
+		if !reflect.DeepEqual(planFiles, test.planFiles) {
+			t.Errorf("test %d: expected the plan files %v, but got %v", i, test.planFiles, planFiles)
+		}
+	}
+}
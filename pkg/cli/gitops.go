@@ -0,0 +1,373 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/gitops"
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type gitopsOpts struct {
+	repo               string
+	branch             string
+	path               string
+	workDir            string
+	interval           time.Duration
+	retryBackoff       time.Duration
+	maxRetryBackoff    time.Duration
+	webhookAddress     string
+	webhookSecret      string
+	healthInterval     time.Duration
+	alertsFile         string
+	kubectlPath        string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+	skipPreFlight      bool
+	acceptWarnings     bool
+}
+
+// NewCmdGitOps returns the command for keeping clusters in sync with the
+// plan files in a git repository
+func NewCmdGitOps(out io.Writer) *cobra.Command {
+	opts := gitopsOpts{}
+	cmd := &cobra.Command{
+		Use:   "gitops",
+		Short: "apply the plan files in a git repository when they change",
+		Long: `Apply the plan files in a git repository when they change, so that a fleet of clusters is managed
+declaratively, and changes to the clusters are reviewed as changes to the repository.
+
+Each plan file in the --path directory of the repository describes a cluster, named after the file.
+The repository is polled every --interval, or when a push webhook is received on --webhook-address.
+New and changed plan files are applied. Plan files that fail to apply are applied again after
+--retry-backoff, and the delay doubles with each failure in a row, up to --max-retry-backoff. Plan
+files that fail validation are not applied again until they change. KET does not destroy clusters: when a plan file is removed, the cluster is reported and
+forgotten, and its machines must be deprovisioned.
+
+The clusters that were applied are probed every --health-interval with their generated kubeconfig: a cluster is
+healthy when its API server is reachable and all its nodes are Ready. Their health is recorded in the work
+directory, and served on /health and /metrics of the --webhook-address, along with the versions of the components
+of their nodes on /clusters/<name>/versions. The webhooks listed in the --alerts-file
+are notified when the clusters that match their selector become unhealthy, and when they recover.
+
+The generated assets of each cluster are stored in a directory named after the cluster, under
+--generated-assets-dir.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			if opts.repo == "" {
+				return errors.New("--repo is required")
+			}
+			if opts.alertsFile != "" && opts.healthInterval == 0 {
+				return errors.New("--alerts-file requires the clusters to be probed, --health-interval cannot be 0")
+			}
+			return doGitOps(out, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.repo, "repo", "", "URL of the git repository with the plan files")
+	cmd.Flags().StringVar(&opts.branch, "branch", "master", "branch of the git repository")
+	cmd.Flags().StringVar(&opts.path, "path", ".", "path to the directory of the plan files in the git repository")
+	cmd.Flags().StringVar(&opts.workDir, "work-dir", "gitops", "path to the directory where the git repository is checked out, and the applied plan files are recorded")
+	cmd.Flags().DurationVar(&opts.interval, "interval", 5*time.Minute, "how often the git repository is polled. When 0, the plan files are applied once")
+	cmd.Flags().DurationVar(&opts.retryBackoff, "retry-backoff", time.Minute, "how long to wait before applying a plan file that failed again")
+	cmd.Flags().DurationVar(&opts.maxRetryBackoff, "max-retry-backoff", time.Hour, "the maximum time to wait before applying a plan file that failed again")
+	cmd.Flags().StringVar(&opts.webhookAddress, "webhook-address", "", "address to listen on for the push webhooks of the git repository, such as :8080")
+	cmd.Flags().StringVar(&opts.webhookSecret, "webhook-secret", "", "secret the push webhooks are signed with")
+	cmd.Flags().DurationVar(&opts.healthInterval, "health-interval", 5*time.Minute, "how often the clusters that were applied are probed. When 0, the clusters are not probed")
+	cmd.Flags().StringVar(&opts.alertsFile, "alerts-file", "", "path to the file listing the webhooks notified when the health of the clusters degrades")
+	cmd.Flags().StringVar(&opts.kubectlPath, "kubectl", defaultKubectlPath(), "path to the kubectl binary used to probe the clusters")
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().BoolVar(&opts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks")
+	addAcceptWarningsFlag(cmd.Flags(), &opts.acceptWarnings)
+	return cmd
+}
+
+func doGitOps(out io.Writer, opts gitopsOpts) error {
+	repo := gitops.Repository{
+		URL:    opts.repo,
+		Branch: opts.branch,
+		Dir:    filepath.Join(opts.workDir, "repo"),
+	}
+	reconciler := gitops.Reconciler{
+		Dir:       filepath.Join(repo.Dir, opts.path),
+		StateFile: filepath.Join(opts.workDir, "state.json"),
+		Apply: func(cluster, planFile string) error {
+			return classifyApplyErr(gitopsApply(out, opts, cluster, planFile))
+		},
+		Backoff: gitops.Backoff{Initial: opts.retryBackoff, Max: opts.maxRetryBackoff},
+	}
+	if opts.interval == 0 {
+		return gitopsSync(out, repo, reconciler)
+	}
+
+	healthFile := filepath.Join(opts.workDir, "health.json")
+	if opts.healthInterval > 0 {
+		checker := gitops.HealthChecker{
+			StateFile:  reconciler.StateFile,
+			HealthFile: healthFile,
+			Probe: func(cluster string) ([]gitops.NodeVersions, error) {
+				plan, err := clusterPlan(reconciler.Dir, cluster)
+				if err != nil {
+					return nil, err
+				}
+				return probeClusterHealth(*plan, data.LocalKubectl{
+					KubectlPath: opts.kubectlPath,
+					Kubeconfig:  filepath.Join(opts.generatedAssetsDir, cluster, "kubeconfig"),
+				})
+			},
+		}
+		alerter := gitops.Alerter{
+			Labels: func(cluster string) (map[string]string, error) {
+				return clusterLabels(reconciler.Dir, cluster)
+			},
+			StateFile: filepath.Join(opts.workDir, "alerts.json"),
+		}
+		if opts.alertsFile != "" {
+			config, err := gitops.ReadAlertConfig(opts.alertsFile)
+			if err != nil {
+				return err
+			}
+			alerter.Config = *config
+		}
+		go gitopsHealth(out, checker, alerter, opts.healthInterval)
+	}
+
+	sync := make(chan struct{}, 1)
+	if opts.webhookAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/", gitops.WebhookHandler(opts.webhookSecret, sync))
+		mux.Handle("/health", gitops.HealthHandler(healthFile, false))
+		mux.Handle("/metrics", gitops.HealthHandler(healthFile, true))
+		mux.Handle("/clusters/", gitops.VersionsHandler(healthFile))
+		go func() {
+			if err := http.ListenAndServe(opts.webhookAddress, mux); err != nil {
+				util.PrettyPrintErr(out, "Listening for webhooks on %q: %v", opts.webhookAddress, err)
+			}
+		}()
+	}
+	for {
+		if err := gitopsSync(out, repo, reconciler); err != nil {
+			util.PrettyPrintErr(out, "%v", err)
+		}
+		select {
+		case <-time.After(opts.interval):
+		case <-sync:
+		}
+	}
+}
+
+func gitopsSync(out io.Writer, repo gitops.Repository, reconciler gitops.Reconciler) error {
+	util.PrintHeader(out, fmt.Sprintf("Syncing %s (%s)", repo.URL, repo.Branch), '=')
+	rev, err := repo.Sync()
+	if err != nil {
+		return fmt.Errorf("error syncing repository: %v", err)
+	}
+	res, err := reconciler.Reconcile(rev)
+	if err != nil {
+		return fmt.Errorf("error applying plan files of revision %s: %v", rev, err)
+	}
+	printReconcileResult(out, res)
+	if len(res.Failed) > 0 {
+		return fmt.Errorf("%d clusters failed to apply at revision %s", len(res.Failed), rev)
+	}
+	return nil
+}
+
+// gitopsHealth probes the clusters every interval, and reports the clusters
+// whose health changed
+func gitopsHealth(out io.Writer, checker gitops.HealthChecker, alerter gitops.Alerter, interval time.Duration) {
+	for {
+		previous, err := gitops.ReadHealth(checker.HealthFile)
+		if err != nil {
+			util.PrettyPrintErr(out, "%v", err)
+		}
+		health, err := checker.Check()
+		if err != nil {
+			util.PrettyPrintErr(out, "Probing the health of the clusters: %v", err)
+			time.Sleep(interval)
+			continue
+		}
+		for c, h := range health {
+			p, ok := previous[c]
+			switch {
+			case !h.Healthy && (!ok || p.Healthy || p.Summary != h.Summary):
+				util.PrettyPrintWarn(out, "Cluster %q is not healthy: %s", c, h.Summary)
+			case h.Healthy && ok && !p.Healthy:
+				util.PrettyPrintOk(out, "Cluster %q is healthy", c)
+			}
+		}
+		sent, err := alerter.Notify(health)
+		for _, n := range sent {
+			util.PrettyPrintOk(out, "Sent %s alert of cluster %q", n.Status, n.Cluster)
+		}
+		if err != nil {
+			util.PrettyPrintErr(out, "%v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// clusterLabels returns the labels of the cluster, from its plan file in the
+// directory
+func clusterLabels(dir, cluster string) (map[string]string, error) {
+	plan, err := clusterPlan(dir, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Cluster.Labels, nil
+}
+
+// clusterPlan returns the plan of the cluster, from its plan file in the
+// directory
+func clusterPlan(dir, cluster string) (*install.Plan, error) {
+	files, err := gitops.PlanFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := files[cluster]
+	if !ok {
+		return nil, fmt.Errorf("plan file of cluster %q was not found", cluster)
+	}
+	fp := install.FilePlanner{File: file}
+	plan, err := fp.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file of cluster %q: %v", cluster, err)
+	}
+	return plan, nil
+}
+
+// probeClusterHealth returns the versions of the components of the nodes of
+// the cluster, and its problems: an API server that is not reachable, or nodes
+// that are not Ready. The version of the CNI provider is not known when the
+// pods cannot be listed.
+func probeClusterHealth(plan install.Plan, kubectl data.LocalKubectl) ([]gitops.NodeVersions, error) {
+	nodes, err := kubectl.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	if nodes == nil || len(nodes.Items) == 0 {
+		return nil, errors.New("no nodes are registered with the API server")
+	}
+	pods, _ := kubectl.ListPods()
+	cniVersions := install.CNIVersions(plan, pods)
+	var versions []gitops.NodeVersions
+	var notReady []string
+	for _, n := range nodes.Items {
+		if !n.Ready() {
+			notReady = append(notReady, n.Name)
+		}
+		info := n.Status.NodeInfo
+		versions = append(versions, gitops.NodeVersions{
+			Node:             n.Name,
+			Kubelet:          info.KubeletVersion,
+			KubeProxy:        info.KubeProxyVersion,
+			ContainerRuntime: info.ContainerRuntimeVersion,
+			OSImage:          info.OSImage,
+			KernelVersion:    info.KernelVersion,
+			CNI:              cniVersions[n.Name],
+			Skew:             install.NodeVersionSkew(plan, info, cniVersions[n.Name]),
+		})
+	}
+	if len(notReady) > 0 {
+		return versions, gitops.NodesNotReadyError{Nodes: notReady, Total: len(nodes.Items)}
+	}
+	return versions, nil
+}
+
+// printReconcileResult prints what happened to each cluster during a
+// reconciliation
+func printReconcileResult(out io.Writer, res *gitops.Result) {
+	for _, c := range res.Unchanged {
+		util.PrettyPrintSkipped(out, "Cluster %q is unchanged", c)
+	}
+	for _, c := range res.Applied {
+		util.PrettyPrintOk(out, "Applied cluster %q", c)
+	}
+	for _, c := range res.Removed {
+		util.PrettyPrintWarn(out, "Plan file of cluster %q was removed, its machines must be deprovisioned", c)
+	}
+	for c, f := range res.Deferred {
+		if f.Retryable() {
+			util.PrettyPrintSkipped(out, "Cluster %q failed to apply %d times (%s), retrying at %s", c, f.Attempts, f.Class, f.NextRetryAt.Local().Format(time.RFC3339))
+		} else {
+			util.PrettyPrintSkipped(out, "Cluster %q failed to apply (%s), waiting for its plan file to change", c, f.Class)
+		}
+	}
+	for c, f := range res.Failed {
+		util.PrettyPrintErr(out, "Applying cluster %q (%s): %s", c, f.Class, f.Error)
+		if f.Retryable() {
+			util.PrettyPrintWarn(out, "Cluster %q will be applied again at %s", c, f.NextRetryAt.Local().Format(time.RFC3339))
+		}
+	}
+}
+
+func gitopsApply(out io.Writer, opts gitopsOpts, cluster, planFile string) error {
+	util.PrintHeader(out, fmt.Sprintf("Applying cluster %q", cluster), '=')
+	return applyPlanFile(out, planFile, applyOpts{
+		generatedAssetsDir: filepath.Join(opts.generatedAssetsDir, cluster),
+		verbose:            opts.verbose,
+		outputFormat:       opts.outputFormat,
+		skipPreFlight:      opts.skipPreFlight,
+		acceptWarnings:     opts.acceptWarnings,
+	})
+}
+
+// applyPlanFile applies the plan file of one of the clusters of a fleet
+func applyPlanFile(out io.Writer, planFile string, opts applyOpts) error {
+	executor, err := install.NewExecutor(out, os.Stderr, install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		RunsDirectory:            opts.runsDir,
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+	})
+	if err != nil {
+		return err
+	}
+	c := &applyCmd{
+		out:                out,
+		planner:            &install.FilePlanner{File: planFile},
+		executor:           executor,
+		planFile:           planFile,
+		generatedAssetsDir: opts.generatedAssetsDir,
+		runsDir:            opts.runsDir,
+		verbose:            opts.verbose,
+		outputFormat:       opts.outputFormat,
+		skipPreFlight:      opts.skipPreFlight,
+		acceptWarnings:     opts.acceptWarnings,
+	}
+	return c.run()
+}
+
+// classifyApplyErr returns the error to apply a plan file with the class
+// of the failure
+func classifyApplyErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	cause := err
+	if se, ok := err.(stepErr); ok {
+		cause = se.err
+	}
+	class := gitops.FailurePlaybook
+	switch cause.(type) {
+	case planInvalidErr, planWarningsErr:
+		class = gitops.FailureValidation
+	case sshUnreachableErr:
+		class = gitops.FailureUnreachable
+	case externalServiceErr:
+		class = gitops.FailureTransient
+	}
+	return gitops.ClassifiedError{Class: class, Err: err}
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type hibernateOpts struct {
+	planFilename       string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+	force              bool
+}
+
+// NewCmdHibernate returns the command for hibernating the cluster
+func NewCmdHibernate(in io.Reader, out io.Writer) *cobra.Command {
+	opts := &hibernateOpts{}
+	cmd := &cobra.Command{
+		Use:   "hibernate",
+		Short: "stop the Kubernetes services and shut down the cluster nodes",
+		Long: `Stop the Kubernetes services and shut down the cluster nodes, preserving their disks.
+
+Cloud instances are stopped when they are shut down, so that a cluster that is not used, such as a
+development cluster overnight, does not incur the cost of its instances. Start the nodes and run
+"kismatic resume" to bring the cluster back.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			if !opts.force {
+				ans, err := util.PromptForString(in, out, "Are you sure you want to shut down all the nodes of the cluster?", "N", []string{"N", "y"})
+				if err != nil {
+					return fmt.Errorf("error getting user response: %v", err)
+				}
+				if strings.ToLower(ans) != "y" {
+					os.Exit(0)
+				}
+			}
+			planner := &install.FilePlanner{File: opts.planFilename}
+			executor, err := install.NewExecutor(out, os.Stderr, opts.executorOptions())
+			if err != nil {
+				return err
+			}
+			return doHibernate(out, planner, executor, opts.generatedAssetsDir, opts.planFilename)
+		},
+	}
+	addHibernateFlags(cmd, opts)
+	cmd.Flags().BoolVar(&opts.force, "force", false, "do not prompt")
+	return cmd
+}
+
+// NewCmdResume returns the command for resuming a hibernated cluster
+func NewCmdResume(out io.Writer) *cobra.Command {
+	opts := &hibernateOpts{}
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "start the Kubernetes services of a hibernated cluster, and verify its health",
+		Long: `Start the Kubernetes services of a hibernated cluster, and verify that the control plane is running
+and that all nodes are ready.
+
+KET does not manage the machines of the cluster, so the nodes must be started before running this
+command. The command waits up to 10 minutes for the nodes to be reachable.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			planner := &install.FilePlanner{File: opts.planFilename}
+			executor, err := install.NewExecutor(out, os.Stderr, opts.executorOptions())
+			if err != nil {
+				return err
+			}
+			return doResume(out, planner, executor, opts.generatedAssetsDir, opts.planFilename)
+		},
+	}
+	addHibernateFlags(cmd, opts)
+	return cmd
+}
+
+func addHibernateFlags(cmd *cobra.Command, opts *hibernateOpts) {
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw)`)
+}
+
+func (opts hibernateOpts) executorOptions() install.ExecutorOptions {
+	return install.ExecutorOptions{
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+	}
+}
+
+func doHibernate(out io.Writer, planner install.Planner, executor install.Executor, generatedAssetsDir, planFile string) (err error) {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planFile}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	release, err := fetchAssets(out, plan, generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	if err = executor.Hibernate(*plan); err != nil {
+		return fmt.Errorf("error hibernating the cluster: %v", err)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "The nodes of the cluster %q are shutting down. Start the nodes and run \"kismatic resume\" to resume the cluster.\n", plan.Cluster.Name)
+	return nil
+}
+
+func doResume(out io.Writer, planner install.Planner, executor install.Executor, generatedAssetsDir, planFile string) (err error) {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planFile}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	release, err := fetchAssets(out, plan, generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	if err = executor.Resume(*plan); err != nil {
+		return fmt.Errorf("error resuming the cluster: %v", err)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "The cluster %q was resumed and all its nodes are ready.\n", plan.Cluster.Name)
+	return nil
+}
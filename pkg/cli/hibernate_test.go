@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestHibernateAndResume(t *testing.T) {
+	tests := []struct {
+		planExists bool
+		execErr    error
+		expectErr  bool
+	}{
+		{
+			planExists: true,
+		},
+		{
+			planExists: false,
+			expectErr:  true,
+		},
+		{
+			planExists: true,
+			execErr:    errors.New("unreachable"),
+			expectErr:  true,
+		},
+	}
+	for i, test := range tests {
+		for name, do := range map[string]func(*fakePlanner, *fakeExecutor) error{
+			"hibernate": func(p *fakePlanner, e *fakeExecutor) error {
+				return doHibernate(&bytes.Buffer{}, p, e, "generated", "kismatic-cluster.yaml")
+			},
+			"resume": func(p *fakePlanner, e *fakeExecutor) error {
+				return doResume(&bytes.Buffer{}, p, e, "generated", "kismatic-cluster.yaml")
+			},
+		} {
+			planner := &fakePlanner{exists: test.planExists, plan: &install.Plan{}}
+			err := do(planner, &fakeExecutor{err: test.execErr})
+			if test.expectErr && err == nil {
+				t.Errorf("test %d: %s: expected an error, but didn't get one", i, name)
+			}
+			if !test.expectErr && err != nil {
+				t.Errorf("test %d: %s: unexpected error: %v", i, name, err)
+			}
+		}
+	}
+}
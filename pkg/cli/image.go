@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type imageBakeOpts struct {
+	planFilename       string
+	ip                 string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+}
+
+// NewCmdImage returns the image command
+func NewCmdImage(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image",
+		Short: "manage the machine images the cluster nodes are created from",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	cmd.AddCommand(NewCmdImageBake(out))
+	return cmd
+}
+
+// NewCmdImageBake returns the command for preparing a machine that a machine
+// image is created from
+func NewCmdImageBake(out io.Writer) *cobra.Command {
+	opts := imageBakeOpts{}
+	cmd := &cobra.Command{
+		Use:   "bake",
+		Short: "install the packages and container images of the cluster on a machine, to create a machine image from it",
+		Long: `Install the packages and pull the container images of the cluster on a builder machine, to create
+a machine image from it, such as an AWS AMI.
+
+The builder is accessed with the SSH settings of the plan file. Once the command succeeds, shut down the
+builder and create the machine image from it. Create the cluster nodes from the image and set
+cluster.baked_image to true in the plan file, so that KET does not download the packages when the
+cluster is installed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			planner := &install.FilePlanner{File: opts.planFilename}
+			executor, err := install.NewExecutor(out, os.Stderr, install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+			})
+			if err != nil {
+				return err
+			}
+			return doImageBake(out, planner, executor, opts)
+		},
+	}
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	cmd.Flags().StringVar(&opts.ip, "ip", "", "IP address of the builder machine")
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw)`)
+	return cmd
+}
+
+func doImageBake(out io.Writer, planner install.Planner, executor install.Executor, opts imageBakeOpts) (err error) {
+	if opts.ip == "" {
+		return errors.New("--ip is required")
+	}
+	if net.ParseIP(opts.ip) == nil {
+		return fmt.Errorf("--ip %q is not a valid IP address", opts.ip)
+	}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	release, err := fetchAssets(out, plan, opts.generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	builder := install.Node{Host: "image-builder", IP: opts.ip}
+	if err = executor.BakeImage(*plan, builder); err != nil {
+		return fmt.Errorf("error baking image: %v", err)
+	}
+	fmt.Fprintln(out)
+	util.PrettyPrintOk(out, "Installed the packages and container images on %s", opts.ip)
+	fmt.Fprintln(out, "Shut down the machine and create the machine image from it.")
+	if !plan.Cluster.BakedImage {
+		fmt.Fprintln(out, "Set cluster.baked_image to true in the plan file of the clusters created from the image.")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestImageBake(t *testing.T) {
+	tests := []struct {
+		ip         string
+		planExists bool
+		execErr    error
+		expectErr  bool
+	}{
+		{
+			ip:         "10.0.0.10",
+			planExists: true,
+		},
+		{
+			planExists: true,
+			expectErr:  true,
+		},
+		{
+			ip:         "builder",
+			planExists: true,
+			expectErr:  true,
+		},
+		{
+			ip:        "10.0.0.10",
+			expectErr: true,
+		},
+		{
+			ip:         "10.0.0.10",
+			planExists: true,
+			execErr:    errors.New("unreachable"),
+			expectErr:  true,
+		},
+	}
+	for i, test := range tests {
+		planner := &fakePlanner{exists: test.planExists, plan: &install.Plan{}}
+		opts := imageBakeOpts{planFilename: "kismatic-cluster.yaml", ip: test.ip}
+		err := doImageBake(&bytes.Buffer{}, planner, &fakeExecutor{err: test.execErr}, opts)
+		if test.expectErr && err == nil {
+			t.Errorf("test %d: expected an error, but didn't get one", i)
+		}
+		if !test.expectErr && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+	}
+}
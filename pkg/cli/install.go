@@ -7,7 +7,8 @@ import (
 )
 
 type installOpts struct {
-	planFilename string
+	planFilename   string
+	valuesFilename string
 }
 
 // NewCmdInstall creates a new install command
@@ -28,9 +29,11 @@ func NewCmdInstall(in io.Reader, out io.Writer) *cobra.Command {
 	cmd.AddCommand(NewCmdApply(out, opts))
 	cmd.AddCommand(NewCmdAddWorker(out, opts))
 	cmd.AddCommand(NewCmdStep(out, opts))
+	cmd.AddCommand(NewCmdTemplate(out, opts))
 
 	// PersistentFlags
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addValuesFileFlag(cmd.PersistentFlags(), &opts.valuesFilename)
 
 	return cmd
 }
@@ -29,8 +29,19 @@ more documentation is available at https://github.com/apprenda/kismatic`,
 	cmd.AddCommand(NewCmdInfo(out))
 	cmd.AddCommand(NewCmdUpgrade(in, out))
 	cmd.AddCommand(NewCmdDiagnostic(out))
+	cmd.AddCommand(NewCmdCompliance(out))
+	cmd.AddCommand(NewCmdEtcdBackup(in, out))
+	cmd.AddCommand(NewCmdHibernate(in, out))
+	cmd.AddCommand(NewCmdResume(out))
+	cmd.AddCommand(NewCmdGitOps(out))
+	cmd.AddCommand(NewCmdBatch(out))
+	cmd.AddCommand(NewCmdFleet(in, out))
+	cmd.AddCommand(NewCmdImage(out))
 	cmd.AddCommand(NewCmdCertificates(out))
 	cmd.AddCommand(NewCmdSeedRegistry(out, stderr))
+	cmd.AddCommand(NewCmdStats(out))
+	cmd.AddCommand(NewCmdAudit(out))
+	cmd.AddCommand(NewCmdLogs(out))
 
 	return cmd, nil
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+type logsOpts struct {
+	runsDirectory string
+	outputFormat  string
+	failedOnly    bool
+}
+
+// NewCmdLogs returns the command for listing the runs and printing their logs
+func NewCmdLogs(out io.Writer) *cobra.Command {
+	opts := &logsOpts{}
+	cmd := &cobra.Command{
+		Use:   "logs [RUN]",
+		Short: "list the runs of kismatic, or print the log of a run",
+		Long: `List the runs of kismatic recorded in the runs directory, with their result.
+
+When RUN is given, the Ansible log of the run is printed. RUN is the ID of a run as listed, or "latest" for the latest run.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch len(args) {
+			case 0:
+				return doListRuns(out, opts)
+			case 1:
+				return doPrintRunLog(out, opts.runsDirectory, args[0])
+			default:
+				return fmt.Errorf("Unexpected args: %v", args[1:])
+			}
+		},
+	}
+	cmd.Flags().StringVar(&opts.runsDirectory, "runs-dir", "runs", "path to the directory where the runs of kismatic are recorded")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format of the list of runs (options "simple"|"json")`)
+	cmd.Flags().BoolVar(&opts.failedOnly, "failed", false, "only list the runs that failed")
+	return cmd
+}
+
+func doListRuns(out io.Writer, opts *logsOpts) error {
+	records, err := install.ReadRunIndex(opts.runsDirectory)
+	if err != nil {
+		return err
+	}
+	if opts.failedOnly {
+		var failed []install.RunRecord
+		for _, r := range records {
+			if r.Result == install.RunResultFailed {
+				failed = append(failed, r)
+			}
+		}
+		records = failed
+	}
+	switch opts.outputFormat {
+	case "simple":
+		if len(records) == 0 {
+			fmt.Fprintln(out, "No runs have been recorded")
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN\tOPERATION\tSTARTED\tDURATION\tRESULT")
+		for _, r := range records {
+			duration := "-"
+			if !r.Finished.IsZero() {
+				duration = (r.Finished.Sub(r.Started) / time.Second * time.Second).String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Task, r.Started.Local().Format(time.RFC3339), duration, r.Result)
+		}
+		return w.Flush()
+	case "json":
+		b, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling runs: %v", err)
+		}
+		fmt.Fprintln(out, string(b))
+		return nil
+	default:
+		return fmt.Errorf("Output format %q is not supported", opts.outputFormat)
+	}
+}
+
+func doPrintRunLog(out io.Writer, runsDirectory, id string) error {
+	run, err := install.FindRun(runsDirectory, id)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filepath.Join(runsDirectory, filepath.FromSlash(run.Log)))
+	if err != nil {
+		return fmt.Errorf("error opening the log of run %q: %v", run.ID, err)
+	}
+	defer f.Close()
+	_, err = io.Copy(out, f)
+	return err
+}
@@ -23,6 +23,11 @@ func NewCmdPlan(in io.Reader, out io.Writer, options *installOpts) *cobra.Comman
 		},
 	}
 
+	// Subcommands
+	cmd.AddCommand(NewCmdPlanFromCluster(out, options))
+	cmd.AddCommand(NewCmdPlanClone(in, out, options))
+	cmd.AddCommand(NewCmdPlanFromTemplate(out, options))
+
 	return cmd
 }
 
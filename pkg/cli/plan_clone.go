@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type planCloneOpts struct {
+	name string
+}
+
+// NewCmdPlanClone creates a new command for generating a plan file that is
+// configured like an existing plan file
+func NewCmdPlanClone(in io.Reader, out io.Writer, options *installOpts) *cobra.Command {
+	opts := &planCloneOpts{}
+	cmd := &cobra.Command{
+		Use:   "clone SOURCE_PLAN_FILE",
+		Short: "generate a plan file for a new cluster that is configured like an existing cluster",
+		Long: `Generate a plan file for a new cluster that is configured like the cluster of an existing plan file.
+
+The nodes, the master load balancer, the NFS servers, the etcd backup destination and the docker registry password
+of the existing cluster are replaced with ${VAR} placeholders, which must be defined in a values file or in the
+environment before the new plan file is used. The admin password of the new cluster is prompted for, or regenerated.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("the plan file of the existing cluster is required")
+			}
+			if opts.name == "" {
+				return fmt.Errorf("the --name flag is required")
+			}
+			source := &install.FilePlanner{File: args[0], ValuesFile: options.valuesFilename}
+			planner := &install.FilePlanner{File: options.planFilename}
+			return doPlanClone(in, out, source, planner, opts.name, options.planFilename)
+		},
+	}
+	cmd.Flags().StringVar(&opts.name, "name", "", "name of the new cluster")
+	return cmd
+}
+
+func doPlanClone(in io.Reader, out io.Writer, source install.Planner, planner install.Planner, name string, planFile string) error {
+	if planner.PlanExists() {
+		return fmt.Errorf("plan file %q already exists", planFile)
+	}
+	if !source.PlanExists() {
+		return fmt.Errorf("plan file of the existing cluster not found")
+	}
+	p, err := source.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file of the existing cluster: %v", err)
+	}
+	if p.Cluster.Name == name {
+		return fmt.Errorf("the name of the new cluster must be different from the name of the existing cluster")
+	}
+	clone, warnings, err := install.ClonePlan(*p, name)
+	if err != nil {
+		return err
+	}
+
+	// The credentials of the existing cluster are not reused
+	pw, err := util.PromptForText(in, out, "Admin password of the new cluster (leave blank to generate one)", clone.Cluster.AdminPassword)
+	if err != nil {
+		return fmt.Errorf("error reading admin password: %v", err)
+	}
+	clone.Cluster.AdminPassword = pw
+	if clone.DockerRegistry.Username != "" {
+		prompt := fmt.Sprintf("Password of the docker registry user %q (leave blank to set DOCKER_REGISTRY_PASSWORD later)", clone.DockerRegistry.Username)
+		pw, err := util.PromptForText(in, out, prompt, clone.DockerRegistry.Password)
+		if err != nil {
+			return fmt.Errorf("error reading docker registry password: %v", err)
+		}
+		clone.DockerRegistry.Password = pw
+	}
+
+	if err = planner.Write(clone); err != nil {
+		return fmt.Errorf("error writing plan file: %v", err)
+	}
+	fmt.Fprintf(out, "Wrote plan file for cluster %q to %q\n", name, planFile)
+	vars, err := install.PlanVariables(clone)
+	if err != nil {
+		return err
+	}
+	if len(vars) > 0 {
+		fmt.Fprintln(out, "Define the following variables in a values file (--values-file) or in the environment before using the plan file:")
+		for _, v := range vars {
+			fmt.Fprintf(out, "- %s\n", v)
+		}
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintln(out, "The following must be reviewed before using the plan file:")
+		for _, w := range warnings {
+			util.PrintColor(out, util.Orange, "- %s\n", w)
+		}
+	}
+	return nil
+}
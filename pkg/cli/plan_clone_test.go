@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestPlanClone(t *testing.T) {
+	tests := []struct {
+		name             string
+		in               string
+		targetExists     bool
+		shouldError      bool
+		expectedPassword string
+	}{
+		{
+			name:             "staging",
+			in:               "new-password\n",
+			expectedPassword: "new-password",
+		},
+		{
+			// the plan file of the new cluster is not overwritten
+			name:         "staging",
+			targetExists: true,
+			shouldError:  true,
+		},
+		{
+			name:        "prod",
+			shouldError: true,
+		},
+	}
+	for i, test := range tests {
+		source := &fakePlanner{
+			exists: true,
+			plan: &install.Plan{
+				Cluster: install.Cluster{Name: "prod", AdminPassword: "prod-password"},
+				Worker:  install.NodeGroup{ExpectedCount: 1, Nodes: []install.Node{{Host: "prod-worker", IP: "10.0.1.1"}}},
+			},
+		}
+		target := &fakePlanner{exists: test.targetExists}
+		out := &bytes.Buffer{}
+		err := doPlanClone(strings.NewReader(test.in), out, source, target, test.name, "staging.yaml")
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("test %d: expected an error, but didn't get one", i)
+			}
+			if target.plan != nil {
+				t.Errorf("test %d: expected the plan file not to be written", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if target.plan == nil {
+			t.Fatalf("test %d: expected the plan file to be written", i)
+		}
+		if target.plan.Cluster.Name != test.name || target.plan.Cluster.AdminPassword != test.expectedPassword {
+			t.Errorf("test %d: unexpected cluster: %+v", i, target.plan.Cluster)
+		}
+		if target.plan.Worker.Nodes[0].Host != "${NODE_1_HOST}" {
+			t.Errorf("test %d: expected the worker node to be a placeholder, but got %+v", i, target.plan.Worker.Nodes[0])
+		}
+		if !strings.Contains(out.String(), "- NODE_1_HOST") {
+			t.Errorf("test %d: expected the variables to be printed, but got %q", i, out.String())
+		}
+	}
+}
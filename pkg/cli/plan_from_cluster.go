@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type planFromClusterOpts struct {
+	kubeconfig  string
+	kubectlPath string
+}
+
+// NewCmdPlanFromCluster creates a new command for generating a plan file
+// from an existing cluster
+func NewCmdPlanFromCluster(out io.Writer, options *installOpts) *cobra.Command {
+	opts := &planFromClusterOpts{}
+	cmd := &cobra.Command{
+		Use:   "from-cluster",
+		Short: "generate a plan file by inspecting an existing cluster that was installed by KET",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			if opts.kubeconfig == "" {
+				return fmt.Errorf("the --kubeconfig flag is required")
+			}
+			kubectl := data.LocalKubectl{
+				KubectlPath: opts.kubectlPath,
+				Kubeconfig:  opts.kubeconfig,
+			}
+			planner := &install.FilePlanner{File: options.planFilename}
+			return doPlanFromCluster(out, planner, kubectl, options.planFilename)
+		},
+	}
+	cmd.Flags().StringVar(&opts.kubeconfig, "kubeconfig", "", "path to the kubeconfig file of the existing cluster")
+	cmd.Flags().StringVar(&opts.kubectlPath, "kubectl", defaultKubectlPath(), "path to the kubectl binary")
+	return cmd
+}
+
+func doPlanFromCluster(out io.Writer, planner install.Planner, kubectl data.LocalKubectl, planFile string) error {
+	if planner.PlanExists() {
+		return fmt.Errorf("plan file %q already exists", planFile)
+	}
+	util.PrintHeader(out, "Inspecting Cluster", '=')
+	server, err := kubectl.Server()
+	if err != nil {
+		return err
+	}
+	plan, warnings, err := install.BuildPlanFromCluster(kubectl, server)
+	if err != nil {
+		util.PrettyPrintErr(out, "Inspecting cluster at %q", server)
+		return fmt.Errorf("error inspecting cluster: %v", err)
+	}
+	util.PrettyPrintOk(out, "Inspecting cluster at %q", server)
+	if err = planner.Write(plan); err != nil {
+		return fmt.Errorf("error writing plan file: %v", err)
+	}
+	fmt.Fprintf(out, "Wrote plan file to %q\n", planFile)
+	if len(warnings) > 0 {
+		fmt.Fprintln(out, "The following must be reviewed before using the plan file:")
+		for _, w := range warnings {
+			util.PrintColor(out, util.Orange, "- %s\n", w)
+		}
+	}
+	return nil
+}
+
+// use the kubectl binary that is shipped with KET, if it exists
+func defaultKubectlPath() string {
+	if _, err := os.Stat("kubectl"); err == nil {
+		return "./kubectl"
+	}
+	return "kubectl"
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type planFromTemplateOpts struct {
+	templatesDir string
+	set          []string
+}
+
+// NewCmdPlanFromTemplate creates a new command for generating a plan file
+// from a cluster template
+func NewCmdPlanFromTemplate(out io.Writer, options *installOpts) *cobra.Command {
+	opts := &planFromTemplateOpts{}
+	cmd := &cobra.Command{
+		Use:   "from-template NAME",
+		Short: "generate a plan file from a cluster template",
+		Long: `Generate a plan file from a cluster template.
+
+The variables of the template are set with --set, then from the --values-file, then from the environment.
+Variables that are not set take the default value of the template.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("the name of the template is required")
+			}
+			store := install.TemplateStore{Dir: opts.templatesDir}
+			planner := &install.FilePlanner{File: options.planFilename}
+			return doPlanFromTemplate(out, store, planner, args[0], options.planFilename, options.valuesFilename, opts.set)
+		},
+	}
+	addTemplatesDirFlag(cmd.Flags(), &opts.templatesDir)
+	cmd.Flags().StringSliceVar(&opts.set, "set", []string{}, "values of the variables of the template, as VAR=value pairs separated by ','")
+	return cmd
+}
+
+func doPlanFromTemplate(out io.Writer, store install.TemplateStore, planner install.Planner, name, planFile, valuesFile string, set []string) error {
+	if planner.PlanExists() {
+		return fmt.Errorf("plan file %q already exists", planFile)
+	}
+	t, err := store.Get(name)
+	if err != nil {
+		return err
+	}
+	values := map[string]string{}
+	if valuesFile != "" {
+		d, err := ioutil.ReadFile(valuesFile)
+		if err != nil {
+			return fmt.Errorf("error reading values file: %v", err)
+		}
+		if err = yaml.Unmarshal(d, &values); err != nil {
+			return fmt.Errorf("error unmarshaling values file: %v", err)
+		}
+	}
+	for _, s := range set {
+		pair := strings.SplitN(s, "=", 2)
+		if len(pair) != 2 {
+			return fmt.Errorf("invalid value %q provided, must be VAR=value pair", s)
+		}
+		values[pair[0]] = pair[1]
+	}
+	p, err := t.Render(values)
+	if err != nil {
+		return err
+	}
+	if err = planner.Write(p); err != nil {
+		return fmt.Errorf("error writing plan file: %v", err)
+	}
+	util.PrettyPrintOk(out, "Wrote plan file generated from template %q to %q", name, planFile)
+	return nil
+}
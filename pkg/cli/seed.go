@@ -1,16 +1,20 @@
 package cli
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os/exec"
-	"strings"
+	"net"
+	"net/http"
+	"sync"
 
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/registry"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
 )
@@ -19,13 +23,21 @@ const seedRegistryLong = `
 Seed a registry with the container images required by KET during the installation
 or upgrade of your Kubernetes cluster.
 
-The docker command line client must be installed on this machine to be able 
-to push the images to the registry.
+The images are copied directly from their source registries to your registry,
+including all the platforms of multi-arch images. Images that are already
+present in your registry, with the same digest, are skipped unless the --force
+flag is used. Multiple images are copied in parallel.
 
-The location of the registry is obtained from the plan file by default. If you
-don't have a plan file, you can pass the location of the registry using the 
---server flag. The server specified through the flag takes precedence over the 
-one defined in the plan file.
+The location of the registry is obtained from the plan file by default, along
+with its credentials and certificate authority. If you don't have a plan file,
+you can pass the location of the registry using the --server flag. The server
+specified through the flag takes precedence over the one defined in the plan file.
+The --username and --password flags take precedence over the credentials of the
+plan file.
+
+Registries that use plain HTTP or a self-signed certificate must be seeded with
+the --insecure flag. As with the docker client, registries on the loopback
+interface (e.g. localhost:5000) are always treated as insecure.
 
 If you want to further control how your registry is seeded, or if you are only
 interested in the list of all images that can be used in a KET installation, you
@@ -37,8 +49,13 @@ const imageManifestFile = "./ansible/playbooks/group_vars/container_images.yaml"
 type seedRegistryOptions struct {
 	listOnly       bool
 	verbose        bool
+	force          bool
+	parallel       int
 	planFile       string
 	registryServer string
+	username       string
+	password       string
+	insecure       bool
 }
 
 type imageManifest struct {
@@ -48,6 +65,26 @@ type imageManifest struct {
 type image struct {
 	Name    string `yaml:"name"`
 	Version string `yaml:"version"`
+	// The image used on arm64 nodes, if the image is not multi-arch.
+	// The version defaults to the version of the amd64 image.
+	ARM64 *image `yaml:"arm64"`
+}
+
+// images returns all the images in the manifest, including the images that
+// are specific to an architecture
+func (im imageManifest) images() []image {
+	var images []image
+	for _, img := range im.OfficialImages {
+		images = append(images, img)
+		if img.ARM64 != nil {
+			arm64 := *img.ARM64
+			if arm64.Version == "" {
+				arm64.Version = img.Version
+			}
+			images = append(images, arm64)
+		}
+	}
+	return images
 }
 
 func (i image) String() string {
@@ -74,7 +111,12 @@ func NewCmdSeedRegistry(stdout, stderr io.Writer) *cobra.Command {
 	}
 	cmd.Flags().BoolVar(&options.listOnly, "list-only", false, "when true, the images will only be listed but not pushed to the registry")
 	cmd.Flags().BoolVar(&options.verbose, "verbose", false, "enable verbose logging")
+	cmd.Flags().BoolVar(&options.force, "force", false, "push the images even if they are already present in the registry")
+	cmd.Flags().IntVar(&options.parallel, "parallel", 4, "number of images to push in parallel")
 	cmd.Flags().StringVar(&options.registryServer, "server", "", "set to the location of the registry server, without the protocol (e.g. localhost:5000)")
+	cmd.Flags().StringVar(&options.username, "username", "", "username used to authenticate with the registry")
+	cmd.Flags().StringVar(&options.password, "password", "", "password used to authenticate with the registry")
+	cmd.Flags().BoolVar(&options.insecure, "insecure", false, "allow connecting to a registry that uses plain HTTP or an untrusted certificate")
 	addPlanFileFlag(cmd.Flags(), &options.planFile)
 	return cmd
 }
@@ -84,7 +126,7 @@ func doListImages(out io.Writer, options seedRegistryOptions, imageManifestFile
 	if err != nil {
 		return err
 	}
-	for _, img := range im.OfficialImages {
+	for _, img := range im.images() {
 		fmt.Fprintf(out, "%s\n", img)
 	}
 	return nil
@@ -93,16 +135,15 @@ func doListImages(out io.Writer, options seedRegistryOptions, imageManifestFile
 func doSeedRegistry(stdout, stderr io.Writer, options seedRegistryOptions, imageManifestFile string) error {
 	util.PrintHeader(stdout, "Seed Container Image Registry", '=')
 
-	// Validate that docker is available
-	if _, err := exec.LookPath("docker"); err != nil {
-		return errors.New("Did not find docker installed on this node. The docker CLI must be available for seeding the registry.")
+	if options.parallel <= 0 {
+		return errors.New("The number of images to push in parallel must be greater than zero")
 	}
 
 	// Figure out the registry we are to seed
 	// The registry specified through the command-line flag takes precedence
 	// over the one defined in the plan file.
-	server := options.registryServer
-	if server == "" {
+	target := &registry.Client{Server: options.registryServer}
+	if target.Server == "" {
 		// we need to get the server from the plan file
 		planner := install.FilePlanner{File: options.planFile}
 		if !planner.PlanExists() {
@@ -126,62 +167,180 @@ func doSeedRegistry(stdout, stderr io.Writer, options seedRegistryOptions, image
 			util.PrintValidationErrors(stdout, errs)
 			return errors.New("Invalid registry configuration found in plan file")
 		}
-		server = plan.DockerRegistry.Server
+		target.Server = plan.DockerRegistry.Server
+		target.Username = plan.DockerRegistry.Username
+		target.Password = plan.DockerRegistry.Password
+		if plan.DockerRegistry.CAPath != "" {
+			httpClient, err := httpClientWithCA(plan.DockerRegistry.CAPath)
+			if err != nil {
+				return err
+			}
+			target.HTTPClient = httpClient
+		}
 	}
 
+	if options.username != "" {
+		target.Username = options.username
+		target.Password = options.password
+	}
+	if err := pingRegistry(target, options.insecure || isLoopbackRegistry(target.Server)); err != nil {
+		util.PrettyPrintErr(stdout, "Connecting to registry %q", target.Server)
+		return err
+	}
+	util.PrettyPrintOk(stdout, "Connecting to registry %q", target.Server)
+
 	im, err := readImageManifest()
 	if err != nil {
 		return err
 	}
 
-	// Seed the registry with the images
-	n := len(im.OfficialImages)
-	i := 1
-	for _, img := range im.OfficialImages {
-		l := fmt.Sprintf("(%d/%d) Seeding %s ", i, n, img)
-		pad := 80 - len(l)
-		if pad < 0 {
-			pad = 0
-		}
-		fmt.Fprintf(stdout, l+strings.Repeat(" ", pad))
-		if err := seedImage(stdout, stderr, img, server, options.verbose); err != nil {
-			return fmt.Errorf("Error seeding image %q: %v", img, err)
+	// Seed the registry with the images, reporting the progress as each
+	// image is done
+	all := im.images()
+	images := make(chan image)
+	go func() {
+		for _, img := range all {
+			images <- img
 		}
-		util.PrintOkln(stdout)
-		i++
+		close(images)
+	}()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		done     int
+		skipped  int
+		failures []error
+		sources  = map[string]*registry.Client{}
+	)
+	n := len(all)
+	for i := 0; i < options.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for img := range images {
+				var log io.Writer
+				if options.verbose {
+					log = &prefixWriter{out: stdout, mu: &mu, prefix: img.String() + ": "}
+				}
+				server, repo := registry.ParseImageName(img.Name)
+				mu.Lock()
+				// reuse clients to avoid requesting the same tokens again
+				src, ok := sources[server]
+				if !ok {
+					src = &registry.Client{Server: server}
+					sources[server] = src
+				}
+				mu.Unlock()
+				res, err := registry.CopyImage(src, repo, target, img.Name, img.Version, options.force, log)
+
+				mu.Lock()
+				done++
+				switch {
+				case err != nil:
+					util.PrettyPrintErr(stdout, "(%d/%d) Seeding %s", done, n, img)
+					failures = append(failures, fmt.Errorf("Error seeding image %q: %v", img, err))
+				case res.Skipped:
+					skipped++
+					util.PrettyPrintSkipped(stdout, "(%d/%d) Seeding %s", done, n, img)
+				default:
+					util.PrettyPrintOk(stdout, "(%d/%d) Seeding %s", done, n, img)
+				}
+				mu.Unlock()
+			}
+		}()
 	}
+	wg.Wait()
 
-	util.PrintColor(stdout, util.Green, "\nThe registry %q was seeded successfully.\n", server)
+	// the errors of the copies go to stderr, like the output of the docker
+	// client used to
+	if len(failures) > 0 {
+		util.PrintValidationErrors(stderr, failures)
+		return fmt.Errorf("Failed to seed %d of %d images", len(failures), n)
+	}
+	util.PrintColor(stdout, util.Green, "\nThe registry %q was seeded successfully (%d images pushed, %d already present).\n", target.Server, n-skipped, skipped)
 	fmt.Fprintln(stdout)
 	return nil
 }
 
-func seedImage(stdout, stderr io.Writer, img image, registry string, verbose bool) error {
-	runDockerCmd := func(args ...string) error {
-		command := exec.Command("docker", args...)
-		command.Stderr = stderr
-		if verbose {
-			command.Stdout = stdout
-		}
-		return command.Run()
+// pingRegistry verifies that the registry can be reached with the client's
+// credentials. Insecure registries are tried over HTTPS without verifying
+// the certificate first, and then over plain HTTP, like the docker client does.
+func pingRegistry(c *registry.Client, insecure bool) error {
+	if !insecure {
+		return c.Ping()
 	}
-
-	// pull
-	if err := runDockerCmd("pull", img.String()); err != nil {
-		return err
+	httpsClient := c.HTTPClient
+	c.HTTPClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
 	}
-	// tag
-	privateImgTag := fmt.Sprintf("%s/%s", registry, img)
-	if err := runDockerCmd("tag", img.String(), privateImgTag); err != nil {
-		return err
+	err := c.Ping()
+	if err == nil {
+		return nil
 	}
-	// push
-	if err := runDockerCmd("push", privateImgTag); err != nil {
-		return err
+	c.HTTPClient = httpsClient
+	c.PlainHTTP = true
+	if httpErr := c.Ping(); httpErr != nil {
+		return fmt.Errorf("%v (over plain HTTP: %v)", err, httpErr)
 	}
 	return nil
 }
 
+// returns true if the registry is on the loopback interface, which the
+// docker client treats as an insecure registry
+func isLoopbackRegistry(server string) bool {
+	host := server
+	if h, _, err := net.SplitHostPort(server); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// returns an HTTP client that trusts the given certificate authority, in
+// addition to the system's
+func httpClientWithCA(caFile string) (*http.Client, error) {
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading registry CA: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("registry CA file %q does not contain any certificates", caFile)
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
+
+// prefixWriter prefixes the lines written by the parallel image copies,
+// so that the verbose output can be told apart
+type prefixWriter struct {
+	out    io.Writer
+	mu     *sync.Mutex
+	prefix string
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := fmt.Fprintf(w.out, "%s%s", w.prefix, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 func readImageManifest() (imageManifest, error) {
 	im := imageManifest{}
 	imBytes, err := ioutil.ReadFile(imageManifestFile)
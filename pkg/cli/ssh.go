@@ -5,8 +5,6 @@ import (
 	"io"
 	"strings"
 
-	"github.com/apprenda/kismatic/pkg/ssh"
-
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
@@ -82,7 +80,7 @@ func doSSH(out io.Writer, planner install.Planner, opts *sshOpts) error {
 		return fmt.Errorf("cannot validate SSH connection to node %q", opts.host)
 	}
 
-	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key)
+	client, err := con.SSHConfig.NewClient(con.Node.IP)
 	if err != nil {
 		return fmt.Errorf("error creating SSH client: %v", err)
 	}
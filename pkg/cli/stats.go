@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+type statsOpts struct {
+	runsDirectory string
+	outputFormat  string
+	nodes         int
+}
+
+// NewCmdStats returns the stats command
+func NewCmdStats(out io.Writer) *cobra.Command {
+	opts := &statsOpts{}
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "display the duration of previous installations and upgrades",
+		Long: `Display the duration of the operations recorded in the runs directory, grouped by operation and KET version.
+
+The duration of an operation on a given number of nodes is estimated from the previous runs of the operation.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doStats(out, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.runsDirectory, "runs-dir", "runs", "path to the directory where the runs of kismatic are recorded")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"json"|"prometheus")`)
+	cmd.Flags().IntVar(&opts.nodes, "nodes", 0, "estimate the duration of the operations on this number of nodes")
+	return cmd
+}
+
+func doStats(out io.Writer, opts *statsOpts) error {
+	records, err := install.ReadOperationHistory(opts.runsDirectory)
+	if err != nil {
+		return err
+	}
+	stats := install.AggregateOperationStats(records)
+	switch opts.outputFormat {
+	case "simple":
+		printStats(out, stats, opts.nodes)
+	case "json":
+		b, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling stats: %v", err)
+		}
+		fmt.Fprintln(out, string(b))
+	case "prometheus":
+		printPrometheusStats(out, stats)
+	default:
+		return fmt.Errorf("Output format %q is not supported", opts.outputFormat)
+	}
+	return nil
+}
+
+func printStats(out io.Writer, stats []install.OperationStats, nodes int) {
+	if len(stats) == 0 {
+		fmt.Fprintln(out, "No operations have been recorded")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	header := "OPERATION\tVERSION\tRUNS\tMEAN\tMAX\tPER NODE"
+	if nodes > 0 {
+		header += fmt.Sprintf("\tESTIMATE (%d NODES)", nodes)
+	}
+	fmt.Fprintln(w, header)
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s", s.Task, s.Version, s.Runs, seconds(s.MeanSeconds), seconds(s.MaxSeconds), seconds(s.SecondsPerNode))
+		if nodes > 0 {
+			fmt.Fprintf(w, "\t%s", s.Estimate(nodes)/time.Second*time.Second)
+		}
+		fmt.Fprintln(w)
+		for _, p := range s.Phases {
+			fmt.Fprintf(w, "  %s\t\t\t%s\t%s\t\n", p.Phase, seconds(p.MeanSeconds), seconds(p.MaxSeconds))
+		}
+	}
+	w.Flush()
+}
+
+// printPrometheusStats prints the stats in the Prometheus text format, so
+// that they can be collected by a node exporter textfile collector
+func printPrometheusStats(out io.Writer, stats []install.OperationStats) {
+	fmt.Fprintln(out, "# HELP kismatic_operation_runs Number of successful runs of the operation.")
+	fmt.Fprintln(out, "# TYPE kismatic_operation_runs gauge")
+	for _, s := range stats {
+		fmt.Fprintf(out, "kismatic_operation_runs{operation=%q,version=%q} %d\n", s.Task, s.Version, s.Runs)
+	}
+	fmt.Fprintln(out, "# HELP kismatic_operation_duration_seconds Mean duration of the successful runs of the operation.")
+	fmt.Fprintln(out, "# TYPE kismatic_operation_duration_seconds gauge")
+	for _, s := range stats {
+		fmt.Fprintf(out, "kismatic_operation_duration_seconds{operation=%q,version=%q} %g\n", s.Task, s.Version, s.MeanSeconds)
+	}
+	fmt.Fprintln(out, "# HELP kismatic_operation_duration_seconds_per_node Increase of the duration of the operation for each node.")
+	fmt.Fprintln(out, "# TYPE kismatic_operation_duration_seconds_per_node gauge")
+	for _, s := range stats {
+		fmt.Fprintf(out, "kismatic_operation_duration_seconds_per_node{operation=%q,version=%q} %g\n", s.Task, s.Version, s.SecondsPerNode)
+	}
+	fmt.Fprintln(out, "# HELP kismatic_operation_phase_duration_seconds Mean duration of the phase of the operation.")
+	fmt.Fprintln(out, "# TYPE kismatic_operation_phase_duration_seconds gauge")
+	for _, s := range stats {
+		for _, p := range s.Phases {
+			fmt.Fprintf(out, "kismatic_operation_phase_duration_seconds{operation=%q,version=%q,phase=%q} %g\n", s.Task, s.Version, p.Phase, p.MeanSeconds)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s) * time.Second
+}
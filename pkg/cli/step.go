@@ -49,7 +49,7 @@ func NewCmdStep(out io.Writer, opts *installOpts) *cobra.Command {
 			}
 			stepCmd.task = args[0]
 			stepCmd.planFile = opts.planFilename
-			stepCmd.planner = &install.FilePlanner{File: stepCmd.planFile}
+			stepCmd.planner = &install.FilePlanner{File: stepCmd.planFile, ValuesFile: opts.valuesFilename}
 			stepCmd.executor = executor
 			return stepCmd.run()
 		},
@@ -61,7 +61,7 @@ func NewCmdStep(out io.Writer, opts *installOpts) *cobra.Command {
 	return cmd
 }
 
-func (c stepCmd) run() error {
+func (c stepCmd) run() (err error) {
 	valOpts := &validateOpts{
 		planFile:           c.planFile,
 		verbose:            c.verbose,
@@ -76,6 +76,15 @@ func (c stepCmd) run() error {
 	if err != nil {
 		return fmt.Errorf("error reading plan file: %v", err)
 	}
+	release, err := fetchAssets(c.out, plan, c.generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
 	util.PrintHeader(c.out, "Running Task", '=')
 	if err := c.executor.RunPlay(c.task, plan); err != nil {
 		return err
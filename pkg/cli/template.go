@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func addTemplatesDirFlag(flagSet *pflag.FlagSet, p *string) {
+	flagSet.StringVar(p, "templates-dir", "templates", "path to the directory where the cluster templates are stored")
+}
+
+// NewCmdTemplate creates a new command for managing cluster templates
+func NewCmdTemplate(out io.Writer, options *installOpts) *cobra.Command {
+	var templatesDir string
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "manage the cluster templates that plan files are generated from",
+		Long: `Manage the cluster templates that plan files are generated from.
+
+A cluster template is a plan file with ${VAR} placeholders for the values that differ between the clusters
+generated from it, such as the name of the cluster and its nodes. Use "install plan from-template" to
+generate a plan file from a template.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	addTemplatesDirFlag(cmd.PersistentFlags(), &templatesDir)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "list the cluster templates",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doTemplateList(out, install.TemplateStore{Dir: templatesDir})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show NAME",
+		Short: "show the variables of a cluster template, and the template",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("the name of the template is required")
+			}
+			return doTemplateShow(out, install.TemplateStore{Dir: templatesDir}, args[0])
+		},
+	})
+	var defaultsFile string
+	create := &cobra.Command{
+		Use:   "create NAME",
+		Short: "create a cluster template from the plan file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("the name of the template is required")
+			}
+			return doTemplateCreate(out, install.TemplateStore{Dir: templatesDir}, args[0], options.planFilename, defaultsFile)
+		},
+	}
+	create.Flags().StringVar(&defaultsFile, "defaults", "", "path to a YAML file with the default values of the variables of the template")
+	cmd.AddCommand(create)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete NAME",
+		Short: "delete a cluster template",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("the name of the template is required")
+			}
+			return doTemplateDelete(out, install.TemplateStore{Dir: templatesDir}, args[0])
+		},
+	})
+	return cmd
+}
+
+func doTemplateList(out io.Writer, store install.TemplateStore) error {
+	templates, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		fmt.Fprintf(out, "No templates found in %q\n", store.Dir)
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVARIABLES")
+	for _, t := range templates {
+		fmt.Fprintf(w, "%s\t%s\n", t.Name, strings.Join(t.Variables, ","))
+	}
+	return w.Flush()
+}
+
+func doTemplateShow(out io.Writer, store install.TemplateStore, name string) error {
+	t, err := store.Get(name)
+	if err != nil {
+		return err
+	}
+	d, err := ioutil.ReadFile(t.File)
+	if err != nil {
+		return fmt.Errorf("error reading template: %v", err)
+	}
+	fmt.Fprintf(out, "Template %q, stored in %q\n", t.Name, t.File)
+	fmt.Fprintln(out, "Variables:")
+	for _, v := range t.Variables {
+		if def, ok := t.Defaults[v]; ok {
+			fmt.Fprintf(out, "- %s (default %q)\n", v, def)
+			continue
+		}
+		fmt.Fprintf(out, "- %s\n", v)
+	}
+	fmt.Fprintln(out)
+	_, err = out.Write(d)
+	return err
+}
+
+func doTemplateCreate(out io.Writer, store install.TemplateStore, name, planFile, defaultsFile string) error {
+	defaults := map[string]string{}
+	if defaultsFile != "" {
+		d, err := ioutil.ReadFile(defaultsFile)
+		if err != nil {
+			return fmt.Errorf("error reading defaults file: %v", err)
+		}
+		if err = yaml.Unmarshal(d, &defaults); err != nil {
+			return fmt.Errorf("error unmarshaling defaults file: %v", err)
+		}
+	}
+	t, err := store.Create(name, planFile, defaults)
+	if err != nil {
+		return err
+	}
+	util.PrettyPrintOk(out, "Created template %q from %q with variables %s", t.Name, planFile, strings.Join(t.Variables, ","))
+	return nil
+}
+
+func doTemplateDelete(out io.Writer, store install.TemplateStore, name string) error {
+	if err := store.Delete(name); err != nil {
+		return err
+	}
+	util.PrettyPrintOk(out, "Deleted template %q", name)
+	return nil
+}
@@ -6,25 +6,32 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/data"
 	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/trace"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
 )
 
 type upgradeOpts struct {
 	generatedAssetsDir string
+	runsDir            string
 	verbose            bool
 	outputFormat       string
 	skipPreflight      bool
 	ignoreSafetyChecks bool
 	online             bool
 	planFile           string
+	valuesFile         string
 	restartServices    bool
 	partialAllowed     bool
 	maxParallelWorkers int
 	dryRun             bool
+	force              bool
+	canaryWorkers      int
+	canarySoak         time.Duration
 }
 
 // NewCmdUpgrade returns the upgrade command
@@ -44,6 +51,12 @@ Nodes in the cluster are upgraded in the following order:
 1. Etcd nodes
 2. Master nodes
 3. Worker nodes (regardless of specialization)
+
+A cluster that is more than one minor version behind is first upgraded with the last patch release
+of each of the minor versions in between, in order. These releases are downloaded from GitHub.
+
+When --canary-workers is set, only that number of worker nodes is upgraded at first. The rest of
+the worker nodes are upgraded once the cluster is still healthy after the --canary-soak period.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
@@ -57,11 +70,17 @@ Nodes in the cluster are upgraded in the following order:
 	cmd.PersistentFlags().BoolVar(&opts.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.PersistentFlags().BoolVar(&opts.partialAllowed, "partial-ok", false, "allow the upgrade of ready nodes, and skip nodes that have been deemed unready for upgrade")
 	cmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "simulate the upgrade, but don't actually upgrade the cluster")
+	cmd.PersistentFlags().BoolVar(&opts.force, "force", false, "upgrade the cluster even if the upgrade readiness report found problems, or roll back or upgrade the add-ons even if their checks fail")
+	cmd.PersistentFlags().IntVar(&opts.canaryWorkers, "canary-workers", 0, "the number of worker nodes to upgrade before the rest of the worker nodes, which are only upgraded if the cluster is healthy after the soak period")
+	cmd.PersistentFlags().DurationVar(&opts.canarySoak, "canary-soak", 5*time.Minute, "the time to wait after upgrading the canary worker nodes, before verifying the health of the cluster")
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFile)
+	addValuesFileFlag(cmd.PersistentFlags(), &opts.valuesFile)
 
 	// Subcommands
 	cmd.AddCommand(NewCmdUpgradeOffline(in, out, &opts))
 	cmd.AddCommand(NewCmdUpgradeOnline(in, out, &opts))
+	cmd.AddCommand(NewCmdUpgradeRollback(out, &opts))
+	cmd.AddCommand(NewCmdUpgradeAddOns(out, &opts))
 	return cmd
 }
 
@@ -112,18 +131,33 @@ before any changes are applied.
 }
 
 func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
+	tracer := trace.FromEnvironment("upgrade")
+	err := upgrade(in, out, opts, tracer)
+	finishTrace(out, tracer, err)
+	return err
+}
+
+func upgrade(in io.Reader, out io.Writer, opts *upgradeOpts, tracer *trace.Tracer) (err error) {
 	if opts.maxParallelWorkers < 1 {
 		return fmt.Errorf("max-parallel-workers must be greater or equal to 1, got: %d", opts.maxParallelWorkers)
 	}
+	if opts.canaryWorkers < 0 {
+		return fmt.Errorf("canary-workers must be greater or equal to 0, got: %d", opts.canaryWorkers)
+	}
+	if opts.canarySoak < 0 {
+		return fmt.Errorf("canary-soak must not be negative, got: %s", opts.canarySoak)
+	}
 
 	planFile := opts.planFile
-	planner := install.FilePlanner{File: planFile}
+	planner := install.FilePlanner{File: planFile, ValuesFile: opts.valuesFile}
 	executorOpts := install.ExecutorOptions{
 		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		RunsDirectory:            opts.runsDir,
 		RestartServices:          opts.restartServices,
 		OutputFormat:             opts.outputFormat,
 		Verbose:                  opts.verbose,
 		DryRun:                   opts.dryRun,
+		Tracer:                   tracer,
 	}
 	executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
 	if err != nil {
@@ -148,9 +182,11 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 		util.PrettyPrintErr(out, "Reading plan file")
 		return fmt.Errorf("error reading plan file %q: %v", planFile, err)
 	}
+	printPlanMigrations(out, plan)
 
 	// Validate the plan file before we do anything
-	if err = validatePlan(out, plan); err != nil {
+	// The warnings don't stop the upgrade of a cluster that already exists
+	if err = validateExistingPlan(out, plan); err != nil {
 		return err
 	}
 
@@ -158,6 +194,16 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 		return err
 	}
 
+	release, err := fetchAssets(out, plan, opts.generatedAssetsDir, opts.runsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+
 	// Generate new certs, or use existing ones. Always ensure that the CA exists.
 	if err = executor.GenerateCertificates(plan, true); err != nil {
 		return err
@@ -181,6 +227,26 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 		return fmt.Errorf("error listing cluster versions: %v", err)
 	}
 
+	// Clusters that are more than one minor version behind are upgraded with
+	// each of the minor versions in between first
+	path, err := install.UpgradePath(cv.EarliestVersion)
+	if err != nil {
+		return err
+	}
+	if len(path) > 0 {
+		if opts.partialAllowed {
+			return fmt.Errorf("the cluster cannot be partially upgraded from KET v%s, since it is upgraded with KET %s first", cv.EarliestVersion, strings.Join(path, ", then "))
+		}
+		if err = upgradeAlongPath(in, out, plan, *opts, path, install.DefaultKismaticReleases, runKismaticRelease, install.ListVersions); err != nil {
+			return err
+		}
+		if !opts.dryRun {
+			if cv, err = install.ListVersions(plan); err != nil {
+				return fmt.Errorf("error listing cluster versions: %v", err)
+			}
+		}
+	}
+
 	// Figure out which nodes to upgrade
 	var toUpgrade []install.ListableNode
 	var toSkip []install.ListableNode
@@ -201,11 +267,28 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 		fmt.Fprintln(out)
 	}
 
+	var skipped []string
+	if len(toUpgrade) > 0 {
+		if toUpgrade, skipped, err = upgradeReadiness(out, *plan, *opts, toUpgrade); err != nil {
+			return err
+		}
+	}
+
+	// Record the state of the cluster, so that the control plane can be
+	// rolled back if the upgrade fails
+	if len(toUpgrade) > 0 && !opts.dryRun {
+		var point *install.RollbackPoint
+		if point, err = createRollbackPoint(out, *plan, cv, executor, opts.generatedAssetsDir); err != nil {
+			return err
+		}
+		defer func() { recordUpgradeOutcome(out, point, opts.generatedAssetsDir, err) }()
+	}
+
 	// Print message if there's no work to do
 	if len(toUpgrade) == 0 {
 		fmt.Fprintln(out, "All nodes are at the target version. Skipping node upgrades.")
 	} else {
-		if err = upgradeNodes(in, out, *plan, *opts, toUpgrade, executor, preflightExec); err != nil {
+		if err = upgradeNodes(in, out, *plan, *opts, toUpgrade, skipped, executor, preflightExec); err != nil {
 			return err
 		}
 	}
@@ -243,7 +326,79 @@ without the "--partial-ok" flag to perform a full upgrade.
 	return nil
 }
 
-func upgradeNodes(in io.Reader, out io.Writer, plan install.Plan, opts upgradeOpts, nodesNeedUpgrade []install.ListableNode, executor install.Executor, preflightExec install.PreFlightExecutor) error {
+// upgradeReadiness runs the upgrade readiness checks and persists the report.
+// The upgrade is blocked when the report is not clean, unless it is forced.
+// A partial upgrade skips the workers that are not ready to be upgraded
+// instead, and the nodes that remain to be upgraded are returned.
+func upgradeReadiness(out io.Writer, plan install.Plan, opts upgradeOpts, toUpgrade []install.ListableNode) ([]install.ListableNode, []string, error) {
+	util.PrintHeader(out, "Upgrade Readiness Report", '=')
+	// Use the first master node for running kubectl
+	client, err := plan.GetSSHClient(plan.Master.Nodes[0].Host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting SSH client: %v", err)
+	}
+	kubeClient := data.RemoteKubectl{SSHClient: client}
+	diskSpace := func(n install.Node) (uint64, error) {
+		return install.NodeAvailableDiskSpace(plan, n)
+	}
+	report := install.UpgradeReadiness(plan, opts.online, kubeClient, diskSpace)
+	for _, c := range report.Checks {
+		if c.Success {
+			util.PrettyPrintOk(out, "%s: %s", c.Name, c.Subject)
+			continue
+		}
+		util.PrettyPrintErr(out, "%s: %s", c.Name, c.Subject)
+		fmt.Fprintln(out, "-", c.Message)
+	}
+	file, err := install.WriteUpgradeReadinessReport(report, opts.generatedAssetsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(out, "The upgrade readiness report was written to %q\n", file)
+	var unready []string
+	if opts.partialAllowed {
+		controlPlane := map[string]bool{}
+		for _, n := range plan.Etcd.Nodes {
+			controlPlane[n.Host] = true
+		}
+		for _, n := range plan.Master.Nodes {
+			controlPlane[n.Host] = true
+		}
+		for _, host := range report.UnreadyNodes() {
+			if !controlPlane[host] {
+				unready = append(unready, host)
+			}
+		}
+		report = report.ExcludeNodes(unready)
+		var ready []install.ListableNode
+		for _, n := range toUpgrade {
+			skip := false
+			for _, host := range unready {
+				if n.Node.Host == host {
+					skip = true
+				}
+			}
+			if skip {
+				util.PrettyPrintWarn(out, "Skipping node %q, which is not ready to be upgraded", n.Node.Host)
+				continue
+			}
+			ready = append(ready, n)
+		}
+		toUpgrade = ready
+	}
+	if report.Clean() {
+		return toUpgrade, unready, nil
+	}
+	if opts.force {
+		util.PrettyPrintWarn(out, "\nIgnoring the problems found by the upgrade readiness report and continuing with the upgrade")
+		return toUpgrade, unready, nil
+	}
+	return nil, nil, fmt.Errorf("the upgrade readiness report found %d problems. Use --force to upgrade anyway", len(report.Failures()))
+}
+
+// upgradeNodes upgrades the nodes that need it. The skipped nodes were not
+// ready to be upgraded, and are not checked by the canary health gate.
+func upgradeNodes(in io.Reader, out io.Writer, plan install.Plan, opts upgradeOpts, nodesNeedUpgrade []install.ListableNode, skipped []string, executor install.Executor, preflightExec install.PreFlightExecutor) error {
 	// Run safety checks if doing an online upgrade
 	unsafeNodes := []install.ListableNode{}
 	if opts.online {
@@ -364,9 +519,48 @@ func upgradeNodes(in io.Reader, out io.Writer, plan install.Plan, opts upgradeOp
 		}
 	}
 
+	// Upgrade the canary worker nodes first, if any
+	if opts.canaryWorkers > 0 {
+		canary, rest := install.CanaryUpgrade(toUpgrade, opts.canaryWorkers)
+		if len(rest) > 0 {
+			if err := executor.UpgradeNodes(plan, canary, opts.online, opts.maxParallelWorkers); err != nil {
+				return fmt.Errorf("Failed to upgrade nodes: %v", err)
+			}
+			if err := verifyCanaryUpgrade(out, plan, opts, skipped); err != nil {
+				return fmt.Errorf("The remaining %d nodes were not upgraded: %v", len(rest), err)
+			}
+			toUpgrade = rest
+		}
+	}
+
 	// Run the upgrade on the nodes that need it
 	if err := executor.UpgradeNodes(plan, toUpgrade, opts.online, opts.maxParallelWorkers); err != nil {
 		return fmt.Errorf("Failed to upgrade nodes: %v", err)
 	}
 	return nil
 }
+
+// verifyCanaryUpgrade waits for the soak period, and verifies that the
+// cluster is healthy after the canary worker nodes have been upgraded
+func verifyCanaryUpgrade(out io.Writer, plan install.Plan, opts upgradeOpts, skipped []string) error {
+	util.PrintHeader(out, "Verify Canary Upgrade", '=')
+	if opts.dryRun {
+		util.PrettyPrintSkipped(out, "Soak period and cluster health (dry run)")
+		return nil
+	}
+	util.PrettyPrint(out, "Waiting %s before verifying the health of the cluster", opts.canarySoak)
+	time.Sleep(opts.canarySoak)
+	util.PrintOkln(out)
+	client, err := plan.GetSSHClient(plan.Master.Nodes[0].Host)
+	if err != nil {
+		return fmt.Errorf("error getting SSH client: %v", err)
+	}
+	util.PrettyPrint(out, "Cluster health")
+	if err := install.VerifyCanaryHealth(plan, data.RemoteKubectl{SSHClient: client}, skipped); err != nil {
+		util.PrintError(out)
+		fmt.Fprintln(out)
+		return err
+	}
+	util.PrintOkln(out)
+	return nil
+}
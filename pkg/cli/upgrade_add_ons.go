@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdUpgradeAddOns returns the command for upgrading the add-ons of the
+// cluster without upgrading Kubernetes
+func NewCmdUpgradeAddOns(out io.Writer, opts *upgradeOpts) *cobra.Command {
+	var addOns []string
+	cmd := cobra.Command{
+		Use:   "add-ons",
+		Short: "Upgrade the add-ons of your Kubernetes cluster, without upgrading Kubernetes",
+		Long: fmt.Sprintf(`Upgrade the add-ons of your Kubernetes cluster to the versions bundled with this version of
+Kismatic, without upgrading the control plane or the nodes.
+
+The add-ons that can be upgraded are %s. All the add-ons that are enabled in the
+plan file are upgraded, unless a subset is selected with --add-ons.
+
+The add-ons are checked against the version of Kubernetes that the cluster is running. Use --force to
+upgrade add-ons that are not compatible with it.
+`, strings.Join(install.UpgradableAddOns(), ", ")),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			planner := &install.FilePlanner{File: opts.planFile, ValuesFile: opts.valuesFile}
+			executorOpts := install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				RestartServices:          opts.restartServices,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+				DryRun:                   opts.dryRun,
+			}
+			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			if err != nil {
+				return err
+			}
+			return doUpgradeAddOns(out, planner, executor, masterKubeletVersion, opts.generatedAssetsDir, opts.planFile, addOns, opts.force)
+		},
+	}
+	cmd.Flags().StringSliceVar(&addOns, "add-ons", []string{}, fmt.Sprintf("comma-separated list of the add-ons to upgrade, from %s", strings.Join(install.UpgradableAddOns(), ",")))
+	return &cmd
+}
+
+func doUpgradeAddOns(out io.Writer, planner install.Planner, executor install.Executor, kubernetesVersion func(install.Plan) string, generatedAssetsDir, planFile string, addOns []string, force bool) (err error) {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planFile}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	release, err := fetchAssets(out, plan, generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	util.PrintHeader(out, "Add-On Version Skew", '=')
+	version := kubernetesVersion(*plan)
+	if version == "" {
+		if !force {
+			return fmt.Errorf("could not determine the version of Kubernetes of the cluster. Use --force to upgrade the add-ons anyway")
+		}
+		util.PrettyPrintWarn(out, "Could not determine the version of Kubernetes of the cluster")
+	} else {
+		errs := install.AddOnVersionSkew(*plan, version, addOns)
+		for _, err := range errs {
+			util.PrettyPrintErr(out, "%v", err)
+		}
+		if len(errs) > 0 && !force {
+			return fmt.Errorf("the add-ons are not compatible with Kubernetes %s. Upgrade the cluster with \"kismatic upgrade\", or use --force to upgrade the add-ons anyway", version)
+		}
+		if len(errs) == 0 {
+			util.PrettyPrintOk(out, "The add-ons are compatible with Kubernetes %s", version)
+		}
+	}
+
+	util.PrintHeader(out, "Upgrade: Add-Ons", '=')
+	upgraded, err := executor.UpgradeAddOns(*plan, addOns)
+	if err != nil {
+		return fmt.Errorf("error upgrading the add-ons: %v", err)
+	}
+	if len(upgraded) == 0 {
+		fmt.Fprintln(out, "No add-ons are enabled in the plan file.")
+		return nil
+	}
+	util.PrettyPrintOk(out, "Upgraded the add-ons %s", strings.Join(upgraded, ", "))
+	return nil
+}
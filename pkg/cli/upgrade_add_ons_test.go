@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestUpgradeAddOns(t *testing.T) {
+	tests := []struct {
+		name              string
+		planExists        bool
+		kubernetesVersion string
+		force             bool
+		execErr           error
+		expectErr         bool
+	}{
+		{
+			name:              "compatible",
+			planExists:        true,
+			kubernetesVersion: "v1.8.4",
+		},
+		{
+			name:              "plan file not found",
+			kubernetesVersion: "v1.8.4",
+			expectErr:         true,
+		},
+		{
+			name:              "not compatible",
+			planExists:        true,
+			kubernetesVersion: "v1.5.7",
+			expectErr:         true,
+		},
+		{
+			name:              "not compatible forced",
+			planExists:        true,
+			kubernetesVersion: "v1.5.7",
+			force:             true,
+		},
+		{
+			name:       "unknown kubernetes version",
+			planExists: true,
+			expectErr:  true,
+		},
+		{
+			name:       "unknown kubernetes version forced",
+			planExists: true,
+			force:      true,
+		},
+		{
+			name:              "upgrade fails",
+			planExists:        true,
+			kubernetesVersion: "v1.8.4",
+			execErr:           errors.New("add-on not enabled"),
+			expectErr:         true,
+		},
+	}
+	for _, test := range tests {
+		plan := &install.Plan{}
+		plan.AddOns.CNI = &install.CNI{Provider: "calico"}
+		planner := &fakePlanner{exists: test.planExists, plan: plan}
+		kubernetesVersion := func(install.Plan) string { return test.kubernetesVersion }
+		err := doUpgradeAddOns(&bytes.Buffer{}, planner, &fakeExecutor{err: test.execErr}, kubernetesVersion, "generated", "kismatic-cluster.yaml", []string{"cni"}, test.force)
+		if test.expectErr && err == nil {
+			t.Errorf("%s: expected an error, but didn't get one", test.name)
+		}
+		if !test.expectErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/blang/semver"
+)
+
+// kismaticReleases lists and downloads the releases of Kismatic
+type kismaticReleases interface {
+	Versions() ([]semver.Version, error)
+	Download(v semver.Version, dir string) (string, error)
+}
+
+// runUpgradeHop runs the kismatic binary of a downloaded release, in the
+// directory of the release, with the given arguments
+type runUpgradeHop func(in io.Reader, out io.Writer, releaseDir string, args []string) error
+
+func runKismaticRelease(in io.Reader, out io.Writer, releaseDir string, args []string) error {
+	cmd := exec.Command(filepath.Join(releaseDir, "kismatic"), args...)
+	cmd.Dir = releaseDir
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// upgradeAlongPath upgrades the cluster with the last patch release of each
+// of the minor versions of the upgrade path, in order. Each release is
+// downloaded and runs the same kind of upgrade against the plan file and the
+// generated assets. The upgrades that complete are recorded in a checkpoint,
+// and the cluster must be at the version of the release once it completes,
+// so that an upgrade path that stops can be continued by running the upgrade
+// again.
+func upgradeAlongPath(in io.Reader, out io.Writer, plan *install.Plan, opts upgradeOpts, path []string, releases kismaticReleases, run runUpgradeHop, listVersions func(*install.Plan) (install.ClusterVersion, error)) error {
+	util.PrintHeader(out, "Upgrade Path", '=')
+	versions, err := releases.Versions()
+	if err != nil {
+		util.PrettyPrintErr(out, "Finding the releases of KET %s", strings.Join(path, ", "))
+		return err
+	}
+	hops, err := install.LatestPatchReleases(path, versions)
+	if err != nil {
+		util.PrettyPrintErr(out, "Finding the releases of KET %s", strings.Join(path, ", "))
+		return err
+	}
+	for _, v := range hops {
+		util.PrettyPrintOk(out, "- KET v%s", v)
+	}
+	util.PrettyPrintOk(out, "- KET v%s", install.KismaticVersion)
+	if opts.dryRun {
+		fmt.Fprintln(out, "The cluster would be upgraded with each of the releases above, in order.")
+		return nil
+	}
+
+	checkpoint, err := install.ReadUpgradePathCheckpoint(opts.generatedAssetsDir)
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil || checkpoint.TargetVersion != install.KismaticVersion.String() {
+		checkpoint = &install.UpgradePathCheckpoint{TargetVersion: install.KismaticVersion.String()}
+	}
+	for _, hop := range checkpoint.CompletedHops {
+		util.PrettyPrintOk(out, "The cluster was upgraded with KET v%s on %s", hop.Version, hop.Completed.Format(time.RFC1123))
+	}
+
+	generatedAssetsDir, err := filepath.Abs(opts.generatedAssetsDir)
+	if err != nil {
+		return fmt.Errorf("error getting the absolute path of %q: %v", opts.generatedAssetsDir, err)
+	}
+	dir, err := ioutil.TempDir("", "kismatic-upgrade-path")
+	if err != nil {
+		return fmt.Errorf("error creating directory for the releases: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, v := range hops {
+		util.PrintHeader(out, fmt.Sprintf("Upgrade Path: KET v%s (%d/%d)", v, i+1, len(hops)), '=')
+		releaseDir, err := releases.Download(v, dir)
+		if err != nil {
+			util.PrettyPrintErr(out, "Downloading KET v%s", v)
+			return err
+		}
+		util.PrettyPrintOk(out, "Downloading KET v%s", v)
+
+		// The release reads the plan that was rendered and migrated by this
+		// version, since older releases don't read plan templates, plans in
+		// other formats, or the stacked etcd topology
+		fp := install.FilePlanner{File: filepath.Join(releaseDir, "kismatic-cluster.yaml")}
+		if err = fp.Write(plan.WithEtcdNodes()); err != nil {
+			return fmt.Errorf("error writing the plan file of KET v%s: %v", v, err)
+		}
+		if err = run(in, out, releaseDir, upgradeHopArgs(opts, fp.File, generatedAssetsDir)); err != nil {
+			return fmt.Errorf("error upgrading the cluster with KET v%s: %v", v, err)
+		}
+
+		cv, err := listVersions(plan)
+		if err != nil {
+			return fmt.Errorf("error listing cluster versions: %v", err)
+		}
+		if cv.EarliestVersion.LT(v) {
+			return fmt.Errorf("the cluster is at KET v%s after the upgrade with KET v%s", cv.EarliestVersion, v)
+		}
+		checkpoint.CompletedHops = append(checkpoint.CompletedHops, install.UpgradeHop{Version: v.String(), Completed: time.Now()})
+		if err = install.WriteUpgradePathCheckpoint(*checkpoint, opts.generatedAssetsDir); err != nil {
+			return err
+		}
+		util.PrettyPrintOk(out, "Upgraded the cluster with KET v%s", v)
+	}
+	return nil
+}
+
+// upgradeHopArgs returns the arguments of the upgrade that a release runs.
+// Each release upgrades every node and the cluster services, so partial
+// upgrades are not passed on.
+func upgradeHopArgs(opts upgradeOpts, planFile, generatedAssetsDir string) []string {
+	args := []string{"upgrade", "offline"}
+	if opts.online {
+		args[1] = "online"
+	}
+	args = append(args, "--plan-file", planFile, "--generated-assets-dir", generatedAssetsDir, "-o", opts.outputFormat)
+	if opts.verbose {
+		args = append(args, "--verbose")
+	}
+	if opts.skipPreflight {
+		args = append(args, "--skip-preflight")
+	}
+	if opts.restartServices {
+		args = append(args, "--restart-services")
+	}
+	if opts.online && opts.ignoreSafetyChecks {
+		args = append(args, "--ignore-safety-checks")
+	}
+	if !opts.online {
+		args = append(args, "--max-parallel-workers", strconv.Itoa(opts.maxParallelWorkers))
+	}
+	return args
+}
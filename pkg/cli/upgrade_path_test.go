@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/blang/semver"
+)
+
+type fakeKismaticReleases struct {
+	versions   []semver.Version
+	downloaded []string
+}
+
+func (r *fakeKismaticReleases) Versions() ([]semver.Version, error) {
+	return r.versions, nil
+}
+
+func (r *fakeKismaticReleases) Download(v semver.Version, dir string) (string, error) {
+	r.downloaded = append(r.downloaded, v.String())
+	releaseDir := filepath.Join(dir, "v"+v.String())
+	return releaseDir, os.MkdirAll(releaseDir, 0700)
+}
+
+func TestUpgradeAlongPath(t *testing.T) {
+	released := []semver.Version{semver.MustParse("1.4.0"), semver.MustParse("1.4.2"), semver.MustParse("1.5.1")}
+	tests := []struct {
+		name              string
+		dryRun            bool
+		runErr            error
+		stuckAt           string
+		expectErr         bool
+		expectRuns        []string
+		expectCheckpoints []string
+	}{
+		{
+			name:              "upgrade path",
+			expectRuns:        []string{"1.4.2", "1.5.1"},
+			expectCheckpoints: []string{"1.4.2", "1.5.1"},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+		},
+		{
+			name:       "release fails",
+			runErr:     errors.New("exit status 1"),
+			expectErr:  true,
+			expectRuns: []string{"1.4.2"},
+		},
+		{
+			name:              "cluster not upgraded by a release",
+			stuckAt:           "1.4.2",
+			expectErr:         true,
+			expectRuns:        []string{"1.4.2", "1.5.1"},
+			expectCheckpoints: []string{"1.4.2"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "upgrade-path")
+			if err != nil {
+				t.Fatalf("error creating temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			releases := &fakeKismaticReleases{versions: released}
+			version := semver.MustParse("1.3.0")
+			var runs []string
+			run := func(in io.Reader, out io.Writer, releaseDir string, args []string) error {
+				if _, err := os.Stat(filepath.Join(releaseDir, "kismatic-cluster.yaml")); err != nil {
+					t.Errorf("the plan file was not written for the release: %v", err)
+				}
+				v := semver.MustParse(filepath.Base(releaseDir)[1:])
+				runs = append(runs, v.String())
+				if test.runErr != nil {
+					return test.runErr
+				}
+				if test.stuckAt == "" || version.LT(semver.MustParse(test.stuckAt)) {
+					version = v
+				}
+				return nil
+			}
+			listVersions := func(*install.Plan) (install.ClusterVersion, error) {
+				return install.ClusterVersion{EarliestVersion: version}, nil
+			}
+			opts := upgradeOpts{generatedAssetsDir: dir, dryRun: test.dryRun, outputFormat: "simple", maxParallelWorkers: 1}
+			err = upgradeAlongPath(nil, &bytes.Buffer{}, &install.Plan{}, opts, []string{"v1.4", "v1.5"}, releases, run, listVersions)
+			if (err != nil) != test.expectErr {
+				t.Errorf("expected error to be %v, but got %v", test.expectErr, err)
+			}
+			if !reflect.DeepEqual(runs, test.expectRuns) {
+				t.Errorf("expected the releases %v to run, but got %v", test.expectRuns, runs)
+			}
+			checkpoint, err := install.ReadUpgradePathCheckpoint(dir)
+			if err != nil {
+				t.Fatalf("error reading the checkpoint: %v", err)
+			}
+			var completed []string
+			if checkpoint != nil {
+				for _, hop := range checkpoint.CompletedHops {
+					completed = append(completed, hop.Version)
+				}
+			}
+			if !reflect.DeepEqual(completed, test.expectCheckpoints) {
+				t.Errorf("expected the completed releases %v, but got %v", test.expectCheckpoints, completed)
+			}
+		})
+	}
+}
+
+func TestUpgradeHopArgs(t *testing.T) {
+	args := upgradeHopArgs(upgradeOpts{online: true, outputFormat: "raw", partialAllowed: true, ignoreSafetyChecks: true}, "/plan.yaml", "/generated")
+	expected := []string{"upgrade", "online", "--plan-file", "/plan.yaml", "--generated-assets-dir", "/generated", "-o", "raw", "--ignore-safety-checks"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected args %v, but got %v", expected, args)
+	}
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdUpgradeRollback returns the command for rolling back a failed upgrade
+func NewCmdUpgradeRollback(out io.Writer, opts *upgradeOpts) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back the control plane of your Kubernetes cluster after a failed upgrade",
+		Long: `Roll back the control plane of your Kubernetes cluster after a failed upgrade.
+
+Before upgrading the nodes, Kismatic creates a rollback point: it takes a snapshot of the Kubernetes
+etcd cluster, archives the configuration of the master nodes, and records the versions of the nodes.
+Rolling back restores the etcd cluster from the snapshot, and the master nodes to their prior
+configuration and Kubernetes version, so that the cluster is not left half-upgraded.
+
+Only the latest rollback point can be restored, and only if its upgrade failed. The control plane is
+not rolled back if worker nodes were already upgraded, as their kubelet would be newer than the API
+server. Use --force to roll back anyway.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			planner := &install.FilePlanner{File: opts.planFile, ValuesFile: opts.valuesFile}
+			executorOpts := install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+			}
+			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			if err != nil {
+				return err
+			}
+			return doUpgradeRollback(out, planner, executor, install.ListVersions, opts.generatedAssetsDir, opts.planFile, opts.force)
+		},
+	}
+	return &cmd
+}
+
+func doUpgradeRollback(out io.Writer, planner install.Planner, executor install.Executor, listVersions func(*install.Plan) (install.ClusterVersion, error), generatedAssetsDir, planFile string, force bool) (err error) {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planFile}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	release, err := fetchAssets(out, plan, generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	point, err := install.LatestRollbackPoint(generatedAssetsDir)
+	if err != nil {
+		return err
+	}
+	if point == nil {
+		return fmt.Errorf("no rollback point found in %q. Rollback points are created when the cluster is upgraded", install.RollbackDirectory(generatedAssetsDir))
+	}
+	if point.ClusterName != plan.Cluster.Name {
+		return fmt.Errorf("the latest rollback point %q is of cluster %q, not %q", point.Name, point.ClusterName, plan.Cluster.Name)
+	}
+	switch point.Status {
+	case install.RollbackPointRolledBack:
+		return fmt.Errorf("the cluster was already rolled back to the rollback point %q", point.Name)
+	case install.RollbackPointSucceeded:
+		if !force {
+			return fmt.Errorf("the upgrade to version %s of the rollback point %q succeeded. Use --force to roll back anyway", point.TargetVersion, point.Name)
+		}
+	}
+
+	cv, err := listVersions(plan)
+	if err != nil {
+		return fmt.Errorf("error listing cluster versions: %v", err)
+	}
+	if upgraded := point.UpgradedNonControlPlaneNodes(cv); len(upgraded) > 0 {
+		if !force {
+			return fmt.Errorf("the nodes %s were already upgraded, and their kubelet would be newer than the API server after rolling back the control plane. Use --force to roll back anyway", strings.Join(upgraded, ", "))
+		}
+		util.PrettyPrintWarn(out, "The nodes %s were already upgraded, and are not rolled back", strings.Join(upgraded, ", "))
+	}
+
+	util.PrintHeader(out, "Rollback", '=')
+	if err = executor.Rollback(*plan, *point); err != nil {
+		return fmt.Errorf("error rolling back the control plane: %v", err)
+	}
+	point.Status = install.RollbackPointRolledBack
+	if err = install.WriteRollbackPoint(*point, generatedAssetsDir); err != nil {
+		return err
+	}
+	util.PrettyPrintOk(out, "Rolled back the control plane to the rollback point %q", point.Name)
+	return nil
+}
+
+// createRollbackPoint snapshots the state of the cluster before its nodes
+// are upgraded, so that the control plane can be rolled back if the upgrade
+// fails
+func createRollbackPoint(out io.Writer, plan install.Plan, cv install.ClusterVersion, executor install.Executor, generatedAssetsDir string) (*install.RollbackPoint, error) {
+	util.PrintHeader(out, "Create Rollback Point", '=')
+	kubernetesVersion := masterKubeletVersion(plan)
+	if kubernetesVersion == "" {
+		util.PrettyPrintWarn(out, "Could not determine the Kubernetes version of the master nodes, the Kubernetes packages will not be downgraded if the upgrade is rolled back")
+	}
+	point := install.NewRollbackPoint(plan, cv, kubernetesVersion)
+	if err := executor.SnapshotForRollback(plan, point); err != nil {
+		return nil, fmt.Errorf("error creating rollback point: %v", err)
+	}
+	if err := install.WriteRollbackPoint(point, generatedAssetsDir); err != nil {
+		return nil, err
+	}
+	util.PrettyPrintOk(out, "Created rollback point %q", point.Name)
+	return &point, nil
+}
+
+// recordUpgradeOutcome records whether the upgrade of the rollback point
+// failed, so that it can be rolled back
+func recordUpgradeOutcome(out io.Writer, point *install.RollbackPoint, generatedAssetsDir string, upgradeErr error) {
+	if point == nil {
+		return
+	}
+	point.Status = install.RollbackPointSucceeded
+	if upgradeErr != nil {
+		point.Status = install.RollbackPointFailed
+	}
+	if err := install.WriteRollbackPoint(*point, generatedAssetsDir); err != nil {
+		util.PrettyPrintWarn(out, "Error recording the outcome of the upgrade: %v", err)
+		return
+	}
+	if upgradeErr != nil {
+		fmt.Fprintf(out, "\nThe upgrade failed. Run \"kismatic upgrade rollback\" to roll back the control plane to the rollback point %q\n", point.Name)
+	}
+}
+
+// masterKubeletVersion returns the version of the kubelet of the first
+// master node, or an empty string if it cannot be determined
+func masterKubeletVersion(plan install.Plan) string {
+	master := plan.Master.Nodes[0].Host
+	client, err := plan.GetSSHClient(master)
+	if err != nil {
+		return ""
+	}
+	nodes, err := data.RemoteKubectl{SSHClient: client}.ListNodes()
+	if err != nil || nodes == nil {
+		return ""
+	}
+	for _, n := range nodes.Items {
+		host := n.Labels["kismatic/host"]
+		if host == "" {
+			host = n.Name
+		}
+		if strings.EqualFold(host, master) {
+			return n.Status.NodeInfo.KubeletVersion
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/blang/semver"
+)
+
+func TestUpgradeRollback(t *testing.T) {
+	versions := func(worker string) func(*install.Plan) (install.ClusterVersion, error) {
+		return func(*install.Plan) (install.ClusterVersion, error) {
+			return install.ClusterVersion{Nodes: []install.ListableNode{
+				{Node: install.Node{Host: "master01"}, Roles: []string{"master"}, Version: semver.MustParse("1.7.0")},
+				{Node: install.Node{Host: "worker01"}, Roles: []string{"worker"}, Version: semver.MustParse(worker)},
+			}}, nil
+		}
+	}
+	tests := []struct {
+		name         string
+		point        *install.RollbackPoint
+		listVersions func(*install.Plan) (install.ClusterVersion, error)
+		execErr      error
+		force        bool
+		expectErr    bool
+	}{
+		{
+			name:  "failed upgrade",
+			point: &install.RollbackPoint{Status: install.RollbackPointFailed},
+		},
+		{
+			name:  "interrupted upgrade",
+			point: &install.RollbackPoint{Status: install.RollbackPointUpgrading},
+		},
+		{
+			name:      "no rollback point",
+			expectErr: true,
+		},
+		{
+			name:      "successful upgrade",
+			point:     &install.RollbackPoint{Status: install.RollbackPointSucceeded},
+			expectErr: true,
+		},
+		{
+			name:  "successful upgrade forced",
+			point: &install.RollbackPoint{Status: install.RollbackPointSucceeded},
+			force: true,
+		},
+		{
+			name:      "already rolled back",
+			point:     &install.RollbackPoint{Status: install.RollbackPointRolledBack},
+			force:     true,
+			expectErr: true,
+		},
+		{
+			name:         "worker upgraded",
+			point:        &install.RollbackPoint{Status: install.RollbackPointFailed},
+			listVersions: versions("1.7.0"),
+			expectErr:    true,
+		},
+		{
+			name:         "worker upgraded forced",
+			point:        &install.RollbackPoint{Status: install.RollbackPointFailed},
+			listVersions: versions("1.7.0"),
+			force:        true,
+		},
+		{
+			name:      "rollback fails",
+			point:     &install.RollbackPoint{Status: install.RollbackPointFailed},
+			execErr:   errors.New("etcd is unreachable"),
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "upgrade-rollback")
+		if err != nil {
+			t.Fatalf("error creating temp dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		plan := &install.Plan{}
+		plan.Cluster.Name = "test"
+		if test.point != nil {
+			test.point.Name = "20171215T100000Z"
+			test.point.ClusterName = "test"
+			test.point.NodeVersions = map[string]string{"master01": "1.6.0", "worker01": "1.6.0"}
+			if err = install.WriteRollbackPoint(*test.point, dir); err != nil {
+				t.Fatalf("error writing rollback point: %v", err)
+			}
+		}
+		listVersions := versions("1.6.0")
+		if test.listVersions != nil {
+			listVersions = test.listVersions
+		}
+		planner := &fakePlanner{exists: true, plan: plan}
+		err = doUpgradeRollback(&bytes.Buffer{}, planner, &fakeExecutor{err: test.execErr}, listVersions, dir, "kismatic-cluster.yaml", test.force)
+		if test.expectErr && err == nil {
+			t.Errorf("%s: expected an error, but didn't get one", test.name)
+		}
+		if !test.expectErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.expectErr {
+			point, err := install.LatestRollbackPoint(dir)
+			if err != nil || point.Status != install.RollbackPointRolledBack {
+				t.Errorf("%s: expected the rollback point to be rolled back, but got %v (%v)", test.name, point, err)
+			}
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 type validateOpts struct {
@@ -18,11 +19,14 @@ type validateOpts struct {
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
+	// failOnWarnings stops the validation when the plan file has warnings
+	failOnWarnings bool
 }
 
 // NewCmdValidate creates a new install validate command
 func NewCmdValidate(out io.Writer, installOpts *installOpts) *cobra.Command {
 	opts := &validateOpts{}
+	var acceptWarnings bool
 	cmd := &cobra.Command{
 		Use:   "validate",
 		Short: "validate your plan file",
@@ -30,8 +34,9 @@ func NewCmdValidate(out io.Writer, installOpts *installOpts) *cobra.Command {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: installOpts.planFilename}
+			planner := &install.FilePlanner{File: installOpts.planFilename, ValuesFile: installOpts.valuesFilename}
 			opts.planFile = installOpts.planFilename
+			opts.failOnWarnings = !acceptWarnings
 			return doValidate(out, planner, opts)
 		},
 	}
@@ -39,6 +44,8 @@ func NewCmdValidate(out io.Writer, installOpts *installOpts) *cobra.Command {
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
 	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options simple|raw)")
 	cmd.Flags().BoolVar(&opts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks")
+	addAcceptWarningsFlag(cmd.Flags(), &acceptWarnings)
+	cmd.AddCommand(NewCmdValidateServe(out))
 	return cmd
 }
 
@@ -56,9 +63,10 @@ func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) erro
 		return fmt.Errorf("error reading plan file: %v", err)
 	}
 	util.PrettyPrintOk(out, "Reading installation plan file %q", opts.planFile)
+	printPlanMigrations(out, plan)
 
 	// Validate plan file
-	if err := validatePlan(out, plan); err != nil {
+	if err := validatePlan(out, plan, opts.failOnWarnings); err != nil {
 		return err
 	}
 
@@ -110,14 +118,49 @@ func newPKI(stdout io.Writer, options *validateOpts) (*install.LocalPKI, error)
 	return pki, nil
 }
 
-func validatePlan(out io.Writer, plan *install.Plan) error {
+// printPlanMigrations warns about the changes that were made to a plan file
+// written by an older version of KET when it was read.
+func printPlanMigrations(out io.Writer, plan *install.Plan) {
+	for _, m := range plan.Migrations() {
+		util.PrettyPrintWarn(out, "Upgraded plan file schema (%s)", m)
+	}
+}
+
+func addAcceptWarningsFlag(flagSet *pflag.FlagSet, p *bool) {
+	flagSet.BoolVar(p, "accept-warnings", false, "proceed even if the plan file has warnings, such as an even number of etcd nodes")
+}
+
+// validatePlan validates the plan file, and prints its warnings. The warnings
+// stop the validation when failOnWarnings is true.
+func validatePlan(out io.Writer, plan *install.Plan, failOnWarnings bool) error {
 	ok, errs := install.ValidatePlan(plan)
+	return printPlanValidation(out, ok, errs, install.ValidatePlanWarnings(plan), failOnWarnings)
+}
+
+// validateExistingPlan validates the plan file of a cluster that is already
+// installed, and prints its warnings, which don't stop the validation.
+func validateExistingPlan(out io.Writer, plan *install.Plan) error {
+	ok, errs := install.ValidateExistingPlan(plan)
+	return printPlanValidation(out, ok, errs, install.ValidateExistingPlanWarnings(plan), false)
+}
+
+func printPlanValidation(out io.Writer, ok bool, errs []error, warnings []error, failOnWarnings bool) error {
 	if !ok {
 		util.PrettyPrintErr(out, "Validating installation plan file")
 		util.PrintValidationErrors(out, errs)
-		return fmt.Errorf("Plan file validation error prevents installation from proceeding")
+		return planInvalidErr{}
+	}
+	if len(warnings) == 0 {
+		util.PrettyPrintOk(out, "Validating installation plan file")
+		return nil
+	}
+	util.PrettyPrintWarn(out, "Validating installation plan file")
+	for _, w := range warnings {
+		util.PrintColor(out, util.Orange, "- %v\n", w)
+	}
+	if failOnWarnings {
+		return planWarningsErr{}
 	}
-	util.PrettyPrintOk(out, "Validating installation plan file")
 	return nil
 }
 
@@ -126,7 +169,7 @@ func validateSSHConnectivity(out io.Writer, plan *install.Plan) error {
 	if !ok {
 		util.PrettyPrintErr(out, "Validating SSH connectivity to nodes")
 		util.PrintValidationErrors(out, errs)
-		return fmt.Errorf("SSH connectivity validation error prevents installation from proceeding")
+		return sshUnreachableErr{}
 	}
 	util.PrettyPrintOk(out, "Validating SSH connectivity to nodes")
 	return nil
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// maxValidatePlanSize is the largest plan file that is validated
+const maxValidatePlanSize = 1 << 20
+
+// validationResponse is the result of validating a plan file
+type validationResponse struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+	// Warnings are the problems of the plan file that don't prevent the
+	// installation, and the changes that were made to a plan file written by
+	// an older version of KET to read it
+	Warnings []string `json:"warnings"`
+	// Name is the canonical name of the cluster, when the request asked for
+	// the name to be normalized
+	Name string `json:"name,omitempty"`
+}
+
+// defaultsResponse describes the defaults of the plan files that are
+// validated
+type defaultsResponse struct {
+	KismaticVersion string `json:"kismaticVersion"`
+	// Versions of the components installed by this version of KET
+	Versions map[string]string `json:"versions"`
+	// Plan is the plan that an empty plan file becomes, once the defaults
+	// are applied
+	Plan json.RawMessage `json:"plan"`
+}
+
+// NewCmdValidateServe returns the command for serving the validation of
+// plan files over HTTP
+func NewCmdValidateServe(out io.Writer) *cobra.Command {
+	var address, defaultsFile string
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "validate plan files sent over HTTP",
+		Long: `Validate plan files sent over HTTP, so that tools such as UIs can validate plan files as they are edited.
+
+A plan file in YAML, JSON or HCL format that is POSTed to /validate is validated, and the response lists all
+the validation errors, and the warnings about the plan file. Nothing is stored, and the SSH connectivity to the
+nodes, the certificates and the pre-flight checks are not validated.
+
+With the normalize=true query parameter, the cluster name is lower cased before it is validated, and the canonical
+name is returned in the response.
+
+The --defaults-file is a plan file that only sets the fields that the plan files that are received may leave empty,
+such as the networking CIDRs, the add-ons or the worker pools. The plan files are read on top of it. GET /defaults
+returns the versions of the components installed by KET, and the plan that an empty plan file becomes.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			var defaults *install.PlanDefaults
+			if defaultsFile != "" {
+				var err error
+				if defaults, err = install.ReadPlanDefaults(defaultsFile); err != nil {
+					return err
+				}
+				util.PrettyPrintOk(out, "Reading plan defaults %q", defaultsFile)
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/validate", validationHandler(defaults))
+			mux.Handle("/defaults", defaultsHandler(defaults))
+			util.PrettyPrintOk(out, "Validating plan files on %s/validate", address)
+			return http.ListenAndServe(address, mux)
+		},
+	}
+	cmd.Flags().StringVar(&address, "listen", ":8080", "address to listen on for the plan files to validate")
+	cmd.Flags().StringVar(&defaultsFile, "defaults-file", "", "plan file with the values of the fields that the plan files to validate don't set")
+	return cmd
+}
+
+// validationHandler returns a handler that validates the plan file in the
+// body of the request. The format of the plan file is set by the content
+// type of the request, or detected from the plan file. The plan file is read
+// on top of the defaults, which may be nil.
+func validationHandler(defaults *install.PlanDefaults) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxValidatePlanSize))
+		if err != nil {
+			writeValidationResponse(w, http.StatusRequestEntityTooLarge, validationResponse{Errors: []string{fmt.Sprintf("error reading plan file: %v", err)}})
+			return
+		}
+		plan, err := install.ReadPlanWithDefaults(body, requestPlanFormat(r, body), defaults)
+		if err != nil {
+			writeValidationResponse(w, http.StatusBadRequest, validationResponse{Errors: []string{err.Error()}})
+			return
+		}
+		var name string
+		if r.URL.Query().Get("normalize") == "true" {
+			plan.Cluster.Name = install.NormalizeClusterName(plan.Cluster.Name)
+			name = plan.Cluster.Name
+		}
+		ok, errs := install.ValidatePlan(plan)
+		res := validationResponse{Valid: ok, Name: name}
+		for _, err := range errs {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		for _, m := range plan.Migrations() {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("Upgraded plan file schema (%s)", m))
+		}
+		for _, w := range install.ValidatePlanWarnings(plan) {
+			res.Warnings = append(res.Warnings, w.Error())
+		}
+		writeValidationResponse(w, http.StatusOK, res)
+	})
+}
+
+// defaultsHandler returns a handler that describes the defaults of the plan
+// files that are validated
+func defaultsHandler(defaults *install.PlanDefaults) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		plan, err := install.MinimalPlanJSON(defaults)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res := defaultsResponse{
+			KismaticVersion: install.KismaticVersion.String(),
+			Versions:        install.InstalledVersions(),
+			Plan:            plan,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	})
+}
+
+func requestPlanFormat(r *http.Request, body []byte) install.PlanFormat {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return install.PlanFormatJSON
+	case "application/x-yaml", "application/yaml", "text/yaml":
+		return install.PlanFormatYAML
+	case "application/hcl":
+		return install.PlanFormatHCL
+	}
+	return install.DetectPlanFormat("", body)
+}
+
+func writeValidationResponse(w http.ResponseWriter, status int, res validationResponse) {
+	if res.Errors == nil {
+		res.Errors = []string{}
+	}
+	if res.Warnings == nil {
+		res.Warnings = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
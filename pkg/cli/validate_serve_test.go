@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestValidationHandler(t *testing.T) {
+	tests := []struct {
+		method        string
+		url           string
+		contentType   string
+		body          string
+		status        int
+		valid         bool
+		expectedError string
+		expectedName  string
+	}{
+		{
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			method:        http.MethodPost,
+			body:          "cluster: [",
+			status:        http.StatusBadRequest,
+			expectedError: "failed to unmarshal plan",
+		},
+		{
+			method:        http.MethodPost,
+			body:          "cluster:\n  name: dev\n",
+			status:        http.StatusOK,
+			expectedError: "Admin password cannot be empty",
+		},
+		{
+			method:        http.MethodPost,
+			contentType:   "application/json; charset=utf-8",
+			body:          `{"cluster": {"name": "dev"}}`,
+			status:        http.StatusOK,
+			expectedError: "Admin password cannot be empty",
+		},
+		{
+			method:        http.MethodPost,
+			contentType:   "application/json",
+			body:          "cluster:\n  name: dev\n",
+			status:        http.StatusBadRequest,
+			expectedError: "failed to unmarshal plan",
+		},
+		{
+			method:        http.MethodPost,
+			body:          "cluster:\n  name: Dev\n",
+			status:        http.StatusOK,
+			expectedError: `Cluster name "Dev" is not valid`,
+		},
+		{
+			method:        http.MethodPost,
+			url:           "/validate?normalize=true",
+			body:          "cluster:\n  name: Dev\n",
+			status:        http.StatusOK,
+			expectedError: "Admin password cannot be empty",
+			expectedName:  "dev",
+		},
+	}
+	handler := validationHandler(nil)
+	for i, test := range tests {
+		url := test.url
+		if url == "" {
+			url = "/validate"
+		}
+		req := httptest.NewRequest(test.method, url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("test %d: expected status %d, but got %d", i, test.status, rec.Code)
+			continue
+		}
+		if test.status == http.StatusMethodNotAllowed {
+			continue
+		}
+		var res validationResponse
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatalf("test %d: error decoding response: %v", i, err)
+		}
+		if res.Name != test.expectedName {
+			t.Errorf("test %d: expected name %q, but got %q", i, test.expectedName, res.Name)
+		}
+		if res.Valid != test.valid {
+			t.Errorf("test %d: expected valid to be %v, but got %v", i, test.valid, res.Valid)
+		}
+		found := false
+		for _, e := range res.Errors {
+			if strings.Contains(e, test.expectedError) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("test %d: expected an error containing %q, but got %v", i, test.expectedError, res.Errors)
+		}
+	}
+}
+
+func TestDefaultsHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate-serve-defaults")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "defaults.yaml")
+	if err = ioutil.WriteFile(file, []byte("cluster:\n  admin_password: secret\n  networking:\n    pod_cidr_block: 10.0.0.0/16\n"), 0644); err != nil {
+		t.Fatalf("error writing defaults: %v", err)
+	}
+	defaults, err := install.ReadPlanDefaults(file)
+	if err != nil {
+		t.Fatalf("unexpected error reading defaults: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	defaultsHandler(defaults).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/defaults", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, but got %d", http.StatusOK, rec.Code)
+	}
+	var res struct {
+		Versions map[string]string
+		Plan     struct {
+			Cluster struct {
+				Networking struct {
+					PodCIDRBlock string `json:"pod_cidr_block"`
+				}
+			}
+		}
+	}
+	if err = json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	if res.Versions["kubernetes"] == "" {
+		t.Errorf("expected the kubernetes version, but got %v", res.Versions)
+	}
+	if res.Plan.Cluster.Networking.PodCIDRBlock != "10.0.0.0/16" {
+		t.Errorf("expected the default pod CIDR, but got %q", res.Plan.Cluster.Networking.PodCIDRBlock)
+	}
+
+	// the plan files that are validated are read on top of the defaults
+	rec = httptest.NewRecorder()
+	validationHandler(defaults).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader("cluster:\n  name: dev\n")))
+	var validation validationResponse
+	if err = json.NewDecoder(rec.Body).Decode(&validation); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	for _, e := range validation.Errors {
+		if strings.Contains(e, "Admin password cannot be empty") {
+			t.Errorf("expected the default admin password to be used, but got error %q", e)
+		}
+	}
+}
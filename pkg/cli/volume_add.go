@@ -54,7 +54,7 @@ This function requires a target cluster that has storage nodes.`,
 	return cmd
 }
 
-func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []string) error {
+func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []string) (err error) {
 	// get volume name and size from arguments
 	var volumeName string
 	var volumeSizeStrGB string
@@ -94,6 +94,15 @@ func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []s
 	if err != nil {
 		return err
 	}
+	release, err := fetchAssets(out, plan, opts.generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
 
 	// Run validation
 	vopts := &validateOpts{
@@ -47,7 +47,7 @@ WARNING all data in the volume will be lost.`,
 	return cmd
 }
 
-func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, args []string) error {
+func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, args []string) (err error) {
 	// get volume name and size from arguments
 	var volumeName string
 	switch len(args) {
@@ -76,6 +76,15 @@ func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, ar
 	if err != nil {
 		return err
 	}
+	release, err := fetchAssets(out, plan, opts.generatedAssetsDir, defaultRunsDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
 
 	// Run validation
 	vopts := &validateOpts{
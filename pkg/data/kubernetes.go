@@ -3,6 +3,7 @@ package data
 import (
 	"encoding/json"
 	"fmt"
+	"os/exec"
 	"strings"
 
 	"github.com/apprenda/kismatic/pkg/ssh"
@@ -18,6 +19,11 @@ type PVLister interface {
 	ListPersistentVolumes() (*PersistentVolumeList, error)
 }
 
+// NodeLister lists the nodes of a Kubernetes cluster
+type NodeLister interface {
+	ListNodes() (*NodeList, error)
+}
+
 // PersistentVolumeGetter gets a persistent volume
 type PersistentVolumeGetter interface {
 	GetPersistentVolume(name string) (*PersistentVolume, error)
@@ -48,6 +54,23 @@ type StatefulSetGetter interface {
 	GetStatefulSet(namespace, name string) (*StatefulSet, error)
 }
 
+// PodDisruptionBudgetLister lists the pod disruption budgets of a Kubernetes
+// cluster
+type PodDisruptionBudgetLister interface {
+	ListPodDisruptionBudgets() (*PodDisruptionBudgetList, error)
+}
+
+// ComponentStatusLister lists the health of the control plane components and
+// of the etcd members
+type ComponentStatusLister interface {
+	ListComponentStatuses() (*ComponentStatusList, error)
+}
+
+// ResourceCounter counts the objects of a resource type
+type ResourceCounter interface {
+	CountResources(resource string) (int, error)
+}
+
 type KubernetesClient interface {
 	PodLister
 	PVLister
@@ -102,6 +125,65 @@ func UnmarshalPods(raw string) (*PodList, error) {
 	return &pods, nil
 }
 
+// ListNodes returns Nodes data
+func (k RemoteKubectl) ListNodes() (*NodeList, error) {
+	nodesRaw, err := k.SSHClient.Output(true, "sudo kubectl get nodes -o json")
+	if err != nil {
+		return nil, fmt.Errorf("error getting node data: %v", err)
+	}
+	return UnmarshalNodes(nodesRaw)
+}
+
+// ListPodDisruptionBudgets returns PodDisruptionBudgets data with
+// --all-namespaces=true flag
+func (k RemoteKubectl) ListPodDisruptionBudgets() (*PodDisruptionBudgetList, error) {
+	pdbRaw, err := k.SSHClient.Output(true, "sudo kubectl get pdb --all-namespaces=true -o json")
+	if err != nil {
+		return nil, fmt.Errorf("error getting pod disruption budget data: %v", err)
+	}
+	if isNoResourcesResponse(pdbRaw) {
+		return nil, nil
+	}
+	var pdbs PodDisruptionBudgetList
+	if err := json.Unmarshal([]byte(pdbRaw), &pdbs); err != nil {
+		return nil, fmt.Errorf("error unmarshalling pod disruption budget data: %v", err)
+	}
+	return &pdbs, nil
+}
+
+// ListComponentStatuses returns ComponentStatuses data
+func (k RemoteKubectl) ListComponentStatuses() (*ComponentStatusList, error) {
+	csRaw, err := k.SSHClient.Output(true, "sudo kubectl get componentstatuses -o json")
+	if err != nil {
+		return nil, fmt.Errorf("error getting component status data: %v", err)
+	}
+	if isNoResourcesResponse(csRaw) {
+		return nil, nil
+	}
+	var cs ComponentStatusList
+	if err := json.Unmarshal([]byte(csRaw), &cs); err != nil {
+		return nil, fmt.Errorf("error unmarshalling component status data: %v", err)
+	}
+	return &cs, nil
+}
+
+// CountResources returns the number of objects of the resource type in all
+// namespaces. Resource types that are not served by the API server have no
+// objects.
+func (k RemoteKubectl) CountResources(resource string) (int, error) {
+	raw, err := k.SSHClient.Output(true, fmt.Sprintf("sudo kubectl get %s --all-namespaces=true -o name", resource))
+	if err != nil {
+		if isUnknownResourceResponse(raw) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error getting %s: %v", resource, err)
+	}
+	if isNoResourcesResponse(raw) {
+		return 0, nil
+	}
+	return len(strings.Fields(raw)), nil
+}
+
 // GetDaemonSet returns the DaemonSet with the given namespace and name. If not found,
 // returns an error.
 func (k RemoteKubectl) GetDaemonSet(namespace, name string) (*DaemonSet, error) {
@@ -210,6 +292,70 @@ func (k RemoteKubectl) GetStatefulSet(namespace, name string) (*StatefulSet, err
 	return &s, nil
 }
 
+// LocalKubectl is a kubectl client that runs the kubectl binary on the local
+// machine, using the given kubeconfig file to connect to the cluster.
+type LocalKubectl struct {
+	// KubectlPath is the path to the kubectl binary
+	KubectlPath string
+	// Kubeconfig is the path to the kubeconfig file
+	Kubeconfig string
+}
+
+func (k LocalKubectl) output(args ...string) (string, error) {
+	args = append([]string{"--kubeconfig", k.Kubeconfig}, args...)
+	out, err := exec.Command(k.KubectlPath, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// ListNodes returns Nodes data
+func (k LocalKubectl) ListNodes() (*NodeList, error) {
+	nodesRaw, err := k.output("get", "nodes", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error getting node data: %v", err)
+	}
+	return UnmarshalNodes(nodesRaw)
+}
+
+// ListPods returns Pods data with --all-namespaces=true flag
+func (k LocalKubectl) ListPods() (*PodList, error) {
+	podsRaw, err := k.output("get", "pods", "--all-namespaces=true", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error getting pod data: %v", err)
+	}
+	return UnmarshalPods(podsRaw)
+}
+
+// Server returns the URL of the API server defined in the current context
+// of the kubeconfig file
+func (k LocalKubectl) Server() (string, error) {
+	server, err := k.output("config", "view", "--minify", "-o", "jsonpath={.clusters[0].cluster.server}")
+	if err != nil {
+		return "", fmt.Errorf("error getting API server from kubeconfig: %v", err)
+	}
+	return strings.TrimSpace(server), nil
+}
+
+func UnmarshalNodes(raw string) (*NodeList, error) {
+	if isNoResourcesResponse(raw) {
+		return nil, nil
+	}
+	var nodes NodeList
+	err := json.Unmarshal([]byte(raw), &nodes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling node data: %v", err)
+	}
+	return &nodes, nil
+}
+
+// kubectl will print this message when the API server does not serve the
+// resource type
+func isUnknownResourceResponse(s string) bool {
+	return strings.Contains(s, "the server doesn't have a resource type")
+}
+
 // kubectl will print this message when no resources are returned
 func isNoResourcesResponse(s string) bool {
 	if strings.Contains(strings.TrimSpace(s), "No resources found") {
@@ -71,6 +71,8 @@ type PersistentVolumeClaimVolumeSource struct {
 // A single application container that you want to run within a pod.
 type Container struct {
 	Name         string        `json:"name"`
+	Image        string        `json:"image,omitempty"`
+	Command      []string      `json:"command,omitempty"`
 	VolumeMounts []VolumeMount `json:"volumeMounts,omitempty"`
 }
 
@@ -234,3 +236,154 @@ type StatefulSetStatus struct {
 	// Replicas is the number of actual replicas.
 	Replicas int32
 }
+
+// NodeList is the whole list of all Nodes which have been registered with master.
+type NodeList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata,omitempty"`
+	Items    []Node `json:"items"`
+}
+
+// Node is a worker node in Kubernetes.
+type Node struct {
+	TypeMeta   `json:",inline"`
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       NodeSpec   `json:"spec,omitempty"`
+	Status     NodeStatus `json:"status,omitempty"`
+}
+
+// NodeSpec describes the attributes that a node is created with.
+type NodeSpec struct {
+	// Unschedulable controls node schedulability of new pods.
+	Unschedulable bool `json:"unschedulable,omitempty"`
+	// If specified, the node's taints.
+	Taints []Taint `json:"taints,omitempty"`
+}
+
+// Taint is attached to a node, and repels pods that do not tolerate it.
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// NodeStatus is information about the current status of a node.
+type NodeStatus struct {
+	// List of addresses reachable to the node.
+	Addresses []NodeAddress `json:"addresses,omitempty"`
+	// Set of ids/uuids to uniquely identify the node.
+	NodeInfo NodeSystemInfo `json:"nodeInfo,omitempty"`
+	// Conditions is an array of current observed node conditions.
+	Conditions []NodeCondition `json:"conditions,omitempty"`
+}
+
+// NodeCondition contains condition information for a node.
+type NodeCondition struct {
+	// Type of node condition, such as Ready or OutOfDisk.
+	Type string `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status string `json:"status"`
+	// Human readable message indicating details about the condition.
+	Message string `json:"message,omitempty"`
+}
+
+// Ready returns true when the node is ready to accept pods
+func (n Node) Ready() bool {
+	for _, c := range n.Status.Conditions {
+		if c.Type == "Ready" {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+// NodeAddressType is the type of a node address
+type NodeAddressType string
+
+// The node address types
+const (
+	NodeHostName   NodeAddressType = "Hostname"
+	NodeExternalIP NodeAddressType = "ExternalIP"
+	NodeInternalIP NodeAddressType = "InternalIP"
+)
+
+// NodeAddress contains information for the node's address.
+type NodeAddress struct {
+	// Node address type, one of Hostname, ExternalIP or InternalIP.
+	Type NodeAddressType `json:"type"`
+	// The node address.
+	Address string `json:"address"`
+}
+
+// NodeSystemInfo is a set of ids/uuids to uniquely identify the node.
+type NodeSystemInfo struct {
+	// Kernel Version reported by the node.
+	KernelVersion string `json:"kernelVersion"`
+	// OS Image reported by the node.
+	OSImage string `json:"osImage"`
+	// ContainerRuntime Version reported by the node.
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion"`
+	// Kubelet Version reported by the node.
+	KubeletVersion string `json:"kubeletVersion"`
+	// KubeProxy Version reported by the node.
+	KubeProxyVersion string `json:"kubeProxyVersion"`
+	// The Operating System reported by the node
+	OperatingSystem string `json:"operatingSystem"`
+	// The Architecture reported by the node
+	Architecture string `json:"architecture"`
+}
+
+// PodDisruptionBudgetList is a collection of PodDisruptionBudgets.
+type PodDisruptionBudgetList struct {
+	Items []PodDisruptionBudget `json:"items"`
+}
+
+// PodDisruptionBudget is an object to define the max disruption that can be
+// caused to a collection of pods.
+type PodDisruptionBudget struct {
+	ObjectMeta `json:"metadata,omitempty"`
+	Status     PodDisruptionBudgetStatus `json:"status,omitempty"`
+}
+
+// PodDisruptionBudgetStatus represents information about the status of a
+// PodDisruptionBudget.
+type PodDisruptionBudgetStatus struct {
+	// Number of pod disruptions that are currently allowed.
+	PodDisruptionsAllowed int32 `json:"disruptionsAllowed"`
+	// total number of pods counted by this disruption budget
+	ExpectedPods int32 `json:"expectedPods"`
+}
+
+// ComponentStatusList is a collection of ComponentStatuses.
+type ComponentStatusList struct {
+	Items []ComponentStatus `json:"items"`
+}
+
+// ComponentStatus is the health of a control plane component, or of an etcd
+// member.
+type ComponentStatus struct {
+	ObjectMeta `json:"metadata,omitempty"`
+	Conditions []ComponentCondition `json:"conditions,omitempty"`
+}
+
+// ComponentCondition is the condition of a component.
+type ComponentCondition struct {
+	// Type of condition for a component. Valid value: "Healthy"
+	Type string `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status string `json:"status"`
+	// Message about the condition for a component.
+	Message string `json:"message,omitempty"`
+	// Condition error code for a component.
+	Error string `json:"error,omitempty"`
+}
+
+// Healthy returns true when the component is healthy
+func (c ComponentStatus) Healthy() bool {
+	for _, cond := range c.Conditions {
+		if cond.Type == "Healthy" {
+			return cond.Status == "True"
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package gitops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The status of an alert notification
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertConfig lists the webhooks that are notified when the health of the
+// clusters degrades
+type AlertConfig struct {
+	Webhooks []AlertWebhook `yaml:"webhooks"`
+}
+
+// AlertWebhook is notified when a cluster that matches its selector becomes
+// unhealthy beyond its thresholds, and when the cluster recovers
+type AlertWebhook struct {
+	// URL the notifications are POSTed to
+	URL string `yaml:"url"`
+	// Selector are the labels that the clusters must match. All the clusters
+	// match an empty selector.
+	Selector map[string]string `yaml:"selector,omitempty"`
+	// After is the number of probes in a row that must find the cluster
+	// unhealthy. Defaults to 1.
+	After int `yaml:"after,omitempty"`
+	// NotReadyNodes is the number of nodes that must not be Ready, when the
+	// API server is reachable. Defaults to 1.
+	NotReadyNodes int `yaml:"not_ready_nodes,omitempty"`
+}
+
+// ReadAlertConfig reads and validates the alerts file, and sets the default
+// thresholds of its webhooks
+func ReadAlertConfig(file string) (*AlertConfig, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading alerts file: %v", err)
+	}
+	c := &AlertConfig{}
+	if err = yaml.Unmarshal(d, c); err != nil {
+		return nil, fmt.Errorf("error unmarshaling alerts file: %v", err)
+	}
+	var errs []string
+	for i := range c.Webhooks {
+		w := &c.Webhooks[i]
+		if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("webhook %d: URL %q is not a valid http or https URL", i, w.URL))
+		}
+		if w.After < 0 || w.NotReadyNodes < 0 {
+			errs = append(errs, fmt.Sprintf("webhook %d: thresholds cannot be negative", i))
+		}
+		if w.After == 0 {
+			w.After = 1
+		}
+		if w.NotReadyNodes == 0 {
+			w.NotReadyNodes = 1
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("alerts file is not valid:\n- %s", strings.Join(errs, "\n- "))
+	}
+	return c, nil
+}
+
+// firing returns whether the health of the cluster is beyond the thresholds
+// of the webhook
+func (w AlertWebhook) firing(h Health) bool {
+	if h.Healthy || h.UnhealthyProbes < w.After {
+		return false
+	}
+	// the API server is not reachable when no nodes are reported
+	return len(h.NotReadyNodes) == 0 || len(h.NotReadyNodes) >= w.NotReadyNodes
+}
+
+func (w AlertWebhook) matches(labels map[string]string) bool {
+	for k, v := range w.Selector {
+		if val, ok := labels[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Notification is the body of the request sent to an alert webhook
+type Notification struct {
+	Cluster string            `json:"cluster"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	// Status is firing when the cluster became unhealthy, and resolved when
+	// it recovered
+	Status  string `json:"status"`
+	Summary string `json:"summary,omitempty"`
+	// APIServerReachable is false when the API server of the cluster could
+	// not be reached
+	APIServerReachable bool       `json:"apiServerReachable"`
+	NotReadyNodes      []string   `json:"notReadyNodes,omitempty"`
+	CheckedAt          time.Time  `json:"checkedAt"`
+	LastHealthyAt      *time.Time `json:"lastHealthyAt,omitempty"`
+}
+
+// Alerter notifies the webhooks of the clusters whose health crossed their
+// thresholds
+type Alerter struct {
+	Config AlertConfig
+	// Labels returns the labels of the cluster
+	Labels func(cluster string) (map[string]string, error)
+	// Client sends the notifications, and defaults to a client with a 10
+	// second timeout
+	Client *http.Client
+	// StateFile records the status of the last notification each webhook
+	// received for each cluster, so that the notifications that failed are
+	// sent again on the next probe
+	StateFile string
+}
+
+// alertState is the status of the last notification delivered to each
+// webhook, by cluster and webhook URL
+type alertState map[string]map[string]string
+
+// Notify sends a firing notification to the webhooks whose thresholds the
+// health of a cluster crosses, and a resolved notification to the webhooks
+// whose thresholds it no longer crosses, unless the webhook already received
+// it. It returns the notifications that were sent.
+func (a Alerter) Notify(current map[string]Health) ([]Notification, error) {
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	delivered, err := a.readState()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	state := alertState{}
+	var sent []Notification
+	var errs []string
+	for _, name := range names {
+		h := current[name]
+		state[name] = map[string]string{}
+		var labels map[string]string
+		if len(a.Config.Webhooks) > 0 {
+			var err error
+			if labels, err = a.Labels(name); err != nil {
+				state[name] = delivered[name]
+				errs = append(errs, fmt.Sprintf("cluster %q: %v", name, err))
+				continue
+			}
+		}
+		for _, w := range a.Config.Webhooks {
+			if !w.matches(labels) {
+				continue
+			}
+			last := delivered[name][w.URL]
+			if last != "" {
+				state[name][w.URL] = last
+			}
+			status := AlertResolved
+			if w.firing(h) {
+				status = AlertFiring
+			}
+			// the webhooks that never received a notification of the
+			// cluster only get notified once it fires
+			if status == last || (status == AlertResolved && last == "") {
+				continue
+			}
+			n := Notification{
+				Cluster:            name,
+				Labels:             labels,
+				Status:             status,
+				Summary:            h.Summary,
+				APIServerReachable: h.Healthy || len(h.NotReadyNodes) > 0,
+				NotReadyNodes:      h.NotReadyNodes,
+				CheckedAt:          h.CheckedAt,
+				LastHealthyAt:      h.LastHealthyAt,
+			}
+			if err := send(client, w.URL, n); err != nil {
+				errs = append(errs, fmt.Sprintf("cluster %q: %v", name, err))
+				continue
+			}
+			state[name][w.URL] = status
+			sent = append(sent, n)
+		}
+	}
+	if err := a.writeState(state); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("error sending alert notifications:\n- %s", strings.Join(errs, "\n- "))
+	}
+	return sent, nil
+}
+
+func (a Alerter) readState() (alertState, error) {
+	s := alertState{}
+	d, err := ioutil.ReadFile(a.StateFile)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading alerts state file: %v", err)
+	}
+	if err = json.Unmarshal(d, &s); err != nil {
+		return nil, fmt.Errorf("error unmarshaling alerts state file: %v", err)
+	}
+	return s, nil
+}
+
+func (a Alerter) writeState(s alertState) error {
+	d, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling alerts state: %v", err)
+	}
+	if err = writeFileAtomic(a.StateFile, d, 0600); err != nil {
+		return fmt.Errorf("error writing alerts state file: %v", err)
+	}
+	return nil
+}
+
+func send(client *http.Client, webhook string, n Notification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("error encoding notification: %v", err)
+	}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("error notifying %q: %v", webhook, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error notifying %q: %s", webhook, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package gitops
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadAlertConfig(t *testing.T) {
+	tests := []struct {
+		content  string
+		valid    bool
+		expected []AlertWebhook
+	}{
+		{
+			content: "webhooks:\n- url: https://hooks.example.com/all\n",
+			valid:   true,
+			expected: []AlertWebhook{
+				{URL: "https://hooks.example.com/all", After: 1, NotReadyNodes: 1},
+			},
+		},
+		{
+			content: "webhooks:\n- url: http://hooks.example.com/dev\n  selector:\n    env: dev\n  after: 3\n  not_ready_nodes: 2\n",
+			valid:   true,
+			expected: []AlertWebhook{
+				{URL: "http://hooks.example.com/dev", Selector: map[string]string{"env": "dev"}, After: 3, NotReadyNodes: 2},
+			},
+		},
+		{
+			content: "webhooks:\n- url: hooks.example.com\n",
+		},
+		{
+			content: "webhooks:\n- url: https://hooks.example.com\n  after: -1\n",
+		},
+	}
+	dir, err := ioutil.TempDir("", "gitops-alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, test := range tests {
+		file := filepath.Join(dir, "alerts.yaml")
+		if err := ioutil.WriteFile(file, []byte(test.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		c, err := ReadAlertConfig(file)
+		if (err == nil) != test.valid {
+			t.Errorf("test %d: expected valid to be %v, but got error %v", i, test.valid, err)
+			continue
+		}
+		if test.valid && !reflect.DeepEqual(c.Webhooks, test.expected) {
+			t.Errorf("test %d: expected webhooks %v, but got %v", i, test.expected, c.Webhooks)
+		}
+	}
+}
+
+func TestAlerterNotify(t *testing.T) {
+	var received []Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := Notification{}
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("error decoding notification: %v", err)
+		}
+		received = append(received, n)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "gitops-alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := Alerter{
+		Config: AlertConfig{
+			Webhooks: []AlertWebhook{
+				{URL: server.URL, Selector: map[string]string{"env": "prod"}, After: 2, NotReadyNodes: 2},
+			},
+		},
+		Labels: func(cluster string) (map[string]string, error) {
+			return map[string]string{"env": cluster}, nil
+		},
+		StateFile: filepath.Join(dir, "alerts.json"),
+	}
+	healthy := Health{Healthy: true}
+	oneNotReady := Health{Summary: "1 of 3 nodes are not Ready: worker1", NotReadyNodes: []string{"worker1"}, UnhealthyProbes: 2}
+	twoNotReady := Health{Summary: "2 of 3 nodes are not Ready: worker1, worker2", NotReadyNodes: []string{"worker1", "worker2"}, UnhealthyProbes: 2}
+	unreachable := Health{Summary: "connection refused", UnhealthyProbes: 1}
+
+	// the probes of the clusters, in order
+	tests := []struct {
+		current  map[string]Health
+		expected []Notification
+	}{
+		{
+			// below the threshold of not ready nodes
+			current: map[string]Health{"prod": oneNotReady},
+		},
+		{
+			// the cluster does not match the selector
+			current: map[string]Health{"dev": twoNotReady},
+		},
+		{
+			// below the number of unhealthy probes in a row
+			current: map[string]Health{"prod": unreachable},
+		},
+		{
+			current: map[string]Health{"prod": {Summary: "connection refused", UnhealthyProbes: 2}},
+			expected: []Notification{
+				{Cluster: "prod", Labels: map[string]string{"env": "prod"}, Status: AlertFiring, Summary: "connection refused"},
+			},
+		},
+		{
+			// already notified
+			current: map[string]Health{"prod": twoNotReady},
+		},
+		{
+			current: map[string]Health{"prod": healthy},
+			expected: []Notification{
+				{Cluster: "prod", Labels: map[string]string{"env": "prod"}, Status: AlertResolved, APIServerReachable: true},
+			},
+		},
+		{
+			current: map[string]Health{"prod": healthy},
+		},
+		{
+			current: map[string]Health{"prod": twoNotReady},
+			expected: []Notification{
+				{Cluster: "prod", Labels: map[string]string{"env": "prod"}, Status: AlertFiring, Summary: twoNotReady.Summary, APIServerReachable: true, NotReadyNodes: twoNotReady.NotReadyNodes},
+			},
+		},
+	}
+	for i, test := range tests {
+		received = nil
+		sent, err := a.Notify(test.current)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(sent, test.expected) {
+			t.Errorf("test %d: expected notifications %v, but sent %v", i, test.expected, sent)
+		}
+		if len(received) != len(test.expected) {
+			t.Errorf("test %d: expected the webhook to receive %d notifications, but got %d", i, len(test.expected), len(received))
+		}
+	}
+}
+
+func TestAlerterNotifyWebhookFailure(t *testing.T) {
+	failing := true
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received++
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "gitops-alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := Alerter{
+		Config:    AlertConfig{Webhooks: []AlertWebhook{{URL: server.URL, After: 1, NotReadyNodes: 1}}},
+		Labels:    func(cluster string) (map[string]string, error) { return nil, nil },
+		StateFile: filepath.Join(dir, "alerts.json"),
+	}
+	current := map[string]Health{"dev": {Summary: "connection refused", UnhealthyProbes: 1}}
+	for i := 0; i < 2; i++ {
+		sent, err := a.Notify(current)
+		if err == nil {
+			t.Error("expected an error when the webhook fails")
+		}
+		if len(sent) != 0 {
+			t.Errorf("expected no notifications to be sent, but got %v", sent)
+		}
+	}
+	// the notification is sent again until the webhook receives it
+	failing = false
+	if sent, err := a.Notify(current); err != nil || len(sent) != 1 {
+		t.Errorf("expected the notification to be sent again, but sent %v: %v", sent, err)
+	}
+	// the notifications that were received are not sent again
+	if sent, err := a.Notify(current); err != nil || len(sent) != 0 {
+		t.Errorf("expected no notifications to be sent, but sent %v: %v", sent, err)
+	}
+	if received != 1 {
+		t.Errorf("expected the webhook to receive 1 notification, but got %d", received)
+	}
+}
@@ -0,0 +1,77 @@
+package gitops
+
+import "time"
+
+// The classes of the failures to apply a plan file
+const (
+	// FailureValidation is a plan file that is not valid. It is not applied
+	// again until the plan file changes.
+	FailureValidation = "validation"
+	// FailureUnreachable is a node that could not be reached over SSH
+	FailureUnreachable = "unreachable"
+	// FailureTransient is an error of an external service, such as the
+	// object storage or the secrets store of the cluster
+	FailureTransient = "transient"
+	// FailurePlaybook is a playbook that failed, or any other error
+	FailurePlaybook = "playbook"
+)
+
+// ClassifiedError is an error to apply a plan file, with the class of the
+// failure. Errors that are not classified are playbook failures.
+type ClassifiedError struct {
+	Class string
+	Err   error
+}
+
+func (e ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func classOf(err error) string {
+	if ce, ok := err.(ClassifiedError); ok {
+		return ce.Class
+	}
+	return FailurePlaybook
+}
+
+// Failure is a failure to apply a plan file
+type Failure struct {
+	// Checksum of the plan file that failed to apply
+	Checksum string `json:"checksum"`
+	Class    string `json:"class"`
+	Error    string `json:"error"`
+	// Attempts is the number of times the plan file failed to apply in a row
+	Attempts int `json:"attempts"`
+	// NextRetryAt is when the plan file is applied again. It is zero when the
+	// plan file is not applied again until it changes.
+	NextRetryAt time.Time `json:"nextRetryAt,omitempty"`
+}
+
+// Retryable returns whether the plan file is applied again without changes
+func (f Failure) Retryable() bool {
+	return !f.NextRetryAt.IsZero()
+}
+
+// Backoff is the delay before a plan file that failed to apply is applied
+// again. The delay doubles with each failure in a row, up to the maximum.
+// When the initial delay is zero, the plan file is applied again on the
+// next reconciliation.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// delay returns the delay after the given number of failures in a row
+func (b Backoff) delay(attempts int) time.Duration {
+	d := b.Initial
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if b.Max > 0 && d >= b.Max {
+			return b.Max
+		}
+	}
+	if b.Max > 0 && d > b.Max {
+		return b.Max
+	}
+	return d
+}
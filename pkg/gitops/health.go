@@ -0,0 +1,266 @@
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodesNotReadyError is the problem of a cluster whose API server is
+// reachable, but with nodes that are not Ready
+type NodesNotReadyError struct {
+	// Nodes that are not Ready
+	Nodes []string
+	// Total is the number of nodes of the cluster
+	Total int
+}
+
+func (e NodesNotReadyError) Error() string {
+	return fmt.Sprintf("%d of %d nodes are not Ready: %s", len(e.Nodes), e.Total, strings.Join(e.Nodes, ", "))
+}
+
+// NodeVersions are the versions of the components running on a node
+type NodeVersions struct {
+	Node             string `json:"node"`
+	Kubelet          string `json:"kubelet"`
+	KubeProxy        string `json:"kubeProxy"`
+	ContainerRuntime string `json:"containerRuntime"`
+	OSImage          string `json:"osImage"`
+	KernelVersion    string `json:"kernelVersion"`
+	// CNI is the version of the CNI provider. It is empty when it is not
+	// known.
+	CNI string `json:"cni,omitempty"`
+	// Skew describes the components whose version is not the target
+	// version of the plan file of the cluster
+	Skew []string `json:"skew,omitempty"`
+}
+
+// Health of a cluster, as found by its last probe
+type Health struct {
+	Healthy bool `json:"healthy"`
+	// Summary of the problems found by the last probe. It is empty when the
+	// cluster is healthy.
+	Summary string `json:"summary,omitempty"`
+	// NotReadyNodes are the nodes that were not Ready. It is empty when the
+	// API server was not reachable.
+	NotReadyNodes []string `json:"notReadyNodes,omitempty"`
+	// UnhealthyProbes is the number of probes in a row that found the
+	// cluster unhealthy
+	UnhealthyProbes int `json:"unhealthyProbes,omitempty"`
+	// CheckedAt is when the cluster was last probed
+	CheckedAt time.Time `json:"checkedAt"`
+	// LastHealthyAt is when the cluster was last found healthy. It is nil
+	// when the cluster was never found healthy.
+	LastHealthyAt *time.Time `json:"lastHealthyAt,omitempty"`
+	// Nodes are the versions of the components of the nodes. They are
+	// kept from the previous probes when the nodes could not be listed.
+	Nodes []NodeVersions `json:"nodes,omitempty"`
+}
+
+// HealthChecker probes the health of the clusters that were applied by a
+// reconciler
+type HealthChecker struct {
+	// StateFile is the state file of the reconciler
+	StateFile string
+	// HealthFile records the health of the clusters
+	HealthFile string
+	// Probe returns the versions of the components of the nodes of the
+	// cluster, and its problems, or nil when it is healthy. It returns a
+	// NodesNotReadyError when only nodes are not Ready.
+	Probe func(cluster string) ([]NodeVersions, error)
+	// Now returns the current time, and defaults to time.Now
+	Now func() time.Time
+}
+
+// Check probes the clusters that were applied, and records their health.
+// Clusters that are no longer applied are forgotten.
+func (h HealthChecker) Check() (map[string]Health, error) {
+	s, err := Reconciler{StateFile: h.StateFile}.readState()
+	if err != nil {
+		return nil, err
+	}
+	previous, err := ReadHealth(h.HealthFile)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	names := make([]string, 0, len(s.Clusters))
+	for name := range s.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	health := map[string]Health{}
+	for _, name := range names {
+		nodes, err := h.Probe(name)
+		c := Health{CheckedAt: now(), LastHealthyAt: previous[name].LastHealthyAt, Nodes: nodes}
+		if nodes == nil {
+			c.Nodes = previous[name].Nodes
+		}
+		if err != nil {
+			c.Summary = err.Error()
+			c.UnhealthyProbes = previous[name].UnhealthyProbes + 1
+			if nr, ok := err.(NodesNotReadyError); ok {
+				c.NotReadyNodes = nr.Nodes
+			}
+		} else {
+			c.Healthy = true
+			c.LastHealthyAt = &c.CheckedAt
+		}
+		health[name] = c
+	}
+	d, err := json.MarshalIndent(health, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling health: %v", err)
+	}
+	if err = writeFileAtomic(h.HealthFile, d, 0600); err != nil {
+		return nil, fmt.Errorf("error writing health file: %v", err)
+	}
+	return health, nil
+}
+
+// writeFileAtomic writes the file through a temporary file that is renamed,
+// so that readers never see a partially written file
+func writeFileAtomic(file string, d []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(d)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// ReadHealth returns the health of the clusters recorded in the file. It is
+// empty when no cluster was probed.
+func ReadHealth(file string) (map[string]Health, error) {
+	health := map[string]Health{}
+	d, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return health, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading health file: %v", err)
+	}
+	if err = json.Unmarshal(d, &health); err != nil {
+		return nil, fmt.Errorf("error unmarshaling health file: %v", err)
+	}
+	return health, nil
+}
+
+// WriteHealthMetrics writes the health of the clusters in the Prometheus text
+// format
+func WriteHealthMetrics(w io.Writer, health map[string]Health) {
+	names := make([]string, 0, len(health))
+	for name := range health {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "# HELP kismatic_cluster_healthy Whether the API server of the cluster was reachable and all its nodes were Ready when last probed.")
+	fmt.Fprintln(w, "# TYPE kismatic_cluster_healthy gauge")
+	for _, name := range names {
+		healthy := 0
+		if health[name].Healthy {
+			healthy = 1
+		}
+		fmt.Fprintf(w, "kismatic_cluster_healthy{cluster=%q} %d\n", name, healthy)
+	}
+	fmt.Fprintln(w, "# HELP kismatic_cluster_last_healthy_timestamp_seconds When the cluster was last found healthy.")
+	fmt.Fprintln(w, "# TYPE kismatic_cluster_last_healthy_timestamp_seconds gauge")
+	for _, name := range names {
+		if t := health[name].LastHealthyAt; t != nil {
+			fmt.Fprintf(w, "kismatic_cluster_last_healthy_timestamp_seconds{cluster=%q} %d\n", name, t.Unix())
+		}
+	}
+}
+
+// HealthHandler returns a handler that serves the health of the clusters
+// recorded in the file, in JSON, or in the Prometheus text format when
+// metrics is true
+func HealthHandler(file string, metrics bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		health, err := ReadHealth(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if metrics {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			WriteHealthMetrics(w, health)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(health)
+	})
+}
+
+// ClusterVersions are the versions of the components of the nodes of a
+// cluster
+type ClusterVersions struct {
+	Cluster   string         `json:"cluster"`
+	CheckedAt time.Time      `json:"checkedAt"`
+	Skewed    bool           `json:"skewed"`
+	Nodes     []NodeVersions `json:"nodes"`
+}
+
+// VersionsHandler returns a handler that serves the versions of the
+// components of the nodes of a cluster on /clusters/<name>/versions, as
+// recorded in the health file
+func VersionsHandler(file string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 3 || parts[0] != "clusters" || parts[2] != "versions" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		health, err := ReadHealth(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h, ok := health[parts[1]]
+		if !ok {
+			http.Error(w, fmt.Sprintf("cluster %q has not been probed", parts[1]), http.StatusNotFound)
+			return
+		}
+		v := ClusterVersions{Cluster: parts[1], CheckedAt: h.CheckedAt, Nodes: h.Nodes}
+		if v.Nodes == nil {
+			v.Nodes = []NodeVersions{}
+		}
+		for _, n := range v.Nodes {
+			if len(n.Skew) > 0 {
+				v.Skewed = true
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	})
+}
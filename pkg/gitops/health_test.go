@@ -0,0 +1,221 @@
+package gitops
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitops-health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state.json")
+	r := Reconciler{StateFile: stateFile}
+	if err = r.writeState(&state{Clusters: map[string]string{"dev": "a", "prod": "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	unhealthy := map[string]bool{}
+	unreachable := map[string]bool{}
+	versions := []NodeVersions{{Node: "worker1", Kubelet: "v1.8.4"}}
+	h := HealthChecker{
+		StateFile:  stateFile,
+		HealthFile: filepath.Join(dir, "health.json"),
+		Probe: func(cluster string) ([]NodeVersions, error) {
+			if unreachable[cluster] {
+				return nil, errors.New("the API server is not reachable")
+			}
+			if unhealthy[cluster] {
+				return versions, NodesNotReadyError{Nodes: []string{"worker1"}, Total: 3}
+			}
+			return versions, nil
+		},
+		Now: func() time.Time { return now },
+	}
+	firstCheck := now
+
+	tests := []struct {
+		setup           func()
+		healthy         map[string]bool
+		lastHealthyAt   map[string]*time.Time
+		unhealthyProbes int
+		notReadyNodes   []string
+	}{
+		{
+			setup:         func() {},
+			healthy:       map[string]bool{"dev": true, "prod": true},
+			lastHealthyAt: map[string]*time.Time{"dev": &firstCheck, "prod": &firstCheck},
+		},
+		{
+			// the last time the cluster was healthy is kept
+			setup: func() {
+				now = now.Add(time.Minute)
+				unhealthy["prod"] = true
+			},
+			healthy:         map[string]bool{"dev": true, "prod": false},
+			lastHealthyAt:   map[string]*time.Time{"dev": &now, "prod": &firstCheck},
+			unhealthyProbes: 1,
+			notReadyNodes:   []string{"worker1"},
+		},
+		{
+			// clusters that are no longer applied are forgotten
+			setup: func() {
+				now = now.Add(time.Minute)
+				if err := r.writeState(&state{Clusters: map[string]string{"prod": "b"}}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			healthy:         map[string]bool{"prod": false},
+			lastHealthyAt:   map[string]*time.Time{"prod": &firstCheck},
+			unhealthyProbes: 2,
+			notReadyNodes:   []string{"worker1"},
+		},
+		{
+			// the versions of the nodes are kept when they cannot be listed
+			setup: func() {
+				now = now.Add(time.Minute)
+				unreachable["prod"] = true
+			},
+			healthy:         map[string]bool{"prod": false},
+			lastHealthyAt:   map[string]*time.Time{"prod": &firstCheck},
+			unhealthyProbes: 3,
+		},
+	}
+	for i, test := range tests {
+		test.setup()
+		health, err := h.Check()
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		recorded, err := ReadHealth(h.HealthFile)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error reading health: %v", i, err)
+		}
+		if len(health) != len(test.healthy) || len(recorded) != len(test.healthy) {
+			t.Errorf("test %d: expected the health of %d clusters, but got %d and recorded %d", i, len(test.healthy), len(health), len(recorded))
+		}
+		for c, healthy := range test.healthy {
+			if health[c].Healthy != healthy {
+				t.Errorf("test %d: expected cluster %q healthy to be %v, but got %v", i, c, healthy, health[c].Healthy)
+			}
+			if !healthy && health[c].Summary == "" {
+				t.Errorf("test %d: expected a summary of the problems of cluster %q", i, c)
+			}
+			if !healthy && !reflect.DeepEqual(health[c].NotReadyNodes, test.notReadyNodes) {
+				t.Errorf("test %d: expected the nodes of cluster %q that are not Ready to be %v, but got %v", i, c, test.notReadyNodes, health[c].NotReadyNodes)
+			}
+			if !reflect.DeepEqual(recorded[c].Nodes, versions) {
+				t.Errorf("test %d: expected the versions of the nodes of cluster %q to be %v, but got %v", i, c, versions, recorded[c].Nodes)
+			}
+			if !healthy && health[c].UnhealthyProbes != test.unhealthyProbes {
+				t.Errorf("test %d: expected %d unhealthy probes of cluster %q, but got %d", i, test.unhealthyProbes, c, health[c].UnhealthyProbes)
+			}
+			if !health[c].CheckedAt.Equal(now) {
+				t.Errorf("test %d: expected cluster %q to be checked at %s, but got %s", i, c, now, health[c].CheckedAt)
+			}
+			expected, got := test.lastHealthyAt[c], recorded[c].LastHealthyAt
+			if got == nil || !got.Equal(*expected) {
+				t.Errorf("test %d: expected cluster %q to be last healthy at %s, but got %v", i, c, expected, got)
+			}
+		}
+	}
+	// the health file is written through a temporary file that is renamed
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected only the state and health files, but got %d files", len(files))
+	}
+	if info, err := os.Stat(h.HealthFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the health file to have mode 0600, but got %v", info)
+	}
+}
+
+func TestWriteHealthMetrics(t *testing.T) {
+	at := time.Unix(1496318400, 0)
+	health := map[string]Health{
+		"prod": {Summary: "no nodes are registered with the API server"},
+		"dev":  {Healthy: true, LastHealthyAt: &at},
+	}
+	var b bytes.Buffer
+	WriteHealthMetrics(&b, health)
+	for _, m := range []string{
+		`kismatic_cluster_healthy{cluster="dev"} 1`,
+		`kismatic_cluster_healthy{cluster="prod"} 0`,
+		`kismatic_cluster_last_healthy_timestamp_seconds{cluster="dev"} 1496318400`,
+	} {
+		if !strings.Contains(b.String(), m+"\n") {
+			t.Errorf("expected metric %q in:\n%s", m, b.String())
+		}
+	}
+	if strings.Contains(b.String(), `kismatic_cluster_last_healthy_timestamp_seconds{cluster="prod"}`) {
+		t.Errorf("expected no last healthy timestamp for a cluster that was never healthy:\n%s", b.String())
+	}
+}
+
+func TestVersionsHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitops-versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "health.json")
+	health := map[string]Health{
+		"dev": {Healthy: true, Nodes: []NodeVersions{{Node: "worker1", Kubelet: "v1.8.4"}}},
+		"prod": {Healthy: true, Nodes: []NodeVersions{
+			{Node: "worker1", Kubelet: "v1.8.4"},
+			{Node: "worker2", Kubelet: "v1.7.5", Skew: []string{"kubelet v1.7.5 is not the target version v1.8.4"}},
+		}},
+	}
+	d, err := json.Marshal(health)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(file, d, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method string
+		path   string
+		status int
+		skewed bool
+		nodes  int
+	}{
+		{method: http.MethodGet, path: "/clusters/dev/versions", status: http.StatusOK, nodes: 1},
+		{method: http.MethodGet, path: "/clusters/prod/versions", status: http.StatusOK, skewed: true, nodes: 2},
+		{method: http.MethodGet, path: "/clusters/staging/versions", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/clusters/prod", status: http.StatusNotFound},
+		{method: http.MethodPost, path: "/clusters/prod/versions", status: http.StatusMethodNotAllowed},
+	}
+	for i, test := range tests {
+		rec := httptest.NewRecorder()
+		VersionsHandler(file).ServeHTTP(rec, httptest.NewRequest(test.method, test.path, nil))
+		if rec.Code != test.status {
+			t.Errorf("test %d: expected status %d, but got %d", i, test.status, rec.Code)
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var v ClusterVersions
+		if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+			t.Fatalf("test %d: unexpected error decoding versions: %v", i, err)
+		}
+		if v.Skewed != test.skewed || len(v.Nodes) != test.nodes {
+			t.Errorf("test %d: expected skewed %v with %d nodes, but got %v with %d nodes", i, test.skewed, test.nodes, v.Skewed, len(v.Nodes))
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package gitops
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+var planExtensions = []string{".yaml", ".yml", ".json", ".hcl"}
+
+// Reconciler applies the plan files in a directory that changed since they
+// were last applied. The name of each cluster is the name of its plan file,
+// without the extension.
+type Reconciler struct {
+	// Dir is the directory of the plan files
+	Dir string
+	// StateFile records the plan files that were applied
+	StateFile string
+	// Apply applies the plan file of the cluster. It may return a
+	// ClassifiedError, to set the class of the failure.
+	Apply func(cluster, planFile string) error
+	// Backoff is the delay before the plan files that failed are applied again
+	Backoff Backoff
+	// Now returns the current time, and defaults to time.Now
+	Now func() time.Time
+}
+
+// Result of a reconciliation
+type Result struct {
+	// Revision of the repository that was reconciled
+	Revision string
+	// Applied are the clusters that were applied
+	Applied []string
+	// Unchanged are the clusters that were not changed since they were applied
+	Unchanged []string
+	// Removed are the clusters whose plan files were removed
+	Removed []string
+	// Failed are the clusters that failed to apply, and the failures
+	Failed map[string]Failure
+	// Deferred are the clusters that failed to apply before, and are not
+	// applied again yet, and their last failures
+	Deferred map[string]Failure
+}
+
+type state struct {
+	Revision string `json:"revision"`
+	// Clusters maps the name of the cluster to the checksum of the plan file
+	// that was applied
+	Clusters map[string]string `json:"clusters"`
+	// Failures maps the name of the cluster to the last failure to apply its
+	// plan file
+	Failures map[string]Failure `json:"failures,omitempty"`
+}
+
+// Reconcile applies the plan files that are new, or that changed since they
+// were last applied. Plan files that fail to apply are applied again on the
+// next reconciliation that is past their backoff, unless they are not valid.
+// Clusters whose plan files were removed are reported, and forgotten.
+func (r Reconciler) Reconcile(revision string) (*Result, error) {
+	files, err := PlanFiles(r.Dir)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.readState()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	res := &Result{Revision: revision, Failed: map[string]Failure{}, Deferred: map[string]Failure{}}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checksum, err := planChecksum(files[name])
+		if err != nil {
+			return nil, err
+		}
+		if s.Clusters[name] == checksum {
+			delete(s.Failures, name)
+			res.Unchanged = append(res.Unchanged, name)
+			continue
+		}
+		last, failedBefore := s.Failures[name]
+		if s.deferred(name, checksum, now()) {
+			res.Deferred[name] = last
+			continue
+		}
+		if err := r.Apply(name, files[name]); err != nil {
+			f := Failure{Checksum: checksum, Class: classOf(err), Error: err.Error(), Attempts: 1}
+			if failedBefore && last.Checksum == checksum {
+				f.Attempts = last.Attempts + 1
+			}
+			if f.Class != FailureValidation {
+				f.NextRetryAt = now().Add(r.Backoff.delay(f.Attempts))
+			}
+			s.Failures[name] = f
+			res.Failed[name] = f
+			if err = r.writeState(s); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		delete(s.Failures, name)
+		s.Clusters[name] = checksum
+		res.Applied = append(res.Applied, name)
+		// Record each cluster as soon as it is applied, so that it is not
+		// applied again if the reconciliation is interrupted
+		if err = r.writeState(s); err != nil {
+			return nil, err
+		}
+	}
+	for name := range s.Clusters {
+		if _, ok := files[name]; !ok {
+			res.Removed = append(res.Removed, name)
+			delete(s.Clusters, name)
+		}
+	}
+	for name := range s.Failures {
+		if _, ok := files[name]; !ok {
+			delete(s.Failures, name)
+		}
+	}
+	sort.Strings(res.Removed)
+	s.Revision = revision
+	if err = r.writeState(s); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// The changes that a reconciliation makes to a cluster
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	// ChangeRemove forgets the cluster. KET does not destroy clusters.
+	ChangeRemove = "remove"
+)
+
+// Change is a change that the next reconciliation makes to a cluster
+type Change struct {
+	Cluster string
+	Action  string
+}
+
+// Diff returns the changes that the next reconciliation makes, sorted by
+// cluster, without applying them. Clusters whose plan files failed to apply
+// and are not applied again yet are not changed.
+func (r Reconciler) Diff() ([]Change, error) {
+	files, err := PlanFiles(r.Dir)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.readState()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	var changes []Change
+	for name, file := range files {
+		checksum, err := planChecksum(file)
+		if err != nil {
+			return nil, err
+		}
+		applied, ok := s.Clusters[name]
+		switch {
+		case applied == checksum || s.deferred(name, checksum, now()):
+		case ok:
+			changes = append(changes, Change{Cluster: name, Action: ChangeUpdate})
+		default:
+			changes = append(changes, Change{Cluster: name, Action: ChangeCreate})
+		}
+	}
+	for name := range s.Clusters {
+		if _, ok := files[name]; !ok {
+			changes = append(changes, Change{Cluster: name, Action: ChangeRemove})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Cluster < changes[j].Cluster })
+	return changes, nil
+}
+
+// deferred returns whether the plan file of the cluster failed to apply, and
+// is not applied again yet
+func (s *state) deferred(name, checksum string, now time.Time) bool {
+	last, ok := s.Failures[name]
+	return ok && last.Checksum == checksum && (!last.Retryable() || now.Before(last.NextRetryAt))
+}
+
+func planChecksum(file string) (string, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("error reading plan file: %v", err)
+	}
+	sum := sha256.Sum256(d)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// PlanFiles returns the plan files in the directory, keyed by cluster name.
+// The name of each cluster is the name of its plan file, without the extension.
+func PlanFiles(dir string) (map[string]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan files directory: %v", err)
+	}
+	files := map[string]string{}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || !util.Contains(ext, planExtensions) {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), ext)
+		if existing, ok := files[name]; ok {
+			return nil, fmt.Errorf("found more than one plan file for cluster %q: %q and %q", name, filepath.Base(existing), e.Name())
+		}
+		files[name] = filepath.Join(dir, e.Name())
+	}
+	return files, nil
+}
+
+func (r Reconciler) readState() (*state, error) {
+	s := &state{Clusters: map[string]string{}, Failures: map[string]Failure{}}
+	d, err := ioutil.ReadFile(r.StateFile)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %v", err)
+	}
+	if err = json.Unmarshal(d, s); err != nil {
+		return nil, fmt.Errorf("error unmarshaling state file: %v", err)
+	}
+	if s.Clusters == nil {
+		s.Clusters = map[string]string{}
+	}
+	if s.Failures == nil {
+		s.Failures = map[string]Failure{}
+	}
+	return s, nil
+}
+
+func (r Reconciler) writeState(s *state) error {
+	d, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling state: %v", err)
+	}
+	if err = ioutil.WriteFile(r.StateFile, d, 0600); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package gitops
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconcile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitops-reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	plans := filepath.Join(dir, "clusters")
+	if err = os.Mkdir(plans, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writePlan := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(plans, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var applied []string
+	failing := map[string]bool{}
+	r := Reconciler{
+		Dir:       plans,
+		StateFile: filepath.Join(dir, "state.json"),
+		Apply: func(cluster, planFile string) error {
+			if failing[cluster] {
+				return errors.New("apply failed")
+			}
+			applied = append(applied, cluster)
+			return nil
+		},
+	}
+
+	tests := []struct {
+		setup     func()
+		applied   []string
+		unchanged []string
+		removed   []string
+		failed    []string
+	}{
+		{
+			setup: func() {
+				writePlan("dev.yaml", "cluster:\n  name: dev\n")
+				writePlan("test.hcl", "cluster {\n  name = \"test\"\n}\n")
+				writePlan("README.md", "not a plan file")
+			},
+			applied: []string{"dev", "test"},
+		},
+		{
+			setup:     func() {},
+			unchanged: []string{"dev", "test"},
+		},
+		{
+			setup: func() {
+				writePlan("dev.yaml", "cluster:\n  name: dev\n  admin_password: foo\n")
+				writePlan("staging.yaml", "cluster:\n  name: staging\n")
+				failing["staging"] = true
+			},
+			applied:   []string{"dev"},
+			unchanged: []string{"test"},
+			failed:    []string{"staging"},
+		},
+		{
+			setup: func() {
+				failing["staging"] = false
+				os.Remove(filepath.Join(plans, "test.hcl"))
+			},
+			applied:   []string{"staging"},
+			unchanged: []string{"dev"},
+			removed:   []string{"test"},
+		},
+	}
+	for i, test := range tests {
+		applied = nil
+		test.setup()
+		res, err := r.Reconcile("rev")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		var failed []string
+		for name := range res.Failed {
+			failed = append(failed, name)
+		}
+		if !reflect.DeepEqual(res.Applied, test.applied) || !reflect.DeepEqual(applied, test.applied) {
+			t.Errorf("test %d: expected applied %v, but got %v", i, test.applied, res.Applied)
+		}
+		if !reflect.DeepEqual(res.Unchanged, test.unchanged) {
+			t.Errorf("test %d: expected unchanged %v, but got %v", i, test.unchanged, res.Unchanged)
+		}
+		if !reflect.DeepEqual(res.Removed, test.removed) {
+			t.Errorf("test %d: expected removed %v, but got %v", i, test.removed, res.Removed)
+		}
+		if !reflect.DeepEqual(failed, test.failed) {
+			t.Errorf("test %d: expected failed %v, but got %v", i, test.failed, failed)
+		}
+	}
+}
+
+func TestReconcileDuplicateCluster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitops-reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, f := range []string{"dev.yaml", "dev.hcl"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, f), []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := Reconciler{
+		Dir:       dir,
+		StateFile: filepath.Join(dir, "state.json"),
+		Apply:     func(string, string) error { return nil },
+	}
+	if _, err = r.Reconcile("rev"); err == nil {
+		t.Error("expected an error, but didn't get one")
+	}
+}
+
+func TestReconcileBackoff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitops-reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	plans := filepath.Join(dir, "clusters")
+	if err = os.Mkdir(plans, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(plans, "dev.yaml"), []byte("cluster:\n  name: dev\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	var applyErr error
+	applies := 0
+	r := Reconciler{
+		Dir:       plans,
+		StateFile: filepath.Join(dir, "state.json"),
+		Apply: func(string, string) error {
+			applies++
+			return applyErr
+		},
+		Backoff: Backoff{Initial: time.Minute, Max: 3 * time.Minute},
+		Now:     func() time.Time { return now },
+	}
+	tests := []struct {
+		after       time.Duration
+		applyErr    error
+		applied     bool
+		deferred    bool
+		attempts    int
+		nextRetryIn time.Duration
+	}{
+		{
+			applyErr:    ClassifiedError{Class: FailureUnreachable, Err: errors.New("unreachable")},
+			applied:     true,
+			attempts:    1,
+			nextRetryIn: time.Minute,
+		},
+		{
+			after:    30 * time.Second,
+			deferred: true,
+		},
+		{
+			after:       30 * time.Second,
+			applyErr:    errors.New("playbook failed"),
+			applied:     true,
+			attempts:    2,
+			nextRetryIn: 2 * time.Minute,
+		},
+		{
+			after:       2 * time.Minute,
+			applyErr:    errors.New("playbook failed"),
+			applied:     true,
+			attempts:    3,
+			nextRetryIn: 3 * time.Minute,
+		},
+		{
+			after:   3 * time.Minute,
+			applied: true,
+		},
+	}
+	for i, test := range tests {
+		now = now.Add(test.after)
+		applyErr = test.applyErr
+		applies = 0
+		res, err := r.Reconcile("rev")
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		if (applies == 1) != test.applied {
+			t.Errorf("test %d: expected applied to be %v, but it was applied %d times", i, test.applied, applies)
+		}
+		if _, ok := res.Deferred["dev"]; ok != test.deferred {
+			t.Errorf("test %d: expected deferred to be %v", i, test.deferred)
+		}
+		f, failed := res.Failed["dev"]
+		if failed != (test.attempts > 0) {
+			t.Fatalf("test %d: expected failed to be %v", i, test.attempts > 0)
+		}
+		if !failed {
+			continue
+		}
+		if f.Attempts != test.attempts {
+			t.Errorf("test %d: expected %d attempts, but got %d", i, test.attempts, f.Attempts)
+		}
+		if !f.NextRetryAt.Equal(now.Add(test.nextRetryIn)) {
+			t.Errorf("test %d: expected next retry at %v, but got %v", i, now.Add(test.nextRetryIn), f.NextRetryAt)
+		}
+	}
+}
+
+func TestReconcileValidationFailureNotRetried(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitops-reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	plans := filepath.Join(dir, "clusters")
+	if err = os.Mkdir(plans, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writePlan := func(content string) {
+		if err := ioutil.WriteFile(filepath.Join(plans, "dev.yaml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	applies := 0
+	r := Reconciler{
+		Dir:       plans,
+		StateFile: filepath.Join(dir, "state.json"),
+		Apply: func(string, string) error {
+			applies++
+			return ClassifiedError{Class: FailureValidation, Err: errors.New("plan file is invalid")}
+		},
+	}
+	writePlan("cluster:\n  name: dev\n")
+	for i := 0; i < 2; i++ {
+		if _, err = r.Reconcile("rev"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if applies != 1 {
+		t.Errorf("expected the invalid plan file to be applied once, but it was applied %d times", applies)
+	}
+	writePlan("cluster:\n  name: dev\n  admin_password: foo\n")
+	if _, err = r.Reconcile("rev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applies != 2 {
+		t.Errorf("expected the changed plan file to be applied, but it was applied %d times", applies)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitops-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	plans := filepath.Join(dir, "clusters")
+	if err = os.Mkdir(plans, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writePlan := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(plans, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failing := map[string]bool{}
+	r := Reconciler{
+		Dir:       plans,
+		StateFile: filepath.Join(dir, "state.json"),
+		Apply: func(cluster, planFile string) error {
+			if failing[cluster] {
+				return ClassifiedError{Class: FailureValidation, Err: errors.New("plan file is invalid")}
+			}
+			return nil
+		},
+	}
+	writePlan("dev.yaml", "cluster:\n  name: dev\n")
+	writePlan("prod.yaml", "cluster:\n  name: prod\n")
+	writePlan("test.yaml", "cluster:\n  name: test\n")
+	failing["test"] = true
+	if _, err = r.Reconcile("rev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writePlan("dev.yaml", "cluster:\n  name: dev\n  admin_password: foo\n")
+	writePlan("staging.yaml", "cluster:\n  name: staging\n")
+	if err = os.Remove(filepath.Join(plans, "prod.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := r.Diff()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the invalid test plan file is not applied again until it changes
+	expected := []Change{
+		{Cluster: "dev", Action: ChangeUpdate},
+		{Cluster: "prod", Action: ChangeRemove},
+		{Cluster: "staging", Action: ChangeCreate},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes %v, but got %v", expected, changes)
+	}
+}
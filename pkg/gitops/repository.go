@@ -0,0 +1,59 @@
+// Package gitops keeps clusters in sync with the plan files stored in a git
+// repository.
+package gitops
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repository is a git repository that is checked out to a local directory
+type Repository struct {
+	// URL of the repository
+	URL string
+	// Branch that is checked out
+	Branch string
+	// Dir is the directory the repository is checked out to
+	Dir string
+}
+
+// Sync checks out the latest revision of the branch, discarding any local
+// changes, and returns the revision.
+func (r Repository) Sync() (string, error) {
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(r.Dir), 0700); err != nil {
+			return "", fmt.Errorf("error creating directory for repository: %v", err)
+		}
+		if _, err = git("", "clone", "--branch", r.Branch, "--single-branch", r.URL, r.Dir); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err = git(r.Dir, "fetch", "origin", r.Branch); err != nil {
+			return "", err
+		}
+		if _, err = git(r.Dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+		if _, err = git(r.Dir, "clean", "-fdx"); err != nil {
+			return "", err
+		}
+	}
+	rev, err := git(r.Dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(rev), nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error running git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
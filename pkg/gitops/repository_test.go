@@ -0,0 +1,70 @@
+package gitops
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepositorySync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "gitops-repository")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	origin := filepath.Join(dir, "origin")
+	commit := func(file, content string) {
+		if err := ioutil.WriteFile(filepath.Join(origin, file), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "-A"}, {"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", file}} {
+			if _, err := git(origin, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = os.Mkdir(origin, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = git(origin, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = git(origin, "checkout", "-q", "-b", "clusters"); err != nil {
+		t.Fatal(err)
+	}
+	commit("dev.yaml", "cluster:\n  name: dev\n")
+
+	r := Repository{URL: origin, Branch: "clusters", Dir: filepath.Join(dir, "checkout", "repo")}
+	first, err := r.Sync()
+	if err != nil {
+		t.Fatalf("unexpected error cloning repository: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(r.Dir, "dev.yaml")); err != nil {
+		t.Errorf("expected plan file in checkout: %v", err)
+	}
+
+	// local changes are discarded
+	if err = ioutil.WriteFile(filepath.Join(r.Dir, "dev.yaml"), []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	commit("test.yaml", "cluster:\n  name: test\n")
+	second, err := r.Sync()
+	if err != nil {
+		t.Fatalf("unexpected error syncing repository: %v", err)
+	}
+	if first == second {
+		t.Errorf("expected revision to change after sync, but got %q", second)
+	}
+	d, err := ioutil.ReadFile(filepath.Join(r.Dir, "dev.yaml"))
+	if err != nil || string(d) != "cluster:\n  name: dev\n" {
+		t.Errorf("expected local changes to be discarded, got %q (%v)", d, err)
+	}
+	if _, err = os.Stat(filepath.Join(r.Dir, "test.yaml")); err != nil {
+		t.Errorf("expected new plan file in checkout: %v", err)
+	}
+}
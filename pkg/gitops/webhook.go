@@ -0,0 +1,50 @@
+package gitops
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// WebhookHandler returns a handler for the push events of the repository,
+// that triggers a sync. When the secret is set, the request must be signed
+// with it in the X-Hub-Signature-256 header, as GitHub signs webhooks.
+func WebhookHandler(secret string, sync chan<- struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if secret != "" && !validSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// A sync is already pending when the channel is full
+		select {
+		case sync <- struct{}{}:
+		default:
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func validSignature(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
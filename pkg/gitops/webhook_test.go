@@ -0,0 +1,69 @@
+package gitops
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/master"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		method    string
+		secret    string
+		signature string
+		status    int
+		synced    bool
+	}{
+		{
+			method: "POST",
+			status: http.StatusAccepted,
+			synced: true,
+		},
+		{
+			method:    "POST",
+			secret:    "secret",
+			signature: signature,
+			status:    http.StatusAccepted,
+			synced:    true,
+		},
+		{
+			method:    "POST",
+			secret:    "secret",
+			signature: "sha256=00",
+			status:    http.StatusUnauthorized,
+		},
+		{
+			method: "POST",
+			secret: "secret",
+			status: http.StatusUnauthorized,
+		},
+		{
+			method: "GET",
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+	for i, test := range tests {
+		sync := make(chan struct{}, 1)
+		req := httptest.NewRequest(test.method, "/", bytes.NewReader(body))
+		if test.signature != "" {
+			req.Header.Set("X-Hub-Signature-256", test.signature)
+		}
+		rec := httptest.NewRecorder()
+		WebhookHandler(test.secret, sync).ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("test %d: expected status %d, but got %d", i, test.status, rec.Code)
+		}
+		if synced := len(sync) == 1; synced != test.synced {
+			t.Errorf("test %d: expected sync to be triggered %v, but got %v", i, test.synced, synced)
+		}
+	}
+}
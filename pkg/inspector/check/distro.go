@@ -12,8 +12,10 @@ import (
 
 const (
 	Ubuntu      Distro = "ubuntu"
+	Debian      Distro = "debian"
 	RHEL        Distro = "rhel"
 	CentOS      Distro = "centos"
+	Rocky       Distro = "rocky"
 	Darwin      Distro = "darwin"
 	Unsupported Distro = ""
 )
@@ -21,46 +23,112 @@ const (
 // Distro is a Linux distribution that the inspector supports
 type Distro string
 
-// DetectDistro uses the /etc/os-release file to get distro information.
-func DetectDistro() (Distro, error) {
+// supportedReleases is the list of supported versions of each distribution.
+// Versions are matched against the VERSION_ID field of /etc/os-release, or
+// its major version. Only the releases that the package repositories of the
+// playbooks (el7, xenial and stretch) can be installed on are listed:
+// docker-engine 1.12.6 and GlusterFS 3.8 are not published for Ubuntu 18.04
+// and later, or for EL8. Rocky Linux is detected, but none of its releases is
+// supported.
+var supportedReleases = map[Distro][]string{
+	Ubuntu: {"16.04"},
+	Debian: {"9"},
+	RHEL:   {"7"},
+	CentOS: {"7"},
+}
+
+// OSRelease is the operating system release running on a node
+type OSRelease struct {
+	// Distro is the detected distribution, or Unsupported
+	Distro Distro
+	// ID and VersionID are the values of the ID and VERSION_ID fields
+	// of /etc/os-release
+	ID        string
+	VersionID string
+}
+
+func (o OSRelease) String() string {
+	if o.VersionID == "" {
+		return o.ID
+	}
+	return fmt.Sprintf("%s %s", o.ID, o.VersionID)
+}
+
+// Supported returns an error describing why the release is not supported,
+// or nil if it is supported
+func (o OSRelease) Supported() error {
+	if o.Distro == Darwin {
+		return nil
+	}
+	versions, ok := supportedReleases[o.Distro]
+	if !ok {
+		return fmt.Errorf("Unsupported distribution detected: %s. Supported releases are: %s", o.ID, supportedReleasesString())
+	}
+	major := strings.SplitN(o.VersionID, ".", 2)[0]
+	for _, v := range versions {
+		if o.VersionID == v || major == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unsupported release of %s detected: %s. Supported releases are: %s", o.ID, o.VersionID, supportedReleasesString())
+}
+
+func supportedReleasesString() string {
+	var releases []string
+	for _, d := range []Distro{Ubuntu, Debian, RHEL, CentOS} {
+		releases = append(releases, fmt.Sprintf("%s %s", d, strings.Join(supportedReleases[d], ", ")))
+	}
+	return strings.Join(releases, "; ")
+}
+
+// DetectOSRelease uses the /etc/os-release file to get the distribution and
+// its version. The distribution is Unsupported if it is not known to the
+// inspector.
+func DetectOSRelease() (OSRelease, error) {
 	if runtime.GOOS == "darwin" {
-		return Darwin, nil
+		return OSRelease{Distro: Darwin, ID: "darwin"}, nil
 	}
 	f, err := os.Open("/etc/os-release")
 	if err != nil {
-		return Unsupported, fmt.Errorf("error reading /etc/os-release file: %v", err)
+		return OSRelease{}, fmt.Errorf("error reading /etc/os-release file: %v", err)
 	}
 	defer f.Close()
-	return detectDistroFromOSRelease(f)
+	return readOSRelease(f)
 }
 
-func detectDistroFromOSRelease(r io.Reader) (Distro, error) {
-	idLine := ""
+func readOSRelease(r io.Reader) (OSRelease, error) {
+	fields := map[string]string{}
 	s := bufio.NewScanner(r)
 	for s.Scan() {
 		l := s.Text()
-		if strings.HasPrefix(l, "ID=") {
-			idLine = l
-			break
+		if !strings.HasPrefix(l, "ID=") && !strings.HasPrefix(l, "VERSION_ID=") {
+			continue
 		}
+		kv := strings.Split(l, "=")
+		if len(kv) != 2 {
+			return OSRelease{}, fmt.Errorf("Unknown format of /etc/os-release file. Line was: %s", l)
+		}
+		// Remove double-quotes from field value
+		fields[kv[0]] = strings.Replace(kv[1], "\"", "", -1)
 	}
-	if idLine == "" {
-		return Unsupported, errors.New("/etc/os-release file does not contain ID= field")
-	}
-	fields := strings.Split(idLine, "=")
-	if len(fields) != 2 {
-		return Unsupported, fmt.Errorf("Unknown format of /etc/os-release file. ID line was: %s", idLine)
+	id, ok := fields["ID"]
+	if !ok {
+		return OSRelease{}, errors.New("/etc/os-release file does not contain ID= field")
 	}
-
-	// Remove double-quotes from field value
-	switch strings.Replace(fields[1], "\"", "", -1) {
+	o := OSRelease{ID: id, VersionID: fields["VERSION_ID"]}
+	switch id {
 	case "centos":
-		return CentOS, nil
+		o.Distro = CentOS
 	case "rhel":
-		return RHEL, nil
+		o.Distro = RHEL
+	case "rocky":
+		o.Distro = Rocky
 	case "ubuntu":
-		return Ubuntu, nil
+		o.Distro = Ubuntu
+	case "debian":
+		o.Distro = Debian
 	default:
-		return Unsupported, fmt.Errorf("Unsupported distribution detected: %s", fields[1])
+		o.Distro = Unsupported
 	}
+	return o, nil
 }
@@ -5,26 +5,42 @@ import (
 	"testing"
 )
 
-func TestDetectDistroFromOSRelease(t *testing.T) {
+func TestReadOSRelease(t *testing.T) {
 	tests := []struct {
-		osReleaseFile  string
-		expectedDistro Distro
-		expectErr      bool
+		osReleaseFile     string
+		expectedDistro    Distro
+		expectedVersionID string
+		expectErr         bool
 	}{
 		{
-			osReleaseFile:  centos7ReleaseFile,
-			expectedDistro: CentOS,
-			expectErr:      false,
+			osReleaseFile:     centos7ReleaseFile,
+			expectedDistro:    CentOS,
+			expectedVersionID: "7",
 		},
 		{
-			osReleaseFile:  rhel7ReleaseFile,
-			expectedDistro: RHEL,
-			expectErr:      false,
+			osReleaseFile:     rhel7ReleaseFile,
+			expectedDistro:    RHEL,
+			expectedVersionID: "7.2",
 		},
 		{
-			osReleaseFile:  ubuntu1604ReleaseFile,
-			expectedDistro: Ubuntu,
-			expectErr:      false,
+			osReleaseFile:     ubuntu1604ReleaseFile,
+			expectedDistro:    Ubuntu,
+			expectedVersionID: "16.04",
+		},
+		{
+			osReleaseFile:     debian9ReleaseFile,
+			expectedDistro:    Debian,
+			expectedVersionID: "9",
+		},
+		{
+			osReleaseFile:     rocky8ReleaseFile,
+			expectedDistro:    Rocky,
+			expectedVersionID: "8.5",
+		},
+		{
+			osReleaseFile:     fedoraReleaseFile,
+			expectedDistro:    Unsupported,
+			expectedVersionID: "27",
 		},
 		{
 			osReleaseFile:  "",
@@ -39,7 +55,7 @@ func TestDetectDistroFromOSRelease(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		d, err := detectDistroFromOSRelease(strings.NewReader(test.osReleaseFile))
+		o, err := readOSRelease(strings.NewReader(test.osReleaseFile))
 		if test.expectErr && err == nil {
 			t.Error("expected an error, but didn't get one")
 		}
@@ -48,8 +64,43 @@ func TestDetectDistroFromOSRelease(t *testing.T) {
 			t.Errorf("unexpected error occurred when running test: %v", err)
 		}
 
-		if d != test.expectedDistro {
-			t.Errorf("failed to detect distro. expected %s, found %s", test.expectedDistro, d)
+		if o.Distro != test.expectedDistro {
+			t.Errorf("failed to detect distro. expected %s, found %s", test.expectedDistro, o.Distro)
+		}
+
+		if o.VersionID != test.expectedVersionID {
+			t.Errorf("failed to detect version. expected %s, found %s", test.expectedVersionID, o.VersionID)
+		}
+	}
+}
+
+func TestOSReleaseSupported(t *testing.T) {
+	tests := []struct {
+		release   OSRelease
+		supported bool
+	}{
+		{OSRelease{Distro: Ubuntu, ID: "ubuntu", VersionID: "16.04"}, true},
+		{OSRelease{Distro: Ubuntu, ID: "ubuntu", VersionID: "18.04"}, false},
+		{OSRelease{Distro: Ubuntu, ID: "ubuntu", VersionID: "17.10"}, false},
+		{OSRelease{Distro: Ubuntu, ID: "ubuntu", VersionID: "14.04"}, false},
+		{OSRelease{Distro: Debian, ID: "debian", VersionID: "9"}, true},
+		{OSRelease{Distro: Debian, ID: "debian", VersionID: "8"}, false},
+		{OSRelease{Distro: Debian, ID: "debian", VersionID: "10"}, false},
+		{OSRelease{Distro: RHEL, ID: "rhel", VersionID: "7.2"}, true},
+		{OSRelease{Distro: RHEL, ID: "rhel", VersionID: "8.4"}, false},
+		{OSRelease{Distro: RHEL, ID: "rhel", VersionID: "6.9"}, false},
+		{OSRelease{Distro: CentOS, ID: "centos", VersionID: "7"}, true},
+		{OSRelease{Distro: Rocky, ID: "rocky", VersionID: "8.5"}, false},
+		{OSRelease{Distro: Unsupported, ID: "fedora", VersionID: "27"}, false},
+		{OSRelease{Distro: Darwin, ID: "darwin"}, true},
+	}
+	for _, test := range tests {
+		err := test.release.Supported()
+		if test.supported && err != nil {
+			t.Errorf("expected %s to be supported, but got error: %v", test.release, err)
+		}
+		if !test.supported && err == nil {
+			t.Errorf("expected %s to be unsupported, but it was supported", test.release)
 		}
 	}
 }
@@ -97,6 +148,35 @@ SUPPORT_URL="http://help.ubuntu.com/"
 BUG_REPORT_URL="http://bugs.launchpad.net/ubuntu/"
 UBUNTU_CODENAME=xenial`
 
+var debian9ReleaseFile = `PRETTY_NAME="Debian GNU/Linux 9 (stretch)"
+NAME="Debian GNU/Linux"
+VERSION_ID="9"
+VERSION="9 (stretch)"
+ID=debian
+HOME_URL="https://www.debian.org/"
+SUPPORT_URL="https://www.debian.org/support"
+BUG_REPORT_URL="https://bugs.debian.org/"`
+
+var rocky8ReleaseFile = `NAME="Rocky Linux"
+VERSION="8.5 (Green Obsidian)"
+ID="rocky"
+ID_LIKE="rhel centos fedora"
+VERSION_ID="8.5"
+PLATFORM_ID="platform:el8"
+PRETTY_NAME="Rocky Linux 8.5 (Green Obsidian)"
+ANSI_COLOR="0;32"
+CPE_NAME="cpe:/o:rocky:rocky:8:GA"
+HOME_URL="https://rockylinux.org/"
+BUG_REPORT_URL="https://bugs.rockylinux.org/"`
+
+var fedoraReleaseFile = `NAME=Fedora
+VERSION="27 (Server Edition)"
+ID=fedora
+VERSION_ID=27
+PRETTY_NAME="Fedora 27 (Server Edition)"
+ANSI_COLOR="0;34"
+CPE_NAME="cpe:/o:fedoraproject:fedora:27"`
+
 var missingIDFieldOSReleaseFile = `NAME="Ubuntu"
 VERSION="16.04.1 LTS (Xenial Xerus)"
 ID_LIKE=debian
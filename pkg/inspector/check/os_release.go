@@ -0,0 +1,15 @@
+package check
+
+// OSReleaseCheck returns true if the operating system release running on the
+// node is supported
+type OSReleaseCheck struct {
+	OSRelease OSRelease
+}
+
+// Check returns an error explaining why the release is not supported
+func (c OSReleaseCheck) Check() (bool, error) {
+	if err := c.OSRelease.Supported(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -22,15 +22,17 @@ func NewPackageManager(distro Distro) (PackageManager, error) {
 		return r, err
 	}
 	switch distro {
-	case RHEL, CentOS:
+	case RHEL, CentOS, Rocky:
 		return &rpmManager{
 			run: run,
 		}, nil
-	case Ubuntu:
+	case Ubuntu, Debian:
 		return &debManager{
 			run: run,
 		}, nil
-	case Darwin:
+	case Darwin, Unsupported:
+		// package checks do not apply to unsupported distributions, as
+		// the node fails the supported operating system check instead
 		return noopManager{}, nil
 	default:
 		return nil, fmt.Errorf("%s is not supported", distro)
@@ -2,6 +2,7 @@ package inspector
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -16,7 +17,10 @@ type Client struct {
 	TargetNode string
 	// TargetNodeRole is the role of the node we are inspecting
 	TargetNodeFacts []string
-	engine          *rule.Engine
+	// TLSConfig is used to connect to an inspector server that serves over
+	// HTTPS. The connection uses HTTP when nil.
+	TLSConfig *tls.Config
+	engine    *rule.Engine
 }
 
 // NewClient returns an inspector client for running checks against remote nodes.
@@ -45,7 +49,8 @@ func (c Client) ExecuteRules(rules []rule.Rule) ([]rule.Result, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling check request: %v", err)
 	}
-	resp, err := http.Post(fmt.Sprintf("http://%s%s", c.TargetNode, executeEndpoint), "application/json", bytes.NewReader(d))
+	httpClient := c.httpClient()
+	resp, err := httpClient.Post(c.url(executeEndpoint), "application/json", bytes.NewReader(d))
 	if err != nil {
 		return nil, fmt.Errorf("error posting request to server: %v", err)
 	}
@@ -77,8 +82,8 @@ func (c Client) ExecuteRules(rules []rule.Rule) ([]rule.Result, error) {
 	}
 	results = append(results, remoteResults...)
 
-	endpoint := fmt.Sprintf("http://%s%s", c.TargetNode, closeEndpoint)
-	resp, err = http.Get(endpoint)
+	endpoint := c.url(closeEndpoint)
+	resp, err = httpClient.Get(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("GET request to %q failed. You might have to restart the inspector server. Error was: %v", endpoint, err)
 	}
@@ -86,6 +91,21 @@ func (c Client) ExecuteRules(rules []rule.Rule) ([]rule.Result, error) {
 	return results, nil
 }
 
+func (c Client) url(endpoint string) string {
+	scheme := "http"
+	if c.TLSConfig != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, c.TargetNode, endpoint)
+}
+
+func (c Client) httpClient() *http.Client {
+	if c.TLSConfig == nil {
+		return http.DefaultClient
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: c.TLSConfig}}
+}
+
 func getServerSideRules(rules []rule.Rule) []rule.Rule {
 	localRules := []rule.Rule{}
 	for _, r := range rules {
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/apprenda/kismatic/pkg/inspector"
 	"github.com/spf13/cobra"
@@ -15,6 +18,7 @@ type clientOpts struct {
 	rulesFile          string
 	targetNode         string
 	useUpgradeDefaults bool
+	caFile             string
 }
 
 var clientExample = `# Run the inspector against an etcd node
@@ -46,6 +50,7 @@ func NewCmdClient(out io.Writer) *cobra.Command {
 	cmd.Flags().StringVar(&opts.nodeRoles, "node-roles", "", "comma-separated list of the node's roles. Valid roles are 'etcd', 'master', 'worker'")
 	cmd.Flags().StringVarP(&opts.rulesFile, "file", "f", "", "the path to an inspector rules file. If blank, the inspector uses the default rules")
 	cmd.Flags().BoolVarP(&opts.useUpgradeDefaults, "upgrade", "u", false, "use defaults for upgrade, rather than install")
+	cmd.Flags().StringVar(&opts.caFile, "ca-file", "", "the path to the CA certificate that signed the certificate of the inspector server. When set, the client connects to the server over HTTPS")
 	return cmd
 }
 
@@ -64,6 +69,17 @@ func runClient(out io.Writer, opts clientOpts) error {
 	if err != nil {
 		return fmt.Errorf("error creating inspector client: %v", err)
 	}
+	if opts.caFile != "" {
+		ca, err := ioutil.ReadFile(opts.caFile)
+		if err != nil {
+			return fmt.Errorf("error reading CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificates found in %q", opts.caFile)
+		}
+		c.TLSConfig = &tls.Config{RootCAs: pool}
+	}
 	rules, err := getRulesFromFileOrDefault(out, opts.rulesFile, opts.useUpgradeDefaults)
 	if err != nil {
 		return err
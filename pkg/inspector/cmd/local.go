@@ -58,11 +58,11 @@ func runLocal(out io.Writer, opts localOpts) error {
 		return err
 	}
 	// Set up engine dependencies
-	distro, err := check.DetectDistro()
+	osRelease, err := check.DetectOSRelease()
 	if err != nil {
 		return fmt.Errorf("error running checks locally: %v", err)
 	}
-	pkgMgr, err := check.NewPackageManager(distro)
+	pkgMgr, err := check.NewPackageManager(osRelease.Distro)
 	if err != nil {
 		return err
 	}
@@ -72,9 +72,10 @@ func runLocal(out io.Writer, opts localOpts) error {
 		RuleCheckMapper: rule.DefaultCheckMapper{
 			PackageManager:              pkgMgr,
 			PackageInstallationDisabled: opts.packageInstallationDisabled,
+			OSRelease:                   osRelease,
 		},
 	}
-	labels := append(roles, string(distro))
+	labels := append(roles, string(osRelease.Distro))
 	results, err := e.ExecuteRules(rules, labels)
 	if err != nil {
 		return fmt.Errorf("error running local rules: %v", err)
@@ -3,9 +3,15 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/apprenda/kismatic/pkg/inspector"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var serverExample = `# Run the inspector in server mode
@@ -13,51 +19,121 @@ kismatic-inspector server --node-roles master,worker
 
 # Run the inspector in server mode, in a specific port
 kismatic-inspector server --port 9000 --node-roles master
+
+# Run the inspector in server mode, using a configuration file
+kismatic-inspector server --config inspector-server.yaml
 `
 
+type serverOpts struct {
+	configFile                  string
+	port                        int
+	nodeRoles                   string
+	packageInstallationDisabled bool
+	disconnectedInstallation    bool
+	enablePprof                 bool
+}
+
 // NewCmdServer returns the "server" command
 func NewCmdServer(out io.Writer) *cobra.Command {
-	var port int
-	var nodeRoles string
-	var packageInstallationDisabled bool
-	var disconnectedInstallation bool
+	opts := serverOpts{}
 	cmd := &cobra.Command{
 		Use:     "server",
 		Short:   "Stand up the inspector server for running checks remotely",
 		Example: serverExample,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServer(out, cmd.Parent().Name(), port, nodeRoles, packageInstallationDisabled, disconnectedInstallation)
+			return runServer(out, cmd.Parent().Name(), cmd.Flags(), opts)
 		},
 	}
-	cmd.Flags().IntVar(&port, "port", 9090, "the port number for standing up the Inspector server")
-	cmd.Flags().StringVar(&nodeRoles, "node-roles", "", "comma-separated list of the node's roles. Valid roles are 'etcd', 'master', 'worker', 'ingress', 'storage'")
-	cmd.Flags().BoolVar(&packageInstallationDisabled, "pkg-installation-disabled", false, "when true, the inspector will ensure that the necessary packages are installed on the node")
-	cmd.Flags().BoolVar(&disconnectedInstallation, "disconnected-installation", false, "when true will check for the required packages needed during a disconnected install")
+	cmd.Flags().StringVar(&opts.configFile, "config", "", "the path to the server configuration file. The flags that are set override the configuration file. Sending SIGHUP to the server reloads the log level, the request limit, the admin token and the lifetime of the session tokens from the file")
+	cmd.Flags().IntVar(&opts.port, "port", 9090, "the port number for standing up the Inspector server")
+	cmd.Flags().StringVar(&opts.nodeRoles, "node-roles", "", "comma-separated list of the node's roles. Valid roles are 'etcd', 'master', 'worker', 'ingress', 'storage'")
+	cmd.Flags().BoolVar(&opts.packageInstallationDisabled, "pkg-installation-disabled", false, "when true, the inspector will ensure that the necessary packages are installed on the node")
+	cmd.Flags().BoolVar(&opts.disconnectedInstallation, "disconnected-installation", false, "when true will check for the required packages needed during a disconnected install")
+	cmd.Flags().BoolVar(&opts.enablePprof, "enable-pprof", false, "serve the pprof profiles under /debug/pprof/. Requires the admin_token in the configuration file")
 	return cmd
 }
 
-func runServer(out io.Writer, commandName string, port int, nodeRoles string, packageInstallationDisabled bool, disconnectedInstallation bool) error {
-	if nodeRoles == "" {
-		return fmt.Errorf("--node-roles is required")
-	}
-	nodeFacts, err := getNodeRoles(nodeRoles)
+func runServer(out io.Writer, commandName string, flags *pflag.FlagSet, opts serverOpts) error {
+	config, err := serverConfig(flags, opts)
 	if err != nil {
 		return err
 	}
-	if disconnectedInstallation {
-		nodeFacts = append(nodeFacts, "disconnected")
-	}
-	s, err := inspector.NewServer(nodeFacts, port, packageInstallationDisabled)
+	s, err := inspector.NewServerFromConfig(*config)
 	if err != nil {
 		return fmt.Errorf("error starting up inspector server: %v", err)
 	}
-	fmt.Fprintf(out, "Inspector is listening on port %d\n", port)
-	fmt.Fprintf(out, "Node roles: %s\n", nodeRoles)
-	fmt.Fprintf(out, "Package installation disabled: %v\n", packageInstallationDisabled)
-	fmt.Fprintf(out, "Disconnected installation: %v\n", disconnectedInstallation)
-	fmt.Fprintf(out, "Run %s from another node to run checks remotely: %[1]s client [NODE_IP]:%d\n", commandName, port)
+	if opts.configFile != "" {
+		go reloadOnSIGHUP(s, flags, opts)
+	}
+	fmt.Fprintf(out, "Inspector is listening on %s\n", config.ListenAddress)
+	fmt.Fprintf(out, "Node roles: %s\n", strings.Join(config.NodeRoles, ","))
+	fmt.Fprintf(out, "Package installation disabled: %v\n", config.PackageInstallationDisabled)
+	fmt.Fprintf(out, "Disconnected installation: %v\n", config.DisconnectedInstallation)
+	fmt.Fprintf(out, "TLS enabled: %v\n", config.TLS.Enabled())
+	fmt.Fprintf(out, "Log level: %s\n", config.LogLevel)
+	fmt.Fprintf(out, "pprof enabled: %v\n", config.EnablePprof)
+	fmt.Fprintf(out, "Run %s from another node to run checks remotely: %[1]s client [NODE_IP]:PORT\n", commandName)
 	if err := s.Start(); err != nil {
 		return err
 	}
 	return nil
 }
+
+// serverConfig returns the configuration read from the configuration file,
+// if any, overridden by the flags that were set
+func serverConfig(flags *pflag.FlagSet, opts serverOpts) (*inspector.ServerConfig, error) {
+	config := &inspector.ServerConfig{
+		ListenAddress: fmt.Sprintf(":%d", opts.port),
+		LogLevel:      inspector.LogLevelInfo,
+	}
+	if opts.configFile != "" {
+		var err error
+		config, err = inspector.ReadServerConfig(opts.configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if flags.Changed("port") {
+		config.ListenAddress = fmt.Sprintf(":%d", opts.port)
+	}
+	if flags.Changed("node-roles") || opts.configFile == "" {
+		if opts.nodeRoles == "" {
+			return nil, fmt.Errorf("--node-roles is required")
+		}
+		roles, err := getNodeRoles(opts.nodeRoles)
+		if err != nil {
+			return nil, err
+		}
+		config.NodeRoles = roles
+	}
+	if flags.Changed("pkg-installation-disabled") {
+		config.PackageInstallationDisabled = opts.packageInstallationDisabled
+	}
+	if flags.Changed("disconnected-installation") {
+		config.DisconnectedInstallation = opts.disconnectedInstallation
+	}
+	if flags.Changed("enable-pprof") {
+		config.EnablePprof = opts.enablePprof
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func reloadOnSIGHUP(s *inspector.Server, flags *pflag.FlagSet, opts serverOpts) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		config, err := serverConfig(flags, opts)
+		if err != nil {
+			log.Printf("error reloading the server configuration, keeping the current configuration: %v", err)
+			continue
+		}
+		changed := s.Reload(*config)
+		log.Printf("reloaded the server configuration from %q: log level %q, max concurrent requests %d", opts.configFile, config.LogLevel, config.MaxConcurrentRequests)
+		if len(changed) > 0 {
+			log.Printf("the server must be restarted to apply the changes to: %s", strings.Join(changed, ", "))
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package inspector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// LogLevelError only logs the errors of the server
+	LogLevelError = "error"
+	// LogLevelInfo also logs the outcome of the checks run by the server
+	LogLevelInfo = "info"
+	// LogLevelDebug also logs every request that is handled by the server
+	LogLevelDebug = "debug"
+)
+
+var logLevels = map[string]int{
+	LogLevelError: 0,
+	LogLevelInfo:  1,
+	LogLevelDebug: 2,
+}
+
+// ServerConfig is the configuration of the inspector server
+type ServerConfig struct {
+	// ListenAddress is the host:port the server listens on
+	ListenAddress string `yaml:"listen_address"`
+	// TLS serves the requests over HTTPS when the certificate and key are set
+	TLS ServerTLSConfig `yaml:"tls"`
+	// NodeRoles are the roles of the node where the server is running
+	NodeRoles []string `yaml:"node_roles"`
+	// PackageInstallationDisabled is true when the packages are not installed
+	// by the installer, and must already be installed on the node
+	PackageInstallationDisabled bool `yaml:"package_installation_disabled"`
+	// DisconnectedInstallation is true when the node does not have access to
+	// the internet
+	DisconnectedInstallation bool `yaml:"disconnected_installation"`
+	// LogLevel is "error", "info" or "debug". It is applied when the
+	// configuration is reloaded.
+	LogLevel string `yaml:"log_level"`
+	// MaxConcurrentRequests limits the number of requests that are handled
+	// at the same time. Requests over the limit are rejected. 0 is unlimited.
+	// It is applied when the configuration is reloaded.
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+	// AdminToken is the bearer token required by the debug endpoints. The
+	// debug endpoints are disabled when empty. It is applied when the
+	// configuration is reloaded.
+	AdminToken string `yaml:"admin_token"`
+	// TokenTTL is how long the session tokens issued by /login are valid,
+	// such as 15m, which is the default. It is applied when the
+	// configuration is reloaded.
+	TokenTTL string `yaml:"token_ttl"`
+	// SessionMaxAge is how long a session token can be refreshed for after
+	// logging in with the admin token, such as 12h, which is the default.
+	// It is applied when the configuration is reloaded.
+	SessionMaxAge string `yaml:"session_max_age"`
+	// EnablePprof serves the pprof profiles under /debug/pprof/
+	EnablePprof bool `yaml:"enable_pprof"`
+}
+
+// ServerTLSConfig is the TLS configuration of the inspector server
+type ServerTLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled returns true if the server is configured to serve over HTTPS
+func (c ServerTLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// ReadServerConfig reads the server configuration from the YAML file
+func ReadServerConfig(file string) (*ServerConfig, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading server configuration from %q: %v", file, err)
+	}
+	c := &ServerConfig{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("error unmarshaling server configuration from %q: %v", file, err)
+	}
+	c.setDefaults()
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid server configuration in %q: %v", file, err)
+	}
+	return c, nil
+}
+
+func (c *ServerConfig) setDefaults() {
+	if c.ListenAddress == "" {
+		c.ListenAddress = ":9090"
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = LogLevelInfo
+	}
+}
+
+// Validate returns an error if the configuration is not valid
+func (c ServerConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.ListenAddress); err != nil {
+		return fmt.Errorf("listen_address %q is not valid: %v", c.ListenAddress, err)
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return fmt.Errorf("tls.cert_file and tls.key_file must be set together")
+	}
+	if len(c.NodeRoles) == 0 {
+		return fmt.Errorf("node_roles is required")
+	}
+	for _, r := range c.NodeRoles {
+		if r != "etcd" && r != "master" && r != "worker" && r != "ingress" && r != "storage" {
+			return fmt.Errorf("%s is not a valid node role", r)
+		}
+	}
+	if _, ok := logLevels[c.LogLevel]; !ok {
+		return fmt.Errorf("log_level %q is not valid, must be %q, %q or %q", c.LogLevel, LogLevelError, LogLevelInfo, LogLevelDebug)
+	}
+	if c.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("max_concurrent_requests cannot be negative")
+	}
+	if c.EnablePprof && c.AdminToken == "" {
+		return fmt.Errorf("admin_token is required when enable_pprof is true")
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+	}{
+		{name: "token_ttl", value: c.TokenTTL},
+		{name: "session_max_age", value: c.SessionMaxAge},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d.value); err != nil || v <= 0 {
+			return fmt.Errorf("%s %q is not valid, must be a positive duration such as 15m", d.name, d.value)
+		}
+	}
+	return nil
+}
+
+// tokenTTL returns how long the session tokens are valid
+func (c ServerConfig) tokenTTL() time.Duration {
+	if d, err := time.ParseDuration(c.TokenTTL); err == nil && d > 0 {
+		return d
+	}
+	return defaultTokenTTL
+}
+
+// sessionMaxAge returns how long a session token can be refreshed for
+func (c ServerConfig) sessionMaxAge() time.Duration {
+	if d, err := time.ParseDuration(c.SessionMaxAge); err == nil && d > 0 {
+		return d
+	}
+	return defaultSessionMaxAge
+}
+
+// restartRequired returns the settings that changed between the two
+// configurations, and are only applied when the server starts
+func restartRequired(current, next ServerConfig) []string {
+	var changed []string
+	if current.ListenAddress != next.ListenAddress {
+		changed = append(changed, "listen_address")
+	}
+	if current.TLS != next.TLS {
+		changed = append(changed, "tls")
+	}
+	if strings.Join(current.NodeRoles, ",") != strings.Join(next.NodeRoles, ",") {
+		changed = append(changed, "node_roles")
+	}
+	if current.PackageInstallationDisabled != next.PackageInstallationDisabled {
+		changed = append(changed, "package_installation_disabled")
+	}
+	if current.DisconnectedInstallation != next.DisconnectedInstallation {
+		changed = append(changed, "disconnected_installation")
+	}
+	if current.EnablePprof != next.EnablePprof {
+		changed = append(changed, "enable_pprof")
+	}
+	return changed
+}
@@ -0,0 +1,173 @@
+package inspector
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadServerConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "inspector-server-config")
+	if err != nil {
+		t.Fatalf("error creating temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	config := `
+node_roles:
+- master
+- worker
+tls:
+  cert_file: /etc/kismatic/inspector.pem
+  key_file: /etc/kismatic/inspector-key.pem
+max_concurrent_requests: 2
+`
+	if _, err := f.WriteString(config); err != nil {
+		t.Fatalf("error writing config file: %v", err)
+	}
+	f.Close()
+
+	c, err := ReadServerConfig(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &ServerConfig{
+		ListenAddress: ":9090",
+		TLS: ServerTLSConfig{
+			CertFile: "/etc/kismatic/inspector.pem",
+			KeyFile:  "/etc/kismatic/inspector-key.pem",
+		},
+		NodeRoles:             []string{"master", "worker"},
+		LogLevel:              LogLevelInfo,
+		MaxConcurrentRequests: 2,
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, c)
+	}
+}
+
+func TestValidateServerConfig(t *testing.T) {
+	tests := []struct {
+		config ServerConfig
+		valid  bool
+	}{
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo},
+			valid:  true,
+		},
+		{
+			config: ServerConfig{ListenAddress: "9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo},
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", LogLevel: LogLevelInfo},
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"bastion"}, LogLevel: LogLevelInfo},
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: "trace"},
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo, TLS: ServerTLSConfig{CertFile: "cert.pem"}},
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo, MaxConcurrentRequests: -1},
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo, TokenTTL: "5m", SessionMaxAge: "8h"},
+			valid:  true,
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo, TokenTTL: "five minutes"},
+		},
+		{
+			config: ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo, SessionMaxAge: "-1h"},
+		},
+	}
+	for i, test := range tests {
+		err := test.config.Validate()
+		if test.valid && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("test %d: expected an error, but didn't get one", i)
+		}
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	current := ServerConfig{ListenAddress: ":9090", NodeRoles: []string{"etcd"}, LogLevel: LogLevelInfo}
+	s := &Server{config: current}
+
+	next := current
+	next.LogLevel = LogLevelDebug
+	next.MaxConcurrentRequests = 1
+	if changed := s.Reload(next); len(changed) != 0 {
+		t.Errorf("expected no settings that require a restart, but got %v", changed)
+	}
+	if s.config.LogLevel != LogLevelDebug || cap(s.requests) != 1 {
+		t.Errorf("the reloadable settings were not applied")
+	}
+
+	next.ListenAddress = ":9000"
+	next.NodeRoles = []string{"etcd", "master"}
+	changed := s.Reload(next)
+	if !reflect.DeepEqual(changed, []string{"listen_address", "node_roles"}) {
+		t.Errorf("unexpected settings that require a restart: %v", changed)
+	}
+	if s.config.ListenAddress != ":9090" {
+		t.Errorf("the listen address was changed without a restart")
+	}
+}
+
+func TestServerConcurrentRequestLimit(t *testing.T) {
+	s := &Server{}
+	s.Reload(ServerConfig{MaxConcurrentRequests: 1})
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	handler := s.handle(true, func(w http.ResponseWriter, req *http.Request) {
+		inFlight <- struct{}{}
+		<-release
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	done := make(chan int)
+	go func() {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	<-inFlight
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, but got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected status %d for the first request, but got %d", http.StatusOK, code)
+	}
+
+	// Lifting the limit lets the requests through
+	s.Reload(ServerConfig{})
+	go func() { <-inFlight }()
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, but got %d", http.StatusOK, resp.StatusCode)
+	}
+}
@@ -0,0 +1,125 @@
+package inspector
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+var logLevelEndpoint = "/debug/loglevel"
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// LogLevel returns the current log level of the server
+func (s *Server) LogLevel() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.LogLevel
+}
+
+// SetLogLevel changes the log level of the server until the configuration is
+// reloaded
+func (s *Server) SetLogLevel(level string) error {
+	if _, ok := logLevels[level]; !ok {
+		return fmt.Errorf("log level %q is not valid, must be %q, %q or %q", level, LogLevelError, LogLevelInfo, LogLevelDebug)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.LogLevel = level
+	return nil
+}
+
+// logf logs the message if the server logs messages of the given level
+func (s *Server) logf(level string, format string, a ...interface{}) {
+	s.mu.RLock()
+	current := logLevels[s.config.LogLevel]
+	s.mu.RUnlock()
+	if logLevels[level] <= current {
+		log.Printf(format, a...)
+	}
+}
+
+// admin only lets the request through if it carries the admin token, or a
+// session token that has not expired
+func (s *Server) admin(h http.HandlerFunc) http.HandlerFunc {
+	return s.authorize(true, true, h)
+}
+
+// adminTokenOnly only lets the request through if it carries the admin token
+func (s *Server) adminTokenOnly(h http.HandlerFunc) http.HandlerFunc {
+	return s.authorize(true, false, h)
+}
+
+// sessionOnly only lets the request through if it carries a session token
+// that has not expired
+func (s *Server) sessionOnly(h http.HandlerFunc) http.HandlerFunc {
+	return s.authorize(false, true, h)
+}
+
+// authorize lets the request through if it carries one of the allowed
+// tokens. All requests are rejected when the admin token is not set.
+func (s *Server) authorize(allowAdminToken, allowSessionToken bool, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		s.mu.RLock()
+		adminToken := s.config.AdminToken
+		s.mu.RUnlock()
+		if adminToken == "" {
+			http.Error(w, "the debug endpoints are disabled, set the admin_token in the server configuration", http.StatusForbidden)
+			return
+		}
+		token := bearerToken(req)
+		authorized := allowAdminToken && isAdminToken(token, adminToken)
+		if !authorized && allowSessionToken && token != "" {
+			_, err := s.verifySessionToken(token, time.Now())
+			authorized = err == nil
+		}
+		if !authorized {
+			s.logf(LogLevelError, "rejected unauthorized request to %s from %s", req.URL.Path, req.RemoteAddr)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h(w, req)
+	}
+}
+
+func (s *Server) handleLogLevel(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPut {
+		l := logLevel{}
+		if err := json.NewDecoder(req.Body).Decode(&l); err != nil {
+			http.Error(w, fmt.Sprintf("error decoding log level: %v", err), http.StatusBadRequest)
+			return
+		}
+		previous := s.LogLevel()
+		if err := s.SetLogLevel(l.Level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("log level changed from %q to %q by %s", previous, l.Level, req.RemoteAddr)
+	}
+	if err := json.NewEncoder(w).Encode(logLevel{Level: s.LogLevel()}); err != nil {
+		s.logf(LogLevelError, "error writing server response: %v", err)
+	}
+}
+
+// registerDebugHandlers adds the log level endpoint, and the pprof endpoints
+// if they are enabled, to the router. They require the admin token.
+func (s *Server) registerDebugHandlers(r *router) {
+	get := []string{http.MethodGet}
+	r.handle(logLevelEndpoint, []string{http.MethodGet, http.MethodPut}, s.handle(false, s.admin(s.handleLogLevel)))
+	s.mu.RLock()
+	enablePprof := s.config.EnablePprof
+	s.mu.RUnlock()
+	if !enablePprof {
+		return
+	}
+	r.handle("/debug/pprof/", get, s.handle(false, s.admin(pprof.Index)))
+	r.handle("/debug/pprof/cmdline", get, s.handle(false, s.admin(pprof.Cmdline)))
+	r.handle("/debug/pprof/profile", get, s.handle(false, s.admin(pprof.Profile)))
+	r.handle("/debug/pprof/symbol", []string{http.MethodGet, http.MethodPost}, s.handle(false, s.admin(pprof.Symbol)))
+	r.handle("/debug/pprof/trace", get, s.handle(false, s.admin(pprof.Trace)))
+}
@@ -0,0 +1,113 @@
+package inspector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogLevelEndpoint(t *testing.T) {
+	tests := []struct {
+		adminToken     string
+		method         string
+		authorization  string
+		body           string
+		expectedStatus int
+		expectedLevel  string
+	}{
+		{
+			// the endpoint is disabled without an admin token
+			method:         http.MethodPut,
+			body:           `{"level":"debug"}`,
+			expectedStatus: http.StatusForbidden,
+			expectedLevel:  LogLevelInfo,
+		},
+		{
+			adminToken:     "secret",
+			method:         http.MethodPut,
+			authorization:  "Bearer wrong",
+			body:           `{"level":"debug"}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedLevel:  LogLevelInfo,
+		},
+		{
+			adminToken:     "secret",
+			method:         http.MethodPut,
+			authorization:  "Bearer secret",
+			body:           `{"level":"debug"}`,
+			expectedStatus: http.StatusOK,
+			expectedLevel:  LogLevelDebug,
+		},
+		{
+			adminToken:     "secret",
+			method:         http.MethodPut,
+			authorization:  "Bearer secret",
+			body:           `{"level":"trace"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  LogLevelInfo,
+		},
+		{
+			adminToken:     "secret",
+			method:         http.MethodGet,
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusOK,
+			expectedLevel:  LogLevelInfo,
+		},
+		{
+			adminToken:     "secret",
+			method:         http.MethodDelete,
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedLevel:  LogLevelInfo,
+		},
+	}
+	for i, test := range tests {
+		s := &Server{config: ServerConfig{LogLevel: LogLevelInfo, AdminToken: test.adminToken}}
+		mux := newRouter()
+		s.registerDebugHandlers(mux)
+		req := httptest.NewRequest(test.method, logLevelEndpoint, strings.NewReader(test.body))
+		if test.authorization != "" {
+			req.Header.Set("Authorization", test.authorization)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != test.expectedStatus {
+			t.Errorf("test %d: expected status %d, but got %d", i, test.expectedStatus, w.Code)
+		}
+		if s.LogLevel() != test.expectedLevel {
+			t.Errorf("test %d: expected log level %q, but got %q", i, test.expectedLevel, s.LogLevel())
+		}
+		if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), test.expectedLevel) {
+			t.Errorf("test %d: expected the log level in the response, but got %q", i, w.Body.String())
+		}
+	}
+}
+
+func TestPprofEndpoints(t *testing.T) {
+	tests := []struct {
+		enablePprof    bool
+		expectedStatus int
+	}{
+		{
+			enablePprof:    false,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			enablePprof:    true,
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for i, test := range tests {
+		s := &Server{config: ServerConfig{LogLevel: LogLevelInfo, AdminToken: "secret", EnablePprof: test.enablePprof}}
+		mux := newRouter()
+		s.registerDebugHandlers(mux)
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/cmdline", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != test.expectedStatus {
+			t.Errorf("test %d: expected status %d, but got %d", i, test.expectedStatus, w.Code)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package inspector
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var apisEndpoint = "/apis"
+
+// route is an endpoint of the server, and the methods it accepts
+type route struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+}
+
+type apiList struct {
+	Routes []route `json:"routes"`
+}
+
+// router registers the handlers of the server on a mux, and keeps track of
+// the routes so that clients can discover them
+type router struct {
+	mux    *http.ServeMux
+	routes []route
+}
+
+func newRouter() *router {
+	r := &router{mux: http.NewServeMux()}
+	r.handle(apisEndpoint, []string{http.MethodGet}, r.handleAPIs)
+	return r
+}
+
+func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+// handle registers the handler for the path. OPTIONS requests are answered
+// with the methods accepted by the path, and requests with any other method
+// are rejected with a 405.
+func (r *router) handle(path string, methods []string, h http.HandlerFunc) {
+	r.routes = append(r.routes, route{Path: path, Methods: methods})
+	allow := allowHeader(methods)
+	r.mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodOptions {
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !allowed(methods, req.Method) {
+			w.Header().Set("Allow", allow)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, req)
+	})
+}
+
+// handleAPIs writes the routes of the server, sorted by path
+func (r *router) handleAPIs(w http.ResponseWriter, req *http.Request) {
+	routes := make([]route, len(r.routes))
+	for i, rt := range r.routes {
+		routes[i] = route{Path: rt.Path, Methods: strings.Split(allowHeader(rt.Methods), ", ")}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Path < routes[j].Path })
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(apiList{Routes: routes}); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// allowed returns true if the method is one of the methods, a HEAD request is
+// allowed wherever a GET request is
+func allowed(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method || (m == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowHeader returns the value of the Allow header for the methods
+func allowHeader(methods []string) string {
+	all := append([]string{}, methods...)
+	for _, m := range methods {
+		if m == http.MethodGet {
+			all = append(all, http.MethodHead)
+		}
+	}
+	all = append(all, http.MethodOptions)
+	return strings.Join(all, ", ")
+}
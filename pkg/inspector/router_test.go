@@ -0,0 +1,85 @@
+package inspector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouterMethods(t *testing.T) {
+	tests := []struct {
+		method         string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			method:         http.MethodHead,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			method:         http.MethodPut,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			method:         http.MethodOptions,
+			expectedStatus: http.StatusNoContent,
+			expectedAllow:  "GET, PUT, HEAD, OPTIONS",
+		},
+		{
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedAllow:  "GET, PUT, HEAD, OPTIONS",
+		},
+		{
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedAllow:  "GET, PUT, HEAD, OPTIONS",
+		},
+	}
+	r := newRouter()
+	r.handle("/test", []string{http.MethodGet, http.MethodPut}, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for i, test := range tests {
+		req := httptest.NewRequest(test.method, "/test", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.expectedStatus {
+			t.Errorf("test %d: expected status %d, but got %d", i, test.expectedStatus, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != test.expectedAllow {
+			t.Errorf("test %d: expected Allow header %q, but got %q", i, test.expectedAllow, allow)
+		}
+	}
+}
+
+func TestAPIsEndpoint(t *testing.T) {
+	s := &Server{config: ServerConfig{LogLevel: LogLevelInfo}}
+	r := newRouter()
+	r.handle(executeEndpoint, []string{http.MethodPost}, func(w http.ResponseWriter, req *http.Request) {})
+	s.registerDebugHandlers(r)
+	req := httptest.NewRequest(http.MethodGet, apisEndpoint, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, but got %d", http.StatusOK, w.Code)
+	}
+	apis := apiList{}
+	if err := json.NewDecoder(w.Body).Decode(&apis); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	expected := []route{
+		{Path: apisEndpoint, Methods: []string{"GET", "HEAD", "OPTIONS"}},
+		{Path: logLevelEndpoint, Methods: []string{"GET", "PUT", "HEAD", "OPTIONS"}},
+		{Path: executeEndpoint, Methods: []string{"POST", "OPTIONS"}},
+	}
+	if !reflect.DeepEqual(apis.Routes, expected) {
+		t.Errorf("expected routes %v, but got %v", expected, apis.Routes)
+	}
+}
@@ -21,6 +21,8 @@ type DefaultCheckMapper struct {
 	TargetNodeIP string
 	// PackageInstallationDisabled determines whether Kismatic is allowed to install packages on the node
 	PackageInstallationDisabled bool
+	// OSRelease is the operating system release of the node
+	OSRelease check.OSRelease
 }
 
 // GetCheckForRule returns the check for the given rule. If the rule
@@ -50,6 +52,8 @@ func (m DefaultCheckMapper) GetCheckForRule(rule Rule) (check.Check, error) {
 	case FreeSpace:
 		bytes, _ := r.minimumBytesAsUint64() // ignore this err, as we have already validated the rule
 		c = &check.FreeSpaceCheck{Path: r.Path, MinimumBytes: bytes}
+	case SupportedOS:
+		c = &check.OSReleaseCheck{OSRelease: m.OSRelease}
 	}
 	return c, nil
 }
@@ -134,6 +134,9 @@ func buildRule(catchAll catchAllRule) (Rule, error) {
 		}
 		r.Meta = meta
 		return r, nil
-
+	case "supportedos":
+		r := SupportedOS{}
+		r.Meta = meta
+		return r, nil
 	}
 }
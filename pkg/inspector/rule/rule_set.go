@@ -7,6 +7,9 @@ import (
 
 // DefaultRuleSet is the list of rules that are built into the inspector
 const defaultRuleSet = `---
+- kind: SupportedOS
+  when: []
+
 - kind: FreeSpace
   path: /
   minimumBytes: 1000000000
@@ -374,6 +377,144 @@ const defaultRuleSet = `---
   packageVersion: 1.8.4-0
 
 
+- kind: PackageDependency
+  when: ["etcd","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+
+- kind: PackageDependency
+  when: ["etcd","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+
 # Gluster packages
 - kind: PackageDependency
   when: ["storage", "centos"]
@@ -387,6 +528,14 @@ const defaultRuleSet = `---
   when: ["storage", "ubuntu"]
   packageName: glusterfs-server
   packageVersion: 3.8.15-ubuntu1~xenial1
+- kind: PackageDependency
+  when: ["storage", "debian"]
+  packageName: glusterfs-server
+  packageVersion: 3.8.8-1
+- kind: PackageDependency
+  when: ["storage", "rocky"]
+  packageName: glusterfs-server
+  packageVersion: 3.8.15-2.el7
 
 # Port required for gluster-healthz
 - kind: TCPPortAvailable
@@ -437,6 +586,9 @@ const defaultRuleSet = `---
 `
 
 const upgradeRuleSet = `---
+- kind: SupportedOS
+  when: []
+
 - kind: FreeSpace
   path: /
   minimumBytes: 1000000000
@@ -648,6 +800,156 @@ const upgradeRuleSet = `---
   packageName: kubectl
   packageVersion: 1.8.4-0
 
+- kind: PackageDependency
+  when: ["etcd","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["master","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-0~debian-stretch
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: kubelet
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: nfs-common
+  anyVersion: true
+- kind: PackageDependency
+  when: ["worker","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["ingress","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+- kind: PackageDependency
+  when: ["storage","debian"]
+  packageName: kubectl
+  packageVersion: 1.8.4-00
+
+- kind: PackageDependency
+  when: ["etcd","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["master","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: kubelet
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: nfs-utils
+  anyVersion: true
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: kubectl
+  packageVersion: 1.8.4-0
+- kind: PackageDependency
+  when: ["worker","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["ingress","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+- kind: PackageDependency
+  when: ["storage","rocky"]
+  packageName: docker-engine
+  packageVersion: 1.12.6-1.el7.centos
+
 # Gluster packages
 - kind: PackageDependency
   when: ["storage", "centos"]
@@ -661,6 +963,14 @@ const upgradeRuleSet = `---
   when: ["storage", "ubuntu"]
   packageName: glusterfs-server
   packageVersion: 3.8.15-ubuntu1~xenial1
+- kind: PackageDependency
+  when: ["storage", "debian"]
+  packageName: glusterfs-server
+  packageVersion: 3.8.8-1
+- kind: PackageDependency
+  when: ["storage", "rocky"]
+  packageName: glusterfs-server
+  packageVersion: 3.8.15-2.el7
 `
 
 // DefaultRules returns the list of rules that are built into the inspector
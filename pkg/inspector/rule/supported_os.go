@@ -0,0 +1,18 @@
+package rule
+
+// SupportedOS is a rule that ensures the node is running a supported
+// operating system release
+type SupportedOS struct {
+	Meta
+}
+
+// Name is the name of the rule
+func (s SupportedOS) Name() string {
+	return "Operating System is supported"
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (s SupportedOS) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (s SupportedOS) Validate() []error { return nil }
@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"sync"
 
 	"github.com/apprenda/kismatic/pkg/inspector/check"
 	"github.com/apprenda/kismatic/pkg/inspector/rule"
@@ -15,10 +15,22 @@ import (
 type Server struct {
 	// The Port the server will listen on
 	Port int
+	// ListenAddress is the host:port the server will listen on. It takes
+	// precedence over the Port.
+	ListenAddress string
+	// TLS serves the requests over HTTPS when set
+	TLS ServerTLSConfig
 	// NodeFacts are the facts that apply to the node where the server is running
 	NodeFacts []string
 	// RulesEngine for running inspector rules
 	rulesEngine *rule.Engine
+
+	// the settings that can be reloaded while the server is running
+	mu       sync.RWMutex
+	config   ServerConfig
+	requests chan struct{}
+	// the key the session tokens are signed with
+	sessionSigningKey []byte
 }
 
 type serverError struct {
@@ -32,14 +44,15 @@ var closeEndpoint = "/close"
 // with the default rules engine
 func NewServer(nodeFacts []string, port int, packageInstallationDisabled bool) (*Server, error) {
 	s := &Server{
-		Port: port,
+		Port:   port,
+		config: ServerConfig{LogLevel: LogLevelInfo},
 	}
-	distro, err := check.DetectDistro()
+	osRelease, err := check.DetectOSRelease()
 	if err != nil {
 		return nil, fmt.Errorf("error building server: %v", err)
 	}
-	s.NodeFacts = append(nodeFacts, string(distro))
-	pkgMgr, err := check.NewPackageManager(distro)
+	s.NodeFacts = append(nodeFacts, string(osRelease.Distro))
+	pkgMgr, err := check.NewPackageManager(osRelease.Distro)
 	if err != nil {
 		return nil, fmt.Errorf("error building server: %v", err)
 	}
@@ -47,59 +60,139 @@ func NewServer(nodeFacts []string, port int, packageInstallationDisabled bool) (
 		RuleCheckMapper: rule.DefaultCheckMapper{
 			PackageManager:              pkgMgr,
 			PackageInstallationDisabled: packageInstallationDisabled,
+			OSRelease:                   osRelease,
 		},
 	}
 	s.rulesEngine = engine
 	return s, nil
 }
 
+// NewServerFromConfig returns an inspector server that has been initialized
+// with the default rules engine, and the given configuration
+func NewServerFromConfig(c ServerConfig) (*Server, error) {
+	nodeFacts := append([]string{}, c.NodeRoles...)
+	if c.DisconnectedInstallation {
+		nodeFacts = append(nodeFacts, "disconnected")
+	}
+	s, err := NewServer(nodeFacts, 0, c.PackageInstallationDisabled)
+	if err != nil {
+		return nil, err
+	}
+	s.ListenAddress = c.ListenAddress
+	s.TLS = c.TLS
+	s.config = c
+	s.Reload(c)
+	return s, nil
+}
+
+// Reload applies the settings of the configuration that can be changed while
+// the server is running, which are the log level, the limit of concurrent
+// requests, the admin token and the lifetime of the session tokens. Changing
+// the admin token revokes the session tokens. It returns the settings that
+// changed, but are only applied when the server is restarted.
+func (s *Server) Reload(c ServerConfig) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := restartRequired(s.config, c)
+	s.config.LogLevel = c.LogLevel
+	s.config.MaxConcurrentRequests = c.MaxConcurrentRequests
+	if s.config.AdminToken != c.AdminToken {
+		s.sessionSigningKey = nil
+	}
+	s.config.AdminToken = c.AdminToken
+	s.config.TokenTTL = c.TokenTTL
+	s.config.SessionMaxAge = c.SessionMaxAge
+	// Requests that are in flight release their slot in the previous channel
+	s.requests = nil
+	if c.MaxConcurrentRequests > 0 {
+		s.requests = make(chan struct{}, c.MaxConcurrentRequests)
+	}
+	return changed
+}
+
+// handle logs the request when debugging, and rejects it when the server is
+// already handling the maximum number of concurrent requests
+func (s *Server) handle(limited bool, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		s.mu.RLock()
+		requests := s.requests
+		s.mu.RUnlock()
+		s.logf(LogLevelDebug, "%s %s from %s", req.Method, req.URL.Path, req.RemoteAddr)
+		if limited && requests != nil {
+			select {
+			case requests <- struct{}{}:
+				defer func() { <-requests }()
+			default:
+				s.logf(LogLevelError, "rejected request from %s: the server is handling %d requests", req.RemoteAddr, cap(requests))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		}
+		h(w, req)
+	}
+}
+
 // Start the server
 func (s *Server) Start() error {
-	mux := http.NewServeMux()
+	r := newRouter()
 	// Execute endpoint
-	mux.HandleFunc(executeEndpoint, func(w http.ResponseWriter, req *http.Request) {
-		if req.Method != http.MethodPost {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
+	r.handle(executeEndpoint, []string{http.MethodPost}, s.handle(true, func(w http.ResponseWriter, req *http.Request) {
 		// Decode rules
 		data, err := ioutil.ReadAll(req.Body)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
-			log.Printf("error decoding rules when processing request: %v", err)
+			s.logf(LogLevelError, "error decoding rules when processing request: %v", err)
 			return
 		}
 		defer req.Body.Close()
 		rules, err := rule.UnmarshalRulesJSON(data)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			log.Printf("error unmarshaling rules from JSON: %v", err)
+			s.logf(LogLevelError, "error unmarshaling rules from JSON: %v", err)
 			return
 		}
 		// Run the rules that we received
 		results, err := s.rulesEngine.ExecuteRules(rules, s.NodeFacts)
 		if err != nil {
+			s.logf(LogLevelError, "error executing rules: %v", err)
 			err = json.NewEncoder(w).Encode(serverError{Error: err.Error()})
 			if err != nil {
-				log.Printf("error writing server response: %v\n", err)
+				s.logf(LogLevelError, "error writing server response: %v", err)
 			}
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		failed := 0
+		for _, r := range results {
+			if !r.Success {
+				failed++
+				s.logf(LogLevelDebug, "check %q failed: %s", r.Name, r.Error)
+			}
+		}
+		s.logf(LogLevelInfo, "ran %d checks for %s, %d failed", len(results), req.RemoteAddr, failed)
 		err = json.NewEncoder(w).Encode(results)
 		if err != nil {
-			log.Printf("error writing server response: %v\n", err)
+			s.logf(LogLevelError, "error writing server response: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
-	})
+	}))
 	// Close endpoint
-	mux.HandleFunc(closeEndpoint, func(w http.ResponseWriter, req *http.Request) {
+	r.handle(closeEndpoint, []string{http.MethodGet, http.MethodPost}, s.handle(false, func(w http.ResponseWriter, req *http.Request) {
 		err := s.rulesEngine.CloseChecks()
 		if err != nil {
-			log.Printf("error closing checks: %v", err)
+			s.logf(LogLevelError, "error closing checks: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		w.WriteHeader(http.StatusOK)
-	})
-	return http.ListenAndServe(fmt.Sprintf(":%d", s.Port), mux)
+	}))
+	s.registerSessionHandlers(r)
+	s.registerDebugHandlers(r)
+	addr := s.ListenAddress
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", s.Port)
+	}
+	if s.TLS.Enabled() {
+		return http.ListenAndServeTLS(addr, s.TLS.CertFile, s.TLS.KeyFile, r)
+	}
+	return http.ListenAndServe(addr, r)
 }
@@ -0,0 +1,191 @@
+package inspector
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var loginEndpoint = "/login"
+var refreshEndpoint = "/login/refresh"
+
+const (
+	defaultTokenTTL      = 15 * time.Minute
+	defaultSessionMaxAge = 12 * time.Hour
+)
+
+// The header of the session tokens, which are JSON web tokens signed with
+// HMAC-SHA256
+var sessionTokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// sessionClaims are the claims of a session token
+type sessionClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	// AuthTime is when the session was opened with the admin token. It is
+	// kept when the token is refreshed.
+	AuthTime int64 `json:"auth_time"`
+}
+
+type sessionToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// sessionKey returns the key the session tokens are signed with. The key is
+// generated the first time it is needed, and again when the admin token
+// changes, which revokes the sessions that were opened with the previous one.
+func (s *Server) sessionKey() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessionSigningKey == nil {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("error generating session signing key: %v", err)
+		}
+		s.sessionSigningKey = key
+	}
+	return s.sessionSigningKey, nil
+}
+
+// issueSessionToken returns a session token that expires after the token TTL,
+// or when the session reaches its maximum age, whichever comes first
+func (s *Server) issueSessionToken(authTime, now time.Time) (*sessionToken, error) {
+	key, err := s.sessionKey()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	ttl, maxAge := s.config.tokenTTL(), s.config.sessionMaxAge()
+	s.mu.RUnlock()
+	expiresAt := now.Add(ttl)
+	if end := authTime.Add(maxAge); end.Before(expiresAt) {
+		expiresAt = end
+	}
+	claims, err := json.Marshal(sessionClaims{
+		Subject:   "admin",
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		AuthTime:  authTime.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling session claims: %v", err)
+	}
+	payload := sessionTokenHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(signSessionToken(key, payload))
+	return &sessionToken{Token: token, ExpiresAt: time.Unix(expiresAt.Unix(), 0).UTC()}, nil
+}
+
+// verifySessionToken returns the claims of the session token, or an error if
+// it was not signed by the server or has expired
+func (s *Server) verifySessionToken(token string, now time.Time) (*sessionClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != sessionTokenHeader {
+		return nil, errors.New("malformed session token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed session token")
+	}
+	key, err := s.sessionKey()
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(signature, signSessionToken(key, parts[0]+"."+parts[1])) {
+		return nil, errors.New("invalid session token signature")
+	}
+	d, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed session token")
+	}
+	claims := &sessionClaims{}
+	if err := json.Unmarshal(d, claims); err != nil {
+		return nil, errors.New("malformed session token")
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, errors.New("session token has expired")
+	}
+	return claims, nil
+}
+
+func signSessionToken(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// bearerToken returns the bearer token of the request, or an empty string
+func bearerToken(req *http.Request) string {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(auth, "Bearer ")
+}
+
+// isAdminToken returns true if the token is the admin token
+func isAdminToken(token, adminToken string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// handleLogin exchanges the admin token for a session token
+func (s *Server) handleLogin(w http.ResponseWriter, req *http.Request) {
+	now := time.Now()
+	t, err := s.issueSessionToken(now, now)
+	if err != nil {
+		s.logf(LogLevelError, "error issuing session token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.logf(LogLevelInfo, "opened a session for %s, the token expires at %s", req.RemoteAddr, t.ExpiresAt.Format(time.RFC3339))
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		s.logf(LogLevelError, "error writing server response: %v", err)
+	}
+}
+
+// handleRefresh exchanges a session token that has not expired for a new
+// one, until the session reaches its maximum age
+func (s *Server) handleRefresh(w http.ResponseWriter, req *http.Request) {
+	now := time.Now()
+	claims, err := s.verifySessionToken(bearerToken(req), now)
+	if err != nil {
+		s.logf(LogLevelError, "rejected session refresh from %s: %v", req.RemoteAddr, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	authTime := time.Unix(claims.AuthTime, 0)
+	s.mu.RLock()
+	maxAge := s.config.sessionMaxAge()
+	s.mu.RUnlock()
+	if !now.Before(authTime.Add(maxAge)) {
+		s.logf(LogLevelError, "rejected session refresh from %s: the session is older than %s", req.RemoteAddr, maxAge)
+		http.Error(w, "the session has reached its maximum age, log in with the admin token", http.StatusUnauthorized)
+		return
+	}
+	t, err := s.issueSessionToken(authTime, now)
+	if err != nil {
+		s.logf(LogLevelError, "error issuing session token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		s.logf(LogLevelError, "error writing server response: %v", err)
+	}
+}
+
+// registerSessionHandlers adds the login endpoints to the router. Logging in
+// requires the admin token, and refreshing requires a session token.
+func (s *Server) registerSessionHandlers(r *router) {
+	post := []string{http.MethodPost}
+	r.handle(loginEndpoint, post, s.handle(false, s.adminTokenOnly(s.handleLogin)))
+	r.handle(refreshEndpoint, post, s.handle(false, s.sessionOnly(s.handleRefresh)))
+}
@@ -0,0 +1,136 @@
+package inspector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionToken(t *testing.T) {
+	s := &Server{config: ServerConfig{AdminToken: "secret", TokenTTL: "15m", SessionMaxAge: "1h"}}
+	login := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		authTime  time.Time
+		issuedAt  time.Time
+		checkedAt time.Time
+		expiresAt time.Time
+		valid     bool
+	}{
+		{
+			authTime:  login,
+			issuedAt:  login,
+			checkedAt: login.Add(10 * time.Minute),
+			expiresAt: login.Add(15 * time.Minute),
+			valid:     true,
+		},
+		{
+			authTime:  login,
+			issuedAt:  login,
+			checkedAt: login.Add(15 * time.Minute),
+			expiresAt: login.Add(15 * time.Minute),
+		},
+		{
+			// a refreshed token does not outlive the session
+			authTime:  login,
+			issuedAt:  login.Add(50 * time.Minute),
+			checkedAt: login.Add(55 * time.Minute),
+			expiresAt: login.Add(time.Hour),
+			valid:     true,
+		},
+	}
+	for i, test := range tests {
+		token, err := s.issueSessionToken(test.authTime, test.issuedAt)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		if !token.ExpiresAt.Equal(test.expiresAt) {
+			t.Errorf("test %d: expected the token to expire at %s, but got %s", i, test.expiresAt, token.ExpiresAt)
+		}
+		claims, err := s.verifySessionToken(token.Token, test.checkedAt)
+		if (err == nil) != test.valid {
+			t.Errorf("test %d: expected valid to be %v, but got error %v", i, test.valid, err)
+		}
+		if err == nil && claims.AuthTime != test.authTime.Unix() {
+			t.Errorf("test %d: expected the session to be opened at %d, but got %d", i, test.authTime.Unix(), claims.AuthTime)
+		}
+	}
+
+	token, err := s.issueSessionToken(login, login)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = s.verifySessionToken(token.Token+"x", login); err == nil {
+		t.Errorf("expected a tampered token to be rejected")
+	}
+	// changing the admin token revokes the sessions
+	s.Reload(ServerConfig{AdminToken: "rotated"})
+	if _, err = s.verifySessionToken(token.Token, login); err == nil {
+		t.Errorf("expected the token to be rejected after the admin token changed")
+	}
+}
+
+func TestLoginEndpoints(t *testing.T) {
+	s := &Server{config: ServerConfig{LogLevel: LogLevelInfo, AdminToken: "secret"}}
+	mux := newRouter()
+	s.registerSessionHandlers(mux)
+	s.registerDebugHandlers(mux)
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	session := func(w *httptest.ResponseRecorder) string {
+		var t sessionToken
+		json.NewDecoder(w.Body).Decode(&t)
+		return t.Token
+	}
+
+	w := do(http.MethodPost, loginEndpoint, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d logging in, but got %d", http.StatusOK, w.Code)
+	}
+	token := session(w)
+	if token == "" {
+		t.Fatalf("expected a session token")
+	}
+	w = do(http.MethodPost, refreshEndpoint, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d refreshing, but got %d", http.StatusOK, w.Code)
+	}
+	refreshed := session(w)
+
+	tests := []struct {
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{method: http.MethodPost, path: loginEndpoint, expectedStatus: http.StatusUnauthorized},
+		{method: http.MethodPost, path: loginEndpoint, token: "wrong", expectedStatus: http.StatusUnauthorized},
+		{method: http.MethodGet, path: loginEndpoint, token: "secret", expectedStatus: http.StatusMethodNotAllowed},
+		// a session cannot be used to open another session
+		{method: http.MethodPost, path: loginEndpoint, token: token, expectedStatus: http.StatusUnauthorized},
+		// the admin token cannot be refreshed
+		{method: http.MethodPost, path: refreshEndpoint, token: "secret", expectedStatus: http.StatusUnauthorized},
+		{method: http.MethodGet, path: logLevelEndpoint, token: token, expectedStatus: http.StatusOK},
+		{method: http.MethodGet, path: logLevelEndpoint, token: refreshed, expectedStatus: http.StatusOK},
+		{method: http.MethodGet, path: logLevelEndpoint, token: "secret", expectedStatus: http.StatusOK},
+	}
+	for i, test := range tests {
+		if w := do(test.method, test.path, test.token); w.Code != test.expectedStatus {
+			t.Errorf("test %d: expected status %d, but got %d", i, test.expectedStatus, w.Code)
+		}
+	}
+
+	// the login endpoints are disabled without an admin token
+	s.Reload(ServerConfig{LogLevel: LogLevelInfo})
+	if w := do(http.MethodPost, loginEndpoint, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d without an admin token, but got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -3,7 +3,6 @@ package install
 import (
 	"fmt"
 
-	"github.com/apprenda/kismatic/pkg/ssh"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/blang/semver"
 )
@@ -75,7 +74,7 @@ func ListVersions(plan *Plan) (ClusterVersion, error) {
 	sshDeets := plan.Cluster.SSH
 	verFile := "/etc/kismatic-version"
 	for i, node := range nodes {
-		client, err := ssh.NewClient(node.IP, sshDeets.Port, sshDeets.User, sshDeets.Key)
+		client, err := sshDeets.NewClient(node.IP)
 		if err != nil {
 			return cv, fmt.Errorf("error creating SSH client: %v", err)
 		}
@@ -0,0 +1,65 @@
+package install
+
+import (
+	"fmt"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// The add-ons that can be upgraded independently of Kubernetes
+const (
+	addOnCNI           = "cni"
+	addOnDNS           = "dns"
+	addOnIngress       = "ingress"
+	addOnHeapster      = "heapster"
+	addOnMetricsServer = "metrics-server"
+	addOnDashboard     = "dashboard"
+	addOnHelm          = "helm"
+)
+
+// UpgradableAddOns returns the add-ons that can be upgraded independently of
+// Kubernetes
+func UpgradableAddOns() []string {
+	return []string{addOnCNI, addOnDNS, addOnIngress, addOnHeapster, addOnMetricsServer, addOnDashboard, addOnHelm}
+}
+
+// addOnsEnabled returns whether each upgradable add-on is enabled in the
+// cluster catalog. The custom CNI provider is not managed by Kismatic, so it
+// cannot be upgraded.
+func addOnsEnabled(cc ansible.ClusterCatalog) map[string]bool {
+	return map[string]bool{
+		addOnCNI:           cc.CNI.Enabled && cc.CNI.Provider != cniProviderCustom,
+		addOnDNS:           cc.DNS.Enabled,
+		addOnIngress:       cc.EnableConfigureIngress,
+		addOnHeapster:      cc.Heapster.Enabled,
+		addOnMetricsServer: cc.MetricsServer.Enabled,
+		addOnDashboard:     cc.Dashboard.Enabled,
+		addOnHelm:          cc.Helm.Enabled,
+	}
+}
+
+// selectAddOnsToUpgrade returns the requested add-ons, or all the enabled
+// add-ons if none is requested. It is an error to request an add-on that is
+// not enabled.
+func selectAddOnsToUpgrade(cc ansible.ClusterCatalog, requested []string) ([]string, error) {
+	enabled := addOnsEnabled(cc)
+	if len(requested) == 0 {
+		var selected []string
+		for _, a := range UpgradableAddOns() {
+			if enabled[a] {
+				selected = append(selected, a)
+			}
+		}
+		return selected, nil
+	}
+	for _, a := range requested {
+		if !util.Contains(a, UpgradableAddOns()) {
+			return nil, fmt.Errorf("%q is not an add-on that can be upgraded, options are %v", a, UpgradableAddOns())
+		}
+		if !enabled[a] {
+			return nil, fmt.Errorf("the %q add-on is not enabled in the plan file", a)
+		}
+	}
+	return requested, nil
+}
@@ -0,0 +1,77 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestSelectAddOnsToUpgrade(t *testing.T) {
+	cc := ansible.ClusterCatalog{}
+	cc.CNI.Enabled = true
+	cc.CNI.Provider = cniProviderCalico
+	cc.DNS.Enabled = true
+	cc.Dashboard.Enabled = true
+
+	tests := []struct {
+		name      string
+		catalog   func(ansible.ClusterCatalog) ansible.ClusterCatalog
+		requested []string
+		expected  []string
+		valid     bool
+	}{
+		{
+			name:     "all enabled add-ons",
+			expected: []string{"cni", "dns", "dashboard"},
+			valid:    true,
+		},
+		{
+			name:      "requested add-ons",
+			requested: []string{"dashboard", "dns"},
+			expected:  []string{"dashboard", "dns"},
+			valid:     true,
+		},
+		{
+			name:      "add-on not enabled",
+			requested: []string{"heapster"},
+		},
+		{
+			name: "metrics server",
+			catalog: func(cc ansible.ClusterCatalog) ansible.ClusterCatalog {
+				cc.MetricsServer.Enabled = true
+				return cc
+			},
+			requested: []string{"metrics-server"},
+			expected:  []string{"metrics-server"},
+			valid:     true,
+		},
+		{
+			name:      "unknown add-on",
+			requested: []string{"rescheduler"},
+		},
+		{
+			name: "custom cni is not upgraded",
+			catalog: func(cc ansible.ClusterCatalog) ansible.ClusterCatalog {
+				cc.CNI.Provider = cniProviderCustom
+				return cc
+			},
+			expected: []string{"dns", "dashboard"},
+			valid:    true,
+		},
+	}
+	for _, test := range tests {
+		c := cc
+		if test.catalog != nil {
+			c = test.catalog(c)
+		}
+		selected, err := selectAddOnsToUpgrade(c, test.requested)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got error %v", test.name, test.valid, err)
+			continue
+		}
+		if !reflect.DeepEqual(selected, test.expected) {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.expected, selected)
+		}
+	}
+}
@@ -11,12 +11,16 @@ var errMissingClusterCA = errors.New("The Certificate Authority's private key an
 	"the cluster are required for adding worker nodes.")
 
 // AddWorker adds a worker node to the original cluster described in the plan.
-// If successful, the updated plan is returned.
-func (ae *ansibleExecutor) AddWorker(originalPlan *Plan, newWorker Node) (*Plan, error) {
+// The node is added to the named worker pool, or to the worker group if the
+// pool is empty. If successful, the updated plan is returned.
+func (ae *ansibleExecutor) AddWorker(originalPlan *Plan, newWorker Node, pool string) (*Plan, error) {
 	if err := checkAddWorkerPrereqs(ae.pki, newWorker); err != nil {
 		return nil, err
 	}
-	updatedPlan := addWorkerToPlan(*originalPlan, newWorker)
+	updatedPlan, err := addWorkerToPlan(*originalPlan, newWorker, pool)
+	if err != nil {
+		return nil, err
+	}
 
 	// Generate node certificates
 	util.PrintHeader(ae.stdout, "Generating Certificate For Worker Node", '=')
@@ -98,10 +102,21 @@ func (ae *ansibleExecutor) AddWorker(originalPlan *Plan, newWorker Node) (*Plan,
 	return &updatedPlan, nil
 }
 
-func addWorkerToPlan(plan Plan, worker Node) Plan {
-	plan.Worker.ExpectedCount++
-	plan.Worker.Nodes = append(plan.Worker.Nodes, worker)
-	return plan
+func addWorkerToPlan(plan Plan, worker Node, pool string) (Plan, error) {
+	if pool == "" {
+		plan.Worker.ExpectedCount++
+		plan.Worker.Nodes = append(plan.Worker.Nodes, worker)
+		return plan, nil
+	}
+	// copy the pools so that the original plan is not modified
+	plan.WorkerPools = append([]WorkerPool{}, plan.WorkerPools...)
+	wp := plan.workerPool(pool)
+	if wp == nil {
+		return plan, fmt.Errorf("worker pool %q is not defined in the plan", pool)
+	}
+	wp.ExpectedCount++
+	wp.Nodes = append(append([]Node{}, wp.Nodes...), worker)
+	return plan, nil
 }
 
 // ensure the assumptions we are making are solid
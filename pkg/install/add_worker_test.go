@@ -33,7 +33,7 @@ func TestAddWorkerCertMissingCAMissing(t *testing.T) {
 		},
 	}
 	newWorker := Node{}
-	newPlan, err := e.AddWorker(originalPlan, newWorker)
+	newPlan, err := e.AddWorker(originalPlan, newWorker, "")
 	if newPlan != nil {
 		t.Errorf("add worker returned an updated plan")
 	}
@@ -69,7 +69,7 @@ func TestAddWorkerCertMissingCAExists(t *testing.T) {
 		},
 	}
 	newWorker := Node{}
-	_, err := e.AddWorker(originalPlan, newWorker)
+	_, err := e.AddWorker(originalPlan, newWorker, "")
 	if err != nil {
 		t.Errorf("unexpected error while adding worker: %v", err)
 	}
@@ -113,7 +113,7 @@ func TestAddWorkerPlanIsUpdated(t *testing.T) {
 	newWorker := Node{
 		Host: "test",
 	}
-	updatedPlan, err := e.AddWorker(originalPlan, newWorker)
+	updatedPlan, err := e.AddWorker(originalPlan, newWorker, "")
 	if err != nil {
 		t.Errorf("unexpected error while adding worker: %v", err)
 	}
@@ -131,6 +131,36 @@ func TestAddWorkerPlanIsUpdated(t *testing.T) {
 	}
 }
 
+func TestAddWorkerToPool(t *testing.T) {
+	originalPlan := Plan{
+		Worker: NodeGroup{
+			ExpectedCount: 1,
+			Nodes:         []Node{{Host: "existingWorker"}},
+		},
+		WorkerPools: []WorkerPool{
+			{Name: "cpu", ExpectedCount: 1, Nodes: []Node{{Host: "cpu01"}}},
+			{Name: "gpu", ExpectedCount: 1, Nodes: []Node{{Host: "gpu01"}}},
+		},
+	}
+	updatedPlan, err := addWorkerToPlan(originalPlan, Node{Host: "gpu02"}, "gpu")
+	if err != nil {
+		t.Fatalf("unexpected error while adding worker: %v", err)
+	}
+	gpu := updatedPlan.workerPool("gpu")
+	if gpu.ExpectedCount != 2 || len(gpu.Nodes) != 2 || gpu.Nodes[1].Host != "gpu02" {
+		t.Errorf("the new worker was not added to the pool: %+v", gpu)
+	}
+	if updatedPlan.Worker.ExpectedCount != 1 || updatedPlan.workerPool("cpu").ExpectedCount != 1 {
+		t.Errorf("the new worker was added to another group: %+v", updatedPlan)
+	}
+	if originalPlan.workerPool("gpu").ExpectedCount != 1 || len(originalPlan.workerPool("gpu").Nodes) != 1 {
+		t.Errorf("the original plan was modified")
+	}
+	if _, err := addWorkerToPlan(originalPlan, Node{Host: "foo"}, "foo"); err == nil {
+		t.Errorf("expected an error adding a worker to an undefined pool")
+	}
+}
+
 func TestAddWorkerPlanNotUpdatedAfterFailure(t *testing.T) {
 	e := ansibleExecutor{
 		options:             ExecutorOptions{RestartServices: true, RunsDirectory: mustGetTempDir(t)},
@@ -163,7 +193,7 @@ func TestAddWorkerPlanNotUpdatedAfterFailure(t *testing.T) {
 	newWorker := Node{
 		Host: "test",
 	}
-	updatedPlan, err := e.AddWorker(originalPlan, newWorker)
+	updatedPlan, err := e.AddWorker(originalPlan, newWorker, "")
 	if err == nil {
 		t.Errorf("expected an error, but didn't get one")
 	}
@@ -207,7 +237,7 @@ func TestAddWorkerRestartServicesEnabled(t *testing.T) {
 	newWorker := Node{
 		Host: "test",
 	}
-	_, err := e.AddWorker(originalPlan, newWorker)
+	_, err := e.AddWorker(originalPlan, newWorker, "")
 	if err != nil {
 		t.Errorf("unexpected error")
 	}
@@ -265,7 +295,7 @@ func TestAddWorkerHostsFilesDNSEnabled(t *testing.T) {
 	newWorker := Node{
 		Host: "test",
 	}
-	_, err := e.AddWorker(originalPlan, newWorker)
+	_, err := e.AddWorker(originalPlan, newWorker, "")
 	if err != nil {
 		t.Errorf("unexpected error")
 	}
@@ -317,7 +347,9 @@ func (f *fakeRunner) StartPlaybook(playbookFile string, inventory ansible.Invent
 	f.allNodesPlaybooks = append(f.allNodesPlaybooks, playbookFile)
 	return f.eventChan, f.err
 }
-func (f *fakeRunner) WaitPlaybook() error { return f.err }
+func (f *fakeRunner) WaitPlaybook() error   { return f.err }
+func (f *fakeRunner) CancelPlaybook() error { return nil }
+func (f *fakeRunner) KillPlaybook() error   { return nil }
 func (f *fakeRunner) StartPlaybookOnNode(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog, node ...string) (<-chan ansible.Event, error) {
 	f.incomingCatalog = cc
 	return f.eventChan, f.err
@@ -0,0 +1,160 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// The directories that are kept in the assets storage, under the location of
+// the storage
+const (
+	assetsStorageGeneratedDir = "generated"
+	assetsStorageRunsDir      = "runs"
+)
+
+// blobStore keeps a copy of local directories in object storage
+type blobStore interface {
+	// download copies the objects under the prefix to the directory
+	download(prefix string, dir string) error
+	// upload copies the directory to the objects under the prefix, and deletes
+	// the objects of the files that are no longer in the directory
+	upload(dir string, prefix string) error
+}
+
+// remote returns the command line tool of the object storage provider, and
+// the location of the assets as a URL without a trailing slash
+func (s ClusterAssetsStorage) remote() (tool string, location string, err error) {
+	u, err := url.Parse(s.Location)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "s3":
+		tool = "aws"
+	case "gs":
+		tool = "gsutil"
+	default:
+		return "", "", fmt.Errorf("unsupported scheme %q, options are [s3 gs]", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", "", errors.New("bucket name is missing")
+	}
+	location = u.Scheme + "://" + u.Host
+	if prefix := strings.Trim(u.Path, "/"); prefix != "" {
+		location = location + "/" + prefix
+	}
+	return tool, location, nil
+}
+
+func newBlobStore(s ClusterAssetsStorage) (blobStore, error) {
+	tool, location, err := s.remote()
+	if err != nil {
+		return nil, err
+	}
+	run, err := commandLineTool(tool)
+	if err != nil {
+		return nil, err
+	}
+	if tool == "gsutil" {
+		return gcsBlobStore{location: location, run: run}, nil
+	}
+	return s3BlobStore{location: location, run: run}, nil
+}
+
+// DownloadAssets copies the generated assets and the logs of the runs from
+// the assets storage of the cluster to the local directories. Nothing is
+// copied when the plan has no assets storage.
+func DownloadAssets(p *Plan, generatedAssetsDir, runsDir string) error {
+	if p.Cluster.AssetsStorage.Location == "" {
+		return nil
+	}
+	store, err := newBlobStore(p.Cluster.AssetsStorage)
+	if err != nil {
+		return err
+	}
+	for prefix, dir := range assetsStorageDirs(generatedAssetsDir, runsDir) {
+		if err = os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("error creating directory %q: %v", dir, err)
+		}
+		if err = store.download(prefix, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UploadAssets copies the generated assets and the logs of the runs to the
+// assets storage of the cluster. Nothing is copied when the plan has no
+// assets storage.
+func UploadAssets(p *Plan, generatedAssetsDir, runsDir string) error {
+	if p.Cluster.AssetsStorage.Location == "" {
+		return nil
+	}
+	store, err := newBlobStore(p.Cluster.AssetsStorage)
+	if err != nil {
+		return err
+	}
+	for prefix, dir := range assetsStorageDirs(generatedAssetsDir, runsDir) {
+		if _, err = os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		if err = store.upload(dir, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The etcd snapshots of the rollback points hold every secret of the cluster,
+// so they are never synced with the assets storage, and are only kept on the
+// machine that took them
+const (
+	s3SnapshotExclude  = "*.db"
+	gcsSnapshotExclude = `.*\.db$`
+)
+
+func assetsStorageDirs(generatedAssetsDir, runsDir string) map[string]string {
+	return map[string]string{
+		assetsStorageGeneratedDir: generatedAssetsDir,
+		assetsStorageRunsDir:      runsDir,
+	}
+}
+
+// s3BlobStore keeps the directories in an S3 bucket
+type s3BlobStore struct {
+	location string
+	run      commandRunner
+}
+
+func (s s3BlobStore) download(prefix string, dir string) error {
+	_, err := s.run(nil, "s3", "sync", "--only-show-errors", "--exclude", s3SnapshotExclude, s.location+"/"+prefix, dir)
+	return err
+}
+
+func (s s3BlobStore) upload(dir string, prefix string) error {
+	_, err := s.run(nil, "s3", "sync", "--only-show-errors", "--delete", "--exclude", s3SnapshotExclude, dir, s.location+"/"+prefix)
+	return err
+}
+
+// gcsBlobStore keeps the directories in a GCS bucket
+type gcsBlobStore struct {
+	location string
+	run      commandRunner
+}
+
+func (s gcsBlobStore) download(prefix string, dir string) error {
+	_, err := s.run(nil, "-q", "-m", "rsync", "-r", "-x", gcsSnapshotExclude, s.location+"/"+prefix, dir)
+	if err != nil && strings.Contains(err.Error(), "No URLs matched") {
+		// nothing was uploaded yet
+		return nil
+	}
+	return err
+}
+
+func (s gcsBlobStore) upload(dir string, prefix string) error {
+	_, err := s.run(nil, "-q", "-m", "rsync", "-r", "-d", "-x", gcsSnapshotExclude, dir, s.location+"/"+prefix)
+	return err
+}
@@ -0,0 +1,122 @@
+package install
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestAssetsStorageRemote(t *testing.T) {
+	tests := []struct {
+		location         string
+		expectedTool     string
+		expectedLocation string
+		expectErr        bool
+	}{
+		{
+			location:         "s3://bucket",
+			expectedTool:     "aws",
+			expectedLocation: "s3://bucket",
+		},
+		{
+			location:         "s3://bucket/clusters/prod/",
+			expectedTool:     "aws",
+			expectedLocation: "s3://bucket/clusters/prod",
+		},
+		{
+			location:         "gs://bucket/prod",
+			expectedTool:     "gsutil",
+			expectedLocation: "gs://bucket/prod",
+		},
+		{
+			location:  "https://bucket/prod",
+			expectErr: true,
+		},
+		{
+			location:  "s3:///prod",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		tool, location, err := ClusterAssetsStorage{Location: test.location}.remote()
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, but didn't get one", test.location)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.location, err)
+			continue
+		}
+		if tool != test.expectedTool || location != test.expectedLocation {
+			t.Errorf("%s: expected %s %s, but got %s %s", test.location, test.expectedTool, test.expectedLocation, tool, location)
+		}
+	}
+}
+
+func TestBlobStoreCommands(t *testing.T) {
+	var calls [][]string
+	run := func(in []byte, args ...string) (string, error) {
+		calls = append(calls, args)
+		return "", nil
+	}
+	tests := []struct {
+		store            blobStore
+		expectedDownload []string
+		expectedUpload   []string
+	}{
+		{
+			store:            s3BlobStore{location: "s3://bucket/prod", run: run},
+			expectedDownload: []string{"s3", "sync", "--only-show-errors", "--exclude", "*.db", "s3://bucket/prod/generated", "assets"},
+			expectedUpload:   []string{"s3", "sync", "--only-show-errors", "--delete", "--exclude", "*.db", "assets", "s3://bucket/prod/generated"},
+		},
+		{
+			store:            gcsBlobStore{location: "gs://bucket/prod", run: run},
+			expectedDownload: []string{"-q", "-m", "rsync", "-r", "-x", `.*\.db$`, "gs://bucket/prod/generated", "assets"},
+			expectedUpload:   []string{"-q", "-m", "rsync", "-r", "-d", "-x", `.*\.db$`, "assets", "gs://bucket/prod/generated"},
+		},
+	}
+	for i, test := range tests {
+		calls = nil
+		if err := test.store.download("generated", "assets"); err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if err := test.store.upload("assets", "generated"); err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		expected := [][]string{test.expectedDownload, test.expectedUpload}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("test %d: expected commands %v, but got %v", i, expected, calls)
+		}
+	}
+}
+
+func TestBlobStoreExcludesSnapshots(t *testing.T) {
+	gcsExclude := regexp.MustCompile(gcsSnapshotExclude)
+	tests := []struct {
+		path     string
+		excluded bool
+	}{
+		{path: "rollback/20180102T150405Z-etcd.db", excluded: true},
+		{path: "etcd.db", excluded: true},
+		{path: "rollback/20180102T150405Z.json"},
+		{path: "keys/ca.pem"},
+		{path: "kubeconfig"},
+	}
+	for _, test := range tests {
+		// the patterns of the aws command line tool are shell patterns where
+		// the wildcard also matches the separator
+		s3Excluded, err := filepath.Match(s3SnapshotExclude, filepath.Base(test.path))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s3Excluded != test.excluded {
+			t.Errorf("%s: expected the S3 exclusion to be %v, but was %v", test.path, test.excluded, s3Excluded)
+		}
+		if gcsExcluded := gcsExclude.MatchString(test.path); gcsExcluded != test.excluded {
+			t.Errorf("%s: expected the GCS exclusion to be %v, but was %v", test.path, test.excluded, gcsExcluded)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package install
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// AuditLogPath is the path of the API server audit log on the master nodes
+const AuditLogPath = "/var/log/kubernetes/audit.log"
+
+// applyAuditLog sets the API server options required by the plan's audit
+// log configuration on the cluster catalog. Options that were explicitly
+// overridden in the plan file are left untouched.
+func applyAuditLog(p *Plan, cc *ansible.ClusterCatalog) {
+	a := p.Cluster.Audit
+	if !a.Enabled {
+		return
+	}
+	cc.Audit.Enabled = true
+	cc.Audit.Level = a.Level
+	cc.Audit.Sink = a.Sink
+	cc.Audit.WebhookURL = a.WebhookURL
+
+	options := map[string]string{
+		"audit-policy-file": "{{ kubernetes_audit_policy_path }}",
+	}
+	switch a.Sink {
+	case auditSinkWebhook:
+		options["audit-webhook-config-file"] = "{{ kubernetes_audit_webhook_config_path }}"
+		options["audit-webhook-mode"] = "batch"
+	default:
+		options["audit-log-path"] = AuditLogPath
+		options["audit-log-format"] = "json"
+		options["audit-log-maxage"] = strconv.Itoa(a.MaxAge)
+		options["audit-log-maxbackup"] = strconv.Itoa(a.MaxBackups)
+		options["audit-log-maxsize"] = strconv.Itoa(a.MaxSize)
+	}
+	cc.APIServerOptions = copyOptions(cc.APIServerOptions)
+	for k, v := range options {
+		if _, ok := cc.APIServerOptions[k]; !ok {
+			cc.APIServerOptions[k] = v
+		}
+	}
+}
+
+// AuditEvent is a request recorded in the API server audit log
+type AuditEvent struct {
+	Stage      string `json:"stage"`
+	RequestURI string `json:"requestURI"`
+	Verb       string `json:"verb"`
+	User       struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups,omitempty"`
+	} `json:"user"`
+	SourceIPs      []string `json:"sourceIPs,omitempty"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus,omitempty"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	StageTimestamp           time.Time `json:"stageTimestamp"`
+	// Host is the master node that recorded the event
+	Host string `json:"host,omitempty"`
+}
+
+// Code returns the response code of the request, or zero if the request
+// did not complete
+func (e AuditEvent) Code() int {
+	if e.ResponseStatus == nil {
+		return 0
+	}
+	return e.ResponseStatus.Code
+}
+
+// Latency returns the time it took the API server to respond to the request
+func (e AuditEvent) Latency() time.Duration {
+	if e.RequestReceivedTimestamp.IsZero() || e.StageTimestamp.IsZero() {
+		return 0
+	}
+	return e.StageTimestamp.Sub(e.RequestReceivedTimestamp)
+}
+
+// AuditFilter selects audit events. Empty fields match all events.
+type AuditFilter struct {
+	User  string
+	Verb  string
+	Path  string
+	Since time.Time
+	// FailedOnly selects the requests with a response code of 400 or higher
+	FailedOnly bool
+}
+
+func (f AuditFilter) matches(e AuditEvent) bool {
+	switch {
+	case f.User != "" && e.User.Username != f.User:
+		return false
+	case f.Verb != "" && e.Verb != f.Verb:
+		return false
+	case f.Path != "" && !strings.HasPrefix(e.RequestURI, f.Path):
+		return false
+	case !f.Since.IsZero() && e.StageTimestamp.Before(f.Since):
+		return false
+	case f.FailedOnly && e.Code() < 400:
+		return false
+	}
+	return true
+}
+
+// ReadAuditEvents returns the completed requests of the audit log that match
+// the filter. Lines that are not JSON audit events are skipped.
+func ReadAuditEvents(r io.Reader, host string, filter AuditFilter) ([]AuditEvent, error) {
+	var events []AuditEvent
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for s.Scan() {
+		var e AuditEvent
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			continue
+		}
+		// each request is recorded once per stage, only keep the final one
+		if e.Stage != "ResponseComplete" && e.Stage != "Panic" {
+			continue
+		}
+		if !filter.matches(e) {
+			continue
+		}
+		e.Host = host
+		events = append(events, e)
+	}
+	return events, s.Err()
+}
@@ -0,0 +1,97 @@
+package install
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestApplyAuditLog(t *testing.T) {
+	tests := []struct {
+		audit     AuditLog
+		overrides map[string]string
+		expected  map[string]string
+	}{
+		{
+			audit:    AuditLog{},
+			expected: map[string]string{},
+		},
+		{
+			audit: AuditLog{Enabled: true, Level: "Metadata", Sink: "file", MaxAge: 7, MaxBackups: 3, MaxSize: 50},
+			expected: map[string]string{
+				"audit-policy-file":   "{{ kubernetes_audit_policy_path }}",
+				"audit-log-path":      AuditLogPath,
+				"audit-log-format":    "json",
+				"audit-log-maxage":    "7",
+				"audit-log-maxbackup": "3",
+				"audit-log-maxsize":   "50",
+			},
+		},
+		{
+			audit:     AuditLog{Enabled: true, Level: "Request", Sink: "webhook", WebhookURL: "https://audit.example.com"},
+			overrides: map[string]string{"audit-webhook-mode": "blocking"},
+			expected: map[string]string{
+				"audit-policy-file":         "{{ kubernetes_audit_policy_path }}",
+				"audit-webhook-config-file": "{{ kubernetes_audit_webhook_config_path }}",
+				"audit-webhook-mode":        "blocking",
+			},
+		},
+	}
+	for i, test := range tests {
+		p := &Plan{}
+		p.Cluster.Audit = test.audit
+		cc := &ansible.ClusterCatalog{APIServerOptions: test.overrides}
+		applyAuditLog(p, cc)
+		if len(cc.APIServerOptions) != len(test.expected) {
+			t.Errorf("test %d: expected options %v, but got %v", i, test.expected, cc.APIServerOptions)
+		}
+		for k, v := range test.expected {
+			if cc.APIServerOptions[k] != v {
+				t.Errorf("test %d: expected option %q to be %q, but got %q", i, k, v, cc.APIServerOptions[k])
+			}
+		}
+		if cc.Audit.Enabled != test.audit.Enabled || cc.Audit.WebhookURL != test.audit.WebhookURL {
+			t.Errorf("test %d: unexpected audit catalog: %+v", i, cc.Audit)
+		}
+	}
+}
+
+func TestReadAuditEvents(t *testing.T) {
+	log := `{"kind":"Event","apiVersion":"audit.k8s.io/v1beta1","level":"Metadata","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods","verb":"list","user":{"username":"admin","groups":["system:masters"]},"responseStatus":{"code":200},"requestReceivedTimestamp":"2017-11-20T10:00:00.000000Z","stageTimestamp":"2017-11-20T10:00:00.250000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1beta1","level":"Metadata","stage":"ResponseStarted","requestURI":"/api/v1/watch/pods","verb":"watch","user":{"username":"admin"},"requestReceivedTimestamp":"2017-11-20T10:01:00Z","stageTimestamp":"2017-11-20T10:01:00Z"}
+not an audit event
+{"kind":"Event","apiVersion":"audit.k8s.io/v1beta1","level":"Metadata","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/kube-system/secrets","verb":"get","user":{"username":"jane"},"responseStatus":{"code":403},"requestReceivedTimestamp":"2017-11-20T10:02:00Z","stageTimestamp":"2017-11-20T10:02:00Z"}
+`
+	events, err := ReadAuditEvents(strings.NewReader(log), "master01", AuditFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 completed requests, but got %d", len(events))
+	}
+	if e := events[0]; e.Host != "master01" || e.User.Username != "admin" || e.Verb != "list" || e.Code() != 200 || e.Latency() != 250*time.Millisecond {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	filters := []struct {
+		filter   AuditFilter
+		expected int
+	}{
+		{AuditFilter{User: "jane"}, 1},
+		{AuditFilter{Verb: "delete"}, 0},
+		{AuditFilter{Path: "/api/v1/namespaces/default"}, 1},
+		{AuditFilter{FailedOnly: true}, 1},
+		{AuditFilter{Since: time.Date(2017, 11, 20, 10, 1, 0, 0, time.UTC)}, 1},
+	}
+	for i, f := range filters {
+		events, err := ReadAuditEvents(strings.NewReader(log), "master01", f.filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != f.expected {
+			t.Errorf("filter %d: expected %d events, but got %d", i, f.expected, len(events))
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package install
+
+import (
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// The authorizers of the API server when the authorization is delegated to
+// the webhook. The webhook is only consulted for the requests that the
+// other authorizers do not allow.
+const authWebhookAuthorizationMode = "Node,RBAC,ABAC,Webhook"
+
+// applyAuthWebhook sets the API server options required by the plan's auth
+// webhook configuration on the cluster catalog. Options that were explicitly
+// overridden in the plan file are left untouched.
+func applyAuthWebhook(p *Plan, cc *ansible.ClusterCatalog) {
+	w := p.Cluster.AuthWebhook
+	if !w.enabled() {
+		return
+	}
+	cc.AuthWebhook.AuthenticationURL = w.AuthenticationURL
+	cc.AuthWebhook.AuthorizationURL = w.AuthorizationURL
+	cc.AuthWebhook.CAPath = w.CAPath
+
+	options := map[string]string{}
+	if w.AuthenticationURL != "" {
+		options["authentication-token-webhook-config-file"] = "{{ kubernetes_authentication_webhook_config_path }}"
+		options["authentication-token-webhook-cache-ttl"] = w.CacheTTL
+	}
+	if w.AuthorizationURL != "" {
+		options["authorization-mode"] = authWebhookAuthorizationMode
+		options["authorization-webhook-config-file"] = "{{ kubernetes_authorization_webhook_config_path }}"
+		options["authorization-webhook-cache-authorized-ttl"] = w.CacheTTL
+	}
+	cc.APIServerOptions = copyOptions(cc.APIServerOptions)
+	for k, v := range options {
+		if _, ok := cc.APIServerOptions[k]; !ok {
+			cc.APIServerOptions[k] = v
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestApplyAuthWebhook(t *testing.T) {
+	tests := []struct {
+		webhook   AuthWebhook
+		overrides map[string]string
+		expected  map[string]string
+	}{
+		{
+			webhook:  AuthWebhook{},
+			expected: map[string]string{},
+		},
+		{
+			webhook: AuthWebhook{AuthenticationURL: "https://identity.example.com/authenticate", CacheTTL: "2m", CAPath: "/etc/identity/ca.pem"},
+			expected: map[string]string{
+				"authentication-token-webhook-config-file": "{{ kubernetes_authentication_webhook_config_path }}",
+				"authentication-token-webhook-cache-ttl":   "2m",
+			},
+		},
+		{
+			webhook: AuthWebhook{AuthorizationURL: "https://identity.example.com/authorize", CacheTTL: "30s"},
+			expected: map[string]string{
+				"authorization-mode":                         "Node,RBAC,ABAC,Webhook",
+				"authorization-webhook-config-file":          "{{ kubernetes_authorization_webhook_config_path }}",
+				"authorization-webhook-cache-authorized-ttl": "30s",
+			},
+		},
+		{
+			webhook: AuthWebhook{
+				AuthenticationURL: "https://identity.example.com/authenticate",
+				AuthorizationURL:  "https://identity.example.com/authorize",
+				CacheTTL:          "2m",
+			},
+			overrides: map[string]string{"authorization-mode": "Node,Webhook"},
+			expected: map[string]string{
+				"authentication-token-webhook-config-file":   "{{ kubernetes_authentication_webhook_config_path }}",
+				"authentication-token-webhook-cache-ttl":     "2m",
+				"authorization-mode":                         "Node,Webhook",
+				"authorization-webhook-config-file":          "{{ kubernetes_authorization_webhook_config_path }}",
+				"authorization-webhook-cache-authorized-ttl": "2m",
+			},
+		},
+	}
+	for i, test := range tests {
+		p := &Plan{}
+		p.Cluster.AuthWebhook = test.webhook
+		cc := &ansible.ClusterCatalog{APIServerOptions: test.overrides}
+		applyAuthWebhook(p, cc)
+		if len(cc.APIServerOptions) != len(test.expected) {
+			t.Errorf("test %d: expected options %v, but got %v", i, test.expected, cc.APIServerOptions)
+		}
+		for k, v := range test.expected {
+			if cc.APIServerOptions[k] != v {
+				t.Errorf("test %d: expected option %q to be %q, but got %q", i, k, v, cc.APIServerOptions[k])
+			}
+		}
+		if cc.AuthWebhook.AuthenticationURL != test.webhook.AuthenticationURL || cc.AuthWebhook.AuthorizationURL != test.webhook.AuthorizationURL || cc.AuthWebhook.CAPath != test.webhook.CAPath {
+			t.Errorf("test %d: unexpected auth webhook catalog: %+v", i, cc.AuthWebhook)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// the default AS number of calico, which is a private AS number
+const defaultCalicoASNumber = 64512
+
+const (
+	calicoIPIPModeAlways      = "always"
+	calicoIPIPModeCrossSubnet = "cross-subnet"
+	calicoIPIPModeOff         = "off"
+)
+
+func calicoIPIPModes() []string {
+	return []string{calicoIPIPModeAlways, calicoIPIPModeCrossSubnet, calicoIPIPModeOff}
+}
+
+func (b *CalicoBGP) validate() (bool, []error) {
+	v := newValidator()
+	peers := map[string]bool{}
+	for _, p := range append(append([]CalicoBGPPeer{}, b.Peers...), b.RouteReflectors...) {
+		v.validate(&p)
+		key := p.IP + "/" + p.Node
+		if peers[key] {
+			v.addError(fmt.Errorf("BGP peer %q is duplicated", p.IP))
+		}
+		peers[key] = true
+	}
+	for _, rr := range b.RouteReflectors {
+		if rr.Node != "" {
+			v.addError(fmt.Errorf("Route reflector %q cannot be limited to a node, as all nodes must peer with the route reflectors", rr.IP))
+		}
+	}
+	return v.valid()
+}
+
+func (p *CalicoBGPPeer) validate() (bool, []error) {
+	v := newValidator()
+	if net.ParseIP(p.IP) == nil {
+		v.addError(fmt.Errorf("BGP peer IP %q is not a valid IP address", p.IP))
+	}
+	if p.ASNumber == 0 {
+		v.addError(fmt.Errorf("AS number of BGP peer %q is required", p.IP))
+	}
+	return v.valid()
+}
+
+func (pool *CalicoIPPool) validate() (bool, []error) {
+	v := newValidator()
+	if _, _, err := net.ParseCIDR(pool.CIDR); err != nil {
+		v.addError(fmt.Errorf("IP pool CIDR %q is not valid: %v", pool.CIDR, err))
+	}
+	if pool.IPIPMode != "" && !util.Contains(pool.IPIPMode, calicoIPIPModes()) {
+		v.addError(fmt.Errorf("IP-in-IP mode %q of IP pool %q is not valid, options are %v", pool.IPIPMode, pool.CIDR, calicoIPIPModes()))
+	}
+	return v.valid()
+}
+
+// validateCalicoBGP validates the BGP configuration and the IP pools of
+// calico against the nodes and the pod CIDR block of the plan
+func (p *Plan) validateCalicoBGP() []error {
+	cni := p.AddOns.CNI
+	if cni == nil || cni.Disable {
+		return nil
+	}
+	calico := cni.Options.Calico
+	if cni.Provider != cniProviderCalico {
+		if len(calico.BGP.Peers) > 0 || len(calico.BGP.RouteReflectors) > 0 || len(calico.IPPools) > 0 {
+			return []error{errors.New("Calico BGP peers and IP pools require the calico CNI provider")}
+		}
+		return nil
+	}
+	v := newValidator()
+	v.validateWithErrPrefix("Calico BGP", &calico.BGP)
+	for _, peer := range calico.BGP.Peers {
+		if peer.Node != "" && !p.hasNode(peer.Node) {
+			v.addError(fmt.Errorf("Calico BGP: node %q of BGP peer %q is not a node of the cluster", peer.Node, peer.IP))
+		}
+	}
+	_, podCIDR, podErr := net.ParseCIDR(p.Cluster.Networking.PodCIDRBlock)
+	var pools []*net.IPNet
+	for i := range calico.IPPools {
+		pool := &calico.IPPools[i]
+		v.validateWithErrPrefix("Calico IP pool", pool)
+		_, cidr, err := net.ParseCIDR(pool.CIDR)
+		if err != nil {
+			continue
+		}
+		if podErr == nil && !cidrContains(podCIDR, cidr) {
+			v.addError(fmt.Errorf("Calico IP pool %q is not within the pod CIDR block %q", pool.CIDR, p.Cluster.Networking.PodCIDRBlock))
+		}
+		for _, other := range pools {
+			if cidrsOverlap(cidr, other) {
+				v.addError(fmt.Errorf("Calico IP pool %q overlaps with IP pool %q", pool.CIDR, other.String()))
+			}
+		}
+		pools = append(pools, cidr)
+	}
+	_, errs := v.valid()
+	return errs
+}
+
+// cidrContains returns whether the block a contains the block b
+func cidrContains(a, b *net.IPNet) bool {
+	aOnes, _ := a.Mask.Size()
+	bOnes, _ := b.Mask.Size()
+	return a.Contains(b.IP) && aOnes <= bOnes
+}
+
+// hasNode returns whether the host is a node of the plan
+func (p *Plan) hasNode(host string) bool {
+	for _, n := range p.GetUniqueNodes() {
+		if n.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCalicoBGP sets the BGP configuration and the IP pools of calico on
+// the cluster catalog
+func applyCalicoBGP(p *Plan, cc *ansible.ClusterCatalog) {
+	if p.AddOns.CNI == nil || p.AddOns.CNI.Provider != cniProviderCalico {
+		return
+	}
+	calico := p.AddOns.CNI.Options.Calico
+	bgp := &cc.CNI.Options.Calico.BGP
+	bgp.ASNumber = calico.BGP.ASNumber
+	bgp.NodeToNodeMesh = len(calico.BGP.RouteReflectors) == 0
+	for _, peer := range append(append([]CalicoBGPPeer{}, calico.BGP.Peers...), calico.BGP.RouteReflectors...) {
+		bgp.Peers = append(bgp.Peers, ansible.CalicoBGPPeer{IP: peer.IP, ASNumber: peer.ASNumber, Node: peer.Node})
+	}
+	for _, pool := range calico.IPPools {
+		cc.CNI.Options.Calico.IPPools = append(cc.CNI.Options.Calico.IPPools, ansible.CalicoIPPool{
+			CIDR:        pool.CIDR,
+			IPIPMode:    pool.IPIPMode,
+			NATOutgoing: pool.NATOutgoing,
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestValidateCalicoBGP(t *testing.T) {
+	tests := []struct {
+		name    string
+		calico  CalicoOptions
+		podCIDR string
+		valid   bool
+	}{
+		{
+			name:  "no BGP configuration",
+			valid: true,
+		},
+		{
+			name: "peers, route reflectors and pools",
+			calico: CalicoOptions{
+				BGP: CalicoBGP{
+					ASNumber:        64512,
+					Peers:           []CalicoBGPPeer{{IP: "10.0.0.1", ASNumber: 64600, Node: "worker"}},
+					RouteReflectors: []CalicoBGPPeer{{IP: "10.0.0.254", ASNumber: 64512}},
+				},
+				IPPools: []CalicoIPPool{
+					{CIDR: "172.16.0.0/17", IPIPMode: "cross-subnet"},
+					{CIDR: "172.16.128.0/17", IPIPMode: "off"},
+				},
+			},
+			valid: true,
+		},
+		{
+			name:   "peer without AS number",
+			calico: CalicoOptions{BGP: CalicoBGP{Peers: []CalicoBGPPeer{{IP: "10.0.0.1"}}}},
+		},
+		{
+			name:   "peer of an unknown node",
+			calico: CalicoOptions{BGP: CalicoBGP{Peers: []CalicoBGPPeer{{IP: "10.0.0.1", ASNumber: 64600, Node: "rack2-worker"}}}},
+		},
+		{
+			name:   "route reflector of a node",
+			calico: CalicoOptions{BGP: CalicoBGP{RouteReflectors: []CalicoBGPPeer{{IP: "10.0.0.254", ASNumber: 64512, Node: "worker"}}}},
+		},
+		{
+			name:   "pool outside of the pod CIDR block",
+			calico: CalicoOptions{IPPools: []CalicoIPPool{{CIDR: "10.200.0.0/24"}}},
+		},
+		{
+			name:   "pool larger than the pod CIDR block",
+			calico: CalicoOptions{IPPools: []CalicoIPPool{{CIDR: "172.0.0.0/8"}}},
+		},
+		{
+			name:   "overlapping pools",
+			calico: CalicoOptions{IPPools: []CalicoIPPool{{CIDR: "172.16.0.0/17"}, {CIDR: "172.16.64.0/18"}}},
+		},
+		{
+			name:   "VXLAN pool",
+			calico: CalicoOptions{IPPools: []CalicoIPPool{{CIDR: "172.16.0.0/17", IPIPMode: "vxlan"}}},
+		},
+	}
+	for _, test := range tests {
+		p := &Plan{
+			Cluster: Cluster{Networking: NetworkConfig{PodCIDRBlock: "172.16.0.0/16"}},
+			Worker:  NodeGroup{Nodes: []Node{{Host: "worker", IP: "10.0.0.10"}}},
+			AddOns:  AddOns{CNI: &CNI{Provider: cniProviderCalico, Options: CNIOptions{Calico: test.calico}}},
+		}
+		if errs := p.validateCalicoBGP(); (len(errs) == 0) != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got errors %v", test.name, test.valid, errs)
+		}
+	}
+}
+
+func TestApplyCalicoBGP(t *testing.T) {
+	p := &Plan{
+		AddOns: AddOns{CNI: &CNI{Provider: cniProviderCalico, Options: CNIOptions{Calico: CalicoOptions{
+			BGP: CalicoBGP{
+				ASNumber:        64513,
+				Peers:           []CalicoBGPPeer{{IP: "10.0.0.1", ASNumber: 64600, Node: "worker"}},
+				RouteReflectors: []CalicoBGPPeer{{IP: "10.0.0.254", ASNumber: 64513}},
+			},
+			IPPools: []CalicoIPPool{{CIDR: "172.16.0.0/17", IPIPMode: "cross-subnet", NATOutgoing: true}},
+		}}}},
+	}
+	cc := &ansible.ClusterCatalog{}
+	applyCalicoBGP(p, cc)
+	bgp := cc.CNI.Options.Calico.BGP
+	if bgp.ASNumber != 64513 || bgp.NodeToNodeMesh || len(bgp.Peers) != 2 {
+		t.Errorf("unexpected BGP configuration: %+v", bgp)
+	}
+	if pools := cc.CNI.Options.Calico.IPPools; len(pools) != 1 || pools[0].IPIPMode != "cross-subnet" || !pools[0].NATOutgoing {
+		t.Errorf("unexpected IP pools: %+v", pools)
+	}
+}
@@ -0,0 +1,140 @@
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const cisBenchmarkReportFilename = "report.json"
+
+// CISBenchmarkReport is the result of running the CIS Kubernetes Benchmark
+// on the cluster nodes
+type CISBenchmarkReport struct {
+	ClusterName string                   `json:"clusterName"`
+	Time        time.Time                `json:"time"`
+	Nodes       []CISBenchmarkNodeReport `json:"nodes"`
+}
+
+// CISBenchmarkNodeReport contains the benchmark results of a single node,
+// for a single kube-bench target (master or node)
+type CISBenchmarkNodeReport struct {
+	Host   string              `json:"host"`
+	Target string              `json:"target"`
+	Pass   int                 `json:"pass"`
+	Fail   int                 `json:"fail"`
+	Warn   int                 `json:"warn"`
+	Info   int                 `json:"info"`
+	Checks []CISBenchmarkCheck `json:"checks"`
+}
+
+// CISBenchmarkCheck is the result of a single benchmark recommendation
+type CISBenchmarkCheck struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Scored      bool   `json:"scored"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// Failed returns the number of failed checks across all nodes
+func (r CISBenchmarkReport) Failed() int {
+	var failed int
+	for _, n := range r.Nodes {
+		failed += n.Fail
+	}
+	return failed
+}
+
+// kubeBenchControls is the JSON output of kube-bench
+type kubeBenchControls struct {
+	Groups []struct {
+		Checks []struct {
+			ID          string `json:"test_number"`
+			Text        string `json:"test_desc"`
+			Remediation string `json:"remediation"`
+			State       string `json:"status"`
+			Scored      bool   `json:"scored"`
+		} `json:"results"`
+	} `json:"tests"`
+	Pass int `json:"total_pass"`
+	Fail int `json:"total_fail"`
+	Warn int `json:"total_warn"`
+	Info int `json:"total_info"`
+}
+
+func parseKubeBenchResults(host, target string, b []byte) (*CISBenchmarkNodeReport, error) {
+	var c kubeBenchControls
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("error parsing kube-bench %s results of node %q: %v", target, host, err)
+	}
+	r := &CISBenchmarkNodeReport{
+		Host:   host,
+		Target: target,
+		Pass:   c.Pass,
+		Fail:   c.Fail,
+		Warn:   c.Warn,
+		Info:   c.Info,
+	}
+	for _, g := range c.Groups {
+		for _, check := range g.Checks {
+			r.Checks = append(r.Checks, CISBenchmarkCheck{
+				ID:          check.ID,
+				Description: check.Text,
+				Status:      check.State,
+				Scored:      check.Scored,
+				Remediation: check.Remediation,
+			})
+		}
+	}
+	return r, nil
+}
+
+// readCISBenchmarkResults reads the kube-bench results fetched from the nodes.
+// The results of each node are in a directory named after the node, with a
+// JSON file per kube-bench target.
+func readCISBenchmarkResults(dir string) (*CISBenchmarkReport, error) {
+	hosts, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CIS benchmark results directory: %v", err)
+	}
+	report := &CISBenchmarkReport{}
+	for _, h := range hosts {
+		if !h.IsDir() {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(dir, h.Name(), "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("error listing CIS benchmark results of node %q: %v", h.Name(), err)
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("error reading file %q: %v", f, err)
+			}
+			target := strings.TrimSuffix(filepath.Base(f), ".json")
+			nr, err := parseKubeBenchResults(h.Name(), target, b)
+			if err != nil {
+				return nil, err
+			}
+			report.Nodes = append(report.Nodes, *nr)
+		}
+	}
+	return report, nil
+}
+
+func writeCISBenchmarkReport(r *CISBenchmarkReport, file string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling CIS benchmark report: %v", err)
+	}
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return fmt.Errorf("error writing CIS benchmark report %q: %v", file, err)
+	}
+	return nil
+}
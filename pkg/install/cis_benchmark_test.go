@@ -0,0 +1,96 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const kubeBenchMasterOutput = `{
+  "id": "1",
+  "version": "1.8",
+  "text": "Master Node Security Configuration",
+  "node_type": "master",
+  "tests": [
+    {
+      "section": "1.1",
+      "desc": "API Server",
+      "results": [
+        {"test_number": "1.1.1", "test_desc": "Ensure that the --anonymous-auth argument is set to false", "status": "PASS", "scored": true},
+        {"test_number": "1.1.2", "test_desc": "Ensure that the --basic-auth-file argument is not set", "remediation": "Remove the --basic-auth-file argument", "status": "FAIL", "scored": true}
+      ]
+    },
+    {
+      "section": "1.2",
+      "desc": "Scheduler",
+      "results": [
+        {"test_number": "1.2.1", "test_desc": "Ensure that the --profiling argument is set to false", "status": "WARN", "scored": true}
+      ]
+    }
+  ],
+  "total_pass": 1,
+  "total_fail": 1,
+  "total_warn": 1,
+  "total_info": 0
+}`
+
+func TestParseKubeBenchResults(t *testing.T) {
+	r, err := parseKubeBenchResults("master01", "master", []byte(kubeBenchMasterOutput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Host != "master01" || r.Target != "master" {
+		t.Errorf("unexpected host and target: %s %s", r.Host, r.Target)
+	}
+	if r.Pass != 1 || r.Fail != 1 || r.Warn != 1 || r.Info != 0 {
+		t.Errorf("unexpected totals: %+v", r)
+	}
+	if len(r.Checks) != 3 {
+		t.Fatalf("expected 3 checks, but got %d", len(r.Checks))
+	}
+	failed := r.Checks[1]
+	if failed.ID != "1.1.2" || failed.Status != "FAIL" || failed.Remediation != "Remove the --basic-auth-file argument" {
+		t.Errorf("unexpected check: %+v", failed)
+	}
+}
+
+func TestParseKubeBenchResultsInvalid(t *testing.T) {
+	if _, err := parseKubeBenchResults("master01", "master", []byte("not json")); err == nil {
+		t.Errorf("expected an error parsing invalid output")
+	}
+}
+
+func TestReadCISBenchmarkResults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cis-benchmark-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, f := range []string{"master01/master.json", "master01/node.json", "worker01/node.json"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(kubeBenchMasterOutput), 0644); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	r, err := readCISBenchmarkResults(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Nodes) != 3 {
+		t.Fatalf("expected 3 node reports, but got %d", len(r.Nodes))
+	}
+	expected := []struct{ host, target string }{{"master01", "master"}, {"master01", "node"}, {"worker01", "node"}}
+	for i, e := range expected {
+		if r.Nodes[i].Host != e.host || r.Nodes[i].Target != e.target {
+			t.Errorf("expected node report %d to be %s/%s, but got %s/%s", i, e.host, e.target, r.Nodes[i].Host, r.Nodes[i].Target)
+		}
+	}
+	if r.Failed() != 3 {
+		t.Errorf("expected 3 failed checks, but got %d", r.Failed())
+	}
+}
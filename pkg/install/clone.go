@@ -0,0 +1,101 @@
+package install
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ClonePlan returns the plan of a new cluster with the given name, that is
+// configured like the cluster of the given plan.
+//
+// The settings that identify the existing cluster are replaced with ${VAR}
+// placeholders that must be defined before the plan is used: the hosts and
+// IPs of the nodes, the master load balancer, the NFS servers, the etcd
+// backup destination and the docker registry password. A node that has
+// more than one role in the existing cluster is given the same placeholders
+// in each of its groups. The admin password is regenerated.
+//
+// The returned warnings describe the settings that are copied as they are,
+// but may refer to resources of the existing cluster.
+func ClonePlan(p Plan, name string) (*Plan, []string, error) {
+	// Copy the plan, so that the clone does not share maps and slices with it
+	d, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error copying plan: %v", err)
+	}
+	clone := &Plan{}
+	if err = yaml.Unmarshal(d, clone); err != nil {
+		return nil, nil, fmt.Errorf("error copying plan: %v", err)
+	}
+	clone.etcdFromMasters = p.etcdFromMasters
+
+	clone.Cluster.Name = name
+	if clone.Cluster.AdminPassword, err = generateAlphaNumericPassword(); err != nil {
+		return nil, nil, fmt.Errorf("error generating random password: %v", err)
+	}
+	if clone.DockerRegistry.Username != "" {
+		clone.DockerRegistry.Password = "${DOCKER_REGISTRY_PASSWORD}"
+	}
+	clone.Master.LoadBalancedFQDN = "${MASTER_LOAD_BALANCED_FQDN}"
+	clone.Master.LoadBalancedShortName = "${MASTER_LOAD_BALANCED_SHORT_NAME}"
+	if clone.Cluster.EtcdBackup.Enabled {
+		clone.Cluster.EtcdBackup.Destination = "${ETCD_BACKUP_DESTINATION}"
+	}
+	for i := range clone.NFS.Volumes {
+		clone.NFS.Volumes[i].Host = fmt.Sprintf("${NFS_VOLUME_%d_HOST}", i+1)
+	}
+
+	placeholders := map[string]Node{}
+	replaceNodes := func(nodes []Node) {
+		for i, n := range nodes {
+			key := n.HashCode()
+			ph, ok := placeholders[key]
+			if !ok {
+				id := len(placeholders) + 1
+				ph = Node{
+					Host: fmt.Sprintf("${NODE_%d_HOST}", id),
+					IP:   fmt.Sprintf("${NODE_%d_IP}", id),
+				}
+				if n.InternalIP != "" {
+					ph.InternalIP = fmt.Sprintf("${NODE_%d_INTERNAL_IP}", id)
+				}
+				placeholders[key] = ph
+			}
+			nodes[i].Host = ph.Host
+			nodes[i].IP = ph.IP
+			nodes[i].InternalIP = ph.InternalIP
+			// The copy turns unset maps into empty ones
+			if len(nodes[i].Labels) == 0 {
+				nodes[i].Labels = nil
+			}
+			if len(nodes[i].KubeletOptions.Overrides) == 0 {
+				nodes[i].KubeletOptions.Overrides = nil
+			}
+		}
+	}
+	replaceNodes(clone.Etcd.Nodes)
+	replaceNodes(clone.Master.Nodes)
+	replaceNodes(clone.Worker.Nodes)
+	for _, pool := range clone.WorkerPools {
+		replaceNodes(pool.Nodes)
+	}
+	replaceNodes(clone.Ingress.Nodes)
+	replaceNodes(clone.Storage.Nodes)
+
+	var warnings []string
+	warnings = append(warnings, fmt.Sprintf("The SSH key %q of the existing cluster is used to access the nodes", clone.Cluster.SSH.Key))
+	if clone.Cluster.CloudProvider.Config != "" {
+		warnings = append(warnings, fmt.Sprintf("The cloud provider configuration %q of the existing cluster is used", clone.Cluster.CloudProvider.Config))
+	}
+	if clone.Cluster.EtcdBackup.CredentialsFile != "" {
+		warnings = append(warnings, fmt.Sprintf("The etcd backup credentials %q of the existing cluster are used", clone.Cluster.EtcdBackup.CredentialsFile))
+	}
+	if clone.Cluster.Audit.WebhookURL != "" {
+		warnings = append(warnings, fmt.Sprintf("The audit events are sent to the webhook %q of the existing cluster", clone.Cluster.Audit.WebhookURL))
+	}
+	if clone.Cluster.AuthWebhook.enabled() {
+		warnings = append(warnings, "The authentication and authorization decisions are delegated to the auth webhook of the existing cluster")
+	}
+	return clone, warnings, nil
+}
@@ -0,0 +1,80 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClonePlan(t *testing.T) {
+	etcdMaster := Node{Host: "prod-01", IP: "10.0.1.1", InternalIP: "192.168.1.1"}
+	worker := Node{Host: "prod-02", IP: "10.0.1.2", Labels: map[string]string{"disk": "ssd"}}
+	gpu := Node{Host: "prod-03", IP: "10.0.1.3"}
+	p := Plan{
+		Cluster: Cluster{
+			Name:          "prod",
+			AdminPassword: "secret",
+			SSH:           SSHConfig{Key: "/keys/prod.pem"},
+			EtcdBackup:    EtcdBackup{Enabled: true, Destination: "s3://prod-backups/etcd"},
+		},
+		DockerRegistry: DockerRegistry{Server: "registry:5000", Username: "admin", Password: "registry-secret"},
+		Etcd:           NodeGroup{ExpectedCount: 1, Nodes: []Node{etcdMaster}},
+		Master:         MasterNodeGroup{ExpectedCount: 1, Nodes: []Node{etcdMaster}, LoadBalancedFQDN: "prod.example.com", LoadBalancedShortName: "prod"},
+		Worker:         NodeGroup{ExpectedCount: 1, Nodes: []Node{worker}},
+		WorkerPools:    []WorkerPool{{Name: "gpu", ExpectedCount: 1, Nodes: []Node{gpu}}},
+		NFS:            NFS{Volumes: []NFSVolume{{Host: "nfs.prod", Path: "/exports"}}},
+	}
+
+	clone, warnings, err := ClonePlan(p, "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clone.Cluster.Name != "staging" {
+		t.Errorf("expected the cluster name to be staging, but got %q", clone.Cluster.Name)
+	}
+	if clone.Cluster.AdminPassword == "" || clone.Cluster.AdminPassword == "secret" {
+		t.Errorf("expected a new admin password, but got %q", clone.Cluster.AdminPassword)
+	}
+	if clone.DockerRegistry.Server != "registry:5000" || clone.DockerRegistry.Password != "${DOCKER_REGISTRY_PASSWORD}" {
+		t.Errorf("unexpected docker registry: %+v", clone.DockerRegistry)
+	}
+	// A node with multiple roles gets the same placeholders in each group
+	expectedEtcdMaster := Node{Host: "${NODE_1_HOST}", IP: "${NODE_1_IP}", InternalIP: "${NODE_1_INTERNAL_IP}"}
+	if !reflect.DeepEqual(clone.Etcd.Nodes[0], expectedEtcdMaster) || !reflect.DeepEqual(clone.Master.Nodes[0], expectedEtcdMaster) {
+		t.Errorf("unexpected etcd and master nodes: %+v, %+v", clone.Etcd.Nodes[0], clone.Master.Nodes[0])
+	}
+	expectedWorker := Node{Host: "${NODE_2_HOST}", IP: "${NODE_2_IP}", Labels: map[string]string{"disk": "ssd"}}
+	if !reflect.DeepEqual(clone.Worker.Nodes[0], expectedWorker) {
+		t.Errorf("unexpected worker node: %+v", clone.Worker.Nodes[0])
+	}
+	if clone.WorkerPools[0].Nodes[0].Host != "${NODE_3_HOST}" {
+		t.Errorf("unexpected worker pool node: %+v", clone.WorkerPools[0].Nodes[0])
+	}
+	if clone.Master.LoadBalancedFQDN != "${MASTER_LOAD_BALANCED_FQDN}" || clone.Cluster.EtcdBackup.Destination != "${ETCD_BACKUP_DESTINATION}" || clone.NFS.Volumes[0].Host != "${NFS_VOLUME_1_HOST}" {
+		t.Errorf("expected the resources of the existing cluster to be replaced with placeholders")
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning about the SSH key, but got %v", warnings)
+	}
+
+	// The existing plan is not modified
+	if p.Cluster.Name != "prod" || p.Etcd.Nodes[0].Host != "prod-01" || p.Worker.Nodes[0].Host != "prod-02" || p.WorkerPools[0].Nodes[0].Host != "prod-03" {
+		t.Errorf("the existing plan was modified")
+	}
+
+	vars, err := PlanVariables(clone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedVars := []string{
+		"ETCD_BACKUP_DESTINATION",
+		"DOCKER_REGISTRY_PASSWORD",
+		"NODE_1_HOST", "NODE_1_IP", "NODE_1_INTERNAL_IP",
+		"MASTER_LOAD_BALANCED_FQDN", "MASTER_LOAD_BALANCED_SHORT_NAME",
+		"NODE_2_HOST", "NODE_2_IP",
+		"NODE_3_HOST", "NODE_3_IP",
+		"NFS_VOLUME_1_HOST",
+	}
+	if !reflect.DeepEqual(vars, expectedVars) {
+		t.Errorf("expected variables %v, but got %v", expectedVars, vars)
+	}
+}
@@ -0,0 +1,226 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/util"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The defaults of a template are stored next to the template, in a file
+// with this suffix
+const templateDefaultsSuffix = ".defaults.yaml"
+
+var templateNameRE = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+var templateExtensions = []string{".yaml", ".yml", ".json", ".hcl"}
+
+// ClusterTemplate is a plan file with ${VAR} placeholders, that is used to
+// generate the plan files of clusters of the same shape
+type ClusterTemplate struct {
+	// Name of the template
+	Name string
+	// File the template is stored in
+	File string
+	// Variables referenced in the template
+	Variables []string
+	// Defaults are the values of the variables that are used when the
+	// variables are not set
+	Defaults map[string]string
+}
+
+// TemplateStore stores the cluster templates as files in a directory
+type TemplateStore struct {
+	Dir string
+}
+
+// List returns the templates in the store, sorted by name
+func (s TemplateStore) List() ([]ClusterTemplate, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading templates directory: %v", err)
+	}
+	var templates []ClusterTemplate
+	for _, f := range files {
+		if f.IsDir() || strings.HasSuffix(f.Name(), templateDefaultsSuffix) {
+			continue
+		}
+		ext := filepath.Ext(f.Name())
+		name := strings.TrimSuffix(f.Name(), ext)
+		if !util.Contains(ext, templateExtensions) || !templateNameRE.MatchString(name) {
+			continue
+		}
+		t, err := s.read(name, filepath.Join(s.Dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Get returns the template with the given name
+func (s TemplateStore) Get(name string) (*ClusterTemplate, error) {
+	file, err := s.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return nil, fmt.Errorf("template %q not found", name)
+	}
+	return s.read(name, file)
+}
+
+// Create stores the plan file as a template with the given name. The
+// defaults are the values of the variables that are used when they are not
+// set when rendering the template.
+func (s TemplateStore) Create(name string, planFile string, defaults map[string]string) (*ClusterTemplate, error) {
+	if !templateNameRE.MatchString(name) {
+		return nil, fmt.Errorf("template name %q is not valid, must consist of lower case alphanumeric characters or '-'", name)
+	}
+	existing, err := s.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != "" {
+		return nil, fmt.Errorf("template %q already exists", name)
+	}
+	d, err := ioutil.ReadFile(planFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file: %v", err)
+	}
+	// Verify the variable references, and that the template is a document
+	// of the plan file format
+	format := DetectPlanFormat(planFile, d)
+	values := map[string]string{}
+	for _, v := range planVariables(d, format) {
+		values[v] = ""
+	}
+	if _, err = renderPlanTemplate(d, format, values); err != nil {
+		return nil, fmt.Errorf("plan file %q is not a valid template: %v", planFile, err)
+	}
+	if format != PlanFormatHCL {
+		var doc map[string]interface{}
+		if err = yaml.Unmarshal(d, &doc); err != nil {
+			return nil, fmt.Errorf("plan file %q is not a valid template: %v", planFile, err)
+		}
+	}
+	for k := range defaults {
+		if _, ok := values[k]; !ok {
+			return nil, fmt.Errorf("default set for variable %q, which is not referenced in the template", k)
+		}
+	}
+
+	if err = os.MkdirAll(s.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating templates directory: %v", err)
+	}
+	ext := filepath.Ext(planFile)
+	if !util.Contains(ext, templateExtensions) {
+		ext = ".yaml"
+	}
+	file := filepath.Join(s.Dir, name+ext)
+	if err = ioutil.WriteFile(file, d, 0600); err != nil {
+		return nil, fmt.Errorf("error writing template: %v", err)
+	}
+	if len(defaults) > 0 {
+		b, err := yaml.Marshal(defaults)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling template defaults: %v", err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(s.Dir, name+templateDefaultsSuffix), b, 0600); err != nil {
+			return nil, fmt.Errorf("error writing template defaults: %v", err)
+		}
+	}
+	return s.read(name, file)
+}
+
+// Delete removes the template with the given name
+func (s TemplateStore) Delete(name string) error {
+	file, err := s.find(name)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		return fmt.Errorf("template %q not found", name)
+	}
+	if err = os.Remove(file); err != nil {
+		return fmt.Errorf("error deleting template: %v", err)
+	}
+	if err = os.Remove(filepath.Join(s.Dir, name+templateDefaultsSuffix)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting template defaults: %v", err)
+	}
+	return nil
+}
+
+// find returns the file of the template, or an empty string if the
+// template does not exist
+func (s TemplateStore) find(name string) (string, error) {
+	if !templateNameRE.MatchString(name) {
+		return "", fmt.Errorf("template name %q is not valid", name)
+	}
+	for _, ext := range templateExtensions {
+		file := filepath.Join(s.Dir, name+ext)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return "", nil
+}
+
+func (s TemplateStore) read(name, file string) (*ClusterTemplate, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template %q: %v", name, err)
+	}
+	t := &ClusterTemplate{
+		Name:      name,
+		File:      file,
+		Variables: planVariables(d, DetectPlanFormat(file, d)),
+		Defaults:  map[string]string{},
+	}
+	defaults, err := ioutil.ReadFile(filepath.Join(s.Dir, name+templateDefaultsSuffix))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading defaults of template %q: %v", name, err)
+	}
+	if err = yaml.Unmarshal(defaults, &t.Defaults); err != nil {
+		return nil, fmt.Errorf("error reading defaults of template %q: %v", name, err)
+	}
+	return t, nil
+}
+
+// Render returns the plan of the template, with the variables set to the
+// given values. Variables that are not set are read from the environment,
+// or take the default value of the template.
+func (t ClusterTemplate) Render(values map[string]string) (*Plan, error) {
+	d, err := ioutil.ReadFile(t.File)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template %q: %v", t.Name, err)
+	}
+	merged := map[string]string{}
+	for k, v := range t.Defaults {
+		if _, ok := os.LookupEnv(k); !ok {
+			merged[k] = v
+		}
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	if d, err = renderPlanTemplate(d, DetectPlanFormat(t.File, d), merged); err != nil {
+		return nil, fmt.Errorf("error rendering template %q: %v", t.Name, err)
+	}
+	p, err := UnmarshalPlan(d, DetectPlanFormat(t.File, d))
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling template %q: %v", t.Name, err)
+	}
+	return p, nil
+}
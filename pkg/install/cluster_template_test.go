@@ -0,0 +1,96 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testClusterTemplate = `cluster:
+  name: ${CLUSTER_NAME}
+  admin_password: ${ADMIN_PASSWORD}
+worker:
+  expected_count: ${WORKER_COUNT}
+`
+
+func TestTemplateStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cluster-templates")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	planFile := filepath.Join(dir, "plan.yaml")
+	if err = ioutil.WriteFile(planFile, []byte(testClusterTemplate), 0600); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	store := TemplateStore{Dir: filepath.Join(dir, "templates")}
+
+	// The store is empty before the directory is created
+	templates, err := store.List()
+	if err != nil || len(templates) != 0 {
+		t.Fatalf("expected no templates, but got %v, %v", templates, err)
+	}
+
+	if _, err = store.Create("Small", planFile, nil); err == nil {
+		t.Errorf("expected an error creating a template with an invalid name")
+	}
+	if _, err = store.Create("small", planFile, map[string]string{"REGION": "us-east-1"}); err == nil {
+		t.Errorf("expected an error setting the default of a variable that is not referenced")
+	}
+	created, err := store.Create("small", planFile, map[string]string{"WORKER_COUNT": "3"})
+	if err != nil {
+		t.Fatalf("unexpected error creating template: %v", err)
+	}
+	expectedVars := []string{"CLUSTER_NAME", "ADMIN_PASSWORD", "WORKER_COUNT"}
+	if !reflect.DeepEqual(created.Variables, expectedVars) {
+		t.Errorf("expected variables %v, but got %v", expectedVars, created.Variables)
+	}
+	if _, err = store.Create("small", planFile, nil); err == nil {
+		t.Errorf("expected an error creating a template that already exists")
+	}
+
+	templates, err = store.List()
+	if err != nil {
+		t.Fatalf("unexpected error listing templates: %v", err)
+	}
+	if len(templates) != 1 || templates[0].Name != "small" || templates[0].Defaults["WORKER_COUNT"] != "3" {
+		t.Errorf("unexpected templates: %+v", templates)
+	}
+
+	tmpl, err := store.Get("small")
+	if err != nil {
+		t.Fatalf("unexpected error getting template: %v", err)
+	}
+	if _, err = tmpl.Render(map[string]string{"CLUSTER_NAME": "dev"}); err == nil {
+		t.Errorf("expected an error rendering a template with undefined variables")
+	}
+	p, err := tmpl.Render(map[string]string{"CLUSTER_NAME": "dev", "ADMIN_PASSWORD": "password"})
+	if err != nil {
+		t.Fatalf("unexpected error rendering template: %v", err)
+	}
+	if p.Cluster.Name != "dev" || p.Cluster.AdminPassword != "password" || p.Worker.ExpectedCount != 3 {
+		t.Errorf("unexpected plan rendered from template: %+v", p)
+	}
+	p, err = tmpl.Render(map[string]string{"CLUSTER_NAME": "dev", "ADMIN_PASSWORD": "password", "WORKER_COUNT": "5"})
+	if err != nil {
+		t.Fatalf("unexpected error rendering template: %v", err)
+	}
+	if p.Worker.ExpectedCount != 5 {
+		t.Errorf("expected the value to override the default, but got %d workers", p.Worker.ExpectedCount)
+	}
+
+	if err = store.Delete("small"); err != nil {
+		t.Fatalf("unexpected error deleting template: %v", err)
+	}
+	if _, err = store.Get("small"); err == nil {
+		t.Errorf("expected an error getting a deleted template")
+	}
+	if _, err = os.Stat(filepath.Join(store.Dir, "small"+templateDefaultsSuffix)); !os.IsNotExist(err) {
+		t.Errorf("expected the defaults of the template to be deleted")
+	}
+	if err = store.Delete("small"); err == nil {
+		t.Errorf("expected an error deleting a template that does not exist")
+	}
+}
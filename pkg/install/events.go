@@ -0,0 +1,165 @@
+package install
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+const (
+	eventPublisherNATS  = "nats"
+	eventPublisherKafka = "kafka"
+	defaultEventsTopic  = "kismatic.events"
+	// EventStarted is the type suffix of the event published when a run
+	// starts. The event published when it finishes has the result of the run
+	// as suffix, such as apply.succeeded.
+	EventStarted = "started"
+)
+
+func eventPublishers() []string {
+	return []string{eventPublisherNATS, eventPublisherKafka}
+}
+
+// Event is a lifecycle event of a cluster, published as JSON
+type Event struct {
+	// Type is the operation and what happened, such as apply.started or
+	// upgrade-nodes.failed
+	Type    string `json:"type"`
+	Cluster string `json:"cluster"`
+	// Run is the ID of the run in the run index
+	Run  string    `json:"run"`
+	Time time.Time `json:"time"`
+	// Error is the error of a run that failed, with its secrets masked
+	Error string `json:"error,omitempty"`
+}
+
+// EventPublisher publishes the lifecycle events of a cluster to a message broker
+type EventPublisher interface {
+	Publish(e Event) error
+}
+
+// NewEventPublisher returns the publisher of the events of the cluster, or nil
+// when the plan has no event publisher
+func NewEventPublisher(e ClusterEvents) (EventPublisher, error) {
+	switch e.Publisher {
+	case "":
+		return nil, nil
+	case eventPublisherNATS:
+		return natsPublisher{servers: e.Servers, subject: e.Topic, timeout: 10 * time.Second}, nil
+	case eventPublisherKafka:
+		run, err := commandLineTool("kcat")
+		if err != nil {
+			return nil, err
+		}
+		return kafkaPublisher{brokers: e.Servers, topic: e.Topic, run: run}, nil
+	default:
+		return nil, fmt.Errorf("event publisher %q is not supported", e.Publisher)
+	}
+}
+
+func runEvent(p Plan, run RunRecord, runErr error) Event {
+	e := Event{
+		Type:    run.Task + "." + EventStarted,
+		Cluster: p.Cluster.Name,
+		Run:     run.ID,
+		Time:    run.Started,
+	}
+	if !run.Finished.IsZero() {
+		e.Type = run.Task + "." + run.Result
+		e.Time = run.Finished
+	}
+	if runErr != nil {
+		e.Error = util.Redact(runErr.Error())
+	}
+	return e
+}
+
+// natsPublisher publishes the events to a NATS subject. It speaks the text
+// protocol of NATS, and does not support authentication or TLS.
+type natsPublisher struct {
+	servers []string
+	subject string
+	timeout time.Duration
+}
+
+// Publish sends the event to the first server that accepts it
+func (p natsPublisher) Publish(e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %v", err)
+	}
+	var errs []string
+	for _, s := range p.servers {
+		err = p.publish(s, payload)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", s, err))
+	}
+	return fmt.Errorf("error publishing event to NATS: %s", strings.Join(errs, "; "))
+}
+
+func (p natsPublisher) publish(server string, payload []byte) error {
+	conn, err := net.DialTimeout("tcp", server, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(p.timeout)); err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	info, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("error reading server info: %v", err)
+	}
+	if !strings.HasPrefix(info, "INFO ") {
+		return fmt.Errorf("unexpected server info %q", strings.TrimSpace(info))
+	}
+	// The PING is answered once the message was processed, so that errors
+	// are reported before the connection is closed
+	msg := fmt.Sprintf("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"kismatic\"}\r\nPUB %s %d\r\n%s\r\nPING\r\n", p.subject, len(payload), payload)
+	if _, err = conn.Write([]byte(msg)); err != nil {
+		return err
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("error reading server response: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.Trim(strings.TrimPrefix(line, "-ERR"), " '"))
+		}
+	}
+}
+
+// kafkaPublisher publishes the events to a Kafka topic with kcat
+type kafkaPublisher struct {
+	brokers []string
+	topic   string
+	run     commandRunner
+}
+
+func (p kafkaPublisher) Publish(e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %v", err)
+	}
+	// kcat produces a message for each line read from stdin. The cluster
+	// name is the key, so that the events of a cluster are kept in order.
+	_, err = p.run(append(payload, '\n'), "-P", "-b", strings.Join(p.brokers, ","), "-t", p.topic, "-k", e.Cluster)
+	if err != nil {
+		return fmt.Errorf("error publishing event to Kafka: %v", err)
+	}
+	return nil
+}
@@ -6,7 +6,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
 	"time"
 
 	"strings"
@@ -14,6 +18,7 @@ import (
 	"github.com/apprenda/kismatic/pkg/ansible"
 	"github.com/apprenda/kismatic/pkg/install/explain"
 	"github.com/apprenda/kismatic/pkg/tls"
+	"github.com/apprenda/kismatic/pkg/trace"
 	"github.com/apprenda/kismatic/pkg/util"
 )
 
@@ -31,18 +36,26 @@ type Executor interface {
 	Install(p *Plan) error
 	GenerateCertificates(p *Plan, useExistingCA bool) error
 	RunSmokeTest(*Plan) error
-	AddWorker(*Plan, Node) (*Plan, error)
+	AddWorker(plan *Plan, newWorker Node, pool string) (*Plan, error)
 	RunPlay(string, *Plan) error
 	AddVolume(*Plan, StorageVolume) error
 	DeleteVolume(*Plan, string) error
 	UpgradeNodes(plan Plan, nodesToUpgrade []ListableNode, onlineUpgrade bool, maxParallelWorkers int) error
 	ValidateControlPlane(plan Plan) error
 	UpgradeClusterServices(plan Plan) error
+	UpgradeAddOns(plan Plan, addOns []string) ([]string, error)
+	RestoreEtcd(plan Plan, snapshot string) error
+	SnapshotForRollback(plan Plan, point RollbackPoint) error
+	Rollback(plan Plan, point RollbackPoint) error
+	Hibernate(plan Plan) error
+	Resume(plan Plan) error
+	BakeImage(plan Plan, builder Node) error
 }
 
 // DiagnosticsExecutor will run diagnostics on the nodes after an install
 type DiagnosticsExecutor interface {
 	DiagnoseNodes(plan Plan) error
+	RunCISBenchmark(plan Plan) (*CISBenchmarkReport, error)
 }
 
 // ExecutorOptions are used to configure the executor
@@ -63,6 +76,9 @@ type ExecutorOptions struct {
 	DiagnosticsDirecty string
 	// DryRun determines if the executor should actually run the task
 	DryRun bool
+	// Tracer records a span for each task, and for each play of the task.
+	// Tracing is disabled when nil.
+	Tracer *trace.Tracer
 }
 
 // NewExecutor returns an executor for performing installations according to the installation plan.
@@ -189,7 +205,7 @@ type task struct {
 }
 
 // execute will run the given task, and setup all what's needed for us to run ansible.
-func (ae *ansibleExecutor) execute(t task) error {
+func (ae *ansibleExecutor) execute(t task) (err error) {
 	if ae.options.DryRun {
 		return nil
 	}
@@ -209,12 +225,39 @@ func (ae *ansibleExecutor) execute(t task) error {
 	if err != nil {
 		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
 	}
+	// Record the run in the run index, so that its log can be found by its
+	// result once it finishes
+	run, err := newRunRecord(ae.options.RunsDirectory, runDirectory, t.name, time.Now())
+	if err != nil {
+		return fmt.Errorf("error recording run: %v", err)
+	}
+	if err = recordRun(ae.options.RunsDirectory, run); err != nil {
+		return err
+	}
+	ae.publishEvent(t.plan, run, nil)
+	defer func() {
+		run.Finished = time.Now()
+		run.Result = runResult(err)
+		if recordErr := recordRun(ae.options.RunsDirectory, run); recordErr != nil && err == nil {
+			err = recordErr
+		}
+		ae.publishEvent(t.plan, run, err)
+	}()
 	runner, explainer, err := ae.ansibleRunnerWithExplainer(t.explainer, ansibleLogFile, runDirectory)
 	if err != nil {
 		return err
 	}
+	phases := newPhaseTracker(ae.options.RunsDirectory)
+	if err := phases.startTask(t.name); err != nil {
+		return err
+	}
+
+	span := ae.options.Tracer.Start(t.name)
+	span.SetAttribute("playbook", t.playbook)
+	span.SetAttribute("limit", strings.Join(t.limit, ","))
 
 	// Start running ansible with the given playbook
+	started := time.Now()
 	var eventStream <-chan ansible.Event
 	if t.limit != nil && len(t.limit) != 0 {
 		eventStream, err = runner.StartPlaybookOnNode(t.playbook, t.inventory, t.clusterCatalog, t.limit...)
@@ -224,15 +267,89 @@ func (ae *ansibleExecutor) execute(t task) error {
 	if err != nil {
 		return fmt.Errorf("error running ansible playbook: %v", err)
 	}
+	progress := &runProgress{Playbook: t.playbook}
+	timer := newPhaseTimer(t.name)
+	_, trackPhases := taskPhases[t.name]
+	var playSpanLock sync.Mutex
+	var playSpan *trace.Span
+	progress.onPlayStart = func(play string) {
+		timer.startPlay(play)
+		playSpanLock.Lock()
+		playSpan.End(nil)
+		playSpan = span.Start(play)
+		playSpanLock.Unlock()
+		if trackPhases {
+			// The install progress is refreshed as each play starts. A
+			// failure to record it does not stop the playbook.
+			phases.startPlay(play)
+		}
+	}
 	// Ansible blocks until explainer starts reading from stream. Start
 	// explainer in a separate go routine
-	go explainer.Explain(eventStream)
+	go explainer.Explain(progress.track(eventStream))
+
+	// Cancel the playbook when interrupted. The playbook stops before its
+	// next play starts. Ansible runs in its own process group, so it is
+	// killed if interrupted a second time.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-interrupt:
+			fmt.Fprintln(ae.stdout, "\nCancelling... the current play will run to completion. Interrupt again to abort immediately")
+			progress.cancel()
+			if err := runner.CancelPlaybook(); err != nil {
+				fmt.Fprintf(ae.stdout, "Error cancelling the playbook: %v\n", err)
+			}
+		case <-done:
+			return
+		}
+		select {
+		case <-interrupt:
+			fmt.Fprintln(ae.stdout, "\nAborting... the nodes may be left half-way through the current play")
+			progress.abort()
+			if err := runner.KillPlaybook(); err != nil {
+				fmt.Fprintf(ae.stdout, "Error aborting the playbook: %v\n", err)
+			}
+		case <-done:
+		}
+	}()
 
 	// Wait until ansible exits
-	if err = runner.WaitPlaybook(); err != nil {
+	err = runner.WaitPlaybook()
+	phaseSeconds := timer.stop()
+	playSpanLock.Lock()
+	playSpan.End(err)
+	playSpanLock.Unlock()
+	span.End(err)
+	if err != nil {
+		if progress.stoppedByCancel() {
+			if err := progress.write(runDirectory); err != nil {
+				return err
+			}
+			return CancelledError{Task: t.name, RunDirectory: runDirectory, CompletedPlays: progress.completedPlays()}
+		}
 		return fmt.Errorf("error running playbook: %v", err)
 	}
-	return nil
+	nodes := len(t.limit)
+	if nodes == 0 {
+		nodes = len(t.plan.GetUniqueNodes())
+	}
+	record := OperationRecord{
+		Task:         t.name,
+		Version:      KismaticVersion.String(),
+		Nodes:        nodes,
+		Started:      started,
+		Seconds:      time.Since(started).Seconds(),
+		PhaseSeconds: phaseSeconds,
+	}
+	if err := recordOperation(ae.options.RunsDirectory, record); err != nil {
+		return err
+	}
+	return phases.completeTask(t.name)
 }
 
 // GenerateCertificatesprivate generates keys and certificates for the cluster, if needed
@@ -380,8 +497,8 @@ func (ae *ansibleExecutor) RunUpgradePreFlightCheck(p *Plan, node ListableNode)
 }
 
 func setPreflightOptions(p Plan, cc ansible.ClusterCatalog) (*ansible.ClusterCatalog, error) {
-	cc.KismaticPreflightCheckerLinux = filepath.Join("inspector", "linux", "amd64", "kismatic-inspector")
-	cc.EnablePackageInstallation = !p.Cluster.DisablePackageInstallation
+	cc.KismaticPreflightCheckerLinux = filepath.Join("inspector", "linux", "{{ node_arch }}", "kismatic-inspector")
+	cc.EnablePackageInstallation = p.packageInstallationEnabled()
 	return &cc, nil
 }
 
@@ -425,7 +542,7 @@ func (ae *ansibleExecutor) AddVolume(plan *Plan, volume StorageVolume) error {
 
 	// Allow nodes and pods to access volumes
 	allowedNodes := plan.Master.Nodes
-	allowedNodes = append(allowedNodes, plan.Worker.Nodes...)
+	allowedNodes = append(allowedNodes, plan.workerNodes()...)
 	allowedNodes = append(allowedNodes, plan.Ingress.Nodes...)
 	allowedNodes = append(allowedNodes, plan.Storage.Nodes...)
 
@@ -608,6 +725,31 @@ func (ae *ansibleExecutor) UpgradeClusterServices(plan Plan) error {
 	return ae.execute(t)
 }
 
+// UpgradeAddOns upgrades the given add-ons to the versions of this version of
+// Kismatic, without upgrading the control plane. All the enabled add-ons are
+// upgraded when none is given. It returns the add-ons that were upgraded.
+func (ae *ansibleExecutor) UpgradeAddOns(plan Plan, addOns []string) ([]string, error) {
+	cc, err := ae.buildClusterCatalog(&plan)
+	if err != nil {
+		return nil, err
+	}
+	if cc.UpgradeAddOns, err = selectAddOnsToUpgrade(*cc, addOns); err != nil {
+		return nil, err
+	}
+	if len(cc.UpgradeAddOns) == 0 {
+		return nil, nil
+	}
+	t := task{
+		name:           "upgrade-add-ons",
+		playbook:       "upgrade-add-ons.yaml",
+		inventory:      buildInventoryFromPlan(&plan),
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	return cc.UpgradeAddOns, ae.execute(t)
+}
+
 func (ae *ansibleExecutor) DiagnoseNodes(plan Plan) error {
 	inventory := buildInventoryFromPlan(&plan)
 	cc, err := ae.buildClusterCatalog(&plan)
@@ -629,6 +771,191 @@ func (ae *ansibleExecutor) DiagnoseNodes(plan Plan) error {
 	return ae.execute(t)
 }
 
+// RestoreEtcd restores the Kubernetes etcd cluster from a snapshot taken by
+// the scheduled etcd backups.
+func (ae *ansibleExecutor) RestoreEtcd(plan Plan, snapshot string) error {
+	if !plan.Cluster.EtcdBackup.Enabled {
+		return errors.New("etcd backups are not enabled in the plan file")
+	}
+	cc, err := ae.buildClusterCatalog(&plan)
+	if err != nil {
+		return err
+	}
+	cc.EtcdBackup.Snapshot = snapshot
+	t := task{
+		name:           "restore-etcd",
+		playbook:       "restore-etcd.yaml",
+		inventory:      buildInventoryFromPlan(&plan),
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	return ae.execute(t)
+}
+
+// SnapshotForRollback takes a snapshot of the Kubernetes etcd cluster, and
+// archives the configuration of the master nodes, so that the control plane
+// can be rolled back to the rollback point.
+func (ae *ansibleExecutor) SnapshotForRollback(plan Plan, point RollbackPoint) error {
+	cc, err := ae.rollbackCatalog(plan, point)
+	if err != nil {
+		return err
+	}
+	t := task{
+		name:           "rollback-snapshot",
+		playbook:       "rollback-snapshot.yaml",
+		inventory:      buildInventoryFromPlan(&plan),
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	return ae.execute(t)
+}
+
+// Rollback restores the control plane to the rollback point: the Kubernetes
+// etcd cluster is restored from the snapshot, and the master nodes are
+// restored to their prior configuration and Kubernetes version.
+func (ae *ansibleExecutor) Rollback(plan Plan, point RollbackPoint) error {
+	cc, err := ae.rollbackCatalog(plan, point)
+	if err != nil {
+		return err
+	}
+	// the snapshots are not kept in the assets storage of the cluster
+	snapshot := filepath.Join(cc.Rollback.LocalDir, point.Name+"-etcd.db")
+	if _, err = os.Stat(snapshot); err != nil {
+		return fmt.Errorf("the etcd snapshot of the rollback point was not found: %v", err)
+	}
+	t := task{
+		name:           "rollback",
+		playbook:       "rollback.yaml",
+		inventory:      buildInventoryFromPlan(&plan),
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	return ae.execute(t)
+}
+
+func (ae *ansibleExecutor) rollbackCatalog(plan Plan, point RollbackPoint) (*ansible.ClusterCatalog, error) {
+	cc, err := ae.buildClusterCatalog(&plan)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(RollbackDirectory(ae.options.GeneratedAssetsDirectory))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine absolute path of the rollback directory: %v", err)
+	}
+	cc.Rollback.Name = point.Name
+	cc.Rollback.LocalDir = dir
+	cc.Rollback.KubernetesVersion = strings.TrimPrefix(point.KubernetesVersion, "v")
+	cc.Rollback.NodeVersions = point.NodeVersions
+	return cc, nil
+}
+
+// Hibernate stops the Kubernetes services and shuts down the cluster nodes.
+func (ae *ansibleExecutor) Hibernate(plan Plan) error {
+	cc, err := ae.buildClusterCatalog(&plan)
+	if err != nil {
+		return err
+	}
+	t := task{
+		name:           "hibernate",
+		playbook:       "hibernate.yaml",
+		inventory:      buildInventoryFromPlan(&plan),
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	return ae.execute(t)
+}
+
+// Resume waits for the cluster nodes to be reachable after they were
+// started, starts the Kubernetes services and verifies that the control plane
+// is running and that all nodes are ready.
+func (ae *ansibleExecutor) Resume(plan Plan) error {
+	cc, err := ae.buildClusterCatalog(&plan)
+	if err != nil {
+		return err
+	}
+	t := task{
+		name:           "resume",
+		playbook:       "resume.yaml",
+		inventory:      buildInventoryFromPlan(&plan),
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	return ae.execute(t)
+}
+
+// BakeImage installs the packages and pulls the container images of the
+// cluster on the builder machine, so that a machine image can be created
+// from it for the cluster nodes.
+func (ae *ansibleExecutor) BakeImage(plan Plan, builder Node) error {
+	cc, err := ae.buildClusterCatalog(&plan)
+	if err != nil {
+		return err
+	}
+	// The packages are installed on the builder, even if the nodes of the
+	// cluster are created from a baked image
+	cc.EnablePackageInstallation = true
+	inventory := ansible.Inventory{
+		Roles: []ansible.Role{
+			{
+				Name:  "builder",
+				Nodes: []ansible.Node{installNodeToAnsibleNode(&builder, &plan.Cluster.SSH)},
+			},
+		},
+	}
+	t := task{
+		name:           "bake-image",
+		playbook:       "bake-image.yaml",
+		inventory:      inventory,
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	return ae.execute(t)
+}
+
+// RunCISBenchmark runs kube-bench on the cluster nodes, and persists the
+// aggregated report in the generated assets directory.
+func (ae *ansibleExecutor) RunCISBenchmark(plan Plan) (*CISBenchmarkReport, error) {
+	inventory := buildInventoryFromPlan(&plan)
+	cc, err := ae.buildClusterCatalog(&plan)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	resultsDir, err := filepath.Abs(filepath.Join(ae.options.GeneratedAssetsDirectory, "cis-benchmark", now.Format("2006-01-02-15-04-05")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine absolute path of the CIS benchmark results directory: %v", err)
+	}
+	cc.CISBenchmarkDirectory = resultsDir
+	cc.CISBenchmarkDateTime = now.Format("2006-01-02-15-04-05")
+	t := task{
+		name:           "cis-benchmark",
+		playbook:       "cis-benchmark.yaml",
+		inventory:      inventory,
+		clusterCatalog: *cc,
+		plan:           plan,
+		explainer:      ae.defaultExplainer(),
+	}
+	if err := ae.execute(t); err != nil {
+		return nil, err
+	}
+	report, err := readCISBenchmarkResults(resultsDir)
+	if err != nil {
+		return nil, err
+	}
+	report.ClusterName = plan.Cluster.Name
+	report.Time = now
+	if err := writeCISBenchmarkReport(report, filepath.Join(resultsDir, cisBenchmarkReportFilename)); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
 // creates the extra vars that are required for the installation playbook.
 func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog, error) {
 	tlsDir, err := filepath.Abs(ae.certsDir)
@@ -649,7 +976,10 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 		PodCIDR:                      p.Cluster.Networking.PodCIDRBlock,
 		DNSServiceIP:                 dnsIP,
 		EnableModifyHosts:            p.Cluster.Networking.UpdateHostsFiles,
-		EnablePackageInstallation:    !p.Cluster.DisablePackageInstallation,
+		EnableManageFirewall:         p.Cluster.ManageFirewall,
+		SELinuxMode:                  p.Cluster.SELinux,
+		HardeningProfile:             p.Cluster.HardeningProfile,
+		EnablePackageInstallation:    p.packageInstallationEnabled(),
 		KuberangPath:                 filepath.Join("kuberang", "linux", "amd64", "kuberang"),
 		DisconnectedInstallation:     p.Cluster.DisconnectedInstallation,
 		HTTPProxy:                    p.Cluster.Networking.HTTPProxy,
@@ -659,7 +989,20 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 		KubeControllerManagerOptions: p.Cluster.KubeControllerManagerOptions.Overrides,
 		KubeSchedulerOptions:         p.Cluster.KubeSchedulerOptions.Overrides,
 		KubeProxyOptions:             p.Cluster.KubeProxyOptions.Overrides,
-		KubeletOptions:               p.Cluster.KubeletOptions.Overrides,
+		KubeletOptions:               kubeletOverrides(p.Cluster.KubeletOptions),
+	}
+	// audit log settings take precedence over the hardening profile defaults
+	applyAuditLog(p, &cc)
+	applyAuthWebhook(p, &cc)
+	applyHardeningProfile(p, &cc)
+	applyClusterExpiration(p, &cc)
+	cc.SysctlSettings = sysctlSettings(p.Cluster.Sysctl)
+	cc.SysctlRoleSettings = p.Cluster.Sysctl.RoleSettings
+	if err := applyPostInstall(p, &cc); err != nil {
+		return nil, err
+	}
+	if err := applyRegistryCredentials(p, &cc); err != nil {
+		return nil, err
 	}
 
 	cc.NoProxy = p.AllAddresses()
@@ -675,6 +1018,14 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 	}
 	cc.LocalKubeconfigDirectory = generatedDir
 
+	// The manifests of the add-ons that are rendered from charts are kept
+	// in the generated assets directory
+	addOnsDir, err := filepath.Abs(filepath.Join(ae.options.GeneratedAssetsDirectory, "add-ons"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine absolute path to %s: %v", filepath.Join(ae.options.GeneratedAssetsDirectory, "add-ons"), err)
+	}
+	cc.AddOnManifestsDirectory = addOnsDir
+
 	// Setup FQDN or default to first master
 	if p.Master.LoadBalancedFQDN != "" {
 		cc.LoadBalancedFQDN = p.Master.LoadBalancedFQDN
@@ -682,6 +1033,13 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 		cc.LoadBalancedFQDN = p.Master.Nodes[0].InternalIP
 	}
 
+	if p.Master.VirtualIP.Address != "" {
+		cc.VirtualIP.Enabled = true
+		cc.VirtualIP.Address = p.Master.VirtualIP.Address
+		cc.VirtualIP.Interface = p.Master.VirtualIP.Interface
+		cc.VirtualIP.RouterID = p.Master.VirtualIP.RouterID
+	}
+
 	if p.PrivateRegistryProvided() {
 		cc.ConfigureDockerWithPrivateRegistry = true
 		cc.DockerRegistryServer = p.DockerRegistry.Server
@@ -696,6 +1054,8 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 		cc.DockerDirectLVMBlockDevicePath = p.Docker.Storage.DirectLVM.BlockDevice
 		cc.DockerDirectLVMDeferredDeletionEnabled = p.Docker.Storage.DirectLVM.EnableDeferredDeletion
 	}
+	cc.DockerRegistryMirrors = p.Docker.RegistryMirrors
+	cc.DockerInsecureRegistries = p.Docker.InsecureRegistries
 	if ae.options.RestartServices {
 		cc.EnableRestart()
 	}
@@ -718,14 +1078,49 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 	cc.CloudProvider = p.Cluster.CloudProvider.Provider
 	cc.CloudConfig = p.Cluster.CloudProvider.Config
 
+	// etcd backups
+	if p.Cluster.EtcdBackup.Enabled {
+		provider, remote, err := p.Cluster.EtcdBackup.remote()
+		if err != nil {
+			return nil, fmt.Errorf("invalid etcd backup destination: %v", err)
+		}
+		cc.EtcdBackup.Enabled = true
+		cc.EtcdBackup.Schedule = p.Cluster.EtcdBackup.Schedule
+		cc.EtcdBackup.Provider = provider
+		cc.EtcdBackup.Remote = remote
+		cc.EtcdBackup.Region = p.Cluster.EtcdBackup.Region
+		cc.EtcdBackup.CredentialsFile = p.Cluster.EtcdBackup.CredentialsFile
+		cc.EtcdBackup.EncryptionKeyFile = p.Cluster.EtcdBackup.EncryptionKeyFile
+		cc.EtcdBackup.Retention = p.Cluster.EtcdBackup.Retention
+	}
+	cc.DefaultPolicies.Enabled = p.Cluster.DefaultPolicies.Enabled
+	cc.DefaultPolicies.DenyIngressNamespaces = p.Cluster.DefaultPolicies.DenyIngressNamespaces
+	cc.HostDNS.Nameservers = p.Cluster.Networking.HostDNS.Nameservers
+	cc.HostDNS.SearchDomains = p.Cluster.Networking.HostDNS.SearchDomains
+	cc.HostDNS.Options = p.Cluster.Networking.HostDNS.Options
+	cc.TimeSync.Enabled = p.Cluster.TimeSync.Enabled
+	cc.TimeSync.Servers = p.Cluster.TimeSync.Servers
+	cc.TimeSync.MaxSkewSeconds = p.Cluster.TimeSync.MaxSkewSeconds
+	cc.KubeletCertificateRotation.Client = p.Cluster.KubeletCertificateRotation.Client
+	cc.KubeletCertificateRotation.Server = p.Cluster.KubeletCertificateRotation.Server
+	if p.Cluster.EtcdDataVolume.Enabled {
+		cc.EtcdDataVolume.Enabled = true
+		cc.EtcdDataVolume.BlockDevice = p.Cluster.EtcdDataVolume.BlockDevice
+		cc.EtcdDataVolume.Filesystem = p.Cluster.EtcdDataVolume.Filesystem
+		cc.EtcdDataVolume.MaxSyncLatencyMS = p.Cluster.EtcdDataVolume.MaxSyncLatencyMS
+	}
+
 	// add_ons
 	cc.RunPodValidation = p.NetworkConfigured()
 	// CNI
 	if p.AddOns.CNI != nil && !p.AddOns.CNI.Disable {
 		cc.CNI.Enabled = true
 		cc.CNI.Provider = p.AddOns.CNI.Provider
+		cc.CNI.MTU = p.AddOns.CNI.MTU
+		cc.CNI.Encryption = p.AddOns.CNI.Encryption
 		cc.CNI.Options.Calico.Mode = p.AddOns.CNI.Options.Calico.Mode
 		cc.CNI.Options.Calico.LogLevel = p.AddOns.CNI.Options.Calico.LogLevel
+		applyCalicoBGP(p, &cc)
 
 		if cc.CNI.Provider == cniProviderContiv {
 			cc.InsecureNetworkingEtcd = true
@@ -734,6 +1129,16 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 
 	// DNS
 	cc.DNS.Enabled = !p.AddOns.DNS.Disable
+	cc.DNS.Provider = p.AddOns.DNS.Provider
+	cc.DNS.Options.UpstreamNameservers = p.AddOns.DNS.Options.UpstreamNameservers
+	cc.DNS.Options.StubDomains = p.AddOns.DNS.Options.StubDomains
+	cc.DNS.Options.Autoscaler.Enabled = p.AddOns.DNS.Options.Autoscaler.Enabled
+	cc.DNS.Options.Autoscaler.CoresPerReplica = p.AddOns.DNS.Options.Autoscaler.CoresPerReplica
+	cc.DNS.Options.Autoscaler.NodesPerReplica = p.AddOns.DNS.Options.Autoscaler.NodesPerReplica
+	cc.DNS.Options.Autoscaler.MinReplicas = p.AddOns.DNS.Options.Autoscaler.MinReplicas
+	cc.DNS.Options.Autoscaler.MaxReplicas = p.AddOns.DNS.Options.Autoscaler.MaxReplicas
+	cc.DNS.Options.NodeLocalCache.Enabled = p.AddOns.DNS.Options.NodeLocalCache.Enabled
+	cc.DNS.Options.NodeLocalCache.IP = p.AddOns.DNS.Options.NodeLocalCache.IP
 
 	// heapster
 	if p.AddOns.HeapsterMonitoring != nil && !p.AddOns.HeapsterMonitoring.Disable {
@@ -749,6 +1154,17 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 	if p.AddOns.Dashboard != nil && p.AddOns.Dashboard.Disable {
 		cc.Dashboard.Enabled = false
 	}
+	if cc.Dashboard.Enabled && p.AddOns.Dashboard != nil && p.AddOns.Dashboard.Ingress.Enabled {
+		i := p.AddOns.Dashboard.Ingress
+		cc.Dashboard.Ingress.Enabled = true
+		cc.Dashboard.Ingress.Host = i.Host
+		cc.Dashboard.Ingress.Auth = i.Auth
+		cc.Dashboard.Ingress.OIDC.IssuerURL = i.OIDC.IssuerURL
+		cc.Dashboard.Ingress.OIDC.ClientID = i.OIDC.ClientID
+		cc.Dashboard.Ingress.OIDC.ClientSecret = i.OIDC.ClientSecret
+		cc.Dashboard.Ingress.OIDC.CookieSecret = i.OIDC.CookieSecret
+		cc.Dashboard.Ingress.OIDC.EmailDomain = i.OIDC.EmailDomain
+	}
 
 	// package_manager
 	if !p.AddOns.PackageManager.Disable {
@@ -763,22 +1179,67 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 
 	cc.Rescheduler.Enabled = !p.AddOns.Rescheduler.Disable
 
-	// merge node labels
+	// cluster autoscaler
+	if p.AddOns.ClusterAutoscaler.Enabled {
+		cc.ClusterAutoscaler.Enabled = true
+		for _, g := range p.AddOns.ClusterAutoscaler.NodeGroups {
+			cc.ClusterAutoscaler.NodeGroups = append(cc.ClusterAutoscaler.NodeGroups, ansible.AutoscalerNodeGroup{Name: g.Name, MinSize: g.MinSize, MaxSize: g.MaxSize})
+		}
+	}
+
+	// external DNS
+	if p.AddOns.ExternalDNS.Enabled {
+		cc.ExternalDNS.Enabled = true
+		cc.ExternalDNS.Provider = p.AddOns.ExternalDNS.Provider
+		cc.ExternalDNS.DomainFilters = p.AddOns.ExternalDNS.DomainFilters
+		cc.ExternalDNS.GoogleProject = p.AddOns.ExternalDNS.GoogleProject
+		cc.ExternalDNS.IngressWildcard = p.AddOns.ExternalDNS.IngressWildcard
+	}
+
+	// reboot coordinator
+	if p.AddOns.RebootCoordinator.Enabled {
+		cc.RebootCoordinator.Enabled = true
+		cc.RebootCoordinator.SentinelFile = p.AddOns.RebootCoordinator.SentinelFile
+		cc.RebootCoordinator.Period = p.AddOns.RebootCoordinator.Period
+		cc.RebootCoordinator.BlockingPodSelectors = p.AddOns.RebootCoordinator.BlockingPodSelectors
+	}
+
+	cc.MetricsServer.Enabled = p.AddOns.MetricsServer.Enabled
+
+	// merge node labels and taints
 	// cannot use inventory file because nodes share roles
 	// set it to a map[host][]key=value
+	metadata := newNodeMetadata()
+	metadata.add(p.Etcd.Nodes, nil, nil)
+	metadata.add(p.Master.Nodes, p.Master.Labels, p.Master.Taints)
+	metadata.add(p.Worker.Nodes, p.Worker.Labels, p.Worker.Taints)
+	for _, pool := range p.WorkerPools {
+		poolLabels := map[string]string{kismaticWorkerPoolLabel: pool.Name}
+		if pool.InstanceType != "" {
+			poolLabels[kismaticInstanceTypeLabel] = pool.InstanceType
+		}
+		for k, v := range pool.Labels {
+			poolLabels[k] = v
+		}
+		metadata.add(pool.Nodes, poolLabels, pool.Taints)
+	}
+	metadata.add(p.Ingress.Nodes, p.Ingress.Labels, p.Ingress.Taints)
+	metadata.add(p.Storage.Nodes, p.Storage.Labels, p.Storage.Taints)
 	cc.NodeLabels = make(map[string][]string)
-	for _, n := range p.getAllNodes() {
-		if val, ok := cc.NodeLabels[n.Host]; ok {
-			cc.NodeLabels[n.Host] = append(val, keyValueList(n.Labels)...)
-		} else {
-			cc.NodeLabels[n.Host] = keyValueList(n.Labels)
+	for host, l := range metadata.labels() {
+		cc.NodeLabels[host] = keyValueList(l)
+	}
+	cc.NodeTaints = make(map[string][]string)
+	for host, t := range metadata.taints() {
+		for _, taint := range t {
+			cc.NodeTaints[host] = append(cc.NodeTaints[host], taint.String())
 		}
 	}
 
 	// setup kubelet node overrides
 	cc.KubeletNodeOptions = make(map[string]map[string]string)
 	for _, n := range p.GetUniqueNodes() {
-		cc.KubeletNodeOptions[n.Host] = n.KubeletOptions.Overrides
+		cc.KubeletNodeOptions[n.Host] = kubeletOverrides(n.KubeletOptions)
 	}
 
 	return &cc, nil
@@ -786,11 +1247,36 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 
 func (ae *ansibleExecutor) createRunDirectory(runName string) (string, error) {
 	start := time.Now()
-	runDirectory := filepath.Join(ae.options.RunsDirectory, runName, start.Format("2006-01-02-15-04-05"))
-	if err := os.MkdirAll(runDirectory, 0777); err != nil {
+	parent := filepath.Join(ae.options.RunsDirectory, runName)
+	if err := os.MkdirAll(parent, 0777); err != nil {
 		return "", fmt.Errorf("error creating directory: %v", err)
 	}
-	return runDirectory, nil
+	// Runs of the same task that start in the same second, such as the runs
+	// of a batch, get a numbered directory so that they don't share a log
+	name := start.Format("2006-01-02-15-04-05")
+	for i := 2; ; i++ {
+		runDirectory := filepath.Join(parent, name)
+		err := os.Mkdir(runDirectory, 0777)
+		if err == nil {
+			return runDirectory, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("error creating directory: %v", err)
+		}
+		name = fmt.Sprintf("%s-%d", start.Format("2006-01-02-15-04-05"), i)
+	}
+}
+
+// publishEvent publishes the lifecycle event of the run, if the plan has an
+// event publisher. Events that fail to publish do not fail the run.
+func (ae *ansibleExecutor) publishEvent(p Plan, run RunRecord, runErr error) {
+	publisher, err := NewEventPublisher(p.Cluster.Events)
+	if err == nil && publisher != nil {
+		err = publisher.Publish(runEvent(p, run, runErr))
+	}
+	if err != nil {
+		util.PrettyPrintWarn(ae.stdout, "Could not publish the event of run %q: %v", run.ID, err)
+	}
 }
 
 func (ae *ansibleExecutor) ansibleRunnerWithExplainer(explainer explain.AnsibleEventExplainer, ansibleLog io.Writer, runDirectory string) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
@@ -852,7 +1338,7 @@ func buildInventoryFromPlan(p *Plan) ansible.Inventory {
 		masterNodes = append(masterNodes, installNodeToAnsibleNode(&n, &p.Cluster.SSH))
 	}
 	workerNodes := []ansible.Node{}
-	for _, n := range p.Worker.Nodes {
+	for _, n := range p.workerNodes() {
 		workerNodes = append(workerNodes, installNodeToAnsibleNode(&n, &p.Cluster.SSH))
 	}
 	ingressNodes := []ansible.Node{}
@@ -898,28 +1384,35 @@ func buildInventoryFromPlan(p *Plan) ansible.Inventory {
 
 // Converts plan node to ansible node
 func installNodeToAnsibleNode(n *Node, s *SSHConfig) ansible.Node {
-	return ansible.Node{
+	node := ansible.Node{
 		Host:          n.Host,
 		PublicIP:      n.IP,
 		InternalIP:    n.InternalIP,
 		SSHPrivateKey: s.Key,
 		SSHUser:       s.User,
 		SSHPort:       s.Port,
+		Arch:          n.Arch,
 	}
+	if b := s.sshBastion(); b != nil {
+		node.SSHProxyCommand = b.ProxyCommand()
+	}
+	return node
 }
 
-// Prepend each line of the incoming stream with a timestamp
+// Prepend each line of the incoming stream with a timestamp, and mask the
+// secrets in it
 func timestampWriter(out io.Writer) io.Writer {
 	pr, pw := io.Pipe()
 	go func(r io.Reader) {
 		lr := util.NewLineReader(r, 64*1024)
+		redactor := &util.Redactor{}
 		var (
 			err  error
 			line []byte
 		)
 		for err == nil {
 			line, err = lr.Read()
-			fmt.Fprintf(out, "%s - %s\n", time.Now().UTC().Format("2006-01-02 15:04:05.000-0700"), string(line))
+			fmt.Fprintf(out, "%s - %s\n", time.Now().UTC().Format("2006-01-02 15:04:05.000-0700"), redactor.Line(string(line)))
 		}
 		if err != io.EOF {
 			fmt.Printf("Error timestamping ansible logs: %v", err)
@@ -928,11 +1421,87 @@ func timestampWriter(out io.Writer) io.Writer {
 	return pw
 }
 
-// key=value slice
+// nodeMetadata collects the labels and taints of the nodes from the groups
+// they are in. The labels and taints of a node take precedence over the ones
+// of all its groups, whatever the order of the groups.
+type nodeMetadata struct {
+	groupLabels map[string]map[string]string
+	nodeLabels  map[string]map[string]string
+	groupTaints map[string][]Taint
+	nodeTaints  map[string][]Taint
+}
+
+func newNodeMetadata() *nodeMetadata {
+	return &nodeMetadata{
+		groupLabels: make(map[string]map[string]string),
+		nodeLabels:  make(map[string]map[string]string),
+		groupTaints: make(map[string][]Taint),
+		nodeTaints:  make(map[string][]Taint),
+	}
+}
+
+// add records the labels and taints of the nodes in a group
+func (m *nodeMetadata) add(nodes []Node, groupLabels map[string]string, groupTaints []Taint) {
+	for _, n := range nodes {
+		m.groupLabels[n.Host] = mergeLabels(m.groupLabels[n.Host], groupLabels)
+		m.nodeLabels[n.Host] = mergeLabels(m.nodeLabels[n.Host], n.Labels)
+		for _, t := range groupTaints {
+			m.groupTaints[n.Host] = mergeTaint(m.groupTaints[n.Host], t)
+		}
+		for _, t := range n.Taints {
+			m.nodeTaints[n.Host] = mergeTaint(m.nodeTaints[n.Host], t)
+		}
+	}
+}
+
+// labels returns the labels of each node
+func (m *nodeMetadata) labels() map[string]map[string]string {
+	labels := make(map[string]map[string]string)
+	for host, l := range m.groupLabels {
+		labels[host] = mergeLabels(mergeLabels(nil, l), m.nodeLabels[host])
+	}
+	return labels
+}
+
+// taints returns the taints of each node. A taint replaces any taint that has
+// the same key and effect.
+func (m *nodeMetadata) taints() map[string][]Taint {
+	taints := make(map[string][]Taint)
+	for host, groupTaints := range m.groupTaints {
+		for _, t := range append(append([]Taint{}, groupTaints...), m.nodeTaints[host]...) {
+			taints[host] = mergeTaint(taints[host], t)
+		}
+	}
+	return taints
+}
+
+func mergeLabels(labels, in map[string]string) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range in {
+		labels[k] = v
+	}
+	return labels
+}
+
+func mergeTaint(taints []Taint, t Taint) []Taint {
+	for i, existing := range taints {
+		if existing.Key == t.Key && existing.Effect == t.Effect {
+			taints[i] = t
+			return taints
+		}
+	}
+	return append(taints, t)
+}
+
+// key=value slice, sorted so that the kubelet options don't change between
+// runs
 func keyValueList(in map[string]string) []string {
 	pairs := make([]string, 0, len(in))
 	for k, v := range in {
 		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
 	}
+	sort.Strings(pairs)
 	return pairs
 }
@@ -0,0 +1,39 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNodeMetadata(t *testing.T) {
+	m := newNodeMetadata()
+	master := Node{
+		Host:   "node1",
+		Labels: map[string]string{"zone": "a"},
+		Taints: []Taint{{Key: "maintenance", Effect: "NoExecute"}},
+	}
+	worker := Node{
+		Host:   "node1",
+		Labels: map[string]string{"tier": "frontend"},
+		Taints: []Taint{{Key: "dedicated", Value: "web", Effect: "NoSchedule"}},
+	}
+	m.add([]Node{master}, map[string]string{"tier": "control", "group": "master"}, []Taint{{Key: "dedicated", Value: "master", Effect: "NoSchedule"}})
+	// the labels and taints of the node in the master group take
+	// precedence over the ones of the worker group
+	m.add([]Node{worker, {Host: "node2"}}, map[string]string{"group": "worker", "zone": "b"}, []Taint{{Key: "maintenance", Value: "worker", Effect: "NoExecute"}})
+
+	expectedLabels := map[string]map[string]string{
+		"node1": {"zone": "a", "tier": "frontend", "group": "worker"},
+		"node2": {"group": "worker", "zone": "b"},
+	}
+	if labels := m.labels(); !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("expected labels %v, but got %v", expectedLabels, labels)
+	}
+	expectedTaints := map[string][]Taint{
+		"node1": {{Key: "dedicated", Value: "web", Effect: "NoSchedule"}, {Key: "maintenance", Effect: "NoExecute"}},
+		"node2": {{Key: "maintenance", Value: "worker", Effect: "NoExecute"}},
+	}
+	if taints := m.taints(); !reflect.DeepEqual(taints, expectedTaints) {
+		t.Errorf("expected taints %v, but got %v", expectedTaints, taints)
+	}
+}
@@ -0,0 +1,33 @@
+package install
+
+import (
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func (e ClusterExpiration) enabled() bool {
+	return e.TTL != "" || e.ExpiresAt != ""
+}
+
+// applyClusterExpiration sets the expiration of the cluster on the cluster
+// catalog. The expiration time is computed on the nodes when the TTL is used,
+// so that it is relative to when the cluster was installed.
+func applyClusterExpiration(p *Plan, cc *ansible.ClusterCatalog) {
+	e := p.Cluster.Expiration
+	if !e.enabled() {
+		return
+	}
+	cc.ClusterExpiration.Enabled = true
+	cc.ClusterExpiration.WebhookURL = e.WebhookURL
+	if e.ExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, e.ExpiresAt); err == nil {
+			cc.ClusterExpiration.ExpiresAt = t.Unix()
+		}
+	} else if d, err := time.ParseDuration(e.TTL); err == nil {
+		cc.ClusterExpiration.TTLSeconds = int64(d.Seconds())
+	}
+	if d, err := time.ParseDuration(e.WarnBefore); err == nil {
+		cc.ClusterExpiration.WarnBeforeSeconds = int64(d.Seconds())
+	}
+}
@@ -0,0 +1,43 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestApplyClusterExpiration(t *testing.T) {
+	tests := []struct {
+		e                 ClusterExpiration
+		enabled           bool
+		ttlSeconds        int64
+		expiresAt         int64
+		warnBeforeSeconds int64
+	}{
+		{
+			e: ClusterExpiration{WarnBefore: "24h"},
+		},
+		{
+			e:                 ClusterExpiration{TTL: "72h", WarnBefore: "24h"},
+			enabled:           true,
+			ttlSeconds:        259200,
+			warnBeforeSeconds: 86400,
+		},
+		{
+			e:                 ClusterExpiration{TTL: "72h", ExpiresAt: "2017-10-31T18:00:00Z", WarnBefore: "1h"},
+			enabled:           true,
+			expiresAt:         1509472800,
+			warnBeforeSeconds: 3600,
+		},
+	}
+	for i, test := range tests {
+		p := &Plan{}
+		p.Cluster.Expiration = test.e
+		cc := &ansible.ClusterCatalog{}
+		applyClusterExpiration(p, cc)
+		e := cc.ClusterExpiration
+		if e.Enabled != test.enabled || e.TTLSeconds != test.ttlSeconds || e.ExpiresAt != test.expiresAt || e.WarnBeforeSeconds != test.warnBeforeSeconds {
+			t.Errorf("test %d: unexpected cluster expiration catalog: %+v", i, e)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Fleet declares the clusters of a fleet, and the templates their plan files
+// are generated from
+type Fleet struct {
+	Clusters []FleetCluster `yaml:"clusters"`
+}
+
+// FleetCluster is a cluster of a fleet
+type FleetCluster struct {
+	// Name of the cluster, which is also the name of its plan file
+	Name string `yaml:"name"`
+	// Template the plan file of the cluster is generated from
+	Template string `yaml:"template"`
+	// Values of the variables of the template
+	Values map[string]string `yaml:"values,omitempty"`
+	// Overrides are fields of the plan file that replace the fields of the
+	// template, such as cluster.labels. Lists replace the lists of the
+	// template, and objects are merged with the objects of the template.
+	Overrides map[string]interface{} `yaml:"overrides,omitempty"`
+}
+
+// ReadFleet reads and validates the fleet file
+func ReadFleet(file string) (*Fleet, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fleet file: %v", err)
+	}
+	f := &Fleet{}
+	if err = yaml.Unmarshal(d, f); err != nil {
+		return nil, fmt.Errorf("error unmarshaling fleet file: %v", err)
+	}
+	if ok, errs := f.validate(); !ok {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return nil, fmt.Errorf("fleet file %q is not valid: %s", file, strings.Join(msgs, "; "))
+	}
+	return f, nil
+}
+
+func (f *Fleet) validate() (bool, []error) {
+	v := newValidator()
+	if len(f.Clusters) == 0 {
+		v.addError(errors.New("At least one cluster is required"))
+	}
+	names := map[string]bool{}
+	for i, c := range f.Clusters {
+		if !templateNameRE.MatchString(c.Name) {
+			v.addError(fmt.Errorf("Cluster %d: name %q is not valid, must consist of lower case alphanumeric characters or '-'", i, c.Name))
+		}
+		if names[c.Name] {
+			v.addError(fmt.Errorf("Cluster %q is declared more than once", c.Name))
+		}
+		names[c.Name] = true
+		if c.Template == "" {
+			v.addError(fmt.Errorf("Cluster %q: template is required", c.Name))
+		}
+	}
+	return v.valid()
+}
+
+// Render returns the plan of the cluster, generated from its template in the
+// store. The name of the cluster in the plan is the name of the fleet cluster.
+func (c FleetCluster) Render(store TemplateStore) (*Plan, error) {
+	t, err := store.Get(c.Template)
+	if err != nil {
+		return nil, err
+	}
+	p, err := t.Render(c.Values)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Overrides) > 0 {
+		// The overrides are decoded on top of the plan, so that the fields
+		// they don't set keep the values of the template
+		d, err := yaml.Marshal(c.Overrides)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling overrides of cluster %q: %v", c.Name, err)
+		}
+		if err = yaml.Unmarshal(d, p); err != nil {
+			return nil, fmt.Errorf("overrides of cluster %q are not valid: %v", c.Name, err)
+		}
+	}
+	p.Cluster.Name = c.Name
+	return p, nil
+}
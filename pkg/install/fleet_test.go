@@ -0,0 +1,98 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadFleet(t *testing.T) {
+	tests := []struct {
+		fleet string
+		valid bool
+	}{
+		{
+			fleet: "clusters:\n- name: dev\n  template: small\n- name: prod\n  template: large\n",
+			valid: true,
+		},
+		{
+			fleet: "clusters: []\n",
+			valid: false,
+		},
+		{
+			fleet: "clusters:\n- name: Dev\n  template: small\n",
+			valid: false,
+		},
+		{
+			fleet: "clusters:\n- name: dev\n  template: small\n- name: dev\n  template: large\n",
+			valid: false,
+		},
+		{
+			fleet: "clusters:\n- name: dev\n",
+			valid: false,
+		},
+	}
+	dir, err := ioutil.TempDir("", "fleet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, test := range tests {
+		file := filepath.Join(dir, "fleet.yaml")
+		if err = ioutil.WriteFile(file, []byte(test.fleet), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ReadFleet(file)
+		if (err == nil) != test.valid {
+			t.Errorf("test %d: expected valid to be %v, but got error %v", i, test.valid, err)
+		}
+	}
+}
+
+func TestFleetClusterRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	planFile := filepath.Join(dir, "plan.yaml")
+	if err = ioutil.WriteFile(planFile, []byte(testClusterTemplate), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := TemplateStore{Dir: filepath.Join(dir, "templates")}
+	if _, err = store.Create("small", planFile, map[string]string{"WORKER_COUNT": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	c := FleetCluster{
+		Name:     "dev-east",
+		Template: "small",
+		Values:   map[string]string{"CLUSTER_NAME": "ignored", "ADMIN_PASSWORD": "secret"},
+		Overrides: map[string]interface{}{
+			"cluster": map[string]interface{}{
+				"labels": map[string]string{"env": "dev"},
+			},
+		},
+	}
+	p, err := c.Render(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the fields that are not overridden keep the values of the template
+	if p.Cluster.Name != "dev-east" || p.Cluster.AdminPassword != "secret" || p.Worker.ExpectedCount != 3 {
+		t.Errorf("unexpected plan: name %q, admin password %q, worker count %d", p.Cluster.Name, p.Cluster.AdminPassword, p.Worker.ExpectedCount)
+	}
+	if !reflect.DeepEqual(p.Cluster.Labels, map[string]string{"env": "dev"}) {
+		t.Errorf("expected the labels to be overridden, but got %v", p.Cluster.Labels)
+	}
+
+	c.Overrides = map[string]interface{}{"worker": "three"}
+	if _, err = c.Render(store); err == nil {
+		t.Errorf("expected an error with overrides that are not valid")
+	}
+	c.Template = "large"
+	if _, err = c.Render(store); err == nil {
+		t.Errorf("expected an error with a template that does not exist")
+	}
+}
@@ -0,0 +1,361 @@
+package install
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+const (
+	hardeningProfileCIS = "cis"
+
+	complianceReportFilename = "cis-compliance-report.txt"
+)
+
+func hardeningProfiles() []string {
+	return []string{hardeningProfileCIS}
+}
+
+const (
+	componentAPIServer         = "kube-apiserver"
+	componentControllerManager = "kube-controller-manager"
+	componentScheduler         = "kube-scheduler"
+	componentKubelet           = "kubelet"
+)
+
+// hardeningSetting is a component flag set by a hardening profile, along with
+// the CIS Kubernetes Benchmark recommendation it addresses.
+type hardeningSetting struct {
+	id          string
+	description string
+	component   string
+	option      string
+	value       string
+}
+
+// hardeningFile is a file permission enforced on the nodes by the hardening role.
+type hardeningFile struct {
+	id          string
+	description string
+	path        string
+	mode        string
+	// masterOnly is true for the files that only exist on the master nodes
+	masterOnly bool
+}
+
+var cisSettings = []hardeningSetting{
+	{"1.1.1", "Anonymous requests to the API server are disabled", componentAPIServer, "anonymous-auth", "false"},
+	{"1.1.6", "API server profiling is disabled", componentAPIServer, "profiling", "false"},
+	{"1.1.15", "API server audit log path is set", componentAPIServer, "audit-log-path", "{{ kubernetes_audit_log_dir }}/audit.log"},
+	{"1.1.16", "API server audit log retention is 30 days", componentAPIServer, "audit-log-maxage", "30"},
+	{"1.1.17", "API server keeps 10 audit log backups", componentAPIServer, "audit-log-maxbackup", "10"},
+	{"1.1.18", "API server audit logs rotate at 100MB", componentAPIServer, "audit-log-maxsize", "100"},
+	{"1.1.23", "Service account tokens are validated against etcd", componentAPIServer, "service-account-lookup", "true"},
+	{"1.1.30", "API server authenticates to the kubelets with a client certificate", componentAPIServer, "kubelet-client-certificate", "{{ kubernetes_certificates.api_server }}"},
+	{"1.1.30", "API server authenticates to the kubelets with a client certificate", componentAPIServer, "kubelet-client-key", "{{ kubernetes_certificates.api_server_key }}"},
+	{"1.2.1", "Scheduler profiling is disabled", componentScheduler, "profiling", "false"},
+	{"1.3.1", "Terminated pods are garbage collected", componentControllerManager, "terminated-pod-gc-threshold", "12500"},
+	{"1.3.2", "Controller manager profiling is disabled", componentControllerManager, "profiling", "false"},
+	{"1.3.3", "Controllers use individual service account credentials", componentControllerManager, "use-service-account-credentials", "true"},
+	{"2.1.1", "Anonymous requests to the kubelet are disabled", componentKubelet, "anonymous-auth", "false"},
+	{"2.1.3", "Kubelet clients are authenticated with the cluster CA", componentKubelet, "client-ca-file", "{{ kubernetes_certificates.ca }}"},
+	{"2.1.8", "Kubelet manages iptables utility chains", componentKubelet, "make-iptables-util-chains", "true"},
+}
+
+// The paths are the values of the ansible variables used by the hardening
+// role, as set in ansible/group_vars/all.yaml.
+var cisFiles = []hardeningFile{
+	{"1.4.1", "API server pod specification file permissions", "/etc/kubernetes/manifests/kube-apiserver.yaml", "0644", true},
+	{"1.4.3", "Controller manager pod specification file permissions", "/etc/kubernetes/manifests/kube-controller-manager.yaml", "0644", true},
+	{"1.4.5", "Scheduler pod specification file permissions", "/etc/kubernetes/manifests/kube-scheduler.yaml", "0644", true},
+	{"2.2.1", "Kubelet kubeconfig file permissions", "/etc/kubernetes/kubelet.conf", "0644", false},
+	{"2.2.3", "Kubelet service file permissions", "/etc/systemd/system/kubelet.service", "0644", false},
+	{"2.2.5", "Proxy kubeconfig file permissions", "/etc/kubernetes/kube-proxy.conf", "0644", false},
+	{"-", "Private keys are readable only by root", "/etc/kubernetes/pki/*-key.pem", "0600", false},
+}
+
+// hardeningVars renders the ansible variables used in the values of the
+// hardening settings, so that they can be compared with the flags of the
+// processes running on the nodes.
+var hardeningVars = strings.NewReplacer(
+	"{{ kubernetes_audit_log_dir }}", "/var/log/kubernetes",
+	"{{ kubernetes_certificates.api_server }}", "/etc/kubernetes/pki/api-server.pem",
+	"{{ kubernetes_certificates.api_server_key }}", "/etc/kubernetes/pki/api-server-key.pem",
+	"{{ kubernetes_certificates.ca }}", "/etc/kubernetes/pki/ca.pem",
+)
+
+// applyHardeningProfile sets the component options required by the plan's
+// hardening profile on the cluster catalog. Options that were explicitly
+// overridden in the plan file are left untouched.
+func applyHardeningProfile(p *Plan, cc *ansible.ClusterCatalog) {
+	if p.Cluster.HardeningProfile != hardeningProfileCIS {
+		return
+	}
+	cc.APIServerOptions = copyOptions(cc.APIServerOptions)
+	cc.KubeControllerManagerOptions = copyOptions(cc.KubeControllerManagerOptions)
+	cc.KubeSchedulerOptions = copyOptions(cc.KubeSchedulerOptions)
+	cc.KubeletOptions = copyOptions(cc.KubeletOptions)
+	for _, s := range cisSettings {
+		opts := hardeningComponentOptions(s.component, cc)
+		if _, ok := opts[s.option]; !ok {
+			opts[s.option] = s.value
+		}
+	}
+}
+
+func hardeningComponentOptions(component string, cc *ansible.ClusterCatalog) map[string]string {
+	switch component {
+	case componentAPIServer:
+		return cc.APIServerOptions
+	case componentControllerManager:
+		return cc.KubeControllerManagerOptions
+	case componentScheduler:
+		return cc.KubeSchedulerOptions
+	default:
+		return cc.KubeletOptions
+	}
+}
+
+func copyOptions(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+// complianceResult is a single line of the compliance report
+type complianceResult struct {
+	id          string
+	description string
+	setting     string
+	expected    string
+	actual      string
+	pass        bool
+}
+
+// nodeComplianceState is the state of a node that is checked by the
+// compliance report
+type nodeComplianceState struct {
+	// flags of the running control plane and kubelet processes, by component
+	flags map[string]map[string]string
+	// files are the permissions and owners of the files, by path
+	files map[string]fileState
+	// err is set when the state of the node could not be read
+	err error
+}
+
+type fileState struct {
+	mode  string
+	owner string
+}
+
+// complianceStateCommand prints the command line of the processes running on
+// the node, followed by the permissions and owners of the hardened files.
+func complianceStateCommand() string {
+	var paths []string
+	for _, f := range cisFiles {
+		paths = append(paths, f.path)
+	}
+	return fmt.Sprintf("ps -e -o args=; echo %s; stat -c '%%a %%U:%%G %%n' %s 2>/dev/null; true", complianceStateSeparator, strings.Join(paths, " "))
+}
+
+const complianceStateSeparator = "---kismatic-compliance---"
+
+// parseComplianceState parses the output of complianceStateCommand
+func parseComplianceState(out string) (nodeComplianceState, error) {
+	parts := strings.SplitN(out, complianceStateSeparator+"\n", 2)
+	if len(parts) != 2 {
+		return nodeComplianceState{}, fmt.Errorf("unexpected output: %q", out)
+	}
+	state := nodeComplianceState{
+		flags: map[string]map[string]string{},
+		files: map[string]fileState{},
+	}
+	for _, l := range strings.Split(parts[0], "\n") {
+		args := strings.Fields(l)
+		if len(args) == 0 {
+			continue
+		}
+		component := filepath.Base(args[0])
+		switch component {
+		case componentAPIServer, componentControllerManager, componentScheduler, componentKubelet:
+		default:
+			continue
+		}
+		flags := map[string]string{}
+		for _, a := range args[1:] {
+			if !strings.HasPrefix(a, "--") {
+				continue
+			}
+			kv := strings.SplitN(strings.TrimPrefix(a, "--"), "=", 2)
+			if len(kv) == 1 {
+				kv = append(kv, "true")
+			}
+			flags[kv[0]] = kv[1]
+		}
+		state.flags[component] = flags
+	}
+	for _, l := range strings.Split(parts[1], "\n") {
+		fields := strings.Fields(l)
+		if len(fields) != 3 {
+			continue
+		}
+		state.files[fields[2]] = fileState{mode: fields[0], owner: fields[1]}
+	}
+	return state, nil
+}
+
+// readComplianceState reads the state of the Kubernetes nodes of the cluster
+// over SSH
+func readComplianceState(p *Plan) map[string]nodeComplianceState {
+	states := map[string]nodeComplianceState{}
+	for _, n := range p.KubeletNodes() {
+		var state nodeComplianceState
+		client, err := p.GetSSHClient(n.Host)
+		if err == nil {
+			var out string
+			if out, err = client.Output(false, complianceStateCommand()); err != nil {
+				err = fmt.Errorf("%v: %s", err, strings.TrimSpace(out))
+			} else {
+				state, err = parseComplianceState(out)
+			}
+		}
+		state.err = err
+		states[n.Host] = state
+	}
+	return states
+}
+
+// complianceResults evaluates the plan's hardening profile settings against
+// the flags of the processes and the files on the nodes. A setting passes
+// when it is compliant on every node it applies to.
+func complianceResults(p *Plan, states map[string]nodeComplianceState) []complianceResult {
+	var results []complianceResult
+	for _, s := range cisSettings {
+		expected := hardeningVars.Replace(s.value)
+		r := complianceResult{
+			id:          s.id,
+			description: s.description,
+			setting:     fmt.Sprintf("%s --%s", s.component, s.option),
+			expected:    expected,
+			actual:      expected,
+			pass:        true,
+		}
+		for _, n := range complianceNodes(p, s.component == componentKubelet) {
+			state := states[n.Host]
+			actual := "not running"
+			if state.err != nil {
+				actual = fmt.Sprintf("unknown: %v", state.err)
+			} else if flags, ok := state.flags[s.component]; ok {
+				actual = "not set"
+				if v, ok := flags[s.option]; ok {
+					actual = v
+				}
+			}
+			if actual != expected {
+				r.actual = fmt.Sprintf("%s (node %s)", actual, n.Host)
+				r.pass = false
+				break
+			}
+		}
+		results = append(results, r)
+	}
+	for _, f := range cisFiles {
+		r := complianceResult{
+			id:          f.id,
+			description: f.description,
+			setting:     f.path,
+			expected:    fmt.Sprintf("%s root:root", f.mode),
+			actual:      fmt.Sprintf("%s root:root", f.mode),
+			pass:        true,
+		}
+		for _, n := range complianceNodes(p, !f.masterOnly) {
+			if actual, ok := fileCompliance(f, states[n.Host]); !ok {
+				r.actual = fmt.Sprintf("%s (node %s)", actual, n.Host)
+				r.pass = false
+				break
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// complianceNodes returns the nodes that run the kubelet, or only the master
+// nodes
+func complianceNodes(p *Plan, kubeletNodes bool) []Node {
+	if kubeletNodes {
+		return p.KubeletNodes()
+	}
+	return p.Master.Nodes
+}
+
+// fileCompliance returns the state of the hardened file on the node, and
+// whether the file is owned by root with the expected, or more restrictive,
+// permissions. Paths with wildcards match all the files that were found.
+func fileCompliance(f hardeningFile, state nodeComplianceState) (string, bool) {
+	if state.err != nil {
+		return fmt.Sprintf("unknown: %v", state.err), false
+	}
+	expected, err := strconv.ParseUint(f.mode, 8, 32)
+	if err != nil {
+		return fmt.Sprintf("invalid mode %q", f.mode), false
+	}
+	var found bool
+	for path, fs := range state.files {
+		if match, _ := filepath.Match(f.path, path); !match {
+			continue
+		}
+		found = true
+		mode, err := strconv.ParseUint(fs.mode, 8, 32)
+		if err != nil || mode&^expected != 0 || fs.owner != "root:root" {
+			return fmt.Sprintf("%s %s", fs.mode, fs.owner), false
+		}
+	}
+	if !found {
+		return "not found", false
+	}
+	return fmt.Sprintf("%s root:root", f.mode), true
+}
+
+func writeComplianceReport(out io.Writer, p *Plan, states map[string]nodeComplianceState) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "# CIS Kubernetes Benchmark compliance report for cluster %q\n", p.Cluster.Name)
+	fmt.Fprintln(w, "ID\tSTATUS\tDESCRIPTION\tSETTING\tEXPECTED\tACTUAL")
+	for _, r := range complianceResults(p, states) {
+		status := "PASS"
+		if !r.pass {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.id, status, r.description, r.setting, r.expected, r.actual)
+	}
+	return w.Flush()
+}
+
+// GenerateComplianceReport writes a report of the hardening profile settings
+// applied to the cluster into the generated assets directory. The settings
+// are read from the nodes over SSH. Nothing is written when the plan does not
+// define a hardening profile.
+func GenerateComplianceReport(p *Plan, generatedAssetsDir string) (string, error) {
+	if p.Cluster.HardeningProfile == "" {
+		return "", nil
+	}
+	states := readComplianceState(p)
+	reportFile := filepath.Join(generatedAssetsDir, complianceReportFilename)
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return "", fmt.Errorf("error creating compliance report file: %v", err)
+	}
+	defer f.Close()
+	if err := writeComplianceReport(f, p, states); err != nil {
+		return "", fmt.Errorf("error writing compliance report: %v", err)
+	}
+	return reportFile, nil
+}
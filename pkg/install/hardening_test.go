@@ -0,0 +1,171 @@
+package install
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestApplyHardeningProfile(t *testing.T) {
+	p := &Plan{}
+	p.Cluster.HardeningProfile = "cis"
+	p.Cluster.APIServerOptions.Overrides = map[string]string{"profiling": "true"}
+	cc := &ansible.ClusterCatalog{
+		APIServerOptions: p.Cluster.APIServerOptions.Overrides,
+	}
+	applyHardeningProfile(p, cc)
+
+	if cc.APIServerOptions["profiling"] != "true" {
+		t.Errorf("expected the plan override to take precedence, but got profiling=%q", cc.APIServerOptions["profiling"])
+	}
+	if cc.APIServerOptions["anonymous-auth"] != "false" {
+		t.Errorf("expected anonymous-auth to be disabled on the API server")
+	}
+	if cc.KubeletOptions["anonymous-auth"] != "false" {
+		t.Errorf("expected anonymous-auth to be disabled on the kubelet")
+	}
+	if cc.KubeSchedulerOptions["profiling"] != "false" {
+		t.Errorf("expected profiling to be disabled on the scheduler")
+	}
+	if _, ok := p.Cluster.APIServerOptions.Overrides["anonymous-auth"]; ok {
+		t.Errorf("expected the plan overrides to be left untouched")
+	}
+}
+
+func TestApplyHardeningProfileNotSet(t *testing.T) {
+	p := &Plan{}
+	cc := &ansible.ClusterCatalog{}
+	applyHardeningProfile(p, cc)
+	if cc.APIServerOptions != nil || cc.KubeletOptions != nil {
+		t.Errorf("expected no options to be set when the hardening profile is not set")
+	}
+}
+
+// compliantNodeOutput returns the output of the compliance state command
+// on a node that is compliant with the CIS hardening profile
+func compliantNodeOutput(master bool, flags map[string]map[string]string) string {
+	var out bytes.Buffer
+	components := []string{componentKubelet}
+	if master {
+		components = append(components, componentAPIServer, componentControllerManager, componentScheduler)
+	}
+	for _, c := range components {
+		args := []string{"/usr/bin/" + c}
+		for _, s := range cisSettings {
+			if s.component == c {
+				args = append(args, fmt.Sprintf("--%s=%s", s.option, hardeningVars.Replace(s.value)))
+			}
+		}
+		for k, v := range flags[c] {
+			args = append(args, fmt.Sprintf("--%s=%s", k, v))
+		}
+		fmt.Fprintln(&out, strings.Join(args, " "))
+	}
+	fmt.Fprintln(&out, "/usr/bin/dockerd --debug")
+	fmt.Fprintln(&out, complianceStateSeparator)
+	for _, f := range cisFiles {
+		if f.masterOnly && !master {
+			continue
+		}
+		path := strings.Replace(f.path, "*", "api-server", 1)
+		fmt.Fprintf(&out, "%s root:root %s\n", strings.TrimPrefix(f.mode, "0"), path)
+	}
+	return out.String()
+}
+
+func TestComplianceResults(t *testing.T) {
+	tests := []struct {
+		name          string
+		masterFlags   map[string]map[string]string
+		workerFlags   map[string]map[string]string
+		workerOutput  string
+		workerErr     error
+		expectedFails []string
+	}{
+		{
+			name: "compliant nodes",
+		},
+		{
+			name:          "API server with non-compliant flag",
+			masterFlags:   map[string]map[string]string{componentAPIServer: {"profiling": "true"}},
+			expectedFails: []string{"kube-apiserver --profiling"},
+		},
+		{
+			name:          "worker kubelet with non-compliant flag",
+			workerFlags:   map[string]map[string]string{componentKubelet: {"anonymous-auth": "true"}},
+			expectedFails: []string{"kubelet --anonymous-auth"},
+		},
+		{
+			name:          "worker with loose file permissions",
+			workerOutput:  strings.Replace(compliantNodeOutput(false, nil), "600 root:root", "640 root:root", 1),
+			expectedFails: []string{"/etc/kubernetes/pki/*-key.pem"},
+		},
+		{
+			name:      "unreachable worker",
+			workerErr: errors.New("connection refused"),
+			expectedFails: []string{
+				"kubelet --anonymous-auth", "kubelet --client-ca-file", "kubelet --make-iptables-util-chains",
+				"/etc/kubernetes/kubelet.conf", "/etc/systemd/system/kubelet.service", "/etc/kubernetes/kube-proxy.conf", "/etc/kubernetes/pki/*-key.pem",
+			},
+		},
+	}
+	for _, test := range tests {
+		p := &Plan{}
+		p.Cluster.HardeningProfile = "cis"
+		p.Master.Nodes = []Node{{Host: "master01"}}
+		p.Worker.Nodes = []Node{{Host: "worker01"}}
+
+		states := map[string]nodeComplianceState{}
+		master, err := parseComplianceState(compliantNodeOutput(true, test.masterFlags))
+		if err != nil {
+			t.Fatalf("%s: unexpected error parsing master state: %v", test.name, err)
+		}
+		states["master01"] = master
+		workerOutput := test.workerOutput
+		if workerOutput == "" {
+			workerOutput = compliantNodeOutput(false, test.workerFlags)
+		}
+		worker, err := parseComplianceState(workerOutput)
+		if err != nil {
+			t.Fatalf("%s: unexpected error parsing worker state: %v", test.name, err)
+		}
+		worker.err = test.workerErr
+		states["worker01"] = worker
+
+		var fails []string
+		for _, r := range complianceResults(p, states) {
+			if !r.pass {
+				fails = append(fails, r.setting)
+			}
+		}
+		if strings.Join(fails, ",") != strings.Join(test.expectedFails, ",") {
+			t.Errorf("%s: expected failures %v, but got %v", test.name, test.expectedFails, fails)
+		}
+	}
+}
+
+func TestWriteComplianceReport(t *testing.T) {
+	p := &Plan{}
+	p.Cluster.Name = "test"
+	p.Cluster.HardeningProfile = "cis"
+	p.Master.Nodes = []Node{{Host: "master01"}}
+	state, err := parseComplianceState(compliantNodeOutput(true, nil))
+	if err != nil {
+		t.Fatalf("unexpected error parsing state: %v", err)
+	}
+	out := &bytes.Buffer{}
+	if err := writeComplianceReport(out, p, map[string]nodeComplianceState{"master01": state}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(cisSettings)+len(cisFiles)+2 {
+		t.Errorf("expected %d lines in the report, but got %d", len(cisSettings)+len(cisFiles)+2, len(lines))
+	}
+	if strings.Contains(out.String(), "FAIL") {
+		t.Errorf("expected all checks to pass, but got:\n%s", out.String())
+	}
+}
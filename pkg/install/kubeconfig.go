@@ -2,13 +2,17 @@ package install
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"html/template"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/apprenda/kismatic/pkg/tls"
 	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/cloudflare/cfssl/csr"
 )
 
 const kubeconfigFilename = "kubeconfig"
@@ -47,11 +51,7 @@ users:
 
 // GenerateKubeconfig generate a kubeconfig file for a specific user
 func GenerateKubeconfig(p *Plan, generatedAssetsDir string) error {
-	user := "admin"
-	server := "https://" + p.Master.LoadBalancedFQDN + ":6443"
-	cluster := p.Cluster.Name
-	context := p.Cluster.Name + "-" + user
-
+	user := adminUser
 	certsDir := filepath.Join(generatedAssetsDir, "keys")
 
 	// Base64 encoded ca
@@ -70,20 +70,13 @@ func GenerateKubeconfig(p *Plan, generatedAssetsDir string) error {
 		return fmt.Errorf("error reading certificate key file for kubeconfig: %v", err)
 	}
 
-	// Process template file
-	tmpl, err := template.New("kubeconfig").Parse(kubeconfigTemplate)
+	kubeconfig, err := renderKubeconfig(p, user, caEncoded, certEncoded, keyEncoded)
 	if err != nil {
-		return fmt.Errorf("error reading config template: %v", err)
-	}
-	configOptions := ConfigOptions{caEncoded, server, cluster, user, context, certEncoded, keyEncoded}
-	var kubeconfig bytes.Buffer
-	err = tmpl.Execute(&kubeconfig, configOptions)
-	if err != nil {
-		return fmt.Errorf("error processing config template: %v", err)
+		return err
 	}
 	// Write config file
 	kubeconfigFile := filepath.Join(generatedAssetsDir, kubeconfigFilename)
-	err = ioutil.WriteFile(kubeconfigFile, kubeconfig.Bytes(), 0644)
+	err = ioutil.WriteFile(kubeconfigFile, kubeconfig, 0644)
 	if err != nil {
 		return fmt.Errorf("error writing kubeconfig file: %v", err)
 	}
@@ -91,6 +84,53 @@ func GenerateKubeconfig(p *Plan, generatedAssetsDir string) error {
 	return nil
 }
 
+// MintKubeconfig returns a kubeconfig for the user with a new client
+// certificate signed by the cluster CA, that expires after the given TTL.
+// The certificate is not written to disk, so that a leaked kubeconfig is
+// only usable until it expires.
+func MintKubeconfig(p *Plan, ca *tls.CA, user string, groups []string, ttl time.Duration) ([]byte, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("kubeconfig TTL must be greater than 0, but got %v", ttl)
+	}
+	req := csr.CertificateRequest{
+		CN: user,
+		KeyRequest: &csr.BasicKeyRequest{
+			A: "rsa",
+			S: 2048,
+		},
+	}
+	for _, g := range groups {
+		req.Names = append(req.Names, csr.Name{O: g})
+	}
+	key, cert, err := tls.NewCert(ca, req, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("error generating client certificate for %q: %v", user, err)
+	}
+	return renderKubeconfig(p, user,
+		base64.StdEncoding.EncodeToString(ca.Cert),
+		base64.StdEncoding.EncodeToString(cert),
+		base64.StdEncoding.EncodeToString(key))
+}
+
+func renderKubeconfig(p *Plan, user, caEncoded, certEncoded, keyEncoded string) ([]byte, error) {
+	server := "https://" + p.Master.LoadBalancedFQDN + ":6443"
+	cluster := p.Cluster.Name
+	context := p.Cluster.Name + "-" + user
+
+	// Process template file
+	tmpl, err := template.New("kubeconfig").Parse(kubeconfigTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config template: %v", err)
+	}
+	configOptions := ConfigOptions{caEncoded, server, cluster, user, context, certEncoded, keyEncoded}
+	var kubeconfig bytes.Buffer
+	err = tmpl.Execute(&kubeconfig, configOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error processing config template: %v", err)
+	}
+	return kubeconfig.Bytes(), nil
+}
+
 // RegenerateKubeconfig backs up the old kubeconfig file if it exists. Returns
 // true if the new kubeconfig file is different than the previous one.
 // Otherwise returns false.
@@ -1,10 +1,16 @@
 package install
 
 import (
+	"encoding/base64"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/tls"
+	"github.com/cloudflare/cfssl/helpers"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func createTempDirForRegenerateKubeconfigTests(t *testing.T) string {
@@ -104,3 +110,49 @@ func TestRegenerateKubeconfigSameIsNotBackedUp(t *testing.T) {
 		t.Error("did not find expected kubeconfig file")
 	}
 }
+
+func TestMintKubeconfig(t *testing.T) {
+	key, caCert, err := tls.NewCACert("test/ca-csr.json", "someCN", "12345h")
+	if err != nil {
+		t.Fatalf("error creating CA: %v", err)
+	}
+	p := &Plan{}
+	p.Cluster.Name = "test"
+	p.Master.LoadBalancedFQDN = "test"
+
+	b, err := MintKubeconfig(p, &tls.CA{Key: key, Cert: caCert}, "jane", []string{"devs"}, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeconfig := struct {
+		Users []struct {
+			User struct {
+				Cert string `yaml:"client-certificate-data"`
+			}
+		}
+	}{}
+	if err := yaml.Unmarshal(b, &kubeconfig); err != nil {
+		t.Fatalf("error unmarshaling kubeconfig: %v", err)
+	}
+	if len(kubeconfig.Users) != 1 {
+		t.Fatalf("expected one user in kubeconfig, but got %d", len(kubeconfig.Users))
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(kubeconfig.Users[0].User.Cert)
+	if err != nil {
+		t.Fatalf("error decoding client certificate: %v", err)
+	}
+	cert, err := helpers.ParseCertificatePEM(certPEM)
+	if err != nil {
+		t.Fatalf("error parsing client certificate: %v", err)
+	}
+	if cert.Subject.CommonName != "jane" || len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != "devs" {
+		t.Errorf("unexpected certificate subject: %+v", cert.Subject)
+	}
+	if cert.NotAfter.After(time.Now().Add(time.Hour)) || cert.NotAfter.Before(time.Now().Add(50*time.Minute)) {
+		t.Errorf("expected certificate to expire in an hour, but expires at %v", cert.NotAfter)
+	}
+
+	if _, err := MintKubeconfig(p, &tls.CA{Key: key, Cert: caCert}, "jane", nil, 0); err == nil {
+		t.Errorf("expected an error with a TTL of 0")
+	}
+}
@@ -2,7 +2,13 @@ package install
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/util"
 )
 
 var kubeletProtectedOptions = []string{
@@ -24,6 +30,22 @@ var kubeletProtectedOptions = []string{
 	"tls-private-key-file",
 }
 
+// the Kubernetes defaults of the image garbage collection thresholds
+const (
+	defaultImageGCHighThresholdPercent = 85
+	defaultImageGCLowThresholdPercent  = 80
+)
+
+var kubeletEvictionSignals = []string{
+	"memory.available",
+	"nodefs.available",
+	"nodefs.inodesFree",
+	"imagefs.available",
+	"imagefs.inodesFree",
+}
+
+var quantityRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti|Pi|Ei|k|M|G|T|P|E)?$`)
+
 func (options *KubeletOptions) validate() (bool, []error) {
 	v := newValidator()
 	overrides := make([]string, 0)
@@ -37,6 +59,160 @@ func (options *KubeletOptions) validate() (bool, []error) {
 	if len(overrides) > 0 {
 		v.addError(fmt.Errorf("Kubelet Option(s) [%v] cannot be overridden", strings.Join(overrides, ", ")))
 	}
+	v.addError(options.validateGarbageCollectionAndEviction()...)
 
 	return v.valid()
 }
+
+// validateGarbageCollectionAndEviction validates the garbage collection and
+// eviction settings, which can also be set on the nodes
+func (options *KubeletOptions) validateGarbageCollectionAndEviction() []error {
+	errs := []error{}
+	gc := options.GarbageCollection
+	for _, p := range []int{gc.ImageGCHighThresholdPercent, gc.ImageGCLowThresholdPercent} {
+		if p < 0 || p > 100 {
+			errs = append(errs, fmt.Errorf("Image garbage collection threshold %d must be between 0 and 100", p))
+		}
+	}
+	high, low := gc.ImageGCHighThresholdPercent, gc.ImageGCLowThresholdPercent
+	if high == 0 {
+		high = defaultImageGCHighThresholdPercent
+	}
+	if low == 0 {
+		low = defaultImageGCLowThresholdPercent
+	}
+	if low >= high {
+		errs = append(errs, fmt.Errorf("Image garbage collection low threshold %d must be lower than the high threshold %d", low, high))
+	}
+
+	e := options.Eviction
+	errs = append(errs, validateEvictionThresholds("hard", e.Hard)...)
+	errs = append(errs, validateEvictionThresholds("soft", e.Soft)...)
+	errs = append(errs, validateEvictionThresholds("minimum reclaim", e.MinimumReclaim)...)
+	for signal := range e.Soft {
+		if _, ok := e.SoftGracePeriod[signal]; !ok {
+			errs = append(errs, fmt.Errorf("Soft eviction threshold %q requires a grace period", signal))
+		}
+	}
+	for signal, period := range e.SoftGracePeriod {
+		if _, ok := e.Soft[signal]; !ok {
+			errs = append(errs, fmt.Errorf("Soft eviction grace period %q does not have a soft eviction threshold", signal))
+		}
+		if d, err := time.ParseDuration(period); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("Soft eviction grace period %q of %q is not a valid duration", period, signal))
+		}
+	}
+	for signal, soft := range e.Soft {
+		hard, ok := e.Hard[signal]
+		if ok && evictionThresholdBelow(soft, hard) {
+			errs = append(errs, fmt.Errorf("Soft eviction threshold %q of %q must not be lower than the hard eviction threshold %q", soft, signal, hard))
+		}
+	}
+	if e.PressureTransitionPeriod != "" {
+		if d, err := time.ParseDuration(e.PressureTransitionPeriod); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("Eviction pressure transition period %q is not a valid duration", e.PressureTransitionPeriod))
+		}
+	}
+
+	var conflicts []string
+	for flag := range options.garbageCollectionAndEvictionFlags() {
+		if _, ok := options.Overrides[flag]; ok {
+			conflicts = append(conflicts, flag)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		errs = append(errs, fmt.Errorf("Kubelet Option(s) [%v] are set by the garbage collection or eviction settings and cannot be overridden", strings.Join(conflicts, ", ")))
+	}
+	return errs
+}
+
+func validateEvictionThresholds(kind string, thresholds map[string]string) []error {
+	errs := []error{}
+	for signal, threshold := range thresholds {
+		if !util.Contains(signal, kubeletEvictionSignals) {
+			errs = append(errs, fmt.Errorf("Eviction signal %q is not valid, options are %v", signal, kubeletEvictionSignals))
+			continue
+		}
+		if !validEvictionThreshold(threshold) {
+			errs = append(errs, fmt.Errorf("The %s eviction threshold %q of %q is not valid, must be a quantity such as 100Mi or a percentage such as 10%%", kind, threshold, signal))
+		}
+	}
+	return errs
+}
+
+func validEvictionThreshold(threshold string) bool {
+	if strings.HasSuffix(threshold, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		return err == nil && p > 0 && p < 100
+	}
+	return quantityRegexp.MatchString(threshold)
+}
+
+// evictionThresholdBelow returns whether the threshold a is lower than the
+// threshold b, when both are percentages
+func evictionThresholdBelow(a, b string) bool {
+	if !strings.HasSuffix(a, "%") || !strings.HasSuffix(b, "%") {
+		return false
+	}
+	pa, errA := strconv.ParseFloat(strings.TrimSuffix(a, "%"), 64)
+	pb, errB := strconv.ParseFloat(strings.TrimSuffix(b, "%"), 64)
+	return errA == nil && errB == nil && pa < pb
+}
+
+// garbageCollectionAndEvictionFlags returns the kubelet flags of the
+// garbage collection and eviction settings
+func (options *KubeletOptions) garbageCollectionAndEvictionFlags() map[string]string {
+	flags := map[string]string{}
+	gc := options.GarbageCollection
+	if gc.ImageGCHighThresholdPercent != 0 {
+		flags["image-gc-high-threshold"] = strconv.Itoa(gc.ImageGCHighThresholdPercent)
+	}
+	if gc.ImageGCLowThresholdPercent != 0 {
+		flags["image-gc-low-threshold"] = strconv.Itoa(gc.ImageGCLowThresholdPercent)
+	}
+	e := options.Eviction
+	if len(e.Hard) > 0 {
+		flags["eviction-hard"] = joinEvictionThresholds(e.Hard, "<")
+	}
+	if len(e.Soft) > 0 {
+		flags["eviction-soft"] = joinEvictionThresholds(e.Soft, "<")
+	}
+	if len(e.SoftGracePeriod) > 0 {
+		flags["eviction-soft-grace-period"] = joinEvictionThresholds(e.SoftGracePeriod, "=")
+	}
+	if len(e.MinimumReclaim) > 0 {
+		flags["eviction-minimum-reclaim"] = joinEvictionThresholds(e.MinimumReclaim, "=")
+	}
+	if e.PressureTransitionPeriod != "" {
+		flags["eviction-pressure-transition-period"] = e.PressureTransitionPeriod
+	}
+	return flags
+}
+
+func joinEvictionThresholds(thresholds map[string]string, op string) string {
+	signals := make([]string, 0, len(thresholds))
+	for signal := range thresholds {
+		signals = append(signals, signal)
+	}
+	sort.Strings(signals)
+	parts := make([]string, 0, len(signals))
+	for _, signal := range signals {
+		parts = append(parts, signal+op+thresholds[signal])
+	}
+	return strings.Join(parts, ",")
+}
+
+// kubeletOverrides returns the option overrides of the kubelet along with
+// the flags of the garbage collection and eviction settings
+func kubeletOverrides(options KubeletOptions) map[string]string {
+	flags := options.garbageCollectionAndEvictionFlags()
+	if len(flags) == 0 {
+		return options.Overrides
+	}
+	overrides := copyOptions(options.Overrides)
+	for k, v := range flags {
+		overrides[k] = v
+	}
+	return overrides
+}
@@ -0,0 +1,110 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateKubeletGarbageCollectionAndEviction(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  KubeletOptions
+		valid bool
+	}{
+		{
+			name:  "no settings",
+			valid: true,
+		},
+		{
+			name: "valid settings",
+			opts: KubeletOptions{
+				GarbageCollection: KubeletGarbageCollection{ImageGCHighThresholdPercent: 75, ImageGCLowThresholdPercent: 60},
+				Eviction: KubeletEviction{
+					Hard:                     map[string]string{"memory.available": "200Mi", "nodefs.available": "10%"},
+					Soft:                     map[string]string{"nodefs.available": "15%"},
+					SoftGracePeriod:          map[string]string{"nodefs.available": "1m30s"},
+					MinimumReclaim:           map[string]string{"nodefs.available": "1Gi"},
+					PressureTransitionPeriod: "5m",
+				},
+			},
+			valid: true,
+		},
+		{
+			name:  "threshold over 100",
+			opts:  KubeletOptions{GarbageCollection: KubeletGarbageCollection{ImageGCHighThresholdPercent: 110}},
+			valid: false,
+		},
+		{
+			name:  "low threshold above the default high threshold",
+			opts:  KubeletOptions{GarbageCollection: KubeletGarbageCollection{ImageGCLowThresholdPercent: 90}},
+			valid: false,
+		},
+		{
+			name:  "unknown signal",
+			opts:  KubeletOptions{Eviction: KubeletEviction{Hard: map[string]string{"disk.available": "10%"}}},
+			valid: false,
+		},
+		{
+			name:  "invalid quantity",
+			opts:  KubeletOptions{Eviction: KubeletEviction{Hard: map[string]string{"memory.available": "100 megabytes"}}},
+			valid: false,
+		},
+		{
+			name:  "soft threshold without grace period",
+			opts:  KubeletOptions{Eviction: KubeletEviction{Soft: map[string]string{"memory.available": "500Mi"}}},
+			valid: false,
+		},
+		{
+			name: "soft threshold below hard threshold",
+			opts: KubeletOptions{Eviction: KubeletEviction{
+				Hard:            map[string]string{"nodefs.available": "15%"},
+				Soft:            map[string]string{"nodefs.available": "10%"},
+				SoftGracePeriod: map[string]string{"nodefs.available": "1m"},
+			}},
+			valid: false,
+		},
+		{
+			name:  "invalid pressure transition period",
+			opts:  KubeletOptions{Eviction: KubeletEviction{PressureTransitionPeriod: "5 minutes"}},
+			valid: false,
+		},
+		{
+			name: "conflicting override",
+			opts: KubeletOptions{
+				Overrides:         map[string]string{"image-gc-high-threshold": "90"},
+				GarbageCollection: KubeletGarbageCollection{ImageGCHighThresholdPercent: 75},
+			},
+			valid: false,
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.opts.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestKubeletOverrides(t *testing.T) {
+	opts := KubeletOptions{
+		Overrides:         map[string]string{"v": "4"},
+		GarbageCollection: KubeletGarbageCollection{ImageGCHighThresholdPercent: 75},
+		Eviction: KubeletEviction{
+			Hard:            map[string]string{"nodefs.available": "10%", "memory.available": "200Mi"},
+			Soft:            map[string]string{"nodefs.available": "15%"},
+			SoftGracePeriod: map[string]string{"nodefs.available": "1m"},
+		},
+	}
+	expected := map[string]string{
+		"v":                          "4",
+		"image-gc-high-threshold":    "75",
+		"eviction-hard":              "memory.available<200Mi,nodefs.available<10%",
+		"eviction-soft":              "nodefs.available<15%",
+		"eviction-soft-grace-period": "nodefs.available=1m",
+	}
+	if got := kubeletOverrides(opts); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, but got %v", expected, got)
+	}
+	if _, ok := opts.Overrides["eviction-hard"]; ok {
+		t.Errorf("the overrides of the plan were modified")
+	}
+}
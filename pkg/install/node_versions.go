@@ -0,0 +1,93 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+// cniImages are the images of the containers that run the CNI providers on
+// each node, whose tag is the version of the provider
+var cniImages = map[string]string{
+	cniProviderCalico: "calico/node",
+	cniProviderWeave:  "weaveworks/weave-kube",
+	cniProviderContiv: "contiv/netplugin",
+}
+
+// CNIVersions returns the version of the CNI provider of the plan that is
+// running on each node, keyed by the name of the node. It is empty when the
+// CNI add-on is disabled, or when the provider is not installed by Kismatic.
+func CNIVersions(plan Plan, pods *data.PodList) map[string]string {
+	versions := map[string]string{}
+	if plan.AddOns.CNI == nil || plan.AddOns.CNI.Disable || pods == nil {
+		return versions
+	}
+	image, ok := cniImages[plan.AddOns.CNI.Provider]
+	if !ok {
+		return versions
+	}
+	for _, p := range pods.Items {
+		if p.Namespace != "kube-system" || p.Spec.NodeName == "" {
+			continue
+		}
+		for _, c := range p.Spec.Containers {
+			// images can be pulled from a private registry
+			i := strings.LastIndex(c.Image, ":")
+			if i < 0 || strings.Contains(c.Image[i:], "/") {
+				continue
+			}
+			if repo := c.Image[:i]; repo == image || strings.HasSuffix(repo, "/"+image) {
+				versions[p.Spec.NodeName] = c.Image[i+1:]
+			}
+		}
+	}
+	return versions
+}
+
+// NodeVersionSkew returns the components of a node whose version is not the
+// version that Kismatic installs on the cluster of the plan. When package
+// installation is disabled, Docker is only skewed when it is not compatible
+// with the version of Kubernetes. The CNI provider is not checked when its
+// version is empty.
+func NodeVersionSkew(plan Plan, info data.NodeSystemInfo, cniVersion string) []string {
+	var skew []string
+	target := installedVersions.Kubernetes
+	for _, c := range []struct {
+		component string
+		version   string
+	}{
+		{component: "kubelet", version: info.KubeletVersion},
+		{component: "kube-proxy", version: info.KubeProxyVersion},
+	} {
+		if strings.TrimPrefix(c.version, "v") != target {
+			skew = append(skew, fmt.Sprintf("%s %s is not the target version v%s", c.component, c.version, target))
+		}
+	}
+	if docker := strings.TrimPrefix(info.ContainerRuntimeVersion, "docker://"); docker != info.ContainerRuntimeVersion {
+		if plan.packageInstallationEnabled() {
+			if docker != installedVersions.Docker {
+				skew = append(skew, fmt.Sprintf("Docker %s is not the target version %s", docker, installedVersions.Docker))
+			}
+		} else if minor, err := minorVersion(target); err == nil {
+			if err := checkCompatibility("Docker", docker, versionCompatibility[minor].Docker, target); err != nil {
+				skew = append(skew, err.Error())
+			}
+		}
+	}
+	if cniVersion != "" && plan.AddOns.CNI != nil {
+		var expected string
+		switch plan.AddOns.CNI.Provider {
+		case cniProviderCalico:
+			expected = installedVersions.Calico
+		case cniProviderWeave:
+			expected = installedVersions.Weave
+		case cniProviderContiv:
+			expected = installedVersions.Contiv
+		}
+		if expected != "" && strings.TrimPrefix(cniVersion, "v") != expected {
+			skew = append(skew, fmt.Sprintf("%s %s is not the target version %s", plan.AddOns.CNI.Provider, cniVersion, expected))
+		}
+	}
+	return skew
+}
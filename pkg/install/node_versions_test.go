@@ -0,0 +1,121 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+func TestCNIVersions(t *testing.T) {
+	pod := func(namespace, node string, images ...string) data.Pod {
+		p := data.Pod{ObjectMeta: data.ObjectMeta{Namespace: namespace}, Spec: data.PodSpec{NodeName: node}}
+		for _, i := range images {
+			p.Spec.Containers = append(p.Spec.Containers, data.Container{Image: i})
+		}
+		return p
+	}
+	pods := &data.PodList{Items: []data.Pod{
+		pod("kube-system", "worker1", "calico/node:v2.6.2", "calico/cni:v1.11.0"),
+		pod("kube-system", "worker2", "registry.local:8443/calico/node:v2.6.1"),
+		pod("default", "worker3", "calico/node:v2.5.0"),
+		pod("kube-system", "worker4", "weaveworks/weave-kube:2.0.5"),
+	}}
+	tests := []struct {
+		cni      *CNI
+		expected map[string]string
+	}{
+		{
+			cni:      &CNI{Provider: cniProviderCalico},
+			expected: map[string]string{"worker1": "v2.6.2", "worker2": "v2.6.1"},
+		},
+		{
+			cni:      &CNI{Provider: cniProviderWeave},
+			expected: map[string]string{"worker4": "2.0.5"},
+		},
+		{
+			cni:      &CNI{Provider: cniProviderCalico, Disable: true},
+			expected: map[string]string{},
+		},
+		{
+			cni:      &CNI{Provider: "custom"},
+			expected: map[string]string{},
+		},
+	}
+	for i, test := range tests {
+		versions := CNIVersions(Plan{AddOns: AddOns{CNI: test.cni}}, pods)
+		if !reflect.DeepEqual(versions, test.expected) {
+			t.Errorf("test %d: expected CNI versions %v, but got %v", i, test.expected, versions)
+		}
+	}
+}
+
+func TestNodeVersionSkew(t *testing.T) {
+	current := data.NodeSystemInfo{
+		KubeletVersion:          "v1.8.4",
+		KubeProxyVersion:        "v1.8.4",
+		ContainerRuntimeVersion: "docker://1.12.6",
+	}
+	calico := AddOns{CNI: &CNI{Provider: cniProviderCalico}}
+	tests := []struct {
+		plan       Plan
+		info       data.NodeSystemInfo
+		cniVersion string
+		skewed     int
+	}{
+		{
+			plan:       Plan{AddOns: calico},
+			info:       current,
+			cniVersion: "v2.6.2",
+		},
+		{
+			plan: Plan{AddOns: calico},
+			info: data.NodeSystemInfo{
+				KubeletVersion:          "v1.7.5",
+				KubeProxyVersion:        "v1.7.5",
+				ContainerRuntimeVersion: "docker://1.12.6",
+			},
+			cniVersion: "v2.6.1",
+			skewed:     3,
+		},
+		{
+			// docker is upgraded by kismatic
+			plan: Plan{},
+			info: data.NodeSystemInfo{
+				KubeletVersion:          "v1.8.4",
+				KubeProxyVersion:        "v1.8.4",
+				ContainerRuntimeVersion: "docker://1.11.2",
+			},
+			skewed: 1,
+		},
+		{
+			// a compatible docker is not skewed when package installation is disabled
+			plan: Plan{Cluster: Cluster{DisablePackageInstallation: true}},
+			info: data.NodeSystemInfo{
+				KubeletVersion:          "v1.8.4",
+				KubeProxyVersion:        "v1.8.4",
+				ContainerRuntimeVersion: "docker://17.3.2",
+			},
+		},
+		{
+			plan: Plan{Cluster: Cluster{DisablePackageInstallation: true}},
+			info: data.NodeSystemInfo{
+				KubeletVersion:          "v1.8.4",
+				KubeProxyVersion:        "v1.8.4",
+				ContainerRuntimeVersion: "docker://1.10.3",
+			},
+			skewed: 1,
+		},
+		{
+			// the CNI provider is not checked when its version is not known
+			plan: Plan{AddOns: calico},
+			info: current,
+		},
+	}
+	for i, test := range tests {
+		skew := NodeVersionSkew(test.plan, test.info, test.cniVersion)
+		if len(skew) != test.skewed {
+			t.Errorf("test %d: expected %d skewed components, but got %v", i, test.skewed, skew)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	installProgressFilename = "install-progress.yaml"
+	phaseDurationsFilename  = "phase-durations.yaml"
+
+	phasePreflight    = "preflight"
+	phasePKI          = "pki"
+	phaseEtcd         = "etcd"
+	phaseControlPlane = "control-plane"
+	phaseWorkers      = "workers"
+	phaseAddOns       = "add-ons"
+	phaseSmokeTest    = "smoketest"
+	phaseDone         = "done"
+)
+
+// installPhases are the phases of an installation, in the order they run.
+// The weight of a phase is the share of the installation it represents.
+var installPhases = []struct {
+	name   string
+	weight int
+}{
+	{phasePreflight, 5},
+	{phasePKI, 5},
+	{phaseEtcd, 10},
+	{phaseControlPlane, 20},
+	{phaseWorkers, 30},
+	{phaseAddOns, 20},
+	{phaseSmokeTest, 10},
+	{phaseDone, 0},
+}
+
+// taskPhase is the phase that starts when an installation task starts, and
+// the phase that starts once the task completes
+type taskPhase struct {
+	start string
+	done  string
+	// whether the task starts a new installation, unless it is the next step
+	// of the installation that is in progress
+	restart bool
+}
+
+var taskPhases = map[string]taskPhase{
+	"preflight": {start: phasePreflight, restart: true},
+	"apply":     {start: phasePKI, restart: true},
+	"smoketest": {start: phaseSmokeTest, done: phaseDone},
+}
+
+// playPhases map the plays of the installation playbook to the phase they
+// belong to. Plays that are not listed belong to the current phase.
+var playPhases = []struct {
+	play  string
+	phase string
+}{
+	{"Certificates", phasePKI},
+	{"Kubectl Config", phasePKI},
+	{"Etcd", phaseEtcd},
+	{"Kubelet", phaseControlPlane},
+	{"API Server", phaseControlPlane},
+	{"Scheduler", phaseControlPlane},
+	{"Controller Manager", phaseControlPlane},
+	{"Control Plane", phaseControlPlane},
+	{"Proxy", phaseWorkers},
+	{"Label Kubernetes Nodes", phaseWorkers},
+	{"Calico", phaseWorkers},
+	{"Weave", phaseWorkers},
+	{"Contiv", phaseWorkers},
+	{"Rescheduler", phaseAddOns},
+	{"Autoscaler", phaseAddOns},
+	{"DNS", phaseAddOns},
+	{"Heapster", phaseAddOns},
+	{"Dashboard", phaseAddOns},
+	{"Helm", phaseAddOns},
+	{"Ingress", phaseAddOns},
+	{"Storage", phaseAddOns},
+	{"NFS", phaseAddOns},
+}
+
+// Progress is the progress of an installation
+type Progress struct {
+	// CurrentPhase is the phase of the installation that is running
+	CurrentPhase string `yaml:"currentPhase"`
+	// PhaseStarted is the time when the current phase started
+	PhaseStarted time.Time `yaml:"phaseStarted"`
+	// PercentComplete is the completed share of the installation
+	PercentComplete int `yaml:"percentComplete"`
+	// ETA is the estimated time of completion, based on the duration of
+	// previous installations. It is not set until all phases have run once.
+	ETA *time.Time `yaml:"eta,omitempty"`
+}
+
+// phaseDuration is the average duration of a phase across installations
+type phaseDuration struct {
+	Runs           int     `yaml:"runs"`
+	AverageSeconds float64 `yaml:"average_seconds"`
+}
+
+func (d phaseDuration) average() time.Duration {
+	return time.Duration(d.AverageSeconds * float64(time.Second))
+}
+
+// ReadInstallProgress returns the progress of the last installation that
+// recorded its runs in the given directory. Nil is returned if no
+// installation has run.
+func ReadInstallProgress(runsDirectory string) (*Progress, error) {
+	p, err := readInstallProgress(filepath.Join(runsDirectory, installProgressFilename))
+	if err != nil || p.CurrentPhase == "" {
+		return nil, err
+	}
+	return p, nil
+}
+
+func phaseIndex(phase string) int {
+	for i, p := range installPhases {
+		if p.name == phase {
+			return i
+		}
+	}
+	return -1
+}
+
+func phaseOfPlay(play string) string {
+	for _, p := range playPhases {
+		if strings.Contains(play, p.play) {
+			return p.phase
+		}
+	}
+	return ""
+}
+
+// estimateProgress returns the progress of an installation that is in the
+// given phase, using the duration of the phases of previous installations
+func estimateProgress(phase string, started, now time.Time, history map[string]phaseDuration) Progress {
+	p := Progress{CurrentPhase: phase, PhaseStarted: started}
+	current := phaseIndex(phase)
+	if current < 0 {
+		return p
+	}
+	var percent float64
+	for _, ph := range installPhases[:current] {
+		percent += float64(ph.weight)
+	}
+	elapsed := now.Sub(started)
+	remaining := time.Duration(0)
+	estimated := true
+	if d, ok := history[phase]; ok && d.average() > 0 {
+		// Don't report the phase as complete while it is still running
+		fraction := float64(elapsed) / float64(d.average())
+		if fraction > 0.99 {
+			fraction = 0.99
+		}
+		percent += fraction * float64(installPhases[current].weight)
+		if elapsed < d.average() {
+			remaining = d.average() - elapsed
+		}
+	} else if installPhases[current].weight > 0 {
+		estimated = false
+	}
+	for _, ph := range installPhases[current+1:] {
+		d, ok := history[ph.name]
+		if !ok && ph.weight > 0 {
+			estimated = false
+		}
+		remaining += d.average()
+	}
+	p.PercentComplete = int(percent)
+	if estimated {
+		eta := now.Add(remaining)
+		p.ETA = &eta
+	}
+	return p
+}
+
+// phaseTracker records the phase of the installation in the runs directory
+// as its tasks and plays run, and the duration of each phase once it
+// completes.
+type phaseTracker struct {
+	mu            sync.Mutex
+	runsDirectory string
+	now           func() time.Time
+}
+
+func newPhaseTracker(runsDirectory string) *phaseTracker {
+	return &phaseTracker{runsDirectory: runsDirectory, now: time.Now}
+}
+
+// startTask starts the phase of the task, if the task is part of the
+// installation
+func (t *phaseTracker) startTask(task string) error {
+	phase, ok := taskPhases[task]
+	if !ok {
+		return nil
+	}
+	return t.advance(phase.start, phase.restart)
+}
+
+// completeTask starts the phase that follows the task, if any
+func (t *phaseTracker) completeTask(task string) error {
+	phase, ok := taskPhases[task]
+	if !ok || phase.done == "" {
+		return nil
+	}
+	return t.advance(phase.done, false)
+}
+
+// startPlay moves the installation to the phase of the play. The progress
+// is also refreshed when the play belongs to the current phase.
+func (t *phaseTracker) startPlay(play string) error {
+	return t.advance(phaseOfPlay(play), false)
+}
+
+// advance moves the installation to the given phase, if the phase comes
+// after the current one. When restart is true, a new installation starts
+// if the phase does not come after the current one.
+func (t *phaseTracker) advance(phase string, restart bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progressFile := filepath.Join(t.runsDirectory, installProgressFilename)
+	durationsFile := filepath.Join(t.runsDirectory, phaseDurationsFilename)
+	progress, err := readInstallProgress(progressFile)
+	if err != nil {
+		return err
+	}
+	history, err := readPhaseDurations(durationsFile)
+	if err != nil {
+		return err
+	}
+	now := t.now()
+	current := phaseIndex(progress.CurrentPhase)
+	next := phaseIndex(phase)
+	switch {
+	case restart && next <= current, restart && current < 0:
+		progress.CurrentPhase = phase
+		progress.PhaseStarted = now
+	case current < 0:
+		// no installation is in progress
+		return nil
+	case next > current:
+		d := history[progress.CurrentPhase]
+		elapsed := now.Sub(progress.PhaseStarted).Seconds()
+		d.AverageSeconds = (d.AverageSeconds*float64(d.Runs) + elapsed) / float64(d.Runs+1)
+		d.Runs++
+		history[progress.CurrentPhase] = d
+		if err := writeYAMLFile(durationsFile, history); err != nil {
+			return err
+		}
+		progress.CurrentPhase = phase
+		progress.PhaseStarted = now
+	}
+	p := estimateProgress(progress.CurrentPhase, progress.PhaseStarted, now, history)
+	return writeYAMLFile(progressFile, p)
+}
+
+func readInstallProgress(file string) (*Progress, error) {
+	p := &Progress{}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading install progress from %q: %v", file, err)
+	}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("error unmarshaling install progress from %q: %v", file, err)
+	}
+	return p, nil
+}
+
+func readPhaseDurations(file string) (map[string]phaseDuration, error) {
+	d := map[string]phaseDuration{}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading phase durations from %q: %v", file, err)
+	}
+	if err := yaml.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("error unmarshaling phase durations from %q: %v", file, err)
+	}
+	return d, nil
+}
+
+func writeYAMLFile(file string, v interface{}) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error marshaling %q: %v", file, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return fmt.Errorf("error creating directory: %v", err)
+	}
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return fmt.Errorf("error writing %q: %v", file, err)
+	}
+	return nil
+}
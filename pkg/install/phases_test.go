@@ -0,0 +1,171 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPhaseOfPlay(t *testing.T) {
+	tests := []struct {
+		play  string
+		phase string
+	}{
+		{"Configure Cluster Prerequisites", ""},
+		{"Deploy Cluster Certificates", phasePKI},
+		{"Generate Kubectl Config File", phasePKI},
+		{"Start Kubernetes Etcd Cluster", phaseEtcd},
+		{"Schedule Kubernetes Etcd Backups", phaseEtcd},
+		{"Start Kubernetes Kubelet", phaseControlPlane},
+		{"Validate Kubernetes Control Plane is Running", phaseControlPlane},
+		{"Start Kubernetes Proxy", phaseWorkers},
+		{"Validate Calico Network Components", phaseWorkers},
+		{"Start Kubernetes DNS", phaseAddOns},
+		{"Configure PersistentVolumes for NFS", phaseAddOns},
+		{"Update Kismatic Version File", ""},
+	}
+	for _, test := range tests {
+		if phase := phaseOfPlay(test.play); phase != test.phase {
+			t.Errorf("expected play %q to be in phase %q, but got %q", test.play, test.phase, phase)
+		}
+	}
+}
+
+func TestEstimateProgress(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	history := map[string]phaseDuration{
+		phasePreflight:    {Runs: 1, AverageSeconds: 60},
+		phasePKI:          {Runs: 1, AverageSeconds: 60},
+		phaseEtcd:         {Runs: 1, AverageSeconds: 120},
+		phaseControlPlane: {Runs: 1, AverageSeconds: 300},
+		phaseWorkers:      {Runs: 1, AverageSeconds: 600},
+		phaseAddOns:       {Runs: 1, AverageSeconds: 300},
+		phaseSmokeTest:    {Runs: 1, AverageSeconds: 60},
+	}
+	tests := []struct {
+		phase           string
+		elapsed         time.Duration
+		history         map[string]phaseDuration
+		percentComplete int
+		remaining       time.Duration
+		noETA           bool
+	}{
+		{
+			phase:   phasePreflight,
+			history: map[string]phaseDuration{},
+			noETA:   true,
+		},
+		{
+			phase:           phaseControlPlane,
+			elapsed:         150 * time.Second,
+			history:         map[string]phaseDuration{phaseControlPlane: {Runs: 1, AverageSeconds: 300}},
+			percentComplete: 30,
+			noETA:           true,
+		},
+		{
+			phase:           phaseControlPlane,
+			elapsed:         150 * time.Second,
+			history:         history,
+			percentComplete: 30,
+			remaining:       (150 + 600 + 300 + 60) * time.Second,
+		},
+		{
+			// the phase is taking longer than it used to
+			phase:           phaseSmokeTest,
+			elapsed:         120 * time.Second,
+			history:         history,
+			percentComplete: 99,
+		},
+		{
+			phase:           phaseDone,
+			history:         history,
+			percentComplete: 100,
+		},
+	}
+	for i, test := range tests {
+		p := estimateProgress(test.phase, now.Add(-test.elapsed), now, test.history)
+		if p.CurrentPhase != test.phase {
+			t.Errorf("test %d: expected phase %q, but got %q", i, test.phase, p.CurrentPhase)
+		}
+		if p.PercentComplete != test.percentComplete {
+			t.Errorf("test %d: expected %d percent complete, but got %d", i, test.percentComplete, p.PercentComplete)
+		}
+		if test.noETA {
+			if p.ETA != nil {
+				t.Errorf("test %d: expected no ETA, but got %v", i, p.ETA)
+			}
+			continue
+		}
+		if p.ETA == nil {
+			t.Errorf("test %d: expected an ETA, but got none", i)
+			continue
+		}
+		if remaining := p.ETA.Sub(now); remaining != test.remaining {
+			t.Errorf("test %d: expected %v remaining, but got %v", i, test.remaining, remaining)
+		}
+	}
+}
+
+func TestPhaseTracker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phase-tracker-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	tracker := &phaseTracker{runsDirectory: dir, now: func() time.Time { return now }}
+
+	// plays are ignored until an installation starts
+	if err := tracker.startPlay("Start Kubernetes Etcd Cluster"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, err := ReadInstallProgress(dir); err != nil || p != nil {
+		t.Fatalf("expected no progress, but got %v (err: %v)", p, err)
+	}
+
+	steps := []struct {
+		run     func() error
+		elapsed time.Duration
+		phase   string
+	}{
+		{func() error { return tracker.startTask("preflight") }, 0, phasePreflight},
+		{func() error { return tracker.startTask("apply") }, time.Minute, phasePKI},
+		{func() error { return tracker.startPlay("Configure Cluster Prerequisites") }, time.Minute, phasePKI},
+		{func() error { return tracker.startPlay("Start Kubernetes Etcd Cluster") }, time.Minute, phaseEtcd},
+		{func() error { return tracker.startPlay("Start Kubernetes API Server") }, time.Minute, phaseControlPlane},
+		// plays of earlier phases don't move the installation back
+		{func() error { return tracker.startPlay("Start Kubernetes Etcd Cluster") }, time.Minute, phaseControlPlane},
+		{func() error { return tracker.startTask("smoketest") }, time.Minute, phaseSmokeTest},
+		{func() error { return tracker.completeTask("smoketest") }, time.Minute, phaseDone},
+		// a new installation starts
+		{func() error { return tracker.startTask("preflight") }, time.Minute, phasePreflight},
+	}
+	for i, s := range steps {
+		now = now.Add(s.elapsed)
+		if err := s.run(); err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		p, err := ReadInstallProgress(dir)
+		if err != nil {
+			t.Fatalf("step %d: unexpected error reading progress: %v", i, err)
+		}
+		if p == nil || p.CurrentPhase != s.phase {
+			t.Errorf("step %d: expected phase %q, but got %v", i, s.phase, p)
+		}
+	}
+
+	history, err := readPhaseDurations(filepath.Join(dir, phaseDurationsFilename))
+	if err != nil {
+		t.Fatalf("unexpected error reading phase durations: %v", err)
+	}
+	for _, phase := range []string{phasePreflight, phasePKI, phaseEtcd, phaseSmokeTest} {
+		if d := history[phase]; d.Runs != 1 {
+			t.Errorf("expected phase %q to have run once, but got %d", phase, d.Runs)
+		}
+	}
+	if d := history[phasePKI]; d.AverageSeconds != 120 {
+		t.Errorf("expected the pki phase to take 120 seconds, but got %v", d.AverageSeconds)
+	}
+}
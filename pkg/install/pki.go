@@ -31,6 +31,9 @@ const (
 	kubeletUserPrefix                   = "system:node"
 	kubeletGroup                        = "system:nodes"
 	contivProxyServerCertFilename       = "contiv-proxy-server"
+	frontProxyClientCertFilename        = "front-proxy-client"
+	frontProxyClientUser                = "front-proxy-client"
+	dashboardIngressCertFilename        = "dashboard-ingress"
 )
 
 // The PKI provides a way for generating certificates for the cluster described by the Plan
@@ -139,6 +142,9 @@ func certManifestForNode(plan Plan, node Node) ([]certificateSpec, error) {
 		if !contains(plan.Master.LoadBalancedShortName, san) {
 			san = append(san, plan.Master.LoadBalancedShortName)
 		}
+		if vip := plan.Master.VirtualIP.Address; vip != "" && !contains(vip, san) {
+			san = append(san, vip)
+		}
 		m = append(m, certificateSpec{
 			description:           fmt.Sprintf("%s API server", node.Host),
 			filename:              fmt.Sprintf("%s-apiserver", node.Host),
@@ -157,6 +163,13 @@ func certManifestForNode(plan Plan, node Node) ([]certificateSpec, error) {
 			filename:    schedulerCertFilenamePrefix,
 			commonName:  schedulerUser,
 		})
+		// Client certificate of the API server when it proxies requests to
+		// the extension API servers, such as the metrics server
+		m = append(m, certificateSpec{
+			description: "API server front proxy client",
+			filename:    frontProxyClientCertFilename,
+			commonName:  frontProxyClientUser,
+		})
 		// Certificate for signing service account tokens
 		m = append(m, certificateSpec{
 			description: "service account signing",
@@ -221,6 +234,16 @@ func certManifestForCluster(plan Plan) ([]certificateSpec, error) {
 		})
 	}
 
+	// Serving certificate of the dashboard ingress
+	if d := plan.AddOns.Dashboard; d != nil && !d.Disable && d.Ingress.Enabled {
+		m = append(m, certificateSpec{
+			description:           "dashboard ingress",
+			filename:              dashboardIngressCertFilename,
+			commonName:            d.Ingress.Host,
+			subjectAlternateNames: []string{d.Ingress.Host},
+		})
+	}
+
 	// Admin certificate
 	m = append(m, certificateSpec{
 		description:   "admin client",
@@ -465,6 +465,11 @@ func TestGenerateClusterCertificatesValidateCertificateInformation(t *testing.T)
 			certFilename:       "kube-controller-manager.pem",
 			expectedCommonName: "system:kube-controller-manager",
 		},
+		{
+			name:               "front proxy client certificate",
+			certFilename:       "front-proxy-client.pem",
+			expectedCommonName: "front-proxy-client",
+		},
 		{
 			name:               "kube-proxy certificate",
 			certFilename:       "kube-proxy.pem",
@@ -685,6 +690,35 @@ func TestAPIServerCertContainsInternalIP(t *testing.T) {
 	}
 }
 
+func TestAPIServerCertContainsVirtualIP(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	p := getPlan()
+	p.Master.VirtualIP = MasterVirtualIP{Address: "10.10.10.100", RouterID: 51}
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	node := p.Master.Nodes[0]
+	if err := pki.GenerateNodeCertificate(p, node, ca); err != nil {
+		t.Fatalf("failed to generate certificate for node: %v", err)
+	}
+	certFile := filepath.Join(pki.GeneratedCertsDirectory, fmt.Sprintf("%s-apiserver.pem", node.Host))
+	cert := mustReadCertFile(certFile, t)
+	found := false
+	vip := net.ParseIP(p.Master.VirtualIP.Address)
+	for _, ip := range cert.IPAddresses {
+		if ip.Equal(vip) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("API server certificate does not have the virtual IP as an IP address")
+	}
+}
+
 func TestValidateClusterCertificatesNoExistingCerts(t *testing.T) {
 	pki := getPKI(t)
 	defer cleanup(pki.GeneratedCertsDirectory, t)
@@ -50,6 +50,10 @@ type Planner interface {
 // FilePlanner is a file-based installation planner
 type FilePlanner struct {
 	File string
+	// ValuesFile is an optional YAML file containing the values of the
+	// variables referenced in the plan file. Variables that are not defined
+	// in this file are read from the environment.
+	ValuesFile string
 }
 
 // Read the plan from the file system
@@ -59,13 +63,55 @@ func (fp *FilePlanner) Read() (*Plan, error) {
 		return nil, fmt.Errorf("could not read file: %v", err)
 	}
 
+	values := map[string]string{}
+	if fp.ValuesFile != "" {
+		if values, err = readPlanValues(fp.ValuesFile); err != nil {
+			return nil, err
+		}
+	}
+	if d, err = renderPlanTemplate(d, DetectPlanFormat(fp.File, d), values); err != nil {
+		return nil, fmt.Errorf("failed to render plan: %v", err)
+	}
+
+	return ReadPlan(d, DetectPlanFormat(fp.File, d))
+}
+
+// IsTemplate returns true if the plan file references variables, in which
+// case the plan read from it must not be written back to the file, as that
+// would replace the references with their values.
+func (fp *FilePlanner) IsTemplate() (bool, error) {
+	d, err := ioutil.ReadFile(fp.File)
+	if err != nil {
+		return false, fmt.Errorf("could not read file: %v", err)
+	}
+	return len(planVariables(d, DetectPlanFormat(fp.File, d))) > 0, nil
+}
+
+// ReadPlan decodes the plan, which is in the given format, upgrades it to the
+// current schema, and sets the defaults of the fields that are not set.
+func ReadPlan(d []byte, format PlanFormat) (*Plan, error) {
+	return ReadPlanWithDefaults(d, format, nil)
+}
+
+// ReadPlanWithDefaults reads the plan like ReadPlan, but the plan is decoded
+// on top of the plan defaults, when they are set, so that the fields the plan
+// does not set take the value of the defaults.
+func ReadPlanWithDefaults(d []byte, format PlanFormat, defaults *PlanDefaults) (*Plan, error) {
 	p := &Plan{}
-	if err = yaml.Unmarshal(d, p); err != nil {
+	if defaults != nil {
+		if err := yaml.Unmarshal(defaults.doc, p); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal plan defaults: %v", err)
+		}
+	}
+	err := unmarshalPlanInto(d, format, p)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal plan: %v", err)
 	}
 
-	// read deprecated fields and set it the new version of the cluster file
-	readDeprecatedFields(p)
+	// upgrade plans written by older versions of KET to the current schema
+	if p.migrations, err = migratePlan(p); err != nil {
+		return nil, err
+	}
 
 	// set nil values to defaults
 	setDefaults(p)
@@ -73,17 +119,22 @@ func (fp *FilePlanner) Read() (*Plan, error) {
 	return p, nil
 }
 
-func readDeprecatedFields(p *Plan) {
+// readDeprecatedFields sets the current fields of the plan from the
+// deprecated ones, and returns a description of the fields that were read.
+func readDeprecatedFields(p *Plan) []string {
+	var changes []string
 	// only set if not already being set by the user
 	// package_manager moved from features: to add_ons: after KET v1.3.3
 	if p.Features != nil && p.Features.PackageManager != nil {
 		p.AddOns.PackageManager.Disable = !p.Features.PackageManager.Enabled
 		// KET v1.3.3 did not have a provider field
 		p.AddOns.PackageManager.Provider = ket133PackageManagerProvider
+		changes = append(changes, "features.package_manager moved to add_ons.package_manager")
 	}
 	// allow_package_installation renamed to disable_package_installation after KET v1.4.0
 	if p.Cluster.AllowPackageInstallation != nil {
 		p.Cluster.DisablePackageInstallation = !*p.Cluster.AllowPackageInstallation
+		changes = append(changes, "cluster.allow_package_installation replaced by cluster.disable_package_installation")
 	}
 
 	// Only read the deprecated dashboard field if the new one is not set
@@ -91,11 +142,14 @@ func readDeprecatedFields(p *Plan) {
 		p.AddOns.Dashboard = &Dashboard{
 			Disable: p.AddOns.DashboardDeprecated.Disable,
 		}
+		changes = append(changes, "add_ons.dashbard renamed to add_ons.dashboard")
 	}
 
 	if p.DockerRegistry.Server == "" && p.DockerRegistry.Address != "" && p.DockerRegistry.Port != 0 {
 		p.DockerRegistry.Server = fmt.Sprintf("%s:%d", p.DockerRegistry.Address, p.DockerRegistry.Port)
+		changes = append(changes, "docker_registry.address and docker_registry.port merged into docker_registry.server")
 	}
+	return changes
 }
 
 func setDefaults(p *Plan) {
@@ -112,6 +166,66 @@ func setDefaults(p *Plan) {
 	if p.AddOns.CNI.Options.Calico.LogLevel == "" {
 		p.AddOns.CNI.Options.Calico.LogLevel = "info"
 	}
+	if calico := &p.AddOns.CNI.Options.Calico; p.AddOns.CNI.Provider == cniProviderCalico {
+		if calico.BGP.ASNumber == 0 {
+			calico.BGP.ASNumber = defaultCalicoASNumber
+		}
+		for i := range calico.IPPools {
+			if calico.IPPools[i].IPIPMode == "" {
+				calico.IPPools[i].IPIPMode = calicoIPIPModeAlways
+				if calico.Mode == "routed" {
+					calico.IPPools[i].IPIPMode = calicoIPIPModeOff
+				}
+			}
+		}
+	}
+
+	if b := &p.Cluster.SSH.Bastion; b.Host != "" {
+		if b.User == "" {
+			b.User = p.Cluster.SSH.User
+		}
+		if b.Key == "" {
+			b.Key = p.Cluster.SSH.Key
+		}
+		if b.Port == 0 {
+			b.Port = 22
+		}
+	}
+
+	if p.AddOns.ExternalDNS.Enabled && p.AddOns.ExternalDNS.Provider == "" {
+		p.AddOns.ExternalDNS.Provider = externalDNSProviderAWS
+	}
+
+	if p.AddOns.RebootCoordinator.Enabled {
+		if p.AddOns.RebootCoordinator.SentinelFile == "" {
+			p.AddOns.RebootCoordinator.SentinelFile = "/var/run/reboot-required"
+		}
+		if p.AddOns.RebootCoordinator.Period == "" {
+			p.AddOns.RebootCoordinator.Period = "1h"
+		}
+	}
+
+	if p.Master.VirtualIP.Address != "" && p.Master.VirtualIP.RouterID == 0 {
+		p.Master.VirtualIP.RouterID = 51
+	}
+
+	if p.AddOns.DNS.Provider == "" {
+		p.AddOns.DNS.Provider = dnsProviderKubeDNS
+	}
+	if p.AddOns.DNS.Options.Autoscaler.Enabled {
+		if p.AddOns.DNS.Options.Autoscaler.CoresPerReplica == 0 {
+			p.AddOns.DNS.Options.Autoscaler.CoresPerReplica = 256
+		}
+		if p.AddOns.DNS.Options.Autoscaler.NodesPerReplica == 0 {
+			p.AddOns.DNS.Options.Autoscaler.NodesPerReplica = 16
+		}
+		if p.AddOns.DNS.Options.Autoscaler.MinReplicas == 0 {
+			p.AddOns.DNS.Options.Autoscaler.MinReplicas = 2
+		}
+	}
+	if p.AddOns.DNS.Options.NodeLocalCache.Enabled && p.AddOns.DNS.Options.NodeLocalCache.IP == "" {
+		p.AddOns.DNS.Options.NodeLocalCache.IP = "169.254.20.10"
+	}
 
 	if p.AddOns.HeapsterMonitoring == nil {
 		p.AddOns.HeapsterMonitoring = &HeapsterMonitoring{}
@@ -140,12 +254,103 @@ func setDefaults(p *Plan) {
 	if p.AddOns.Dashboard == nil {
 		p.AddOns.Dashboard = &Dashboard{}
 	}
+	if len(p.Docker.RegistryCredentials) > 0 && len(p.Docker.PullSecretNamespaces) == 0 {
+		p.Docker.PullSecretNamespaces = []string{"default"}
+	}
+	if p.Cluster.DefaultPolicies.Enabled && len(p.Cluster.DefaultPolicies.DenyIngressNamespaces) == 0 {
+		p.Cluster.DefaultPolicies.DenyIngressNamespaces = []string{"default"}
+	}
+	if p.AddOns.Dashboard.Ingress.Enabled {
+		if p.AddOns.Dashboard.Ingress.Auth == "" {
+			p.AddOns.Dashboard.Ingress.Auth = dashboardIngressAuthClientCertificate
+		}
+	}
+
+	if p.Cluster.Events.Publisher != "" && p.Cluster.Events.Topic == "" {
+		p.Cluster.Events.Topic = defaultEventsTopic
+	}
+
+	if p.Cluster.EtcdTopology == "" {
+		p.Cluster.EtcdTopology = etcdTopologyExternal
+	}
+	if p.Cluster.EtcdBackup.Enabled {
+		if p.Cluster.EtcdBackup.Schedule == "" {
+			p.Cluster.EtcdBackup.Schedule = "daily"
+		}
+		if p.Cluster.EtcdBackup.Retention == 0 {
+			p.Cluster.EtcdBackup.Retention = 7
+		}
+	}
+	if p.Cluster.TimeSync.MaxSkewSeconds == 0 {
+		p.Cluster.TimeSync.MaxSkewSeconds = 30
+	}
+	if p.Cluster.EtcdDataVolume.Enabled {
+		if p.Cluster.EtcdDataVolume.Filesystem == "" {
+			p.Cluster.EtcdDataVolume.Filesystem = etcdDataVolumeXFS
+		}
+		if p.Cluster.EtcdDataVolume.MaxSyncLatencyMS == 0 {
+			p.Cluster.EtcdDataVolume.MaxSyncLatencyMS = 10
+		}
+	}
+	if p.Cluster.Audit.Enabled {
+		if p.Cluster.Audit.Level == "" {
+			p.Cluster.Audit.Level = auditLevelMetadata
+		}
+		if p.Cluster.Audit.Sink == "" {
+			p.Cluster.Audit.Sink = auditSinkFile
+		}
+		if p.Cluster.Audit.MaxAge == 0 {
+			p.Cluster.Audit.MaxAge = 30
+		}
+		if p.Cluster.Audit.MaxBackups == 0 {
+			p.Cluster.Audit.MaxBackups = 10
+		}
+		if p.Cluster.Audit.MaxSize == 0 {
+			p.Cluster.Audit.MaxSize = 100
+		}
+	}
+	if p.Cluster.AuthWebhook.enabled() && p.Cluster.AuthWebhook.CacheTTL == "" {
+		p.Cluster.AuthWebhook.CacheTTL = "2m"
+	}
+	if p.Cluster.Expiration.enabled() && p.Cluster.Expiration.WarnBefore == "" {
+		p.Cluster.Expiration.WarnBefore = "24h"
+	}
+	if len(p.PostInstall.Manifests) > 0 && p.PostInstall.Retries == nil {
+		retries := defaultPostInstallRetries
+		p.PostInstall.Retries = &retries
+	}
+	// with a stacked topology, etcd runs on the master nodes
+	if p.Cluster.EtcdTopology == etcdTopologyStacked && len(p.Etcd.Nodes) == 0 {
+		for _, n := range p.Master.Nodes {
+			p.Etcd.Nodes = append(p.Etcd.Nodes, Node{Host: n.Host, IP: n.IP, InternalIP: n.InternalIP})
+		}
+		p.Etcd.ExpectedCount = len(p.Etcd.Nodes)
+		p.etcdFromMasters = true
+	}
 }
 
 var yamlKeyRE = regexp.MustCompile(`[^a-zA-Z]*([a-z_\-A-Z]+)[ ]*:`)
 
 // Write the plan to the file system
 func (fp *FilePlanner) Write(p *Plan) error {
+	// the etcd nodes derived from the master nodes are not persisted, so
+	// that they follow the master nodes when the plan changes
+	if p.etcdFromMasters {
+		c := *p
+		c.Etcd.ExpectedCount = 0
+		c.Etcd.Nodes = nil
+		p = &c
+	}
+	switch DetectPlanFormat(fp.File, nil) {
+	case PlanFormatJSON:
+		bytez, err := marshalPlanJSON(p)
+		if err != nil {
+			return fmt.Errorf("error marshalling plan to json: %v", err)
+		}
+		return ioutil.WriteFile(fp.File, append(bytez, '\n'), 0644)
+	case PlanFormatHCL:
+		return errors.New("writing plan files in HCL format is not supported")
+	}
 	// make a copy of the global comment map
 	oneTimeComments := map[string][]string{}
 	for k, v := range commentMap {
@@ -270,10 +475,13 @@ func WritePlanTemplate(planTemplateOpts PlanTemplateOptions, w PlanReadWriter) e
 // template options
 func buildPlanFromTemplateOptions(templateOpts PlanTemplateOptions) Plan {
 	p := Plan{}
+	p.APIVersion = planAPIVersion
 	p.Cluster.Name = "kubernetes"
 	p.Cluster.AdminPassword = templateOpts.AdminPassword
 	p.Cluster.DisablePackageInstallation = false
 	p.Cluster.DisconnectedInstallation = false
+	p.Cluster.EtcdTopology = etcdTopologyExternal
+	p.Cluster.SELinux = selinuxEnforcing
 
 	// Set SSH defaults
 	p.Cluster.SSH.User = "kismaticuser"
@@ -295,11 +503,15 @@ func buildPlanFromTemplateOptions(templateOpts PlanTemplateOptions) Plan {
 	p.AddOns.CNI.Provider = cniProviderCalico
 	p.AddOns.CNI.Options.Calico.Mode = "overlay"
 	p.AddOns.CNI.Options.Calico.LogLevel = "info"
+	// DNS
+	p.AddOns.DNS.Provider = dnsProviderKubeDNS
 	// Heapster
 	p.AddOns.HeapsterMonitoring = &HeapsterMonitoring{}
 	p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas = 2
 	p.AddOns.HeapsterMonitoring.Options.Heapster.ServiceType = "ClusterIP"
 	p.AddOns.HeapsterMonitoring.Options.Heapster.Sink = "influxdb:http://heapster-influxdb.kube-system.svc:8086"
+	// Metrics Server
+	p.AddOns.MetricsServer.Enabled = true
 
 	// Package Manager
 	p.AddOns.PackageManager.Provider = "helm"
@@ -395,6 +607,8 @@ var commentMap = map[string][]string{
 	"cluster.admin_password":                             []string{"This password is used to login to the Kubernetes Dashboard and can also be", "used for administration without a security certificate."},
 	"cluster.disable_package_installation":               []string{"Set to true if the nodes have the required packages installed."},
 	"cluster.disconnected_installation":                  []string{"Set to true if you are performing a disconnected installation."},
+	"cluster.etcd_topology":                              []string{"Set to 'stacked' to run etcd on the master nodes. The etcd nodes can then", "be left empty. Options: 'external','stacked'."},
+	"cluster.selinux":                                    []string{"The SELinux mode of the nodes that have SELinux enabled. When 'enforcing',", "the required SELinux policies are installed. Options: 'enforcing','permissive'."},
 	"cluster.networking":                                 []string{"Networking configuration of your cluster."},
 	"cluster.networking.pod_cidr_block":                  []string{"Kubernetes will assign pods IPs in this range. Do not use a range that is", "already in use on your local network!"},
 	"cluster.networking.service_cidr_block":              []string{"Kubernetes will assign services IPs in this range. Do not use a range", "that is already in use by your local network or pod network!"},
@@ -436,9 +650,11 @@ var commentMap = map[string][]string{
 	"add_ons.cni.provider":                               []string{"Selecting 'custom' will result in a CNI ready cluster, however it is up to", "you to configure a plugin after the install.", "Options: 'calico','weave','contiv','custom'."},
 	"add_ons.cni.options.calico.mode":                    []string{"Options: 'overlay','routed'."},
 	"add_ons.cni.options.calico.log_level":               []string{"Options: 'warning','info','debug'."},
+	"add_ons.dns.provider":                               []string{"Options: 'kubedns','coredns'."},
 	"add_ons.heapster.options.influxdb.pvc_name":         []string{"Provide the name of the persistent volume claim that you will create", "after installation. If not specified, the data will be stored in", "ephemeral storage."},
 	"add_ons.heapster.options.heapster.service_type":     []string{"Specify kubernetes ServiceType. Defaults to 'ClusterIP'.", "Options: 'ClusterIP','NodePort','LoadBalancer','ExternalName'."},
 	"add_ons.heapster.options.heapster.sink":             []string{"Specify the sink to store heapster data. Defaults to an influxdb pod", "running on the cluster."},
+	"add_ons.metrics_server":                             []string{"The metrics server is required by 'kubectl top' and the horizontal pod", "autoscalers."},
 	"add_ons.package_manager.provider":                   []string{"Options: 'helm'"},
 	"add_ons.rescheduler":                                []string{"The rescheduler ensures that critical add-ons remain running on the cluster."},
 }
@@ -0,0 +1,48 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PlanDefaults are the values that a server uses for the fields that the plan
+// files it receives don't set, such as the networking CIDRs, the add-ons or
+// the worker pools. They are set by an administrator in a plan file that only
+// has these fields.
+type PlanDefaults struct {
+	// the defaults, as a YAML plan file
+	doc []byte
+}
+
+// ReadPlanDefaults reads the plan defaults from the file, which is a plan
+// file in any of the plan formats
+func ReadPlanDefaults(file string) (*PlanDefaults, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan defaults: %v", err)
+	}
+	p, err := UnmarshalPlan(d, DetectPlanFormat(file, d))
+	if err != nil {
+		return nil, fmt.Errorf("plan defaults %q are not valid: %v", file, err)
+	}
+	// the schema version is the one of the plan files that are received
+	p.APIVersion = ""
+	doc, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling plan defaults: %v", err)
+	}
+	return &PlanDefaults{doc: doc}, nil
+}
+
+// MinimalPlanJSON returns, in JSON, the plan that an empty plan file becomes
+// once the plan defaults, and the defaults of the plan file, are applied. The
+// defaults may be nil.
+func MinimalPlanJSON(defaults *PlanDefaults) ([]byte, error) {
+	p, err := ReadPlanWithDefaults([]byte{}, PlanFormatYAML, defaults)
+	if err != nil {
+		return nil, err
+	}
+	return marshalPlanJSON(p)
+}
@@ -0,0 +1,99 @@
+package install
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePlanDefaults(t *testing.T, dir, content string) *PlanDefaults {
+	file := filepath.Join(dir, "defaults.yaml")
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("error writing plan defaults: %v", err)
+	}
+	defaults, err := ReadPlanDefaults(file)
+	if err != nil {
+		t.Fatalf("unexpected error reading plan defaults: %v", err)
+	}
+	return defaults
+}
+
+func TestReadPlanWithDefaults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan-defaults")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defaults := writePlanDefaults(t, dir, `api_version: v1
+cluster:
+  networking:
+    pod_cidr_block: 10.0.0.0/16
+    service_cidr_block: 10.1.0.0/16
+add_ons:
+  cni:
+    provider: weave
+`)
+	d := []byte(`cluster:
+  name: dev
+  networking:
+    pod_cidr_block: 192.168.0.0/16
+`)
+	p, err := ReadPlanWithDefaults(d, PlanFormatYAML, defaults)
+	if err != nil {
+		t.Fatalf("unexpected error reading plan: %v", err)
+	}
+	if p.Cluster.Networking.PodCIDRBlock != "192.168.0.0/16" {
+		t.Errorf("expected the pod CIDR of the plan, but got %q", p.Cluster.Networking.PodCIDRBlock)
+	}
+	if p.Cluster.Networking.ServiceCIDRBlock != "10.1.0.0/16" {
+		t.Errorf("expected the default service CIDR, but got %q", p.Cluster.Networking.ServiceCIDRBlock)
+	}
+	if p.AddOns.CNI == nil || p.AddOns.CNI.Provider != cniProviderWeave {
+		t.Errorf("expected the default CNI provider, but got %+v", p.AddOns.CNI)
+	}
+
+	// the defaults are not changed by the plans read on top of them
+	if p, err = ReadPlanWithDefaults([]byte{}, PlanFormatYAML, defaults); err != nil {
+		t.Fatalf("unexpected error reading plan: %v", err)
+	}
+	if p.Cluster.Networking.PodCIDRBlock != "10.0.0.0/16" {
+		t.Errorf("expected the default pod CIDR, but got %q", p.Cluster.Networking.PodCIDRBlock)
+	}
+}
+
+func TestMinimalPlanJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan-defaults")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defaults := writePlanDefaults(t, dir, `worker_pools:
+- name: general
+  instance_type: m4.xlarge
+`)
+	d, err := MinimalPlanJSON(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var p struct {
+		AddOns struct {
+			CNI struct {
+				Provider string
+			}
+		} `json:"add_ons"`
+		WorkerPools []struct {
+			InstanceType string `json:"instance_type"`
+		} `json:"worker_pools"`
+	}
+	if err = json.Unmarshal(d, &p); err != nil {
+		t.Fatalf("error decoding plan: %v", err)
+	}
+	if p.AddOns.CNI.Provider != cniProviderCalico {
+		t.Errorf("expected the CNI provider to default to %q, but got %q", cniProviderCalico, p.AddOns.CNI.Provider)
+	}
+	if len(p.WorkerPools) != 1 || p.WorkerPools[0].InstanceType != "m4.xlarge" {
+		t.Errorf("expected the default worker pool, but got %+v", p.WorkerPools)
+	}
+}
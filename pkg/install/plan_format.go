@@ -0,0 +1,196 @@
+package install
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PlanFormat is the format of a plan file
+type PlanFormat string
+
+const (
+	// PlanFormatYAML is the default plan file format
+	PlanFormatYAML PlanFormat = "yaml"
+	// PlanFormatJSON is the JSON plan file format
+	PlanFormatJSON PlanFormat = "json"
+	// PlanFormatHCL is the HashiCorp Configuration Language plan file format
+	PlanFormatHCL PlanFormat = "hcl"
+)
+
+// DetectPlanFormat returns the format of the plan file, based on the file's
+// extension. If the extension is not known, the format is detected from the
+// contents of the file.
+func DetectPlanFormat(file string, d []byte) PlanFormat {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return PlanFormatJSON
+	case ".hcl", ".tf":
+		return PlanFormatHCL
+	case ".yaml", ".yml":
+		return PlanFormatYAML
+	}
+	trimmed := bytes.TrimSpace(d)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return PlanFormatJSON
+	}
+	// A plan is a map of the top-level sections. HCL bodies such as
+	// 'cluster { name = "foo" }' are valid YAML too, but decode to a plain
+	// scalar, so the file is only YAML if it decodes to a map.
+	var y interface{}
+	if err := yaml.Unmarshal(d, &y); err == nil {
+		if _, ok := y.(map[interface{}]interface{}); ok {
+			return PlanFormatYAML
+		}
+	}
+	var h interface{}
+	if len(trimmed) > 0 && hcl.Unmarshal(d, &h) == nil {
+		return PlanFormatHCL
+	}
+	return PlanFormatYAML
+}
+
+// UnmarshalPlan decodes the plan, which is in the given format.
+func UnmarshalPlan(d []byte, format PlanFormat) (*Plan, error) {
+	p := &Plan{}
+	if err := unmarshalPlanInto(d, format, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// unmarshalPlanInto decodes the plan on top of p, so that the fields the
+// plan does not set keep their values
+func unmarshalPlanInto(d []byte, format PlanFormat, p *Plan) error {
+	switch format {
+	case PlanFormatYAML, PlanFormatJSON:
+		// JSON is a subset of YAML, so the yaml field names apply to both.
+		// The yaml decoder accepts any YAML though, so JSON is checked first.
+		if format == PlanFormatJSON && !json.Valid(d) {
+			return errors.New("plan is not valid JSON")
+		}
+		return yaml.Unmarshal(d, p)
+	case PlanFormatHCL:
+		var raw interface{}
+		if err := hcl.Unmarshal(d, &raw); err != nil {
+			return err
+		}
+		// HCL decodes blocks as lists of objects. Use the plan's structure to
+		// turn them into the objects expected by the yaml decoder, so that the
+		// yaml field names can be used in HCL plans too.
+		y, err := yaml.Marshal(normalizeHCL(raw, reflect.TypeOf(*p)))
+		if err != nil {
+			return err
+		}
+		return yaml.Unmarshal(y, p)
+	}
+	return fmt.Errorf("plan format %q is not supported", format)
+}
+
+// normalizeHCL merges the lists of objects produced by the HCL decoder into
+// a single object when the corresponding field of the target type is not a
+// list.
+func normalizeHCL(v interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch val := v.(type) {
+	case []map[string]interface{}:
+		if t.Kind() == reflect.Slice {
+			out := make([]interface{}, 0, len(val))
+			for _, m := range val {
+				out = append(out, normalizeHCL(m, t.Elem()))
+			}
+			return out
+		}
+		merged := map[string]interface{}{}
+		for _, m := range val {
+			for k, e := range m {
+				merged[k] = e
+			}
+		}
+		return normalizeHCL(merged, t)
+	case []interface{}:
+		if t.Kind() != reflect.Slice {
+			return val
+		}
+		out := make([]interface{}, 0, len(val))
+		for _, e := range val {
+			out = append(out, normalizeHCL(e, t.Elem()))
+		}
+		return out
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, e := range val {
+			switch t.Kind() {
+			case reflect.Struct:
+				if f, ok := fieldForYAMLKey(t, k); ok {
+					out[k] = normalizeHCL(e, f.Type)
+					continue
+				}
+			case reflect.Map:
+				out[k] = normalizeHCL(e, t.Elem())
+				continue
+			}
+			out[k] = e
+		}
+		return out
+	}
+	return v
+}
+
+// returns the struct field that is decoded from the given yaml key
+func fieldForYAMLKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			// yaml.v2 uses the lowercased field name by default
+			name = strings.ToLower(f.Name)
+		}
+		if name == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// marshals the plan to JSON, using the yaml field names
+func marshalPlanJSON(p *Plan) ([]byte, error) {
+	y, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err = yaml.Unmarshal(y, &raw); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(stringKeys(raw), "", "  ")
+}
+
+// yaml decodes objects as map[interface{}]interface{}, which cannot be
+// encoded as JSON.
+func stringKeys(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := map[string]interface{}{}
+		for k, e := range val {
+			out[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(val))
+		for _, e := range val {
+			out = append(out, stringKeys(e))
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,141 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectPlanFormat(t *testing.T) {
+	tests := []struct {
+		file     string
+		data     string
+		expected PlanFormat
+	}{
+		{
+			file:     "kismatic-cluster.yaml",
+			data:     "cluster:\n  name: foo\n",
+			expected: PlanFormatYAML,
+		},
+		{
+			file:     "kismatic-cluster.json",
+			expected: PlanFormatJSON,
+		},
+		{
+			file:     "kismatic-cluster.hcl",
+			expected: PlanFormatHCL,
+		},
+		{
+			file:     "kismatic-cluster",
+			data:     "  {\"cluster\": {\"name\": \"foo\"}}",
+			expected: PlanFormatJSON,
+		},
+		{
+			file:     "kismatic-cluster",
+			data:     "cluster {\n  name = \"foo\"\n}\n",
+			expected: PlanFormatHCL,
+		},
+		{
+			file:     "kismatic-cluster",
+			data:     "cluster {\n  name = \"foo\"\n}\nworker {\n  expected_count = 3\n}\n",
+			expected: PlanFormatHCL,
+		},
+		{
+			file:     "kismatic-cluster",
+			data:     "cluster:\n  name: foo\n",
+			expected: PlanFormatYAML,
+		},
+	}
+	for _, test := range tests {
+		if f := DetectPlanFormat(test.file, []byte(test.data)); f != test.expected {
+			t.Errorf("%s: expected format %q, but got %q", test.file, test.expected, f)
+		}
+	}
+}
+
+const hclPlan = `
+cluster {
+  name = "kubernetes"
+  networking {
+    pod_cidr_block = "172.16.0.0/16"
+  }
+}
+master {
+  expected_count = 2
+  nodes = [
+    {
+      host = "master1"
+      ip = "10.0.0.1"
+      labels {
+        zone = "a"
+      }
+    },
+    {
+      host = "master2"
+      ip = "10.0.0.2"
+    },
+  ]
+}
+`
+
+func TestUnmarshalPlanHCL(t *testing.T) {
+	p, err := UnmarshalPlan([]byte(hclPlan), PlanFormatHCL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Cluster.Name != "kubernetes" {
+		t.Errorf("expected cluster name %q, but got %q", "kubernetes", p.Cluster.Name)
+	}
+	if p.Cluster.Networking.PodCIDRBlock != "172.16.0.0/16" {
+		t.Errorf("expected pod cidr %q, but got %q", "172.16.0.0/16", p.Cluster.Networking.PodCIDRBlock)
+	}
+	expectedNodes := []Node{
+		{Host: "master1", IP: "10.0.0.1", Labels: map[string]string{"zone": "a"}},
+		{Host: "master2", IP: "10.0.0.2"},
+	}
+	if p.Master.ExpectedCount != 2 || !reflect.DeepEqual(p.Master.Nodes, expectedNodes) {
+		t.Errorf("expected master nodes %+v, but got %+v", expectedNodes, p.Master.Nodes)
+	}
+}
+
+func TestUnmarshalPlanJSON(t *testing.T) {
+	p, err := UnmarshalPlan([]byte(`{"cluster": {"name": "kubernetes"}}`), PlanFormatJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Cluster.Name != "kubernetes" {
+		t.Errorf("expected cluster name %q, but got %q", "kubernetes", p.Cluster.Name)
+	}
+	if _, err = UnmarshalPlan([]byte("cluster:\n  name: kubernetes\n"), PlanFormatJSON); err == nil {
+		t.Errorf("expected an error unmarshalling a YAML plan as JSON, but didn't get one")
+	}
+}
+
+func TestWriteReadPlanJSON(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-plan-json")
+	if err != nil {
+		t.Fatalf("error creating tmp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	planner := FilePlanner{File: filepath.Join(tmpDir, "kismatic-cluster.json")}
+	p := buildPlanFromTemplateOptions(PlanTemplateOptions{AdminPassword: "password", EtcdNodes: 1, MasterNodes: 1, WorkerNodes: 1})
+	if err = planner.Write(&p); err != nil {
+		t.Fatalf("error writing plan: %v", err)
+	}
+	read, err := planner.Read()
+	if err != nil {
+		t.Fatalf("error reading plan: %v", err)
+	}
+	if read.Cluster.AdminPassword != "password" || len(read.Worker.Nodes) != 1 {
+		t.Errorf("plan read from JSON does not match the plan that was written: %+v", read)
+	}
+}
+
+func TestWritePlanHCL(t *testing.T) {
+	planner := FilePlanner{File: "kismatic-cluster.hcl"}
+	if err := planner.Write(&Plan{}); err == nil {
+		t.Errorf("expected an error writing an HCL plan, but didn't get one")
+	}
+}
@@ -0,0 +1,256 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+const (
+	kismaticHostLabel         = "kismatic/host"
+	kismaticCNIProviderLabel  = "kismatic/cni-provider"
+	kismaticIngressLabel      = "kismatic/ingress"
+	kismaticStorageLabel      = "kismatic/storage"
+	kismaticWorkerPoolLabel   = "kismatic/worker-pool"
+	kismaticInstanceTypeLabel = "kismatic/instance-type"
+	kismaticVersionAnnotation = "kismatic/version"
+	masterRoleLabel           = "node-role.kubernetes.io/master"
+)
+
+// ClusterInfoClient is used to gather information about a running cluster
+type ClusterInfoClient interface {
+	data.NodeLister
+	data.PodLister
+}
+
+// BuildPlanFromCluster inspects a running cluster that was installed by KET,
+// and returns a best-effort plan that describes it. Information that cannot
+// be obtained from the cluster, such as the SSH configuration, is left for
+// the operator to fill out. The returned warnings describe the parts of the
+// plan that require attention.
+func BuildPlanFromCluster(client ClusterInfoClient, apiServerURL string) (*Plan, []string, error) {
+	nodes, err := client.ListNodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if nodes == nil || len(nodes.Items) == 0 {
+		return nil, nil, errors.New("the cluster does not have any nodes")
+	}
+	pods, err := client.ListPods()
+	if err != nil {
+		return nil, nil, err
+	}
+	if pods == nil {
+		pods = &data.PodList{}
+	}
+
+	p := buildPlanFromTemplateOptions(PlanTemplateOptions{})
+	warnings := []string{
+		"cluster.admin_password could not be determined, and must be set",
+		"cluster.ssh could not be determined, and must be set",
+	}
+
+	// Nodes and their roles
+	for _, n := range nodes.Items {
+		node := nodeFromClusterNode(n)
+		_, isMaster := n.Labels[masterRoleLabel]
+		isIngress := n.Labels[kismaticIngressLabel] == "true"
+		isStorage := n.Labels[kismaticStorageLabel] == "true"
+		if isMaster {
+			p.Master.Nodes = append(p.Master.Nodes, node)
+		}
+		if isIngress {
+			p.Ingress.Nodes = append(p.Ingress.Nodes, node)
+		}
+		if isStorage {
+			p.Storage.Nodes = append(p.Storage.Nodes, node)
+		}
+		// only worker nodes are registered as schedulable
+		if !n.Spec.Unschedulable || (!isMaster && !isIngress && !isStorage) {
+			if name := n.Labels[kismaticWorkerPoolLabel]; name != "" {
+				pool := p.workerPool(name)
+				if pool == nil {
+					p.WorkerPools = append(p.WorkerPools, WorkerPool{Name: name, InstanceType: n.Labels[kismaticInstanceTypeLabel]})
+					pool = &p.WorkerPools[len(p.WorkerPools)-1]
+				}
+				pool.Nodes = append(pool.Nodes, node)
+			} else {
+				p.Worker.Nodes = append(p.Worker.Nodes, node)
+			}
+		}
+		if provider := n.Labels[kismaticCNIProviderLabel]; provider != "" {
+			p.AddOns.CNI.Provider = provider
+		}
+	}
+	if !contains(p.AddOns.CNI.Provider, cniProviders()) {
+		warnings = append(warnings, fmt.Sprintf("add_ons.cni.provider %q is not supported", p.AddOns.CNI.Provider))
+	}
+
+	// Control plane configuration
+	var etcdServers string
+	for _, pod := range pods.Items {
+		if pod.Namespace != "kube-system" || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		flags := commandFlags(pod.Spec.Containers[0].Command)
+		switch pod.Labels["component"] {
+		case "kube-apiserver":
+			if v := flags["service-cluster-ip-range"]; v != "" {
+				p.Cluster.Networking.ServiceCIDRBlock = v
+			}
+			if v := flags["cloud-provider"]; v != "" {
+				p.Cluster.CloudProvider.Provider = v
+			}
+			if v := flags["etcd-servers"]; v != "" {
+				etcdServers = v
+			}
+			if v := pod.Annotations[kismaticVersionAnnotation]; v != "" && v != KismaticVersion.String() {
+				warnings = append(warnings, fmt.Sprintf("the cluster was installed with KET %s, but the plan was generated with KET %s", v, KismaticVersion))
+			}
+		case "kube-controller-manager":
+			if v := flags["cluster-cidr"]; v != "" {
+				p.Cluster.Networking.PodCIDRBlock = v
+			}
+			if v := flags["cluster-name"]; v != "" {
+				p.Cluster.Name = v
+			}
+		}
+	}
+
+	// Etcd nodes are not part of the Kubernetes cluster. Get them from the
+	// API server configuration.
+	if etcdServers == "" {
+		warnings = append(warnings, "etcd nodes could not be determined, and must be added")
+	}
+	for _, s := range strings.Split(etcdServers, ",") {
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		host, _, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			host = u.Host
+		}
+		etcd := Node{Host: host}
+		if net.ParseIP(host) != nil {
+			etcd.IP = host
+		}
+		for _, n := range p.GetUniqueNodes() {
+			if n.Host == host || n.IP == host || n.InternalIP == host {
+				etcd = Node{Host: n.Host, IP: n.IP, InternalIP: n.InternalIP}
+				break
+			}
+		}
+		if etcd.IP == "" {
+			warnings = append(warnings, fmt.Sprintf("the IP of etcd node %q could not be determined, and must be set", host))
+		}
+		p.Etcd.Nodes = append(p.Etcd.Nodes, etcd)
+	}
+
+	// Add-ons
+	switch {
+	case hasPodWithPrefix(*pods, "coredns"):
+		p.AddOns.DNS.Provider = dnsProviderCoreDNS
+	case hasPodWithPrefix(*pods, "kube-dns"):
+		p.AddOns.DNS.Provider = dnsProviderKubeDNS
+	default:
+		p.AddOns.DNS.Disable = true
+	}
+	p.AddOns.HeapsterMonitoring.Disable = !hasPodWithPrefix(*pods, "heapster")
+	p.AddOns.Dashboard.Disable = !hasPodWithPrefix(*pods, "kubernetes-dashboard")
+	p.AddOns.PackageManager.Disable = !hasPodWithPrefix(*pods, "tiller-deploy")
+	p.AddOns.Rescheduler.Disable = !hasPodWithPrefix(*pods, "rescheduler")
+
+	// Load balanced endpoint of the masters
+	if u, err := url.Parse(apiServerURL); err == nil && u.Host != "" {
+		host := u.Host
+		if h, _, err := net.SplitHostPort(u.Host); err == nil {
+			host = h
+		}
+		p.Master.LoadBalancedFQDN = host
+		p.Master.LoadBalancedShortName = host
+		if net.ParseIP(host) == nil {
+			p.Master.LoadBalancedShortName = strings.Split(host, ".")[0]
+		}
+	} else {
+		warnings = append(warnings, "master.load_balanced_fqdn could not be determined, and must be set")
+	}
+
+	p.Etcd.ExpectedCount = len(p.Etcd.Nodes)
+	p.Master.ExpectedCount = len(p.Master.Nodes)
+	p.Worker.ExpectedCount = len(p.Worker.Nodes)
+	for i := range p.WorkerPools {
+		p.WorkerPools[i].ExpectedCount = len(p.WorkerPools[i].Nodes)
+	}
+	p.Ingress.ExpectedCount = len(p.Ingress.Nodes)
+	p.Storage.ExpectedCount = len(p.Storage.Nodes)
+	return &p, warnings, nil
+}
+
+// converts a Kubernetes node to a plan node
+func nodeFromClusterNode(n data.Node) Node {
+	node := Node{Host: n.Labels[kismaticHostLabel]}
+	if node.Host == "" {
+		node.Host = n.Name
+	}
+	var externalIP, internalIP string
+	for _, a := range n.Status.Addresses {
+		switch a.Type {
+		case data.NodeExternalIP:
+			externalIP = a.Address
+		case data.NodeInternalIP:
+			internalIP = a.Address
+		}
+	}
+	node.IP = internalIP
+	if externalIP != "" && externalIP != internalIP {
+		node.IP = externalIP
+		node.InternalIP = internalIP
+	}
+	// only keep the labels that were set by the operator
+	for k, v := range n.Labels {
+		if strings.HasPrefix(k, "kismatic/") || strings.Contains(k, "kubernetes.io/") {
+			continue
+		}
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		node.Labels[k] = v
+	}
+	for _, t := range n.Spec.Taints {
+		// taints added by Kubernetes itself are prefixed with node.kubernetes.io/
+		if strings.Contains(t.Key, "kubernetes.io/") {
+			continue
+		}
+		node.Taints = append(node.Taints, Taint{Key: t.Key, Value: t.Value, Effect: t.Effect})
+	}
+	return node
+}
+
+// returns the --flag=value pairs of a container command
+func commandFlags(command []string) map[string]string {
+	flags := map[string]string{}
+	for _, c := range command {
+		if !strings.HasPrefix(c, "--") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(c, "--"), "=", 2)
+		if len(kv) == 2 {
+			flags[kv[0]] = kv[1]
+		}
+	}
+	return flags
+}
+
+func hasPodWithPrefix(pods data.PodList, prefix string) bool {
+	for _, p := range pods.Items {
+		if p.Namespace == "kube-system" && strings.HasPrefix(p.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+type fakeClusterInfoClient struct {
+	nodes *data.NodeList
+	pods  *data.PodList
+}
+
+func (f fakeClusterInfoClient) ListNodes() (*data.NodeList, error) { return f.nodes, nil }
+func (f fakeClusterInfoClient) ListPods() (*data.PodList, error)   { return f.pods, nil }
+
+func clusterNode(host, ip string, unschedulable bool, labels map[string]string) data.Node {
+	n := data.Node{}
+	n.Name = host
+	n.Labels = map[string]string{
+		"kismatic/host":          host,
+		"kismatic/cni-provider":  "weave",
+		"kubernetes.io/hostname": host,
+	}
+	for k, v := range labels {
+		n.Labels[k] = v
+	}
+	n.Spec.Unschedulable = unschedulable
+	n.Status.Addresses = []data.NodeAddress{{Type: data.NodeInternalIP, Address: ip}}
+	return n
+}
+
+func kubeSystemPod(name, component string, command ...string) data.Pod {
+	p := data.Pod{}
+	p.Name = name
+	p.Namespace = "kube-system"
+	p.Labels = map[string]string{"component": component}
+	p.Spec.Containers = []data.Container{{Name: component, Command: command}}
+	return p
+}
+
+func TestBuildPlanFromCluster(t *testing.T) {
+	client := fakeClusterInfoClient{
+		nodes: &data.NodeList{
+			Items: []data.Node{
+				clusterNode("master01", "10.0.0.1", true, map[string]string{"node-role.kubernetes.io/master": ""}),
+				clusterNode("worker01", "10.0.0.2", false, map[string]string{"team": "blue"}),
+				clusterNode("ingress01", "10.0.0.3", true, map[string]string{"kismatic/ingress": "true"}),
+				clusterNode("gpu01", "10.0.0.4", false, map[string]string{"kismatic/worker-pool": "gpu", "kismatic/instance-type": "p2.xlarge"}),
+			},
+		},
+		pods: &data.PodList{
+			Items: []data.Pod{
+				kubeSystemPod("kube-apiserver-master01", "kube-apiserver", "kube-apiserver", "--etcd-servers=https://master01:2379,https://etcd02:2379", "--service-cluster-ip-range=10.20.0.0/16"),
+				kubeSystemPod("kube-controller-manager-master01", "kube-controller-manager", "kube-controller-manager", "--cluster-cidr=10.10.0.0/16", "--cluster-name=prod"),
+				kubeSystemPod("kube-dns-1234", "kube-dns"),
+			},
+		},
+	}
+	p, warnings, err := BuildPlanFromCluster(client, "https://cluster.example.com:6443")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warnings) == 0 {
+		t.Errorf("expected warnings, but didn't get any")
+	}
+	if p.Cluster.Name != "prod" {
+		t.Errorf("expected cluster name %q, got %q", "prod", p.Cluster.Name)
+	}
+	if p.Cluster.Networking.PodCIDRBlock != "10.10.0.0/16" || p.Cluster.Networking.ServiceCIDRBlock != "10.20.0.0/16" {
+		t.Errorf("unexpected networking configuration: %+v", p.Cluster.Networking)
+	}
+	if p.Master.ExpectedCount != 1 || p.Master.Nodes[0].Host != "master01" {
+		t.Errorf("unexpected master nodes: %+v", p.Master.Nodes)
+	}
+	if p.Worker.ExpectedCount != 1 || p.Worker.Nodes[0].Host != "worker01" {
+		t.Errorf("unexpected worker nodes: %+v", p.Worker.Nodes)
+	}
+	if p.Worker.Nodes[0].Labels["team"] != "blue" || len(p.Worker.Nodes[0].Labels) != 1 {
+		t.Errorf("unexpected worker labels: %v", p.Worker.Nodes[0].Labels)
+	}
+	if len(p.WorkerPools) != 1 || p.WorkerPools[0].Name != "gpu" || p.WorkerPools[0].InstanceType != "p2.xlarge" || p.WorkerPools[0].ExpectedCount != 1 || p.WorkerPools[0].Nodes[0].Host != "gpu01" {
+		t.Errorf("unexpected worker pools: %+v", p.WorkerPools)
+	}
+	if p.Ingress.ExpectedCount != 1 || p.Ingress.Nodes[0].Host != "ingress01" {
+		t.Errorf("unexpected ingress nodes: %+v", p.Ingress.Nodes)
+	}
+	if p.Etcd.ExpectedCount != 2 || p.Etcd.Nodes[0].IP != "10.0.0.1" || p.Etcd.Nodes[1].Host != "etcd02" {
+		t.Errorf("unexpected etcd nodes: %+v", p.Etcd.Nodes)
+	}
+	if p.Master.LoadBalancedFQDN != "cluster.example.com" || p.Master.LoadBalancedShortName != "cluster" {
+		t.Errorf("unexpected load balanced names: %q, %q", p.Master.LoadBalancedFQDN, p.Master.LoadBalancedShortName)
+	}
+	if p.AddOns.CNI.Provider != "weave" {
+		t.Errorf("expected CNI provider %q, got %q", "weave", p.AddOns.CNI.Provider)
+	}
+	if p.AddOns.DNS.Disable || p.AddOns.DNS.Provider != "kubedns" {
+		t.Errorf("expected the kubedns DNS add-on to be enabled, got %+v", p.AddOns.DNS)
+	}
+	if !p.AddOns.Dashboard.Disable {
+		t.Errorf("expected dashboard add-on to be disabled")
+	}
+}
+
+func TestBuildPlanFromClusterNoNodes(t *testing.T) {
+	client := fakeClusterInfoClient{nodes: &data.NodeList{}, pods: &data.PodList{}}
+	if _, _, err := BuildPlanFromCluster(client, "https://10.0.0.1:6443"); err == nil {
+		t.Errorf("expected an error for a cluster without nodes, but didn't get one")
+	}
+}
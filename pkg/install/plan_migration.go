@@ -0,0 +1,79 @@
+package install
+
+import "fmt"
+
+// planAPIVersion is the version of the plan file schema supported by this
+// version of KET. It must be updated, and a migration must be added, whenever
+// a field of the plan file is renamed or moved.
+const planAPIVersion = "v1"
+
+// A planMigration upgrades a plan from one schema version to the next. The
+// migration returns a human-readable description of every change it made to
+// the plan.
+type planMigration struct {
+	from    string
+	to      string
+	migrate func(p *Plan) []string
+}
+
+// planMigrations is the ordered list of migrations that are run when reading
+// a plan file that was written by an older version of KET.
+var planMigrations = []planMigration{
+	{
+		// plan files written before the api_version field was introduced
+		from: "",
+		to:   "v1",
+		migrate: func(p *Plan) []string {
+			return append(readDeprecatedFields(p), allowExistingSingleNode(p)...)
+		},
+	},
+}
+
+// allowExistingSingleNode sets allow_single_node on plans that assign all
+// the node roles to a single machine, as these were valid before the field
+// was introduced.
+func allowExistingSingleNode(p *Plan) []string {
+	if !p.isSingleNode() || p.Cluster.AllowSingleNode {
+		return nil
+	}
+	p.Cluster.AllowSingleNode = true
+	return []string{"set cluster.allow_single_node to true, as all the node roles are assigned to a single node"}
+}
+
+// migratePlan upgrades the plan to the current schema version. It returns
+// the list of changes that were made to the plan, or an error if the plan's
+// schema version is not known to this version of KET. The deprecated fields
+// are read from plans of any version, as they are still accepted.
+func migratePlan(p *Plan) ([]string, error) {
+	var changes []string
+	if p.APIVersion == planAPIVersion {
+		for _, c := range readDeprecatedFields(p) {
+			changes = append(changes, fmt.Sprintf("%s: %s", planAPIVersion, c))
+		}
+	}
+	for p.APIVersion != planAPIVersion {
+		var found bool
+		for _, m := range planMigrations {
+			if m.from != p.APIVersion {
+				continue
+			}
+			for _, c := range m.migrate(p) {
+				changes = append(changes, fmt.Sprintf("%s -> %s: %s", displayAPIVersion(m.from), m.to, c))
+			}
+			p.APIVersion = m.to
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("plan file api_version %q is not supported by this version of KET (supported: %q)", p.APIVersion, planAPIVersion)
+		}
+	}
+	return changes, nil
+}
+
+func displayAPIVersion(v string) string {
+	if v == "" {
+		return "unversioned"
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package install
+
+import "testing"
+
+func TestMigratePlanUnversioned(t *testing.T) {
+	b := true
+	p := &Plan{}
+	p.Cluster.AllowPackageInstallation = &b
+	p.DockerRegistry.Address = "10.0.0.1"
+	p.DockerRegistry.Port = 5000
+
+	changes, err := migratePlan(p)
+	if err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if p.APIVersion != planAPIVersion {
+		t.Errorf("expected api_version to be %q, but got %q", planAPIVersion, p.APIVersion)
+	}
+	if len(changes) != 2 {
+		t.Errorf("expected 2 changes, but got %d: %v", len(changes), changes)
+	}
+	if p.Cluster.DisablePackageInstallation {
+		t.Errorf("expected cluster.disable_package_installation to be read from cluster.allow_package_installation")
+	}
+	if p.DockerRegistry.Server != "10.0.0.1:5000" {
+		t.Errorf("expected docker_registry.server to be %q, but got %q", "10.0.0.1:5000", p.DockerRegistry.Server)
+	}
+}
+
+func TestMigratePlanCurrentVersion(t *testing.T) {
+	b := false
+	p := &Plan{APIVersion: planAPIVersion}
+	p.Cluster.AllowPackageInstallation = &b
+
+	changes, err := migratePlan(p)
+	if err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("expected 1 change, but got %d: %v", len(changes), changes)
+	}
+	if !p.Cluster.DisablePackageInstallation {
+		t.Errorf("expected the deprecated fields to be read from a plan with the current api_version")
+	}
+
+	p = &Plan{APIVersion: planAPIVersion}
+	if changes, err = migratePlan(p); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, but got %v", changes)
+	}
+}
+
+func TestMigratePlanUnknownVersion(t *testing.T) {
+	p := &Plan{APIVersion: "v1000"}
+	if _, err := migratePlan(p); err == nil {
+		t.Errorf("expected an error migrating a plan with an unknown api_version, but didn't get one")
+	}
+}
+
+func TestMigratePlanUnversionedSingleNode(t *testing.T) {
+	n := Node{Host: "node1", IP: "10.0.0.1"}
+	p := &Plan{}
+	p.Etcd.Nodes = []Node{n}
+	p.Master.Nodes = []Node{n}
+	p.Worker.Nodes = []Node{n}
+
+	changes, err := migratePlan(p)
+	if err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if !p.Cluster.AllowSingleNode {
+		t.Errorf("expected cluster.allow_single_node to be set on an existing single node plan")
+	}
+	if len(changes) != 1 {
+		t.Errorf("expected 1 change, but got %d: %v", len(changes), changes)
+	}
+
+	p = &Plan{APIVersion: planAPIVersion}
+	p.Etcd.Nodes = []Node{n}
+	p.Master.Nodes = []Node{n}
+	p.Worker.Nodes = []Node{n}
+	if _, err = migratePlan(p); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if p.Cluster.AllowSingleNode {
+		t.Errorf("cluster.allow_single_node should not be set on a plan with the current api_version")
+	}
+}
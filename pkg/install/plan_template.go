@@ -0,0 +1,261 @@
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// planVarRE matches variable references of the form ${NAME}. A reference
+// prefixed with an additional '$' (e.g. $${NAME}) is escaped, and is rendered
+// as the literal ${NAME}.
+var planVarRE = regexp.MustCompile(`\$(\$?)\{([^}]*)\}`)
+
+var planVarNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// readPlanValues reads the variable values from the YAML file. The file
+// must contain a flat map of variable names to values.
+func readPlanValues(file string) (map[string]string, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read values file: %v", err)
+	}
+	values := map[string]string{}
+	if err = yaml.Unmarshal(d, &values); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal values file: %v", err)
+	}
+	return values, nil
+}
+
+// renderPlanTemplate replaces all variable references in the plan with
+// their values. Values are looked up in the values map first, and then in
+// the environment. An error is returned if any of the referenced variables
+// is undefined. References in comments are left as they are, and values are
+// quoted when they could otherwise change the structure of the plan, which is
+// in the given format.
+func renderPlanTemplate(d []byte, format PlanFormat, values map[string]string) ([]byte, error) {
+	var invalid, unquoted []string
+	undefined := map[string]bool{}
+	rendered := expandPlanVars(d, format, func(ref planVarRef) string {
+		if !planVarNameRE.MatchString(ref.name) {
+			invalid = append(invalid, ref.raw)
+			return ref.raw
+		}
+		v, ok := values[ref.name]
+		if !ok {
+			v, ok = os.LookupEnv(ref.name)
+		}
+		if !ok {
+			undefined[ref.name] = true
+			return ref.raw
+		}
+		switch {
+		case ref.quote == '"':
+			q := strconv.Quote(v)
+			return q[1 : len(q)-1]
+		case ref.quote == '\'':
+			return strings.Replace(v, "'", "''", -1)
+		case v == "" || planPlainValueRE.MatchString(v):
+			return v
+		case ref.wholeScalar:
+			return strconv.Quote(v)
+		}
+		unquoted = append(unquoted, ref.name)
+		return ref.raw
+	})
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("invalid variable references: %s", strings.Join(invalid, ", "))
+	}
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for n := range undefined {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(names, ", "))
+	}
+	if len(unquoted) > 0 {
+		return nil, fmt.Errorf("the values of variables %s must be quoted in the plan file", strings.Join(unquoted, ", "))
+	}
+	return rendered, nil
+}
+
+// planPlainValueRE matches the values that are safe to render as part of a
+// plain (unquoted) scalar
+var planPlainValueRE = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/:@%+=~-]*$`)
+
+// planVarRef is a variable reference in the plan file
+type planVarRef struct {
+	// raw is the reference as written in the plan file, e.g. ${NAME}
+	raw  string
+	name string
+	// quote is the quote character of the scalar the reference is in, or
+	// zero if the scalar is not quoted
+	quote byte
+	// wholeScalar is true if the reference is the whole value of an
+	// unquoted scalar
+	wholeScalar bool
+}
+
+// expandPlanVars replaces the variable references of the plan file, which is
+// in the given format, with the text returned by expand. Escaped references
+// are rendered as the literal reference, and comments are left as they are.
+func expandPlanVars(d []byte, format PlanFormat, expand func(planVarRef) string) []byte {
+	code := string(d)
+	var out bytes.Buffer
+	s := planScanner{format: format}
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		before := code[s.lineStart:i]
+		switch {
+		case c == '\n':
+			s.lineStart = i + 1
+		case s.quote == '"' && c == '\\' && i+1 < len(code):
+			out.WriteString(code[i : i+2])
+			i++
+			continue
+		case s.quote == '\'' && c == '\'' && i+1 < len(code) && code[i+1] == '\'':
+			// an escaped quote of a single quoted scalar
+			out.WriteString("''")
+			i++
+			continue
+		case s.quote != 0 && c == s.quote:
+			s.quote = 0
+		case s.quote == 0 && s.commentStarts(code[i:], before):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				end = len(code) - i
+			}
+			out.WriteString(code[i : i+end])
+			i += end - 1
+			continue
+		case s.quote == 0 && s.quoteStarts(c, before):
+			s.quote = c
+		case s.quote == 0 && (c == '[' || c == '{') && (s.flowDepth > 0 || startsPlanScalar(before)):
+			s.flowDepth++
+		case s.quote == 0 && (c == ']' || c == '}') && s.flowDepth > 0:
+			s.flowDepth--
+		case c == '$':
+			m := planVarRE.FindStringSubmatchIndex(code[i:])
+			if m == nil || m[0] != 0 {
+				break
+			}
+			raw := code[i : i+m[1]]
+			if m[3] > m[2] {
+				out.WriteString(raw[1:])
+			} else {
+				ref := planVarRef{
+					raw:         raw,
+					name:        code[i+m[4] : i+m[5]],
+					quote:       s.quote,
+					wholeScalar: s.quote == 0 && s.endsPlanScalar(code[i+m[1]:]) && startsPlanScalar(before),
+				}
+				out.WriteString(expand(ref))
+			}
+			i += m[1] - 1
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.Bytes()
+}
+
+// planScanner keeps track of the state of the plan file as it is scanned.
+// The quotes and the flow collections can span lines.
+type planScanner struct {
+	format PlanFormat
+	// quote is the quote character of the scalar being scanned, or zero
+	quote byte
+	// flowDepth is the number of flow collections ('[...]' or '{...}') that
+	// are open
+	flowDepth int
+	// lineStart is the index of the start of the current line
+	lineStart int
+}
+
+// quoteStarts returns true if the character starts a quoted scalar. In YAML,
+// quotes only start a scalar where a scalar starts, so that the apostrophe of
+// "it's" is part of a plain scalar. Strings are always quoted in JSON and
+// HCL, which have no single quoted strings.
+func (s planScanner) quoteStarts(c byte, before string) bool {
+	if s.format != PlanFormatYAML {
+		return c == '"'
+	}
+	if c != '"' && c != '\'' {
+		return false
+	}
+	if startsPlanScalar(before) {
+		return true
+	}
+	trimmed := strings.TrimRight(before, " \t")
+	return s.flowDepth > 0 && trimmed != "" && strings.ContainsRune("[{,:", rune(trimmed[len(trimmed)-1]))
+}
+
+// commentStarts returns true if a comment starts at the beginning of the
+// text. Comments start with '#' at the start of the line or after a space,
+// and HCL comments may also start with '//'.
+func (s planScanner) commentStarts(text, before string) bool {
+	if before != "" {
+		if last := before[len(before)-1]; last != ' ' && last != '\t' {
+			return false
+		}
+	}
+	return strings.HasPrefix(text, "#") || (s.format == PlanFormatHCL && strings.HasPrefix(text, "//"))
+}
+
+// endsPlanScalar returns true if nothing but whitespace or a comment follows
+// on the line, which is the text after a reference
+func (s planScanner) endsPlanScalar(after string) bool {
+	if end := strings.IndexByte(after, '\n'); end >= 0 {
+		after = after[:end]
+	}
+	trimmed := strings.TrimLeft(after, " \t\r")
+	return trimmed == "" || (len(trimmed) < len(after) && s.commentStarts(trimmed, " "))
+}
+
+// startsPlanScalar returns true if a scalar starts after the given text,
+// which is the case after a key, a list item indicator, or at the start of
+// the line
+func startsPlanScalar(before string) bool {
+	if before == "" {
+		return true
+	}
+	if last := before[len(before)-1]; last != ' ' && last != '\t' {
+		return false
+	}
+	before = strings.TrimSpace(before)
+	return before == "" || before == "-" || strings.HasSuffix(before, " -") ||
+		strings.HasSuffix(before, ":") || strings.HasSuffix(before, "=")
+}
+
+// PlanVariables returns the names of the variables referenced in the plan,
+// in the order they first appear in the plan file
+func PlanVariables(p *Plan) ([]string, error) {
+	d, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling plan: %v", err)
+	}
+	return planVariables(d, PlanFormatYAML), nil
+}
+
+// planVariables returns the names of the variables referenced in the
+// plan file, which is in the given format, in the order they first appear
+func planVariables(d []byte, format PlanFormat) []string {
+	seen := map[string]bool{}
+	var names []string
+	expandPlanVars(d, format, func(ref planVarRef) string {
+		if !seen[ref.name] {
+			seen[ref.name] = true
+			names = append(names, ref.name)
+		}
+		return ref.raw
+	})
+	return names
+}
@@ -0,0 +1,173 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderPlanTemplate(t *testing.T) {
+	os.Setenv("KET_TEST_ENV_VAR", "from-env")
+	defer os.Unsetenv("KET_TEST_ENV_VAR")
+	values := map[string]string{
+		"CLUSTER_NAME": "dev",
+		"POD_CIDR":     "172.16.0.0/16",
+		"PASSWORD":     "it's a \"secret\": #1",
+	}
+	tests := []struct {
+		in          string
+		format      PlanFormat
+		expected    string
+		shouldError bool
+	}{
+		{
+			in:       "name: kubernetes",
+			expected: "name: kubernetes",
+		},
+		{
+			in:       "name: ${CLUSTER_NAME}",
+			expected: "name: dev",
+		},
+		{
+			in:       "name: ${CLUSTER_NAME}-${POD_CIDR}",
+			expected: "name: dev-172.16.0.0/16",
+		},
+		{
+			in:       "name: ${KET_TEST_ENV_VAR}",
+			expected: "name: from-env",
+		},
+		{
+			in:       "password: $${CLUSTER_NAME}",
+			expected: "password: ${CLUSTER_NAME}",
+		},
+		{
+			in:       "password: a$b$$c",
+			expected: "password: a$b$$c",
+		},
+		{
+			in:       "name: dev # set to ${UNDEFINED_VAR}",
+			expected: "name: dev # set to ${UNDEFINED_VAR}",
+		},
+		{
+			in:       "# password: ${UNDEFINED_VAR}\nname: ${CLUSTER_NAME}",
+			expected: "# password: ${UNDEFINED_VAR}\nname: dev",
+		},
+		{
+			in:       "password: ${PASSWORD}",
+			expected: `password: "it's a \"secret\": #1"`,
+		},
+		{
+			in:       "password: \"${PASSWORD}\"",
+			expected: `password: "it's a \"secret\": #1"`,
+		},
+		{
+			in:       "password: '${PASSWORD}'",
+			expected: `password: 'it''s a "secret": #1'`,
+		},
+		{
+			in:       "name: it's ${CLUSTER_NAME} # set to ${UNDEFINED_VAR}",
+			expected: "name: it's dev # set to ${UNDEFINED_VAR}",
+		},
+		{
+			in:       "name: 'it''s ${CLUSTER_NAME}' # set to ${UNDEFINED_VAR}",
+			expected: "name: 'it''s dev' # set to ${UNDEFINED_VAR}",
+		},
+		{
+			in:       "description: \"first line\n  ${PASSWORD} # not a comment\"\nname: ${CLUSTER_NAME}",
+			expected: "description: \"first line\n  it's a \\\"secret\\\": #1 # not a comment\"\nname: dev",
+		},
+		{
+			in:       "description: 'first line\n  ${PASSWORD}'\nname: ${CLUSTER_NAME} # ${UNDEFINED_VAR}",
+			expected: "description: 'first line\n  it''s a \"secret\": #1'\nname: dev # ${UNDEFINED_VAR}",
+		},
+		{
+			in:       "names: ['${CLUSTER_NAME}','${PASSWORD}']",
+			expected: "names: ['dev','it''s a \"secret\": #1']",
+		},
+		{
+			in:       "path: //${CLUSTER_NAME}/share",
+			expected: "path: //dev/share",
+		},
+		{
+			in:       "name = \"${CLUSTER_NAME}\" // set to ${UNDEFINED_VAR}\n# ${UNDEFINED_VAR}",
+			format:   PlanFormatHCL,
+			expected: "name = \"dev\" // set to ${UNDEFINED_VAR}\n# ${UNDEFINED_VAR}",
+		},
+		{
+			in:       "url = \"http://${CLUSTER_NAME}\" // it's ${UNDEFINED_VAR}",
+			format:   PlanFormatHCL,
+			expected: "url = \"http://dev\" // it's ${UNDEFINED_VAR}",
+		},
+		{
+			in:       "{\"name\": \"it's ${CLUSTER_NAME}\", \"password\":\"${PASSWORD}\"}",
+			format:   PlanFormatJSON,
+			expected: "{\"name\": \"it's dev\", \"password\":\"it's a \\\"secret\\\": #1\"}",
+		},
+		{
+			in:          "password: prefix-${PASSWORD}",
+			shouldError: true,
+		},
+		{
+			in:          "name: ${UNDEFINED_VAR}",
+			shouldError: true,
+		},
+		{
+			in:          "name: ${CLUSTER_NAME}-${UNDEFINED_VAR}",
+			shouldError: true,
+		},
+		{
+			in:          "name: ${}",
+			shouldError: true,
+		},
+		{
+			in:          "name: ${1NAME}",
+			shouldError: true,
+		},
+	}
+	for _, test := range tests {
+		if test.format == "" {
+			test.format = PlanFormatYAML
+		}
+		out, err := renderPlanTemplate([]byte(test.in), test.format, values)
+		if err != nil && !test.shouldError {
+			t.Errorf("unexpected error rendering %q: %v", test.in, err)
+		}
+		if err == nil && test.shouldError {
+			t.Errorf("expected an error rendering %q, but didn't get one", test.in)
+		}
+		if err == nil && string(out) != test.expected {
+			t.Errorf("expected %q, but got %q", test.expected, string(out))
+		}
+	}
+}
+
+func TestReadPlanWithValuesFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-read-plan-values-file")
+	if err != nil {
+		t.Fatalf("error creating tmp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	planFile := filepath.Join(tmpDir, "kismatic-cluster.yaml")
+	valuesFile := filepath.Join(tmpDir, "values.yaml")
+	if err = ioutil.WriteFile(planFile, []byte("cluster:\n  name: ${CLUSTER_NAME}\n"), 0666); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	if err = ioutil.WriteFile(valuesFile, []byte("CLUSTER_NAME: stage\n"), 0666); err != nil {
+		t.Fatalf("error writing values file: %v", err)
+	}
+
+	planner := FilePlanner{File: planFile, ValuesFile: valuesFile}
+	plan, err := planner.Read()
+	if err != nil {
+		t.Fatalf("error reading plan file: %v", err)
+	}
+	if plan.Cluster.Name != "stage" {
+		t.Errorf("expected cluster name to be %q, but got %q", "stage", plan.Cluster.Name)
+	}
+
+	planner = FilePlanner{File: planFile}
+	if _, err = planner.Read(); err == nil {
+		t.Errorf("expected an error reading plan with undefined variables, but didn't get one")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"testing"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestWritePlanTemplate(t *testing.T) {
@@ -50,7 +52,7 @@ func TestWritePlanTemplate(t *testing.T) {
 			t.Fatalf("error creating temp dir: %v", err)
 		}
 		file := filepath.Join(tmp, "kismatic-cluster.yaml")
-		fp := &FilePlanner{file}
+		fp := &FilePlanner{File: file}
 		if err = WritePlanTemplate(test.template, fp); err != nil {
 			t.Fatalf("error writing plan template: %v", err)
 		}
@@ -122,11 +124,96 @@ func TestReadWithNil(t *testing.T) {
 		t.Errorf("Expected add_ons.heapster.options.heapster.service_type to equal 'influxdb:http://heapster-influxdb.kube-system.svc:8086', instead got %s", p.AddOns.HeapsterMonitoring.Options.Heapster.Sink)
 	}
 
+	// existing plan files don't get the metrics server when they are read,
+	// only the plan files generated by "install plan" enable it
+	if p.AddOns.MetricsServer.Enabled {
+		t.Errorf("Expected add_ons.metrics_server.enabled to be false")
+	}
+
 	if p.Cluster.Certificates.CAExpiry != defaultCAExpiry {
 		t.Errorf("expected ca cert expiry to be %s, but got %s", defaultCAExpiry, p.Cluster.Certificates.CAExpiry)
 	}
 }
 
+func TestSetDefaultsStackedEtcd(t *testing.T) {
+	masters := []Node{{Host: "master1", IP: "10.0.0.1"}, {Host: "master2", IP: "10.0.0.2", InternalIP: "192.168.0.2"}}
+	p := &Plan{}
+	p.Cluster.EtcdTopology = etcdTopologyStacked
+	p.Master.Nodes = masters
+	setDefaults(p)
+	if p.Etcd.ExpectedCount != 2 || len(p.Etcd.Nodes) != 2 {
+		t.Fatalf("expected the master nodes to be used as etcd nodes, but got %+v", p.Etcd)
+	}
+	for i, n := range p.Etcd.Nodes {
+		if !n.Equal(masters[i]) {
+			t.Errorf("expected etcd node %+v, but got %+v", masters[i], n)
+		}
+	}
+
+	// etcd nodes provided by the user are kept
+	p = &Plan{}
+	p.Cluster.EtcdTopology = etcdTopologyStacked
+	p.Master.Nodes = masters
+	p.Etcd = NodeGroup{ExpectedCount: 1, Nodes: masters[:1]}
+	setDefaults(p)
+	if len(p.Etcd.Nodes) != 1 {
+		t.Errorf("expected 1 etcd node, but got %d", len(p.Etcd.Nodes))
+	}
+
+	p = &Plan{}
+	p.Master.Nodes = masters
+	setDefaults(p)
+	if p.Cluster.EtcdTopology != etcdTopologyExternal || len(p.Etcd.Nodes) != 0 {
+		t.Errorf("expected an external etcd topology without etcd nodes, but got %q with %d nodes", p.Cluster.EtcdTopology, len(p.Etcd.Nodes))
+	}
+}
+
+func TestWriteStackedEtcd(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-write-stacked-etcd")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fp := &FilePlanner{File: filepath.Join(tmpDir, "kismatic-cluster.yaml")}
+	p := &Plan{}
+	p.Cluster.EtcdTopology = etcdTopologyStacked
+	p.Master.ExpectedCount = 1
+	p.Master.Nodes = []Node{{Host: "master1", IP: "10.0.0.1"}}
+	if err = fp.Write(p); err != nil {
+		t.Fatalf("error writing plan: %v", err)
+	}
+
+	// the etcd nodes follow the master nodes once the plan is read again
+	if p, err = fp.Read(); err != nil {
+		t.Fatalf("error reading plan: %v", err)
+	}
+	p.Master.Nodes[0].IP = "10.0.0.2"
+	if err = fp.Write(p); err != nil {
+		t.Fatalf("error writing plan: %v", err)
+	}
+	if p, err = fp.Read(); err != nil {
+		t.Fatalf("error reading plan: %v", err)
+	}
+	if len(p.Etcd.Nodes) != 1 || p.Etcd.Nodes[0].IP != "10.0.0.2" {
+		t.Errorf("expected the etcd nodes to be derived from the master nodes, but got %+v", p.Etcd)
+	}
+
+	if err = fp.Write(p.WithEtcdNodes()); err != nil {
+		t.Fatalf("error writing plan: %v", err)
+	}
+	raw := &Plan{}
+	d, err := ioutil.ReadFile(fp.File)
+	if err != nil {
+		t.Fatalf("error reading plan file: %v", err)
+	}
+	if err = yaml.Unmarshal(d, raw); err != nil {
+		t.Fatalf("error unmarshaling plan file: %v", err)
+	}
+	if len(raw.Etcd.Nodes) != 1 {
+		t.Errorf("expected the etcd nodes to be written, but got %+v", raw.Etcd)
+	}
+}
+
 func TestReadDeprecatedDashboard(t *testing.T) {
 	tmpDir, err := ioutil.TempDir("", "test-read-deprecated-dashboard")
 	if err != nil {
@@ -193,7 +280,7 @@ func TestReadDeprecatedDashboard(t *testing.T) {
 			t.Fatalf("error writing plan file")
 		}
 
-		planner := FilePlanner{file}
+		planner := FilePlanner{File: file}
 		plan, err := planner.Read()
 		if err != nil {
 			t.Fatalf("error reading plan file")
@@ -1,8 +1,10 @@
 package install
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"strings"
 
 	"github.com/apprenda/kismatic/pkg/ssh"
@@ -23,6 +25,53 @@ func cniProviders() []string {
 	return []string{cniProviderCalico, cniProviderContiv, cniProviderWeave, cniProviderCustom}
 }
 
+const (
+	dnsProviderKubeDNS = "kubedns"
+	dnsProviderCoreDNS = "coredns"
+)
+
+func dnsProviders() []string {
+	return []string{dnsProviderKubeDNS, dnsProviderCoreDNS}
+}
+
+const (
+	auditLevelMetadata        = "Metadata"
+	auditLevelRequest         = "Request"
+	auditLevelRequestResponse = "RequestResponse"
+	auditSinkFile             = "file"
+	auditSinkWebhook          = "webhook"
+)
+
+func auditLevels() []string {
+	return []string{auditLevelMetadata, auditLevelRequest, auditLevelRequestResponse}
+}
+
+func auditSinks() []string {
+	return []string{auditSinkFile, auditSinkWebhook}
+}
+
+func clusterAutoscalerCloudProviders() []string {
+	return []string{"aws"}
+}
+
+const (
+	externalDNSProviderAWS    = "aws"
+	externalDNSProviderGoogle = "google"
+)
+
+func externalDNSProviders() []string {
+	return []string{externalDNSProviderAWS, externalDNSProviderGoogle}
+}
+
+const (
+	dashboardIngressAuthClientCertificate = "client_certificate"
+	dashboardIngressAuthOIDC              = "oidc"
+)
+
+func dashboardIngressAuths() []string {
+	return []string{dashboardIngressAuthClientCertificate, dashboardIngressAuthOIDC}
+}
+
 func calicoMode() []string {
 	return []string{"overlay", "routed"}
 }
@@ -39,8 +88,49 @@ func cloudProviders() []string {
 	return []string{"aws", "azure", "cloudstack", "fake", "gce", "mesos", "openstack", "ovirt", "photon", "rackspace", "vsphere"}
 }
 
+const (
+	etcdDataVolumeXFS    = "xfs"
+	etcdDataVolumeExt4   = "ext4"
+	etcdTopologyExternal = "external"
+	etcdTopologyStacked  = "stacked"
+)
+
+func etcdTopologies() []string {
+	return []string{etcdTopologyExternal, etcdTopologyStacked}
+}
+
+func etcdDataVolumeFilesystems() []string {
+	return []string{etcdDataVolumeXFS, etcdDataVolumeExt4}
+}
+
+const (
+	archAMD64 = "amd64"
+	archARM64 = "arm64"
+)
+
+func architectures() []string {
+	return []string{archAMD64, archARM64}
+}
+
+const (
+	selinuxEnforcing  = "enforcing"
+	selinuxPermissive = "permissive"
+)
+
+func selinuxModes() []string {
+	return []string{selinuxEnforcing, selinuxPermissive}
+}
+
+func taintEffects() []string {
+	return []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+}
+
 // Plan is the installation plan that the user intends to execute
 type Plan struct {
+	// Version of the plan file schema. Plan files written by older versions
+	// of KET are upgraded to the current schema when they are read.
+	// +default=v1
+	APIVersion string `yaml:"api_version"`
 	// Kubernetes cluster configuration
 	// +required
 	Cluster Cluster
@@ -59,23 +149,58 @@ type Plan struct {
 	// Master nodes of the cluster
 	// +required
 	Master MasterNodeGroup
-	// Worker nodes of the cluster
+	// Worker nodes of the cluster.
+	// Not required when worker pools are defined.
 	// +required
 	Worker NodeGroup
+	// Named pools of worker nodes. The nodes of a pool are labeled with
+	// kismatic/worker-pool=<name>, and are installed in addition to the nodes
+	// of the worker group.
+	WorkerPools []WorkerPool `yaml:"worker_pools,omitempty"`
 	// Ingress nodes of the cluster
 	Ingress OptionalNodeGroup
 	// Storage nodes of the cluster.
 	Storage OptionalNodeGroup
 	// NFS volumes of the cluster.
 	NFS NFS
+	// Resources that are created on the cluster after it is installed.
+	PostInstall PostInstall `yaml:"post_install,omitempty"`
+
+	// changes made to the plan when upgrading it to the current schema
+	migrations []string
+	// whether the etcd nodes were derived from the master nodes when the
+	// plan was read, in which case they are not written to the plan file
+	etcdFromMasters bool
+}
+
+// Migrations returns a description of the changes that were made to the plan
+// when upgrading it from an older schema version.
+func (p Plan) Migrations() []string {
+	return p.migrations
+}
+
+// WithEtcdNodes returns a copy of the plan whose etcd nodes are written to the
+// plan file, even when they are derived from the master nodes. This is used
+// for versions of KET that don't support the stacked etcd topology.
+func (p Plan) WithEtcdNodes() *Plan {
+	p.etcdFromMasters = false
+	return &p
 }
 
 // Cluster describes a Kubernetes cluster
 type Cluster struct {
 	// Name of the cluster to be used when generating assets that require a
 	// cluster name, such as kubeconfig files and certificates.
+	// Must be a DNS-1123 label: lower case alphanumeric characters or '-',
+	// up to 63 characters. `default`, `localhost`, `kube-system`,
+	// `kube-public` and `kube-node-lease` are reserved. The plan files of
+	// clusters that are already installed, when upgrading or adding workers,
+	// only get a warning, as clusters cannot be renamed.
 	// +required
 	Name string
+	// Labels to group the cluster with other clusters, such as `env: dev`.
+	// Batch operations select the clusters they run on by their labels.
+	Labels map[string]string `yaml:"labels,omitempty"`
 	// The password for the admin user. This is mainly used to access the Kubernetes Dashboard.
 	// +required
 	AdminPassword string `yaml:"admin_password"`
@@ -92,6 +217,41 @@ type Cluster struct {
 	// registry are required for installation.
 	// +default=false
 	DisconnectedInstallation bool `yaml:"disconnected_installation"`
+	// Whether the nodes were created from a machine image that was baked with
+	// `kismatic image bake`. The packages and container images are already
+	// on the nodes, so KET does not install the packages, and instead verifies
+	// that they are installed.
+	// +default=false
+	BakedImage bool `yaml:"baked_image,omitempty"`
+	// Where the etcd cluster runs. When set to `stacked`, etcd is co-located
+	// on the master nodes, and the etcd node group can be left empty.
+	// +default=external
+	// +options=external,stacked
+	EtcdTopology string `yaml:"etcd_topology"`
+	// Whether all the node roles can be assigned to a single machine.
+	// This layout has no redundancy, and is only suitable for demos and
+	// test environments.
+	// +default=false
+	AllowSingleNode bool `yaml:"allow_single_node,omitempty"`
+	// Whether KET should open the ports required by each node's roles in the
+	// node's firewall (firewalld or ufw). When false, the firewall must be
+	// disabled or configured by the operator.
+	// +default=false
+	ManageFirewall bool `yaml:"manage_firewall,omitempty"`
+	// The SELinux mode of the nodes that have SELinux enabled.
+	// When `enforcing`, KET installs the SELinux policies and file contexts
+	// required by Docker and the kubelet, and keeps SELinux enforcing.
+	// When `permissive`, KET switches the nodes to permissive mode.
+	// When not set, the installation fails on nodes with SELinux enforcing.
+	// +options=enforcing,permissive
+	SELinux string `yaml:"selinux,omitempty"`
+	// The hardening profile applied to the cluster during installation.
+	// When `cis`, KET configures the control plane components and kubelets
+	// following the CIS Kubernetes Benchmark, restricts the permissions of
+	// the Kubernetes files on the nodes, and writes a compliance report to
+	// the generated assets directory.
+	// +options=cis
+	HardeningProfile string `yaml:"hardening_profile,omitempty"`
 	// The Networking configuration for the cluster.
 	Networking NetworkConfig
 	// The Certificates configuration for the cluster.
@@ -108,8 +268,36 @@ type Cluster struct {
 	KubeProxyOptions KubeProxyOptions `yaml:"kube_proxy"`
 	// Kubelet configuration applied to all nodes.
 	KubeletOptions KubeletOptions `yaml:"kubelet"`
+	// Automatic rotation of the certificates of the kubelets.
+	KubeletCertificateRotation KubeletCertificateRotation `yaml:"kubelet_certificate_rotation,omitempty"`
 	// The CloudProvider configuration for the cluster.
 	CloudProvider CloudProvider `yaml:"cloud_provider"`
+	// Scheduled backups of the Kubernetes etcd cluster.
+	EtcdBackup EtcdBackup `yaml:"etcd_backup,omitempty"`
+	// A dedicated disk of the etcd nodes for the etcd data.
+	EtcdDataVolume EtcdDataVolume `yaml:"etcd_data_volume,omitempty"`
+	// Audit logging of the requests made to the Kubernetes API server.
+	Audit AuditLog `yaml:"audit,omitempty"`
+	// External service the authentication and authorization decisions of
+	// the Kubernetes API server are delegated to.
+	AuthWebhook AuthWebhook `yaml:"auth_webhook,omitempty"`
+	// Network policies and pod security settings applied to the cluster, for
+	// a cluster that is secure by default.
+	DefaultPolicies DefaultPolicies `yaml:"default_policies,omitempty"`
+	// Clock synchronization of the nodes with NTP servers.
+	TimeSync TimeSync `yaml:"time_sync,omitempty"`
+	// Kernel parameters applied and persisted on all nodes.
+	Sysctl NodeSysctl `yaml:"sysctl,omitempty"`
+	// Expiration of ephemeral clusters, such as development and test clusters.
+	Expiration ClusterExpiration `yaml:"expiration,omitempty"`
+	// External secret manager where the private keys and the kubeconfig
+	// generated for the cluster are kept, instead of the generated assets directory.
+	SecretsStore ClusterSecretsStore `yaml:"secrets_store,omitempty"`
+	// Object storage where the generated assets and the logs of the runs of
+	// kismatic are kept, so that the cluster can be managed from any machine.
+	AssetsStorage ClusterAssetsStorage `yaml:"assets_storage,omitempty"`
+	// Message broker the lifecycle events of the cluster are published to.
+	Events ClusterEvents `yaml:"events,omitempty"`
 }
 
 type APIServerOptions struct {
@@ -144,6 +332,79 @@ type KubeletOptions struct {
 	// Listing of option overrides that are to be applied to the Kubelet configurations.
 	// This is an advanced feature that can prevent the Kubelet from starting up if invalid configuration is provided.
 	Overrides map[string]string `yaml:"option_overrides"`
+	// Thresholds of the disk usage at which the kubelet deletes unused images.
+	GarbageCollection KubeletGarbageCollection `yaml:"garbage_collection,omitempty"`
+	// Thresholds of the available resources at which the kubelet evicts pods
+	// from the node.
+	Eviction KubeletEviction `yaml:"eviction,omitempty"`
+}
+
+// TimeSync configures the synchronization of the clocks of the nodes. Clock
+// drift breaks the validation of TLS certificates and the etcd cluster.
+type TimeSync struct {
+	// Whether chrony should be installed and configured on all nodes.
+	// +default=false
+	Enabled bool
+	// The NTP servers the nodes synchronize their clocks with, such as
+	// `0.pool.ntp.org` or `10.0.0.1`.
+	// +required
+	Servers []string `yaml:"servers,omitempty"`
+	// The maximum difference, in seconds, between the clock of a node and the
+	// clock of the machine running kismatic accepted by the pre-flight checks.
+	// The clocks are not checked when time synchronization is enabled, as
+	// chrony corrects them.
+	// +default=30
+	MaxSkewSeconds int `yaml:"max_skew_seconds,omitempty"`
+}
+
+// NodeSysctl configures the kernel parameters of the nodes
+type NodeSysctl struct {
+	// The tuning profile applied to the nodes. When `kubernetes`, the
+	// inotify limits and the maximum number of memory map areas are raised
+	// to values that suit nodes running many pods.
+	// +options=kubernetes
+	Profile string `yaml:"profile,omitempty"`
+	// Kernel parameters set on the nodes, such as `fs.file-max: 2097152`.
+	// They take precedence over the values of the profile.
+	Settings map[string]string `yaml:"settings,omitempty"`
+	// Kernel parameters set on the nodes of a role, such as `worker`. They
+	// take precedence over the settings of all the nodes. The roles of a
+	// node with several roles are applied in the order etcd, master, worker,
+	// ingress and storage.
+	RoleSettings map[string]map[string]string `yaml:"role_settings,omitempty"`
+}
+
+// KubeletGarbageCollection configures the image garbage collection of the
+// kubelet. The Kubernetes defaults are used for the thresholds that are not set.
+type KubeletGarbageCollection struct {
+	// The percent of disk usage of the image filesystem after which image
+	// garbage collection is always run. Kubernetes defaults to 85.
+	ImageGCHighThresholdPercent int `yaml:"image_gc_high_threshold_percent,omitempty"`
+	// The percent of disk usage of the image filesystem before which image
+	// garbage collection is never run, and down to which images are deleted.
+	// Kubernetes defaults to 80.
+	ImageGCLowThresholdPercent int `yaml:"image_gc_low_threshold_percent,omitempty"`
+}
+
+// KubeletEviction configures the pod eviction of the kubelet. The thresholds
+// are keyed by eviction signal, such as `memory.available`, `nodefs.available`,
+// `nodefs.inodesFree`, `imagefs.available` and `imagefs.inodesFree`, and are
+// either a quantity, such as `100Mi`, or a percentage, such as `10%`.
+type KubeletEviction struct {
+	// The thresholds that trigger the eviction of pods right away. Kubernetes
+	// defaults to `memory.available: 100Mi`, `nodefs.available: 10%`,
+	// `nodefs.inodesFree: 5%` and `imagefs.available: 15%`.
+	Hard map[string]string `yaml:"hard,omitempty"`
+	// The thresholds that trigger the eviction of pods once they are exceeded
+	// for the grace period of the signal.
+	Soft map[string]string `yaml:"soft,omitempty"`
+	// The grace period of each soft eviction threshold, such as `1m30s`.
+	SoftGracePeriod map[string]string `yaml:"soft_grace_period,omitempty"`
+	// The minimum amount of resources reclaimed by an eviction, such as `500Mi`.
+	MinimumReclaim map[string]string `yaml:"minimum_reclaim,omitempty"`
+	// The duration the kubelet waits before leaving an eviction pressure
+	// condition, such as `5m`.
+	PressureTransitionPeriod string `yaml:"pressure_transition_period,omitempty"`
 }
 
 // NetworkConfig describes the cluster's networking configuration
@@ -172,6 +433,21 @@ type NetworkConfig struct {
 	// should not go through a proxy.
 	// All nodes' 'host' and 'IPs' are always set.
 	NoProxy string `yaml:"no_proxy"`
+	// The DNS resolver configuration of the nodes, which is also used by the
+	// pods to resolve names outside of the cluster.
+	HostDNS HostDNS `yaml:"host_dns,omitempty"`
+}
+
+// HostDNS is the DNS resolver configuration of the nodes. When nameservers
+// are set, the configuration is written to a resolv.conf file used by the
+// kubelet, and to systemd-resolved on the nodes that run it.
+type HostDNS struct {
+	// The IP addresses of up to 3 nameservers.
+	Nameservers []string `yaml:"nameservers,omitempty"`
+	// Up to 6 domains that are appended to the names that are not fully qualified.
+	SearchDomains []string `yaml:"search_domains,omitempty"`
+	// The resolver options, such as `ndots:2` or `timeout:2`.
+	Options []string `yaml:"options,omitempty"`
 }
 
 // CertsConfig describes the cluster's trust and certificate configuration
@@ -199,6 +475,27 @@ type SSHConfig struct {
 	// The port number on which cluster nodes are listening for SSH connections.
 	// +required
 	Port int `yaml:"ssh_port"`
+	// The bastion host that the SSH connections to the cluster nodes are
+	// tunneled through, when the nodes are not reachable from the machine
+	// running kismatic, such as nodes in private subnets.
+	Bastion SSHBastion `yaml:"bastion,omitempty"`
+}
+
+// SSHBastion is a host that the SSH connections to the cluster nodes are
+// tunneled through
+type SSHBastion struct {
+	// The address of the bastion host. When set, the SSH connections of
+	// kismatic and Ansible to the cluster nodes are tunneled through it.
+	Host string `yaml:"host,omitempty"`
+	// The user for accessing the bastion host via SSH.
+	// When not set, the user of the cluster nodes is used.
+	User string `yaml:"user,omitempty"`
+	// The absolute path of the SSH key for accessing the bastion host.
+	// When not set, the key of the cluster nodes is used.
+	Key string `yaml:"ssh_key,omitempty"`
+	// The port number on which the bastion host is listening for SSH connections.
+	// +default=22
+	Port int `yaml:"ssh_port,omitempty"`
 }
 
 // CloudProvider controls the Kubernetes cloud providers feature
@@ -210,10 +507,275 @@ type CloudProvider struct {
 	Config string
 }
 
+// EtcdBackup configures periodic snapshots of the Kubernetes etcd cluster.
+// The snapshots are encrypted on the etcd node before being uploaded to
+// object storage.
+type EtcdBackup struct {
+	// Whether KET should schedule periodic snapshots of the etcd cluster.
+	// +default=false
+	Enabled bool
+	// When the snapshots are taken, as a systemd calendar event.
+	// +default=daily
+	Schedule string `yaml:"schedule,omitempty"`
+	// The object storage location where the snapshots are uploaded,
+	// such as `s3://bucket/prefix` or `gs://bucket/prefix`.
+	// +required
+	Destination string `yaml:"destination,omitempty"`
+	// The region of the S3 bucket.
+	Region string `yaml:"region,omitempty"`
+	// Path to the credentials used to upload the snapshots. For S3, a file
+	// with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment
+	// variables; the node's instance profile is used when not set.
+	// For GCS, a service account key file.
+	CredentialsFile string `yaml:"credentials_file,omitempty"`
+	// Path to a file containing the passphrase used to encrypt the snapshots.
+	// +required
+	EncryptionKeyFile string `yaml:"encryption_key_file,omitempty"`
+	// Number of snapshots kept in the destination. Older snapshots are deleted.
+	// +default=7
+	Retention int `yaml:"retention,omitempty"`
+}
+
+// DefaultPolicies are the network policies and pod security settings that
+// are applied to the cluster after it is installed
+type DefaultPolicies struct {
+	// Whether the default policies should be applied. The API server denies
+	// exec and attach requests to privileged pods, and a network policy denies
+	// the ingress traffic of the pods of the selected namespaces.
+	// +default=false
+	Enabled bool
+	// The namespaces where the ingress traffic of the pods is denied, unless
+	// another network policy allows it. The namespaces are created if they
+	// don't exist. `kube-system` and `kube-public` cannot be selected.
+	// +default=[default]
+	DenyIngressNamespaces []string `yaml:"deny_ingress_namespaces,omitempty"`
+}
+
+// KubeletCertificateRotation configures the kubelets to renew their
+// certificates through the certificates API before they expire.
+type KubeletCertificateRotation struct {
+	// Whether the kubelets should renew their client certificate, used to
+	// connect to the API server. Renewal requests of the client certificate
+	// are approved automatically.
+	// +default=false
+	Client bool `yaml:"client,omitempty"`
+	// Whether the kubelets should request a serving certificate from the
+	// cluster CA and renew it. Serving certificate requests must be approved
+	// with `kubectl certificate approve`.
+	// +default=false
+	Server bool `yaml:"server,omitempty"`
+}
+
+// EtcdDataVolume keeps the etcd data on a dedicated disk of each etcd node,
+// so that etcd does not compete with the rest of the node for the latency of
+// the root volume. The disk must be attached to the nodes before installation.
+type EtcdDataVolume struct {
+	// Whether the etcd data should be kept on a dedicated disk.
+	// Must be set before the cluster is installed, as the data of an
+	// existing etcd cluster is not moved to the disk.
+	// +default=false
+	Enabled bool
+	// Path to the block device of the disk on every etcd node, such as
+	// `/dev/xvdf`. The device is formatted when it has no filesystem, and
+	// mounted at `/var/lib/etcd`.
+	// +required
+	BlockDevice string `yaml:"block_device,omitempty"`
+	// The filesystem the device is formatted with.
+	// +default=xfs
+	// +options=xfs,ext4
+	Filesystem string `yaml:"filesystem,omitempty"`
+	// The maximum latency of a synchronous write to the disk, in milliseconds,
+	// accepted by the pre-flight checks. etcd needs its writes to be synced
+	// to disk in less than 10ms.
+	// +default=10
+	MaxSyncLatencyMS int `yaml:"max_sync_latency_ms,omitempty"`
+}
+
+// ClusterSecretsStore is an external secret manager where the sensitive
+// assets generated for the cluster are kept. The assets are fetched into the
+// generated assets directory when kismatic needs them, and removed from it
+// once they are stored.
+type ClusterSecretsStore struct {
+	// The secret manager where the assets are kept. The `vault` and `aws`
+	// command line tools must be installed and authenticated respectively.
+	// +options=vault,aws-secrets-manager
+	Provider string `yaml:"provider,omitempty"`
+	// The path under which the assets are kept, such as `secret/kismatic/prod`
+	// for Vault, or the name prefix of the secrets for AWS Secrets Manager.
+	Path string `yaml:"path,omitempty"`
+}
+
+// ClusterAssetsStorage is the object storage bucket where the generated
+// assets directory and the logs of the runs are kept. They are downloaded
+// before kismatic changes the cluster, and uploaded when it is done.
+type ClusterAssetsStorage struct {
+	// The bucket and prefix where the assets are kept, such as
+	// `s3://bucket/prefix` or `gs://bucket/prefix`. The `aws` or `gsutil`
+	// command line tool must be installed and authenticated respectively.
+	Location string `yaml:"location,omitempty"`
+}
+
+// ClusterEvents is the message broker the lifecycle events of the cluster
+// are published to, such as the start and the result of an installation or
+// an upgrade. Events are published in addition to the run index.
+type ClusterEvents struct {
+	// The message broker the events are published to. The `kcat` command
+	// line tool must be installed to publish to Kafka.
+	// +options=nats,kafka
+	Publisher string `yaml:"publisher,omitempty"`
+	// The addresses of the brokers, as `host:port`.
+	Servers []string `yaml:"servers,omitempty"`
+	// The NATS subject or the Kafka topic the events are published to.
+	// +default=kismatic.events
+	Topic string `yaml:"topic,omitempty"`
+}
+
+// AuditLog configures the audit log of the Kubernetes API server. Each
+// audit event records the user, the verb, the resource, the response code
+// and the timestamps of a request.
+type AuditLog struct {
+	// Whether the API server should record audit events.
+	// +default=false
+	Enabled bool
+	// The amount of information recorded for each request.
+	// +default=Metadata
+	// +options=Metadata,Request,RequestResponse
+	Level string `yaml:"level,omitempty"`
+	// Where the audit events are sent.
+	// When `file`, the events are written to /var/log/kubernetes/audit.log
+	// on the master nodes, and can be queried with `kismatic audit`.
+	// When `webhook`, the events are sent in batches to the webhook URL.
+	// +default=file
+	// +options=file,webhook
+	Sink string `yaml:"sink,omitempty"`
+	// Number of days the rotated audit log files are kept for.
+	// +default=30
+	MaxAge int `yaml:"max_age,omitempty"`
+	// Number of rotated audit log files that are kept.
+	// +default=10
+	MaxBackups int `yaml:"max_backups,omitempty"`
+	// Size in megabytes at which the audit log file is rotated.
+	// +default=100
+	MaxSize int `yaml:"max_size,omitempty"`
+	// The URL of the webhook the audit events are sent to.
+	// Required when the sink is `webhook`.
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+}
+
+// AuthWebhook delegates the authentication and authorization decisions of
+// the Kubernetes API server to an external HTTP service, so that an identity
+// system can be plugged in without changes to the cluster. The service
+// implements the Kubernetes webhook contracts.
+type AuthWebhook struct {
+	// The https URL of the service that authenticates the bearer tokens of
+	// the requests. It receives TokenReview objects.
+	// Authentication is not delegated when empty.
+	AuthenticationURL string `yaml:"authentication_url,omitempty"`
+	// The URL of the service that authorizes the requests that the Node, RBAC
+	// and ABAC authorizers do not allow. It receives SubjectAccessReview
+	// objects. Authorization is not delegated when empty.
+	AuthorizationURL string `yaml:"authorization_url,omitempty"`
+	// How long the API server caches the authenticated tokens and the
+	// allowed requests.
+	// +default=2m
+	CacheTTL string `yaml:"cache_ttl,omitempty"`
+	// The absolute path of the Certificate Authority that signed the
+	// certificate of the service, on the machine running kismatic.
+	// The system's trusted Certificate Authorities are used when empty.
+	CAPath string `yaml:"ca,omitempty"`
+}
+
+func (w AuthWebhook) enabled() bool {
+	return w.AuthenticationURL != "" || w.AuthorizationURL != ""
+}
+
+// ClusterExpiration schedules the expiration of the cluster. KET does not
+// provision the machines of the cluster, so when the cluster expires, the
+// nodes are cordoned and the webhook is notified, so that the machines can
+// be destroyed by the system that provisioned them.
+type ClusterExpiration struct {
+	// How long the cluster lives after it is installed, such as `72h`.
+	// The expiration time is recorded on the first master node when the
+	// cluster is installed, and is not changed when the plan is applied again.
+	TTL string `yaml:"ttl,omitempty"`
+	// The time the cluster expires, in RFC 3339 format, such as
+	// `2017-10-31T18:00:00Z`. Takes precedence over the TTL, and can be used
+	// to extend the life of the cluster.
+	ExpiresAt string `yaml:"expires_at,omitempty"`
+	// How long before the cluster expires the warning is sent to the webhook.
+	// +default=24h
+	WarnBefore string `yaml:"warn_before,omitempty"`
+	// The URL that is sent a POST request when the cluster is about to
+	// expire, and when it expires.
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+}
+
+const (
+	etcdBackupProviderS3  = "s3"
+	etcdBackupProviderGCS = "gcs"
+)
+
+// remote returns the object storage provider and the location of the
+// snapshots, as an rclone remote path.
+func (b EtcdBackup) remote() (provider string, path string, err error) {
+	u, err := url.Parse(b.Destination)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "s3":
+		provider = etcdBackupProviderS3
+	case "gs":
+		provider = etcdBackupProviderGCS
+	default:
+		return "", "", fmt.Errorf("unsupported scheme %q, options are [s3 gs]", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", "", errors.New("bucket name is missing")
+	}
+	path = fmt.Sprintf(":%s:%s", provider, u.Host)
+	if prefix := strings.Trim(u.Path, "/"); prefix != "" {
+		path = path + "/" + prefix
+	}
+	return provider, path, nil
+}
+
 // Docker includes the configuration for the docker installation owned by KET.
 type Docker struct {
 	// Storage configuration for the docker engine
 	Storage DockerStorage
+	// Credentials of the private registries that the images of the workloads
+	// are pulled from. They are written to the docker config file of the kubelet
+	// on all nodes, and to an image pull secret of the default service account
+	// of the pull secret namespaces.
+	RegistryCredentials []RegistryCredential `yaml:"registry_credentials,omitempty"`
+	// The namespaces whose default service account is given the image pull
+	// secret with the registry credentials. The namespaces are created if they
+	// don't exist.
+	// +default=[default]
+	PullSecretNamespaces []string `yaml:"pull_secret_namespaces,omitempty"`
+	// The URLs of the registry mirrors that the docker engine on all nodes
+	// pulls the images from before falling back to Docker Hub, such as
+	// `https://mirror.example.com:5000`.
+	RegistryMirrors []string `yaml:"registry_mirrors,omitempty"`
+	// The registries that the docker engine on all nodes is allowed to reach
+	// over plain HTTP or with an untrusted certificate, such as
+	// `registry.example.com:5000` or a CIDR like `10.0.0.0/8`.
+	InsecureRegistries []string `yaml:"insecure_registries,omitempty"`
+}
+
+// RegistryCredential is the credential of a private container image registry
+type RegistryCredential struct {
+	// The hostname or IP address and port of the registry, such as
+	// `registry.example.com:5000`. Do not include http or https.
+	// +required
+	Server string
+	// The username used to authenticate to the registry.
+	// +required
+	Username string
+	// The password used to authenticate to the registry.
+	// +required
+	Password string
 }
 
 // DockerStorage includes the storage-specific configuration for docker.
@@ -264,6 +826,21 @@ type DockerRegistry struct {
 	Password string
 }
 
+// PostInstall configures the resources that are created on the cluster after
+// it is installed, such as namespaces, RBAC policies and operators.
+type PostInstall struct {
+	// The manifests that are applied to the cluster after it is installed, in
+	// the order in which they are listed. Each manifest is the path of a local
+	// file, or an http or https URL. The manifests are validated by the API
+	// server when they are applied.
+	Manifests []string `yaml:"manifests,omitempty"`
+	// The number of times that applying a manifest is retried when it fails,
+	// for example while the resource types that it depends on are being
+	// registered. Set to 0 to apply the manifests only once.
+	// +default=3
+	Retries *int `yaml:"retries,omitempty"`
+}
+
 // AddOns are components that are deployed on the cluster that KET considers
 // necessary for producing a production cluster.
 type AddOns struct {
@@ -284,6 +861,23 @@ type AddOns struct {
 	// Because the Rescheduler does not have leader election and therefore can only run as a single instance in a cluster, it will be deployed as a static pod on the first master.
 	// More information about the Rescheduler can be found here: https://kubernetes.io/docs/tasks/administer-cluster/guaranteed-scheduling-critical-addon-pods/
 	Rescheduler Rescheduler `yaml:"rescheduler"`
+	// The Cluster Autoscaler add-on configuration.
+	// The cluster autoscaler adds worker nodes when pods cannot be scheduled, and removes
+	// underutilized worker nodes, within the bounds of the node groups of the cloud provider.
+	// It is deployed as a static pod on the first master.
+	ClusterAutoscaler ClusterAutoscaler `yaml:"cluster_autoscaler,omitempty"`
+	// The External DNS add-on configuration.
+	// ExternalDNS creates, updates and deletes the DNS records of the hosts of the ingress
+	// resources and services of the cluster in a DNS provider, such as Route 53.
+	ExternalDNS ExternalDNS `yaml:"external_dns,omitempty"`
+	// The Reboot Coordinator add-on configuration.
+	// The reboot coordinator drains and reboots the nodes that require a reboot after
+	// an OS update, one node at a time.
+	RebootCoordinator RebootCoordinator `yaml:"reboot_coordinator,omitempty"`
+	// The Metrics Server add-on configuration.
+	// The metrics server serves the resource metrics API, used by `kubectl top`
+	// and the horizontal pod autoscalers, through the aggregation layer of the API server.
+	MetricsServer MetricsServer `yaml:"metrics_server,omitempty"`
 }
 
 // Features configuration
@@ -307,6 +901,15 @@ type CNI struct {
 	Provider string
 	// The CNI options that can be configured for each CNI provider.
 	Options CNIOptions `yaml:"options"`
+	// The MTU of the network interfaces of the pods, for the calico and weave
+	// providers. When not set, it is the lowest MTU of the network interfaces
+	// of the nodes minus the overhead of the encapsulation of the provider.
+	MTU int `yaml:"mtu,omitempty"`
+	// Whether the traffic between the pods of different nodes is encrypted.
+	// Only supported by the weave provider, which uses IPsec, or NaCl when
+	// the kernel of the nodes does not support the fast datapath.
+	// +default=false
+	Encryption bool `yaml:"encryption,omitempty"`
 }
 
 // CNIOptions that can be configured for each CNI provider.
@@ -325,6 +928,56 @@ type CalicoOptions struct {
 	// +default=info
 	// +options=warning,info,debug
 	LogLevel string `yaml:"log_level"`
+	// The BGP configuration of the nodes, to advertise the routes of the pods
+	// to the routers of the data center.
+	BGP CalicoBGP `yaml:"bgp,omitempty"`
+	// The IP pools the pod IPs are allocated from, which replace the pool of
+	// the whole pod CIDR block. The pools must be within the pod CIDR block,
+	// and cannot be changed once the cluster is installed.
+	IPPools []CalicoIPPool `yaml:"ip_pools,omitempty"`
+}
+
+// CalicoBGP is the BGP configuration of the calico nodes
+type CalicoBGP struct {
+	// The AS number of the nodes.
+	// +default=64512
+	ASNumber uint32 `yaml:"as_number,omitempty"`
+	// The BGP peers of the nodes, such as top of rack switches.
+	Peers []CalicoBGPPeer `yaml:"peers,omitempty"`
+	// The route reflectors the nodes peer with. When set, the full mesh of
+	// BGP sessions between the nodes is disabled.
+	RouteReflectors []CalicoBGPPeer `yaml:"route_reflectors,omitempty"`
+}
+
+// CalicoBGPPeer is a BGP peer of the calico nodes
+type CalicoBGPPeer struct {
+	// The IP address of the peer.
+	// +required
+	IP string `yaml:"ip"`
+	// The AS number of the peer.
+	// +required
+	ASNumber uint32 `yaml:"as_number"`
+	// The host of the node that peers with the peer, such as the node of the
+	// rack of a top of rack switch. When not set, all nodes peer with it.
+	Node string `yaml:"node,omitempty"`
+}
+
+// CalicoIPPool is a calico IP pool the pod IPs are allocated from
+type CalicoIPPool struct {
+	// The CIDR block of the pool, within the pod CIDR block.
+	// +required
+	CIDR string `yaml:"cidr"`
+	// The IP-in-IP encapsulation of the traffic of the pods of the pool. When
+	// `cross-subnet`, only the traffic between nodes of different subnets is
+	// encapsulated. Defaults to `always` in overlay mode and `off` in routed
+	// mode. VXLAN is not supported by the installed version of calico.
+	// +options=always,cross-subnet,off
+	IPIPMode string `yaml:"ipip_mode,omitempty"`
+	// Whether the traffic of the pods to destinations outside of the pools
+	// is masqueraded. Not needed when the routes of the pods are advertised
+	// to the routers of the data center.
+	// +default=false
+	NATOutgoing bool `yaml:"nat_outgoing,omitempty"`
 }
 
 // The DNS add-on configuration
@@ -332,6 +985,66 @@ type DNS struct {
 	// Whether the DNS add-on should be disabled.
 	// When set to true, no DNS solution will be deployed on the cluster.
 	Disable bool
+	// The DNS provider that should be installed on the cluster.
+	// When the provider is changed on an existing cluster, the new provider is
+	// started alongside the current one, which is removed once the new
+	// provider is ready.
+	// +default=kubedns
+	// +options=kubedns,coredns
+	Provider string
+	// The options that can be configured for the DNS provider.
+	Options DNSOptions `yaml:"options,omitempty"`
+}
+
+// DNSOptions that can be configured for the DNS add-on
+type DNSOptions struct {
+	// The nameservers that should be used to resolve names outside of the
+	// cluster domain, in the form of IP or IP:port.
+	// When not set, the nameservers configured on the nodes are used.
+	UpstreamNameservers []string `yaml:"upstream_nameservers,omitempty"`
+	// The nameservers that should be used to resolve the names of specific
+	// domains, keyed by domain name.
+	StubDomains map[string][]string `yaml:"stub_domains,omitempty"`
+	// The DNS autoscaler configuration.
+	Autoscaler DNSAutoscaler `yaml:"autoscaler,omitempty"`
+	// The NodeLocal DNSCache configuration.
+	NodeLocalCache DNSNodeLocalCache `yaml:"node_local_cache,omitempty"`
+}
+
+// DNSAutoscaler scales the number of DNS replicas with the size of the cluster.
+// The number of replicas is the greater of the number of cores divided by
+// cores_per_replica, and the number of nodes divided by nodes_per_replica.
+type DNSAutoscaler struct {
+	// Whether the DNS autoscaler should be deployed. When disabled, the DNS
+	// add-on runs 2 replicas, or 1 replica when the cluster has a single worker.
+	// +default=false
+	Enabled bool
+	// The number of cores in the cluster for each DNS replica.
+	// +default=256
+	CoresPerReplica int `yaml:"cores_per_replica,omitempty"`
+	// The number of nodes in the cluster for each DNS replica.
+	// +default=16
+	NodesPerReplica int `yaml:"nodes_per_replica,omitempty"`
+	// The minimum number of DNS replicas.
+	// +default=2
+	MinReplicas int `yaml:"min_replicas,omitempty"`
+	// The maximum number of DNS replicas. When not set, the number of replicas
+	// is not limited.
+	MaxReplicas int `yaml:"max_replicas,omitempty"`
+}
+
+// DNSNodeLocalCache runs a DNS cache on every node, that the pods query
+// instead of the DNS service. It reduces the latency of DNS lookups and the
+// load on the DNS replicas, and avoids the conntrack races of UDP lookups
+// through the DNS service.
+type DNSNodeLocalCache struct {
+	// Whether the NodeLocal DNSCache should be deployed. The kubelet is
+	// configured to use the cache as the nameserver of the pods.
+	// +default=false
+	Enabled bool
+	// The link-local IP address that the cache listens on, on every node.
+	// +default=169.254.20.10
+	IP string `yaml:"ip,omitempty"`
 }
 
 // The HeapsterMonitoring add-on configuration
@@ -388,6 +1101,51 @@ type Dashboard struct {
 	// When set to true, the Kubernetes Dashboard will not be installed on the cluster.
 	// +default=false
 	Disable bool
+	// Exposes the dashboard through the ingress controller, so that it can be
+	// reached without running `kubectl proxy`.
+	Ingress DashboardIngress `yaml:"ingress,omitempty"`
+}
+
+// DashboardIngress exposes the dashboard through the ingress controller
+type DashboardIngress struct {
+	// Whether the dashboard should be exposed through the ingress controller.
+	// Requires ingress nodes.
+	// +default=false
+	Enabled bool
+	// The host name the dashboard is served on, such as `dashboard.example.com`.
+	// The TLS certificate of the host is issued by the cluster CA.
+	// +required
+	Host string `yaml:"host,omitempty"`
+	// How users authenticate to the ingress before reaching the dashboard.
+	// `client_certificate` requires a client certificate signed by the cluster CA,
+	// `oidc` requires a login with an OpenID Connect provider, and the
+	// dashboard accesses the API server as the user that logged in.
+	// +default=client_certificate
+	// +options=client_certificate,oidc
+	Auth string `yaml:"auth,omitempty"`
+	// The OpenID Connect provider users log in with, when the auth is `oidc`.
+	OIDC DashboardOIDC `yaml:"oidc,omitempty"`
+}
+
+// DashboardOIDC is the OpenID Connect provider users log in with before
+// reaching the dashboard
+type DashboardOIDC struct {
+	// URL of the issuer of the provider, such as `https://accounts.google.com`.
+	// +required
+	IssuerURL string `yaml:"issuer_url,omitempty"`
+	// The ID of the client registered with the provider.
+	// +required
+	ClientID string `yaml:"client_id,omitempty"`
+	// The secret of the client registered with the provider.
+	// +required
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// The secret the session cookies are signed with. Must be 16, 24 or 32 bytes long.
+	// +required
+	CookieSecret string `yaml:"cookie_secret,omitempty"`
+	// Only the users whose email address is in this domain can log in,
+	// such as `example.com`. Set to `*` to allow any email address.
+	// +required
+	EmailDomain string `yaml:"email_domain,omitempty"`
 }
 
 // PackageManager add-on configuration
@@ -410,6 +1168,81 @@ type Rescheduler struct {
 	Disable bool
 }
 
+// ClusterAutoscaler add-on configuration
+type ClusterAutoscaler struct {
+	// Whether the cluster autoscaler add-on should be enabled.
+	// Requires the aws cloud provider.
+	// +default=false
+	Enabled bool
+	// The node groups that the cluster autoscaler can scale. On AWS, the node
+	// groups are Auto Scaling Groups. The instances launched in a node group must
+	// join the cluster as workers on their own.
+	NodeGroups []AutoscalerNodeGroup `yaml:"node_groups"`
+}
+
+// AutoscalerNodeGroup is a group of worker nodes that is scaled by the
+// cluster autoscaler
+type AutoscalerNodeGroup struct {
+	// The name of the node group. On AWS, the name of the Auto Scaling Group.
+	// +required
+	Name string
+	// The minimum number of nodes in the group.
+	// +required
+	MinSize int `yaml:"min_size"`
+	// The maximum number of nodes in the group.
+	// +required
+	MaxSize int `yaml:"max_size"`
+}
+
+// ExternalDNS add-on configuration
+type ExternalDNS struct {
+	// Whether the external DNS add-on should be enabled.
+	// +default=false
+	Enabled bool
+	// The DNS provider whose records are managed. The credentials of the provider
+	// are those of the IAM role of the instances on AWS, and of the service
+	// account of the instances on Google Cloud.
+	// +default=aws
+	// +options=aws,google
+	Provider string
+	// The domains whose records are managed. The records of other domains are not changed.
+	// +required
+	DomainFilters []string `yaml:"domain_filters"`
+	// The Google Cloud project of the managed zones.
+	// Required when the provider is google.
+	GoogleProject string `yaml:"google_project,omitempty"`
+	// A wildcard domain, such as *.apps.example.com, whose record resolves to
+	// the internal IPs of the ingress nodes.
+	IngressWildcard string `yaml:"ingress_wildcard,omitempty"`
+}
+
+// RebootCoordinator add-on configuration
+type RebootCoordinator struct {
+	// Whether the reboot coordinator add-on should be enabled.
+	// +default=false
+	Enabled bool
+	// The file whose presence means that the node must be rebooted. It is
+	// created by the package manager of Ubuntu when an update requires a reboot.
+	// +default=/var/run/reboot-required
+	SentinelFile string `yaml:"sentinel_file,omitempty"`
+	// How often the nodes check for the sentinel file, such as `1h`.
+	// +default=1h
+	Period string `yaml:"period,omitempty"`
+	// Label selectors of the pods that prevent the node they run on from being
+	// rebooted, such as `app=batch-job`.
+	BlockingPodSelectors []string `yaml:"blocking_pod_selectors,omitempty"`
+}
+
+// MetricsServer add-on configuration
+type MetricsServer struct {
+	// Whether the metrics server add-on should be enabled.
+	// When enabled, the horizontal pod autoscalers read the metrics of the
+	// pods from the metrics server instead of Heapster. The plan files
+	// generated by `kismatic install plan` enable it.
+	// +default=false
+	Enabled bool
+}
+
 type DeprecatedPackageManager struct {
 	// Whether the package manager add-on should be enabled.
 	// +deprecated
@@ -429,9 +1262,35 @@ type MasterNodeGroup struct {
 	// In the case where there is only one master node, this can be set to the IP address of the master nodes.
 	// +required
 	LoadBalancedShortName string `yaml:"load_balanced_short_name"`
+	// The virtual IP of the master nodes.
+	VirtualIP MasterVirtualIP `yaml:"virtual_ip,omitempty"`
 	// List of master nodes that are part of the cluster.
 	// +required
 	Nodes []Node
+	// Labels to add to all the nodes in the group.
+	// Labels set on an individual node take precedence over the labels of the group.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Taints to add to all the nodes in the group.
+	Taints []Taint `yaml:"taints,omitempty"`
+}
+
+// MasterVirtualIP is an address that is held by one of the master nodes at a
+// time, and that moves to another master node when the API server of the node
+// that holds it is down. It makes the API server highly available on premises
+// without an external load balancer.
+type MasterVirtualIP struct {
+	// The virtual IP address. When set, keepalived is deployed on the master
+	// nodes to hold the address, and the load balanced FQDN should be set to
+	// the address, or to a DNS name that resolves to it.
+	// The master nodes must be in the same layer 2 network as the address.
+	Address string `yaml:"address,omitempty"`
+	// The network interface of the master nodes that the virtual IP is added to.
+	// When not set, the interface of the internal IP of the node is used.
+	Interface string `yaml:"interface,omitempty"`
+	// The VRRP virtual router ID of the virtual IP, that must be unique
+	// within the network of the master nodes.
+	// +default=51
+	RouterID int `yaml:"router_id,omitempty"`
 }
 
 // A NodeGroup is a collection of nodes
@@ -442,6 +1301,35 @@ type NodeGroup struct {
 	// List of nodes.
 	// +required
 	Nodes []Node
+	// Labels to add to all the nodes in the group.
+	// Labels set on an individual node take precedence over the labels of the group.
+	// Not supported on the etcd node group.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Taints to add to all the nodes in the group.
+	// Not supported on the etcd node group.
+	Taints []Taint `yaml:"taints,omitempty"`
+}
+
+// A WorkerPool is a named group of worker nodes that share the same
+// instance type, labels and taints
+type WorkerPool struct {
+	// The name of the pool. Must be a valid label value.
+	// +required
+	Name string
+	// The instance type of the nodes in the pool, such as m4.xlarge.
+	// When set, the nodes are labeled with kismatic/instance-type=<instance type>.
+	InstanceType string `yaml:"instance_type,omitempty"`
+	// Number of nodes in the pool.
+	// +required
+	ExpectedCount int `yaml:"expected_count"`
+	// List of nodes in the pool.
+	// +required
+	Nodes []Node
+	// Labels to add to all the nodes in the pool.
+	// Labels set on an individual node take precedence over the labels of the pool.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Taints to add to all the nodes in the pool.
+	Taints []Taint `yaml:"taints,omitempty"`
 }
 
 // An OptionalNodeGroup is a collection of nodes that can be empty
@@ -466,9 +1354,47 @@ type Node struct {
 	// only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence.
 	// It is recommended to use reverse-DNS notation to avoid collision with other labels.
 	Labels map[string]string
+	// Taints to add when installing the node in the cluster.
+	// If a node is defined under multiple roles, the taints for that node will be merged.
+	Taints []Taint `yaml:"taints,omitempty"`
 	// Kubelet configuration applied to this node.
 	// If a node is repeated for multiple roles, the overrides cannot be different.
 	KubeletOptions KubeletOptions `yaml:"kubelet,omitempty"`
+	// The CPU architecture of the node.
+	// Only worker nodes are supported on arm64. If a node is repeated for
+	// multiple roles, the architecture cannot be different.
+	// +default=amd64
+	// +options=amd64,arm64
+	Arch string `yaml:"arch,omitempty"`
+}
+
+// Architecture returns the CPU architecture of the node
+func (n Node) Architecture() string {
+	if n.Arch == "" {
+		return archAMD64
+	}
+	return n.Arch
+}
+
+// A Taint prevents pods that do not tolerate it from being scheduled on a node
+type Taint struct {
+	// The key of the taint.
+	// +required
+	Key string
+	// The value of the taint.
+	Value string `yaml:"value,omitempty"`
+	// The effect of the taint on pods that do not tolerate it.
+	// +required
+	// +options=NoSchedule,PreferNoSchedule,NoExecute
+	Effect string
+}
+
+// String returns the taint in the key=value:effect format used by kubectl
+func (t Taint) String() string {
+	if t.Value == "" {
+		return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+	}
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
 }
 
 // Equal returns true of 2 nodes have the same host, IP and InternalIP
@@ -545,7 +1471,7 @@ func (p *Plan) getAllNodes() []Node {
 	nodes := []Node{}
 	nodes = append(nodes, p.Etcd.Nodes...)
 	nodes = append(nodes, p.Master.Nodes...)
-	nodes = append(nodes, p.Worker.Nodes...)
+	nodes = append(nodes, p.workerNodes()...)
 	if p.Ingress.Nodes != nil {
 		nodes = append(nodes, p.Ingress.Nodes...)
 	}
@@ -555,6 +1481,43 @@ func (p *Plan) getAllNodes() []Node {
 	return nodes
 }
 
+// KubeletNodes returns the unique nodes that run the kubelet. etcd nodes
+// don't run the kubelet, unless they have another role.
+func (p *Plan) KubeletNodes() []Node {
+	seenNodes := map[string]bool{}
+	nodes := []Node{}
+	for _, group := range [][]Node{p.Master.Nodes, p.workerNodes(), p.Ingress.Nodes, p.Storage.Nodes} {
+		for _, node := range group {
+			key := node.HashCode()
+			if seenNodes[key] {
+				continue
+			}
+			nodes = append(nodes, node)
+			seenNodes[key] = true
+		}
+	}
+	return nodes
+}
+
+// workerNodes returns the nodes of the worker group and of all the worker pools
+func (p *Plan) workerNodes() []Node {
+	nodes := append([]Node{}, p.Worker.Nodes...)
+	for _, pool := range p.WorkerPools {
+		nodes = append(nodes, pool.Nodes...)
+	}
+	return nodes
+}
+
+// workerPool returns the worker pool with the given name
+func (p *Plan) workerPool(name string) *WorkerPool {
+	for i := range p.WorkerPools {
+		if p.WorkerPools[i].Name == name {
+			return &p.WorkerPools[i]
+		}
+	}
+	return nil
+}
+
 func (p *Plan) getNodeWithIP(ip string) (*Node, error) {
 	for _, n := range p.getAllNodes() {
 		if n.IP == ip {
@@ -564,6 +1527,21 @@ func (p *Plan) getNodeWithIP(ip string) (*Node, error) {
 	return nil, fmt.Errorf("Node with IP %q was not found in plan", ip)
 }
 
+// isSingleNode returns true if all the node roles are assigned to a single
+// machine
+func (p *Plan) isSingleNode() bool {
+	return len(p.getAllNodes()) > 1 && len(p.GetUniqueNodes()) == 1
+}
+
+func (p *Plan) hasMasterNode(node Node) bool {
+	for _, n := range p.Master.Nodes {
+		if n.Equal(node) {
+			return true
+		}
+	}
+	return false
+}
+
 // AllAddresses will return the hostnames, IPs and internal IPs for all nodes
 func (p *Plan) AllAddresses() string {
 	nodes := p.GetUniqueNodes()
@@ -607,7 +1585,7 @@ func (p *Plan) GetSSHConnection(host string) (*SSHConnection, error) {
 		case "etcd":
 			foundNode = firstIfItExists(p.Etcd.Nodes)
 		case "worker":
-			foundNode = firstIfItExists(p.Worker.Nodes)
+			foundNode = firstIfItExists(p.workerNodes())
 		case "ingress":
 			foundNode = firstIfItExists(p.Ingress.Nodes)
 		case "storage":
@@ -632,7 +1610,7 @@ func (p *Plan) GetSSHClient(host string) (ssh.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key)
+	client, err := con.SSHConfig.NewClient(con.Node.IP)
 	if err != nil {
 		return nil, fmt.Errorf("error creating SSH client for host %s: %v", host, err)
 	}
@@ -640,6 +1618,26 @@ func (p *Plan) GetSSHClient(host string) (ssh.Client, error) {
 	return client, nil
 }
 
+// NewClient returns an SSH client for the node with the given IP, that
+// tunnels through the bastion host when one is configured
+func (s SSHConfig) NewClient(ip string) (ssh.Client, error) {
+	return ssh.NewClient(ip, s.Port, s.User, s.Key, s.sshBastion())
+}
+
+// sshBastion returns the bastion host of the SSH connections, or nil if the
+// nodes are reached directly
+func (s SSHConfig) sshBastion() *ssh.Bastion {
+	if s.Bastion.Host == "" {
+		return nil
+	}
+	return &ssh.Bastion{
+		Host: s.Bastion.Host,
+		Port: s.Bastion.Port,
+		User: s.Bastion.User,
+		Key:  s.Bastion.Key,
+	}
+}
+
 func firstIfItExists(nodes []Node) *Node {
 	if len(nodes) > 0 {
 		return &nodes[0]
@@ -658,7 +1656,7 @@ func (p *Plan) GetRolesForIP(ip string) []string {
 		allRoles = append(allRoles, "etcd")
 	}
 
-	if hasIP(&p.Worker.Nodes, ip) {
+	if workers := p.workerNodes(); hasIP(&workers, ip) {
 		allRoles = append(allRoles, "worker")
 	}
 
@@ -688,6 +1686,12 @@ func (p Plan) PrivateRegistryProvided() bool {
 	return p.DockerRegistry.Server != ""
 }
 
+// packageInstallationEnabled returns true when KET should install the
+// packages on the nodes
+func (p Plan) packageInstallationEnabled() bool {
+	return !p.Cluster.DisablePackageInstallation && !p.Cluster.BakedImage
+}
+
 // NetworkConfigured returns true if pod validation/smoketest should run
 func (p Plan) NetworkConfigured() bool {
 	// CNI disabled or "custom" return false
@@ -0,0 +1,52 @@
+package install
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// The number of times that applying a manifest is retried when the plan does
+// not set it
+const defaultPostInstallRetries = 3
+
+func isManifestURL(m string) bool {
+	return strings.HasPrefix(m, "http://") || strings.HasPrefix(m, "https://")
+}
+
+// applyPostInstall sets the manifests that are applied after the cluster is
+// installed on the cluster catalog, numbered in the order in which they are
+// applied
+func applyPostInstall(p *Plan, cc *ansible.ClusterCatalog) error {
+	cc.PostInstall.Retries = defaultPostInstallRetries
+	if p.PostInstall.Retries != nil {
+		cc.PostInstall.Retries = *p.PostInstall.Retries
+	}
+	for i, m := range p.PostInstall.Manifests {
+		manifest := ansible.PostInstallManifest{Source: m}
+		var name string
+		if isManifestURL(m) {
+			manifest.URL = true
+			if u, err := url.Parse(m); err == nil {
+				name = path.Base(u.Path)
+			}
+		} else {
+			abs, err := filepath.Abs(m)
+			if err != nil {
+				return fmt.Errorf("failed to determine absolute path of manifest %q: %v", m, err)
+			}
+			manifest.Source = abs
+			name = filepath.Base(abs)
+		}
+		if name == "" || name == "." || name == "/" {
+			name = "manifest.yaml"
+		}
+		manifest.Name = fmt.Sprintf("%02d-%s", i+1, name)
+		cc.PostInstall.Manifests = append(cc.PostInstall.Manifests, manifest)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package install
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestApplyPostInstall(t *testing.T) {
+	abs, err := filepath.Abs("manifests/app.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	retries := 5
+	p := &Plan{
+		PostInstall: PostInstall{
+			Manifests: []string{"manifests/app.yaml", "https://example.com/manifests/monitoring.yaml", "https://example.com"},
+			Retries:   &retries,
+		},
+	}
+	cc := &ansible.ClusterCatalog{}
+	if err = applyPostInstall(p, cc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ansible.PostInstallManifest{
+		{Source: abs, Name: "01-app.yaml"},
+		{Source: "https://example.com/manifests/monitoring.yaml", URL: true, Name: "02-monitoring.yaml"},
+		{Source: "https://example.com", URL: true, Name: "03-manifest.yaml"},
+	}
+	if len(cc.PostInstall.Manifests) != len(expected) {
+		t.Fatalf("expected %d manifests, but got %d", len(expected), len(cc.PostInstall.Manifests))
+	}
+	for i, m := range cc.PostInstall.Manifests {
+		if m != expected[i] {
+			t.Errorf("manifest %d: expected %+v, but got %+v", i, expected[i], m)
+		}
+	}
+	if cc.PostInstall.Retries != 5 {
+		t.Errorf("expected 5 retries, but got %d", cc.PostInstall.Retries)
+	}
+}
+
+func TestApplyPostInstallNoRetries(t *testing.T) {
+	retries := 0
+	p := &Plan{PostInstall: PostInstall{Manifests: []string{"https://example.com/manifests/app.yaml"}, Retries: &retries}}
+	setDefaults(p)
+	cc := &ansible.ClusterCatalog{}
+	if err := applyPostInstall(p, cc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cc.PostInstall.Retries != 0 {
+		t.Errorf("expected 0 retries, but got %d", cc.PostInstall.Retries)
+	}
+}
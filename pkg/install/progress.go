@@ -0,0 +1,139 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const progressFilename = "progress.yaml"
+
+// CancelledError is returned when an operation is cancelled before it
+// completes. The plays completed before the cancellation are recorded in the
+// run directory.
+type CancelledError struct {
+	// Task is the name of the cancelled operation
+	Task string
+	// RunDirectory is the directory where the progress of the run is recorded
+	RunDirectory string
+	// CompletedPlays are the plays that ran to completion
+	CompletedPlays []string
+}
+
+func (e CancelledError) Error() string {
+	return fmt.Sprintf("%s was cancelled after completing %d plays, progress was recorded in %q. Run the command again to resume, completed plays are not affected when they run again",
+		e.Task, len(e.CompletedPlays), filepath.Join(e.RunDirectory, progressFilename))
+}
+
+// runProgress keeps track of the plays of a playbook as they run
+type runProgress struct {
+	mu             sync.Mutex
+	Playbook       string   `yaml:"playbook"`
+	Cancelled      bool     `yaml:"cancelled"`
+	CompletedPlays []string `yaml:"completed_plays"`
+	// Aborted is true if the playbook was killed in the middle of a play
+	Aborted     bool `yaml:"aborted,omitempty"`
+	currentPlay string
+	// whether a task of the current play failed
+	failed bool
+	// called when a play starts, if set
+	onPlayStart func(play string)
+}
+
+// track records the play events of the stream, and forwards all the events
+// to the returned channel.
+func (p *runProgress) track(in <-chan ansible.Event) <-chan ansible.Event {
+	out := make(chan ansible.Event)
+	go func() {
+		defer close(out)
+		for e := range in {
+			p.record(e)
+			out <- e
+		}
+	}()
+	return out
+}
+
+func (p *runProgress) record(e ansible.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch event := e.(type) {
+	case *ansible.PlayStartEvent:
+		p.completeCurrentPlay()
+		p.currentPlay = event.Name
+		if p.onPlayStart != nil {
+			p.onPlayStart(event.Name)
+		}
+	case *ansible.PlaybookEndEvent:
+		p.completeCurrentPlay()
+	case *ansible.RunnerFailedEvent:
+		if !event.IgnoreErrors {
+			p.failed = true
+		}
+	case *ansible.RunnerUnreachableEvent:
+		p.failed = true
+	}
+}
+
+func (p *runProgress) completeCurrentPlay() {
+	// the play that was running when the playbook was killed did not complete
+	if p.currentPlay != "" && !p.failed && !p.Aborted {
+		p.CompletedPlays = append(p.CompletedPlays, p.currentPlay)
+	}
+	p.currentPlay = ""
+	p.failed = false
+}
+
+func (p *runProgress) cancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Cancelled = true
+}
+
+// abort records that the playbook is killed, so that the play that is
+// running is not recorded as completed
+func (p *runProgress) abort() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Cancelled = true
+	p.Aborted = true
+}
+
+// stoppedByCancel returns true if the playbook stopped because it was
+// cancelled. The cancellation takes effect when the next play starts, so the
+// play that was running when the playbook stopped ran to completion, unless
+// one of its tasks failed or the playbook was aborted.
+func (p *runProgress) stoppedByCancel() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.Cancelled || p.failed {
+		return false
+	}
+	p.completeCurrentPlay()
+	return true
+}
+
+// write records the progress in the run directory
+func (p *runProgress) write(runDirectory string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("error marshaling run progress: %v", err)
+	}
+	file := filepath.Join(runDirectory, progressFilename)
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return fmt.Errorf("error writing run progress to %q: %v", file, err)
+	}
+	return nil
+}
+
+func (p *runProgress) completedPlays() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.CompletedPlays...)
+}
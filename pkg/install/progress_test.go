@@ -0,0 +1,67 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func playStart(name string) *ansible.PlayStartEvent {
+	e := &ansible.PlayStartEvent{}
+	e.Name = name
+	return e
+}
+
+func TestRunProgressCompletedPlays(t *testing.T) {
+	p := &runProgress{}
+	p.record(playStart("Start Kubernetes Etcd Cluster"))
+	p.record(&ansible.TaskStartEvent{})
+	p.record(playStart("Start Kubernetes Kubelet"))
+	p.record(&ansible.RunnerFailedEvent{})
+	p.record(playStart("Start Kubernetes API Server"))
+	p.record(&ansible.PlaybookEndEvent{})
+
+	expected := []string{"Start Kubernetes Etcd Cluster", "Start Kubernetes API Server"}
+	if !reflect.DeepEqual(p.completedPlays(), expected) {
+		t.Errorf("expected completed plays %v, but got %v", expected, p.completedPlays())
+	}
+}
+
+func TestRunProgressStoppedByCancel(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancelled bool
+		aborted   bool
+		failed    bool
+		expected  bool
+		completed int
+	}{
+		{name: "not cancelled", completed: 1},
+		{name: "cancelled", cancelled: true, expected: true, completed: 2},
+		{name: "cancelled, but the current play failed", cancelled: true, failed: true, completed: 1},
+		{name: "aborted", cancelled: true, aborted: true, expected: true, completed: 1},
+	}
+	for _, test := range tests {
+		p := &runProgress{}
+		p.record(playStart("play1"))
+		p.record(playStart("play2"))
+		if test.failed {
+			p.record(&ansible.RunnerUnreachableEvent{})
+		}
+		if test.cancelled {
+			p.cancel()
+		}
+		if test.aborted {
+			p.abort()
+			// events that were buffered when the playbook was killed
+			p.record(playStart("play3"))
+		}
+		if got := p.stoppedByCancel(); got != test.expected {
+			t.Errorf("%s: expected stoppedByCancel to be %v, but got %v", test.name, test.expected, got)
+		}
+		if len(p.completedPlays()) != test.completed {
+			t.Errorf("%s: expected %d completed plays, but got %v", test.name, test.completed, p.completedPlays())
+		}
+	}
+}
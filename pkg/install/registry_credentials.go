@@ -0,0 +1,54 @@
+package install
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// dockerConfig is the format of the docker config file, which is also the
+// content of the image pull secrets of type kubernetes.io/dockerconfigjson
+type dockerConfig struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+type dockerConfigAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+// applyRegistryCredentials sets the docker config file with the credentials
+// of the private registries of the plan on the cluster catalog
+func applyRegistryCredentials(p *Plan, cc *ansible.ClusterCatalog) error {
+	if len(p.Docker.RegistryCredentials) == 0 {
+		return nil
+	}
+	config, err := dockerConfigJSON(p.Docker.RegistryCredentials)
+	if err != nil {
+		return err
+	}
+	cc.RegistryCredentials.DockerConfigJSON = config
+	cc.RegistryCredentials.Namespaces = p.Docker.PullSecretNamespaces
+	return nil
+}
+
+// dockerConfigJSON returns the docker config file with the credentials of
+// the registries
+func dockerConfigJSON(creds []RegistryCredential) (string, error) {
+	config := dockerConfig{Auths: map[string]dockerConfigAuth{}}
+	for _, c := range creds {
+		config.Auths[c.Server] = dockerConfigAuth{
+			Username: c.Username,
+			Password: c.Password,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password)),
+		}
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("error encoding the registry credentials: %v", err)
+	}
+	return string(b), nil
+}
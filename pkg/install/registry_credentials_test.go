@@ -0,0 +1,52 @@
+package install
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestApplyRegistryCredentials(t *testing.T) {
+	p := &Plan{
+		Docker: Docker{
+			RegistryCredentials: []RegistryCredential{
+				{Server: "registry.example.com:5000", Username: "alice", Password: `pa"ss:word`},
+				{Server: "quay.io", Username: "robot", Password: "token"},
+			},
+			PullSecretNamespaces: []string{"default", "team-a"},
+		},
+	}
+	cc := &ansible.ClusterCatalog{}
+	if err := applyRegistryCredentials(p, cc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var config dockerConfig
+	if err := json.Unmarshal([]byte(cc.RegistryCredentials.DockerConfigJSON), &config); err != nil {
+		t.Fatalf("docker config is not valid JSON: %v", err)
+	}
+	if len(config.Auths) != 2 {
+		t.Fatalf("expected 2 registries, but got %v", config.Auths)
+	}
+	auth := config.Auths["registry.example.com:5000"]
+	if auth.Username != "alice" || auth.Password != `pa"ss:word` {
+		t.Errorf("unexpected credentials %+v", auth)
+	}
+	if auth.Auth != base64.StdEncoding.EncodeToString([]byte(`alice:pa"ss:word`)) {
+		t.Errorf("unexpected auth %q", auth.Auth)
+	}
+	if len(cc.RegistryCredentials.Namespaces) != 2 {
+		t.Errorf("expected the pull secret namespaces, but got %v", cc.RegistryCredentials.Namespaces)
+	}
+}
+
+func TestApplyRegistryCredentialsNone(t *testing.T) {
+	cc := &ansible.ClusterCatalog{}
+	if err := applyRegistryCredentials(&Plan{}, cc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cc.RegistryCredentials.DockerConfigJSON != "" {
+		t.Errorf("expected no docker config, but got %q", cc.RegistryCredentials.DockerConfigJSON)
+	}
+}
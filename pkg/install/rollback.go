@@ -0,0 +1,125 @@
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The states of a rollback point
+const (
+	RollbackPointUpgrading  = "upgrading"
+	RollbackPointFailed     = "failed"
+	RollbackPointSucceeded  = "succeeded"
+	RollbackPointRolledBack = "rolled-back"
+)
+
+// RollbackPoint records the state of the cluster before it is upgraded, so
+// that its control plane can be rolled back to the prior version if the
+// upgrade fails. The etcd snapshot and the configuration of the master nodes
+// of the rollback point are stored in the generated assets directory and on
+// the master nodes respectively. The snapshot is not kept in the assets
+// storage of the cluster, so the cluster can only be rolled back from the
+// machine that upgraded it.
+type RollbackPoint struct {
+	// Name of the rollback point, that names the etcd snapshot and the
+	// archive of the master configuration
+	Name        string    `json:"name"`
+	ClusterName string    `json:"clusterName"`
+	Time        time.Time `json:"time"`
+	// TargetVersion is the version of Kismatic that the cluster is upgraded to
+	TargetVersion string `json:"targetVersion"`
+	// KubernetesVersion is the version of the kubelet on the master nodes
+	// before the upgrade. It is empty if it could not be determined.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// NodeVersions are the versions of Kismatic of the nodes before the
+	// upgrade, keyed by host
+	NodeVersions map[string]string `json:"nodeVersions"`
+	// Status is the state of the upgrade
+	Status string `json:"status"`
+}
+
+// NewRollbackPoint returns a rollback point for the cluster of the given
+// versions, that is about to be upgraded
+func NewRollbackPoint(plan Plan, cv ClusterVersion, kubernetesVersion string) RollbackPoint {
+	now := time.Now()
+	p := RollbackPoint{
+		Name:              now.UTC().Format("20060102T150405Z"),
+		ClusterName:       plan.Cluster.Name,
+		Time:              now,
+		TargetVersion:     KismaticVersion.String(),
+		KubernetesVersion: kubernetesVersion,
+		NodeVersions:      map[string]string{},
+		Status:            RollbackPointUpgrading,
+	}
+	for _, n := range cv.Nodes {
+		p.NodeVersions[n.Node.Host] = n.Version.String()
+	}
+	return p
+}
+
+// RollbackDirectory returns the directory where the rollback points are
+// stored within the generated assets directory
+func RollbackDirectory(generatedAssetsDir string) string {
+	return filepath.Join(generatedAssetsDir, "rollback")
+}
+
+// WriteRollbackPoint persists the rollback point in the generated assets
+// directory
+func WriteRollbackPoint(p RollbackPoint, generatedAssetsDir string) error {
+	dir := RollbackDirectory(generatedAssetsDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating rollback directory: %v", err)
+	}
+	d, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling rollback point: %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, p.Name+".json"), d, 0600); err != nil {
+		return fmt.Errorf("error writing rollback point: %v", err)
+	}
+	return nil
+}
+
+// LatestRollbackPoint returns the most recent rollback point that is stored
+// in the generated assets directory, or nil if there is none
+func LatestRollbackPoint(generatedAssetsDir string) (*RollbackPoint, error) {
+	files, err := filepath.Glob(filepath.Join(RollbackDirectory(generatedAssetsDir), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing rollback points: %v", err)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	// the names of the rollback points sort in chronological order
+	sort.Strings(files)
+	d, err := ioutil.ReadFile(files[len(files)-1])
+	if err != nil {
+		return nil, fmt.Errorf("error reading rollback point: %v", err)
+	}
+	p := &RollbackPoint{}
+	if err = json.Unmarshal(d, p); err != nil {
+		return nil, fmt.Errorf("error unmarshaling rollback point %q: %v", files[len(files)-1], err)
+	}
+	return p, nil
+}
+
+// UpgradedNonControlPlaneNodes returns the nodes that are not etcd or master
+// nodes, and that were upgraded since the rollback point. Rolling back the
+// control plane would leave their components newer than the API server.
+func (p RollbackPoint) UpgradedNonControlPlaneNodes(cv ClusterVersion) []string {
+	var upgraded []string
+	for _, n := range cv.Nodes {
+		if containsAny(n.Roles, []string{"etcd", "master"}) {
+			continue
+		}
+		if v, ok := p.NodeVersions[n.Node.Host]; ok && v != n.Version.String() {
+			upgraded = append(upgraded, n.Node.Host)
+		}
+	}
+	return upgraded
+}
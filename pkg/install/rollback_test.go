@@ -0,0 +1,80 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+func TestRollbackPointRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollback")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := LatestRollbackPoint(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p != nil {
+		t.Errorf("expected no rollback point, but got %v", p)
+	}
+
+	older := RollbackPoint{Name: "20171201T100000Z", ClusterName: "test", NodeVersions: map[string]string{"master01": "1.5.0"}, Status: RollbackPointSucceeded}
+	newer := RollbackPoint{Name: "20171215T100000Z", ClusterName: "test", NodeVersions: map[string]string{"master01": "1.6.0"}, Status: RollbackPointFailed}
+	for _, p := range []RollbackPoint{newer, older} {
+		if err = WriteRollbackPoint(p, dir); err != nil {
+			t.Fatalf("error writing rollback point: %v", err)
+		}
+	}
+	p, err = LatestRollbackPoint(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p == nil || !reflect.DeepEqual(*p, newer) {
+		t.Errorf("expected the latest rollback point %v, but got %v", newer, p)
+	}
+}
+
+func TestUpgradedNonControlPlaneNodes(t *testing.T) {
+	point := RollbackPoint{
+		NodeVersions: map[string]string{
+			"etcd01":   "1.6.0",
+			"master01": "1.6.0",
+			"worker01": "1.6.0",
+			"worker02": "1.6.0",
+		},
+	}
+	listable := func(host string, role string, version string) ListableNode {
+		return ListableNode{Node: Node{Host: host}, Roles: []string{role}, Version: semver.MustParse(version)}
+	}
+	tests := []struct {
+		name     string
+		nodes    []ListableNode
+		upgraded []string
+	}{
+		{
+			name:  "control plane upgraded",
+			nodes: []ListableNode{listable("etcd01", "etcd", "1.7.0"), listable("master01", "master", "1.7.0"), listable("worker01", "worker", "1.6.0")},
+		},
+		{
+			name:     "worker upgraded",
+			nodes:    []ListableNode{listable("master01", "master", "1.7.0"), listable("worker01", "worker", "1.7.0"), listable("worker02", "worker", "1.6.0")},
+			upgraded: []string{"worker01"},
+		},
+		{
+			name:  "node added after the rollback point",
+			nodes: []ListableNode{listable("worker03", "worker", "1.7.0")},
+		},
+	}
+	for _, test := range tests {
+		upgraded := point.UpgradedNonControlPlaneNodes(ClusterVersion{Nodes: test.nodes})
+		if !reflect.DeepEqual(upgraded, test.upgraded) {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.upgraded, upgraded)
+		}
+	}
+}
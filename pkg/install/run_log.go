@@ -0,0 +1,128 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const runIndexFilename = "run-index.yaml"
+
+// runIndexLock serializes the updates of the run index by the runs that
+// execute concurrently, such as the runs of a batch
+var runIndexLock sync.Mutex
+
+// The results of a run
+const (
+	RunResultRunning   = "running"
+	RunResultSucceeded = "succeeded"
+	RunResultFailed    = "failed"
+	RunResultCancelled = "cancelled"
+)
+
+// RunRecord is a run of a kismatic operation, and the location of its log
+type RunRecord struct {
+	// ID identifies the run, as the operation and the time it started,
+	// such as apply-2018-03-01-10-00-00
+	ID string `yaml:"id" json:"id"`
+	// Task is the name of the operation, such as apply or upgrade-nodes
+	Task     string    `yaml:"task" json:"task"`
+	Started  time.Time `yaml:"started" json:"started"`
+	Finished time.Time `yaml:"finished,omitempty" json:"finished,omitempty"`
+	// Result is one of running, succeeded, failed or cancelled. A run that
+	// is still running when kismatic is killed stays running.
+	Result string `yaml:"result" json:"result"`
+	// Log is the path of the Ansible log of the run, relative to the runs directory
+	Log string `yaml:"log" json:"log"`
+}
+
+// ReadRunIndex returns the runs recorded in the runs directory, in the
+// order they started
+func ReadRunIndex(runsDirectory string) ([]RunRecord, error) {
+	file := filepath.Join(runsDirectory, runIndexFilename)
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading run index from %q: %v", file, err)
+	}
+	var records []RunRecord
+	if err := yaml.Unmarshal(b, &records); err != nil {
+		return nil, fmt.Errorf("error unmarshaling run index from %q: %v", file, err)
+	}
+	return records, nil
+}
+
+// FindRun returns the run with the given ID. The latest run is returned
+// when the ID is "latest".
+func FindRun(runsDirectory string, id string) (*RunRecord, error) {
+	records, err := ReadRunIndex(runsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if id == "latest" && len(records) > 0 {
+		return &records[len(records)-1], nil
+	}
+	for i := range records {
+		if records[i].ID == id {
+			return &records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("run %q was not found in %q", id, filepath.Join(runsDirectory, runIndexFilename))
+}
+
+// newRunRecord returns the record of a run that is starting in the run
+// directory, which is in the runs directory
+func newRunRecord(runsDirectory, runDirectory, task string, started time.Time) (RunRecord, error) {
+	logFile, err := filepath.Rel(runsDirectory, filepath.Join(runDirectory, "ansible.log"))
+	if err != nil {
+		return RunRecord{}, err
+	}
+	return RunRecord{
+		ID:      fmt.Sprintf("%s-%s", task, filepath.Base(runDirectory)),
+		Task:    task,
+		Started: started,
+		Result:  RunResultRunning,
+		Log:     filepath.ToSlash(logFile),
+	}, nil
+}
+
+// recordRun adds the run to the run index, or updates it if it is already
+// in the index
+func recordRun(runsDirectory string, r RunRecord) error {
+	runIndexLock.Lock()
+	defer runIndexLock.Unlock()
+	records, err := ReadRunIndex(runsDirectory)
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range records {
+		if records[i].ID == r.ID {
+			records[i] = r
+			found = true
+		}
+	}
+	if !found {
+		records = append(records, r)
+	}
+	return writeYAMLFile(filepath.Join(runsDirectory, runIndexFilename), records)
+}
+
+// runResult returns the result of a run that returned the error
+func runResult(err error) string {
+	switch err.(type) {
+	case nil:
+		return RunResultSucceeded
+	case CancelledError:
+		return RunResultCancelled
+	default:
+		return RunResultFailed
+	}
+}
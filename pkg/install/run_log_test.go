@@ -0,0 +1,82 @@
+package install
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordRun(t *testing.T) {
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+
+	started := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	apply, err := newRunRecord(runsDir, filepath.Join(runsDir, "apply", "2018-03-01-10-00-00"), "apply", started)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apply.ID != "apply-2018-03-01-10-00-00" {
+		t.Errorf("unexpected run ID %q", apply.ID)
+	}
+	if apply.Log != "apply/2018-03-01-10-00-00/ansible.log" {
+		t.Errorf("unexpected log path %q", apply.Log)
+	}
+	if err = recordRun(runsDir, apply); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	apply.Finished = started.Add(time.Minute)
+	apply.Result = runResult(errors.New("error running playbook"))
+	if err = recordRun(runsDir, apply); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	upgrade, err := newRunRecord(runsDir, filepath.Join(runsDir, "upgrade-nodes", "2018-03-01-11-00-00"), "upgrade-nodes", started.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = recordRun(runsDir, upgrade); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := ReadRunIndex(runsDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 runs to be recorded, but got %d", len(records))
+	}
+	if records[0].Result != RunResultFailed || !records[0].Finished.Equal(apply.Finished) {
+		t.Errorf("expected the apply run to be updated, but got %+v", records[0])
+	}
+	if records[1].Result != RunResultRunning {
+		t.Errorf("expected the upgrade run to be running, but got %q", records[1].Result)
+	}
+
+	latest, err := FindRun(runsDir, "latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if latest.ID != upgrade.ID {
+		t.Errorf("expected the latest run to be %q, but got %q", upgrade.ID, latest.ID)
+	}
+	if _, err = FindRun(runsDir, "apply-2017-01-01-00-00-00"); err == nil {
+		t.Errorf("expected an error finding a run that does not exist")
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, RunResultSucceeded},
+		{errors.New("error running playbook"), RunResultFailed},
+		{CancelledError{Task: "apply"}, RunResultCancelled},
+	}
+	for _, test := range tests {
+		if got := runResult(test.err); got != test.expected {
+			t.Errorf("%v: expected %q, but got %q", test.err, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package install
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	secretsStoreProviderVault = "vault"
+	secretsStoreProviderAWS   = "aws-secrets-manager"
+	// secretsIndexName is the name of the secret that lists the assets kept
+	// in the store, so that they can be fetched without listing the store
+	secretsIndexName = "index"
+)
+
+func secretsStoreProviders() []string {
+	return []string{secretsStoreProviderVault, secretsStoreProviderAWS}
+}
+
+// secretsStore keeps the sensitive assets generated for the cluster
+type secretsStore interface {
+	put(name string, data []byte) error
+	// get returns the asset, or nil if it is not in the store
+	get(name string) ([]byte, error)
+}
+
+func newSecretsStore(s ClusterSecretsStore) (secretsStore, error) {
+	switch s.Provider {
+	case secretsStoreProviderVault:
+		run, err := commandLineTool("vault")
+		if err != nil {
+			return nil, err
+		}
+		return vaultSecretsStore{path: s.Path, run: run}, nil
+	case secretsStoreProviderAWS:
+		run, err := commandLineTool("aws")
+		if err != nil {
+			return nil, err
+		}
+		return awsSecretsStore{prefix: s.Path, run: run}, nil
+	default:
+		return nil, fmt.Errorf("secrets store provider %q is not supported", s.Provider)
+	}
+}
+
+// FetchSecretAssets writes the sensitive assets kept in the secrets store of
+// the cluster to the generated assets directory, and returns the number of
+// assets fetched. Nothing is fetched when the plan has no secrets store.
+func FetchSecretAssets(p *Plan, generatedAssetsDir string) (int, error) {
+	if p.Cluster.SecretsStore.Provider == "" {
+		return 0, nil
+	}
+	store, err := newSecretsStore(p.Cluster.SecretsStore)
+	if err != nil {
+		return 0, err
+	}
+	return fetchSecretAssets(store, generatedAssetsDir)
+}
+
+// StoreSecretAssets moves the sensitive assets in the generated assets
+// directory to the secrets store of the cluster, and returns the number of
+// assets stored. Nothing is stored when the plan has no secrets store.
+func StoreSecretAssets(p *Plan, generatedAssetsDir string) (int, error) {
+	if p.Cluster.SecretsStore.Provider == "" {
+		return 0, nil
+	}
+	store, err := newSecretsStore(p.Cluster.SecretsStore)
+	if err != nil {
+		return 0, err
+	}
+	return storeSecretAssets(store, generatedAssetsDir)
+}
+
+func fetchSecretAssets(store secretsStore, generatedAssetsDir string) (int, error) {
+	index, err := store.get(secretsIndexName)
+	if err != nil {
+		return 0, fmt.Errorf("error reading the index of the secrets store: %v", err)
+	}
+	if index == nil {
+		return 0, nil
+	}
+	names := strings.Fields(string(index))
+	for _, name := range names {
+		data, err := store.get(name)
+		if err != nil {
+			return 0, fmt.Errorf("error reading %q from the secrets store: %v", name, err)
+		}
+		if data == nil {
+			return 0, fmt.Errorf("%q is listed in the index of the secrets store, but was not found", name)
+		}
+		file := filepath.Join(generatedAssetsDir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return 0, fmt.Errorf("error creating directory for %q: %v", name, err)
+		}
+		if err = ioutil.WriteFile(file, data, 0600); err != nil {
+			return 0, fmt.Errorf("error writing %q: %v", file, err)
+		}
+	}
+	return len(names), nil
+}
+
+func storeSecretAssets(store secretsStore, generatedAssetsDir string) (int, error) {
+	names, err := secretAssets(generatedAssetsDir)
+	if err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		return 0, nil
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(generatedAssetsDir, filepath.FromSlash(name)))
+		if err != nil {
+			return 0, err
+		}
+		if err = store.put(name, data); err != nil {
+			return 0, fmt.Errorf("error writing %q to the secrets store: %v", name, err)
+		}
+	}
+	// the index is written last, so that it only lists assets that were stored
+	if err = store.put(secretsIndexName, []byte(strings.Join(names, "\n"))); err != nil {
+		return 0, fmt.Errorf("error writing the index of the secrets store: %v", err)
+	}
+	for _, name := range names {
+		if err = os.Remove(filepath.Join(generatedAssetsDir, filepath.FromSlash(name))); err != nil {
+			return 0, fmt.Errorf("error removing %q from the generated assets directory: %v", name, err)
+		}
+	}
+	return len(names), nil
+}
+
+// secretAssets returns the sensitive assets in the generated assets directory,
+// as slash separated paths relative to the directory: the private keys and
+// the kubeconfig file
+func secretAssets(generatedAssetsDir string) ([]string, error) {
+	keys, err := filepath.Glob(filepath.Join(generatedAssetsDir, "keys", "*-key.pem"))
+	if err != nil {
+		return nil, err
+	}
+	files := keys
+	kubeconfig := filepath.Join(generatedAssetsDir, kubeconfigFilename)
+	if _, err = os.Stat(kubeconfig); err == nil {
+		files = append(files, kubeconfig)
+	}
+	var names []string
+	for _, f := range files {
+		rel, err := filepath.Rel(generatedAssetsDir, f)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, filepath.ToSlash(rel))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// commandRunner runs a command line tool, such as the one of a secret
+// manager, with the arguments, and returns its standard output
+type commandRunner func(stdin []byte, args ...string) (string, error)
+
+func commandLineTool(name string) (commandRunner, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("command not found: %s", name)
+	}
+	return func(stdin []byte, args ...string) (string, error) {
+		cmd := exec.Command(path, args...)
+		cmd.Stdin = bytes.NewReader(stdin)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("error running %s %s: %v: %s", name, strings.Join(args[:2], " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return string(out), nil
+	}, nil
+}
+
+// The assets are base64 encoded, as the command line tools trim the values
+// they print
+func encodeSecret(data []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(data))
+}
+
+func decodeSecret(out string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(out))
+}
+
+// vaultSecretsStore keeps the assets in the key/value secrets engine of Vault
+type vaultSecretsStore struct {
+	path string
+	run  commandRunner
+}
+
+func (s vaultSecretsStore) put(name string, data []byte) error {
+	_, err := s.run(encodeSecret(data), "kv", "put", s.path+"/"+name, "value=-")
+	return err
+}
+
+func (s vaultSecretsStore) get(name string) ([]byte, error) {
+	out, err := s.run(nil, "kv", "get", "-field=value", s.path+"/"+name)
+	if err != nil {
+		if strings.Contains(err.Error(), "No value found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return decodeSecret(out)
+}
+
+// awsSecretsStore keeps each asset in its own secret of AWS Secrets Manager
+type awsSecretsStore struct {
+	prefix string
+	run    commandRunner
+}
+
+func (s awsSecretsStore) put(name string, data []byte) error {
+	id := s.prefix + "/" + name
+	// the secret is read from stdin, so that it does not show up in the process list
+	_, err := s.run(encodeSecret(data), "secretsmanager", "put-secret-value", "--secret-id", id, "--secret-string", "file:///dev/stdin")
+	if err != nil && strings.Contains(err.Error(), "ResourceNotFoundException") {
+		_, err = s.run(encodeSecret(data), "secretsmanager", "create-secret", "--name", id, "--secret-string", "file:///dev/stdin")
+	}
+	return err
+}
+
+func (s awsSecretsStore) get(name string) ([]byte, error) {
+	out, err := s.run(nil, "secretsmanager", "get-secret-value", "--secret-id", s.prefix+"/"+name, "--query", "SecretString", "--output", "text")
+	if err != nil {
+		if strings.Contains(err.Error(), "ResourceNotFoundException") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return decodeSecret(out)
+}
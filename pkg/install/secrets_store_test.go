@@ -0,0 +1,160 @@
+package install
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeSecretsStore map[string][]byte
+
+func (s fakeSecretsStore) put(name string, data []byte) error {
+	s[name] = data
+	return nil
+}
+
+func (s fakeSecretsStore) get(name string) ([]byte, error) {
+	return s[name], nil
+}
+
+func TestStoreAndFetchSecretAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"keys/ca-key.pem":              "ca key",
+		"keys/ca.pem":                  "ca cert",
+		"keys/service-account-key.pem": "service account key",
+		"kubeconfig":                   "kubeconfig",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatalf("error creating dir: %v", err)
+		}
+		if err = ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	store := fakeSecretsStore{}
+	n, err := storeSecretAssets(store, dir)
+	if err != nil {
+		t.Fatalf("unexpected error storing assets: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 assets to be stored, but got %d", n)
+	}
+	expectedIndex := "keys/ca-key.pem\nkeys/service-account-key.pem\nkubeconfig"
+	if string(store[secretsIndexName]) != expectedIndex {
+		t.Errorf("expected index %q, but got %q", expectedIndex, store[secretsIndexName])
+	}
+	remaining, err := secretAssets(dir)
+	if err != nil {
+		t.Fatalf("unexpected error listing assets: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("expected the stored assets to be removed, but found %v", remaining)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "keys", "ca.pem")); err != nil {
+		t.Errorf("expected the CA certificate to be kept: %v", err)
+	}
+
+	n, err = fetchSecretAssets(store, dir)
+	if err != nil {
+		t.Fatalf("unexpected error fetching assets: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 assets to be fetched, but got %d", n)
+	}
+	for name, content := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("error reading %q: %v", name, err)
+			continue
+		}
+		if string(b) != content {
+			t.Errorf("expected %q to contain %q, but got %q", name, content, b)
+		}
+	}
+}
+
+func TestFetchSecretAssetsEmptyStore(t *testing.T) {
+	n, err := fetchSecretAssets(fakeSecretsStore{}, "")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected no assets to be fetched, but got %d", n)
+	}
+}
+
+func TestSecretsStoreCommands(t *testing.T) {
+	tests := []struct {
+		store       func(commandRunner) secretsStore
+		notFoundErr string
+		expectedPut [][]string
+		expectedGet []string
+	}{
+		{
+			store:       func(run commandRunner) secretsStore { return vaultSecretsStore{path: "secret/prod", run: run} },
+			notFoundErr: "No value found at secret/prod/kubeconfig",
+			expectedPut: [][]string{{"kv", "put", "secret/prod/kubeconfig", "value=-"}},
+			expectedGet: []string{"kv", "get", "-field=value", "secret/prod/kubeconfig"},
+		},
+		{
+			store:       func(run commandRunner) secretsStore { return awsSecretsStore{prefix: "kismatic/prod", run: run} },
+			notFoundErr: "An error occurred (ResourceNotFoundException)",
+			expectedPut: [][]string{
+				{"secretsmanager", "put-secret-value", "--secret-id", "kismatic/prod/kubeconfig", "--secret-string", "file:///dev/stdin"},
+				{"secretsmanager", "create-secret", "--name", "kismatic/prod/kubeconfig", "--secret-string", "file:///dev/stdin"},
+			},
+			expectedGet: []string{"secretsmanager", "get-secret-value", "--secret-id", "kismatic/prod/kubeconfig", "--query", "SecretString", "--output", "text"},
+		},
+	}
+	for i, test := range tests {
+		// the store is empty, so reading or updating a secret fails with a
+		// not found error
+		var calls [][]string
+		var stdin []string
+		run := func(in []byte, args ...string) (string, error) {
+			calls = append(calls, args)
+			stdin = append(stdin, string(in))
+			switch args[1] {
+			case "get", "get-secret-value", "put-secret-value":
+				return "", errors.New(test.notFoundErr)
+			}
+			return "", nil
+		}
+		store := test.store(run)
+		if err := store.put("kubeconfig", []byte("config")); err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(calls, test.expectedPut) {
+			t.Errorf("test %d: expected put commands %v, but got %v", i, test.expectedPut, calls)
+		}
+		for _, in := range stdin {
+			if strings.Contains(in, "config") {
+				t.Errorf("test %d: expected the secret to be encoded, but got %q", i, in)
+			}
+		}
+
+		calls = nil
+		data, err := store.get("kubeconfig")
+		if err != nil {
+			t.Errorf("test %d: expected a missing secret not to be an error, but got: %v", i, err)
+		}
+		if data != nil {
+			t.Errorf("test %d: expected no data, but got %q", i, data)
+		}
+		if len(calls) != 1 || !reflect.DeepEqual(calls[0], test.expectedGet) {
+			t.Errorf("test %d: expected get command %v, but got %v", i, test.expectedGet, calls)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const operationHistoryFilename = "operation-history.yaml"
+
+// OperationRecord is the duration of a successful run of a kismatic operation
+type OperationRecord struct {
+	// Task is the name of the operation, such as apply or upgrade-nodes
+	Task string `yaml:"task"`
+	// Version is the version of kismatic that ran the operation
+	Version string `yaml:"version"`
+	// Nodes is the number of nodes the operation ran on
+	Nodes   int       `yaml:"nodes"`
+	Started time.Time `yaml:"started"`
+	Seconds float64   `yaml:"seconds"`
+	// PhaseSeconds is the time spent in each phase of the operation
+	PhaseSeconds map[string]float64 `yaml:"phase_seconds,omitempty"`
+}
+
+// OperationStats are the aggregated durations of the runs of an operation
+// with a given version of kismatic
+type OperationStats struct {
+	Task        string  `json:"task"`
+	Version     string  `json:"version"`
+	Runs        int     `json:"runs"`
+	MeanSeconds float64 `json:"meanSeconds"`
+	MaxSeconds  float64 `json:"maxSeconds"`
+	// FixedSeconds and SecondsPerNode are the least squares fit of the
+	// duration to the number of nodes. SecondsPerNode is zero if all runs
+	// had the same number of nodes.
+	FixedSeconds   float64      `json:"fixedSeconds"`
+	SecondsPerNode float64      `json:"secondsPerNode"`
+	Phases         []PhaseStats `json:"phases,omitempty"`
+}
+
+// PhaseStats are the aggregated durations of a phase of an operation
+type PhaseStats struct {
+	Phase       string  `json:"phase"`
+	MeanSeconds float64 `json:"meanSeconds"`
+	MaxSeconds  float64 `json:"maxSeconds"`
+}
+
+// Estimate returns the expected duration of the operation on the given
+// number of nodes
+func (s OperationStats) Estimate(nodes int) time.Duration {
+	secs := s.FixedSeconds + s.SecondsPerNode*float64(nodes)
+	if secs < 0 {
+		secs = 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// ReadOperationHistory returns the operations recorded in the runs directory
+func ReadOperationHistory(runsDirectory string) ([]OperationRecord, error) {
+	file := filepath.Join(runsDirectory, operationHistoryFilename)
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading operation history from %q: %v", file, err)
+	}
+	var records []OperationRecord
+	if err := yaml.Unmarshal(b, &records); err != nil {
+		return nil, fmt.Errorf("error unmarshaling operation history from %q: %v", file, err)
+	}
+	return records, nil
+}
+
+func recordOperation(runsDirectory string, r OperationRecord) error {
+	records, err := ReadOperationHistory(runsDirectory)
+	if err != nil {
+		return err
+	}
+	records = append(records, r)
+	return writeYAMLFile(filepath.Join(runsDirectory, operationHistoryFilename), records)
+}
+
+// AggregateOperationStats returns the stats of the recorded operations,
+// grouped by operation and kismatic version
+func AggregateOperationStats(records []OperationRecord) []OperationStats {
+	type key struct{ task, version string }
+	groups := map[key][]OperationRecord{}
+	var keys []key
+	for _, r := range records {
+		k := key{r.Task, r.Version}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].task != keys[j].task {
+			return keys[i].task < keys[j].task
+		}
+		return keys[i].version < keys[j].version
+	})
+
+	var stats []OperationStats
+	for _, k := range keys {
+		runs := groups[k]
+		s := OperationStats{Task: k.task, Version: k.version, Runs: len(runs)}
+		durations := make([]float64, 0, len(runs))
+		nodes := make([]float64, 0, len(runs))
+		phases := map[string][]float64{}
+		for _, r := range runs {
+			durations = append(durations, r.Seconds)
+			nodes = append(nodes, float64(r.Nodes))
+			for p, secs := range r.PhaseSeconds {
+				phases[p] = append(phases[p], secs)
+			}
+		}
+		s.MeanSeconds, s.MaxSeconds = meanAndMax(durations)
+		s.FixedSeconds, s.SecondsPerNode = leastSquares(nodes, durations)
+		for p, secs := range phases {
+			ps := PhaseStats{Phase: p}
+			ps.MeanSeconds, ps.MaxSeconds = meanAndMax(secs)
+			s.Phases = append(s.Phases, ps)
+		}
+		sort.Slice(s.Phases, func(i, j int) bool {
+			pi, pj := phaseIndex(s.Phases[i].Phase), phaseIndex(s.Phases[j].Phase)
+			if pi != pj {
+				return pi < pj
+			}
+			return s.Phases[i].Phase < s.Phases[j].Phase
+		})
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+func meanAndMax(values []float64) (float64, float64) {
+	var sum, max float64
+	for _, v := range values {
+		sum += v
+		max = math.Max(max, v)
+	}
+	return sum / float64(len(values)), max
+}
+
+// leastSquares returns the intercept and slope of the line that fits the
+// points. The slope is zero if all points have the same x.
+func leastSquares(x, y []float64) (float64, float64) {
+	meanX, _ := meanAndMax(x)
+	meanY, _ := meanAndMax(y)
+	var cov, varX float64
+	for i := range x {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+		varX += (x[i] - meanX) * (x[i] - meanX)
+	}
+	if varX == 0 {
+		return meanY, 0
+	}
+	slope := cov / varX
+	return meanY - slope*meanX, slope
+}
+
+// phaseTimer measures the time spent in each phase of an operation, using
+// the phases of the plays that run
+type phaseTimer struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	phase   string
+	started time.Time
+	seconds map[string]float64
+}
+
+// newPhaseTimer returns a timer for the task. Plays that come before the
+// first play of a known phase are timed as the phase of the task, or as
+// the task itself.
+func newPhaseTimer(task string) *phaseTimer {
+	phase := task
+	if tp, ok := taskPhases[task]; ok {
+		phase = tp.start
+	}
+	return &phaseTimer{now: time.Now, phase: phase, seconds: map[string]float64{}}
+}
+
+func (t *phaseTimer) startPlay(play string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lap()
+	if p := phaseOfPlay(play); p != "" {
+		t.phase = p
+	}
+}
+
+func (t *phaseTimer) stop() map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lap()
+	t.started = time.Time{}
+	return t.seconds
+}
+
+func (t *phaseTimer) lap() {
+	now := t.now()
+	if !t.started.IsZero() {
+		t.seconds[t.phase] += now.Sub(t.started).Seconds()
+	}
+	t.started = now
+}
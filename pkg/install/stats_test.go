@@ -0,0 +1,98 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAggregateOperationStats(t *testing.T) {
+	records := []OperationRecord{
+		{Task: "apply", Version: "1.5.0", Nodes: 5, Seconds: 600, PhaseSeconds: map[string]float64{phaseWorkers: 200, phaseEtcd: 100}},
+		{Task: "apply", Version: "1.5.0", Nodes: 10, Seconds: 800, PhaseSeconds: map[string]float64{phaseWorkers: 400, phaseEtcd: 100}},
+		{Task: "smoketest", Version: "1.5.0", Nodes: 5, Seconds: 60},
+		{Task: "apply", Version: "1.4.0", Nodes: 5, Seconds: 500},
+	}
+	stats := AggregateOperationStats(records)
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 stats, but got %d", len(stats))
+	}
+	if stats[0].Task != "apply" || stats[0].Version != "1.4.0" || stats[1].Version != "1.5.0" || stats[2].Task != "smoketest" {
+		t.Errorf("unexpected order of stats: %+v", stats)
+	}
+
+	apply := stats[1]
+	if apply.Runs != 2 || apply.MeanSeconds != 700 || apply.MaxSeconds != 800 {
+		t.Errorf("unexpected stats: %+v", apply)
+	}
+	if apply.FixedSeconds != 400 || apply.SecondsPerNode != 40 {
+		t.Errorf("expected a fit of 400s + 40s per node, but got %vs + %vs per node", apply.FixedSeconds, apply.SecondsPerNode)
+	}
+	if d := apply.Estimate(50); d != 2400*time.Second {
+		t.Errorf("expected an estimate of 2400s for 50 nodes, but got %v", d)
+	}
+	if len(apply.Phases) != 2 || apply.Phases[0].Phase != phaseEtcd || apply.Phases[1].Phase != phaseWorkers {
+		t.Fatalf("unexpected phases: %+v", apply.Phases)
+	}
+	if apply.Phases[1].MeanSeconds != 300 || apply.Phases[1].MaxSeconds != 400 {
+		t.Errorf("unexpected workers phase stats: %+v", apply.Phases[1])
+	}
+
+	// a single node count can't be fit, so the estimate is the mean
+	if smoke := stats[2]; smoke.SecondsPerNode != 0 || smoke.Estimate(50) != time.Minute {
+		t.Errorf("expected an estimate of 1m, but got %v", smoke.Estimate(50))
+	}
+}
+
+func TestRecordOperation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "operation-history-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, task := range []string{"apply", "smoketest"} {
+		if err := recordOperation(dir, OperationRecord{Task: task, Nodes: 3, Seconds: 10}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	records, err := ReadOperationHistory(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].Task != "apply" || records[1].Task != "smoketest" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
+
+func TestPhaseTimer(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	timer := newPhaseTimer("apply")
+	timer.now = func() time.Time { return now }
+
+	plays := []struct {
+		name    string
+		elapsed time.Duration
+	}{
+		{"Configure Cluster Prerequisites", 0},
+		{"Deploy Cluster Certificates", time.Minute},
+		{"Start Kubernetes Etcd Cluster", time.Minute},
+		{"Start Kubernetes API Server", 2 * time.Minute},
+		{"Update Kismatic Version File", time.Minute},
+	}
+	for _, p := range plays {
+		now = now.Add(p.elapsed)
+		timer.startPlay(p.name)
+	}
+	now = now.Add(time.Minute)
+	seconds := timer.stop()
+	expected := map[string]float64{phasePKI: 120, phaseEtcd: 120, phaseControlPlane: 120}
+	if len(seconds) != len(expected) {
+		t.Errorf("expected %v, but got %v", expected, seconds)
+	}
+	for p, s := range expected {
+		if seconds[p] != s {
+			t.Errorf("expected phase %q to take %vs, but got %vs", p, s, seconds[p])
+		}
+	}
+}
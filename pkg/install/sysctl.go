@@ -0,0 +1,72 @@
+package install
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+const sysctlProfileKubernetes = "kubernetes"
+
+func sysctlProfiles() []string {
+	return []string{sysctlProfileKubernetes}
+}
+
+// sysctlProfileSettings are the kernel parameters set by each tuning profile.
+// The connection tracking table is left to kube-proxy, which sizes it by the
+// number of CPU cores of the node.
+var sysctlProfileSettings = map[string]map[string]string{
+	sysctlProfileKubernetes: {
+		"fs.inotify.max_user_watches":   "524288",
+		"fs.inotify.max_user_instances": "8192",
+		"vm.max_map_count":              "262144",
+	},
+}
+
+func sysctlRoles() []string {
+	return []string{"etcd", "master", "worker", "ingress", "storage"}
+}
+
+var sysctlKeyRegexp = regexp.MustCompile(`^[a-z0-9_]+(\.[a-zA-Z0-9_-]+)+$`)
+
+func (s *NodeSysctl) validate() (bool, []error) {
+	v := newValidator()
+	if s.Profile != "" && !util.Contains(s.Profile, sysctlProfiles()) {
+		v.addError(fmt.Errorf("Profile %q is not valid, options are %v", s.Profile, sysctlProfiles()))
+	}
+	v.addError(validateSysctlSettings(s.Settings)...)
+	for role, settings := range s.RoleSettings {
+		if !util.Contains(role, sysctlRoles()) {
+			v.addError(fmt.Errorf("Role %q is not valid, options are %v", role, sysctlRoles()))
+		}
+		v.addError(validateSysctlSettings(settings)...)
+	}
+	return v.valid()
+}
+
+func validateSysctlSettings(settings map[string]string) []error {
+	var errs []error
+	for k, val := range settings {
+		if !sysctlKeyRegexp.MatchString(k) {
+			errs = append(errs, fmt.Errorf("Kernel parameter %q is not valid, must be a dotted name such as vm.max_map_count", k))
+		}
+		if val == "" {
+			errs = append(errs, fmt.Errorf("Value of kernel parameter %q cannot be empty", k))
+		}
+	}
+	return errs
+}
+
+// sysctlSettings returns the kernel parameters of the profile, along with
+// the settings of the plan, which take precedence
+func sysctlSettings(s NodeSysctl) map[string]string {
+	settings := map[string]string{}
+	for k, v := range sysctlProfileSettings[s.Profile] {
+		settings[k] = v
+	}
+	for k, v := range s.Settings {
+		settings[k] = v
+	}
+	return settings
+}
@@ -0,0 +1,73 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateNodeSysctl(t *testing.T) {
+	tests := []struct {
+		name   string
+		sysctl NodeSysctl
+		valid  bool
+	}{
+		{
+			name:  "no settings",
+			valid: true,
+		},
+		{
+			name:   "profile and settings",
+			sysctl: NodeSysctl{Profile: "kubernetes", Settings: map[string]string{"fs.file-max": "2097152", "net.ipv4.ip_local_port_range": "1024 65000"}},
+			valid:  true,
+		},
+		{
+			name:   "unknown profile",
+			sysctl: NodeSysctl{Profile: "fast"},
+		},
+		{
+			name:   "invalid key",
+			sysctl: NodeSysctl{Settings: map[string]string{"max_map_count": "262144"}},
+		},
+		{
+			name:   "empty value",
+			sysctl: NodeSysctl{Settings: map[string]string{"vm.max_map_count": ""}},
+		},
+		{
+			name:   "role settings",
+			sysctl: NodeSysctl{RoleSettings: map[string]map[string]string{"worker": {"net.netfilter.nf_conntrack_max": "1048576"}}},
+			valid:  true,
+		},
+		{
+			name:   "unknown role",
+			sysctl: NodeSysctl{RoleSettings: map[string]map[string]string{"workers": {"vm.max_map_count": "262144"}}},
+		},
+		{
+			name:   "invalid role setting",
+			sysctl: NodeSysctl{RoleSettings: map[string]map[string]string{"worker": {"max_map_count": "262144"}}},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.sysctl.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestSysctlSettings(t *testing.T) {
+	s := NodeSysctl{
+		Profile:  "kubernetes",
+		Settings: map[string]string{"vm.max_map_count": "524288", "fs.file-max": "2097152"},
+	}
+	expected := map[string]string{
+		"fs.inotify.max_user_watches":   "524288",
+		"fs.inotify.max_user_instances": "8192",
+		"vm.max_map_count":              "524288",
+		"fs.file-max":                   "2097152",
+	}
+	if got := sysctlSettings(s); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, but got %v", expected, got)
+	}
+	if got := sysctlSettings(NodeSysctl{}); len(got) != 0 {
+		t.Errorf("expected no settings, but got %v", got)
+	}
+}
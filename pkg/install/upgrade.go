@@ -162,7 +162,7 @@ func DetectNodeUpgradeSafety(plan Plan, node Node, kubeClient upgradeKubeInfoCli
 			// the volumes on the node. for now, we are choosing not to support online upgrade of storage nodes
 			errs = append(errs, storageNotSupportedErr{})
 		case "worker":
-			if plan.Worker.ExpectedCount < 2 {
+			if len(plan.workerNodes()) < 2 {
 				errs = append(errs, workerNodeCountErr{})
 			}
 			if workerErrs := detectWorkerNodeUpgradeSafety(node, kubeClient); workerErrs != nil {
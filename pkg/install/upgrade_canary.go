@@ -0,0 +1,61 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+type canaryHealthClient interface {
+	data.NodeLister
+	data.ComponentStatusLister
+}
+
+// CanaryUpgrade splits the nodes that need an upgrade in two batches. The
+// first batch contains the etcd and master nodes, and the given number of
+// canary worker nodes. The second batch contains the rest of the nodes, and
+// is empty when there are no more worker nodes than canaries.
+func CanaryUpgrade(nodes []ListableNode, canaryWorkers int) (canary []ListableNode, rest []ListableNode) {
+	for _, n := range nodes {
+		switch {
+		case isControlPlaneNode(n):
+			canary = append(canary, n)
+		case canaryWorkers > 0:
+			canary = append(canary, n)
+			canaryWorkers--
+		default:
+			rest = append(rest, n)
+		}
+	}
+	return canary, rest
+}
+
+func isControlPlaneNode(n ListableNode) bool {
+	for _, r := range n.Roles {
+		if r == "etcd" || r == "master" {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyCanaryHealth returns an error if a node of the cluster is not ready,
+// or if a control plane component or an etcd member is not healthy, once the
+// canary nodes have been upgraded. The skipped nodes, which were not ready
+// to be upgraded, are not checked.
+func VerifyCanaryHealth(plan Plan, kubeClient canaryHealthClient, skipped []string) error {
+	var r UpgradeReadinessReport
+	checkNodeHealth(&r, plan, kubeClient)
+	checkComponentHealth(&r, kubeClient)
+	r = r.ExcludeNodes(skipped)
+	failures := r.Failures()
+	if len(failures) == 0 {
+		return nil
+	}
+	problems := make([]string, 0, len(failures))
+	for _, f := range failures {
+		problems = append(problems, fmt.Sprintf("%s: %s", f.Subject, f.Message))
+	}
+	return fmt.Errorf("the cluster is not healthy: %s", strings.Join(problems, "; "))
+}
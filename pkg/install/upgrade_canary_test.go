@@ -0,0 +1,102 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+func TestCanaryUpgrade(t *testing.T) {
+	nodes := []ListableNode{
+		{Node: Node{Host: "etcd01"}, Roles: []string{"etcd"}},
+		{Node: Node{Host: "master01"}, Roles: []string{"master"}},
+		{Node: Node{Host: "worker01"}, Roles: []string{"worker"}},
+		{Node: Node{Host: "worker02"}, Roles: []string{"worker", "ingress"}},
+		{Node: Node{Host: "storage01"}, Roles: []string{"storage"}},
+	}
+	tests := []struct {
+		canaryWorkers int
+		canary        []string
+		rest          []string
+	}{
+		{
+			canaryWorkers: 0,
+			canary:        []string{"etcd01", "master01"},
+			rest:          []string{"worker01", "worker02", "storage01"},
+		},
+		{
+			canaryWorkers: 1,
+			canary:        []string{"etcd01", "master01", "worker01"},
+			rest:          []string{"worker02", "storage01"},
+		},
+		{
+			canaryWorkers: 3,
+			canary:        []string{"etcd01", "master01", "worker01", "worker02", "storage01"},
+		},
+		{
+			canaryWorkers: 5,
+			canary:        []string{"etcd01", "master01", "worker01", "worker02", "storage01"},
+		},
+	}
+	hosts := func(nodes []ListableNode) []string {
+		var h []string
+		for _, n := range nodes {
+			h = append(h, n.Node.Host)
+		}
+		return h
+	}
+	for i, test := range tests {
+		canary, rest := CanaryUpgrade(nodes, test.canaryWorkers)
+		if !reflect.DeepEqual(hosts(canary), test.canary) {
+			t.Errorf("test %d: expected canary nodes %v, but got %v", i, test.canary, hosts(canary))
+		}
+		if !reflect.DeepEqual(hosts(rest), test.rest) {
+			t.Errorf("test %d: expected remaining nodes %v, but got %v", i, test.rest, hosts(rest))
+		}
+	}
+}
+
+func TestVerifyCanaryHealth(t *testing.T) {
+	plan := Plan{}
+	plan.Master.Nodes = []Node{{Host: "master01"}}
+	plan.Worker.Nodes = []Node{{Host: "worker01"}, {Host: "worker02"}}
+	components := &data.ComponentStatusList{Items: []data.ComponentStatus{component("etcd-0", true)}}
+	tests := []struct {
+		name      string
+		nodes     []data.Node
+		skipped   []string
+		expectErr bool
+	}{
+		{
+			name:  "healthy",
+			nodes: []data.Node{readyNode("master01", true), readyNode("worker01", true), readyNode("worker02", true)},
+		},
+		{
+			name:      "worker not ready",
+			nodes:     []data.Node{readyNode("master01", true), readyNode("worker01", true), readyNode("worker02", false)},
+			expectErr: true,
+		},
+		{
+			name:    "skipped worker not ready",
+			nodes:   []data.Node{readyNode("master01", true), readyNode("worker01", true), readyNode("worker02", false)},
+			skipped: []string{"worker02"},
+		},
+		{
+			name:      "other worker not ready",
+			nodes:     []data.Node{readyNode("master01", true), readyNode("worker01", false), readyNode("worker02", false)},
+			skipped:   []string{"worker02"},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		client := fakeReadinessClient{nodes: &data.NodeList{Items: test.nodes}, components: components}
+		err := VerifyCanaryHealth(plan, client, test.skipped)
+		if err != nil && !test.expectErr {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if err == nil && test.expectErr {
+			t.Errorf("%s: expected an error, but didn't get one", test.name)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package install
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+)
+
+// UpgradePath returns the minor versions of Kismatic, in order, whose last
+// patch release must upgrade a cluster at the given version before this
+// version of Kismatic can upgrade it. It is empty when the cluster can be
+// upgraded directly.
+func UpgradePath(from semver.Version) ([]string, error) {
+	return upgradePath(from, KismaticVersion)
+}
+
+// Kismatic upgrades a cluster from the same minor version, or from the
+// previous minor version, so a cluster that is more than one minor version
+// behind is upgraded with each of the minor versions in between
+func upgradePath(from, to semver.Version) ([]string, error) {
+	if !from.LT(to) {
+		return nil, nil
+	}
+	if from.Major != to.Major {
+		return nil, fmt.Errorf("upgrading a cluster from v%s to v%s is not supported", from, to)
+	}
+	var path []string
+	for minor := from.Minor + 1; minor < to.Minor; minor++ {
+		path = append(path, fmt.Sprintf("v%d.%d", to.Major, minor))
+	}
+	return path, nil
+}
+
+// LatestPatchReleases returns the last patch release of each of the minor
+// versions of the upgrade path, out of the released versions of Kismatic.
+// Pre-releases are ignored.
+func LatestPatchReleases(path []string, released []semver.Version) ([]semver.Version, error) {
+	var releases []semver.Version
+	for _, minor := range path {
+		m, err := semver.Parse(strings.TrimPrefix(minor, "v") + ".0")
+		if err != nil {
+			return nil, fmt.Errorf("invalid minor version %q in the upgrade path: %v", minor, err)
+		}
+		var latest *semver.Version
+		for i, v := range released {
+			if v.Major != m.Major || v.Minor != m.Minor || len(v.Pre) > 0 {
+				continue
+			}
+			if latest == nil || v.GT(*latest) {
+				latest = &released[i]
+			}
+		}
+		if latest == nil {
+			return nil, fmt.Errorf("no release of KET %s was found", minor)
+		}
+		releases = append(releases, *latest)
+	}
+	return releases, nil
+}
+
+// KismaticReleases lists and downloads the releases of Kismatic, which are
+// used to upgrade a cluster with each of the minor versions in between
+type KismaticReleases struct {
+	// ListURL is the GitHub API URL that lists the releases
+	ListURL string
+	// DownloadURL is the URL of the release assets, which are found at
+	// <DownloadURL>/<version>/kismatic-<version>-<os>-amd64.tar.gz
+	DownloadURL string
+	Client      *http.Client
+}
+
+// DefaultKismaticReleases are the releases of Kismatic published on GitHub
+var DefaultKismaticReleases = KismaticReleases{
+	ListURL:     "https://api.github.com/repos/apprenda/kismatic/releases?per_page=100",
+	DownloadURL: "https://github.com/apprenda/kismatic/releases/download",
+	Client:      &http.Client{Timeout: 10 * time.Minute},
+}
+
+// Versions returns the released versions of Kismatic
+func (r KismaticReleases) Versions() ([]semver.Version, error) {
+	resp, err := r.Client.Get(r.ListURL)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the releases of KET: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error listing the releases of KET: %s", resp.Status)
+	}
+	var releases []struct {
+		TagName string `json:"tag_name"`
+		Draft   bool   `json:"draft"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("error decoding the releases of KET: %v", err)
+	}
+	var versions []semver.Version
+	for _, rel := range releases {
+		v, err := semver.Parse(strings.TrimPrefix(rel.TagName, "v"))
+		if err != nil || rel.Draft {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// Download downloads the release of the given version, and extracts it in a
+// directory under dir. It returns the directory of the release, where its
+// kismatic binary and ansible directory are.
+func (r KismaticReleases) Download(v semver.Version, dir string) (string, error) {
+	name := fmt.Sprintf("kismatic-v%s-%s-amd64.tar.gz", v, runtime.GOOS)
+	url := fmt.Sprintf("%s/v%s/%s", r.DownloadURL, v, name)
+	resp, err := r.Client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("error downloading %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error downloading %s: %s", url, resp.Status)
+	}
+	releaseDir := filepath.Join(dir, "v"+v.String())
+	if err = os.MkdirAll(releaseDir, 0700); err != nil {
+		return "", fmt.Errorf("error creating directory %q: %v", releaseDir, err)
+	}
+	if err = extractTarGz(resp.Body, releaseDir); err != nil {
+		return "", fmt.Errorf("error extracting %s: %v", name, err)
+	}
+	if _, err = os.Stat(filepath.Join(releaseDir, "kismatic")); err != nil {
+		return "", fmt.Errorf("the kismatic binary was not found in %s", name)
+	}
+	return releaseDir, nil
+}
+
+// extractTarGz extracts the regular files and directories of the gzipped tar
+// archive in the directory
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if name == "." {
+			continue
+		}
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("file %q is outside of the archive", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err = os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0755)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// UpgradePathCheckpoint records the progress of the upgrade of a cluster with
+// each of the minor versions of its upgrade path, so that an upgrade that
+// stops between two versions can be continued
+type UpgradePathCheckpoint struct {
+	// TargetVersion is the version of Kismatic the cluster is upgraded to
+	TargetVersion string `json:"targetVersion"`
+	// CompletedHops are the intermediate versions the cluster was upgraded
+	// to, in order
+	CompletedHops []UpgradeHop `json:"completedHops"`
+}
+
+// UpgradeHop is the upgrade of the cluster with an intermediate version
+type UpgradeHop struct {
+	Version   string    `json:"version"`
+	Completed time.Time `json:"completed"`
+}
+
+const upgradePathCheckpointFilename = "upgrade-path.json"
+
+// ReadUpgradePathCheckpoint returns the checkpoint of the upgrade path stored
+// in the generated assets directory, or nil if there is none
+func ReadUpgradePathCheckpoint(generatedAssetsDir string) (*UpgradePathCheckpoint, error) {
+	d, err := ioutil.ReadFile(filepath.Join(generatedAssetsDir, upgradePathCheckpointFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading upgrade path checkpoint: %v", err)
+	}
+	c := &UpgradePathCheckpoint{}
+	if err = json.Unmarshal(d, c); err != nil {
+		return nil, fmt.Errorf("error unmarshaling upgrade path checkpoint: %v", err)
+	}
+	return c, nil
+}
+
+// WriteUpgradePathCheckpoint persists the checkpoint of the upgrade path in
+// the generated assets directory
+func WriteUpgradePathCheckpoint(c UpgradePathCheckpoint, generatedAssetsDir string) error {
+	d, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling upgrade path checkpoint: %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(generatedAssetsDir, upgradePathCheckpointFilename), d, 0600); err != nil {
+		return fmt.Errorf("error writing upgrade path checkpoint: %v", err)
+	}
+	return nil
+}
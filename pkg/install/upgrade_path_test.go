@@ -0,0 +1,193 @@
+package install
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+func TestUpgradePath(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		expected []string
+		valid    bool
+	}{
+		{
+			from:  "1.6.0",
+			to:    "1.6.1",
+			valid: true,
+		},
+		{
+			from:  "1.5.2",
+			to:    "1.6.1",
+			valid: true,
+		},
+		{
+			from:  "1.6.1",
+			to:    "1.6.1",
+			valid: true,
+		},
+		{
+			// a node that is newer is not upgraded
+			from:  "1.7.0",
+			to:    "1.6.1",
+			valid: true,
+		},
+		{
+			from:     "1.4.1",
+			to:       "1.6.1",
+			expected: []string{"v1.5"},
+			valid:    true,
+		},
+		{
+			from:     "1.3.0",
+			to:       "1.6.0",
+			expected: []string{"v1.4", "v1.5"},
+			valid:    true,
+		},
+		{
+			from: "0.9.0",
+			to:   "1.6.0",
+		},
+	}
+	for i, test := range tests {
+		path, err := upgradePath(mustParseVersion(test.from), mustParseVersion(test.to))
+		if (err == nil) != test.valid {
+			t.Errorf("test %d: expected valid to be %v, but got error %v", i, test.valid, err)
+		}
+		if !reflect.DeepEqual(path, test.expected) {
+			t.Errorf("test %d: expected upgrade path %v, but got %v", i, test.expected, path)
+		}
+	}
+}
+
+func TestLatestPatchReleases(t *testing.T) {
+	released := []semver.Version{
+		mustParseVersion("1.4.0"),
+		mustParseVersion("1.4.2"),
+		mustParseVersion("1.4.1"),
+		mustParseVersion("1.5.0"),
+		mustParseVersion("1.5.1-alpha.1"),
+		mustParseVersion("1.6.0"),
+	}
+	releases, err := LatestPatchReleases([]string{"v1.4", "v1.5"}, released)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []semver.Version{mustParseVersion("1.4.2"), mustParseVersion("1.5.0")}
+	if !reflect.DeepEqual(releases, expected) {
+		t.Errorf("expected releases %v, but got %v", expected, releases)
+	}
+	if _, err = LatestPatchReleases([]string{"v1.3"}, released); err == nil {
+		t.Error("expected an error when a minor version was not released")
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("error writing tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("error writing tar file: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error closing tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("error closing gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestKismaticReleases(t *testing.T) {
+	archives := map[string][]byte{
+		"v1.5.1": tarGz(t, map[string]string{"kismatic": "binary", "ansible/playbooks/upgrade.yaml": "---"}),
+		"v1.4.0": tarGz(t, map[string]string{"../kismatic": "binary"}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"tag_name": "v1.5.1"}, {"tag_name": "v1.6.0-alpha.0", "prerelease": true}, {"tag_name": "v1.5.2", "draft": true}, {"tag_name": "nightly"}]`)
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		for v, archive := range archives {
+			if r.URL.Path == fmt.Sprintf("/download/%s/kismatic-%s-%s-amd64.tar.gz", v, v, runtime.GOOS) {
+				w.Write(archive)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	releases := KismaticReleases{ListURL: server.URL + "/releases", DownloadURL: server.URL + "/download", Client: server.Client()}
+
+	versions, err := releases.Versions()
+	if err != nil {
+		t.Fatalf("unexpected error listing the releases: %v", err)
+	}
+	expected := []semver.Version{mustParseVersion("1.5.1"), mustParseVersion("1.6.0-alpha.0")}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected versions %v, but got %v", expected, versions)
+	}
+
+	dir, err := ioutil.TempDir("", "kismatic-releases")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	releaseDir, err := releases.Download(mustParseVersion("1.5.1"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error downloading the release: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(releaseDir, "ansible", "playbooks", "upgrade.yaml")); err != nil {
+		t.Errorf("expected the release to be extracted: %v", err)
+	}
+	if _, err = releases.Download(mustParseVersion("1.4.0"), dir); err == nil {
+		t.Error("expected an error extracting a file outside of the release directory")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "kismatic")); !os.IsNotExist(err) {
+		t.Error("a file was extracted outside of the release directory")
+	}
+	if _, err = releases.Download(mustParseVersion("1.3.0"), dir); err == nil {
+		t.Error("expected an error downloading a release that does not exist")
+	}
+}
+
+func TestUpgradePathCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade-path-checkpoint")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := ReadUpgradePathCheckpoint(dir)
+	if err != nil || c != nil {
+		t.Fatalf("expected no checkpoint, but got %v, %v", c, err)
+	}
+	written := UpgradePathCheckpoint{TargetVersion: "1.6.0", CompletedHops: []UpgradeHop{{Version: "1.4.2"}}}
+	if err = WriteUpgradePathCheckpoint(written, dir); err != nil {
+		t.Fatalf("unexpected error writing the checkpoint: %v", err)
+	}
+	if c, err = ReadUpgradePathCheckpoint(dir); err != nil {
+		t.Fatalf("unexpected error reading the checkpoint: %v", err)
+	}
+	if !reflect.DeepEqual(*c, written) {
+		t.Errorf("expected checkpoint %v, but got %v", written, *c)
+	}
+}
@@ -0,0 +1,271 @@
+package install
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+// The minimum disk space that must be available under /var/lib on the nodes
+// for the new packages and container images to be downloaded
+const minUpgradeAvailableDiskSpace = 2 << 30
+
+// unsupportedResources are the resource types that are not supported by the
+// version of Kubernetes that the cluster is upgraded to
+var unsupportedResources = []struct {
+	resource    string
+	replacement string
+}{
+	{resource: "thirdpartyresources", replacement: "CustomResourceDefinitions"},
+	{resource: "scheduledjobs", replacement: "CronJobs"},
+}
+
+// nodeChecks are the checks that are run on each node, with the host of the
+// node as their subject
+var nodeChecks = map[string]bool{"Node health": true, "Disk space": true, "Version skew": true}
+
+type upgradeReadinessClient interface {
+	data.NodeLister
+	data.PodDisruptionBudgetLister
+	data.ComponentStatusLister
+	data.ResourceCounter
+}
+
+// UpgradeReadinessReport is the result of the checks that are run on the
+// cluster before it is upgraded
+type UpgradeReadinessReport struct {
+	ClusterName   string                  `json:"clusterName"`
+	Time          time.Time               `json:"time"`
+	TargetVersion string                  `json:"targetVersion"`
+	Checks        []UpgradeReadinessCheck `json:"checks"`
+}
+
+// UpgradeReadinessCheck is the result of a single readiness check, on a
+// single subject such as a node or a pod disruption budget
+type UpgradeReadinessCheck struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// Clean returns true when all the checks succeeded
+func (r UpgradeReadinessReport) Clean() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the checks that failed
+func (r UpgradeReadinessReport) Failures() []UpgradeReadinessCheck {
+	var failed []UpgradeReadinessCheck
+	for _, c := range r.Checks {
+		if !c.Success {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// UnreadyNodes returns the hosts of the nodes that failed a check
+func (r UpgradeReadinessReport) UnreadyNodes() []string {
+	var hosts []string
+	seen := map[string]bool{}
+	for _, c := range r.Failures() {
+		if nodeChecks[c.Name] && !seen[c.Subject] && c.Subject != "nodes" && c.Subject != "plan" {
+			seen[c.Subject] = true
+			hosts = append(hosts, c.Subject)
+		}
+	}
+	return hosts
+}
+
+// ExcludeNodes returns a copy of the report without the checks of the nodes
+// with the given hosts
+func (r UpgradeReadinessReport) ExcludeNodes(hosts []string) UpgradeReadinessReport {
+	excluded := map[string]bool{}
+	for _, h := range hosts {
+		excluded[h] = true
+	}
+	checks := []UpgradeReadinessCheck{}
+	for _, c := range r.Checks {
+		if nodeChecks[c.Name] && excluded[c.Subject] {
+			continue
+		}
+		checks = append(checks, c)
+	}
+	r.Checks = checks
+	return r
+}
+
+func (r *UpgradeReadinessReport) add(name, subject string, err error) {
+	c := UpgradeReadinessCheck{Name: name, Subject: subject, Success: err == nil}
+	if err != nil {
+		c.Message = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// UpgradeReadiness checks that the cluster is ready to be upgraded: the
+// nodes are ready and have enough disk space, the control plane components
+// and the etcd members are healthy, the versions of the components on the
+// nodes can run against the target version, and no resource types that are
+// not supported by the target version are used. When the upgrade is online, it
+// also checks that the pod disruption budgets allow the nodes to be drained.
+// The available disk space of a node is returned by diskSpace.
+func UpgradeReadiness(plan Plan, online bool, kubeClient upgradeReadinessClient, diskSpace func(Node) (uint64, error)) UpgradeReadinessReport {
+	r := UpgradeReadinessReport{
+		ClusterName:   plan.Cluster.Name,
+		Time:          time.Now(),
+		TargetVersion: KismaticVersion.String(),
+	}
+	nodes := checkNodeHealth(&r, plan, kubeClient)
+	checkVersionSkew(&r, plan, nodes)
+	checkComponentHealth(&r, kubeClient)
+	if online {
+		checkPodDisruptionBudgets(&r, kubeClient)
+	}
+	for _, u := range unsupportedResources {
+		count, err := kubeClient.CountResources(u.resource)
+		if err == nil && count > 0 {
+			err = fmt.Errorf("%d %s are not supported by the target version, and must be migrated to %s", count, u.resource, u.replacement)
+		}
+		r.add("Unsupported API usage", u.resource, err)
+	}
+	for _, n := range plan.GetUniqueNodes() {
+		available, err := diskSpace(n)
+		if err == nil && available < minUpgradeAvailableDiskSpace {
+			err = fmt.Errorf("%d MB available under /var/lib, %d MB required", available>>20, uint64(minUpgradeAvailableDiskSpace)>>20)
+		}
+		r.add("Disk space", n.Host, err)
+	}
+	return r
+}
+
+func checkNodeHealth(r *UpgradeReadinessReport, plan Plan, kubeClient data.NodeLister) *data.NodeList {
+	nodes, err := kubeClient.ListNodes()
+	if err != nil {
+		r.add("Node health", "nodes", err)
+		return nil
+	}
+	registered := map[string]data.Node{}
+	if nodes != nil {
+		for _, n := range nodes.Items {
+			host := n.Labels["kismatic/host"]
+			if host == "" {
+				host = n.Name
+			}
+			registered[strings.ToLower(host)] = n
+		}
+	}
+	seen := map[string]bool{}
+	var kubeNodes []Node
+	kubeNodes = append(kubeNodes, plan.Master.Nodes...)
+	kubeNodes = append(kubeNodes, plan.workerNodes()...)
+	kubeNodes = append(kubeNodes, plan.Ingress.Nodes...)
+	kubeNodes = append(kubeNodes, plan.Storage.Nodes...)
+	for _, n := range kubeNodes {
+		host := strings.ToLower(n.Host)
+		if seen[host] {
+			continue
+		}
+		seen[host] = true
+		var err error
+		kn, ok := registered[host]
+		switch {
+		case !ok:
+			err = errors.New("node is not registered with the API server")
+		case !kn.Ready():
+			err = errors.New("node is not ready")
+		}
+		r.add("Node health", n.Host, err)
+	}
+	return nodes
+}
+
+func checkComponentHealth(r *UpgradeReadinessReport, kubeClient data.ComponentStatusLister) {
+	components, err := kubeClient.ListComponentStatuses()
+	if err != nil {
+		r.add("Component health", "components", err)
+		return
+	}
+	if components == nil {
+		return
+	}
+	for _, c := range components.Items {
+		var err error
+		if !c.Healthy() {
+			msg := "component is not healthy"
+			for _, cond := range c.Conditions {
+				if cond.Error != "" {
+					msg = fmt.Sprintf("%s: %s", msg, cond.Error)
+				}
+			}
+			err = errors.New(msg)
+		}
+		r.add("Component health", c.Name, err)
+	}
+}
+
+func checkPodDisruptionBudgets(r *UpgradeReadinessReport, kubeClient upgradeReadinessClient) {
+	pdbs, err := kubeClient.ListPodDisruptionBudgets()
+	if err != nil {
+		r.add("Pod disruption budget", "pod disruption budgets", err)
+		return
+	}
+	if pdbs == nil {
+		return
+	}
+	for _, pdb := range pdbs.Items {
+		var err error
+		if pdb.Status.ExpectedPods > 0 && pdb.Status.PodDisruptionsAllowed == 0 {
+			err = fmt.Errorf("no disruptions are allowed, so the nodes running its %d pods cannot be drained", pdb.Status.ExpectedPods)
+		}
+		r.add("Pod disruption budget", pdb.Namespace+"/"+pdb.Name, err)
+	}
+}
+
+// WriteUpgradeReadinessReport persists the report in the generated assets
+// directory, and returns the path of the file it was written to
+func WriteUpgradeReadinessReport(r UpgradeReadinessReport, generatedAssetsDir string) (string, error) {
+	dir := filepath.Join(generatedAssetsDir, "upgrade-readiness")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("error creating upgrade readiness report directory: %v", err)
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshaling upgrade readiness report: %v", err)
+	}
+	file := filepath.Join(dir, r.Time.Format("2006-01-02-15-04-05")+".json")
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return "", fmt.Errorf("error writing upgrade readiness report %q: %v", file, err)
+	}
+	return file, nil
+}
+
+// NodeAvailableDiskSpace returns the disk space, in bytes, that is available
+// under /var/lib on the node
+func NodeAvailableDiskSpace(plan Plan, node Node) (uint64, error) {
+	client, err := plan.GetSSHClient(node.Host)
+	if err != nil {
+		return 0, err
+	}
+	out, err := client.Output(false, "df -Pk /var/lib | tail -n 1")
+	if err != nil {
+		return 0, fmt.Errorf("error getting available disk space: %v: %s", err, strings.TrimSpace(out))
+	}
+	fields := strings.Fields(out)
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("unexpected output of df: %q", out)
+	}
+	var kb uint64
+	if _, err := fmt.Sscanf(fields[3], "%d", &kb); err != nil {
+		return 0, fmt.Errorf("unexpected output of df: %q", out)
+	}
+	return kb << 10, nil
+}
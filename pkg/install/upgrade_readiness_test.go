@@ -0,0 +1,219 @@
+package install
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/data"
+)
+
+type fakeReadinessClient struct {
+	nodes      *data.NodeList
+	pdbs       *data.PodDisruptionBudgetList
+	components *data.ComponentStatusList
+	resources  map[string]int
+	err        error
+}
+
+func (f fakeReadinessClient) ListNodes() (*data.NodeList, error) { return f.nodes, f.err }
+func (f fakeReadinessClient) ListPodDisruptionBudgets() (*data.PodDisruptionBudgetList, error) {
+	return f.pdbs, f.err
+}
+func (f fakeReadinessClient) ListComponentStatuses() (*data.ComponentStatusList, error) {
+	return f.components, f.err
+}
+func (f fakeReadinessClient) CountResources(resource string) (int, error) {
+	return f.resources[resource], f.err
+}
+
+func readyNode(host string, ready bool) data.Node {
+	n := data.Node{}
+	n.Name = host
+	status := "False"
+	if ready {
+		status = "True"
+	}
+	n.Status.Conditions = []data.NodeCondition{{Type: "Ready", Status: status}}
+	return n
+}
+
+func component(name string, healthy bool) data.ComponentStatus {
+	c := data.ComponentStatus{}
+	c.Name = name
+	status := "False"
+	if healthy {
+		status = "True"
+	}
+	c.Conditions = []data.ComponentCondition{{Type: "Healthy", Status: status}}
+	return c
+}
+
+func pdb(name string, allowed, expected int32) data.PodDisruptionBudget {
+	p := data.PodDisruptionBudget{}
+	p.Namespace = "default"
+	p.Name = name
+	p.Status.PodDisruptionsAllowed = allowed
+	p.Status.ExpectedPods = expected
+	return p
+}
+
+func TestUpgradeReadiness(t *testing.T) {
+	plan := Plan{}
+	plan.Etcd.Nodes = []Node{{Host: "etcd01", IP: "10.0.0.1"}}
+	plan.Master.Nodes = []Node{{Host: "master01", IP: "10.0.0.2"}}
+	plan.Worker.Nodes = []Node{{Host: "worker01", IP: "10.0.0.3"}}
+	healthy := fakeReadinessClient{
+		nodes:      &data.NodeList{Items: []data.Node{readyNode("master01", true), readyNode("worker01", true)}},
+		pdbs:       &data.PodDisruptionBudgetList{Items: []data.PodDisruptionBudget{pdb("web", 1, 3)}},
+		components: &data.ComponentStatusList{Items: []data.ComponentStatus{component("etcd-0", true), component("scheduler", true)}},
+	}
+	enoughDisk := func(Node) (uint64, error) { return 10 << 30, nil }
+
+	tests := []struct {
+		name      string
+		online    bool
+		client    func(fakeReadinessClient) fakeReadinessClient
+		diskSpace func(Node) (uint64, error)
+		failures  []string
+	}{
+		{
+			name: "clean",
+		},
+		{
+			name: "node not ready",
+			client: func(c fakeReadinessClient) fakeReadinessClient {
+				c.nodes = &data.NodeList{Items: []data.Node{readyNode("master01", true), readyNode("worker01", false)}}
+				return c
+			},
+			failures: []string{"worker01"},
+		},
+		{
+			name: "node not registered",
+			client: func(c fakeReadinessClient) fakeReadinessClient {
+				c.nodes = &data.NodeList{Items: []data.Node{readyNode("master01", true)}}
+				return c
+			},
+			failures: []string{"worker01"},
+		},
+		{
+			name: "kubelet too old",
+			client: func(c fakeReadinessClient) fakeReadinessClient {
+				old := readyNode("worker01", true)
+				old.Status.NodeInfo.KubeletVersion = "v1.5.7"
+				c.nodes = &data.NodeList{Items: []data.Node{readyNode("master01", true), old}}
+				return c
+			},
+			failures: []string{"worker01"},
+		},
+		{
+			name: "unhealthy etcd member",
+			client: func(c fakeReadinessClient) fakeReadinessClient {
+				c.components = &data.ComponentStatusList{Items: []data.ComponentStatus{component("etcd-0", false)}}
+				return c
+			},
+			failures: []string{"etcd-0"},
+		},
+		{
+			name: "blocking pod disruption budget is ignored offline",
+			client: func(c fakeReadinessClient) fakeReadinessClient {
+				c.pdbs = &data.PodDisruptionBudgetList{Items: []data.PodDisruptionBudget{pdb("db", 0, 3)}}
+				return c
+			},
+		},
+		{
+			name:   "blocking pod disruption budget",
+			online: true,
+			client: func(c fakeReadinessClient) fakeReadinessClient {
+				c.pdbs = &data.PodDisruptionBudgetList{Items: []data.PodDisruptionBudget{pdb("web", 1, 3), pdb("db", 0, 3)}}
+				return c
+			},
+			failures: []string{"default/db"},
+		},
+		{
+			name: "unsupported resources",
+			client: func(c fakeReadinessClient) fakeReadinessClient {
+				c.resources = map[string]int{"thirdpartyresources": 2}
+				return c
+			},
+			failures: []string{"thirdpartyresources"},
+		},
+		{
+			name:      "low disk space",
+			diskSpace: func(n Node) (uint64, error) { return 1 << 30, nil },
+			failures:  []string{"etcd01", "master01", "worker01"},
+		},
+		{
+			name:      "disk space unknown",
+			diskSpace: func(n Node) (uint64, error) { return 0, errors.New("unreachable") },
+			failures:  []string{"etcd01", "master01", "worker01"},
+		},
+	}
+	for _, test := range tests {
+		client := healthy
+		if test.client != nil {
+			client = test.client(client)
+		}
+		diskSpace := enoughDisk
+		if test.diskSpace != nil {
+			diskSpace = test.diskSpace
+		}
+		r := UpgradeReadiness(plan, test.online, client, diskSpace)
+		var failures []string
+		for _, c := range r.Failures() {
+			failures = append(failures, c.Subject)
+		}
+		if len(failures) != len(test.failures) {
+			t.Errorf("%s: expected failures %v, but got %v", test.name, test.failures, failures)
+			continue
+		}
+		for i := range failures {
+			if failures[i] != test.failures[i] {
+				t.Errorf("%s: expected failures %v, but got %v", test.name, test.failures, failures)
+				break
+			}
+		}
+		if r.Clean() != (len(test.failures) == 0) {
+			t.Errorf("%s: expected clean to be %v", test.name, len(test.failures) == 0)
+		}
+	}
+}
+
+func TestWriteUpgradeReadinessReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade-readiness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := UpgradeReadinessReport{Checks: []UpgradeReadinessCheck{{Name: "Node health", Subject: "worker01", Success: true}}}
+	file, err := WriteUpgradeReadinessReport(r, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = os.Stat(file); err != nil {
+		t.Errorf("expected report to be written to %q: %v", file, err)
+	}
+}
+
+func TestUpgradeReadinessReportExcludeNodes(t *testing.T) {
+	r := UpgradeReadinessReport{Checks: []UpgradeReadinessCheck{
+		{Name: "Node health", Subject: "worker01", Success: false},
+		{Name: "Disk space", Subject: "worker01", Success: true},
+		{Name: "Disk space", Subject: "worker02", Success: false},
+		{Name: "Version skew", Subject: "plan", Success: false},
+		{Name: "Component health", Subject: "worker01", Success: false},
+	}}
+	unready := r.UnreadyNodes()
+	if !reflect.DeepEqual(unready, []string{"worker01", "worker02"}) {
+		t.Errorf("unexpected unready nodes: %v", unready)
+	}
+	excluded := r.ExcludeNodes(unready)
+	if len(excluded.Checks) != 2 || len(excluded.Failures()) != 2 {
+		t.Errorf("expected the node checks to be excluded, but got %v", excluded.Checks)
+	}
+	if len(r.Checks) != 5 {
+		t.Errorf("the report was modified")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -29,9 +30,63 @@ import (
 func ValidatePlan(p *Plan) (bool, []error) {
 	v := newValidator()
 	v.validate(p)
+	v.addError(validateClusterName(p.Cluster.Name)...)
 	return v.valid()
 }
 
+// ValidateExistingPlan runs validation against the plan of a cluster that is
+// already installed. The cluster cannot be renamed, so the rules of the
+// cluster name are only checked by ValidateExistingPlanWarnings.
+func ValidateExistingPlan(p *Plan) (bool, []error) {
+	v := newValidator()
+	v.validate(p)
+	return v.valid()
+}
+
+// ValidateExistingPlanWarnings returns the warnings of the plan of a cluster
+// that is already installed, including the rules of the cluster name that it
+// breaks.
+func ValidateExistingPlanWarnings(p *Plan) []error {
+	return append(validateClusterName(p.Cluster.Name), ValidatePlanWarnings(p)...)
+}
+
+// ValidatePlanWarnings returns the problems of the plan that don't prevent the
+// installation, but make the cluster less available than it could be, such
+// as an even number of etcd nodes. Plans that allow a single node are
+// expected to have no redundancy, and have no warnings.
+func ValidatePlanWarnings(p *Plan) []error {
+	var warnings []error
+	if p.Cluster.DefaultPolicies.Enabled && !p.enforcesNetworkPolicies() {
+		warnings = append(warnings, errors.New("Default policies: the CNI provider does not enforce network policies, so the ingress traffic of the pods is not denied"))
+	}
+	if p.Cluster.AllowSingleNode {
+		return warnings
+	}
+	switch etcd := len(p.Etcd.Nodes); {
+	case etcd == 1:
+		warnings = append(warnings, errors.New("Etcd nodes: a single etcd node is a single point of failure, use 3 or 5 etcd nodes"))
+	case etcd > 0 && etcd%2 == 0:
+		warnings = append(warnings, fmt.Errorf("Etcd nodes: %d etcd nodes tolerate as many failures as %d nodes, use an odd number of etcd nodes", etcd, etcd-1))
+	}
+	if len(p.Master.Nodes) == 1 {
+		warnings = append(warnings, errors.New("Master nodes: a single master node is a single point of failure of the control plane"))
+	}
+	if len(p.workerNodes()) == 1 {
+		warnings = append(warnings, errors.New("Worker nodes: a single worker node cannot keep the workloads running while it is upgraded or fails"))
+	}
+	if available, needed, err := p.podCIDRSubnets(); err == nil && needed <= available && needed > available-needed {
+		warnings = append(warnings, fmt.Errorf("Pod CIDR block %q provides %d node subnets, which leaves room for only %d more nodes, fewer than the cluster has", p.Cluster.Networking.PodCIDRBlock, available, available-needed))
+	}
+	return warnings
+}
+
+// enforcesNetworkPolicies returns whether the CNI provider installed by
+// kismatic enforces the network policies
+func (p *Plan) enforcesNetworkPolicies() bool {
+	cni := p.AddOns.CNI
+	return cni != nil && !cni.Disable && (cni.Provider == cniProviderCalico || cni.Provider == cniProviderWeave)
+}
+
 // ValidateNode runs validation against the given node.
 func ValidateNode(node *Node) (bool, []error) {
 	v := newValidator()
@@ -144,18 +199,104 @@ func (p *Plan) validate() (bool, []error) {
 	}
 
 	v.validateWithErrPrefix("Docker", p.Docker)
+	if p.Cluster.BakedImage && p.Docker.Storage.DirectLVM.Enabled {
+		v.addError(errors.New("Docker direct-lvm storage cannot be enabled when the nodes are created from a baked image, as the container images of the image would not be used"))
+	}
 	v.validate(&p.AddOns)
+	v.addError(p.validateVersionSkew()...)
+	if p.AddOns.ClusterAutoscaler.Enabled && !util.Contains(p.Cluster.CloudProvider.Provider, clusterAutoscalerCloudProviders()) {
+		v.addError(fmt.Errorf("The cluster autoscaler requires one of the %v cloud providers", clusterAutoscalerCloudProviders()))
+	}
+	v.addError(p.validateExternalDNS()...)
+	if d := p.AddOns.Dashboard; d != nil && !d.Disable && d.Ingress.Enabled && len(p.Ingress.Nodes) == 0 {
+		v.addError(errors.New("Exposing the dashboard through the ingress controller requires ingress nodes"))
+	}
 	v.validate(nodeList{Nodes: p.getAllNodes()})
+	v.addError(p.validateNodeNetworkOverlap()...)
+	v.addError(p.validateCalicoBGP()...)
+	v.addError(p.validatePodCIDRCapacity()...)
 	v.validateWithErrPrefix("Etcd nodes", &p.Etcd)
+	if len(p.Etcd.Labels) > 0 || len(p.Etcd.Taints) > 0 {
+		v.addError(errors.New("Etcd nodes: labels and taints are not supported on the etcd node group"))
+	}
+	if p.Cluster.EtcdTopology == etcdTopologyStacked {
+		for _, n := range p.Etcd.Nodes {
+			if !p.hasMasterNode(n) {
+				v.addError(fmt.Errorf("Etcd nodes: node %q must be a master node when the etcd topology is %q", n.Host, etcdTopologyStacked))
+			}
+		}
+	}
 	v.validateWithErrPrefix("Master nodes", &p.Master)
-	v.validateWithErrPrefix("Worker nodes", &p.Worker)
+	if len(p.WorkerPools) == 0 {
+		v.validateWithErrPrefix("Worker nodes", &p.Worker)
+	} else {
+		// the worker group is optional when the workers are in pools
+		workers := OptionalNodeGroup(p.Worker)
+		v.validateWithErrPrefix("Worker nodes", &workers)
+		v.addError(p.validateWorkerPools()...)
+	}
+	v.addError(p.validateArchitectures()...)
+	if p.isSingleNode() && !p.Cluster.AllowSingleNode {
+		v.addError(errors.New("All the node roles are assigned to a single node, which is only suitable for demos and test environments. Set cluster.allow_single_node to true to use this layout"))
+	}
 	v.validateWithErrPrefix("Ingress nodes", &p.Ingress)
 	v.validate(&p.NFS)
 	v.validateWithErrPrefix("Storage nodes", &p.Storage)
+	v.validateWithErrPrefix("Post-install", &p.PostInstall)
 
 	return v.valid()
 }
 
+func (pi *PostInstall) validate() (bool, []error) {
+	v := newValidator()
+	for _, m := range pi.Manifests {
+		if isManifestURL(m) {
+			if u, err := url.Parse(m); err != nil || u.Host == "" {
+				v.addError(fmt.Errorf("manifest URL %q is not valid", m))
+			}
+			continue
+		}
+		if fi, err := os.Stat(m); err != nil || fi.IsDir() {
+			v.addError(fmt.Errorf("manifest file %q does not exist", m))
+		}
+	}
+	if pi.Retries != nil && *pi.Retries < 0 {
+		v.addError(errors.New("retries cannot be negative"))
+	}
+	return v.valid()
+}
+
+// reservedClusterNames are the names that are used by Kubernetes, or that
+// would be confused with it, in the hostnames and paths derived from the
+// cluster name
+var reservedClusterNames = []string{"default", "localhost", "kube-system", "kube-public", "kube-node-lease"}
+
+// NormalizeClusterName returns the canonical form of the cluster name, which
+// is lower case and has no surrounding spaces
+func NormalizeClusterName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// validateClusterName validates that the cluster name is a DNS-1123 label, as
+// it is used in hostnames, cloud provider tags and file paths
+func validateClusterName(name string) []error {
+	if name == "" {
+		return nil
+	}
+	if util.Contains(name, reservedClusterNames) {
+		return []error{fmt.Errorf("Cluster name %q is reserved", name)}
+	}
+	var errs []error
+	for _, msg := range validation.IsDNS1123Label(name) {
+		err := fmt.Errorf("Cluster name %q is not valid: %s", name, msg)
+		if n := NormalizeClusterName(name); n != name && len(validation.IsDNS1123Label(n)) == 0 {
+			err = fmt.Errorf("Cluster name %q is not valid: %s. Use %q instead", name, msg, n)
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 func (c *Cluster) validate() (bool, []error) {
 	v := newValidator()
 	if c.Name == "" {
@@ -164,6 +305,14 @@ func (c *Cluster) validate() (bool, []error) {
 	if c.AdminPassword == "" {
 		v.addError(errors.New("Admin password cannot be empty"))
 	}
+	for key, val := range c.Labels {
+		for _, err := range validation.IsQualifiedName(key) {
+			v.addError(fmt.Errorf("Cluster label name %q is not valid %s", key, err))
+		}
+		for _, err := range validation.IsValidLabelValue(val) {
+			v.addError(fmt.Errorf("Cluster label %q is not valid %s", val, err))
+		}
+	}
 	v.validate(&c.Networking)
 	v.validate(&c.Certificates)
 	v.validate(&c.SSH)
@@ -173,6 +322,29 @@ func (c *Cluster) validate() (bool, []error) {
 	v.validate(&c.KubeSchedulerOptions)
 	v.validate(&c.KubeletOptions)
 	v.validate(&c.CloudProvider)
+	v.validateWithErrPrefix("Etcd backup", &c.EtcdBackup)
+	v.validateWithErrPrefix("Etcd data volume", &c.EtcdDataVolume)
+	v.validateWithErrPrefix("Audit log", &c.Audit)
+	v.validateWithErrPrefix("Auth webhook", &c.AuthWebhook)
+	v.validateWithErrPrefix("Default policies", &c.DefaultPolicies)
+	v.validateWithErrPrefix("Secrets store", &c.SecretsStore)
+	v.validateWithErrPrefix("Assets storage", &c.AssetsStorage)
+	v.validateWithErrPrefix("Events", &c.Events)
+	v.validateWithErrPrefix("Cluster expiration", &c.Expiration)
+	v.validateWithErrPrefix("Time sync", &c.TimeSync)
+	if _, ok := c.KubeletOptions.Overrides["resolv-conf"]; ok && len(c.Networking.HostDNS.Nameservers) > 0 {
+		v.addError(errors.New("Kubelet Option resolv-conf cannot be overridden when the host DNS nameservers are set"))
+	}
+	v.validateWithErrPrefix("Sysctl", &c.Sysctl)
+	if c.EtcdTopology != "" && !util.Contains(c.EtcdTopology, etcdTopologies()) {
+		v.addError(fmt.Errorf("Etcd topology %q is not valid, options are %v", c.EtcdTopology, etcdTopologies()))
+	}
+	if c.SELinux != "" && !util.Contains(c.SELinux, selinuxModes()) {
+		v.addError(fmt.Errorf("SELinux mode %q is not valid, options are %v", c.SELinux, selinuxModes()))
+	}
+	if c.HardeningProfile != "" && !util.Contains(c.HardeningProfile, hardeningProfiles()) {
+		v.addError(fmt.Errorf("Hardening profile %q is not valid, options are %v", c.HardeningProfile, hardeningProfiles()))
+	}
 
 	return v.valid()
 }
@@ -192,9 +364,162 @@ func (n *NetworkConfig) validate() (bool, []error) {
 	if _, _, err := net.ParseCIDR(n.ServiceCIDRBlock); n.ServiceCIDRBlock != "" && err != nil {
 		v.addError(fmt.Errorf("Invalid Service CIDR block provided: %v", err))
 	}
+	_, pods, podErr := net.ParseCIDR(n.PodCIDRBlock)
+	_, services, serviceErr := net.ParseCIDR(n.ServiceCIDRBlock)
+	if podErr == nil && serviceErr == nil && cidrsOverlap(pods, services) {
+		v.addError(fmt.Errorf("Pod CIDR block %q overlaps with the service CIDR block %q", n.PodCIDRBlock, n.ServiceCIDRBlock))
+	}
+	v.validateWithErrPrefix("Host DNS", &n.HostDNS)
 	return v.valid()
 }
 
+var resolverOptionRegexp = regexp.MustCompile(`^[a-z0-9-]+(:[0-9]+)?$`)
+
+// the limits of the glibc resolver
+const (
+	maxHostDNSNameservers   = 3
+	maxHostDNSSearchDomains = 6
+)
+
+func (d *HostDNS) validate() (bool, []error) {
+	v := newValidator()
+	if len(d.Nameservers) == 0 && (len(d.SearchDomains) > 0 || len(d.Options) > 0) {
+		v.addError(errors.New("Nameservers are required when search domains or options are set"))
+	}
+	if len(d.Nameservers) > maxHostDNSNameservers {
+		v.addError(fmt.Errorf("At most %d nameservers can be set", maxHostDNSNameservers))
+	}
+	for _, ns := range d.Nameservers {
+		if ip := net.ParseIP(ns); ip == nil {
+			v.addError(fmt.Errorf("Nameserver %q is not a valid IP address", ns))
+		} else if ip.IsLoopback() {
+			v.addError(fmt.Errorf("Nameserver %q cannot be a loopback address, as it is not reachable from the pods", ns))
+		}
+	}
+	if len(d.SearchDomains) > maxHostDNSSearchDomains {
+		v.addError(fmt.Errorf("At most %d search domains can be set", maxHostDNSSearchDomains))
+	}
+	for _, domain := range d.SearchDomains {
+		if msgs := validation.IsDNS1123Subdomain(domain); len(msgs) > 0 {
+			v.addError(fmt.Errorf("Search domain %q is not valid: %s", domain, strings.Join(msgs, ", ")))
+		}
+	}
+	for _, opt := range d.Options {
+		if !resolverOptionRegexp.MatchString(opt) {
+			v.addError(fmt.Errorf("Option %q is not valid, must be a name or a name and a number such as ndots:2", opt))
+		}
+	}
+	return v.valid()
+}
+
+// validateNodeNetworkOverlap validates that the addresses of the nodes, and
+// the load balanced address of the masters when it is an IP, are not within
+// the pod or service CIDR blocks, as they would not be routable from the pods
+func (p *Plan) validateNodeNetworkOverlap() []error {
+	blocks := []struct {
+		kind string
+		cidr string
+	}{
+		{kind: "pod", cidr: p.Cluster.Networking.PodCIDRBlock},
+		{kind: "service", cidr: p.Cluster.Networking.ServiceCIDRBlock},
+	}
+	var errs []error
+	for _, b := range blocks {
+		_, cidr, err := net.ParseCIDR(b.cidr)
+		if err != nil {
+			// the CIDR block is validated with the networking configuration
+			continue
+		}
+		for _, n := range p.GetUniqueNodes() {
+			for _, addr := range []string{n.IP, n.InternalIP} {
+				if ip := net.ParseIP(addr); ip != nil && cidr.Contains(ip) {
+					errs = append(errs, fmt.Errorf("Node %q: IP %q is within the %s CIDR block %q", n.Host, addr, b.kind, b.cidr))
+				}
+			}
+		}
+		if ip := net.ParseIP(p.Master.LoadBalancedFQDN); ip != nil && cidr.Contains(ip) {
+			errs = append(errs, fmt.Errorf("Master nodes: load balanced address %q is within the %s CIDR block %q", p.Master.LoadBalancedFQDN, b.kind, b.cidr))
+		}
+	}
+	return errs
+}
+
+// defaultNodeCIDRMaskSize is the size of the subnet of the pod CIDR block that
+// the controller manager allocates to each node, when it is not overridden
+const defaultNodeCIDRMaskSize = 24
+
+// nodeCIDRMaskSize returns the size of the subnet of the pod CIDR block that
+// is allocated to each node
+func (p *Plan) nodeCIDRMaskSize() (int, error) {
+	o, ok := p.Cluster.KubeControllerManagerOptions.Overrides["node-cidr-mask-size"]
+	if !ok {
+		return defaultNodeCIDRMaskSize, nil
+	}
+	size, err := strconv.Atoi(o)
+	if err != nil || size < 1 || size > 32 {
+		return 0, fmt.Errorf("Controller manager option node-cidr-mask-size %q is not valid, must be a number between 1 and 32", o)
+	}
+	return size, nil
+}
+
+// podCIDRSubnets returns the number of node subnets the pod CIDR block
+// provides, and the number of subnets the cluster needs: one for each node
+// running the kubelet, and one for each node the cluster autoscaler can add.
+// The capacity is not checked for IPv6 or invalid CIDR blocks.
+func (p *Plan) podCIDRSubnets() (available int, needed int, err error) {
+	_, cidr, err := net.ParseCIDR(p.Cluster.Networking.PodCIDRBlock)
+	if err != nil {
+		// the CIDR block is validated with the networking configuration
+		return 0, 0, nil
+	}
+	prefix, bits := cidr.Mask.Size()
+	if bits != 32 {
+		return 0, 0, nil
+	}
+	maskSize, err := p.nodeCIDRMaskSize()
+	if err != nil {
+		return 0, 0, err
+	}
+	if prefix > maskSize {
+		return 0, 0, fmt.Errorf("Pod CIDR block %q is smaller than the /%d subnet allocated to each node", p.Cluster.Networking.PodCIDRBlock, maskSize)
+	}
+	if maskSize-prefix < 30 {
+		available = 1 << uint(maskSize-prefix)
+	} else {
+		available = 1 << 30
+	}
+	needed = len(p.KubeletNodes())
+	if p.AddOns.ClusterAutoscaler.Enabled {
+		for _, g := range p.AddOns.ClusterAutoscaler.NodeGroups {
+			needed += g.MaxSize
+		}
+	}
+	return available, needed, nil
+}
+
+// validatePodCIDRCapacity validates that the pod CIDR block provides a subnet
+// for each node, as the nodes that don't get one cannot run pods
+func (p *Plan) validatePodCIDRCapacity() []error {
+	available, needed, err := p.podCIDRSubnets()
+	if err != nil {
+		return []error{err}
+	}
+	if needed <= available {
+		return nil
+	}
+	maskSize, _ := p.nodeCIDRMaskSize()
+	prefix := maskSize
+	for prefix > 1 && 1<<uint(maskSize-prefix) < needed {
+		prefix--
+	}
+	return []error{fmt.Errorf("Pod CIDR block %q provides %d /%d node subnets, but the cluster needs %d, including the nodes the cluster autoscaler can add. Use a pod CIDR block of /%d or larger", p.Cluster.Networking.PodCIDRBlock, available, maskSize, needed, prefix)}
+}
+
+// cidrsOverlap returns true if one of the CIDR blocks contains the other
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
 func (c *CertsConfig) validate() (bool, []error) {
 	v := newValidator()
 	if _, err := time.ParseDuration(c.Expiry); err != nil {
@@ -223,6 +548,20 @@ func (s *SSHConfig) validate() (bool, []error) {
 	if s.Port < 1 || s.Port > 65535 {
 		v.addError(fmt.Errorf("SSH port %d is invalid. Port must be in the range 1-65535", s.Port))
 	}
+	if b := s.Bastion; b.Host != "" {
+		if b.User == "" {
+			v.addError(errors.New("SSH bastion user field is required"))
+		}
+		if _, err := os.Stat(b.Key); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("SSH bastion key file was not found at %q", b.Key))
+		}
+		if !filepath.IsAbs(b.Key) {
+			v.addError(errors.New("SSH bastion key field must be an absolute path"))
+		}
+		if b.Port < 1 || b.Port > 65535 {
+			v.addError(fmt.Errorf("SSH bastion port %d is invalid. Port must be in the range 1-65535", b.Port))
+		}
+	}
 	return v.valid()
 }
 
@@ -241,14 +580,247 @@ func (c *CloudProvider) validate() (bool, []error) {
 	return v.valid()
 }
 
+func (s *ClusterSecretsStore) validate() (bool, []error) {
+	v := newValidator()
+	if s.Provider == "" {
+		if s.Path != "" {
+			v.addError(errors.New("Provider is required when the path is set"))
+		}
+		return v.valid()
+	}
+	if !util.Contains(s.Provider, secretsStoreProviders()) {
+		v.addError(fmt.Errorf("Provider %q is not a valid option %v", s.Provider, secretsStoreProviders()))
+	}
+	if s.Path == "" {
+		v.addError(errors.New("Path is required"))
+	}
+	if strings.HasPrefix(s.Path, "/") || strings.HasSuffix(s.Path, "/") {
+		v.addError(fmt.Errorf("Path %q must not start or end with a slash", s.Path))
+	}
+	return v.valid()
+}
+
+func (s *ClusterAssetsStorage) validate() (bool, []error) {
+	v := newValidator()
+	if s.Location == "" {
+		return v.valid()
+	}
+	if _, _, err := s.remote(); err != nil {
+		v.addError(fmt.Errorf("Location %q is invalid: %v", s.Location, err))
+	}
+	return v.valid()
+}
+
+func (e *ClusterEvents) validate() (bool, []error) {
+	v := newValidator()
+	if e.Publisher == "" {
+		if len(e.Servers) > 0 {
+			v.addError(errors.New("Publisher is required when the servers are set"))
+		}
+		return v.valid()
+	}
+	if !util.Contains(e.Publisher, eventPublishers()) {
+		v.addError(fmt.Errorf("Publisher %q is not a valid option %v", e.Publisher, eventPublishers()))
+	}
+	if len(e.Servers) == 0 {
+		v.addError(errors.New("At least one server is required"))
+	}
+	for _, s := range e.Servers {
+		if _, port, err := net.SplitHostPort(s); err != nil || port == "" {
+			v.addError(fmt.Errorf("Server %q is not valid, must be an address such as broker.example.com:4222", s))
+		}
+	}
+	if strings.ContainsAny(e.Topic, " \t") {
+		v.addError(fmt.Errorf("Topic %q must not contain whitespace", e.Topic))
+	}
+	return v.valid()
+}
+
+func (d *DefaultPolicies) validate() (bool, []error) {
+	v := newValidator()
+	if !d.Enabled {
+		return v.valid()
+	}
+	for _, ns := range d.DenyIngressNamespaces {
+		if ns == "kube-system" || ns == "kube-public" {
+			v.addError(fmt.Errorf("Namespace %q cannot deny ingress traffic, as the cluster services run in it", ns))
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			v.addError(fmt.Errorf("Namespace %q is not valid: %s", ns, msg))
+		}
+	}
+	return v.valid()
+}
+
+func (d *EtcdDataVolume) validate() (bool, []error) {
+	v := newValidator()
+	if !d.Enabled {
+		return v.valid()
+	}
+	if d.BlockDevice == "" {
+		v.addError(errors.New("Block device is required"))
+	} else if !strings.HasPrefix(d.BlockDevice, "/dev/") {
+		v.addError(fmt.Errorf("Block device %q is not valid, must be a path under /dev", d.BlockDevice))
+	}
+	if d.Filesystem != "" && !util.Contains(d.Filesystem, etcdDataVolumeFilesystems()) {
+		v.addError(fmt.Errorf("Filesystem %q is not valid, options are %v", d.Filesystem, etcdDataVolumeFilesystems()))
+	}
+	if d.MaxSyncLatencyMS < 0 {
+		v.addError(fmt.Errorf("Max sync latency %d is invalid, must be greater than or equal to 0", d.MaxSyncLatencyMS))
+	}
+	return v.valid()
+}
+
+func (t *TimeSync) validate() (bool, []error) {
+	v := newValidator()
+	if t.MaxSkewSeconds < 0 {
+		v.addError(fmt.Errorf("Max skew %d is invalid, must be greater than or equal to 0", t.MaxSkewSeconds))
+	}
+	if !t.Enabled {
+		return v.valid()
+	}
+	if len(t.Servers) == 0 {
+		v.addError(errors.New("At least one NTP server is required"))
+	}
+	for _, s := range t.Servers {
+		if s == "" || (net.ParseIP(s) == nil && strings.ContainsAny(s, " /:")) {
+			v.addError(fmt.Errorf("NTP server %q is not valid, must be a hostname or IP address", s))
+		}
+	}
+	return v.valid()
+}
+
+func (b *EtcdBackup) validate() (bool, []error) {
+	v := newValidator()
+	if !b.Enabled {
+		return v.valid()
+	}
+	provider, _, err := b.remote()
+	if err != nil {
+		v.addError(fmt.Errorf("Destination %q is invalid: %v", b.Destination, err))
+	}
+	if b.EncryptionKeyFile == "" {
+		v.addError(errors.New("Encryption key file is required"))
+	} else if _, err := os.Stat(b.EncryptionKeyFile); os.IsNotExist(err) {
+		v.addError(fmt.Errorf("Encryption key file was not found at %q", b.EncryptionKeyFile))
+	}
+	if b.CredentialsFile == "" && provider == etcdBackupProviderGCS {
+		v.addError(errors.New("Credentials file is required when the destination is a GCS bucket"))
+	}
+	if _, err := os.Stat(b.CredentialsFile); b.CredentialsFile != "" && os.IsNotExist(err) {
+		v.addError(fmt.Errorf("Credentials file was not found at %q", b.CredentialsFile))
+	}
+	if b.Retention < 0 {
+		v.addError(fmt.Errorf("Retention %d is invalid, must be greater than or equal to 0", b.Retention))
+	}
+	return v.valid()
+}
+
+func (a *AuditLog) validate() (bool, []error) {
+	v := newValidator()
+	if !a.Enabled {
+		return v.valid()
+	}
+	if a.Level != "" && !util.Contains(a.Level, auditLevels()) {
+		v.addError(fmt.Errorf("Level %q is not valid, options are %v", a.Level, auditLevels()))
+	}
+	if a.Sink != "" && !util.Contains(a.Sink, auditSinks()) {
+		v.addError(fmt.Errorf("Sink %q is not valid, options are %v", a.Sink, auditSinks()))
+	}
+	if a.MaxAge < 0 || a.MaxBackups < 0 || a.MaxSize < 0 {
+		v.addError(errors.New("Max age, max backups and max size cannot be negative"))
+	}
+	if a.Sink == auditSinkWebhook {
+		if u, err := url.Parse(a.WebhookURL); a.WebhookURL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			v.addError(fmt.Errorf("Webhook URL %q is not valid, an http or https URL is required when the sink is %q", a.WebhookURL, auditSinkWebhook))
+		}
+	}
+	return v.valid()
+}
+
+func (w *AuthWebhook) validate() (bool, []error) {
+	v := newValidator()
+	for _, u := range []struct {
+		name string
+		url  string
+	}{
+		{name: "Authentication", url: w.AuthenticationURL},
+		{name: "Authorization", url: w.AuthorizationURL},
+	} {
+		if u.url == "" {
+			continue
+		}
+		if parsed, err := url.Parse(u.url); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			v.addError(fmt.Errorf("%s URL %q is not valid, must be an http or https URL", u.name, u.url))
+		}
+	}
+	// the bearer tokens of the users are sent to the authentication service
+	if parsed, err := url.Parse(w.AuthenticationURL); err == nil && parsed.Scheme == "http" {
+		v.addError(fmt.Errorf("Authentication URL %q is not valid, must be an https URL", w.AuthenticationURL))
+	}
+	if w.CAPath != "" {
+		if !filepath.IsAbs(w.CAPath) {
+			v.addError(fmt.Errorf("CA path %q is not valid, must be an absolute path", w.CAPath))
+		} else if _, err := os.Stat(w.CAPath); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("CA file was not found at %q", w.CAPath))
+		}
+	}
+	if w.CacheTTL != "" {
+		if d, err := time.ParseDuration(w.CacheTTL); err != nil || d < 0 {
+			v.addError(fmt.Errorf("Cache TTL %q is not valid, must be a duration such as 2m", w.CacheTTL))
+		}
+	}
+	return v.valid()
+}
+
+func (e *ClusterExpiration) validate() (bool, []error) {
+	v := newValidator()
+	if !e.enabled() {
+		return v.valid()
+	}
+	if e.TTL != "" {
+		if d, err := time.ParseDuration(e.TTL); err != nil || d <= 0 {
+			v.addError(fmt.Errorf("TTL %q is not valid, must be a positive duration such as 72h", e.TTL))
+		}
+	}
+	if e.ExpiresAt != "" {
+		if _, err := time.Parse(time.RFC3339, e.ExpiresAt); err != nil {
+			v.addError(fmt.Errorf("Expires at %q is not valid, must be a time in RFC 3339 format such as 2017-10-31T18:00:00Z", e.ExpiresAt))
+		}
+	}
+	if e.WarnBefore != "" {
+		if d, err := time.ParseDuration(e.WarnBefore); err != nil || d < 0 {
+			v.addError(fmt.Errorf("Warn before %q is not valid, must be a duration such as 24h", e.WarnBefore))
+		}
+	}
+	if e.WebhookURL != "" {
+		if u, err := url.Parse(e.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			v.addError(fmt.Errorf("Webhook URL %q is not valid, must be an http or https URL", e.WebhookURL))
+		}
+	}
+	return v.valid()
+}
+
 func (f *AddOns) validate() (bool, []error) {
 	v := newValidator()
 	v.validate(f.CNI)
+	v.validate(&f.DNS)
 	v.validate(f.HeapsterMonitoring)
+	v.validate(f.Dashboard)
 	v.validate(&f.PackageManager)
+	v.validate(&f.ClusterAutoscaler)
+	v.validate(&f.ExternalDNS)
+	v.validate(&f.RebootCoordinator)
 	return v.valid()
 }
 
+// the range of the MTU of the pod network interfaces
+const (
+	minCNIMTU = 576
+	maxCNIMTU = 9000
+)
+
 func (n *CNI) validate() (bool, []error) {
 	v := newValidator()
 	if n != nil && !n.Disable {
@@ -263,10 +835,81 @@ func (n *CNI) validate() (bool, []error) {
 				v.addError(fmt.Errorf("%q is not a valid Calico log level. Options are %v", n.Options.Calico.LogLevel, calicoLogLevel()))
 			}
 		}
+		if n.Encryption && n.Provider != cniProviderWeave {
+			v.addError(fmt.Errorf("Encryption is only supported by the %q CNI provider", cniProviderWeave))
+		}
+		if n.MTU != 0 && (n.MTU < minCNIMTU || n.MTU > maxCNIMTU) {
+			v.addError(fmt.Errorf("MTU %d is not valid, must be between %d and %d", n.MTU, minCNIMTU, maxCNIMTU))
+		}
+	}
+	return v.valid()
+}
+
+func (d *DNS) validate() (bool, []error) {
+	v := newValidator()
+	if d.Disable {
+		return v.valid()
+	}
+	// the provider defaults to kubedns when not set
+	if d.Provider != "" && !util.Contains(d.Provider, dnsProviders()) {
+		v.addError(fmt.Errorf("%q is not a valid DNS provider. Options are %v", d.Provider, dnsProviders()))
+	}
+	// kube-dns supports up to 3 upstream nameservers
+	if len(d.Options.UpstreamNameservers) > 3 {
+		v.addError(fmt.Errorf("%d DNS upstream nameservers were provided, but a maximum of 3 are supported", len(d.Options.UpstreamNameservers)))
+	}
+	for _, ns := range d.Options.UpstreamNameservers {
+		if !validNameserver(ns) {
+			v.addError(fmt.Errorf("DNS upstream nameserver %q is not a valid IP or IP:port", ns))
+		}
+	}
+	for domain, nameservers := range d.Options.StubDomains {
+		if domain == "" {
+			v.addError(errors.New("DNS stub domain name cannot be empty"))
+		}
+		if len(nameservers) == 0 {
+			v.addError(fmt.Errorf("DNS stub domain %q must have at least one nameserver", domain))
+		}
+		for _, ns := range nameservers {
+			if !validNameserver(ns) {
+				v.addError(fmt.Errorf("Nameserver %q of DNS stub domain %q is not a valid IP or IP:port", ns, domain))
+			}
+		}
+	}
+	if a := d.Options.Autoscaler; a.Enabled {
+		if a.CoresPerReplica <= 0 {
+			v.addError(fmt.Errorf("DNS autoscaler cores per replica %d is not valid, must be greater than 0", a.CoresPerReplica))
+		}
+		if a.NodesPerReplica <= 0 {
+			v.addError(fmt.Errorf("DNS autoscaler nodes per replica %d is not valid, must be greater than 0", a.NodesPerReplica))
+		}
+		if a.MinReplicas <= 0 {
+			v.addError(fmt.Errorf("DNS autoscaler min replicas %d is not valid, must be greater than 0", a.MinReplicas))
+		}
+		if a.MaxReplicas != 0 && a.MaxReplicas < a.MinReplicas {
+			v.addError(fmt.Errorf("DNS autoscaler max replicas %d is not valid, must be greater than or equal to min replicas", a.MaxReplicas))
+		}
+	}
+	// a link-local address cannot collide with the addresses of the cluster
+	if c := d.Options.NodeLocalCache; c.Enabled {
+		if ip := net.ParseIP(c.IP); ip == nil || ip.To4() == nil || !ip.IsLinkLocalUnicast() {
+			v.addError(fmt.Errorf("NodeLocal DNSCache IP %q is not valid, must be a link-local IPv4 address such as 169.254.20.10", c.IP))
+		}
 	}
 	return v.valid()
 }
 
+func validNameserver(ns string) bool {
+	host := ns
+	if h, port, err := net.SplitHostPort(ns); err == nil {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return false
+		}
+		host = h
+	}
+	return net.ParseIP(host) != nil
+}
+
 func (h *HeapsterMonitoring) validate() (bool, []error) {
 	v := newValidator()
 	if h != nil && !h.Disable {
@@ -280,6 +923,147 @@ func (h *HeapsterMonitoring) validate() (bool, []error) {
 	return v.valid()
 }
 
+func (c *ClusterAutoscaler) validate() (bool, []error) {
+	v := newValidator()
+	if !c.Enabled {
+		return v.valid()
+	}
+	if len(c.NodeGroups) == 0 {
+		v.addError(errors.New("At least one cluster autoscaler node group is required"))
+	}
+	names := map[string]bool{}
+	for _, g := range c.NodeGroups {
+		if g.Name == "" {
+			v.addError(errors.New("Cluster autoscaler node group name cannot be empty"))
+		}
+		if names[g.Name] {
+			v.addError(fmt.Errorf("Cluster autoscaler node group %q is defined more than once", g.Name))
+		}
+		names[g.Name] = true
+		if g.MinSize < 0 {
+			v.addError(fmt.Errorf("Min size %d of cluster autoscaler node group %q is not valid, must be greater than or equal to 0", g.MinSize, g.Name))
+		}
+		if g.MaxSize <= 0 || g.MaxSize < g.MinSize {
+			v.addError(fmt.Errorf("Max size %d of cluster autoscaler node group %q is not valid, must be greater than 0 and greater than or equal to the min size", g.MaxSize, g.Name))
+		}
+	}
+	return v.valid()
+}
+
+func (d *Dashboard) validate() (bool, []error) {
+	v := newValidator()
+	if d == nil || d.Disable || !d.Ingress.Enabled {
+		return v.valid()
+	}
+	i := d.Ingress
+	if i.Host == "" {
+		v.addError(errors.New("Dashboard ingress host is required"))
+	} else if strings.Contains(i.Host, "*") || strings.Contains(i.Host, "/") || strings.Contains(i.Host, ":") {
+		v.addError(fmt.Errorf("Dashboard ingress host %q is not valid, must be a host name such as dashboard.example.com", i.Host))
+	}
+	// the auth defaults to client_certificate when not set
+	if i.Auth != "" && !util.Contains(i.Auth, dashboardIngressAuths()) {
+		v.addError(fmt.Errorf("%q is not a valid dashboard ingress auth. Options are %v", i.Auth, dashboardIngressAuths()))
+	}
+	if i.Auth == dashboardIngressAuthOIDC {
+		o := i.OIDC
+		if u, err := url.Parse(o.IssuerURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			v.addError(fmt.Errorf("Dashboard OIDC issuer URL %q is not valid, must be an https URL", o.IssuerURL))
+		}
+		if o.ClientID == "" {
+			v.addError(errors.New("Dashboard OIDC client ID is required"))
+		}
+		if o.ClientSecret == "" {
+			v.addError(errors.New("Dashboard OIDC client secret is required"))
+		}
+		if l := len(o.CookieSecret); l != 16 && l != 24 && l != 32 {
+			v.addError(errors.New("Dashboard OIDC cookie secret must be 16, 24 or 32 bytes long"))
+		}
+		if o.EmailDomain == "" {
+			v.addError(errors.New("Dashboard OIDC email domain is required, use * to allow any email address"))
+		}
+	}
+	return v.valid()
+}
+
+func (e *ExternalDNS) validate() (bool, []error) {
+	v := newValidator()
+	if !e.Enabled {
+		return v.valid()
+	}
+	// the provider defaults to aws when not set
+	if e.Provider != "" && !util.Contains(e.Provider, externalDNSProviders()) {
+		v.addError(fmt.Errorf("%q is not a valid external DNS provider. Options are %v", e.Provider, externalDNSProviders()))
+	}
+	if e.Provider == externalDNSProviderGoogle && e.GoogleProject == "" {
+		v.addError(errors.New("External DNS Google project is required when the provider is google"))
+	}
+	if len(e.DomainFilters) == 0 {
+		v.addError(errors.New("At least one external DNS domain filter is required"))
+	}
+	for _, d := range e.DomainFilters {
+		if strings.Trim(d, ".") == "" {
+			v.addError(errors.New("External DNS domain filter cannot be empty"))
+		}
+	}
+	if e.IngressWildcard != "" {
+		if !strings.HasPrefix(e.IngressWildcard, "*.") {
+			v.addError(fmt.Errorf("External DNS ingress wildcard %q is not valid, must start with *.", e.IngressWildcard))
+		} else if !inDomains(e.IngressWildcard, e.DomainFilters) {
+			v.addError(fmt.Errorf("External DNS ingress wildcard %q is not in the domain filters %v", e.IngressWildcard, e.DomainFilters))
+		}
+	}
+	return v.valid()
+}
+
+func (r *RebootCoordinator) validate() (bool, []error) {
+	v := newValidator()
+	if !r.Enabled {
+		return v.valid()
+	}
+	if r.SentinelFile != "" && !filepath.IsAbs(r.SentinelFile) {
+		v.addError(fmt.Errorf("Reboot coordinator sentinel file %q is not valid, must be an absolute path", r.SentinelFile))
+	}
+	if r.Period != "" {
+		if d, err := time.ParseDuration(r.Period); err != nil || d <= 0 {
+			v.addError(fmt.Errorf("Reboot coordinator period %q is not valid, must be a positive duration such as 1h", r.Period))
+		}
+	}
+	for _, s := range r.BlockingPodSelectors {
+		if strings.TrimSpace(s) == "" {
+			v.addError(errors.New("Reboot coordinator blocking pod selector cannot be empty"))
+		}
+	}
+	return v.valid()
+}
+
+// validateExternalDNS validates that the records that the external DNS
+// add-on manages for the nodes can be created
+func (p *Plan) validateExternalDNS() []error {
+	e := p.AddOns.ExternalDNS
+	if !e.Enabled {
+		return nil
+	}
+	var errs []error
+	if e.IngressWildcard != "" && len(p.Ingress.Nodes) == 0 {
+		errs = append(errs, errors.New("External DNS: an ingress wildcard record requires ingress nodes"))
+	}
+	return errs
+}
+
+// inDomains returns true if the name is one of the domains, or a subdomain
+// of one of the domains
+func inDomains(name string, domains []string) bool {
+	name = strings.TrimSuffix(name, ".")
+	for _, d := range domains {
+		d = strings.Trim(d, ".")
+		if d != "" && (name == d || strings.HasSuffix(name, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *PackageManager) validate() (bool, []error) {
 	v := newValidator()
 	if !p.Disable {
@@ -297,7 +1081,13 @@ func (s sshConnectionSet) validate() (bool, []error) {
 	err := ssh.ValidUnencryptedPrivateKey(s.SSHConfig.Key)
 	if err != nil {
 		v.addError(fmt.Errorf("SSH key validation error: %v", err))
-	} else {
+	} else if b := s.SSHConfig.sshBastion(); b != nil {
+		// the connections to the nodes fail when the bastion is unreachable
+		if err = ssh.TestConnection(b.Host, b.Port, b.User, b.Key, nil); err != nil {
+			v.addError(fmt.Errorf("SSH connectivity validation failed for bastion %q: %v", b.Host, err))
+		}
+	}
+	if err == nil {
 		var wg sync.WaitGroup
 		errQueue := make(chan error, len(s.Nodes))
 		// number of nodes
@@ -305,7 +1095,7 @@ func (s sshConnectionSet) validate() (bool, []error) {
 		for _, node := range s.Nodes {
 			go func(ip string) {
 				defer wg.Done()
-				sshErr := ssh.TestConnection(ip, s.SSHConfig.Port, s.SSHConfig.User, s.SSHConfig.Key)
+				sshErr := ssh.TestConnection(ip, s.SSHConfig.Port, s.SSHConfig.User, s.SSHConfig.Key, s.SSHConfig.sshBastion())
 				// Need to send something the buffered channel
 				if sshErr != nil {
 					errQueue <- fmt.Errorf("SSH connectivity validation failed for %q: %v", ip, sshErr)
@@ -340,6 +1130,12 @@ func (nl nodeList) validate() (bool, []error) {
 	v := newValidator()
 	v.addError(validateNoDuplicateNodeInfo(nl.Nodes)...)
 	v.addError(validateKubeletOptionsDefinedOnce(nl.Nodes)...)
+	for _, n := range nl.Nodes {
+		for _, err := range n.KubeletOptions.validateGarbageCollectionAndEviction() {
+			v.addError(fmt.Errorf("Node %q: %v", n.Host, err))
+		}
+	}
+	v.addError(validateArchDefinedOnce(nl.Nodes)...)
 	return v.valid()
 }
 
@@ -373,17 +1169,123 @@ func validateNoDuplicateNodeInfo(nodes []Node) []error {
 
 func validateKubeletOptionsDefinedOnce(nodes []Node) []error {
 	errs := []error{}
-	seenNodes := map[string]map[string]string{}
+	seenNodes := map[string]KubeletOptions{}
 	for _, n := range nodes {
-		if val, ok := seenNodes[n.HashCode()]; ok && !reflect.DeepEqual(val, n.KubeletOptions.Overrides) {
+		if val, ok := seenNodes[n.HashCode()]; ok && !reflect.DeepEqual(val, n.KubeletOptions) {
 			errs = append(errs, fmt.Errorf("Cannot redefine kubelet options for node %q", n.Host))
 		} else {
-			seenNodes[n.HashCode()] = n.KubeletOptions.Overrides
+			seenNodes[n.HashCode()] = n.KubeletOptions
+		}
+	}
+	return errs
+}
+
+func validateArchDefinedOnce(nodes []Node) []error {
+	errs := []error{}
+	seenNodes := map[string]string{}
+	for _, n := range nodes {
+		if val, ok := seenNodes[n.HashCode()]; ok && val != n.Architecture() {
+			errs = append(errs, fmt.Errorf("Cannot redefine the architecture of node %q", n.Host))
+		} else {
+			seenNodes[n.HashCode()] = n.Architecture()
+		}
+	}
+	return errs
+}
+
+// validateWorkerPools validates that the worker pool names are unique, and
+// that a worker belongs to a single pool or to the worker group.
+func (p *Plan) validateWorkerPools() []error {
+	var errs []error
+	names := map[string]bool{}
+	// a worker can only belong to one pool, or to the worker group
+	workerGroup := map[string]string{}
+	for _, n := range p.Worker.Nodes {
+		workerGroup[n.Host] = "the worker group"
+	}
+	for _, pool := range p.WorkerPools {
+		if names[pool.Name] {
+			errs = append(errs, fmt.Errorf("Worker pool %q is defined more than once", pool.Name))
+		}
+		names[pool.Name] = true
+		if ok, poolErrs := pool.validate(); !ok {
+			for _, err := range poolErrs {
+				errs = append(errs, fmt.Errorf("Worker pool %q: %v", pool.Name, err))
+			}
+		}
+		for _, n := range pool.Nodes {
+			if group, ok := workerGroup[n.Host]; ok {
+				errs = append(errs, fmt.Errorf("Worker pool %q: node %q is already part of %s", pool.Name, n.Host, group))
+				continue
+			}
+			workerGroup[n.Host] = fmt.Sprintf("worker pool %q", pool.Name)
 		}
 	}
 	return errs
 }
 
+func (wp *WorkerPool) validate() (bool, []error) {
+	v := newValidator()
+	if wp.Name == "" {
+		v.addError(errors.New("Worker pool name cannot be empty"))
+	}
+	for _, err := range validation.IsValidLabelValue(wp.Name) {
+		v.addError(fmt.Errorf("Worker pool name %q is not valid %s", wp.Name, err))
+	}
+	for _, err := range validation.IsValidLabelValue(wp.InstanceType) {
+		v.addError(fmt.Errorf("Instance type %q is not valid %s", wp.InstanceType, err))
+	}
+	ng := NodeGroup{ExpectedCount: wp.ExpectedCount, Nodes: wp.Nodes, Labels: wp.Labels, Taints: wp.Taints}
+	v.validate(&ng)
+	return v.valid()
+}
+
+// validateArchitectures validates the constraints of clusters with nodes of
+// different architectures. The control plane, ingress and storage components
+// are only available for amd64, and so are the cluster add-ons, which are
+// scheduled on amd64 workers.
+func (p *Plan) validateArchitectures() []error {
+	errs := []error{}
+	amd64Only := []struct {
+		role  string
+		nodes []Node
+	}{
+		{"Etcd", p.Etcd.Nodes},
+		{"Master", p.Master.Nodes},
+		{"Ingress", p.Ingress.Nodes},
+		{"Storage", p.Storage.Nodes},
+	}
+	for _, r := range amd64Only {
+		for _, n := range r.nodes {
+			if n.Architecture() != archAMD64 {
+				errs = append(errs, fmt.Errorf("%s nodes: node %q must be %s, %s is only supported on worker nodes", r.role, n.Host, archAMD64, n.Architecture()))
+			}
+		}
+	}
+	var amd64Workers, arm64Workers int
+	for _, n := range p.workerNodes() {
+		switch n.Architecture() {
+		case archAMD64:
+			amd64Workers++
+		case archARM64:
+			arm64Workers++
+		}
+	}
+	if arm64Workers == 0 {
+		return errs
+	}
+	if amd64Workers == 0 {
+		errs = append(errs, fmt.Errorf("Worker nodes: at least one %s worker is required to run the cluster add-ons", archAMD64))
+	}
+	if p.AddOns.CNI == nil || p.AddOns.CNI.Disable {
+		return errs
+	}
+	if p.AddOns.CNI.Provider != cniProviderWeave && p.AddOns.CNI.Provider != cniProviderCustom {
+		errs = append(errs, fmt.Errorf("Worker nodes: the %q CNI provider does not support %s nodes, use %q or %q", p.AddOns.CNI.Provider, archARM64, cniProviderWeave, cniProviderCustom))
+	}
+	return errs
+}
+
 func (ng *NodeGroup) validate() (bool, []error) {
 	v := newValidator()
 	if ng == nil || len(ng.Nodes) <= 0 {
@@ -398,6 +1300,8 @@ func (ng *NodeGroup) validate() (bool, []error) {
 	for i, n := range ng.Nodes {
 		v.validateWithErrPrefix(fmt.Sprintf("Node #%d", i+1), &n)
 	}
+	v.addError(validateNodeLabels(ng.Labels)...)
+	v.addError(validateTaints(ng.Taints)...)
 
 	return v.valid()
 }
@@ -436,6 +1340,8 @@ func (mng *MasterNodeGroup) validate() (bool, []error) {
 	for i, n := range mng.Nodes {
 		v.validateWithErrPrefix(fmt.Sprintf("Node #%d", i+1), &n)
 	}
+	v.addError(validateNodeLabels(mng.Labels)...)
+	v.addError(validateTaints(mng.Taints)...)
 
 	if mng.LoadBalancedFQDN == "" {
 		v.addError(fmt.Errorf("Load balanced FQDN is required"))
@@ -445,6 +1351,29 @@ func (mng *MasterNodeGroup) validate() (bool, []error) {
 		v.addError(fmt.Errorf("Load balanced shortname is required"))
 	}
 
+	if vip := mng.VirtualIP; vip.Address != "" {
+		v.validateWithErrPrefix("Virtual IP", &vip)
+		for _, n := range mng.Nodes {
+			if vip.Address == n.IP || vip.Address == n.InternalIP {
+				v.addError(fmt.Errorf("Virtual IP %q cannot be the IP of the node %q", vip.Address, n.Host))
+			}
+		}
+		if ip := net.ParseIP(mng.LoadBalancedFQDN); ip != nil && mng.LoadBalancedFQDN != vip.Address {
+			v.addError(fmt.Errorf("Load balanced FQDN %q must be the virtual IP %q, or a DNS name that resolves to it", mng.LoadBalancedFQDN, vip.Address))
+		}
+	}
+
+	return v.valid()
+}
+
+func (vip *MasterVirtualIP) validate() (bool, []error) {
+	v := newValidator()
+	if ip := net.ParseIP(vip.Address); ip == nil || ip.To4() == nil {
+		v.addError(fmt.Errorf("%q is not a valid IPv4 address", vip.Address))
+	}
+	if vip.RouterID < 1 || vip.RouterID > 255 {
+		v.addError(fmt.Errorf("Router ID %d is not valid, must be between 1 and 255", vip.RouterID))
+	}
 	return v.valid()
 }
 
@@ -462,21 +1391,48 @@ func (n *Node) validate() (bool, []error) {
 	if ip := net.ParseIP(n.InternalIP); n.InternalIP != "" && ip == nil {
 		v.addError(fmt.Errorf("Invalid InternalIP provided"))
 	}
+	if n.Arch != "" && !util.Contains(n.Arch, architectures()) {
+		v.addError(fmt.Errorf("Node architecture %q is not valid, options are %v", n.Arch, architectures()))
+	}
+	v.addError(validateNodeLabels(n.Labels)...)
+	v.addError(validateTaints(n.Taints)...)
+	return v.valid()
+}
+
+func validateNodeLabels(labels map[string]string) []error {
+	var errs []error
 	// validate node labels don't start with 'kismatic/' as that is reserved
-	for key, val := range n.Labels {
+	for key, val := range labels {
 		if strings.HasPrefix(key, "kismatic/") {
-			v.addError(fmt.Errorf("Node label %q cannot start with 'kismatic/'", key))
+			errs = append(errs, fmt.Errorf("Node label %q cannot start with 'kismatic/'", key))
 		}
-		errs := validation.IsQualifiedName(key)
-		for _, err := range errs {
-			v.addError(fmt.Errorf("Node label name %q is not valid %s", key, err))
+		for _, err := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Errorf("Node label name %q is not valid %s", key, err))
 		}
-		errs = validation.IsValidLabelValue(val)
-		for _, err := range errs {
-			v.addError(fmt.Errorf("Node label %q is not valid %s", val, err))
+		for _, err := range validation.IsValidLabelValue(val) {
+			errs = append(errs, fmt.Errorf("Node label %q is not valid %s", val, err))
 		}
 	}
-	return v.valid()
+	return errs
+}
+
+func validateTaints(taints []Taint) []error {
+	var errs []error
+	for _, t := range taints {
+		if strings.HasPrefix(t.Key, "kismatic/") {
+			errs = append(errs, fmt.Errorf("Node taint %q cannot start with 'kismatic/'", t.Key))
+		}
+		for _, err := range validation.IsQualifiedName(t.Key) {
+			errs = append(errs, fmt.Errorf("Node taint key %q is not valid %s", t.Key, err))
+		}
+		for _, err := range validation.IsValidLabelValue(t.Value) {
+			errs = append(errs, fmt.Errorf("Node taint value %q is not valid %s", t.Value, err))
+		}
+		if !util.Contains(t.Effect, taintEffects()) {
+			errs = append(errs, fmt.Errorf("Node taint effect %q is not valid, options are %v", t.Effect, taintEffects()))
+		}
+	}
+	return errs
 }
 
 func (dr *DockerRegistry) validate() (bool, []error) {
@@ -502,6 +1458,46 @@ func (dr *DockerRegistry) validate() (bool, []error) {
 func (d Docker) validate() (bool, []error) {
 	v := newValidator()
 	v.validateWithErrPrefix("Storage", d.Storage)
+	servers := map[string]bool{}
+	for _, c := range d.RegistryCredentials {
+		v.validateWithErrPrefix("Registry credential", c)
+		if servers[c.Server] {
+			v.addError(fmt.Errorf("Registry credential server %q is duplicated", c.Server))
+		}
+		servers[c.Server] = true
+	}
+	for _, ns := range d.PullSecretNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			v.addError(fmt.Errorf("Pull secret namespace %q is not valid: %s", ns, msg))
+		}
+	}
+	for _, m := range d.RegistryMirrors {
+		u, err := url.Parse(m)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addError(fmt.Errorf("Registry mirror %q is not valid, must be a URL with http or https", m))
+		}
+	}
+	for _, r := range d.InsecureRegistries {
+		if r == "" || strings.Contains(r, "://") {
+			v.addError(fmt.Errorf("Insecure registry %q is not valid, must be a hostname or IP address and port, or a CIDR, without http or https", r))
+		}
+	}
+	return v.valid()
+}
+
+func (c RegistryCredential) validate() (bool, []error) {
+	v := newValidator()
+	if c.Server == "" {
+		v.addError(errors.New("Server is required"))
+	} else if strings.Contains(c.Server, "://") || strings.Contains(c.Server, "/") {
+		v.addError(fmt.Errorf("Server %q is not valid, must be a hostname or IP address and port without http or https", c.Server))
+	}
+	if c.Username == "" {
+		v.addError(fmt.Errorf("Username of server %q is required", c.Server))
+	}
+	if c.Password == "" {
+		v.addError(fmt.Errorf("Password of server %q is required", c.Server))
+	}
 	return v.valid()
 }
 
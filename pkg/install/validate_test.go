@@ -2,6 +2,9 @@ package install
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -192,6 +195,46 @@ func TestValidatePlanNegativeSSHPort(t *testing.T) {
 	assertInvalidPlan(t, p)
 }
 
+func TestValidatePlanSSHBastion(t *testing.T) {
+	tests := []struct {
+		bastion SSHBastion
+		valid   bool
+	}{
+		{
+			bastion: SSHBastion{},
+			valid:   true,
+		},
+		{
+			bastion: SSHBastion{Host: "bastion.example.com", User: "ubuntu", Key: "/bin/sh", Port: 22},
+			valid:   true,
+		},
+		{
+			bastion: SSHBastion{Host: "bastion.example.com", Key: "/bin/sh", Port: 22},
+			valid:   false,
+		},
+		{
+			bastion: SSHBastion{Host: "bastion.example.com", User: "ubuntu", Key: "/foo", Port: 22},
+			valid:   false,
+		},
+		{
+			bastion: SSHBastion{Host: "bastion.example.com", User: "ubuntu", Key: "sh", Port: 22},
+			valid:   false,
+		},
+		{
+			bastion: SSHBastion{Host: "bastion.example.com", User: "ubuntu", Key: "/bin/sh", Port: 70000},
+			valid:   false,
+		},
+	}
+	for i, test := range tests {
+		s := validPlan.Cluster.SSH
+		s.Bastion = test.bastion
+		ok, _ := s.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expected %v, but got %v", i, test.valid, ok)
+		}
+	}
+}
+
 func TestValidatePlanEmptyLoadBalancedFQDN(t *testing.T) {
 	p := validPlan
 	p.Master.LoadBalancedFQDN = ""
@@ -673,6 +716,8 @@ func TestValidatePlanBadCerts(t *testing.T) {
 	if err := pki.GenerateClusterCertificates(&p, ca); err != nil {
 		t.Fatalf("failed to generate certs: %v", err)
 	}
+	// copy the nodes, as they are shared with validPlan
+	p.Master.Nodes = append([]Node{}, p.Master.Nodes...)
 	p.Master.Nodes[0] = Node{
 		Host:       "master01",
 		IP:         "11.12.13.14",
@@ -856,6 +901,31 @@ func TestValidatePlanDisconnectedInstallationSucceeds(t *testing.T) {
 	}
 }
 
+func TestValidatePlanBakedImage(t *testing.T) {
+	tests := []struct {
+		directLVM bool
+		valid     bool
+	}{
+		{
+			directLVM: false,
+			valid:     true,
+		},
+		{
+			directLVM: true,
+			valid:     false,
+		},
+	}
+	for i, test := range tests {
+		plan := validPlan
+		plan.Cluster.BakedImage = true
+		plan.Docker.Storage.DirectLVM = DockerStorageDirectLVM{Enabled: test.directLVM, BlockDevice: "/dev/xvdb"}
+		ok, errs := plan.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t: %v", i, test.valid, ok, errs)
+		}
+	}
+}
+
 func TestDockerRegistry(t *testing.T) {
 	tests := []struct {
 		d     DockerRegistry
@@ -1001,6 +1071,41 @@ func TestCNIAddOn(t *testing.T) {
 			},
 			valid: true,
 		},
+		{
+			n: CNI{
+				Provider: "weave",
+				MTU:      1380,
+			},
+			valid: true,
+		},
+		{
+			n: CNI{
+				Provider: "weave",
+				MTU:      100,
+			},
+			valid: false,
+		},
+		{
+			n: CNI{
+				Provider:   "weave",
+				Encryption: true,
+			},
+			valid: true,
+		},
+		{
+			n: CNI{
+				Provider:   "contiv",
+				Encryption: true,
+			},
+			valid: false,
+		},
+		{
+			n: CNI{
+				Provider: "weave",
+				MTU:      65536,
+			},
+			valid: false,
+		},
 		{
 			n: CNI{
 				Provider: "foo",
@@ -1186,6 +1291,199 @@ func TestHeapsterAddOn(t *testing.T) {
 	}
 }
 
+func TestValidateMasterVirtualIP(t *testing.T) {
+	tests := []struct {
+		vip          MasterVirtualIP
+		loadBalancer string
+		valid        bool
+	}{
+		{
+			vip:          MasterVirtualIP{},
+			loadBalancer: "test",
+			valid:        true,
+		},
+		{
+			vip:          MasterVirtualIP{Address: "192.168.205.100", RouterID: 51},
+			loadBalancer: "test",
+			valid:        true,
+		},
+		{
+			vip:          MasterVirtualIP{Address: "192.168.205.100", Interface: "eth1", RouterID: 51},
+			loadBalancer: "192.168.205.100",
+			valid:        true,
+		},
+		{
+			vip:          MasterVirtualIP{Address: "192.168.205.100", RouterID: 51},
+			loadBalancer: "192.168.205.11",
+			valid:        false,
+		},
+		{
+			vip:          MasterVirtualIP{Address: "192.168.205.11", RouterID: 51},
+			loadBalancer: "test",
+			valid:        false,
+		},
+		{
+			vip:          MasterVirtualIP{Address: "fd00::100", RouterID: 51},
+			loadBalancer: "test",
+			valid:        false,
+		},
+		{
+			vip:          MasterVirtualIP{Address: "192.168.205.100", RouterID: 256},
+			loadBalancer: "test",
+			valid:        false,
+		},
+	}
+	for i, test := range tests {
+		mng := validPlan.Master
+		mng.Nodes = []Node{{Host: "master01", IP: "192.168.205.11"}}
+		mng.VirtualIP = test.vip
+		mng.LoadBalancedFQDN = test.loadBalancer
+		ok, _ := mng.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestDNSAddOn(t *testing.T) {
+	tests := []struct {
+		d     DNS
+		valid bool
+	}{
+		{
+			d:     DNS{Provider: "kubedns"},
+			valid: true,
+		},
+		{
+			d:     DNS{Provider: "coredns"},
+			valid: true,
+		},
+		{
+			d:     DNS{Provider: "foo"},
+			valid: false,
+		},
+		{
+			d:     DNS{Disable: true, Provider: "foo"},
+			valid: true,
+		},
+		{
+			d: DNS{
+				Provider: "coredns",
+				Options: DNSOptions{
+					UpstreamNameservers: []string{"8.8.8.8", "10.0.0.1:5353"},
+					StubDomains:         map[string][]string{"acme.local": {"10.0.0.2"}},
+				},
+			},
+			valid: true,
+		},
+		{
+			d: DNS{
+				Provider: "kubedns",
+				Options: DNSOptions{
+					UpstreamNameservers: []string{"8.8.8.8", "8.8.4.4", "10.0.0.1", "10.0.0.2"},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "kubedns",
+				Options: DNSOptions{
+					UpstreamNameservers: []string{"dns.example.com"},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "kubedns",
+				Options: DNSOptions{
+					UpstreamNameservers: []string{"10.0.0.1:99999"},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "kubedns",
+				Options: DNSOptions{
+					StubDomains: map[string][]string{"acme.local": {}},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "coredns",
+				Options: DNSOptions{
+					Autoscaler: DNSAutoscaler{Enabled: true, CoresPerReplica: 256, NodesPerReplica: 16, MinReplicas: 2, MaxReplicas: 10},
+				},
+			},
+			valid: true,
+		},
+		{
+			d: DNS{
+				Provider: "coredns",
+				Options: DNSOptions{
+					Autoscaler: DNSAutoscaler{Enabled: true, CoresPerReplica: 256, NodesPerReplica: 16, MinReplicas: 3, MaxReplicas: 2},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "coredns",
+				Options: DNSOptions{
+					Autoscaler: DNSAutoscaler{Enabled: true, MinReplicas: 2},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "kubedns",
+				Options: DNSOptions{
+					NodeLocalCache: DNSNodeLocalCache{Enabled: true, IP: "169.254.20.10"},
+				},
+			},
+			valid: true,
+		},
+		{
+			d: DNS{
+				Provider: "kubedns",
+				Options: DNSOptions{
+					NodeLocalCache: DNSNodeLocalCache{Enabled: true, IP: "10.0.0.10"},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "coredns",
+				Options: DNSOptions{
+					NodeLocalCache: DNSNodeLocalCache{Enabled: true},
+				},
+			},
+			valid: false,
+		},
+		{
+			d: DNS{
+				Provider: "coredns",
+				Options: DNSOptions{
+					NodeLocalCache: DNSNodeLocalCache{IP: "foo"},
+				},
+			},
+			valid: true,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.d.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
 func TestPackageManagerAddOn(t *testing.T) {
 	tests := []struct {
 		p     PackageManager
@@ -1397,110 +1695,455 @@ func TestNodeLabels(t *testing.T) {
 	}
 }
 
-func TestNodeKubeletOptions(t *testing.T) {
+func TestNodeTaints(t *testing.T) {
 	tests := []struct {
-		nl    nodeList
-		valid bool
+		taints []Taint
+		valid  bool
 	}{
 		{
-			nl: nodeList{
-				[]Node{
-					{
-						Host: "host1",
-						IP:   "10.0.0.1",
-						KubeletOptions: KubeletOptions{
-							Overrides: map[string]string{
-								"v": "2",
-							},
-						},
-					},
-					{
-						Host: "host2",
-						IP:   "10.0.0.2",
-						KubeletOptions: KubeletOptions{
-							Overrides: map[string]string{
-								"v": "2",
-							},
-						},
-					},
-				},
-			},
-			valid: true,
+			taints: []Taint{{Key: "com.foo/dedicated", Value: "db", Effect: "NoSchedule"}},
+			valid:  true,
 		},
 		{
-			nl: nodeList{
-				[]Node{
-					{
-						Host: "host1",
-						IP:   "10.0.0.1",
-						KubeletOptions: KubeletOptions{
-							Overrides: map[string]string{
-								"v": "2",
-							},
-						},
-					},
-					{
-						Host: "host1",
-						IP:   "10.0.0.1",
-						KubeletOptions: KubeletOptions{
-							Overrides: map[string]string{
-								"v": "2",
-							},
-						},
-					},
-				},
-			},
-			valid: true,
+			taints: []Taint{{Key: "dedicated", Effect: "NoExecute"}, {Key: "gpu", Value: "true", Effect: "PreferNoSchedule"}},
+			valid:  true,
 		},
 		{
-			nl: nodeList{
-				[]Node{
-					{
-						Host: "host1",
-						IP:   "10.0.0.1",
-						KubeletOptions: KubeletOptions{
-							Overrides: map[string]string{
-								"v": "2",
-							},
-						},
-					},
-					{
-						Host: "host1",
-						IP:   "10.0.0.1",
-						KubeletOptions: KubeletOptions{
-							Overrides: map[string]string{
-								"foo": "bar",
-							},
-						},
-					},
-				},
-			},
-			valid: false,
+			taints: []Taint{{Key: "dedicated", Value: "db"}},
+			valid:  false,
 		},
 		{
-			nl: nodeList{
-				[]Node{
-					{
-						Host: "host1",
-						IP:   "10.0.0.1",
-						KubeletOptions: KubeletOptions{
-							Overrides: map[string]string{
-								"v": "2",
-							},
-						},
-					},
-					{
+			taints: []Taint{{Key: "dedicated", Value: "db", Effect: "NoScheduleAtAll"}},
+			valid:  false,
+		},
+		{
+			taints: []Taint{{Value: "db", Effect: "NoSchedule"}},
+			valid:  false,
+		},
+		{
+			taints: []Taint{{Key: "kismatic/foo", Effect: "NoSchedule"}},
+			valid:  false,
+		},
+		{
+			taints: []Taint{{Key: "dedicated", Value: ":db", Effect: "NoSchedule"}},
+			valid:  false,
+		},
+	}
+	for i, test := range tests {
+		n := Node{Host: "foo", IP: "192.1.1.1", Taints: test.taints}
+		if ok, _ := n.validate(); ok != test.valid {
+			t.Errorf("test %d: node: expect %t, but got %t", i, test.valid, ok)
+		}
+		ng := NodeGroup{ExpectedCount: 1, Nodes: []Node{{Host: "foo", IP: "192.1.1.1"}}, Taints: test.taints}
+		if ok, _ := ng.validate(); ok != test.valid {
+			t.Errorf("test %d: node group: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestEtcdNodeGroupLabelsAndTaints(t *testing.T) {
+	p := validPlan
+	p.Etcd.Labels = map[string]string{"com.foo/bar": "baz"}
+	if ok, _ := p.validate(); ok {
+		t.Errorf("expected plan with etcd group labels to be invalid")
+	}
+	p = validPlan
+	p.Etcd.Taints = []Taint{{Key: "dedicated", Effect: "NoSchedule"}}
+	if ok, _ := p.validate(); ok {
+		t.Errorf("expected plan with etcd group taints to be invalid")
+	}
+}
+
+func TestValidateStackedEtcd(t *testing.T) {
+	p := validPlan
+	p.Cluster.EtcdTopology = "colocated"
+	if ok, _ := p.validate(); ok {
+		t.Errorf("expected plan with an invalid etcd topology to be invalid")
+	}
+
+	p = validPlan
+	p.Cluster.EtcdTopology = etcdTopologyStacked
+	p.Etcd = NodeGroup{ExpectedCount: len(p.Master.Nodes), Nodes: p.Master.Nodes}
+	if ok, errs := p.validate(); !ok {
+		t.Errorf("expected plan with stacked etcd to be valid, but got errors: %v", errs)
+	}
+
+	p.Etcd = NodeGroup{ExpectedCount: 1, Nodes: []Node{{Host: "etcd-only", IP: "10.0.0.99"}}}
+	if ok, _ := p.validate(); ok {
+		t.Errorf("expected plan with stacked etcd on a non-master node to be invalid")
+	}
+}
+
+func TestValidateSELinux(t *testing.T) {
+	tests := []struct {
+		mode  string
+		valid bool
+	}{
+		{"", true},
+		{"enforcing", true},
+		{"permissive", true},
+		{"disabled", false},
+		{"Enforcing", false},
+	}
+	for _, test := range tests {
+		c := validPlan.Cluster
+		c.SELinux = test.mode
+		if ok, errs := c.validate(); ok != test.valid {
+			t.Errorf("SELinux mode %q: expected valid to be %v, but got %v: %v", test.mode, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateEtcdBackup(t *testing.T) {
+	tests := []struct {
+		name   string
+		backup EtcdBackup
+		valid  bool
+	}{
+		{
+			name:  "disabled",
+			valid: true,
+		},
+		{
+			name:   "s3 with instance profile",
+			backup: EtcdBackup{Enabled: true, Destination: "s3://bucket/etcd", EncryptionKeyFile: "/bin/sh", Retention: 7},
+			valid:  true,
+		},
+		{
+			name:   "gcs with credentials",
+			backup: EtcdBackup{Enabled: true, Destination: "gs://bucket", CredentialsFile: "/bin/sh", EncryptionKeyFile: "/bin/sh", Retention: 7},
+			valid:  true,
+		},
+		{
+			name:   "gcs without credentials",
+			backup: EtcdBackup{Enabled: true, Destination: "gs://bucket", EncryptionKeyFile: "/bin/sh", Retention: 7},
+		},
+		{
+			name:   "unsupported destination",
+			backup: EtcdBackup{Enabled: true, Destination: "ftp://bucket", EncryptionKeyFile: "/bin/sh", Retention: 7},
+		},
+		{
+			name:   "missing bucket",
+			backup: EtcdBackup{Enabled: true, Destination: "s3:///etcd", EncryptionKeyFile: "/bin/sh", Retention: 7},
+		},
+		{
+			name:   "missing encryption key",
+			backup: EtcdBackup{Enabled: true, Destination: "s3://bucket"},
+		},
+		{
+			name:   "encryption key not found",
+			backup: EtcdBackup{Enabled: true, Destination: "s3://bucket", EncryptionKeyFile: "/foo/bar"},
+		},
+		{
+			name:   "credentials not found",
+			backup: EtcdBackup{Enabled: true, Destination: "s3://bucket", CredentialsFile: "/foo/bar", EncryptionKeyFile: "/bin/sh"},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.backup.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateHostDNS(t *testing.T) {
+	tests := []struct {
+		name  string
+		dns   HostDNS
+		valid bool
+	}{
+		{
+			name:  "not set",
+			valid: true,
+		},
+		{
+			name: "nameservers, search domains and options",
+			dns: HostDNS{
+				Nameservers:   []string{"10.0.0.2", "10.0.0.3"},
+				SearchDomains: []string{"corp.example.com"},
+				Options:       []string{"ndots:2", "rotate"},
+			},
+			valid: true,
+		},
+		{
+			name: "search domains without nameservers",
+			dns:  HostDNS{SearchDomains: []string{"corp.example.com"}},
+		},
+		{
+			name: "too many nameservers",
+			dns:  HostDNS{Nameservers: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}},
+		},
+		{
+			name: "loopback nameserver",
+			dns:  HostDNS{Nameservers: []string{"127.0.0.53"}},
+		},
+		{
+			name: "hostname nameserver",
+			dns:  HostDNS{Nameservers: []string{"dns.example.com"}},
+		},
+		{
+			name: "invalid search domain",
+			dns:  HostDNS{Nameservers: []string{"10.0.0.2"}, SearchDomains: []string{"Corp Example"}},
+		},
+		{
+			name: "invalid option",
+			dns:  HostDNS{Nameservers: []string{"10.0.0.2"}, Options: []string{"ndots=2"}},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.dns.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateTimeSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeSync TimeSync
+		valid    bool
+	}{
+		{
+			name:  "disabled",
+			valid: true,
+		},
+		{
+			name:     "servers",
+			timeSync: TimeSync{Enabled: true, Servers: []string{"0.pool.ntp.org", "10.0.0.1", "fd00::1"}},
+			valid:    true,
+		},
+		{
+			name:     "no servers",
+			timeSync: TimeSync{Enabled: true},
+		},
+		{
+			name:     "server with scheme",
+			timeSync: TimeSync{Enabled: true, Servers: []string{"ntp://0.pool.ntp.org"}},
+		},
+		{
+			name:     "negative skew",
+			timeSync: TimeSync{MaxSkewSeconds: -1},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.timeSync.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateEtcdDataVolume(t *testing.T) {
+	tests := []struct {
+		name   string
+		volume EtcdDataVolume
+		valid  bool
+	}{
+		{
+			name:  "disabled",
+			valid: true,
+		},
+		{
+			name:   "xfs",
+			volume: EtcdDataVolume{Enabled: true, BlockDevice: "/dev/xvdf", Filesystem: "xfs", MaxSyncLatencyMS: 10},
+			valid:  true,
+		},
+		{
+			name:   "ext4 with default latency",
+			volume: EtcdDataVolume{Enabled: true, BlockDevice: "/dev/nvme1n1", Filesystem: "ext4"},
+			valid:  true,
+		},
+		{
+			name:   "missing block device",
+			volume: EtcdDataVolume{Enabled: true, Filesystem: "xfs"},
+		},
+		{
+			name:   "block device not under /dev",
+			volume: EtcdDataVolume{Enabled: true, BlockDevice: "xvdf"},
+		},
+		{
+			name:   "unsupported filesystem",
+			volume: EtcdDataVolume{Enabled: true, BlockDevice: "/dev/xvdf", Filesystem: "btrfs"},
+		},
+		{
+			name:   "negative latency",
+			volume: EtcdDataVolume{Enabled: true, BlockDevice: "/dev/xvdf", MaxSyncLatencyMS: -1},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.volume.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestEtcdBackupRemote(t *testing.T) {
+	tests := []struct {
+		destination string
+		provider    string
+		remote      string
+	}{
+		{"s3://bucket", "s3", ":s3:bucket"},
+		{"s3://bucket/etcd/prod/", "s3", ":s3:bucket/etcd/prod"},
+		{"gs://bucket/etcd", "gcs", ":gcs:bucket/etcd"},
+	}
+	for _, test := range tests {
+		provider, remote, err := EtcdBackup{Destination: test.destination}.remote()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.destination, err)
+			continue
+		}
+		if provider != test.provider || remote != test.remote {
+			t.Errorf("%s: expected %s %s, but got %s %s", test.destination, test.provider, test.remote, provider, remote)
+		}
+	}
+}
+
+func TestValidateSingleNode(t *testing.T) {
+	n := Node{Host: "node1", IP: "192.168.205.10"}
+	p := validPlan
+	p.Etcd = NodeGroup{ExpectedCount: 1, Nodes: []Node{n}}
+	p.Master.ExpectedCount = 1
+	p.Master.Nodes = []Node{n}
+	p.Worker = NodeGroup{ExpectedCount: 1, Nodes: []Node{n}}
+	p.Ingress = OptionalNodeGroup{}
+	p.Storage = OptionalNodeGroup{}
+	if ok, _ := p.validate(); ok {
+		t.Errorf("expected single node plan to be invalid when allow_single_node is not set")
+	}
+	p.Cluster.AllowSingleNode = true
+	if ok, errs := p.validate(); !ok {
+		t.Errorf("expected single node plan to be valid when allow_single_node is set, but got errors: %v", errs)
+	}
+}
+
+func TestValidateArchitectures(t *testing.T) {
+	amd64Worker := Node{Host: "worker01", IP: "192.168.205.12"}
+	arm64Worker := Node{Host: "worker02", IP: "192.168.205.13", Arch: "arm64"}
+	tests := []struct {
+		name     string
+		provider string
+		workers  []Node
+		masters  []Node
+		valid    bool
+	}{
+		{
+			name:     "amd64 only",
+			provider: cniProviderCalico,
+			workers:  []Node{amd64Worker},
+			valid:    true,
+		},
+		{
+			name:     "mixed workers with weave",
+			provider: cniProviderWeave,
+			workers:  []Node{amd64Worker, arm64Worker},
+			valid:    true,
+		},
+		{
+			name:     "mixed workers with calico",
+			provider: cniProviderCalico,
+			workers:  []Node{amd64Worker, arm64Worker},
+		},
+		{
+			name:     "arm64 workers only",
+			provider: cniProviderWeave,
+			workers:  []Node{arm64Worker},
+		},
+		{
+			name:     "arm64 master",
+			provider: cniProviderWeave,
+			workers:  []Node{amd64Worker},
+			masters:  []Node{{Host: "master01", IP: "192.168.205.11", Arch: "arm64"}},
+		},
+		{
+			name:     "invalid architecture",
+			provider: cniProviderWeave,
+			workers:  []Node{{Host: "worker01", IP: "192.168.205.12", Arch: "x86"}},
+		},
+	}
+	for _, test := range tests {
+		p := validPlan
+		cni := *p.AddOns.CNI
+		cni.Provider = test.provider
+		p.AddOns.CNI = &cni
+		p.Worker = NodeGroup{ExpectedCount: len(test.workers), Nodes: test.workers}
+		if test.masters != nil {
+			p.Master.ExpectedCount = len(test.masters)
+			p.Master.Nodes = test.masters
+		}
+		if ok, errs := p.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestNodeArchDefinedOnce(t *testing.T) {
+	nodes := []Node{
+		{Host: "host1", IP: "10.0.0.1"},
+		{Host: "host1", IP: "10.0.0.1", Arch: "arm64"},
+	}
+	if errs := validateArchDefinedOnce(nodes); len(errs) != 1 {
+		t.Errorf("expected an error when redefining the architecture of a node, but got %v", errs)
+	}
+	nodes[1].Arch = "amd64"
+	if errs := validateArchDefinedOnce(nodes); len(errs) != 0 {
+		t.Errorf("expected no errors when the default architecture is set explicitly, but got %v", errs)
+	}
+}
+
+func TestNodeKubeletOptions(t *testing.T) {
+	tests := []struct {
+		nl    nodeList
+		valid bool
+	}{
+		{
+			nl: nodeList{
+				[]Node{
+					{
 						Host: "host1",
 						IP:   "10.0.0.1",
 						KubeletOptions: KubeletOptions{
 							Overrides: map[string]string{
-								"v": "3",
+								"v": "2",
+							},
+						},
+					},
+					{
+						Host: "host2",
+						IP:   "10.0.0.2",
+						KubeletOptions: KubeletOptions{
+							Overrides: map[string]string{
+								"v": "2",
 							},
 						},
 					},
 				},
 			},
-			valid: false,
+			valid: true,
+		},
+		{
+			nl: nodeList{
+				[]Node{
+					{
+						Host: "host1",
+						IP:   "10.0.0.1",
+						KubeletOptions: KubeletOptions{
+							Overrides: map[string]string{
+								"v": "2",
+							},
+						},
+					},
+					{
+						Host: "host1",
+						IP:   "10.0.0.1",
+						KubeletOptions: KubeletOptions{
+							Overrides: map[string]string{
+								"v": "2",
+							},
+						},
+					},
+				},
+			},
+			valid: true,
 		},
 		{
 			nl: nodeList{
@@ -1519,7 +2162,6 @@ func TestNodeKubeletOptions(t *testing.T) {
 						IP:   "10.0.0.1",
 						KubeletOptions: KubeletOptions{
 							Overrides: map[string]string{
-								"v":   "2",
 								"foo": "bar",
 							},
 						},
@@ -1528,11 +2170,1035 @@ func TestNodeKubeletOptions(t *testing.T) {
 			},
 			valid: false,
 		},
-	}
-	for i, test := range tests {
-		ok, _ := test.nl.validate()
-		if ok != test.valid {
-			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		{
+			nl: nodeList{
+				[]Node{
+					{
+						Host: "host1",
+						IP:   "10.0.0.1",
+						KubeletOptions: KubeletOptions{
+							Overrides: map[string]string{
+								"v": "2",
+							},
+						},
+					},
+					{
+						Host: "host1",
+						IP:   "10.0.0.1",
+						KubeletOptions: KubeletOptions{
+							Overrides: map[string]string{
+								"v": "3",
+							},
+						},
+					},
+				},
+			},
+			valid: false,
+		},
+		{
+			nl: nodeList{
+				[]Node{
+					{
+						Host: "host1",
+						IP:   "10.0.0.1",
+						KubeletOptions: KubeletOptions{
+							Overrides: map[string]string{
+								"v": "2",
+							},
+						},
+					},
+					{
+						Host: "host1",
+						IP:   "10.0.0.1",
+						KubeletOptions: KubeletOptions{
+							Overrides: map[string]string{
+								"v":   "2",
+								"foo": "bar",
+							},
+						},
+					},
+				},
+			},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.nl.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestClusterAutoscalerAddOn(t *testing.T) {
+	tests := []struct {
+		c     ClusterAutoscaler
+		valid bool
+	}{
+		{
+			c:     ClusterAutoscaler{},
+			valid: true,
+		},
+		{
+			c:     ClusterAutoscaler{Enabled: true},
+			valid: false,
+		},
+		{
+			c: ClusterAutoscaler{
+				Enabled:    true,
+				NodeGroups: []AutoscalerNodeGroup{{Name: "workers", MinSize: 1, MaxSize: 10}, {Name: "gpu", MinSize: 0, MaxSize: 2}},
+			},
+			valid: true,
+		},
+		{
+			c: ClusterAutoscaler{
+				Enabled:    true,
+				NodeGroups: []AutoscalerNodeGroup{{MinSize: 1, MaxSize: 10}},
+			},
+			valid: false,
+		},
+		{
+			c: ClusterAutoscaler{
+				Enabled:    true,
+				NodeGroups: []AutoscalerNodeGroup{{Name: "workers", MinSize: 1, MaxSize: 10}, {Name: "workers", MinSize: 1, MaxSize: 10}},
+			},
+			valid: false,
+		},
+		{
+			c: ClusterAutoscaler{
+				Enabled:    true,
+				NodeGroups: []AutoscalerNodeGroup{{Name: "workers", MinSize: -1, MaxSize: 10}},
+			},
+			valid: false,
+		},
+		{
+			c: ClusterAutoscaler{
+				Enabled:    true,
+				NodeGroups: []AutoscalerNodeGroup{{Name: "workers", MinSize: 5, MaxSize: 2}},
+			},
+			valid: false,
+		},
+		{
+			c: ClusterAutoscaler{
+				Enabled:    true,
+				NodeGroups: []AutoscalerNodeGroup{{Name: "workers"}},
+			},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.c.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidatePlanClusterAutoscalerRequiresAWS(t *testing.T) {
+	p := validPlan
+	p.AddOns.ClusterAutoscaler = ClusterAutoscaler{
+		Enabled:    true,
+		NodeGroups: []AutoscalerNodeGroup{{Name: "workers", MinSize: 1, MaxSize: 10}},
+	}
+	assertInvalidPlan(t, p)
+	p.Cluster.CloudProvider.Provider = "aws"
+	if valid, errs := p.validate(); !valid {
+		t.Errorf("expected plan to be valid, but got errors: %v", errs)
+	}
+}
+
+func TestValidatePlanWorkerPools(t *testing.T) {
+	tests := []struct {
+		worker NodeGroup
+		pools  []WorkerPool
+		valid  bool
+	}{
+		{
+			worker: validPlan.Worker,
+			pools:  []WorkerPool{{Name: "gpu", InstanceType: "p2.xlarge", ExpectedCount: 1, Nodes: []Node{{Host: "gpu01", IP: "192.168.205.13"}}}},
+			valid:  true,
+		},
+		{
+			// the worker group is optional when pools are defined
+			pools: []WorkerPool{{Name: "gpu", ExpectedCount: 1, Nodes: []Node{{Host: "gpu01", IP: "192.168.205.13"}}}},
+			valid: true,
+		},
+		{
+			pools: []WorkerPool{{Name: "gpu", ExpectedCount: 2, Nodes: []Node{{Host: "gpu01", IP: "192.168.205.13"}}}},
+			valid: false,
+		},
+		{
+			pools: []WorkerPool{{ExpectedCount: 1, Nodes: []Node{{Host: "gpu01", IP: "192.168.205.13"}}}},
+			valid: false,
+		},
+		{
+			pools: []WorkerPool{{Name: "not a label", ExpectedCount: 1, Nodes: []Node{{Host: "gpu01", IP: "192.168.205.13"}}}},
+			valid: false,
+		},
+		{
+			pools: []WorkerPool{
+				{Name: "gpu", ExpectedCount: 1, Nodes: []Node{{Host: "gpu01", IP: "192.168.205.13"}}},
+				{Name: "gpu", ExpectedCount: 1, Nodes: []Node{{Host: "gpu02", IP: "192.168.205.14"}}},
+			},
+			valid: false,
+		},
+		{
+			worker: validPlan.Worker,
+			pools:  []WorkerPool{{Name: "gpu", ExpectedCount: 1, Nodes: validPlan.Worker.Nodes}},
+			valid:  false,
+		},
+		{
+			pools: []WorkerPool{{Name: "gpu", ExpectedCount: 1, Nodes: []Node{{Host: "gpu01", IP: "192.168.205.13"}}, Labels: map[string]string{"kismatic/foo": "bar"}}},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		p := validPlan
+		p.Worker = test.worker
+		p.WorkerPools = test.pools
+		ok, errs := p.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t: %v", i, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateAuditLog(t *testing.T) {
+	tests := []struct {
+		a     AuditLog
+		valid bool
+	}{
+		{
+			a:     AuditLog{Level: "foo"},
+			valid: true,
+		},
+		{
+			a:     AuditLog{Enabled: true, Level: "Metadata", Sink: "file"},
+			valid: true,
+		},
+		{
+			a:     AuditLog{Enabled: true, Level: "Everything"},
+			valid: false,
+		},
+		{
+			a:     AuditLog{Enabled: true, Sink: "syslog"},
+			valid: false,
+		},
+		{
+			a:     AuditLog{Enabled: true, MaxAge: -1},
+			valid: false,
+		},
+		{
+			a:     AuditLog{Enabled: true, Sink: "webhook", WebhookURL: "https://audit.example.com/events"},
+			valid: true,
+		},
+		{
+			a:     AuditLog{Enabled: true, Sink: "webhook"},
+			valid: false,
+		},
+		{
+			a:     AuditLog{Enabled: true, Sink: "webhook", WebhookURL: "audit.example.com"},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.a.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidateAuthWebhook(t *testing.T) {
+	ca, err := ioutil.TempFile("", "auth-webhook-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.Close()
+	defer os.Remove(ca.Name())
+	tests := []struct {
+		w     AuthWebhook
+		valid bool
+	}{
+		{
+			w:     AuthWebhook{},
+			valid: true,
+		},
+		{
+			w:     AuthWebhook{AuthenticationURL: "https://identity.example.com/authenticate", AuthorizationURL: "https://identity.example.com/authorize", CacheTTL: "2m"},
+			valid: true,
+		},
+		{
+			w:     AuthWebhook{AuthorizationURL: "http://10.0.0.10:8080/authorize"},
+			valid: true,
+		},
+		{
+			w:     AuthWebhook{AuthenticationURL: "identity.example.com"},
+			valid: false,
+		},
+		{
+			w:     AuthWebhook{AuthorizationURL: "ftp://identity.example.com"},
+			valid: false,
+		},
+		{
+			w:     AuthWebhook{AuthenticationURL: "https://identity.example.com/authenticate", CacheTTL: "two minutes"},
+			valid: false,
+		},
+		{
+			w:     AuthWebhook{AuthenticationURL: "http://10.0.0.10:8080/authenticate"},
+			valid: false,
+		},
+		{
+			w:     AuthWebhook{AuthenticationURL: "https://identity.example.com/authenticate", CAPath: ca.Name()},
+			valid: true,
+		},
+		{
+			w:     AuthWebhook{AuthenticationURL: "https://identity.example.com/authenticate", CAPath: ca.Name() + "-missing"},
+			valid: false,
+		},
+		{
+			w:     AuthWebhook{AuthenticationURL: "https://identity.example.com/authenticate", CAPath: "ca.pem"},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.w.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidateClusterExpiration(t *testing.T) {
+	tests := []struct {
+		e     ClusterExpiration
+		valid bool
+	}{
+		{
+			e:     ClusterExpiration{WarnBefore: "foo"},
+			valid: true,
+		},
+		{
+			e:     ClusterExpiration{TTL: "72h", WarnBefore: "24h", WebhookURL: "https://hooks.example.com/clusters"},
+			valid: true,
+		},
+		{
+			e:     ClusterExpiration{ExpiresAt: "2017-10-31T18:00:00Z"},
+			valid: true,
+		},
+		{
+			e:     ClusterExpiration{TTL: "3 days"},
+			valid: false,
+		},
+		{
+			e:     ClusterExpiration{TTL: "-1h"},
+			valid: false,
+		},
+		{
+			e:     ClusterExpiration{ExpiresAt: "2017-10-31"},
+			valid: false,
+		},
+		{
+			e:     ClusterExpiration{TTL: "72h", WarnBefore: "-1h"},
+			valid: false,
+		},
+		{
+			e:     ClusterExpiration{TTL: "72h", WebhookURL: "hooks.example.com"},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.e.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidatePostInstall(t *testing.T) {
+	three, zero, negative := 3, 0, -1
+	tests := []struct {
+		pi    PostInstall
+		valid bool
+	}{
+		{
+			pi:    PostInstall{},
+			valid: true,
+		},
+		{
+			pi:    PostInstall{Manifests: []string{"validate_test.go", "https://example.com/manifests/app.yaml"}, Retries: &three},
+			valid: true,
+		},
+		{
+			pi:    PostInstall{Manifests: []string{"validate_test.go"}, Retries: &zero},
+			valid: true,
+		},
+		{
+			pi:    PostInstall{Manifests: []string{"does-not-exist.yaml"}},
+			valid: false,
+		},
+		{
+			pi:    PostInstall{Manifests: []string{"."}},
+			valid: false,
+		},
+		{
+			pi:    PostInstall{Manifests: []string{"https://"}},
+			valid: false,
+		},
+		{
+			pi:    PostInstall{Manifests: []string{"validate_test.go"}, Retries: &negative},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.pi.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidateNetworkConfigCIDROverlap(t *testing.T) {
+	tests := []struct {
+		pods     string
+		services string
+		valid    bool
+	}{
+		{
+			pods:     "172.16.0.0/16",
+			services: "172.20.0.0/16",
+			valid:    true,
+		},
+		{
+			pods:     "172.16.0.0/16",
+			services: "172.16.128.0/24",
+			valid:    false,
+		},
+		{
+			pods:     "10.0.0.0/24",
+			services: "10.0.0.0/8",
+			valid:    false,
+		},
+		{
+			pods:     "10.0.0.0/24",
+			services: "10.0.1.0/24",
+			valid:    true,
+		},
+	}
+	for i, test := range tests {
+		n := NetworkConfig{PodCIDRBlock: test.pods, ServiceCIDRBlock: test.services}
+		ok, _ := n.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidateNodeNetworkOverlap(t *testing.T) {
+	tests := []struct {
+		pods         string
+		services     string
+		loadBalancer string
+		errs         int
+	}{
+		{
+			pods:         "172.16.0.0/16",
+			services:     "172.20.0.0/16",
+			loadBalancer: "test",
+			errs:         0,
+		},
+		{
+			pods:         "192.168.0.0/16",
+			services:     "172.20.0.0/16",
+			loadBalancer: "test",
+			errs:         3,
+		},
+		{
+			pods:         "172.16.0.0/16",
+			services:     "192.168.205.10/31",
+			loadBalancer: "test",
+			errs:         2,
+		},
+		{
+			pods:         "172.16.0.0/16",
+			services:     "172.20.0.0/16",
+			loadBalancer: "172.20.0.10",
+			errs:         1,
+		},
+		{
+			pods:         "foo",
+			services:     "172.20.0.0/16",
+			loadBalancer: "test",
+			errs:         0,
+		},
+	}
+	for i, test := range tests {
+		// the nodes are not shared with validPlan, which other tests modify
+		p := validPlan
+		p.Etcd.Nodes = []Node{{Host: "etcd01", IP: "192.168.205.10"}}
+		p.Master.Nodes = []Node{{Host: "master01", IP: "192.168.205.11"}}
+		p.Worker.Nodes = []Node{{Host: "worker01", IP: "192.168.205.12"}}
+		p.Ingress.Nodes = []Node{{Host: "etcd01", IP: "192.168.205.10"}}
+		p.Cluster.Networking.PodCIDRBlock = test.pods
+		p.Cluster.Networking.ServiceCIDRBlock = test.services
+		p.Master.LoadBalancedFQDN = test.loadBalancer
+		if errs := p.validateNodeNetworkOverlap(); len(errs) != test.errs {
+			t.Errorf("test %d: expected %d errors, but got %v", i, test.errs, errs)
+		}
+	}
+}
+
+func TestExternalDNSAddOn(t *testing.T) {
+	tests := []struct {
+		e     ExternalDNS
+		valid bool
+	}{
+		{
+			e:     ExternalDNS{},
+			valid: true,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "aws", DomainFilters: []string{"example.com"}},
+			valid: true,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "google", GoogleProject: "acme", DomainFilters: []string{"example.com"}, IngressWildcard: "*.apps.example.com"},
+			valid: true,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "google", DomainFilters: []string{"example.com"}},
+			valid: false,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "foo", DomainFilters: []string{"example.com"}},
+			valid: false,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "aws"},
+			valid: false,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "aws", DomainFilters: []string{"."}},
+			valid: false,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "aws", DomainFilters: []string{"example.com"}, IngressWildcard: "apps.example.com"},
+			valid: false,
+		},
+		{
+			e:     ExternalDNS{Enabled: true, Provider: "aws", DomainFilters: []string{"example.com"}, IngressWildcard: "*.apps.example.org"},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.e.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidateExternalDNSRecords(t *testing.T) {
+	tests := []struct {
+		e         ExternalDNS
+		noIngress bool
+		valid     bool
+	}{
+		{
+			e:     ExternalDNS{Enabled: true, DomainFilters: []string{"example.com"}, IngressWildcard: "*.apps.example.com"},
+			valid: true,
+		},
+		{
+			e:         ExternalDNS{Enabled: true, DomainFilters: []string{"example.com"}, IngressWildcard: "*.apps.example.com"},
+			noIngress: true,
+			valid:     false,
+		},
+		{
+			e:         ExternalDNS{IngressWildcard: "*.apps.example.com"},
+			noIngress: true,
+			valid:     true,
+		},
+	}
+	for i, test := range tests {
+		p := validPlan
+		p.AddOns.ExternalDNS = test.e
+		if test.noIngress {
+			p.Ingress = OptionalNodeGroup{}
+		}
+		errs := p.validateExternalDNS()
+		if (len(errs) == 0) != test.valid {
+			t.Errorf("test %d: expect valid %t, but got %v", i, test.valid, errs)
+		}
+	}
+}
+
+func TestValidateSecretsStore(t *testing.T) {
+	tests := []struct {
+		store ClusterSecretsStore
+		valid bool
+	}{
+		{
+			store: ClusterSecretsStore{},
+			valid: true,
+		},
+		{
+			store: ClusterSecretsStore{Provider: "vault", Path: "secret/kismatic/prod"},
+			valid: true,
+		},
+		{
+			store: ClusterSecretsStore{Provider: "aws-secrets-manager", Path: "kismatic/prod"},
+			valid: true,
+		},
+		{
+			store: ClusterSecretsStore{Path: "secret/kismatic/prod"},
+			valid: false,
+		},
+		{
+			store: ClusterSecretsStore{Provider: "keychain", Path: "kismatic/prod"},
+			valid: false,
+		},
+		{
+			store: ClusterSecretsStore{Provider: "vault"},
+			valid: false,
+		},
+		{
+			store: ClusterSecretsStore{Provider: "vault", Path: "secret/kismatic/prod/"},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.store.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expected %v, but got %v", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidateClusterEvents(t *testing.T) {
+	tests := []struct {
+		events ClusterEvents
+		valid  bool
+	}{
+		{
+			events: ClusterEvents{},
+			valid:  true,
+		},
+		{
+			events: ClusterEvents{Publisher: "nats", Servers: []string{"nats.example.com:4222"}, Topic: "kismatic.events"},
+			valid:  true,
+		},
+		{
+			events: ClusterEvents{Publisher: "kafka", Servers: []string{"10.0.0.1:9092", "10.0.0.2:9092"}, Topic: "kismatic-events"},
+			valid:  true,
+		},
+		{
+			events: ClusterEvents{Servers: []string{"nats.example.com:4222"}},
+			valid:  false,
+		},
+		{
+			events: ClusterEvents{Publisher: "rabbitmq", Servers: []string{"rabbit.example.com:5672"}},
+			valid:  false,
+		},
+		{
+			events: ClusterEvents{Publisher: "nats"},
+			valid:  false,
+		},
+		{
+			events: ClusterEvents{Publisher: "kafka", Servers: []string{"kafka.example.com"}},
+			valid:  false,
+		},
+		{
+			events: ClusterEvents{Publisher: "nats", Servers: []string{"nats.example.com:4222"}, Topic: "kismatic events"},
+			valid:  false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.events.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expected %v, but got %v", i, test.valid, ok)
+		}
+	}
+}
+
+func TestValidatePlanWarnings(t *testing.T) {
+	nodes := func(prefix string, n int) []Node {
+		var nodes []Node
+		for i := 0; i < n; i++ {
+			nodes = append(nodes, Node{Host: fmt.Sprintf("%s%02d", prefix, i), IP: fmt.Sprintf("10.0.0.%d", i)})
+		}
+		return nodes
+	}
+	tests := []struct {
+		etcd, master, worker int
+		allowSingleNode      bool
+		warnings             int
+	}{
+		{etcd: 3, master: 2, worker: 3, warnings: 0},
+		{etcd: 5, master: 3, worker: 2, warnings: 0},
+		{etcd: 1, master: 2, worker: 3, warnings: 1},
+		{etcd: 2, master: 2, worker: 3, warnings: 1},
+		{etcd: 4, master: 2, worker: 3, warnings: 1},
+		{etcd: 3, master: 1, worker: 3, warnings: 1},
+		{etcd: 3, master: 2, worker: 1, warnings: 1},
+		{etcd: 1, master: 1, worker: 1, warnings: 3},
+		{etcd: 1, master: 1, worker: 1, allowSingleNode: true, warnings: 0},
+	}
+	for i, test := range tests {
+		p := Plan{
+			Cluster: Cluster{AllowSingleNode: test.allowSingleNode},
+			Etcd:    NodeGroup{Nodes: nodes("etcd", test.etcd)},
+			Master:  MasterNodeGroup{Nodes: nodes("master", test.master)},
+			Worker:  NodeGroup{Nodes: nodes("worker", test.worker)},
+		}
+		warnings := ValidatePlanWarnings(&p)
+		if len(warnings) != test.warnings {
+			t.Errorf("test %d: expected %d warnings, but got %v", i, test.warnings, warnings)
+		}
+	}
+}
+
+func TestValidatePlanWarningsDefaultPolicies(t *testing.T) {
+	tests := []struct {
+		cni      *CNI
+		warnings int
+	}{
+		{cni: &CNI{Provider: cniProviderCalico}, warnings: 0},
+		{cni: &CNI{Provider: cniProviderWeave}, warnings: 0},
+		{cni: &CNI{Provider: cniProviderContiv}, warnings: 1},
+		{cni: &CNI{Provider: cniProviderCustom}, warnings: 1},
+		{cni: &CNI{Provider: cniProviderCalico, Disable: true}, warnings: 1},
+		{cni: nil, warnings: 1},
+	}
+	for i, test := range tests {
+		p := Plan{
+			Cluster: Cluster{AllowSingleNode: true, DefaultPolicies: DefaultPolicies{Enabled: true}},
+			AddOns:  AddOns{CNI: test.cni},
+		}
+		warnings := ValidatePlanWarnings(&p)
+		if len(warnings) != test.warnings {
+			t.Errorf("test %d: expected %d warnings, but got %v", i, test.warnings, warnings)
+		}
+	}
+}
+
+func TestValidateDefaultPolicies(t *testing.T) {
+	tests := []struct {
+		name  string
+		d     DefaultPolicies
+		valid bool
+	}{
+		{
+			name:  "disabled",
+			d:     DefaultPolicies{DenyIngressNamespaces: []string{"kube-system"}},
+			valid: true,
+		},
+		{
+			name:  "selected namespaces",
+			d:     DefaultPolicies{Enabled: true, DenyIngressNamespaces: []string{"default", "team-a"}},
+			valid: true,
+		},
+		{
+			name: "kube-system",
+			d:    DefaultPolicies{Enabled: true, DenyIngressNamespaces: []string{"default", "kube-system"}},
+		},
+		{
+			name: "invalid namespace",
+			d:    DefaultPolicies{Enabled: true, DenyIngressNamespaces: []string{"Team_A"}},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.d.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidatePodCIDRCapacity(t *testing.T) {
+	workers := func(n int) []Node {
+		var nodes []Node
+		for i := 0; i < n; i++ {
+			nodes = append(nodes, Node{Host: fmt.Sprintf("worker%03d", i), IP: fmt.Sprintf("10.0.%d.%d", i/250, i%250)})
+		}
+		return nodes
+	}
+	tests := []struct {
+		podCIDR    string
+		maskSize   string
+		workers    int
+		autoscaled int
+		valid      bool
+		warnings   int
+	}{
+		{podCIDR: "172.16.0.0/16", workers: 100, valid: true},
+		{podCIDR: "172.16.0.0/22", workers: 2, valid: true, warnings: 1},
+		{podCIDR: "172.16.0.0/22", workers: 4, valid: false},
+		{podCIDR: "172.16.0.0/21", workers: 3, valid: true},
+		{podCIDR: "172.16.0.0/21", workers: 6, valid: true, warnings: 1},
+		{podCIDR: "172.16.0.0/21", workers: 3, autoscaled: 5, valid: false},
+		{podCIDR: "172.16.0.0/25", workers: 1, valid: false},
+		{podCIDR: "172.16.0.0/22", maskSize: "26", workers: 14, valid: true, warnings: 1},
+		{podCIDR: "172.16.0.0/22", maskSize: "26", workers: 16, valid: false},
+		{podCIDR: "172.16.0.0/16", maskSize: "foo", workers: 1, valid: false},
+		{podCIDR: "fd00::/120", workers: 100, valid: true},
+	}
+	for i, test := range tests {
+		p := Plan{
+			Master: MasterNodeGroup{Nodes: []Node{{Host: "master01", IP: "10.1.0.1"}}},
+			Worker: NodeGroup{Nodes: workers(test.workers)},
+		}
+		p.Cluster.Networking.PodCIDRBlock = test.podCIDR
+		if test.maskSize != "" {
+			p.Cluster.KubeControllerManagerOptions.Overrides = map[string]string{"node-cidr-mask-size": test.maskSize}
+		}
+		if test.autoscaled > 0 {
+			p.AddOns.ClusterAutoscaler = ClusterAutoscaler{Enabled: true, NodeGroups: []AutoscalerNodeGroup{{Name: "workers", MinSize: 1, MaxSize: test.autoscaled}}}
+		}
+		errs := p.validatePodCIDRCapacity()
+		if valid := len(errs) == 0; valid != test.valid {
+			t.Errorf("test %d: expected valid to be %v, but got %v", i, test.valid, errs)
+		}
+		if !test.valid {
+			continue
+		}
+		// the single master and worker warnings are not counted
+		var warnings int
+		for _, w := range ValidatePlanWarnings(&p) {
+			if strings.HasPrefix(w.Error(), "Pod CIDR") {
+				warnings++
+			}
+		}
+		if warnings != test.warnings {
+			t.Errorf("test %d: expected %d pod CIDR warnings, but got %d", i, test.warnings, warnings)
+		}
+	}
+}
+
+func TestValidateClusterName(t *testing.T) {
+	tests := []struct {
+		name        string
+		valid       bool
+		expectedUse string
+	}{
+		{name: "kubernetes", valid: true},
+		{name: "prod-us-east-1", valid: true},
+		{name: "1cluster", valid: true},
+		{name: "Prod", valid: false, expectedUse: "prod"},
+		{name: " dev ", valid: false, expectedUse: "dev"},
+		{name: "my_cluster", valid: false},
+		{name: "my.cluster", valid: false},
+		{name: "-dev", valid: false},
+		{name: strings.Repeat("a", 64), valid: false},
+		{name: "default", valid: false},
+		{name: "kube-system", valid: false},
+	}
+	for _, test := range tests {
+		errs := validateClusterName(test.name)
+		if valid := len(errs) == 0; valid != test.valid {
+			t.Errorf("%q: expected valid to be %v, but got %v", test.name, test.valid, errs)
+			continue
+		}
+		if test.expectedUse != "" && !strings.Contains(errs[0].Error(), fmt.Sprintf("Use %q", test.expectedUse)) {
+			t.Errorf("%q: expected the error to suggest %q, but got %v", test.name, test.expectedUse, errs[0])
+		}
+	}
+}
+
+func TestValidateExistingPlanClusterName(t *testing.T) {
+	p := validPlan
+	p.Cluster.Name = "Prod"
+	if valid, _ := ValidatePlan(&p); valid {
+		t.Errorf("expected the plan of a new cluster to be invalid, but it was valid")
+	}
+	if valid, errs := ValidateExistingPlan(&p); !valid {
+		t.Errorf("expected the plan of an existing cluster to be valid, but got %v", errs)
+	}
+	var found bool
+	for _, w := range ValidateExistingPlanWarnings(&p) {
+		if strings.HasPrefix(w.Error(), `Cluster name "Prod" is not valid`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a warning about the cluster name of an existing cluster")
+	}
+}
+
+func TestValidateRebootCoordinator(t *testing.T) {
+	tests := []struct {
+		name  string
+		r     RebootCoordinator
+		valid bool
+	}{
+		{
+			name:  "disabled",
+			r:     RebootCoordinator{SentinelFile: "relative"},
+			valid: true,
+		},
+		{
+			name:  "defaults",
+			r:     RebootCoordinator{Enabled: true},
+			valid: true,
+		},
+		{
+			name:  "all options",
+			r:     RebootCoordinator{Enabled: true, SentinelFile: "/var/run/reboot-required", Period: "30m", BlockingPodSelectors: []string{"app=batch-job"}},
+			valid: true,
+		},
+		{
+			name: "relative sentinel file",
+			r:    RebootCoordinator{Enabled: true, SentinelFile: "reboot-required"},
+		},
+		{
+			name: "invalid period",
+			r:    RebootCoordinator{Enabled: true, Period: "hourly"},
+		},
+		{
+			name: "negative period",
+			r:    RebootCoordinator{Enabled: true, Period: "-1h"},
+		},
+		{
+			name: "empty blocking pod selector",
+			r:    RebootCoordinator{Enabled: true, BlockingPodSelectors: []string{" "}},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.r.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateDashboardIngress(t *testing.T) {
+	oidc := DashboardOIDC{
+		IssuerURL:    "https://accounts.google.com",
+		ClientID:     "kismatic",
+		ClientSecret: "secret",
+		CookieSecret: "0123456789abcdef",
+		EmailDomain:  "example.com",
+	}
+	tests := []struct {
+		name  string
+		i     DashboardIngress
+		valid bool
+	}{
+		{
+			name:  "disabled",
+			i:     DashboardIngress{Auth: "basic"},
+			valid: true,
+		},
+		{
+			name:  "client certificate auth",
+			i:     DashboardIngress{Enabled: true, Host: "dashboard.example.com"},
+			valid: true,
+		},
+		{
+			name:  "oidc auth",
+			i:     DashboardIngress{Enabled: true, Host: "dashboard.example.com", Auth: "oidc", OIDC: oidc},
+			valid: true,
+		},
+		{
+			name: "missing host",
+			i:    DashboardIngress{Enabled: true},
+		},
+		{
+			name: "wildcard host",
+			i:    DashboardIngress{Enabled: true, Host: "*.example.com"},
+		},
+		{
+			name: "invalid auth",
+			i:    DashboardIngress{Enabled: true, Host: "dashboard.example.com", Auth: "basic"},
+		},
+		{
+			name: "oidc without provider",
+			i:    DashboardIngress{Enabled: true, Host: "dashboard.example.com", Auth: "oidc"},
+		},
+		{
+			name: "oidc issuer over http",
+			i: DashboardIngress{Enabled: true, Host: "dashboard.example.com", Auth: "oidc", OIDC: DashboardOIDC{
+				IssuerURL: "http://accounts.google.com", ClientID: "kismatic", ClientSecret: "secret", CookieSecret: "0123456789abcdef", EmailDomain: "example.com",
+			}},
+		},
+		{
+			name: "oidc short cookie secret",
+			i: DashboardIngress{Enabled: true, Host: "dashboard.example.com", Auth: "oidc", OIDC: DashboardOIDC{
+				IssuerURL: "https://accounts.google.com", ClientID: "kismatic", ClientSecret: "secret", CookieSecret: "short", EmailDomain: "example.com",
+			}},
+		},
+		{
+			name: "oidc without email domain",
+			i: DashboardIngress{Enabled: true, Host: "dashboard.example.com", Auth: "oidc", OIDC: DashboardOIDC{
+				IssuerURL: "https://accounts.google.com", ClientID: "kismatic", ClientSecret: "secret", CookieSecret: "0123456789abcdef",
+			}},
+		},
+	}
+	for _, test := range tests {
+		d := &Dashboard{Ingress: test.i}
+		if ok, errs := d.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateRegistryCredentials(t *testing.T) {
+	tests := []struct {
+		name  string
+		d     Docker
+		valid bool
+	}{
+		{
+			name:  "no credentials",
+			valid: true,
+		},
+		{
+			name: "credentials",
+			d: Docker{
+				RegistryCredentials:  []RegistryCredential{{Server: "registry.example.com:5000", Username: "alice", Password: "secret"}},
+				PullSecretNamespaces: []string{"default"},
+			},
+			valid: true,
+		},
+		{
+			name: "server with scheme",
+			d:    Docker{RegistryCredentials: []RegistryCredential{{Server: "https://registry.example.com", Username: "alice", Password: "secret"}}},
+		},
+		{
+			name: "missing password",
+			d:    Docker{RegistryCredentials: []RegistryCredential{{Server: "registry.example.com", Username: "alice"}}},
+		},
+		{
+			name: "duplicated server",
+			d: Docker{RegistryCredentials: []RegistryCredential{
+				{Server: "quay.io", Username: "alice", Password: "secret"},
+				{Server: "quay.io", Username: "bob", Password: "secret"},
+			}},
+		},
+		{
+			name: "invalid namespace",
+			d: Docker{
+				RegistryCredentials:  []RegistryCredential{{Server: "quay.io", Username: "alice", Password: "secret"}},
+				PullSecretNamespaces: []string{"Team A"},
+			},
+		},
+		{
+			name: "registry mirrors and insecure registries",
+			d: Docker{
+				RegistryMirrors:    []string{"https://mirror.example.com:5000", "http://10.0.0.5"},
+				InsecureRegistries: []string{"registry.example.com:5000", "10.0.0.0/8"},
+			},
+			valid: true,
+		},
+		{
+			name: "registry mirror without scheme",
+			d:    Docker{RegistryMirrors: []string{"mirror.example.com:5000"}},
+		},
+		{
+			name: "insecure registry with scheme",
+			d:    Docker{InsecureRegistries: []string{"http://registry.example.com"}},
+		},
+	}
+	for _, test := range tests {
+		if ok, errs := test.d.validate(); ok != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, ok, errs)
 		}
 	}
 }
@@ -0,0 +1,360 @@
+package install
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// The maximum number of minor versions that the kubelet and kube-proxy can
+// be older than the API server
+const maxKubeletMinorVersionSkew = 2
+
+// componentVersions are the versions of the components of a cluster. The
+// version of a component that is not installed is empty.
+type componentVersions struct {
+	Kubernetes    string
+	Etcd          string
+	Docker        string
+	Calico        string
+	Weave         string
+	Contiv        string
+	KubeDNS       string
+	CoreDNS       string
+	Dashboard     string
+	Helm          string
+	MetricsServer string
+}
+
+// installedVersions are the versions of the components that are installed by
+// this version of Kismatic. They must match the versions that are set in the
+// ansible group variables.
+var installedVersions = componentVersions{
+	Kubernetes:    "1.8.4",
+	Etcd:          "3.1.10",
+	Docker:        "1.12.6",
+	Calico:        "2.6.2",
+	Weave:         "2.0.5",
+	Contiv:        "1.1.1",
+	KubeDNS:       "1.14.5",
+	CoreDNS:       "1.0.6",
+	Dashboard:     "1.6.3",
+	Helm:          "2.8.2",
+	MetricsServer: "0.2.1",
+}
+
+// InstalledVersions returns the versions of the components that are installed
+// by this version of Kismatic, keyed by the name of the component
+func InstalledVersions() map[string]string {
+	v := installedVersions
+	return map[string]string{
+		"kubernetes":     v.Kubernetes,
+		"etcd":           v.Etcd,
+		"docker":         v.Docker,
+		"calico":         v.Calico,
+		"weave":          v.Weave,
+		"contiv":         v.Contiv,
+		"kube_dns":       v.KubeDNS,
+		"coredns":        v.CoreDNS,
+		"dashboard":      v.Dashboard,
+		"helm":           v.Helm,
+		"metrics_server": v.MetricsServer,
+	}
+}
+
+// compatibleVersions are the minor versions of the components that are
+// compatible with a minor version of Kubernetes
+type compatibleVersions struct {
+	Etcd          []string
+	Docker        []string
+	Calico        []string
+	Weave         []string
+	Contiv        []string
+	KubeDNS       []string
+	CoreDNS       []string
+	Dashboard     []string
+	Helm          []string
+	MetricsServer []string
+}
+
+// versionCompatibility is the compatibility matrix of the components, keyed
+// by the minor version of Kubernetes
+var versionCompatibility = map[string]compatibleVersions{
+	"1.7": {
+		Etcd:          []string{"3.0", "3.1"},
+		Docker:        []string{"1.10", "1.11", "1.12"},
+		Calico:        []string{"2.4", "2.5", "2.6"},
+		Weave:         []string{"2.0"},
+		Contiv:        []string{"1.0", "1.1"},
+		KubeDNS:       []string{"1.14"},
+		CoreDNS:       []string{"0.9", "1.0"},
+		Dashboard:     []string{"1.6"},
+		Helm:          []string{"2.5", "2.6", "2.7", "2.8"},
+		MetricsServer: []string{"0.1"},
+	},
+	"1.8": {
+		Etcd:          []string{"3.0", "3.1"},
+		Docker:        []string{"1.11", "1.12", "1.13", "17.03"},
+		Calico:        []string{"2.6"},
+		Weave:         []string{"2.0"},
+		Contiv:        []string{"1.1"},
+		KubeDNS:       []string{"1.14"},
+		CoreDNS:       []string{"1.0"},
+		Dashboard:     []string{"1.6", "1.7", "1.8"},
+		Helm:          []string{"2.7", "2.8"},
+		MetricsServer: []string{"0.2"},
+	},
+}
+
+// parseMajorMinor returns the major and minor versions of a version string such
+// as "v1.8.4", "1.12.6-1.el7.centos" or "docker://17.03.2-ce"
+func parseMajorMinor(s string) (int, int, error) {
+	v := s
+	if i := strings.Index(v, "://"); i >= 0 {
+		v = v[i+3:]
+	}
+	v = strings.TrimPrefix(v, "v")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid version %q", s)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid version %q", s)
+	}
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid version %q", s)
+	}
+	return major, minor, nil
+}
+
+// minorVersion returns the "major.minor" version of a version string. The
+// minor version is zero padded to two digits for the calendar versions of
+// docker, such as 17.03.
+func minorVersion(s string) (string, error) {
+	major, minor, err := parseMajorMinor(s)
+	if err != nil {
+		return "", err
+	}
+	if major >= 17 {
+		return fmt.Sprintf("%d.%02d", major, minor), nil
+	}
+	return fmt.Sprintf("%d.%d", major, minor), nil
+}
+
+type componentCheck struct {
+	component  string
+	version    string
+	compatible []string
+}
+
+// checkCompatibility returns an error if the version of the component is not
+// one of the compatible minor versions
+func checkCompatibility(component, version string, compatible []string, kubernetesVersion string) error {
+	minor, err := minorVersion(version)
+	if err != nil {
+		return fmt.Errorf("%s: %v", component, err)
+	}
+	if !util.Contains(minor, compatible) {
+		return fmt.Errorf("%s v%s is not compatible with Kubernetes v%s, use one of the compatible versions %s.x", component, strings.TrimPrefix(version, "v"), kubernetesVersion, strings.Join(compatible, ".x, "))
+	}
+	return nil
+}
+
+// checkVersionCompatibility returns the components of the given versions
+// that are not compatible with their version of Kubernetes. The components
+// that are not installed are not checked.
+func checkVersionCompatibility(v componentVersions) []error {
+	minor, err := minorVersion(v.Kubernetes)
+	if err != nil {
+		return []error{err}
+	}
+	compatible, ok := versionCompatibility[minor]
+	if !ok {
+		return []error{fmt.Errorf("Kubernetes v%s is not in the version compatibility matrix", v.Kubernetes)}
+	}
+	checks := []componentCheck{
+		{component: "etcd", version: v.Etcd, compatible: compatible.Etcd},
+		{component: "Docker", version: v.Docker, compatible: compatible.Docker},
+		{component: "Calico", version: v.Calico, compatible: compatible.Calico},
+		{component: "Weave", version: v.Weave, compatible: compatible.Weave},
+		{component: "Contiv", version: v.Contiv, compatible: compatible.Contiv},
+		{component: "KubeDNS", version: v.KubeDNS, compatible: compatible.KubeDNS},
+		{component: "CoreDNS", version: v.CoreDNS, compatible: compatible.CoreDNS},
+		{component: "Kubernetes Dashboard", version: v.Dashboard, compatible: compatible.Dashboard},
+		{component: "Helm", version: v.Helm, compatible: compatible.Helm},
+		{component: "Metrics Server", version: v.MetricsServer, compatible: compatible.MetricsServer},
+	}
+	var errs []error
+	for _, c := range checks {
+		if c.version == "" {
+			continue
+		}
+		if err := checkCompatibility(c.component, c.version, c.compatible, v.Kubernetes); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// AddOnVersionSkew returns an error for each of the given add-ons of this
+// version of Kismatic that is not compatible with the given version of
+// Kubernetes. All the add-ons are checked when none is given.
+func AddOnVersionSkew(plan Plan, kubernetesVersion string, addOns []string) []error {
+	if len(addOns) > 0 && !util.Contains(addOnCNI, addOns) {
+		return nil
+	}
+	if plan.AddOns.CNI == nil || plan.AddOns.CNI.Disable {
+		return nil
+	}
+	// Providers that are not installed by Kismatic, such as custom, are not checked
+	switch plan.AddOns.CNI.Provider {
+	case cniProviderCalico, cniProviderWeave, cniProviderContiv:
+	default:
+		return nil
+	}
+	kubernetesVersion = strings.TrimPrefix(kubernetesVersion, "v")
+	minor, err := minorVersion(kubernetesVersion)
+	if err != nil {
+		return []error{err}
+	}
+	compatible, ok := versionCompatibility[minor]
+	if !ok {
+		return []error{fmt.Errorf("Kubernetes v%s is not in the version compatibility matrix", kubernetesVersion)}
+	}
+	var check componentCheck
+	switch plan.AddOns.CNI.Provider {
+	case cniProviderCalico:
+		check = componentCheck{component: "Calico", version: installedVersions.Calico, compatible: compatible.Calico}
+	case cniProviderWeave:
+		check = componentCheck{component: "Weave", version: installedVersions.Weave, compatible: compatible.Weave}
+	case cniProviderContiv:
+		check = componentCheck{component: "Contiv", version: installedVersions.Contiv, compatible: compatible.Contiv}
+	}
+	if err := checkCompatibility(check.component, check.version, check.compatible, kubernetesVersion); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// plannedVersions returns the versions of the components and add-ons that
+// are installed on the cluster of the plan. Docker is only included when it
+// is installed by Kismatic.
+func (p Plan) plannedVersions() componentVersions {
+	v := componentVersions{
+		Kubernetes: installedVersions.Kubernetes,
+		Etcd:       installedVersions.Etcd,
+	}
+	if p.packageInstallationEnabled() {
+		v.Docker = installedVersions.Docker
+	}
+	if p.AddOns.CNI != nil && !p.AddOns.CNI.Disable {
+		switch p.AddOns.CNI.Provider {
+		case cniProviderCalico:
+			v.Calico = installedVersions.Calico
+		case cniProviderWeave:
+			v.Weave = installedVersions.Weave
+		case cniProviderContiv:
+			v.Contiv = installedVersions.Contiv
+		}
+	}
+	if !p.AddOns.DNS.Disable {
+		switch p.AddOns.DNS.Provider {
+		case "", dnsProviderKubeDNS:
+			v.KubeDNS = installedVersions.KubeDNS
+		case dnsProviderCoreDNS:
+			v.CoreDNS = installedVersions.CoreDNS
+		}
+	}
+	if p.AddOns.Dashboard == nil || !p.AddOns.Dashboard.Disable {
+		v.Dashboard = installedVersions.Dashboard
+	}
+	if !p.AddOns.PackageManager.Disable {
+		v.Helm = installedVersions.Helm
+	}
+	if p.AddOns.MetricsServer.Enabled {
+		v.MetricsServer = installedVersions.MetricsServer
+	}
+	return v
+}
+
+// validateVersionSkew validates that the versions of the components and
+// add-ons that are installed on the cluster of the plan are compatible with
+// each other
+func (p *Plan) validateVersionSkew() []error {
+	var errs []error
+	for _, err := range checkVersionCompatibility(p.plannedVersions()) {
+		errs = append(errs, fmt.Errorf("Version skew: %v", err))
+	}
+	return errs
+}
+
+// kubeletVersionSkew returns an error if the kubelet of the given version
+// cannot run against an API server of the given version
+func kubeletVersionSkew(kubeletVersion, apiServerVersion string) error {
+	kMajor, kMinor, err := parseMajorMinor(kubeletVersion)
+	if err != nil {
+		return fmt.Errorf("kubelet: %v", err)
+	}
+	aMajor, aMinor, err := parseMajorMinor(apiServerVersion)
+	if err != nil {
+		return fmt.Errorf("API server: %v", err)
+	}
+	switch {
+	case kMajor > aMajor || (kMajor == aMajor && kMinor > aMinor):
+		return fmt.Errorf("kubelet %s is newer than Kubernetes v%s, downgrading the cluster is not supported", kubeletVersion, apiServerVersion)
+	case kMajor < aMajor || aMinor-kMinor > maxKubeletMinorVersionSkew:
+		return fmt.Errorf("kubelet %s is more than %d minor versions older than Kubernetes v%s, upgrade the cluster with an intermediate version of Kismatic first", kubeletVersion, maxKubeletMinorVersionSkew, apiServerVersion)
+	}
+	return nil
+}
+
+// checkVersionSkew checks that the versions of the components that are
+// running on the nodes can run against the target version of the control
+// plane while the cluster is upgraded. Docker is only checked when it is not
+// upgraded by Kismatic.
+func checkVersionSkew(r *UpgradeReadinessReport, plan Plan, nodes *data.NodeList) {
+	for _, err := range plan.validateVersionSkew() {
+		r.add("Version skew", "plan", err)
+	}
+	if nodes == nil {
+		return
+	}
+	target := installedVersions.Kubernetes
+	var compatibleDocker []string
+	if minor, err := minorVersion(target); err == nil {
+		compatibleDocker = versionCompatibility[minor].Docker
+	}
+	for _, n := range nodes.Items {
+		host := n.Labels["kismatic/host"]
+		if host == "" {
+			host = n.Name
+		}
+		info := n.Status.NodeInfo
+		var errs []string
+		if info.KubeletVersion != "" {
+			if err := kubeletVersionSkew(info.KubeletVersion, target); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if info.KubeProxyVersion != "" {
+			if err := kubeletVersionSkew(info.KubeProxyVersion, target); err != nil {
+				errs = append(errs, strings.Replace(err.Error(), "kubelet", "kube-proxy", 1))
+			}
+		}
+		if !plan.packageInstallationEnabled() && strings.HasPrefix(info.ContainerRuntimeVersion, "docker://") {
+			if err := checkCompatibility("Docker", strings.TrimPrefix(info.ContainerRuntimeVersion, "docker://"), compatibleDocker, target); err != nil {
+				errs = append(errs, fmt.Sprintf("%v, as package installation is disabled it must be upgraded on the node before the cluster is upgraded", err))
+			}
+		}
+		var err error
+		if len(errs) > 0 {
+			err = fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		r.add("Version skew", host, err)
+	}
+}
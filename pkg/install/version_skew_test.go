@@ -0,0 +1,252 @@
+package install
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMinorVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+		valid    bool
+	}{
+		{version: "v1.8.4", expected: "1.8", valid: true},
+		{version: "1.12.6-1.el7.centos", expected: "1.12", valid: true},
+		{version: "docker://17.3.2", expected: "17.03", valid: true},
+		{version: "docker://17.03.2-ce", expected: "17.03", valid: true},
+		{version: "3.1", expected: "3.1", valid: true},
+		{version: "1", valid: false},
+		{version: "latest", valid: false},
+	}
+	for _, test := range tests {
+		minor, err := minorVersion(test.version)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: expected valid to be %v, but got error %v", test.version, test.valid, err)
+			continue
+		}
+		if minor != test.expected {
+			t.Errorf("%q: expected %q, but got %q", test.version, test.expected, minor)
+		}
+	}
+}
+
+func TestKubeletVersionSkew(t *testing.T) {
+	tests := []struct {
+		kubelet string
+		valid   bool
+	}{
+		{kubelet: "v1.8.4", valid: true},
+		{kubelet: "v1.8.0", valid: true},
+		{kubelet: "v1.7.11", valid: true},
+		{kubelet: "v1.6.13", valid: true},
+		{kubelet: "v1.5.7", valid: false},
+		{kubelet: "v1.9.0", valid: false},
+		{kubelet: "v2.0.0", valid: false},
+		{kubelet: "unknown", valid: false},
+	}
+	for _, test := range tests {
+		err := kubeletVersionSkew(test.kubelet, "1.8.4")
+		if (err == nil) != test.valid {
+			t.Errorf("kubelet %s: expected valid to be %v, but got error %v", test.kubelet, test.valid, err)
+		}
+	}
+}
+
+func TestCheckVersionCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions func(componentVersions) componentVersions
+		errors   int
+	}{
+		{
+			name: "installed versions",
+		},
+		{
+			name: "etcd too old",
+			versions: func(v componentVersions) componentVersions {
+				v.Etcd = "2.3.7"
+				return v
+			},
+			errors: 1,
+		},
+		{
+			name: "docker too new",
+			versions: func(v componentVersions) componentVersions {
+				v.Docker = "17.09.0"
+				return v
+			},
+			errors: 1,
+		},
+		{
+			name: "calico not compatible",
+			versions: func(v componentVersions) componentVersions {
+				v.Calico = "2.4.1"
+				return v
+			},
+			errors: 1,
+		},
+		{
+			name: "calico not installed",
+			versions: func(v componentVersions) componentVersions {
+				v.Calico = ""
+				return v
+			},
+		},
+		{
+			name: "metrics server not compatible",
+			versions: func(v componentVersions) componentVersions {
+				v.MetricsServer = "0.1.0"
+				return v
+			},
+			errors: 1,
+		},
+		{
+			name: "kubernetes not in matrix",
+			versions: func(v componentVersions) componentVersions {
+				v.Kubernetes = "1.4.12"
+				return v
+			},
+			errors: 1,
+		},
+	}
+	for _, test := range tests {
+		v := installedVersions
+		if test.versions != nil {
+			v = test.versions(v)
+		}
+		errs := checkVersionCompatibility(v)
+		if len(errs) != test.errors {
+			t.Errorf("%s: expected %d errors, but got %v", test.name, test.errors, errs)
+		}
+	}
+}
+
+func TestPlannedVersions(t *testing.T) {
+	p := Plan{}
+	p.AddOns.CNI = &CNI{Provider: cniProviderWeave}
+	p.AddOns.DNS.Provider = dnsProviderCoreDNS
+	p.AddOns.Dashboard = &Dashboard{Disable: true}
+	p.AddOns.PackageManager.Disable = true
+	p.AddOns.MetricsServer.Enabled = true
+	p.Cluster.DisablePackageInstallation = true
+	expected := componentVersions{
+		Kubernetes:    installedVersions.Kubernetes,
+		Etcd:          installedVersions.Etcd,
+		Weave:         installedVersions.Weave,
+		CoreDNS:       installedVersions.CoreDNS,
+		MetricsServer: installedVersions.MetricsServer,
+	}
+	if v := p.plannedVersions(); v != expected {
+		t.Errorf("expected versions %+v, but got %+v", expected, v)
+	}
+}
+
+func TestAddOnVersionSkew(t *testing.T) {
+	tests := []struct {
+		name              string
+		cni               *CNI
+		kubernetesVersion string
+		addOns            []string
+		errors            int
+	}{
+		{
+			name:              "same version",
+			cni:               &CNI{Provider: cniProviderCalico},
+			kubernetesVersion: "v1.8.4",
+		},
+		{
+			name:              "calico compatible with older kubernetes",
+			cni:               &CNI{Provider: cniProviderCalico},
+			kubernetesVersion: "v1.7.11",
+		},
+		{
+			name:              "contiv compatible with older kubernetes",
+			cni:               &CNI{Provider: cniProviderContiv},
+			kubernetesVersion: "v1.7.11",
+		},
+		{
+			name:              "kubernetes not in matrix",
+			cni:               &CNI{Provider: cniProviderWeave},
+			kubernetesVersion: "v1.5.7",
+			errors:            1,
+		},
+		{
+			name:              "cni not upgraded",
+			cni:               &CNI{Provider: cniProviderWeave},
+			kubernetesVersion: "v1.5.7",
+			addOns:            []string{addOnDNS},
+		},
+		{
+			name:              "cni disabled",
+			cni:               &CNI{Disable: true},
+			kubernetesVersion: "v1.5.7",
+		},
+		{
+			name:              "custom cni",
+			cni:               &CNI{Provider: cniProviderCustom},
+			kubernetesVersion: "v1.5.7",
+		},
+	}
+	for _, test := range tests {
+		p := Plan{}
+		p.AddOns.CNI = test.cni
+		errs := AddOnVersionSkew(p, test.kubernetesVersion, test.addOns)
+		if len(errs) != test.errors {
+			t.Errorf("%s: expected %d errors, but got %v", test.name, test.errors, errs)
+		}
+	}
+}
+
+// The installed versions must match the versions that the ansible playbooks
+// install
+func TestInstalledVersionsMatchAnsible(t *testing.T) {
+	d, err := ioutil.ReadFile("../../ansible/group_vars/container_images.yaml")
+	if err != nil {
+		t.Fatalf("error reading container images: %v", err)
+	}
+	images := struct {
+		Official map[string]struct {
+			Version string
+		} `yaml:"official_images"`
+	}{}
+	if err = yaml.Unmarshal(d, &images); err != nil {
+		t.Fatalf("error unmarshaling container images: %v", err)
+	}
+	d, err = ioutil.ReadFile("../../ansible/group_vars/all.yaml")
+	if err != nil {
+		t.Fatalf("error reading group vars: %v", err)
+	}
+	vars := map[string]interface{}{}
+	if err = yaml.Unmarshal(d, &vars); err != nil {
+		t.Fatalf("error unmarshaling group vars: %v", err)
+	}
+	tests := []struct {
+		component string
+		expected  string
+		actual    string
+	}{
+		{component: "kube-apiserver", expected: installedVersions.Kubernetes, actual: images.Official["kube_apiserver"].Version},
+		{component: "kube-proxy", expected: installedVersions.Kubernetes, actual: images.Official["kube_proxy"].Version},
+		{component: "kubelet", expected: installedVersions.Kubernetes, actual: vars["kubernetes_yum_version"].(string)},
+		{component: "etcd", expected: installedVersions.Etcd, actual: images.Official["etcd"].Version},
+		{component: "docker", expected: installedVersions.Docker, actual: vars["docker_engine_yum_version"].(string)},
+		{component: "calico", expected: installedVersions.Calico, actual: images.Official["calico_node"].Version},
+		{component: "weave", expected: installedVersions.Weave, actual: images.Official["weave"].Version},
+		{component: "contiv", expected: installedVersions.Contiv, actual: images.Official["contiv_netplugin"].Version},
+		{component: "kubedns", expected: installedVersions.KubeDNS, actual: images.Official["kubedns"].Version},
+		{component: "coredns", expected: installedVersions.CoreDNS, actual: images.Official["coredns"].Version},
+		{component: "dashboard", expected: installedVersions.Dashboard, actual: images.Official["kubernetes_dashboard"].Version},
+		{component: "helm", expected: installedVersions.Helm, actual: images.Official["helm"].Version},
+		{component: "metrics-server", expected: installedVersions.MetricsServer, actual: images.Official["metrics_server"].Version},
+	}
+	for _, test := range tests {
+		actual := strings.SplitN(strings.TrimPrefix(test.actual, "v"), "-", 2)[0]
+		if actual != test.expected {
+			t.Errorf("%s: the installed version is %q, but the ansible playbooks install %q", test.component, test.expected, test.actual)
+		}
+	}
+}
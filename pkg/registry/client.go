@@ -0,0 +1,419 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Manifest media types supported by the client
+const (
+	MediaTypeManifestList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeManifest         = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeSignedManifestV1 = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+	MediaTypeOCIIndex         = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest      = "application/vnd.oci.image.manifest.v1+json"
+
+	mediaTypeForeignLayer = "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip"
+)
+
+// DockerHub is the address of the Docker Hub registry
+const DockerHub = "registry-1.docker.io"
+
+var manifestMediaTypes = []string{
+	MediaTypeManifestList,
+	MediaTypeManifest,
+	MediaTypeSignedManifestV1,
+	MediaTypeOCIIndex,
+	MediaTypeOCIManifest,
+}
+
+// ErrNotFound is returned when the requested object does not exist in the registry
+var ErrNotFound = errors.New("not found")
+
+// A Manifest is an image manifest, or a manifest list, as stored in the registry
+type Manifest struct {
+	MediaType string
+	Digest    string
+	Payload   []byte
+}
+
+// IsList returns true if the manifest references other manifests, instead
+// of image layers.
+func (m Manifest) IsList() bool {
+	return m.MediaType == MediaTypeManifestList || m.MediaType == MediaTypeOCIIndex
+}
+
+// Client for the Docker Registry HTTP API V2
+type Client struct {
+	// Server is the address of the registry, without the protocol (e.g. localhost:5000)
+	Server string
+	// Username and Password used to authenticate with the registry. Leave
+	// empty for anonymous access.
+	Username string
+	Password string
+	// PlainHTTP is true when the registry does not use TLS
+	PlainHTTP bool
+	// HTTPClient used to talk to the registry. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	tokens    map[string]string
+	basicAuth bool
+}
+
+// ParseImageName splits an image name into the registry that hosts it and
+// the name of the repository within the registry, following the same rules
+// as the docker client.
+func ParseImageName(name string) (server string, repository string) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], parts[1]
+	}
+	if len(parts) == 1 {
+		return DockerHub, "library/" + name
+	}
+	return DockerHub, name
+}
+
+// Ping verifies that the registry serves the V2 API, and that it accepts the
+// credentials of the client
+func (c *Client) Ping() error {
+	req, err := http.NewRequest("GET", c.url("/v2/"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, "")
+	if err != nil {
+		return fmt.Errorf("error connecting to registry %s: %v", c.Server, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, "connecting to registry %s", c.Server)
+	}
+	return nil
+}
+
+// GetManifest returns the manifest with the given tag or digest
+func (c *Client) GetManifest(repository, reference string) (*Manifest, error) {
+	resp, err := c.manifestRequest("GET", repository, reference)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	payload, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest %s:%s: %v", repository, reference, err)
+	}
+	m := &Manifest{
+		MediaType: strings.Split(resp.Header.Get("Content-Type"), ";")[0],
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		Payload:   payload,
+	}
+	if m.Digest == "" {
+		m.Digest = Digest(payload)
+	}
+	return m, nil
+}
+
+// ManifestDigest returns the digest of the manifest with the given tag or
+// digest, or ErrNotFound if the manifest does not exist.
+func (c *Client) ManifestDigest(repository, reference string) (string, error) {
+	resp, err := c.manifestRequest("HEAD", repository, reference)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if d := resp.Header.Get("Docker-Content-Digest"); d != "" {
+		return d, nil
+	}
+	// Not all registries return the digest on HEAD requests
+	m, err := c.GetManifest(repository, reference)
+	if err != nil {
+		return "", err
+	}
+	return m.Digest, nil
+}
+
+// PutManifest uploads the manifest with the given tag or digest
+func (c *Client) PutManifest(repository, reference string, m Manifest) error {
+	req, err := http.NewRequest("PUT", c.url("/v2/%s/manifests/%s", repository, reference), bytes.NewReader(m.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", m.MediaType)
+	resp, err := c.do(req, repository)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp, "uploading manifest %s:%s", repository, reference)
+	}
+	return nil
+}
+
+// BlobExists returns true if the blob exists in the repository
+func (c *Client) BlobExists(repository, digest string) (bool, error) {
+	req, err := http.NewRequest("HEAD", c.url("/v2/%s/blobs/%s", repository, digest), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.do(req, repository)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, responseError(resp, "checking blob %s@%s", repository, digest)
+}
+
+// GetBlob returns the contents of the blob and its size. The caller must
+// close the returned reader.
+func (c *Client) GetBlob(repository, digest string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequest("GET", c.url("/v2/%s/blobs/%s", repository, digest), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.do(req, repository)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, 0, responseError(resp, "downloading blob %s@%s", repository, digest)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// PutBlob uploads the blob to the repository in a single request
+func (c *Client) PutBlob(repository, digest string, r io.Reader, size int64) error {
+	req, err := http.NewRequest("POST", c.url("/v2/%s/blobs/uploads/", repository), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, repository)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError(resp, "starting upload of blob %s@%s", repository, digest)
+	}
+	loc, err := req.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location %q: %v", resp.Header.Get("Location"), err)
+	}
+	q := loc.Query()
+	q.Set("digest", digest)
+	loc.RawQuery = q.Encode()
+
+	req, err = http.NewRequest("PUT", loc.String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err = c.do(req, repository)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp, "uploading blob %s@%s", repository, digest)
+	}
+	return nil
+}
+
+// Digest returns the digest of the content, in the format used by the registry
+func Digest(b []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+func (c *Client) manifestRequest(method, repository, reference string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.url("/v2/%s/manifests/%s", repository, reference), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	resp, err := c.do(req, repository)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	defer resp.Body.Close()
+	return nil, responseError(resp, "getting manifest %s:%s", repository, reference)
+}
+
+func (c *Client) url(format string, a ...interface{}) string {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Server) + fmt.Sprintf(format, a...)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// do sends the request, authenticating with the registry if required.
+// Requests with a body can only be retried after authenticating if the body
+// can be read again, so the first request to a repository should not have a
+// streamed body.
+func (c *Client) do(req *http.Request, repository string) (*http.Response, error) {
+	// requests that are not scoped to a repository, such as pings, use the
+	// empty scope
+	var scope string
+	if repository != "" {
+		scope = fmt.Sprintf("repository:%s:pull", repository)
+		if req.Method != "GET" && req.Method != "HEAD" {
+			scope += ",push"
+		}
+	}
+	c.authorize(req, scope)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if req.Body != nil && req.GetBody == nil {
+		return nil, fmt.Errorf("registry %s requires authentication", c.Server)
+	}
+	if err = c.authenticate(resp.Header.Get("WWW-Authenticate"), scope); err != nil {
+		return nil, err
+	}
+	retry := req
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry = new(http.Request)
+		*retry = *req
+		retry.Body = body
+	}
+	retry.Header = cloneHeader(req.Header)
+	c.authorize(retry, scope)
+	return c.httpClient().Do(retry)
+}
+
+func (c *Client) authorize(req *http.Request, scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if token, ok := c.tokens[scope]; ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return
+	}
+	if c.basicAuth && c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+var challengeParamRE = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authenticate obtains the credentials requested by the registry's
+// authentication challenge
+func (c *Client) authenticate(challenge string, scope string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[string]string{}
+	}
+	if strings.HasPrefix(strings.ToLower(challenge), "basic") {
+		if c.Username == "" {
+			return fmt.Errorf("registry %s requires a username and password", c.Server)
+		}
+		c.basicAuth = true
+		return nil
+	}
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer") {
+		return fmt.Errorf("registry %s requested an unsupported authentication scheme %q", c.Server, challenge)
+	}
+	params := map[string]string{}
+	for _, m := range challengeParamRE.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("registry %s returned an invalid authentication realm %q", c.Server, params["realm"])
+	}
+	q := realm.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+	realm.RawQuery = q.Encode()
+	req, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("error getting token for registry %s: %v", c.Server, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, "getting token for registry %s", c.Server)
+	}
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return fmt.Errorf("error decoding token for registry %s: %v", c.Server, err)
+	}
+	if t.Token == "" {
+		t.Token = t.AccessToken
+	}
+	c.tokens[scope] = t.Token
+	return nil
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string{}, v...)
+	}
+	return c
+}
+
+func responseError(resp *http.Response, action string, a ...interface{}) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = resp.Status
+	}
+	return fmt.Errorf("error %s: %s", fmt.Sprintf(action, a...), msg)
+}
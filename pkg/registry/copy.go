@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// the fields of the supported manifest formats that reference other objects
+type manifestReferences struct {
+	// manifest lists
+	Manifests []descriptor `json:"manifests"`
+	// image manifests
+	Config *descriptor  `json:"config"`
+	Layers []descriptor `json:"layers"`
+	// schema 1 image manifests
+	FSLayers []struct {
+		BlobSum string `json:"blobSum"`
+	} `json:"fsLayers"`
+}
+
+// CopyResult describes the outcome of copying an image
+type CopyResult struct {
+	// Skipped is true if the image was already present in the destination
+	Skipped bool
+	// Digest of the image's manifest
+	Digest string
+}
+
+// CopyImage copies the image with the given tag from the source repository
+// to the destination repository, including all the images referenced by a
+// manifest list. The image is not copied if the destination already has a
+// manifest with the same digest, unless force is true. Blobs that already
+// exist in the destination are not uploaded again. Progress messages are
+// written to log, if not nil.
+func CopyImage(src *Client, srcRepository string, dst *Client, dstRepository string, tag string, force bool, log io.Writer) (*CopyResult, error) {
+	if log == nil {
+		log = ioutil.Discard
+	}
+	m, err := src.GetManifest(srcRepository, tag)
+	if err != nil {
+		return nil, err
+	}
+	if !force {
+		d, err := dst.ManifestDigest(dstRepository, tag)
+		if err != nil && err != ErrNotFound {
+			return nil, err
+		}
+		if d == m.Digest {
+			return &CopyResult{Skipped: true, Digest: d}, nil
+		}
+	}
+	if err = copyManifest(src, srcRepository, dst, dstRepository, tag, m, log); err != nil {
+		return nil, err
+	}
+	return &CopyResult{Digest: m.Digest}, nil
+}
+
+func copyManifest(src *Client, srcRepository string, dst *Client, dstRepository string, reference string, m *Manifest, log io.Writer) error {
+	var refs manifestReferences
+	if err := json.Unmarshal(m.Payload, &refs); err != nil {
+		return fmt.Errorf("error decoding manifest %s:%s: %v", srcRepository, reference, err)
+	}
+	if m.IsList() {
+		for _, d := range refs.Manifests {
+			child, err := src.GetManifest(srcRepository, d.Digest)
+			if err != nil {
+				return err
+			}
+			if err = copyManifest(src, srcRepository, dst, dstRepository, d.Digest, child, log); err != nil {
+				return err
+			}
+		}
+	} else {
+		var blobs []string
+		if refs.Config != nil {
+			blobs = append(blobs, refs.Config.Digest)
+		}
+		for _, l := range refs.Layers {
+			// foreign layers are not stored in the registry
+			if l.MediaType == mediaTypeForeignLayer {
+				continue
+			}
+			blobs = append(blobs, l.Digest)
+		}
+		for _, l := range refs.FSLayers {
+			blobs = append(blobs, l.BlobSum)
+		}
+		for _, b := range blobs {
+			if err := copyBlob(src, srcRepository, dst, dstRepository, b, log); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintf(log, "Uploading manifest %s:%s\n", dstRepository, reference)
+	return dst.PutManifest(dstRepository, reference, *m)
+}
+
+func copyBlob(src *Client, srcRepository string, dst *Client, dstRepository string, digest string, log io.Writer) error {
+	exists, err := dst.BlobExists(dstRepository, digest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		fmt.Fprintf(log, "Blob %s already exists\n", digest)
+		return nil
+	}
+	fmt.Fprintf(log, "Copying blob %s\n", digest)
+	r, size, err := src.GetBlob(srcRepository, digest)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.PutBlob(dstRepository, digest, r, size)
+}
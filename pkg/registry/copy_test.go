@@ -0,0 +1,231 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry is an in-memory registry that implements the subset of the
+// API used by the client
+type fakeRegistry struct {
+	mu        sync.Mutex
+	manifests map[string]Manifest // repo:reference -> manifest
+	blobs     map[string][]byte   // repo@digest -> content
+	// when set, requests must have this bearer token
+	token       string
+	uploads     int
+	manifestPut int
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		manifests: map[string]Manifest{},
+		blobs:     map[string][]byte{},
+	}
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/token" {
+		fmt.Fprintf(w, `{"token": %q}`, f.token)
+		return
+	}
+	if f.token != "" && r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="fake"`, r.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case r.URL.Path == "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case strings.Contains(path, "/manifests/"):
+		parts := strings.SplitN(path, "/manifests/", 2)
+		key := parts[0] + ":" + parts[1]
+		switch r.Method {
+		case "GET", "HEAD":
+			m, ok := f.manifests[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", m.MediaType)
+			w.Header().Set("Docker-Content-Digest", m.Digest)
+			if r.Method == "GET" {
+				w.Write(m.Payload)
+			}
+		case "PUT":
+			payload, _ := ioutil.ReadAll(r.Body)
+			m := Manifest{MediaType: r.Header.Get("Content-Type"), Digest: Digest(payload), Payload: payload}
+			f.manifests[key] = m
+			f.manifests[parts[0]+":"+m.Digest] = m
+			f.manifestPut++
+			w.WriteHeader(http.StatusCreated)
+		}
+	case strings.HasSuffix(path, "/blobs/uploads/"):
+		w.Header().Set("Location", "/upload/"+strings.TrimSuffix(path, "/blobs/uploads/"))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.Contains(path, "/blobs/"):
+		parts := strings.SplitN(path, "/blobs/", 2)
+		b, ok := f.blobs[parts[0]+"@"+parts[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == "GET" {
+			w.Write(b)
+		}
+	case strings.HasPrefix(r.URL.Path, "/upload/"):
+		repo := strings.TrimPrefix(r.URL.Path, "/upload/")
+		b, _ := ioutil.ReadAll(r.Body)
+		f.blobs[repo+"@"+r.URL.Query().Get("digest")] = b
+		f.uploads++
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// adds an image to the registry, and returns its manifest
+func (f *fakeRegistry) addImage(repo, reference, layer string) Manifest {
+	config := []byte(`{"architecture": "` + reference + `"}`)
+	f.blobs[repo+"@"+Digest(config)] = config
+	f.blobs[repo+"@"+Digest([]byte(layer))] = []byte(layer)
+	payload, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeManifest,
+		"config":        descriptor{MediaType: "application/vnd.docker.container.image.v1+json", Digest: Digest(config)},
+		"layers":        []descriptor{{MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip", Digest: Digest([]byte(layer))}},
+	})
+	m := Manifest{MediaType: MediaTypeManifest, Digest: Digest(payload), Payload: payload}
+	f.manifests[repo+":"+reference] = m
+	f.manifests[repo+":"+m.Digest] = m
+	return m
+}
+
+func (f *fakeRegistry) addManifestList(repo, tag string, manifests ...Manifest) Manifest {
+	var descs []descriptor
+	for _, m := range manifests {
+		descs = append(descs, descriptor{MediaType: m.MediaType, Digest: m.Digest})
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeManifestList,
+		"manifests":     descs,
+	})
+	m := Manifest{MediaType: MediaTypeManifestList, Digest: Digest(payload), Payload: payload}
+	f.manifests[repo+":"+tag] = m
+	return m
+}
+
+func clientFor(s *httptest.Server) *Client {
+	return &Client{Server: strings.TrimPrefix(s.URL, "http://"), PlainHTTP: true}
+}
+
+func TestCopyImageManifestList(t *testing.T) {
+	srcRegistry := newFakeRegistry()
+	srcRegistry.token = "secret"
+	amd64 := srcRegistry.addImage("calico/node", "amd64", "amd64 layer")
+	arm64 := srcRegistry.addImage("calico/node", "arm64", "arm64 layer")
+	list := srcRegistry.addManifestList("calico/node", "v2.6.2", amd64, arm64)
+	src := httptest.NewServer(srcRegistry)
+	defer src.Close()
+
+	dstRegistry := newFakeRegistry()
+	dst := httptest.NewServer(dstRegistry)
+	defer dst.Close()
+
+	res, err := CopyImage(clientFor(src), "calico/node", clientFor(dst), "calico/node", "v2.6.2", false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error copying image: %v", err)
+	}
+	if res.Skipped || res.Digest != list.Digest {
+		t.Errorf("unexpected copy result: %+v", res)
+	}
+	copied, ok := dstRegistry.manifests["calico/node:v2.6.2"]
+	if !ok || copied.Digest != list.Digest {
+		t.Errorf("expected manifest list %s to be copied, but got %+v", list.Digest, copied)
+	}
+	for _, m := range []Manifest{amd64, arm64} {
+		if _, ok := dstRegistry.manifests["calico/node:"+m.Digest]; !ok {
+			t.Errorf("expected manifest %s to be copied", m.Digest)
+		}
+	}
+	// 2 configs and 2 layers
+	if dstRegistry.uploads != 4 {
+		t.Errorf("expected 4 blob uploads, but got %d", dstRegistry.uploads)
+	}
+
+	// copying again is a no-op
+	res, err = CopyImage(clientFor(src), "calico/node", clientFor(dst), "calico/node", "v2.6.2", false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error copying image: %v", err)
+	}
+	if !res.Skipped {
+		t.Errorf("expected the copy to be skipped, as the image already exists")
+	}
+	if dstRegistry.uploads != 4 || dstRegistry.manifestPut != 3 {
+		t.Errorf("expected no uploads, but got %d blob uploads and %d manifest uploads", dstRegistry.uploads-4, dstRegistry.manifestPut-3)
+	}
+
+	// forcing the copy only uploads the manifests
+	if _, err = CopyImage(clientFor(src), "calico/node", clientFor(dst), "calico/node", "v2.6.2", true, nil); err != nil {
+		t.Fatalf("unexpected error copying image: %v", err)
+	}
+	if dstRegistry.uploads != 4 || dstRegistry.manifestPut != 6 {
+		t.Errorf("expected only the manifests to be uploaded, but got %d blob uploads and %d manifest uploads", dstRegistry.uploads-4, dstRegistry.manifestPut-3)
+	}
+}
+
+func TestCopyImageNotFound(t *testing.T) {
+	src := httptest.NewServer(newFakeRegistry())
+	defer src.Close()
+	dst := httptest.NewServer(newFakeRegistry())
+	defer dst.Close()
+	if _, err := CopyImage(clientFor(src), "foo", clientFor(dst), "foo", "latest", false, nil); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, but got %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	reg := newFakeRegistry()
+	reg.token = "secret"
+	s := httptest.NewServer(reg)
+	defer s.Close()
+	if err := clientFor(s).Ping(); err != nil {
+		t.Errorf("unexpected error pinging registry: %v", err)
+	}
+	c := clientFor(s)
+	c.PlainHTTP = false
+	if err := c.Ping(); err == nil {
+		t.Errorf("expected an error pinging plain HTTP registry over HTTPS, but didn't get one")
+	}
+}
+
+func TestParseImageName(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     string
+		repository string
+	}{
+		{"busybox", DockerHub, "library/busybox"},
+		{"calico/node", DockerHub, "calico/node"},
+		{"quay.io/coreos/etcd", "quay.io", "coreos/etcd"},
+		{"gcr.io/google-containers/kube-proxy-amd64", "gcr.io", "google-containers/kube-proxy-amd64"},
+		{"localhost:5000/foo", "localhost:5000", "foo"},
+		{"localhost/foo", "localhost", "foo"},
+	}
+	for _, test := range tests {
+		server, repo := ParseImageName(test.name)
+		if server != test.server || repo != test.repository {
+			t.Errorf("%s: expected %s %s, but got %s %s", test.name, test.server, test.repository, server, repo)
+		}
+	}
+}
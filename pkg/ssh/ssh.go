@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -35,9 +36,33 @@ type ExternalClient struct {
 	cmd        *exec.Cmd
 }
 
+// Bastion is a host that the SSH connections to the nodes are tunneled
+// through, when the nodes are not reachable from the machine running kismatic
+type Bastion struct {
+	Host string
+	Port int
+	User string
+	Key  string
+}
+
+// ProxyCommand returns the ssh command that tunnels a connection through the
+// bastion host, to be used as the ProxyCommand option of ssh. The %h and %p
+// tokens are replaced by ssh with the host and port of the node.
+func (b Bastion) ProxyCommand() string {
+	args := append([]string{"ssh"}, baseSSHArgs...)
+	args = append(args, "-i", shellQuote(b.Key), "-p", fmt.Sprintf("%d", b.Port), "-W", "%h:%p", fmt.Sprintf("%s@%s", b.User, b.Host))
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes the string for the shell that runs the proxy command
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
 // TestConnection connects to ip:port as user with key and immediately exits.
-func TestConnection(ip string, port int, user, key string) error {
-	client, err := NewClient(ip, port, user, key)
+// The connection is tunneled through the bastion host when it is not nil.
+func TestConnection(ip string, port int, user, key string, bastion *Bastion) error {
+	client, err := NewClient(ip, port, user, key, bastion)
 	if err != nil {
 		return err
 	}
@@ -45,23 +70,33 @@ func TestConnection(ip string, port int, user, key string) error {
 	return client.Shell(false, "exit")
 }
 
-// NewClient verifies ssh is available in the PATH and returns an SSH client
-func NewClient(host string, port int, user string, key string) (Client, error) {
+// NewClient verifies ssh is available in the PATH and returns an SSH client.
+// The connections are tunneled through the bastion host when it is not nil.
+func NewClient(host string, port int, user string, key string, bastion *Bastion) (Client, error) {
 	if err := ValidUnencryptedPrivateKey(key); err != nil {
 		return nil, err
 	}
+	if bastion != nil {
+		if err := ValidUnencryptedPrivateKey(bastion.Key); err != nil {
+			return nil, fmt.Errorf("bastion: %v", err)
+		}
+	}
 
 	sshBinaryPath, err := exec.LookPath("ssh")
 	if err != nil {
 		return nil, fmt.Errorf("command not found: ssh")
 	}
 
-	return newExternalClient(sshBinaryPath, user, host, port, key)
+	return newExternalClient(sshBinaryPath, user, host, port, key, bastion)
 }
 
-func newExternalClient(sshBinaryPath string, user string, host string, port int, key string) (*ExternalClient, error) {
+func newExternalClient(sshBinaryPath string, user string, host string, port int, key string, bastion *Bastion) (*ExternalClient, error) {
 	// Get defailt args with user and host
-	args := append(baseSSHArgs, fmt.Sprintf("%s@%s", user, host))
+	args := append([]string{}, baseSSHArgs...)
+	if bastion != nil {
+		args = append(args, "-o", "ProxyCommand="+bastion.ProxyCommand())
+	}
+	args = append(args, fmt.Sprintf("%s@%s", user, host))
 	// set port
 	args = append(args, "-p", fmt.Sprintf("%d", port))
 	// set key
@@ -1,6 +1,9 @@
 package ssh
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestIsEncrypted(t *testing.T) {
 	for _, data := range testData {
@@ -132,3 +135,15 @@ yPZ77G/aRvyWij802LwB5dyGybbMd7LWPiuuaMAC/onxNnLYmtwh5VQjIwEM54Lc
 -----END RSA PRIVATE KEY-----`),
 	},
 }
+
+func TestBastionProxyCommand(t *testing.T) {
+	b := Bastion{Host: "bastion.example.com", Port: 2222, User: "ubuntu", Key: "/home/ubuntu/my key's.pem"}
+	cmd := b.ProxyCommand()
+	expectedSuffix := ` -i '/home/ubuntu/my key'\''s.pem' -p 2222 -W %h:%p ubuntu@bastion.example.com`
+	if !strings.HasPrefix(cmd, "ssh -F /dev/null ") {
+		t.Errorf("expected proxy command to start with the base ssh arguments, got %q", cmd)
+	}
+	if !strings.HasSuffix(cmd, expectedSuffix) {
+		t.Errorf("expected proxy command to end with %q, got %q", expectedSuffix, cmd)
+	}
+}
@@ -0,0 +1,288 @@
+// Package trace records the steps of a kismatic operation as spans, and
+// exports them to an OpenTelemetry collector using the OTLP/HTTP protocol
+// with JSON encoding. The spans can be viewed in Jaeger, or any other
+// tracing backend that accepts OTLP.
+//
+// A nil Tracer or Span is valid and records nothing, so that operations can
+// be instrumented whether tracing is enabled or not.
+package trace
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EndpointEnvVar is the base URL of the OTLP/HTTP collector. Spans are
+	// sent to <endpoint>/v1/traces.
+	EndpointEnvVar = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// TracesEndpointEnvVar is the full URL spans are sent to. It takes
+	// precedence over EndpointEnvVar.
+	TracesEndpointEnvVar = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	// ServiceNameEnvVar overrides the service name of the spans.
+	ServiceNameEnvVar = "OTEL_SERVICE_NAME"
+
+	defaultServiceName = "kismatic"
+)
+
+// Tracer collects the spans of a single operation. The operation is the
+// root span of the trace.
+type Tracer struct {
+	url     string
+	service string
+	client  *http.Client
+	now     func() time.Time
+
+	mu      sync.Mutex
+	traceID string
+	root    *Span
+	spans   []*Span
+}
+
+// Span is a timed step of an operation
+type Span struct {
+	tracer   *Tracer
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs map[string]string
+	err   error
+}
+
+// FromEnvironment returns a tracer for the operation that exports to the
+// collector set in the environment, or nil if no collector is set.
+func FromEnvironment(operation string) *Tracer {
+	url := os.Getenv(TracesEndpointEnvVar)
+	if url == "" {
+		endpoint := os.Getenv(EndpointEnvVar)
+		if endpoint == "" {
+			return nil
+		}
+		url = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	service := os.Getenv(ServiceNameEnvVar)
+	if service == "" {
+		service = defaultServiceName
+	}
+	return New(url, service, operation)
+}
+
+// New returns a tracer for the operation that exports the spans to the url
+func New(url, service, operation string) *Tracer {
+	t := &Tracer{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+		traceID: randomID(16),
+	}
+	t.root = t.newSpan(operation, "")
+	return t
+}
+
+// TraceID returns the ID of the trace, which can be used to look up the
+// trace in the tracing backend
+func (t *Tracer) TraceID() string {
+	if t == nil {
+		return ""
+	}
+	return t.traceID
+}
+
+// Start starts a span that is a child of the operation
+func (t *Tracer) Start(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	return t.root.Start(name)
+}
+
+// Finish ends the operation with the given error, and exports all the spans
+// to the collector. Spans that have not ended are ended first.
+func (t *Tracer) Finish(err error) error {
+	if t == nil {
+		return nil
+	}
+	t.root.End(err)
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	for _, s := range spans {
+		s.End(nil)
+	}
+	return t.export(spans)
+}
+
+func (t *Tracer) newSpan(name, parentID string) *Span {
+	s := &Span{
+		tracer:   t,
+		spanID:   randomID(8),
+		parentID: parentID,
+		name:     name,
+		start:    t.now(),
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return s
+}
+
+// Start starts a span that is a child of this span
+func (s *Span) Start(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.newSpan(name, s.spanID)
+}
+
+// SetAttribute records a key value pair on the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = map[string]string{}
+	}
+	s.attrs[key] = value
+}
+
+// End ends the span. A non-nil error marks the span as failed. Ending a span
+// more than once has no effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.end.IsZero() {
+		return
+	}
+	s.end = s.tracer.now()
+	s.err = err
+}
+
+// The OTLP JSON encoding of the spans
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+func (t *Tracer) encode(spans []*Span) otlpRequest {
+	rs := otlpResourceSpans{}
+	rs.Resource.Attributes = []otlpAttribute{attribute("service.name", t.service)}
+	ss := otlpScopeSpans{}
+	ss.Scope.Name = defaultServiceName
+	for _, s := range spans {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		for k, v := range s.attrs {
+			span.Attributes = append(span.Attributes, attribute(k, v))
+		}
+		if s.err != nil {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: s.err.Error()}
+		}
+		s.mu.Unlock()
+		ss.Spans = append(ss.Spans, span)
+	}
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+func (t *Tracer) export(spans []*Span) error {
+	b, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return fmt.Errorf("error encoding spans: %v", err)
+	}
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("error exporting spans to %q: %v", t.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error exporting spans to %q: %s", t.url, resp.Status)
+	}
+	return nil
+}
+
+func attribute(key, value string) otlpAttribute {
+	a := otlpAttribute{Key: key}
+	a.Value.StringValue = value
+	return a
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// fall back to the clock, IDs only need to be unique within a trace
+		copy(b, strconv.FormatInt(time.Now().UnixNano(), 16))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start("validate")
+	span.SetAttribute("plan", "kismatic-cluster.yaml")
+	span.Start("child").End(nil)
+	span.End(errors.New("failed"))
+	if err := tracer.Finish(nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTracerExport(t *testing.T) {
+	var received otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &received); err != nil {
+			t.Errorf("error unmarshaling request: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Unix(1500000000, 0)
+	tracer := New(server.URL+"/v1/traces", "kismatic", "apply")
+	tracer.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	tracer.root.start = now
+	task := tracer.Start("install")
+	task.SetAttribute("playbook", "kubernetes.yaml")
+	play := task.Start("Start Kubernetes Etcd Cluster")
+	play.End(errors.New("etcd failed to start"))
+	// the task span is ended by Finish
+	if err := tracer.Finish(errors.New("error installing")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received.ResourceSpans) != 1 || len(received.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request: %+v", received)
+	}
+	if attrs := received.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || attrs[0].Value.StringValue != "kismatic" {
+		t.Errorf("unexpected resource attributes: %+v", attrs)
+	}
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, but got %d", len(spans))
+	}
+	root, install, etcd := spans[0], spans[1], spans[2]
+	if root.Name != "apply" || root.ParentSpanID != "" || root.Status.Code != otlpStatusError {
+		t.Errorf("unexpected root span: %+v", root)
+	}
+	if install.ParentSpanID != root.SpanID || install.Status.Code != otlpStatusOK || len(install.Attributes) != 1 {
+		t.Errorf("unexpected install span: %+v", install)
+	}
+	if etcd.ParentSpanID != install.SpanID || etcd.Status.Message != "etcd failed to start" {
+		t.Errorf("unexpected play span: %+v", etcd)
+	}
+	for _, s := range spans {
+		if s.TraceID != tracer.TraceID() || len(s.TraceID) != 32 || len(s.SpanID) != 16 {
+			t.Errorf("unexpected IDs in span %q: %s %s", s.Name, s.TraceID, s.SpanID)
+		}
+		if s.EndTimeUnixNano <= s.StartTimeUnixNano {
+			t.Errorf("span %q ends before it starts", s.Name)
+		}
+	}
+}
+
+func TestTracerExportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	if err := New(server.URL, "kismatic", "apply").Finish(nil); err == nil {
+		t.Errorf("expected an error when the collector is unavailable")
+	}
+}
@@ -50,6 +50,23 @@ func PromptForString(in io.Reader, out io.Writer, prompt string, defaultValue st
 	return ans, nil
 }
 
+// PromptForText reads a line of command line input. The default value is
+// returned when the line is empty, and is not shown in the prompt.
+func PromptForText(in io.Reader, out io.Writer, prompt string, defaultValue string) (string, error) {
+	fmt.Fprintf(out, "=> %s: ", prompt)
+	s := bufio.NewScanner(in)
+	// Scan the first line
+	s.Scan()
+	if s.Err() != nil {
+		return defaultValue, fmt.Errorf("error reading string: %v", s.Err())
+	}
+	ans := strings.TrimSpace(s.Text())
+	if ans == "" {
+		return defaultValue, nil
+	}
+	return ans, nil
+}
+
 // CreateDir check if directory exists and create it
 func CreateDir(dir string, perm os.FileMode) error {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
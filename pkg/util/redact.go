@@ -0,0 +1,59 @@
+package util
+
+import "regexp"
+
+const redacted = "[REDACTED]"
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+	// secretPatterns are the secrets that are masked, with the replacement
+	// that keeps the text around the secret
+	secretPatterns = []struct {
+		re          *regexp.Regexp
+		replacement string
+	}{
+		// private key blocks that are on a single line, such as in JSON
+		{regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`), redacted},
+		// AWS access key IDs
+		{regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), redacted},
+		// AWS secret access keys, which are only recognizable by their name
+		{regexp.MustCompile(`(?i)(secret_access_key|secret_key|secretaccesskey)(\W{1,8})[A-Za-z0-9/+=]{40}`), "${1}${2}" + redacted},
+		// bearer tokens in authorization headers
+		{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`), "${1}" + redacted},
+	}
+)
+
+// Redact masks the known secret patterns in the text, such as private key
+// blocks, AWS keys and bearer tokens
+func Redact(s string) string {
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// Redactor masks the known secret patterns in the lines of a log. Unlike
+// Redact, it masks private key blocks that span multiple lines.
+type Redactor struct {
+	inPrivateKey bool
+}
+
+// Line returns the line with its secrets masked. The lines of a private key
+// block are returned empty.
+func (r *Redactor) Line(line string) string {
+	if r.inPrivateKey {
+		loc := privateKeyEnd.FindStringIndex(line)
+		if loc == nil {
+			return ""
+		}
+		r.inPrivateKey = false
+		line = line[loc[1]:]
+	}
+	line = Redact(line)
+	if loc := privateKeyBegin.FindStringIndex(line); loc != nil {
+		r.inPrivateKey = true
+		return line[:loc[0]] + redacted
+	}
+	return line
+}